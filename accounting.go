@@ -0,0 +1,57 @@
+package xlsx
+
+import "strings"
+
+// NegativeStyle is the way an accounting number format shows negative
+// amounts.
+type NegativeStyle int
+
+const (
+	// NegativeParentheses shows negative amounts between
+	// parentheses, as Excel does by default: ($ 1,234.50).
+	NegativeParentheses NegativeStyle = iota
+	// NegativeMinus shows negative amounts with a minus sign.
+	NegativeMinus
+	// NegativeRed shows negative amounts in red, without a sign.
+	NegativeRed
+	// NegativeRedParentheses shows negative amounts in red, between
+	// parentheses.
+	NegativeRedParentheses
+)
+
+// AccountingNumberFormat returns the accounting number format of
+// amounts with the given currency symbol, such as "$" or "€", and
+// number of decimals, to be set with Cell.SetFormat or
+// Cell.SetFloatWithFormat.  As in the Accounting format of Excel, the
+// symbol is aligned to the left of the cell and the amounts to the
+// right, zero shows as a dash, and text is indented like the amounts.
+// With no symbol, NegativeParentheses and 0 or 2 decimals, the format
+// is one of the built-in formats 41 to 44, which files refer to by
+// their id.
+func AccountingNumberFormat(symbol string, decimals int, negative NegativeStyle) string {
+	number := "#,##0"
+	zero := `"-"`
+	if decimals > 0 {
+		number += "." + strings.Repeat("0", decimals)
+		zero += strings.Repeat("?", decimals)
+	}
+	if symbol != "" {
+		symbol = `"` + symbol + `"`
+	}
+	// The space following the asterisk is repeated to fill the cell
+	// between the symbol and the amount.
+	fill := symbol + "* "
+	positive := "_(" + fill + number + "_)"
+	var negatives string
+	switch negative {
+	case NegativeMinus:
+		negatives = `\-` + fill + number + "_)"
+	case NegativeRed:
+		negatives = "[Red]_(" + fill + number + "_)"
+	case NegativeRedParentheses:
+		negatives = `[Red]_(` + fill + `\(` + number + `\)`
+	default:
+		negatives = "_(" + fill + `\(` + number + `\)`
+	}
+	return positive + ";" + negatives + ";_(" + fill + zero + "_);_(@_)"
+}
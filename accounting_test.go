@@ -0,0 +1,75 @@
+package xlsx
+
+import (
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type AccountingSuite struct{}
+
+var _ = Suite(&AccountingSuite{})
+
+func (s *AccountingSuite) TestAccountingNumberFormat(c *C) {
+	c.Assert(AccountingNumberFormat("$", 2, NegativeParentheses), Equals, builtInNumFmt[44])
+	c.Assert(AccountingNumberFormat("$", 0, NegativeParentheses), Equals, builtInNumFmt[42])
+	c.Assert(AccountingNumberFormat("", 2, NegativeParentheses), Equals, builtInNumFmt[43])
+	c.Assert(AccountingNumberFormat("", 0, NegativeParentheses), Equals, builtInNumFmt[41])
+	c.Assert(AccountingNumberFormat("€", 2, NegativeMinus), Equals,
+		`_("€"* #,##0.00_);\-"€"* #,##0.00_);_("€"* "-"??_);_(@_)`)
+	c.Assert(AccountingNumberFormat("£", 2, NegativeRed), Equals,
+		`_("£"* #,##0.00_);[Red]_("£"* #,##0.00_);_("£"* "-"??_);_(@_)`)
+	c.Assert(AccountingNumberFormat("¥", 0, NegativeRedParentheses), Equals,
+		`_("¥"* #,##0_);[Red]_("¥"* \(#,##0\);_("¥"* "-"_);_(@_)`)
+}
+
+func (s *AccountingSuite) TestFormattedValue(c *C) {
+	cell := &Cell{}
+	cases := []struct {
+		negative NegativeStyle
+		value    float64
+		expected string
+	}{
+		{NegativeParentheses, 1234.5, "$ 1234.50"},
+		{NegativeParentheses, -1234.5, "$ (1234.50)"},
+		{NegativeMinus, -1234.5, "-$ 1234.50"},
+		{NegativeRed, -1234.5, "$ 1234.50"},
+		{NegativeRedParentheses, -1234.5, "$ (1234.50)"},
+	}
+	for _, tc := range cases {
+		cell.SetFloatWithFormat(tc.value, AccountingNumberFormat("$", 2, tc.negative))
+		value, err := cell.FormattedValue()
+		c.Assert(err, IsNil)
+		c.Assert(value, Equals, tc.expected)
+	}
+}
+
+// Accounting formats are read back as they were written, the built-in
+// ones through their id.
+func (s *AccountingSuite) TestRoundTrip(c *C) {
+	formats := []string{
+		AccountingNumberFormat("$", 2, NegativeParentheses),
+		AccountingNumberFormat("$", 0, NegativeParentheses),
+		AccountingNumberFormat("€", 2, NegativeMinus),
+		AccountingNumberFormat("£", 2, NegativeRed),
+		AccountingNumberFormat("¥", 0, NegativeRedParentheses),
+	}
+	f := NewFile()
+	sheet, err := f.AddSheet("Ledger")
+	c.Assert(err, IsNil)
+	for _, format := range formats {
+		sheet.AddRow().AddCell().SetFloatWithFormat(-1234.5, format)
+	}
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Count(parts["xl/styles.xml"], "<numFmt "), Equals, 3)
+	var buffer bytes.Buffer
+	c.Assert(f.Write(&buffer), IsNil)
+
+	f, err = OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	for i, format := range formats {
+		c.Assert(f.Sheets[0].Cell(i, 0).NumFmt, Equals, format)
+	}
+}
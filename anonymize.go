@@ -0,0 +1,270 @@
+package xlsx
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// AnonymizeAction is the way Anonymize replaces the values of the cells
+// selected by an AnonymizeRule.  The replacements are derived from the
+// values and the Key of the rule, so that a value gets the same
+// replacement wherever it is, and the anonymized columns can still be
+// joined and grouped.
+type AnonymizeAction int
+
+const (
+	// HashValues replaces text by a digest of it, of 12 hexadecimal
+	// digits, and numbers by numbers of as many digits.
+	HashValues AnonymizeAction = iota
+	// FakeValues replaces each letter by a letter of the same case,
+	// and each digit by a digit, the other characters being kept, so
+	// that the values keep their length and shape: "Jane Doe, 0612"
+	// becomes something like "Qwlr Ufa, 0385".
+	FakeValues
+	// BlankValues empties the cells.
+	BlankValues
+)
+
+// anonymizedDigestLength is the number of hexadecimal digits of the
+// digests replacing text, see HashValues.
+const anonymizedDigestLength = 12
+
+// AnonymizeRule selects the cells of a column that Anonymize replaces,
+// the column being given either by its header, compared regardless of
+// case with the cells of the first row of the sheets, or by its letters,
+// such as "C".
+type AnonymizeRule struct {
+	// Sheet is the name of the sheet of the column, every sheet with
+	// the column if empty.
+	Sheet  string
+	Header string
+	Column string
+	Action AnonymizeAction
+	// Mask, if not nil, replaces the values instead of Action, such as
+	// MaskDigits(4), the values that are no longer numbers being
+	// written as text.
+	Mask Mask
+	// Key is the secret the replacements are derived from, which keeps
+	// values that can be guessed, such as names, from being found by
+	// anonymizing guesses.  The rules sharing a Key replace a value
+	// alike.
+	Key []byte
+}
+
+// anonymizedColumn is a column of a sheet selected by a rule.
+type anonymizedColumn struct {
+	sheet *Sheet
+	col   int
+	rule  *AnonymizeRule
+}
+
+// Anonymize replaces the values of the cells of file selected by rules,
+// such as to make test fixtures of spreadsheets holding personal data,
+// and returns the number of cells changed.  Everything else is kept:
+// the sheets, the styles and number formats of the cells, and their
+// formulas, whose cached values are replaced like those of the other
+// cells.  The first row of the sheets, taken as their header row, the
+// booleans and the error values are left as they are.
+//
+// The rules are checked before anything is changed: a rule naming a
+// sheet that doesn't exist, or a column found in no sheet, makes
+// Anonymize fail without changing the File.
+func Anonymize(file *File, rules []AnonymizeRule) (int, error) {
+	var columns []anonymizedColumn
+	for i := range rules {
+		ruleColumns, err := file.anonymizedColumns(&rules[i])
+		if err != nil {
+			return 0, err
+		}
+		columns = append(columns, ruleColumns...)
+	}
+	changed := 0
+	for _, column := range columns {
+		for r := 1; r < len(column.sheet.rowList()); r++ {
+			row := column.sheet.row(r)
+			if row == nil || column.col >= len(row.Cells) || row.Cells[column.col] == nil {
+				continue
+			}
+			if column.rule.anonymize(row.Cells[column.col]) {
+				changed++
+			}
+		}
+	}
+	return changed, nil
+}
+
+// anonymizedColumns returns the columns of the File selected by rule.
+func (f *File) anonymizedColumns(rule *AnonymizeRule) ([]anonymizedColumn, error) {
+	if (rule.Header == "") == (rule.Column == "") {
+		return nil, fmt.Errorf("an anonymize rule needs either a header or a column")
+	}
+	if rule.Action < HashValues || rule.Action > BlankValues {
+		return nil, fmt.Errorf("invalid anonymize action %d", rule.Action)
+	}
+	sheets := f.Sheets
+	if rule.Sheet != "" {
+		sheet, ok := f.Sheet[rule.Sheet]
+		if !ok {
+			return nil, fmt.Errorf("no sheet %q to anonymize", rule.Sheet)
+		}
+		sheets = []*Sheet{sheet}
+	}
+	col := -1
+	if rule.Column != "" {
+		col = ColLettersToIndex(rule.Column)
+		if strings.Trim(strings.ToUpper(rule.Column), "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" || col >= Excel2006MaxColumnCount {
+			return nil, fmt.Errorf("invalid column %q to anonymize", rule.Column)
+		}
+	}
+	var columns []anonymizedColumn
+	for _, sheet := range sheets {
+		sheetCol := col
+		if rule.Header != "" {
+			sheetCol = sheet.headerColumn(rule.Header)
+		}
+		if sheetCol >= 0 {
+			columns = append(columns, anonymizedColumn{sheet: sheet, col: sheetCol, rule: rule})
+		}
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no column %q to anonymize", rule.Header)
+	}
+	return columns, nil
+}
+
+// headerColumn returns the index of the column whose cell in the first
+// row of the Sheet is header, regardless of case, or -1.
+func (s *Sheet) headerColumn(header string) int {
+	row := s.row(0)
+	if row == nil {
+		return -1
+	}
+	for i, cell := range row.Cells {
+		if cell != nil && strings.EqualFold(strings.TrimSpace(cell.Value), strings.TrimSpace(header)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// anonymize replaces the value of cell as the rule says, and returns
+// true if it was changed.
+func (rule *AnonymizeRule) anonymize(cell *Cell) bool {
+	if cell.Value == "" || cell.cellType == CellTypeBool || cell.cellType == CellTypeError {
+		return false
+	}
+	cell.changing()
+	numeric := cell.cellType == CellTypeNumeric || cell.cellType == CellTypeDate
+	var value string
+	switch {
+	case rule.Mask != nil:
+		value = rule.Mask(cell.Value)
+		if numeric {
+			if _, err := parseFloat(value); err != nil {
+				cell.cellType = CellTypeString
+			}
+		}
+	case rule.Action == BlankValues:
+		value = ""
+		if numeric {
+			cell.cellType = CellTypeString
+		}
+	case numeric:
+		value = rule.fakeNumber(cell.Value)
+	case rule.Action == HashValues:
+		digest := rule.digest(cell.Value, 0)
+		value = hex.EncodeToString(digest[:])[:anonymizedDigestLength]
+	default:
+		value = rule.fakeText(cell.Value)
+	}
+	if value == cell.Value {
+		return false
+	}
+	cell.Value = value
+	return true
+}
+
+// digest returns the HMAC of value keyed by the Key of the rule, for
+// the given counter, each counter giving 32 more bytes derived from the
+// value.
+func (rule *AnonymizeRule) digest(value string, counter uint32) [sha256.Size]byte {
+	mac := hmac.New(sha256.New, rule.Key)
+	var prefix [4]byte
+	binary.BigEndian.PutUint32(prefix[:], counter)
+	mac.Write(prefix[:])
+	mac.Write([]byte(value))
+	var digest [sha256.Size]byte
+	copy(digest[:], mac.Sum(nil))
+	return digest
+}
+
+// anonymizedBytes gives the bytes derived from a value, one at a time.
+type anonymizedBytes struct {
+	rule    *AnonymizeRule
+	value   string
+	counter uint32
+	buffer  []byte
+}
+
+// next returns the next byte derived from the value.
+func (b *anonymizedBytes) next() byte {
+	if len(b.buffer) == 0 {
+		digest := b.rule.digest(b.value, b.counter)
+		b.counter++
+		b.buffer = digest[:]
+	}
+	next := b.buffer[0]
+	b.buffer = b.buffer[1:]
+	return next
+}
+
+// fakeText returns value with its letters and digits replaced, see
+// FakeValues.
+func (rule *AnonymizeRule) fakeText(value string) string {
+	source := &anonymizedBytes{rule: rule, value: value}
+	var fake bytes.Buffer
+	for _, r := range value {
+		switch {
+		case r >= 'a' && r <= 'z':
+			fake.WriteByte('a' + source.next()%26)
+		case r >= 'A' && r <= 'Z':
+			fake.WriteByte('A' + source.next()%26)
+		case r >= '0' && r <= '9':
+			fake.WriteByte('0' + source.next()%10)
+		default:
+			fake.WriteRune(r)
+		}
+	}
+	return fake.String()
+}
+
+// fakeNumber returns the number value, as written in a cell, with the
+// digits of its mantissa replaced, its first digit staying 0 or not, so
+// that the number keeps its sign and magnitude.
+func (rule *AnonymizeRule) fakeNumber(value string) string {
+	source := &anonymizedBytes{rule: rule, value: value}
+	fake := []byte(value)
+	first := true
+	for i, b := range fake {
+		if b == 'e' || b == 'E' {
+			break
+		}
+		if b < '0' || b > '9' {
+			continue
+		}
+		switch {
+		case first && b == '0':
+		case first:
+			fake[i] = '1' + source.next()%9
+		default:
+			fake[i] = '0' + source.next()%10
+		}
+		first = false
+	}
+	return string(fake)
+}
@@ -0,0 +1,113 @@
+package xlsx
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type AnonymizeSuite struct{}
+
+var _ = Suite(&AnonymizeSuite{})
+
+// anonymizeFile returns a File of customers, whose names, emails and
+// balances are personal, and of their orders, naming them again.
+func anonymizeFile(c *C) *File {
+	file := NewFile()
+	customers, err := file.AddSheet("Customers")
+	c.Assert(err, IsNil)
+	customers.AddRow().WriteSlice(&[]string{"Name", "Email", "Balance", "Active"}, -1)
+	for _, name := range []string{"Jane Doe", "John Smith"} {
+		row := customers.AddRow()
+		row.AddCell().SetString(name)
+		row.AddCell().SetString(name[:4] + "@example.com")
+		row.AddCell().SetFloat(1234.5)
+		row.AddCell().SetBool(true)
+	}
+	customers.Cell(2, 2).SetFloat(0.25)
+	orders, err := file.AddSheet("Orders")
+	c.Assert(err, IsNil)
+	orders.AddRow().WriteSlice(&[]string{"Order", "name"}, -1)
+	row := orders.AddRow()
+	row.AddCell().SetInt(1)
+	row.AddCell().SetString("Jane Doe")
+	return file
+}
+
+func (s *AnonymizeSuite) TestAnonymize(c *C) {
+	file := anonymizeFile(c)
+	customers := file.Sheet["Customers"]
+	balanceStyle := customers.Cell(1, 2).GetStyle()
+	balanceStyle.Font.Bold = true
+	customers.Cell(1, 2).SetFormula("1000+234.5")
+	key := []byte("fixture key")
+	changed, err := Anonymize(file, []AnonymizeRule{
+		{Header: "name", Action: HashValues, Key: key},
+		{Sheet: "Customers", Column: "b", Action: FakeValues, Key: key},
+		{Sheet: "Customers", Header: "Balance", Action: FakeValues, Key: key},
+		{Sheet: "Customers", Header: "Active", Action: BlankValues},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(changed, Equals, 7)
+
+	// The same names get the same digests across sheets.
+	name := customers.Cell(1, 0).Value
+	c.Assert(name, Matches, "[0-9a-f]{12}")
+	c.Assert(file.Sheet["Orders"].Cell(1, 1).Value, Equals, name)
+	c.Assert(customers.Cell(2, 0).Value, Not(Equals), name)
+	c.Assert(customers.Cell(0, 0).Value, Equals, "Name")
+	email := customers.Cell(1, 1).Value
+	c.Assert(email, Matches, "[A-Z][a-z]{3}@[a-z]{7}\\.[a-z]{3}")
+	c.Assert(email, Not(Equals), "Jane@example.com")
+
+	// The numbers keep their shape, formulas and styles.
+	c.Assert(customers.Cell(1, 2).Value, Matches, "[1-9][0-9]{3}\\.[0-9]")
+	c.Assert(customers.Cell(1, 2).Type(), Equals, CellTypeNumeric)
+	c.Assert(customers.Cell(1, 2).Formula(), Equals, "1000+234.5")
+	c.Assert(customers.Cell(1, 2).GetStyle().Font.Bold, Equals, true)
+	c.Assert(customers.Cell(2, 2).Value, Matches, "0\\.[0-9]{2}")
+	c.Assert(customers.Cell(1, 3).Value, Equals, "1")
+
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	read, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(read.Sheet["Orders"].Cell(1, 1).Value, Equals, name)
+
+	// The replacements depend on the key.
+	other := anonymizeFile(c)
+	_, err = Anonymize(other, []AnonymizeRule{{Header: "Name", Key: []byte("another key")}})
+	c.Assert(err, IsNil)
+	c.Assert(other.Sheet["Customers"].Cell(1, 0).Value, Not(Equals), name)
+	c.Assert(other.Sheet["Customers"].Cell(1, 0).Value, Matches, "[0-9a-f]{12}")
+
+	// A mask replaces the values instead, those that are no longer
+	// numbers becoming text.
+	other = anonymizeFile(c)
+	changed, err = Anonymize(other, []AnonymizeRule{{Sheet: "Customers", Header: "Balance", Mask: MaskDigits(1)}})
+	c.Assert(err, IsNil)
+	c.Assert(changed, Equals, 2)
+	c.Assert(other.Sheet["Customers"].Cell(1, 2).Value, Equals, "****.5")
+	c.Assert(other.Sheet["Customers"].Cell(1, 2).Type(), Equals, CellTypeString)
+}
+
+func (s *AnonymizeSuite) TestAnonymizeErrors(c *C) {
+	file := anonymizeFile(c)
+	for _, tc := range []struct {
+		rule AnonymizeRule
+		err  string
+	}{
+		{AnonymizeRule{}, "an anonymize rule needs either a header or a column"},
+		{AnonymizeRule{Header: "Name", Column: "A"}, "an anonymize rule needs either a header or a column"},
+		{AnonymizeRule{Header: "Name", Action: AnonymizeAction(7)}, "invalid anonymize action 7"},
+		{AnonymizeRule{Sheet: "Missing", Header: "Name"}, `no sheet "Missing" to anonymize`},
+		{AnonymizeRule{Column: "A1"}, `invalid column "A1" to anonymize`},
+		{AnonymizeRule{Header: "Phone"}, `no column "Phone" to anonymize`},
+	} {
+		// The rules are checked before any cell is changed.
+		changed, err := Anonymize(file, []AnonymizeRule{{Header: "Name"}, tc.rule})
+		c.Assert(err, ErrorMatches, tc.err)
+		c.Assert(changed, Equals, 0)
+		c.Assert(file.Sheet["Customers"].Cell(1, 0).Value, Equals, "Jane Doe")
+	}
+}
@@ -0,0 +1,582 @@
+package xlsx
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Operators of a CustomFilter
+const (
+	FilterOperatorEqual              = "equal"
+	FilterOperatorNotEqual           = "notEqual"
+	FilterOperatorLessThan           = "lessThan"
+	FilterOperatorLessThanOrEqual    = "lessThanOrEqual"
+	FilterOperatorGreaterThan        = "greaterThan"
+	FilterOperatorGreaterThanOrEqual = "greaterThanOrEqual"
+)
+
+// Types of a dynamic filter.  The date based types are relative to
+// the day the workbook is opened.
+const (
+	DynamicFilterAboveAverage = "aboveAverage"
+	DynamicFilterBelowAverage = "belowAverage"
+	DynamicFilterToday        = "today"
+	DynamicFilterYesterday    = "yesterday"
+	DynamicFilterTomorrow     = "tomorrow"
+	DynamicFilterThisWeek     = "thisWeek"
+	DynamicFilterLastWeek     = "lastWeek"
+	DynamicFilterThisMonth    = "thisMonth"
+	DynamicFilterLastMonth    = "lastMonth"
+	DynamicFilterThisYear     = "thisYear"
+	DynamicFilterLastYear     = "lastYear"
+	DynamicFilterYearToDate   = "yearToDate"
+)
+
+// Groupings of a DateGroup
+const (
+	DateGroupingYear   = "year"
+	DateGroupingMonth  = "month"
+	DateGroupingDay    = "day"
+	DateGroupingHour   = "hour"
+	DateGroupingMinute = "minute"
+	DateGroupingSecond = "second"
+)
+
+// DateGroup selects the dates within a year, month, day, hour, minute
+// or second, as Excel does when dates are picked in the filter
+// drop-down.  Grouping is one of the DateGrouping* values, and only
+// the fields down to the grouping are used.  For example, all of
+// March 2024 is selected by
+//
+//	DateGroup{Grouping: DateGroupingMonth, Year: 2024, Month: 3}
+type DateGroup struct {
+	Grouping string
+	Year     int
+	Month    int
+	Day      int
+	Hour     int
+	Minute   int
+	Second   int
+}
+
+// FilterColumn holds the criteria that an AutoFilter applies to one of
+// its columns.  Only one kind of criteria should be set: Values and
+// DateGroups, CustomFilters, Top10 or DynamicFilter.
+type FilterColumn struct {
+	// Col is the zero based offset of the column from the left edge
+	// of the AutoFilter range.
+	Col int
+	// Values keeps the rows whose displayed value is one of these,
+	// ignoring case.
+	Values []string
+	// DateGroups keeps the rows whose date falls within one of the
+	// groups.  Date cells are those with a date format, such as the
+	// ones set by Cell.SetDate.
+	DateGroups []DateGroup
+	// Blank also keeps the empty rows when filtering by Values or
+	// DateGroups.
+	Blank bool
+	// CustomFilters keeps the rows that match any of, or if And is
+	// true all of, at most two comparisons.
+	CustomFilters []CustomFilter
+	And           bool
+	// Top10 keeps the rows with the highest or lowest values.
+	Top10 *Top10Filter
+	// DynamicFilter is one of the DynamicFilter* types.
+	DynamicFilter string
+}
+
+// CustomFilter compares the values of a column with Value, using one
+// of the FilterOperator* operators.  For FilterOperatorEqual and
+// FilterOperatorNotEqual, Value may contain the wildcards * and ?.
+type CustomFilter struct {
+	Operator string
+	Value    string
+}
+
+// Top10Filter keeps the rows holding the Value highest, or if Bottom
+// is true lowest, values of a column.  If Percent is true, Value is a
+// percentage of the rows rather than a count.
+type Top10Filter struct {
+	Bottom  bool
+	Percent bool
+	Value   float64
+}
+
+// SortCondition records that the rows of an AutoFilter are sorted by
+// one of its columns, Col being the zero based offset of the column
+// from the left edge of the AutoFilter range.
+type SortCondition struct {
+	Col        int
+	Descending bool
+}
+
+// AddFilterColumn adds the criteria of a column to the AutoFilter.
+func (af *AutoFilter) AddFilterColumn(column *FilterColumn) {
+	af.Columns = append(af.Columns, column)
+}
+
+// bounds returns the zero based coordinates of the corners of the
+// AutoFilter range.
+func (af *AutoFilter) bounds() (minCol, minRow, maxCol, maxRow int, err error) {
+	minCol, minRow, err = GetCoordsFromCellIDString(af.TopLeftCell)
+	if err != nil {
+		return
+	}
+	maxCol, maxRow, err = GetCoordsFromCellIDString(af.BottomRightCell)
+	return
+}
+
+// ApplyAutoFilter hides the rows of the AutoFilter range that don't
+// match the criteria of its columns, and shows the ones that do, the
+// first row being the header.  Excel doesn't filter the rows itself
+// when opening a file, so this is what makes a filtered view appear
+// as such.  Dynamic filters based on dates are left to Excel, which
+// applies them when the filter is reapplied.
+func (s *Sheet) ApplyAutoFilter() error {
+	if s.AutoFilter == nil {
+		return errors.New("sheet has no AutoFilter")
+	}
+	minCol, minRow, maxCol, maxRow, err := s.AutoFilter.bounds()
+	if err != nil {
+		return err
+	}
+	if maxRow >= len(s.rowList()) {
+		maxRow = len(s.rowList()) - 1
+	}
+	matchers := make([]func(*Cell) bool, 0, len(s.AutoFilter.Columns))
+	for _, column := range s.AutoFilter.Columns {
+		col := minCol + column.Col
+		if column.Col < 0 || col > maxCol {
+			return fmt.Errorf("filter column %d is outside of the AutoFilter range", column.Col)
+		}
+		var values []*Cell
+		for r := minRow + 1; r <= maxRow; r++ {
+			values = append(values, s.cellIfPresent(r, col))
+		}
+		matcher, err := column.matcher(values)
+		if err != nil {
+			return err
+		}
+		matchers = append(matchers, matcher)
+	}
+	for r := minRow + 1; r <= maxRow; r++ {
+		row := s.row(r)
+		if row == nil {
+			continue
+		}
+		row.copyForSnapshots()
+		row.Hidden = false
+		for i, matcher := range matchers {
+			if !matcher(s.cellIfPresent(r, minCol+s.AutoFilter.Columns[i].Col)) {
+				row.Hidden = true
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// cellIfPresent returns the cell at the given coordinates, or nil if
+// there is none, without growing the Sheet.
+func (s *Sheet) cellIfPresent(row, col int) *Cell {
+	r := s.row(row)
+	if r == nil || col >= len(r.Cells) {
+		return nil
+	}
+	return r.Cells[col]
+}
+
+// cellFilterValue returns the displayed value of a cell and, when it
+// holds a number, that number.
+func cellFilterValue(cell *Cell) (text string, number float64, isNumber bool) {
+	if cell == nil {
+		return "", 0, false
+	}
+	text = cell.String()
+	if cell.Type() == CellTypeNumeric || cell.Type() == CellTypeDate {
+		if f, err := strconv.ParseFloat(cell.Value, 64); err == nil {
+			return text, f, true
+		}
+	}
+	return text, 0, false
+}
+
+// matcher returns a function telling whether a cell of the column
+// matches its criteria, given all the values of the column.
+func (fc *FilterColumn) matcher(column []*Cell) (func(*Cell) bool, error) {
+	switch {
+	case len(fc.Values) > 0 || len(fc.DateGroups) > 0 || fc.Blank:
+		for _, group := range fc.DateGroups {
+			if _, err := group.fields(); err != nil {
+				return nil, err
+			}
+		}
+		return func(cell *Cell) bool {
+			text, _, _ := cellFilterValue(cell)
+			if text == "" {
+				return fc.Blank
+			}
+			for _, value := range fc.Values {
+				if strings.EqualFold(text, value) {
+					return true
+				}
+			}
+			if len(fc.DateGroups) > 0 && cell.IsTime() {
+				t, err := cell.GetTime(cell.date1904)
+				if err != nil {
+					return false
+				}
+				for _, group := range fc.DateGroups {
+					if group.contains(t) {
+						return true
+					}
+				}
+			}
+			return false
+		}, nil
+	case len(fc.CustomFilters) > 0:
+		if len(fc.CustomFilters) > 2 {
+			return nil, errors.New("a filter column can't have more than two custom filters")
+		}
+		for _, cf := range fc.CustomFilters {
+			if _, err := cf.match("", 0, false); err != nil {
+				return nil, err
+			}
+		}
+		return func(cell *Cell) bool {
+			text, number, isNumber := cellFilterValue(cell)
+			for _, cf := range fc.CustomFilters {
+				matched, _ := cf.match(text, number, isNumber)
+				if matched && !fc.And {
+					return true
+				}
+				if !matched && fc.And {
+					return false
+				}
+			}
+			return fc.And
+		}, nil
+	case fc.Top10 != nil:
+		threshold, ok := fc.Top10.threshold(column)
+		return func(cell *Cell) bool {
+			_, number, isNumber := cellFilterValue(cell)
+			if !ok || !isNumber {
+				return false
+			}
+			if fc.Top10.Bottom {
+				return number <= threshold
+			}
+			return number >= threshold
+		}, nil
+	case fc.DynamicFilter == DynamicFilterAboveAverage || fc.DynamicFilter == DynamicFilterBelowAverage:
+		average, ok := columnAverage(column)
+		return func(cell *Cell) bool {
+			_, number, isNumber := cellFilterValue(cell)
+			if !ok || !isNumber {
+				return false
+			}
+			if fc.DynamicFilter == DynamicFilterAboveAverage {
+				return number > average
+			}
+			return number < average
+		}, nil
+	}
+	return func(*Cell) bool { return true }, nil
+}
+
+// fields returns the number of fields of the DateGroup that are
+// used, from the year down to the grouping.
+func (dg DateGroup) fields() (int, error) {
+	for i, grouping := range []string{DateGroupingYear, DateGroupingMonth, DateGroupingDay, DateGroupingHour, DateGroupingMinute, DateGroupingSecond} {
+		if dg.Grouping == grouping {
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown date grouping '%s'", dg.Grouping)
+}
+
+// contains returns true if t falls within the DateGroup.
+func (dg DateGroup) contains(t time.Time) bool {
+	fields, err := dg.fields()
+	if err != nil {
+		return false
+	}
+	want := []int{dg.Year, dg.Month, dg.Day, dg.Hour, dg.Minute, dg.Second}
+	got := []int{t.Year(), int(t.Month()), t.Day(), t.Hour(), t.Minute(), t.Second()}
+	for i := 0; i < fields; i++ {
+		if want[i] != got[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// match compares a value with the CustomFilter.  Numbers are compared
+// as such when both sides are numeric, text is compared ignoring case.
+func (cf CustomFilter) match(text string, number float64, isNumber bool) (bool, error) {
+	operand, err := strconv.ParseFloat(cf.Value, 64)
+	numeric := err == nil && isNumber
+	var cmp int
+	if numeric {
+		switch {
+		case number < operand:
+			cmp = -1
+		case number > operand:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(strings.ToLower(text), strings.ToLower(cf.Value))
+	}
+	switch cf.Operator {
+	case FilterOperatorEqual, "":
+		if numeric {
+			return cmp == 0, nil
+		}
+		return matchFilterPattern(strings.ToLower(cf.Value), strings.ToLower(text)), nil
+	case FilterOperatorNotEqual:
+		if numeric {
+			return cmp != 0, nil
+		}
+		return !matchFilterPattern(strings.ToLower(cf.Value), strings.ToLower(text)), nil
+	case FilterOperatorLessThan:
+		return cmp < 0, nil
+	case FilterOperatorLessThanOrEqual:
+		return cmp <= 0, nil
+	case FilterOperatorGreaterThan:
+		return cmp > 0, nil
+	case FilterOperatorGreaterThanOrEqual:
+		return cmp >= 0, nil
+	}
+	return false, fmt.Errorf("unknown filter operator '%s'", cf.Operator)
+}
+
+// matchFilterPattern reports whether s matches pattern, in which *
+// matches any run of characters, ? matches a single character and ~
+// escapes the character following it.
+func matchFilterPattern(pattern, s string) bool {
+	p, t := []rune(pattern), []rune(s)
+	for len(p) > 0 {
+		switch p[0] {
+		case '*':
+			for i := 0; i <= len(t); i++ {
+				if matchFilterPattern(string(p[1:]), string(t[i:])) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(t) == 0 {
+				return false
+			}
+		case '~':
+			if len(p) > 1 {
+				p = p[1:]
+			}
+			fallthrough
+		default:
+			if len(t) == 0 || t[0] != p[0] {
+				return false
+			}
+		}
+		p, t = p[1:], t[1:]
+	}
+	return len(t) == 0
+}
+
+// threshold returns the lowest value kept by a top filter, or the
+// highest value kept by a bottom filter, among the numbers in column.
+func (tf *Top10Filter) threshold(column []*Cell) (float64, bool) {
+	var numbers []float64
+	for _, cell := range column {
+		if _, number, isNumber := cellFilterValue(cell); isNumber {
+			numbers = append(numbers, number)
+		}
+	}
+	if len(numbers) == 0 {
+		return 0, false
+	}
+	count := int(tf.Value)
+	if tf.Percent {
+		count = int(float64(len(numbers)) * tf.Value / 100)
+	}
+	if count < 1 {
+		count = 1
+	}
+	if count > len(numbers) {
+		count = len(numbers)
+	}
+	if tf.Bottom {
+		sort.Float64s(numbers)
+	} else {
+		sort.Sort(sort.Reverse(sort.Float64Slice(numbers)))
+	}
+	return numbers[count-1], true
+}
+
+// columnAverage returns the average of the numbers in column.
+func columnAverage(column []*Cell) (float64, bool) {
+	var sum float64
+	var count int
+	for _, cell := range column {
+		if _, number, isNumber := cellFilterValue(cell); isNumber {
+			sum += number
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return sum / float64(count), true
+}
+
+// makeXLSXAutoFilter converts an AutoFilter into its XML
+// representation, intended for internal use only
+func (af *AutoFilter) makeXLSXAutoFilter() *xlsxAutoFilter {
+	xAutoFilter := &xlsxAutoFilter{Ref: fmt.Sprintf("%v:%v", af.TopLeftCell, af.BottomRightCell)}
+	for _, column := range af.Columns {
+		xColumn := xlsxFilterColumn{ColId: column.Col}
+		switch {
+		case len(column.Values) > 0 || len(column.DateGroups) > 0 || column.Blank:
+			xColumn.Filters = &xlsxFilters{Blank: column.Blank}
+			for _, value := range column.Values {
+				xColumn.Filters.Filter = append(xColumn.Filters.Filter, xlsxFilter{Val: value})
+			}
+			for _, group := range column.DateGroups {
+				xColumn.Filters.DateGroupItem = append(xColumn.Filters.DateGroupItem, group.makeXLSXDateGroupItem())
+			}
+		case column.Top10 != nil:
+			xColumn.Top10 = &xlsxTop10{
+				Top:     !column.Top10.Bottom,
+				Percent: column.Top10.Percent,
+				Val:     column.Top10.Value,
+			}
+		case len(column.CustomFilters) > 0:
+			xColumn.CustomFilters = &xlsxCustomFilters{And: column.And}
+			for _, cf := range column.CustomFilters {
+				operator := cf.Operator
+				if operator == FilterOperatorEqual {
+					// equal is the default operator.
+					operator = ""
+				}
+				xColumn.CustomFilters.CustomFilter = append(xColumn.CustomFilters.CustomFilter, xlsxCustomFilter{
+					Operator: operator,
+					Val:      cf.Value,
+				})
+			}
+		case column.DynamicFilter != "":
+			xColumn.DynamicFilter = &xlsxDynamicFilter{Type: column.DynamicFilter}
+		}
+		xAutoFilter.FilterColumn = append(xAutoFilter.FilterColumn, xColumn)
+	}
+	if len(af.Sort) > 0 {
+		minCol, minRow, _, maxRow, err := af.bounds()
+		if err == nil {
+			xAutoFilter.SortState = &xlsxSortState{
+				Ref: GetCellIDStringFromCoords(minCol, minRow+1) + cellRangeChar + af.BottomRightCell,
+			}
+			for _, condition := range af.Sort {
+				col := minCol + condition.Col
+				xAutoFilter.SortState.SortCondition = append(xAutoFilter.SortState.SortCondition, xlsxSortCondition{
+					Descending: condition.Descending,
+					Ref:        GetCellIDStringFromCoords(col, minRow+1) + cellRangeChar + GetCellIDStringFromCoords(col, maxRow),
+				})
+			}
+		}
+	}
+	return xAutoFilter
+}
+
+// makeXLSXDateGroupItem converts a DateGroup into its XML
+// representation, leaving out the fields below its grouping.
+func (dg DateGroup) makeXLSXDateGroupItem() xlsxDateGroupItem {
+	fields, _ := dg.fields()
+	item := xlsxDateGroupItem{DateTimeGrouping: dg.Grouping, Year: dg.Year}
+	values := []**int{&item.Month, &item.Day, &item.Hour, &item.Minute, &item.Second}
+	for i, value := range []int{dg.Month, dg.Day, dg.Hour, dg.Minute, dg.Second} {
+		if i+1 < fields {
+			value := value
+			*values[i] = &value
+		}
+	}
+	return item
+}
+
+// dateGroupField returns the value of a field of a dateGroupItem, zero
+// if it is missing.
+func dateGroupField(value *int) int {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+// readAutoFilter converts the XML representation of an autoFilter
+// into an AutoFilter.
+func readAutoFilter(xAutoFilter *xlsxAutoFilter) *AutoFilter {
+	if xAutoFilter == nil {
+		return nil
+	}
+	af := &AutoFilter{}
+	cells := strings.Split(xAutoFilter.Ref, cellRangeChar)
+	af.TopLeftCell = cells[0]
+	af.BottomRightCell = cells[len(cells)-1]
+	for _, xColumn := range xAutoFilter.FilterColumn {
+		column := &FilterColumn{Col: xColumn.ColId}
+		if xColumn.Filters != nil {
+			column.Blank = xColumn.Filters.Blank
+			for _, filter := range xColumn.Filters.Filter {
+				column.Values = append(column.Values, filter.Val)
+			}
+			for _, item := range xColumn.Filters.DateGroupItem {
+				column.DateGroups = append(column.DateGroups, DateGroup{
+					Grouping: item.DateTimeGrouping,
+					Year:     item.Year,
+					Month:    dateGroupField(item.Month),
+					Day:      dateGroupField(item.Day),
+					Hour:     dateGroupField(item.Hour),
+					Minute:   dateGroupField(item.Minute),
+					Second:   dateGroupField(item.Second),
+				})
+			}
+		}
+		if xColumn.Top10 != nil {
+			column.Top10 = &Top10Filter{
+				Bottom:  !xColumn.Top10.Top,
+				Percent: xColumn.Top10.Percent,
+				Value:   xColumn.Top10.Val,
+			}
+		}
+		if xColumn.CustomFilters != nil {
+			column.And = xColumn.CustomFilters.And
+			for _, xcf := range xColumn.CustomFilters.CustomFilter {
+				operator := xcf.Operator
+				if operator == "" {
+					operator = FilterOperatorEqual
+				}
+				column.CustomFilters = append(column.CustomFilters, CustomFilter{Operator: operator, Value: xcf.Val})
+			}
+		}
+		if xColumn.DynamicFilter != nil {
+			column.DynamicFilter = xColumn.DynamicFilter.Type
+		}
+		af.Columns = append(af.Columns, column)
+	}
+	if xAutoFilter.SortState != nil {
+		minCol, _, err := GetCoordsFromCellIDString(af.TopLeftCell)
+		if err == nil {
+			for _, xCondition := range xAutoFilter.SortState.SortCondition {
+				col, _, err := GetCoordsFromCellIDString(strings.Split(xCondition.Ref, cellRangeChar)[0])
+				if err != nil {
+					continue
+				}
+				af.Sort = append(af.Sort, &SortCondition{Col: col - minCol, Descending: xCondition.Descending})
+			}
+		}
+	}
+	return af
+}
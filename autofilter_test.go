@@ -0,0 +1,164 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type AutoFilterSuite struct{}
+
+var _ = Suite(&AutoFilterSuite{})
+
+// makeTicketSheet creates a sheet holding a header and four tickets.
+func makeTicketSheet(c *C) (*File, *Sheet) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Tickets")
+	c.Assert(err, IsNil)
+	header := sheet.AddRow()
+	header.AddCell().SetString("Status")
+	header.AddCell().SetString("Hours")
+	for _, ticket := range []struct {
+		status string
+		hours  int
+	}{{"Open", 3}, {"Closed", 8}, {"open", 1}, {"Pending", 12}} {
+		row := sheet.AddRow()
+		row.AddCell().SetString(ticket.status)
+		row.AddCell().SetInt(ticket.hours)
+	}
+	sheet.AutoFilter = &AutoFilter{TopLeftCell: "A1", BottomRightCell: "B5"}
+	return file, sheet
+}
+
+func hiddenRows(sheet *Sheet) []bool {
+	var hidden []bool
+	for _, row := range sheet.Rows[1:] {
+		hidden = append(hidden, row.Hidden)
+	}
+	return hidden
+}
+
+func (s *AutoFilterSuite) TestApplyValuesFilter(c *C) {
+	_, sheet := makeTicketSheet(c)
+	sheet.AutoFilter.AddFilterColumn(&FilterColumn{Col: 0, Values: []string{"Open"}})
+	c.Assert(sheet.ApplyAutoFilter(), IsNil)
+	c.Assert(hiddenRows(sheet), DeepEquals, []bool{false, true, false, true})
+	c.Assert(sheet.Rows[0].Hidden, Equals, false)
+}
+
+func (s *AutoFilterSuite) TestApplyCustomFilters(c *C) {
+	_, sheet := makeTicketSheet(c)
+	sheet.AutoFilter.AddFilterColumn(&FilterColumn{Col: 1, And: true, CustomFilters: []CustomFilter{
+		{Operator: FilterOperatorGreaterThan, Value: "2"},
+		{Operator: FilterOperatorLessThanOrEqual, Value: "8"},
+	}})
+	c.Assert(sheet.ApplyAutoFilter(), IsNil)
+	c.Assert(hiddenRows(sheet), DeepEquals, []bool{false, false, true, true})
+
+	sheet.AutoFilter.Columns = []*FilterColumn{{Col: 0, CustomFilters: []CustomFilter{
+		{Operator: FilterOperatorEqual, Value: "*en*"},
+	}}}
+	c.Assert(sheet.ApplyAutoFilter(), IsNil)
+	c.Assert(hiddenRows(sheet), DeepEquals, []bool{false, true, false, false})
+
+	sheet.AutoFilter.Columns[0].CustomFilters[0].Operator = "like"
+	c.Assert(sheet.ApplyAutoFilter(), ErrorMatches, "unknown filter operator 'like'")
+}
+
+func (s *AutoFilterSuite) TestApplyTop10AndAverageFilters(c *C) {
+	_, sheet := makeTicketSheet(c)
+	sheet.AutoFilter.AddFilterColumn(&FilterColumn{Col: 1, Top10: &Top10Filter{Value: 2}})
+	c.Assert(sheet.ApplyAutoFilter(), IsNil)
+	c.Assert(hiddenRows(sheet), DeepEquals, []bool{true, false, true, false})
+
+	sheet.AutoFilter.Columns[0] = &FilterColumn{Col: 1, DynamicFilter: DynamicFilterBelowAverage}
+	c.Assert(sheet.ApplyAutoFilter(), IsNil)
+	c.Assert(hiddenRows(sheet), DeepEquals, []bool{false, true, false, true})
+}
+
+func (s *AutoFilterSuite) TestMatchFilterPattern(c *C) {
+	c.Assert(matchFilterPattern("a*c", "abbc"), Equals, true)
+	c.Assert(matchFilterPattern("a?c", "abc"), Equals, true)
+	c.Assert(matchFilterPattern("a?c", "abbc"), Equals, false)
+	c.Assert(matchFilterPattern("a~*", "a*"), Equals, true)
+	c.Assert(matchFilterPattern("a~*", "ab"), Equals, false)
+}
+
+func (s *AutoFilterSuite) TestAutoFilterCriteriaAreWritten(c *C) {
+	_, sheet := makeTicketSheet(c)
+	sheet.AutoFilter.AddFilterColumn(&FilterColumn{Col: 0, Values: []string{"Open"}})
+	sheet.AutoFilter.AddFilterColumn(&FilterColumn{Col: 1, CustomFilters: []CustomFilter{
+		{Operator: FilterOperatorGreaterThan, Value: "2"},
+	}})
+	sheet.AutoFilter.Sort = []*SortCondition{{Col: 1, Descending: true}}
+
+	worksheet := sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+	c.Assert(worksheet.SheetPr.FilterMode, Equals, true)
+	output, err := xml.Marshal(worksheet.AutoFilter)
+	c.Assert(err, IsNil)
+	c.Assert(string(output), Equals, `<xlsxAutoFilter ref="A1:B5">`+
+		`<filterColumn colId="0"><filters><filter val="Open"></filter></filters></filterColumn>`+
+		`<filterColumn colId="1"><customFilters><customFilter operator="greaterThan" val="2"></customFilter></customFilters></filterColumn>`+
+		`<sortState ref="A2:B5"><sortCondition descending="true" ref="B2:B5"></sortCondition></sortState>`+
+		`</xlsxAutoFilter>`)
+}
+
+func (s *AutoFilterSuite) TestAutoFilterRoundTrip(c *C) {
+	file, sheet := makeTicketSheet(c)
+	sheet.AutoFilter.AddFilterColumn(&FilterColumn{Col: 0, Values: []string{"Open"}, Blank: true})
+	sheet.AutoFilter.AddFilterColumn(&FilterColumn{Col: 1, Top10: &Top10Filter{Bottom: true, Percent: true, Value: 25}})
+	sheet.AutoFilter.Sort = []*SortCondition{{Col: 1}}
+
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	reread, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(reread.Sheets[0].AutoFilter, DeepEquals, sheet.AutoFilter)
+}
+
+func (s *AutoFilterSuite) TestApplyDateGroupFilter(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Dates")
+	c.Assert(err, IsNil)
+	sheet.AddRow().AddCell().SetString("Due")
+	for _, day := range []time.Time{
+		time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, time.March, 4, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC),
+	} {
+		sheet.AddRow().AddCell().SetDate(day)
+	}
+	sheet.AutoFilter = &AutoFilter{TopLeftCell: "A1", BottomRightCell: "A5"}
+	sheet.AutoFilter.AddFilterColumn(&FilterColumn{DateGroups: []DateGroup{
+		{Grouping: DateGroupingMonth, Year: 2024, Month: 3},
+	}})
+	c.Assert(sheet.ApplyAutoFilter(), IsNil)
+	c.Assert(hiddenRows(sheet), DeepEquals, []bool{false, true, true, false})
+
+	sheet.AutoFilter.Columns[0].DateGroups[0].Grouping = "week"
+	c.Assert(sheet.ApplyAutoFilter(), ErrorMatches, "unknown date grouping 'week'")
+}
+
+func (s *AutoFilterSuite) TestDateGroupItemsAreWritten(c *C) {
+	af := &AutoFilter{TopLeftCell: "A1", BottomRightCell: "A5"}
+	af.AddFilterColumn(&FilterColumn{DateGroups: []DateGroup{
+		{Grouping: DateGroupingMonth, Year: 2024, Month: 3, Day: 12},
+		{Grouping: DateGroupingYear, Year: 2023},
+		{Grouping: DateGroupingSecond, Year: 2024, Month: 1, Day: 1},
+	}})
+	output, err := xml.Marshal(af.makeXLSXAutoFilter())
+	c.Assert(err, IsNil)
+	c.Assert(string(output), Equals, `<xlsxAutoFilter ref="A1:A5"><filterColumn colId="0"><filters>`+
+		`<dateGroupItem year="2024" month="3" dateTimeGrouping="month"></dateGroupItem>`+
+		`<dateGroupItem year="2023" dateTimeGrouping="year"></dateGroupItem>`+
+		`<dateGroupItem year="2024" month="1" day="1" hour="0" minute="0" second="0" dateTimeGrouping="second"></dateGroupItem>`+
+		`</filters></filterColumn></xlsxAutoFilter>`)
+	c.Assert(readAutoFilter(af.makeXLSXAutoFilter()).Columns[0].DateGroups, DeepEquals, []DateGroup{
+		{Grouping: DateGroupingMonth, Year: 2024, Month: 3},
+		{Grouping: DateGroupingYear, Year: 2023},
+		{Grouping: DateGroupingSecond, Year: 2024, Month: 1, Day: 1},
+	})
+}
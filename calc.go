@@ -0,0 +1,98 @@
+package xlsx
+
+// CalcMode is the way the formulas of a workbook are recalculated.
+type CalcMode string
+
+const (
+	// CalcModeAuto recalculates formulas whenever the cells they
+	// depend on change, which is the default.
+	CalcModeAuto CalcMode = "auto"
+	// CalcModeAutoNoTable recalculates automatically, except for
+	// data tables.
+	CalcModeAutoNoTable CalcMode = "autoNoTable"
+	// CalcModeManual only recalculates formulas when asked to, so
+	// that large models open without waiting for a recalculation.
+	CalcModeManual CalcMode = "manual"
+)
+
+// Default values of the iterative calculation settings.
+const (
+	defaultIterateCount = 100
+	defaultIterateDelta = 0.001
+)
+
+// CalcProperties holds the calculation settings of a workbook, written
+// to its calcPr element.  The zero value stands for the defaults of
+// Excel.
+type CalcProperties struct {
+	// Mode is the calculation mode, CalcModeAuto if empty.
+	Mode CalcMode
+	// FullCalcOnLoad makes the application recalculate every formula
+	// when the workbook is opened.
+	FullCalcOnLoad bool
+	// SkipCalcOnSave keeps the application from recalculating a
+	// workbook in manual mode before saving it.
+	SkipCalcOnSave bool
+	// Iterate allows circular references, calculated by iteration
+	// up to IterateCount times, 100 if 0, or until values change by
+	// less than IterateDelta, 0.001 if 0.
+	Iterate      bool
+	IterateCount int
+	IterateDelta float64
+	// PrecisionAsDisplayed rounds the values of cells to the
+	// precision of their number format in calculations.
+	PrecisionAsDisplayed bool
+	// CalcId is the version of the calculation engine that last
+	// calculated the workbook, read from a file.  It isn't written,
+	// so that the application opening a saved workbook recalculates
+	// its formulas, whose cached values may be stale once the cells
+	// they depend on are changed.
+	CalcId string
+}
+
+// makeXLSXCalcPr converts the CalcProperties into their XML
+// representation, intended for internal use only.
+func (cp CalcProperties) makeXLSXCalcPr() xlsxCalcPr {
+	xCalcPr := xlsxCalcPr{
+		IterateCount:   cp.IterateCount,
+		RefMode:        "A1",
+		Iterate:        cp.Iterate,
+		IterateDelta:   cp.IterateDelta,
+		FullCalcOnLoad: cp.FullCalcOnLoad,
+	}
+	if xCalcPr.IterateCount == 0 {
+		xCalcPr.IterateCount = defaultIterateCount
+	}
+	if xCalcPr.IterateDelta == 0 {
+		xCalcPr.IterateDelta = defaultIterateDelta
+	}
+	if cp.Mode != CalcModeAuto {
+		xCalcPr.CalcMode = string(cp.Mode)
+	}
+	if cp.SkipCalcOnSave {
+		xCalcPr.CalcOnSave = new(bool)
+	}
+	if cp.PrecisionAsDisplayed {
+		xCalcPr.FullPrecision = new(bool)
+	}
+	return xCalcPr
+}
+
+// readCalcProperties converts the XML representation of the
+// calculation settings of a workbook into CalcProperties.
+func readCalcProperties(xCalcPr xlsxCalcPr) CalcProperties {
+	cp := CalcProperties{
+		Mode:                 CalcMode(xCalcPr.CalcMode),
+		FullCalcOnLoad:       xCalcPr.FullCalcOnLoad,
+		SkipCalcOnSave:       xCalcPr.CalcOnSave != nil && !*xCalcPr.CalcOnSave,
+		Iterate:              xCalcPr.Iterate,
+		IterateCount:         xCalcPr.IterateCount,
+		IterateDelta:         xCalcPr.IterateDelta,
+		PrecisionAsDisplayed: xCalcPr.FullPrecision != nil && !*xCalcPr.FullPrecision,
+		CalcId:               xCalcPr.CalcId,
+	}
+	if cp.Mode == CalcModeAuto {
+		cp.Mode = ""
+	}
+	return cp
+}
@@ -0,0 +1,45 @@
+package xlsx
+
+import (
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type CalcSuite struct{}
+
+var _ = Suite(&CalcSuite{})
+
+func (s *CalcSuite) TestDefaultCalcPr(c *C) {
+	xCalcPr := CalcProperties{}.makeXLSXCalcPr()
+	c.Assert(xCalcPr, DeepEquals, xlsxCalcPr{IterateCount: 100, RefMode: "A1", IterateDelta: 0.001})
+	c.Assert(readCalcProperties(xlsxCalcPr{CalcMode: "auto", CalcId: "191029"}), DeepEquals, CalcProperties{CalcId: "191029"})
+	// The engine that calculated a workbook read isn't written, so
+	// that its formulas are recalculated.
+	c.Assert(CalcProperties{CalcId: "191029"}.makeXLSXCalcPr(), DeepEquals, xCalcPr)
+}
+
+func (s *CalcSuite) TestCalcPropertiesRoundTrip(c *C) {
+	f := NewFile()
+	_, err := f.AddSheet("Model")
+	c.Assert(err, IsNil)
+	f.CalcProperties = CalcProperties{
+		Mode:                 CalcModeManual,
+		SkipCalcOnSave:       true,
+		Iterate:              true,
+		IterateCount:         50,
+		IterateDelta:         0.0001,
+		PrecisionAsDisplayed: true,
+	}
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/workbook.xml"],
+		`<calcPr iterateCount="50" refMode="A1" iterate="true" iterateDelta="0.0001" calcMode="manual" calcOnSave="false" fullPrecision="false"></calcPr>`), Equals, true)
+
+	var buffer bytes.Buffer
+	c.Assert(f.Write(&buffer), IsNil)
+	read, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(read.CalcProperties, DeepEquals, f.CalcProperties)
+}
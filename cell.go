@@ -54,6 +54,25 @@ type Cell struct {
 	VMerge         int
 	cellType       CellType
 	DataValidation *xlsxCellDataValidation
+	// inherited is the style of the row or the column of a cell read
+	// from a file without a style of its own, see EffectiveStyle.
+	inherited *inheritedStyle
+	// formulaType and formulaRef hold the type of an array formula
+	// and the range it applies to, cellMetadata and valueMetadata
+	// the indexes into the metadata part that mark, for example,
+	// dynamic array formulas.
+	formulaType   string
+	formulaRef    string
+	cellMetadata  int
+	valueMetadata int
+	// sharedStringIndex is one more than the index of the value in
+	// the shared strings of the File, or zero.
+	sharedStringIndex int
+	// evictedCol is one more than the column of a cell whose row
+	// was evicted to a cell store, after evictedLoads loads of the
+	// row, see Cell.attach.
+	evictedCol   int
+	evictedLoads int
 }
 
 // CellInterface defines the public API of the Cell.
@@ -69,19 +88,23 @@ func NewCell(r *Row) *Cell {
 
 // Merge with other cells, horizontally and/or vertically.
 func (c *Cell) Merge(hcells, vcells int) {
+	c.changing()
 	c.HMerge = hcells
 	c.VMerge = vcells
 }
 
 // Type returns the CellType of a cell. See CellType constants for more details.
 func (c *Cell) Type() CellType {
+	c.attach()
 	return c.cellType
 }
 
 // SetString sets the value of a cell to a string.
 func (c *Cell) SetString(s string) {
+	c.changing()
 	c.Value = s
 	c.formula = ""
+	c.clearArrayFormula()
 	c.cellType = CellTypeString
 }
 
@@ -89,6 +112,7 @@ func (c *Cell) SetString(s string) {
 // see errors returned from formatting then please use
 // Cell.FormattedValue() instead.
 func (c *Cell) String() string {
+	c.attach()
 	// To preserve the String() interface we'll throw away errors.
 	// Not that using FormattedValue is therefore strongly
 	// preferred.
@@ -98,17 +122,19 @@ func (c *Cell) String() string {
 
 // SetFloat sets the value of a cell to a float.
 func (c *Cell) SetFloat(n float64) {
+	c.changing()
 	c.SetValue(n)
 }
 
 // IsTime returns true if the cell stores a time value.
 func (c *Cell) IsTime() bool {
-	c.getNumberFormat()
-	return c.parsedNumFmt.isTimeFormat
+	c.attach()
+	return c.getNumberFormat().isTimeFormat
 }
 
 //GetTime returns the value of a Cell as a time.Time
 func (c *Cell) GetTime(date1904 bool) (t time.Time, err error) {
+	c.attach()
 	f, err := c.Float()
 	if err != nil {
 		return t, err
@@ -131,6 +157,7 @@ func (c *Cell) GetTime(date1904 bool) (t time.Time, err error) {
 // SetFloatWithFormat sets the value of a cell to a float and applies
 // formatting to the cell.
 func (c *Cell) SetFloatWithFormat(n float64, format string) {
+	c.changing()
 	c.SetValue(n)
 	c.NumFmt = format
 	c.formula = ""
@@ -138,6 +165,7 @@ func (c *Cell) SetFloatWithFormat(n float64, format string) {
 
 // SetCellFormat set cell value  format
 func (c *Cell) SetFormat(format string) {
+	c.changing()
 	c.NumFmt = format
 }
 
@@ -166,30 +194,36 @@ var (
 
 // SetDate sets the value of a cell to a float.
 func (c *Cell) SetDate(t time.Time) {
+	c.changing()
 	c.SetDateWithOptions(t, DefaultDateOptions)
 }
 
 func (c *Cell) SetDateTime(t time.Time) {
+	c.changing()
 	c.SetDateWithOptions(t, DefaultDateTimeOptions)
 }
 
 // SetDateWithOptions allows for more granular control when exporting dates and times
 func (c *Cell) SetDateWithOptions(t time.Time, options DateTimeOptions) {
+	c.changing()
 	_, offset := t.In(options.Location).Zone()
 	t = time.Unix(t.Unix()+int64(offset), 0)
 	c.SetDateTimeWithFormat(TimeToExcelTime(t.In(timeLocationUTC), c.date1904), options.ExcelTimeFormat)
 }
 
 func (c *Cell) SetDateTimeWithFormat(n float64, format string) {
+	c.changing()
 	c.Value = strconv.FormatFloat(n, 'f', -1, 64)
 	c.NumFmt = format
 	c.formula = ""
+	c.clearArrayFormula()
 	c.cellType = CellTypeNumeric
 }
 
 // Float returns the value of cell as a number.
 func (c *Cell) Float() (float64, error) {
-	f, err := strconv.ParseFloat(c.Value, 64)
+	c.attach()
+	f, err := parseFloat(c.Value)
 	if err != nil {
 		return math.NaN(), err
 	}
@@ -198,11 +232,13 @@ func (c *Cell) Float() (float64, error) {
 
 // SetInt64 sets a cell's value to a 64-bit integer.
 func (c *Cell) SetInt64(n int64) {
+	c.changing()
 	c.SetValue(n)
 }
 
 // Int64 returns the value of cell as 64-bit integer.
 func (c *Cell) Int64() (int64, error) {
+	c.attach()
 	f, err := strconv.ParseInt(c.Value, 10, 64)
 	if err != nil {
 		return -1, err
@@ -214,6 +250,7 @@ func (c *Cell) Int64() (int64, error) {
 // to display values when the storage type is Number and the format type is General. It is not 100% identical to the
 // spec but is as close as you can get using the built in Go formatting tools.
 func (c *Cell) GeneralNumeric() (string, error) {
+	c.attach()
 	return generalNumericScientific(c.Value, true)
 }
 
@@ -221,16 +258,26 @@ func (c *Cell) GeneralNumeric() (string, error) {
 // the rules for when XLSX should switch to scientific notation, since sometimes scientific notation is not desired,
 // even if that is how the document is supposed to be formatted.
 func (c *Cell) GeneralNumericWithoutScientific() (string, error) {
+	c.attach()
 	return generalNumericScientific(c.Value, false)
 }
 
 // SetInt sets a cell's value to an integer.
 func (c *Cell) SetInt(n int) {
+	c.changing()
 	c.SetValue(n)
 }
 
-// SetInt sets a cell's value to an integer.
+// SetValue sets a cell's value to n, as a number, a date or a string
+// depending on its type.  The values of the types given a CellEncoder,
+// see RegisterCellEncoder, are encoded by it, or set as strings if it
+// fails.
 func (c *Cell) SetValue(n interface{}) {
+	c.changing()
+	if encoded, ok, err := encodeCellValue(n); ok && err == nil {
+		c.setEncoded(encoded)
+		return
+	}
 	switch t := n.(type) {
 	case time.Time:
 		c.SetDateTime(t)
@@ -259,9 +306,11 @@ func (c *Cell) SetValue(n interface{}) {
 
 // setNumeric sets a cell's value to a number
 func (c *Cell) setNumeric(s string) {
+	c.changing()
 	c.Value = s
 	c.NumFmt = builtInNumFmt[builtInNumFmtIndex_GENERAL]
 	c.formula = ""
+	c.clearArrayFormula()
 	c.cellType = CellTypeNumeric
 }
 
@@ -269,7 +318,8 @@ func (c *Cell) setNumeric(s string) {
 // Has max 53 bits of precision
 // See: float64(int64(math.MaxInt))
 func (c *Cell) Int() (int, error) {
-	f, err := strconv.ParseFloat(c.Value, 64)
+	c.attach()
+	f, err := parseFloat(c.Value)
 	if err != nil {
 		return -1, err
 	}
@@ -278,11 +328,13 @@ func (c *Cell) Int() (int, error) {
 
 // SetBool sets a cell's value to a boolean.
 func (c *Cell) SetBool(b bool) {
+	c.changing()
 	if b {
 		c.Value = "1"
 	} else {
 		c.Value = "0"
 	}
+	c.valueMetadata = 0
 	c.cellType = CellTypeBool
 }
 
@@ -290,6 +342,7 @@ func (c *Cell) SetBool(b bool) {
 // TODO: Determine if the current return value is
 // appropriate for types other than CellTypeBool.
 func (c *Cell) Bool() bool {
+	c.attach()
 	// If bool, just return the value.
 	if c.cellType == CellTypeBool {
 		return c.Value == "1"
@@ -302,24 +355,87 @@ func (c *Cell) Bool() bool {
 	return c.Value != ""
 }
 
+// The error values of cells, as defined by ECMA-376, which Excel shows
+// in cells whose formula can't be computed.
+const (
+	ErrorValueNull        = "#NULL!"
+	ErrorValueDivZero     = "#DIV/0!"
+	ErrorValueValue       = "#VALUE!"
+	ErrorValueRef         = "#REF!"
+	ErrorValueName        = "#NAME?"
+	ErrorValueNum         = "#NUM!"
+	ErrorValueNA          = "#N/A"
+	ErrorValueGettingData = "#GETTING_DATA"
+)
+
+var errorValues = []string{
+	ErrorValueNull,
+	ErrorValueDivZero,
+	ErrorValueValue,
+	ErrorValueRef,
+	ErrorValueName,
+	ErrorValueNum,
+	ErrorValueNA,
+	ErrorValueGettingData,
+}
+
+// SetError sets a cell's value to one of the error values, such as
+// ErrorValueNA, making it a CellTypeError.  The formula of the cell,
+// if any, is kept, so that a cell can hold a formula such as NA()
+// along with its result.
+func (c *Cell) SetError(value string) error {
+	c.changing()
+	for _, errorValue := range errorValues {
+		if value == errorValue {
+			c.Value = value
+			c.valueMetadata = 0
+			c.cellType = CellTypeError
+			return nil
+		}
+	}
+	return fmt.Errorf("'%s' is not an error value", value)
+}
+
 // SetFormula sets the format string for a cell.
 func (c *Cell) SetFormula(formula string) {
+	c.changing()
 	c.formula = formula
 	c.cellType = CellTypeNumeric
+	c.clearArrayFormula()
 }
 
 func (c *Cell) SetStringFormula(formula string) {
+	c.changing()
 	c.formula = formula
 	c.cellType = CellTypeStringFormula
+	c.clearArrayFormula()
+}
+
+// SharedStringIndex returns the index of the value of the cell in the
+// shared strings of its File, see File.SharedStrings, if the value was
+// read from there and hasn't changed since.
+func (c *Cell) SharedStringIndex() (int, bool) {
+	c.attach()
+	if c.sharedStringIndex == 0 || c.Row == nil || c.Row.Sheet == nil || c.Row.Sheet.File == nil {
+		return 0, false
+	}
+	table := c.Row.Sheet.File.referenceTable
+	index := c.sharedStringIndex - 1
+	if table == nil || index >= table.Length() || table.ResolveSharedString(index) != c.Value {
+		return 0, false
+	}
+	return index, true
 }
 
 // Formula returns the formula string for the cell.
 func (c *Cell) Formula() string {
+	c.attach()
 	return c.formula
 }
 
 // GetStyle returns the Style associated with a Cell
 func (c *Cell) GetStyle() *Style {
+	c.changing()
 	if c.style == nil {
 		c.style = NewStyle()
 	}
@@ -328,16 +444,49 @@ func (c *Cell) GetStyle() *Style {
 
 // SetStyle sets the style of a cell.
 func (c *Cell) SetStyle(style *Style) {
+	c.changing()
 	c.style = style
+	c.inherited = nil
+}
+
+// inheritedStyle is the style and the number format a row or a column
+// read from a file gives the cells without a style of their own.
+type inheritedStyle struct {
+	style  *Style
+	numFmt string
+}
+
+// EffectiveStyle returns the style a cell is shown with. A cell read from a file without a style of its own, which
+// GetStyle gives the default style, is shown with the style of its row, if the row has one, or else of its column,
+// like spreadsheet applications show it. The style of any other cell, and of a cell once given a style with
+// SetStyle, is its own.
+func (c *Cell) EffectiveStyle() *Style {
+	c.changing()
+	if c.inherited != nil {
+		return c.inherited.style
+	}
+	return c.GetStyle()
+}
+
+// EffectiveNumberFormat returns the number format a cell is shown with, which, like its style, see EffectiveStyle,
+// may be that of its row or its column, such as the date format of a column of dates, unless the cell was given a
+// number format of its own.
+func (c *Cell) EffectiveNumberFormat() string {
+	c.attach()
+	if c.inherited != nil && (c.NumFmt == "" || c.NumFmt == builtInNumFmt[builtInNumFmtIndex_GENERAL]) {
+		return c.inherited.numFmt
+	}
+	return c.NumFmt
 }
 
 // GetNumberFormat returns the number format string for a cell.
 func (c *Cell) GetNumberFormat() string {
+	c.attach()
 	return c.NumFmt
 }
 
 func (c *Cell) formatToFloat(format string) (string, error) {
-	f, err := strconv.ParseFloat(c.Value, 64)
+	f, err := parseFloat(c.Value)
 	if err != nil {
 		return c.Value, err
 	}
@@ -345,20 +494,48 @@ func (c *Cell) formatToFloat(format string) (string, error) {
 }
 
 func (c *Cell) formatToInt(format string) (string, error) {
-	f, err := strconv.ParseFloat(c.Value, 64)
+	f, err := parseFloat(c.Value)
 	if err != nil {
 		return c.Value, err
 	}
 	return fmt.Sprintf(format, int(f)), nil
 }
 
-// getNumberFormat will update the parsedNumFmt struct if it has become out of date, since a cell's NumFmt string is a
-// public field that could be edited by clients.
+// parsedNumberFormat returns the parsed number format numFmt of cells
+// of the File, parsing it the first time it is asked for.
+func (f *File) parsedNumberFormat(numFmt string) *parsedNumberFormat {
+	f.numberFormatsLock.RLock()
+	parsed, ok := f.numberFormats[numFmt]
+	f.numberFormatsLock.RUnlock()
+	if ok {
+		return parsed
+	}
+	parsed = parseFullNumberFormatString(numFmt)
+	f.numberFormatsLock.Lock()
+	defer f.numberFormatsLock.Unlock()
+	if cached, ok := f.numberFormats[numFmt]; ok {
+		return cached
+	}
+	if f.numberFormats == nil {
+		f.numberFormats = make(map[string]*parsedNumberFormat)
+	}
+	f.numberFormats[numFmt] = parsed
+	return parsed
+}
+
+// getNumberFormat returns the parsed number format of the cell, that of the cells read from a file being parsed when
+// they are read. The parsedNumFmt struct is only used if it is up to date, since a cell's NumFmt string is a public
+// field that could be edited by clients. It isn't updated otherwise, so that reading a cell doesn't change it, see
+// File.RLock, the formats of the other cells being parsed once for their File and shared, or parsed each time for
+// the cells outside of a File.
 func (c *Cell) getNumberFormat() *parsedNumberFormat {
-	if c.parsedNumFmt == nil || c.parsedNumFmt.numFmt != c.NumFmt {
-		c.parsedNumFmt = parseFullNumberFormatString(c.NumFmt)
+	if c.parsedNumFmt != nil && c.parsedNumFmt.numFmt == c.NumFmt {
+		return c.parsedNumFmt
+	}
+	if c.Row == nil || c.Row.Sheet == nil || c.Row.Sheet.File == nil {
+		return parseFullNumberFormatString(c.NumFmt)
 	}
-	return c.parsedNumFmt
+	return c.Row.Sheet.File.parsedNumberFormat(c.NumFmt)
 }
 
 // FormattedValue returns a value, and possibly an error condition
@@ -366,6 +543,7 @@ func (c *Cell) getNumberFormat() *parsedNumberFormat {
 // value, it will do so, if not then an error will be returned, along
 // with the raw value of the Cell.
 func (c *Cell) FormattedValue() (string, error) {
+	c.attach()
 	fullFormat := c.getNumberFormat()
 	returnVal, err := fullFormat.FormatValue(c)
 	if fullFormat.parseEncounteredError != nil {
@@ -376,5 +554,6 @@ func (c *Cell) FormattedValue() (string, error) {
 
 // SetDataValidation set data validation
 func (c *Cell) SetDataValidation(dd *xlsxCellDataValidation) {
+	c.changing()
 	c.DataValidation = dd
 }
@@ -0,0 +1,145 @@
+package xlsx
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// CellEncoder encodes the values of a Go type of an application, such as
+// a Money, a UUID or an enum type, as the values of cells, so that they
+// are written the same way by Cell.SetValue and by the typed writers of
+// StreamFile, see RegisterCellEncoder.
+type CellEncoder interface {
+	EncodeCell(v interface{}) (EncodedCell, error)
+}
+
+// CellEncoderFunc is a function used as a CellEncoder.
+type CellEncoderFunc func(v interface{}) (EncodedCell, error)
+
+// EncodeCell returns f(v).
+func (f CellEncoderFunc) EncodeCell(v interface{}) (EncodedCell, error) {
+	return f(v)
+}
+
+// EncodedCell is a value encoded by a CellEncoder.  Value is the value of
+// a cell of Type, as for a StreamCell: the text of a CellTypeString
+// cell, the number of a CellTypeNumeric cell, "1" or "0" for a
+// CellTypeBool cell, and the date serial number of a CellTypeDate cell,
+// see TimeToExcelTime.  NumFmt is a hint of the style of the cell, the
+// number format it is shown with, such as `#,##0.00 [$€-1]`, or "" for
+// the default of its type.
+type EncodedCell struct {
+	Type   CellType
+	Value  string
+	NumFmt string
+}
+
+var (
+	cellEncodersMu sync.RWMutex
+	cellEncoders   = map[reflect.Type]CellEncoder{}
+)
+
+// RegisterCellEncoder registers encoder for the values of the type of v,
+// such as Money{}, which Cell.SetValue and the struct fields written by
+// StreamFile.WriteStruct then encode with it, as do the pointers to
+// such values.  A nil encoder removes the one registered for the type.
+// The encoders are shared by all the files, and should be registered
+// once, before any is written, such as in an init function.
+func RegisterCellEncoder(v interface{}, encoder CellEncoder) error {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return fmt.Errorf("no type to register a cell encoder for")
+	}
+	cellEncodersMu.Lock()
+	defer cellEncodersMu.Unlock()
+	if encoder == nil {
+		delete(cellEncoders, t)
+		return nil
+	}
+	cellEncoders[t] = encoder
+	return nil
+}
+
+// cellEncoderFor returns the CellEncoder registered for the type t, or
+// else the one registered for the type t points to, with elem true, or
+// nil if there is none.
+func cellEncoderFor(t reflect.Type) (encoder CellEncoder, elem bool) {
+	cellEncodersMu.RLock()
+	defer cellEncodersMu.RUnlock()
+	if len(cellEncoders) == 0 || t == nil {
+		return nil, false
+	}
+	if encoder, ok := cellEncoders[t]; ok {
+		return encoder, false
+	}
+	if t.Kind() == reflect.Ptr {
+		return cellEncoders[t.Elem()], true
+	}
+	return nil, false
+}
+
+// encodeCellValue encodes v with the CellEncoder registered for its
+// type, or for the type it points to.  ok is false if there is none, or
+// if v is a nil pointer to a value of such a type.
+func encodeCellValue(v interface{}) (encoded EncodedCell, ok bool, err error) {
+	encoder, elem := cellEncoderFor(reflect.TypeOf(v))
+	if encoder == nil {
+		return EncodedCell{}, false, nil
+	}
+	if elem {
+		value := reflect.ValueOf(v)
+		if value.IsNil() {
+			return EncodedCell{}, false, nil
+		}
+		v = value.Elem().Interface()
+	}
+	if encoded, err = encoder.EncodeCell(v); err == nil {
+		err = checkStreamCellValue(encoded.Value, encoded.Type)
+	}
+	if err != nil {
+		return EncodedCell{}, true, fmt.Errorf("the %T value can't be encoded: %v", v, err)
+	}
+	return encoded, true, nil
+}
+
+// setEncoded sets the value of the cell to the encoded one.
+func (c *Cell) setEncoded(encoded EncodedCell) {
+	switch encoded.Type {
+	case CellTypeNumeric:
+		c.setNumeric(encoded.Value)
+	case CellTypeDate:
+		serial, _ := strconv.ParseFloat(encoded.Value, 64)
+		c.SetDateTimeWithFormat(serial, DefaultDateTimeOptions.ExcelTimeFormat)
+	case CellTypeBool:
+		c.SetBool(encoded.Value == "1")
+	default:
+		c.SetString(encoded.Value)
+	}
+	if encoded.NumFmt != "" {
+		c.NumFmt = encoded.NumFmt
+	}
+}
+
+// streamCell returns the StreamCell holding the encoded value.
+func (encoded EncodedCell) streamCell() StreamCell {
+	if encoded.Type == CellTypeInline {
+		encoded.Type = CellTypeString
+	}
+	return StreamCell{Value: encoded.Value, Type: encoded.Type}
+}
+
+// columnType returns the type of a column of a streamed sheet holding
+// the values encoded as encoded is.
+func (encoded EncodedCell) columnType() ColumnType {
+	switch encoded.Type {
+	case CellTypeNumeric:
+		return ColumnTypeFloat
+	case CellTypeDate:
+		return ColumnTypeDate
+	case CellTypeBool:
+		return ColumnTypeBool
+	}
+	return ColumnTypeString
+}
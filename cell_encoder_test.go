@@ -0,0 +1,110 @@
+package xlsx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	. "gopkg.in/check.v1"
+)
+
+type CellEncoderSuite struct{}
+
+var _ = Suite(&CellEncoderSuite{})
+
+// encoderMoney and encoderStatus are domain types of an application,
+// written by the cell encoders registered by registerTestEncoders.
+type encoderMoney struct {
+	Cents    int64
+	Currency string
+}
+
+type encoderStatus int
+
+// encoderInvoice is a row of a streamed sheet of invoices.
+type encoderInvoice struct {
+	Number   string
+	Total    encoderMoney
+	Discount *encoderMoney
+	Status   encoderStatus
+}
+
+const encoderEuroFormat = `#,##0.00 [$€-1]`
+
+// registerTestEncoders registers the cell encoders of encoderMoney and
+// encoderStatus, and returns the function removing them.
+func registerTestEncoders(c *C) func() {
+	c.Assert(RegisterCellEncoder(encoderMoney{}, CellEncoderFunc(func(v interface{}) (EncodedCell, error) {
+		money := v.(encoderMoney)
+		if money.Currency != "" && money.Currency != "EUR" {
+			return EncodedCell{}, errors.New("not in euros")
+		}
+		return EncodedCell{
+			Type:   CellTypeNumeric,
+			Value:  fmt.Sprintf("%d.%02d", money.Cents/100, money.Cents%100),
+			NumFmt: encoderEuroFormat,
+		}, nil
+	})), IsNil)
+	c.Assert(RegisterCellEncoder(encoderStatus(0), CellEncoderFunc(func(v interface{}) (EncodedCell, error) {
+		return EncodedCell{Type: CellTypeString, Value: []string{"open", "paid"}[v.(encoderStatus)]}, nil
+	})), IsNil)
+	return func() {
+		c.Assert(RegisterCellEncoder(encoderMoney{}, nil), IsNil)
+		c.Assert(RegisterCellEncoder(encoderStatus(0), nil), IsNil)
+	}
+}
+
+func (s *CellEncoderSuite) TestSetValue(c *C) {
+	defer registerTestEncoders(c)()
+	cell := &Cell{}
+	cell.SetValue(encoderMoney{Cents: 123456, Currency: "EUR"})
+	c.Assert(cell.Type(), Equals, CellTypeNumeric)
+	c.Assert(cell.Value, Equals, "1234.56")
+	c.Assert(cell.NumFmt, Equals, encoderEuroFormat)
+
+	cell.SetValue(&encoderMoney{Cents: 5})
+	c.Assert(cell.Value, Equals, "0.05")
+
+	cell.SetValue(encoderStatus(1))
+	c.Assert(cell.Type(), Equals, CellTypeString)
+	c.Assert(cell.Value, Equals, "paid")
+
+	// A value the encoder fails on is set as a string.
+	cell.SetValue(encoderMoney{Cents: 100, Currency: "USD"})
+	c.Assert(cell.Type(), Equals, CellTypeString)
+	c.Assert(cell.Value, Equals, "{100 USD}")
+
+	// Without the encoders, the values are set as before.
+	c.Assert(RegisterCellEncoder(encoderStatus(0), nil), IsNil)
+	cell.SetValue(encoderStatus(1))
+	c.Assert(cell.Value, Equals, "1")
+	c.Assert(RegisterCellEncoder(nil, nil), ErrorMatches, "no type to register a cell encoder for")
+}
+
+func (s *CellEncoderSuite) TestWriteStruct(c *C) {
+	defer registerTestEncoders(c)()
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	c.Assert(builder.AddSheetFromStruct("Invoices", encoderInvoice{}), IsNil)
+	stream, err := builder.Build()
+	c.Assert(err, IsNil)
+	c.Assert(stream.WriteStruct(encoderInvoice{Number: "A1", Total: encoderMoney{Cents: 250000}, Status: 1,
+		Discount: &encoderMoney{Cents: 1250}}), IsNil)
+	c.Assert(stream.WriteStruct(encoderInvoice{Number: "A2", Total: encoderMoney{Cents: 99}}), IsNil)
+	c.Assert(stream.WriteStruct(encoderInvoice{Number: "A3", Total: encoderMoney{Cents: 1, Currency: "USD"}}), ErrorMatches,
+		`field Total of xlsx.encoderInvoice: the xlsx.encoderMoney value can't be encoded: not in euros`)
+	stream.err = nil
+	c.Assert(stream.Close(), IsNil)
+
+	f, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	c.Assert(sheet.Cell(1, 1).Type(), Equals, CellTypeNumeric)
+	c.Assert(sheet.Cell(1, 1).Value, Equals, "2500.00")
+	c.Assert(sheet.Cell(1, 1).GetNumberFormat(), Equals, encoderEuroFormat)
+	c.Assert(sheet.Cell(1, 2).Value, Equals, "12.50")
+	c.Assert(sheet.Cell(1, 3).Value, Equals, "paid")
+	c.Assert(sheet.Cell(2, 1).Value, Equals, "0.99")
+	c.Assert(sheet.Cell(2, 2).Value, Equals, "")
+	c.Assert(sheet.Cell(2, 3).Value, Equals, "open")
+}
@@ -0,0 +1,562 @@
+package xlsx
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// CellStore is a key-value store holding the cells of the rows that a
+// Sheet has moved out of memory, see Sheet.SetCellStore.  It can be
+// backed by anything from a map to a temporary file or an embedded
+// database.
+type CellStore interface {
+	// Put stores value under key, replacing any previous value.
+	Put(key string, value []byte) error
+	// Get returns the value stored under key.
+	Get(key string) ([]byte, error)
+	// Delete removes the value stored under key, if any.
+	Delete(key string) error
+	// Close releases the resources held by the store.
+	Close() error
+}
+
+// ErrCellStoreKeyNotFound is returned by the CellStores of this
+// package when getting a key that was never stored.
+var ErrCellStoreKeyNotFound = errors.New("key not found in cell store")
+
+// MemoryCellStore is a CellStore keeping its values in memory, mostly
+// useful for testing.
+type MemoryCellStore struct {
+	lock   sync.Mutex
+	values map[string][]byte
+}
+
+// NewMemoryCellStore creates an empty MemoryCellStore.
+func NewMemoryCellStore() *MemoryCellStore {
+	return &MemoryCellStore{values: make(map[string][]byte)}
+}
+
+// Put stores value under key.
+func (ms *MemoryCellStore) Put(key string, value []byte) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	ms.values[key] = value
+	return nil
+}
+
+// Get returns the value stored under key.
+func (ms *MemoryCellStore) Get(key string) ([]byte, error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	value, ok := ms.values[key]
+	if !ok {
+		return nil, ErrCellStoreKeyNotFound
+	}
+	return value, nil
+}
+
+// Delete removes the value stored under key.
+func (ms *MemoryCellStore) Delete(key string) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	delete(ms.values, key)
+	return nil
+}
+
+// Close drops all values.
+func (ms *MemoryCellStore) Close() error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	ms.values = make(map[string][]byte)
+	return nil
+}
+
+// TempFileCellStore is a CellStore appending its values to a temporary
+// file, only their offsets being kept in memory.  The space of values
+// that are replaced or deleted is not reclaimed until the store is
+// closed, which removes the file.
+type TempFileCellStore struct {
+	lock    sync.Mutex
+	file    *os.File
+	size    int64
+	offsets map[string][2]int64
+}
+
+// NewTempFileCellStore creates a TempFileCellStore writing to a new
+// temporary file in dir, or in the default directory for temporary
+// files if dir is empty.
+func NewTempFileCellStore(dir string) (*TempFileCellStore, error) {
+	file, err := ioutil.TempFile(dir, "xlsx-cells-")
+	if err != nil {
+		return nil, err
+	}
+	return &TempFileCellStore{file: file, offsets: make(map[string][2]int64)}, nil
+}
+
+// Put appends value to the file and records it under key.
+func (ts *TempFileCellStore) Put(key string, value []byte) error {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	if _, err := ts.file.WriteAt(value, ts.size); err != nil {
+		return err
+	}
+	ts.offsets[key] = [2]int64{ts.size, int64(len(value))}
+	ts.size += int64(len(value))
+	return nil
+}
+
+// Get reads the value stored under key back from the file.
+func (ts *TempFileCellStore) Get(key string) ([]byte, error) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	location, ok := ts.offsets[key]
+	if !ok {
+		return nil, ErrCellStoreKeyNotFound
+	}
+	value := make([]byte, location[1])
+	if _, err := ts.file.ReadAt(value, location[0]); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Delete forgets the value stored under key.
+func (ts *TempFileCellStore) Delete(key string) error {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	delete(ts.offsets, key)
+	return nil
+}
+
+// Close closes and removes the temporary file.
+func (ts *TempFileCellStore) Close() error {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	err := ts.file.Close()
+	if removeErr := os.Remove(ts.file.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// storedCell holds the content of a Cell while it is in a CellStore.
+// Missing stands for a nil entry of Row.Cells.  The styles and data
+// validations are stored as one more than their index among those
+// interned by the sheetCellStore, or zero for none, so that the cells
+// sharing one still share it once they are loaded back.
+type storedCell struct {
+	Missing           bool
+	Value             string
+	Formula           string
+	Style             int
+	NumFmt            string
+	Date1904          bool
+	Hidden            bool
+	HMerge            int
+	VMerge            int
+	CellType          CellType
+	DataValidation    int
+	FormulaType       string
+	FormulaRef        string
+	CellMetadata      int
+	ValueMetadata     int
+	SharedStringIndex int
+	// InheritedStyle and InheritedNumFmt are the style and number
+	// format the cell takes from its row or its column, if any.
+	InheritedStyle  int
+	InheritedNumFmt string
+}
+
+// cellStoreKeys numbers the rows put in any CellStore, so that stores
+// can be shared between sheets.
+var cellStoreKeys uint64
+
+// sheetCellStore keeps at most maxRows rows of a Sheet in memory, the
+// cells of the other rows living in store.
+type sheetCellStore struct {
+	store   CellStore
+	maxRows int
+	// rows are the rows of the Sheet, which are taken out of
+	// Sheet.Rows, see Sheet.rowList.
+	rows []*Row
+	// loaded holds the rows whose cells are in memory, the most
+	// recently used first.
+	loaded   *list.List
+	elements map[*Row]*list.Element
+	// styles and validations are the styles and data validations of
+	// the stored cells, indexed by styleIndexes and
+	// validationIndexes.
+	styles            []*Style
+	styleIndexes      map[*Style]int
+	validations       []*xlsxCellDataValidation
+	validationIndexes map[*xlsxCellDataValidation]int
+	// err is the first error of store, after which no more rows are
+	// evicted, see Sheet.CellStoreErr.
+	err error
+}
+
+// SetCellStore moves the cells of the Sheet to store, except for those
+// of the maxRows most recently used rows, so that sheets larger than
+// the available memory can be worked on.  Rows are moved in and out of
+// store as they are used and while the File is processed, for example
+// when it is saved.
+//
+// The rows of the Sheet are taken out of Sheet.Rows, which is left
+// empty, since its rows could be evicted at any time: they are reached
+// through Sheet.Row, Sheet.Cell, Sheet.AllRows and Sheet.MaxRow
+// instead.  A Row or a Cell held on to while its row is evicted is
+// loaded back by its methods, so that the values set through them are
+// kept, but its fields should only be read and set once a method of
+// it, or Sheet.Row or Sheet.Cell, has loaded its row.
+//
+// Since the Row and Cell API has no way to report errors, the first
+// failure of store is kept, the rows stop being evicted, and the error
+// is returned by Sheet.CellStoreErr and when the File is saved.
+func (s *Sheet) SetCellStore(store CellStore, maxRows int) error {
+	if maxRows < 1 {
+		return fmt.Errorf("at least one row must be kept in memory, not %d", maxRows)
+	}
+	if s.cellStore != nil {
+		return errors.New("the sheet already has a cell store")
+	}
+	s.cellStore = &sheetCellStore{
+		store:             store,
+		maxRows:           maxRows,
+		rows:              s.Rows,
+		loaded:            list.New(),
+		elements:          make(map[*Row]*list.Element),
+		styleIndexes:      make(map[*Style]int),
+		validationIndexes: make(map[*xlsxCellDataValidation]int),
+	}
+	s.Rows = nil
+	for _, row := range s.cellStore.rows {
+		if row != nil {
+			s.cellStore.touch(row)
+		}
+	}
+	return nil
+}
+
+// CellStoreErr returns the first error of the cell store of the Sheet,
+// see SetCellStore, or nil if it hasn't failed.
+func (s *Sheet) CellStoreErr() error {
+	if s.cellStore == nil {
+		return nil
+	}
+	return s.cellStore.err
+}
+
+// cellStoreErr returns the first error of the cell stores of the
+// sheets of the File, see Sheet.CellStoreErr.
+func (f *File) cellStoreErr() error {
+	for _, sheet := range f.Sheets {
+		if err := sheet.CellStoreErr(); err != nil {
+			return fmt.Errorf("the cell store of sheet '%s' failed: %s", sheet.Name, err)
+		}
+	}
+	return nil
+}
+
+// rowList returns the rows of the Sheet, which are held by its cell
+// store if it has one, intended for internal use only.  The cells of
+// the rows must be loaded with Row.load before they are used.
+func (s *Sheet) rowList() []*Row {
+	if s.cellStore != nil {
+		return s.cellStore.rows
+	}
+	return s.Rows
+}
+
+// setRowList replaces the rows of the Sheet, intended for internal use
+// only.
+func (s *Sheet) setRowList(rows []*Row) {
+	if s.cellStore != nil {
+		s.cellStore.rows = rows
+		return
+	}
+	s.Rows = rows
+}
+
+// row returns the Row at index, with its cells loaded, intended for
+// internal use only.  Rows beyond the end of the Sheet are nil.
+func (s *Sheet) row(index int) *Row {
+	rows := s.rowList()
+	if index < 0 || index >= len(rows) {
+		return nil
+	}
+	row := rows[index]
+	if row != nil {
+		row.load()
+	}
+	return row
+}
+
+// load brings the cells of the Row back from the cell store of its
+// Sheet, if they were evicted, and marks the Row as recently used.
+// A Row whose cells can't be read stays evicted.
+func (r *Row) load() {
+	if r == nil || r.Sheet == nil || r.Sheet.cellStore == nil {
+		return
+	}
+	cs := r.Sheet.cellStore
+	if r.evicted {
+		if err := cs.load(r); err != nil {
+			cs.fail(err)
+			return
+		}
+	}
+	cs.touch(r)
+}
+
+// load reads the cells of row back from the store.
+func (cs *sheetCellStore) load(row *Row) error {
+	cells, err := cs.read(row)
+	if err != nil {
+		return err
+	}
+	row.Cells = cells
+	row.evicted = false
+	row.loads++
+	return nil
+}
+
+// snapshot returns the rowSnapshot of the evicted row, copied from
+// the store.  The row is empty if it can't be read.
+func (cs *sheetCellStore) snapshot(row *Row) *rowSnapshot {
+	cells, err := cs.read(row)
+	if err != nil {
+		cs.fail(err)
+	}
+	rs := &rowSnapshot{hidden: row.Hidden, height: row.Height, cells: make([]*Cell, len(cells))}
+	for c, cell := range cells {
+		rs.cells[c] = snapshotCell(cell)
+	}
+	return rs
+}
+
+// read returns the cells of row stored in the store.
+func (cs *sheetCellStore) read(row *Row) ([]*Cell, error) {
+	data, err := cs.store.Get(row.storeKey)
+	if err != nil {
+		return nil, err
+	}
+	var stored []storedCell
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&stored); err != nil {
+		return nil, err
+	}
+	cells := make([]*Cell, len(stored))
+	for i, sc := range stored {
+		if sc.Missing {
+			continue
+		}
+		cells[i] = &Cell{
+			Row:               row,
+			Value:             sc.Value,
+			formula:           sc.Formula,
+			style:             cs.style(sc.Style),
+			NumFmt:            sc.NumFmt,
+			date1904:          sc.Date1904,
+			Hidden:            sc.Hidden,
+			HMerge:            sc.HMerge,
+			VMerge:            sc.VMerge,
+			cellType:          sc.CellType,
+			DataValidation:    cs.validation(sc.DataValidation),
+			formulaType:       sc.FormulaType,
+			formulaRef:        sc.FormulaRef,
+			cellMetadata:      sc.CellMetadata,
+			valueMetadata:     sc.ValueMetadata,
+			sharedStringIndex: sc.SharedStringIndex,
+		}
+		if sc.NumFmt != "" {
+			cells[i].parsedNumFmt = parseFullNumberFormatString(sc.NumFmt)
+		}
+		if sc.InheritedStyle != 0 {
+			cells[i].inherited = &inheritedStyle{style: cs.style(sc.InheritedStyle), numFmt: sc.InheritedNumFmt}
+		}
+	}
+	return cells, nil
+}
+
+// attach puts a Cell held on to while its row was evicted back in the
+// row, loading the row first, intended for internal use only.  The
+// Cell keeps what was set on it unless the row was loaded and may have
+// been changed since, in which case it takes the content loaded, and
+// the Cell loaded in its place is the one to attach if it is held on
+// to in turn.
+func (c *Cell) attach() {
+	if c == nil || c.evictedCol == 0 || c.Row == nil {
+		return
+	}
+	row := c.Row
+	current := row.evicted && row.loads == c.evictedLoads
+	row.load()
+	if row.evicted {
+		return
+	}
+	row.copyForSnapshots()
+	col := c.evictedCol - 1
+	c.evictedCol = 0
+	for len(row.Cells) <= col {
+		row.Cells = append(row.Cells, nil)
+	}
+	if loaded := row.Cells[col]; loaded != nil && loaded != c && !current {
+		*c = *loaded
+		// The displaced cell is never current, so that it takes the
+		// content back when it is used.
+		loaded.evictedCol, loaded.evictedLoads = col+1, -1
+	}
+	row.Cells[col] = c
+}
+
+// touch marks row as the most recently used, evicting the least
+// recently used rows beyond the limit, unless the store failed.
+func (cs *sheetCellStore) touch(row *Row) {
+	if element, ok := cs.elements[row]; ok {
+		cs.loaded.MoveToFront(element)
+		return
+	}
+	cs.elements[row] = cs.loaded.PushFront(row)
+	for cs.err == nil && cs.loaded.Len() > cs.maxRows {
+		oldest := cs.loaded.Back()
+		evicted := oldest.Value.(*Row)
+		if err := cs.evict(evicted); err != nil {
+			cs.fail(err)
+			return
+		}
+		cs.loaded.Remove(oldest)
+		delete(cs.elements, evicted)
+	}
+}
+
+// evict moves the cells of row to the store.  The cells are only
+// written if they changed since they were last stored, the store
+// keeping what they were.
+func (cs *sheetCellStore) evict(row *Row) error {
+	row.copyForSnapshots()
+	stored := make([]storedCell, len(row.Cells))
+	for i, cell := range row.Cells {
+		if cell == nil {
+			stored[i].Missing = true
+			continue
+		}
+		stored[i] = storedCell{
+			Value:             cell.Value,
+			Formula:           cell.formula,
+			Style:             cs.styleIndex(cell.style),
+			NumFmt:            cell.NumFmt,
+			Date1904:          cell.date1904,
+			Hidden:            cell.Hidden,
+			HMerge:            cell.HMerge,
+			VMerge:            cell.VMerge,
+			CellType:          cell.cellType,
+			DataValidation:    cs.validationIndex(cell.DataValidation),
+			FormulaType:       cell.formulaType,
+			FormulaRef:        cell.formulaRef,
+			CellMetadata:      cell.cellMetadata,
+			ValueMetadata:     cell.valueMetadata,
+			SharedStringIndex: cell.sharedStringIndex,
+		}
+		if cell.inherited != nil {
+			stored[i].InheritedStyle = cs.styleIndex(cell.inherited.style)
+			stored[i].InheritedNumFmt = cell.inherited.numFmt
+		}
+	}
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(stored); err != nil {
+		return err
+	}
+	hash := fnv.New64a()
+	hash.Write(buffer.Bytes())
+	if sum := hash.Sum64(); row.storeKey == "" || sum != row.storedSum {
+		if row.storeKey == "" {
+			row.storeKey = fmt.Sprintf("row%d", atomic.AddUint64(&cellStoreKeys, 1))
+		}
+		if err := cs.store.Put(row.storeKey, buffer.Bytes()); err != nil {
+			return err
+		}
+		row.storedSum = sum
+	}
+	for i, cell := range row.Cells {
+		if cell != nil {
+			cell.evictedCol, cell.evictedLoads = i+1, row.loads
+		}
+	}
+	row.Cells = nil
+	row.evicted = true
+	return nil
+}
+
+// styleIndex returns one more than the index of style among the
+// interned styles, adding it if needed, or zero for no style.
+func (cs *sheetCellStore) styleIndex(style *Style) int {
+	if style == nil {
+		return 0
+	}
+	index, ok := cs.styleIndexes[style]
+	if !ok {
+		cs.styles = append(cs.styles, style)
+		index = len(cs.styles)
+		cs.styleIndexes[style] = index
+	}
+	return index
+}
+
+// style returns the interned style of the given index, see styleIndex.
+func (cs *sheetCellStore) style(index int) *Style {
+	if index == 0 {
+		return nil
+	}
+	return cs.styles[index-1]
+}
+
+// validationIndex returns one more than the index of validation among
+// the interned data validations, adding it if needed, or zero for none.
+func (cs *sheetCellStore) validationIndex(validation *xlsxCellDataValidation) int {
+	if validation == nil {
+		return 0
+	}
+	index, ok := cs.validationIndexes[validation]
+	if !ok {
+		cs.validations = append(cs.validations, validation)
+		index = len(cs.validations)
+		cs.validationIndexes[validation] = index
+	}
+	return index
+}
+
+// validation returns the interned data validation of the given index,
+// see validationIndex.
+func (cs *sheetCellStore) validation(index int) *xlsxCellDataValidation {
+	if index == 0 {
+		return nil
+	}
+	return cs.validations[index-1]
+}
+
+// forget drops a row removed from the Sheet from the store.
+func (cs *sheetCellStore) forget(row *Row) {
+	if element, ok := cs.elements[row]; ok {
+		cs.loaded.Remove(element)
+		delete(cs.elements, row)
+	}
+	if row.storeKey != "" {
+		if err := cs.store.Delete(row.storeKey); err != nil {
+			cs.fail(err)
+		}
+	}
+}
+
+// fail keeps err if it is the first error of the store.
+func (cs *sheetCellStore) fail(err error) {
+	if cs.err == nil {
+		cs.err = err
+	}
+}
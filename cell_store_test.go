@@ -0,0 +1,269 @@
+package xlsx
+
+import (
+	"bytes"
+	"errors"
+	"os"
+
+	. "gopkg.in/check.v1"
+)
+
+type CellStoreSuite struct{}
+
+var _ = Suite(&CellStoreSuite{})
+
+// fillSheet adds rows of a number, a bold string and a formula.
+func fillSheet(c *C, sheet *Sheet, rows int) {
+	bold := NewStyle()
+	bold.Font.Bold = true
+	for r := 0; r < rows; r++ {
+		row := sheet.AddRow()
+		row.AddCell().SetInt(r)
+		cell := row.AddCell()
+		cell.SetString("text")
+		cell.SetStyle(bold)
+		row.AddCell().SetFormula("A1*2")
+	}
+}
+
+func (s *CellStoreSuite) TestRowsAreEvicted(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	store := NewMemoryCellStore()
+	c.Assert(sheet.SetCellStore(store, 2), IsNil)
+	fillSheet(c, sheet, 10)
+
+	c.Assert(sheet.Rows, IsNil)
+	c.Assert(sheet.MaxRow, Equals, 10)
+	loaded := 0
+	for _, row := range sheet.rowList() {
+		if !row.evicted {
+			loaded++
+		}
+	}
+	c.Assert(loaded, Equals, 2)
+	c.Assert(sheet.rowList()[0].Cells, IsNil)
+	c.Assert(store.values, HasLen, 8)
+
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "0")
+	c.Assert(sheet.Cell(0, 1).GetStyle().Font.Bold, Equals, true)
+	c.Assert(sheet.Cell(0, 2).Formula(), Equals, "A1*2")
+	c.Assert(sheet.Row(3).Cells[0].Value, Equals, "3")
+}
+
+func (s *CellStoreSuite) TestChangesToEvictedRowsAreKept(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	store, err := NewTempFileCellStore(c.MkDir())
+	c.Assert(err, IsNil)
+	defer store.Close()
+	c.Assert(sheet.SetCellStore(store, 1), IsNil)
+	fillSheet(c, sheet, 5)
+
+	sheet.Cell(1, 0).SetString("changed")
+	sheet.Cell(4, 0).SetString("last")
+	c.Assert(sheet.rowList()[1].evicted, Equals, true)
+	c.Assert(sheet.Cell(1, 0).Value, Equals, "changed")
+	c.Assert(sheet.Row(1).AddCell(), NotNil)
+	c.Assert(sheet.Row(4).Cells, HasLen, 3)
+	c.Assert(sheet.Row(1).Cells, HasLen, 4)
+}
+
+func (s *CellStoreSuite) TestSaveWithCellStore(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	c.Assert(sheet.SetCellStore(NewMemoryCellStore(), 3), IsNil)
+	fillSheet(c, sheet, 20)
+
+	var buffer bytes.Buffer
+	c.Assert(f.Write(&buffer), IsNil)
+	reread, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	rows := reread.Sheets[0].Rows
+	c.Assert(rows, HasLen, 20)
+	c.Assert(rows[17].Cells[0].Value, Equals, "17")
+	c.Assert(rows[17].Cells[2].Formula(), Equals, "A1*2")
+}
+
+func (s *CellStoreSuite) TestRemoveRowDeletesStoredCells(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	store := NewMemoryCellStore()
+	c.Assert(sheet.SetCellStore(store, 1), IsNil)
+	fillSheet(c, sheet, 3)
+	c.Assert(store.values, HasLen, 2)
+	c.Assert(sheet.RemoveRowAtIndex(0), IsNil)
+	c.Assert(store.values, HasLen, 1)
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "1")
+}
+
+func (s *CellStoreSuite) TestSetCellStoreErrors(c *C) {
+	sheet := &Sheet{}
+	c.Assert(sheet.SetCellStore(NewMemoryCellStore(), 0), ErrorMatches, "at least one row must be kept in memory, not 0")
+	c.Assert(sheet.SetCellStore(NewMemoryCellStore(), 1), IsNil)
+	c.Assert(sheet.SetCellStore(NewMemoryCellStore(), 1), ErrorMatches, "the sheet already has a cell store")
+}
+
+func (s *CellStoreSuite) TestSetCellStoreTakesRows(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	fillSheet(c, sheet, 3)
+	c.Assert(sheet.SetCellStore(NewMemoryCellStore(), 1), IsNil)
+	c.Assert(sheet.Rows, IsNil)
+	c.Assert(sheet.rowList(), HasLen, 3)
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "0")
+	_, err = sheet.AddRowAtIndex(1)
+	c.Assert(err, IsNil)
+	c.Assert(sheet.Rows, IsNil)
+	c.Assert(sheet.rowList(), HasLen, 4)
+	c.Assert(sheet.Cell(2, 0).Value, Equals, "1")
+}
+
+func (s *CellStoreSuite) TestHeldCellsAreAttached(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	c.Assert(sheet.SetCellStore(NewMemoryCellStore(), 1), IsNil)
+	fillSheet(c, sheet, 3)
+
+	// A cell held on to while its row is evicted keeps what is set
+	// on it, through its methods or, before its row is loaded again,
+	// its fields.
+	held := sheet.Cell(0, 0)
+	row := sheet.Row(0)
+	sheet.Cell(2, 0)
+	c.Assert(row.evicted, Equals, true)
+	held.SetString("changed")
+	c.Assert(sheet.Cell(0, 0), Equals, held)
+	sheet.Cell(2, 0)
+	held.Value = "field"
+	c.Assert(held.String(), Equals, "field")
+	sheet.Cell(2, 0)
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "field")
+
+	// Once its row is loaded again, the cell held on to takes what
+	// was set on the cell loaded in its place.
+	sheet.Cell(2, 0)
+	loaded := sheet.Cell(0, 0)
+	c.Assert(loaded != held, Equals, true)
+	loaded.SetString("loaded")
+	sheet.Cell(2, 0)
+	c.Assert(held.String(), Equals, "loaded")
+	c.Assert(loaded.String(), Equals, "loaded")
+	held.SetInt(7)
+	sheet.Cell(2, 0)
+	c.Assert(loaded.Value, Equals, "loaded")
+	c.Assert(loaded.String(), Equals, "7")
+	row.AddCell().SetString("added")
+	c.Assert(row.Cells, HasLen, 4)
+
+	var buffer bytes.Buffer
+	c.Assert(f.Write(&buffer), IsNil)
+	reread, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(reread.Sheets[0].Cell(0, 0).Value, Equals, "7")
+	c.Assert(reread.Sheets[0].Cell(0, 3).Value, Equals, "added")
+}
+
+func (s *CellStoreSuite) TestStylesStayShared(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	c.Assert(sheet.SetCellStore(NewMemoryCellStore(), 1), IsNil)
+	fillSheet(c, sheet, 3)
+	first := sheet.Cell(0, 1).GetStyle()
+	c.Assert(sheet.Cell(1, 1).GetStyle(), Equals, first)
+	c.Assert(sheet.Cell(2, 1).GetStyle(), Equals, first)
+	c.Assert(sheet.Cell(0, 0).GetStyle(), Not(Equals), first)
+}
+
+// countingCellStore is a MemoryCellStore counting the values put in
+// it, which fails once failAfter values were put if failAfter isn't 0.
+type countingCellStore struct {
+	*MemoryCellStore
+	puts      int
+	failAfter int
+}
+
+func (cs *countingCellStore) Put(key string, value []byte) error {
+	if cs.failAfter > 0 && cs.puts >= cs.failAfter {
+		return errors.New("disk full")
+	}
+	cs.puts++
+	return cs.MemoryCellStore.Put(key, value)
+}
+
+func (cs *countingCellStore) Delete(key string) error {
+	if cs.failAfter > 0 {
+		return errors.New("disk full")
+	}
+	return cs.MemoryCellStore.Delete(key)
+}
+
+func (s *CellStoreSuite) TestUnchangedRowsAreNotStoredAgain(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	store := &countingCellStore{MemoryCellStore: NewMemoryCellStore()}
+	c.Assert(sheet.SetCellStore(store, 1), IsNil)
+	fillSheet(c, sheet, 3)
+	c.Assert(store.puts, Equals, 2)
+	sheet.Row(0)
+	sheet.Row(1)
+	c.Assert(store.puts, Equals, 3)
+	sheet.Cell(0, 0).SetString("changed")
+	sheet.Row(1)
+	c.Assert(store.puts, Equals, 4)
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "changed")
+}
+
+func (s *CellStoreSuite) TestCellStoreFailures(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	store := &countingCellStore{MemoryCellStore: NewMemoryCellStore(), failAfter: 1}
+	c.Assert(sheet.SetCellStore(store, 1), IsNil)
+	fillSheet(c, sheet, 3)
+	c.Assert(sheet.CellStoreErr(), ErrorMatches, "disk full")
+
+	// The rows stop being evicted, so that none is lost.
+	c.Assert(sheet.rowList()[1].evicted, Equals, false)
+	c.Assert(sheet.Cell(2, 0).Value, Equals, "2")
+	c.Assert(sheet.RemoveRowAtIndex(0), IsNil)
+	var buffer bytes.Buffer
+	c.Assert(f.Write(&buffer), ErrorMatches, "the cell store of sheet 'Sheet1' failed: disk full")
+
+	sheet, err = f.AddSheet("Sheet2")
+	c.Assert(err, IsNil)
+	c.Assert(sheet.SetCellStore(&countingCellStore{MemoryCellStore: NewMemoryCellStore()}, 1), IsNil)
+	fillSheet(c, sheet, 2)
+	sheet.cellStore.store = NewMemoryCellStore()
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "")
+	c.Assert(sheet.CellStoreErr(), Equals, ErrCellStoreKeyNotFound)
+}
+
+func (s *CellStoreSuite) TestTempFileCellStore(c *C) {
+	store, err := NewTempFileCellStore(c.MkDir())
+	c.Assert(err, IsNil)
+	c.Assert(store.Put("a", []byte("first")), IsNil)
+	c.Assert(store.Put("b", []byte("second")), IsNil)
+	c.Assert(store.Put("a", []byte("third")), IsNil)
+	value, err := store.Get("a")
+	c.Assert(err, IsNil)
+	c.Assert(string(value), Equals, "third")
+	value, err = store.Get("b")
+	c.Assert(err, IsNil)
+	c.Assert(string(value), Equals, "second")
+	c.Assert(store.Delete("b"), IsNil)
+	_, err = store.Get("b")
+	c.Assert(err, Equals, ErrCellStoreKeyNotFound)
+	name := store.file.Name()
+	c.Assert(store.Close(), IsNil)
+	_, err = os.Stat(name)
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
@@ -1,6 +1,7 @@
 package xlsx
 
 import (
+	"bytes"
 	"math"
 	"testing"
 	"time"
@@ -448,10 +449,18 @@ func (l *CellSuite) TestFormattedValue(c *C) {
 	fvc.Equals(cell, "3794775.00%")
 
 	cell.NumFmt = "0.00e+00"
-	fvc.Equals(cell, "3.794775e+04")
+	fvc.Equals(cell, "3.79e+04")
 
-	cell.NumFmt = "##0.0e+0" // This is wrong, but we'll use it for now.
-	fvc.Equals(cell, "3.794775e+04")
+	cell.NumFmt = "##0.0e+0"
+	fvc.Equals(cell, "37.9e+3")
+
+	cell.NumFmt = "# ?/?"
+	fvc.Equals(cell, "37947 3/4")
+
+	cell.NumFmt = "00000000"
+	fvc.Equals(cell, "00037948")
+	negativeCell.NumFmt = "000000"
+	fvc.Equals(negativeCell, "-037948")
 
 	cell.NumFmt = "mm-dd-yy"
 	fvc.Equals(cell, "11-22-03")
@@ -806,3 +815,33 @@ func (s *CellSuite) TestIs12HourtTime(c *C) {
 	c.Assert(is12HourTime("A/P"), Equals, true)
 	c.Assert(is12HourTime("x"), Equals, false)
 }
+
+// Error values make the round trip as errors, along with the formula
+// they result from.
+func (s *CellSuite) TestSetError(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	row := sheet.AddRow()
+	cell := row.AddCell()
+	c.Assert(cell.SetError(ErrorValueDivZero), IsNil)
+	cell = row.AddCell()
+	cell.SetFormula("NA()")
+	c.Assert(cell.SetError(ErrorValueNA), IsNil)
+	c.Assert(cell.Formula(), Equals, "NA()")
+	c.Assert(row.AddCell().SetError("#OOPS!"), ErrorMatches, "'#OOPS!' is not an error value")
+
+	var buffer bytes.Buffer
+	c.Assert(f.Write(&buffer), IsNil)
+	f, err = OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	cell = f.Sheets[0].Cell(0, 0)
+	c.Assert(cell.Type(), Equals, CellTypeError)
+	c.Assert(cell.Value, Equals, "#DIV/0!")
+	cell = f.Sheets[0].Cell(0, 1)
+	c.Assert(cell.Type(), Equals, CellTypeError)
+	c.Assert(cell.Formula(), Equals, "NA()")
+	value, err := cell.FormattedValue()
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "#N/A")
+}
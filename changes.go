@@ -0,0 +1,784 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"hash"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A File opened WithChangeTracking keeps a fingerprint of each of its
+// cells, and of the settings of its sheets and of itself, as read.
+// Changes compares them to the File as it is to list the cells changed
+// since, and WithMinimalRewrite saves the File by patching only the
+// worksheets whose cells changed, copying every other part of the file
+// read, which is opened again, as it is.
+
+// ChangeKind is the way a cell changed since the File was read, see
+// File.Changes.
+type ChangeKind int
+
+const (
+	// CellModified is a cell whose value, formula or format changed.
+	CellModified ChangeKind = iota
+	// CellAdded is a cell that the file read didn't have.
+	CellAdded
+	// CellRemoved is a cell of the file read that was removed.
+	CellRemoved
+)
+
+// CellChange is a cell changed since the File was read, at the zero based Row and Col of the sheet named Sheet.
+type CellChange struct {
+	Sheet string
+	Row   int
+	Col   int
+	Kind  ChangeKind
+}
+
+// changeTracker holds what a File opened WithChangeTracking was read
+// from, and the sheets and the fingerprint of the settings it had.
+type changeTracker struct {
+	source   changeSource
+	sheets   []*Sheet
+	settings uint64
+}
+
+// trackedSheet holds what a Sheet of a File opened WithChangeTracking
+// was when read: the name of its worksheet part, its name, and the
+// fingerprints of its layout and of its cells.
+type trackedSheet struct {
+	part   string
+	name   string
+	layout uint64
+	cells  [][]cellFingerprint
+}
+
+// cellFingerprint holds the hashes of the content of a cell, its value
+// and formula, and of its format, what else is written with it.
+type cellFingerprint struct {
+	present bool
+	content uint64
+	format  uint64
+}
+
+// cellPosition is the zero based row and column of a cell.
+type cellPosition struct {
+	row int
+	col int
+}
+
+// changeSource is what a File opened WithChangeTracking was read from:
+// the file at path, as described by info, or readerAt, of size bytes.
+type changeSource struct {
+	path     string
+	info     os.FileInfo
+	readerAt io.ReaderAt
+	size     int64
+}
+
+// open opens the zip file read again, returning false if it changed
+// since it was read.
+func (cs changeSource) open() (*zip.Reader, io.Closer, bool, error) {
+	if cs.readerAt != nil {
+		r, err := zip.NewReader(cs.readerAt, cs.size)
+		return r, ioutil.NopCloser(nil), err == nil, err
+	}
+	file, err := os.Open(cs.path)
+	if err != nil {
+		return nil, nil, false, nil
+	}
+	info, err := file.Stat()
+	if err != nil || !os.SameFile(info, cs.info) || info.Size() != cs.info.Size() || !info.ModTime().Equal(cs.info.ModTime()) {
+		file.Close()
+		return nil, nil, false, nil
+	}
+	r, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, nil, false, err
+	}
+	return r, file, true, nil
+}
+
+// isFile returns true if the file at path is the file read.
+func (cs changeSource) isFile(path string) bool {
+	if cs.info == nil {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && os.SameFile(info, cs.info)
+}
+
+// track records the sheets and the settings of f as they are.
+func (t *changeTracker) track(f *File) {
+	fp := newFingerprinter()
+	t.sheets = append([]*Sheet(nil), f.Sheets...)
+	t.settings = fp.settings(f)
+	for _, sheet := range f.Sheets {
+		if sheet.tracked == nil {
+			sheet.tracked = &trackedSheet{}
+		}
+		sheet.tracked.name = sheet.Name
+		sheet.tracked.layout = fp.layout(sheet)
+		sheet.tracked.cells = make([][]cellFingerprint, len(sheet.rowList()))
+		for r := range sheet.rowList() {
+			row := sheet.row(r)
+			if row == nil {
+				continue
+			}
+			cells := make([]cellFingerprint, len(row.Cells))
+			for c, cell := range row.Cells {
+				if cell != nil {
+					cells[c] = fp.cell(cell)
+				}
+			}
+			sheet.tracked.cells[r] = cells
+		}
+	}
+}
+
+// Changes returns the cells changed since the File was read, by sheet, row and column, if it was opened
+// WithChangeTracking, or nil. The cells of the sheets added since are all CellAdded, those of the sheets removed all
+// CellRemoved. Changes to anything but cells, such as the width of a column, aren't listed.
+func (f *File) Changes() []CellChange {
+	t := f.changes
+	if t == nil {
+		return nil
+	}
+	fp := newFingerprinter()
+	var changes []CellChange
+	current := make(map[*Sheet]bool, len(f.Sheets))
+	for _, sheet := range f.Sheets {
+		current[sheet] = true
+		name := sheet.Name
+		fp.diff(sheet, sheet.tracked, func(r, c int, kind ChangeKind, formatChanged bool) {
+			changes = append(changes, CellChange{Sheet: name, Row: r, Col: c, Kind: kind})
+		})
+	}
+	for _, sheet := range t.sheets {
+		if current[sheet] {
+			continue
+		}
+		for r, cells := range sheet.tracked.cells {
+			for c, cell := range cells {
+				if cell.present {
+					changes = append(changes, CellChange{Sheet: sheet.tracked.name, Row: r, Col: c, Kind: CellRemoved})
+				}
+			}
+		}
+	}
+	return changes
+}
+
+// fingerprinter hashes cells, sheets and Files, caching the hashes of
+// the styles shared by cells.
+type fingerprinter struct {
+	hash   hash.Hash64
+	buf    []byte
+	styles map[*Style]uint64
+	// blank is the format of a new cell, newStyle the hash of
+	// NewStyle.
+	blank    uint64
+	newStyle uint64
+}
+
+// newFingerprinter returns a fingerprinter.
+func newFingerprinter() *fingerprinter {
+	fp := &fingerprinter{hash: fnv.New64a(), styles: make(map[*Style]uint64)}
+	fp.blank = fp.cell(&Cell{}).format
+	fp.newStyle = fp.json(NewStyle())
+	return fp
+}
+
+// sum returns the hash of b.
+func (fp *fingerprinter) sum(b []byte) uint64 {
+	fp.hash.Reset()
+	fp.hash.Write(b)
+	return fp.hash.Sum64()
+}
+
+// json returns the hash of v encoded as JSON.
+func (fp *fingerprinter) json(v interface{}) uint64 {
+	data, _ := json.Marshal(v)
+	return fp.sum(data)
+}
+
+// style returns the hash of style, or zero if it is nil.
+func (fp *fingerprinter) style(style *Style) uint64 {
+	if style == nil {
+		return 0
+	}
+	sum, ok := fp.styles[style]
+	if !ok {
+		sum = fp.json(style)
+		fp.styles[style] = sum
+	}
+	return sum
+}
+
+// cell returns the fingerprint of cell.  The general number format
+// counts as none, as it is written the same way.
+func (fp *fingerprinter) cell(cell *Cell) cellFingerprint {
+	b := append(fp.buf[:0], byte(cell.cellType))
+	for _, s := range []string{cell.Value, cell.formula, cell.formulaType, cell.formulaRef} {
+		b = append(append(b, s...), 0)
+	}
+	content := fp.sum(b)
+	numFmt := cell.NumFmt
+	if numFmt == builtInNumFmt[builtInNumFmtIndex_GENERAL] {
+		numFmt = ""
+	}
+	b = append(append(b[:0], numFmt...), 0)
+	for _, n := range []int{cell.HMerge, cell.VMerge, cell.cellMetadata, cell.valueMetadata} {
+		b = append(strconv.AppendInt(b, int64(n), 10), ' ')
+	}
+	b = strconv.AppendBool(b, cell.formula != "")
+	b = strconv.AppendUint(b, fp.style(cell.style), 16)
+	if cell.DataValidation != nil {
+		data, _ := json.Marshal(cell.DataValidation)
+		b = append(b, data...)
+	}
+	fp.buf = b
+	return cellFingerprint{present: true, content: content, format: fp.sum(b)}
+}
+
+// layout returns the fingerprint of the settings of sheet, and of its
+// columns and rows whose settings aren't the default ones, such as the
+// columns added along with cells.
+func (fp *fingerprinter) layout(sheet *Sheet) uint64 {
+	type colLayout struct {
+		*Col
+		Index  int
+		NumFmt string
+		Style  uint64
+	}
+	var cols []colLayout
+	for i, col := range sheet.Cols {
+		if col == nil {
+			continue
+		}
+		style := fp.style(col.style)
+		if col.Width != 0 || col.Hidden || col.Collapsed || col.OutlineLevel != 0 || col.numFmt != "" ||
+			col.DataValidation != nil || style != 0 && style != fp.newStyle {
+			cols = append(cols, colLayout{Col: col, Index: i, NumFmt: col.numFmt, Style: style})
+		}
+	}
+	b, _ := json.Marshal(struct {
+		Name, Hidden, VeryHidden, Selected         interface{}
+		SheetViews, SheetFormat, AutoFilter        interface{}
+		Protection, IgnoredErrors, SparklineGroups interface{}
+		Hyperlinks, Properties, Cols, Relationship interface{}
+	}{sheet.Name, sheet.Hidden, sheet.VeryHidden, sheet.Selected,
+		sheet.SheetViews, sheet.SheetFormat, sheet.AutoFilter,
+		sheet.Protection, sheet.IgnoredErrors, sheet.SparklineGroups,
+		sheet.Hyperlinks, sheet.Properties, cols, sheet.rawRelationships})
+	for r, row := range sheet.rowList() {
+		if row != nil && (row.Hidden || row.isCustom || row.Height != 0 || row.OutlineLevel != 0) {
+			b = strconv.AppendInt(append(b, '\n'), int64(r), 10)
+			b = strconv.AppendBool(append(b, ' '), row.Hidden)
+			b = strconv.AppendBool(append(b, ' '), row.isCustom)
+			b = strconv.AppendFloat(append(b, ' '), row.Height, 'g', -1, 64)
+			b = strconv.AppendInt(append(b, ' '), int64(row.OutlineLevel), 10)
+		}
+	}
+	return fp.sum(b)
+}
+
+// settings returns the fingerprint of the settings of f written to its
+// workbook, and of its raw parts.
+func (fp *fingerprinter) settings(f *File) uint64 {
+	b, _ := json.Marshal(struct {
+		Date1904, DefinedNames, CalcProperties, Protection interface{}
+		Theme, Relationships, ContentTypes, CoreProperties interface{}
+	}{f.Date1904, f.DefinedNames, f.CalcProperties, f.Protection,
+		f.Theme, f.rawRelationships, f.rawContentTypes, f.coreProperties})
+	for _, name := range f.RawPartNames() {
+		b = append(append(b, name...), 0)
+		b = strconv.AppendUint(b, fp.sum(f.rawParts[name]), 16)
+	}
+	return fp.sum(b)
+}
+
+// diff calls changed with the position of each cell of sheet that
+// changed since it was tracked, or of every cell if tracked is nil,
+// along with the way it changed, and whether its format changed: that
+// of the cell added or removed not being the blank one.
+func (fp *fingerprinter) diff(sheet *Sheet, tracked *trackedSheet, changed func(r, c int, kind ChangeKind, formatChanged bool)) {
+	var before [][]cellFingerprint
+	if tracked != nil {
+		before = tracked.cells
+	}
+	rows := len(sheet.rowList())
+	if len(before) > rows {
+		rows = len(before)
+	}
+	for r := 0; r < rows; r++ {
+		var was []cellFingerprint
+		if r < len(before) {
+			was = before[r]
+		}
+		var cells []*Cell
+		if row := sheet.row(r); row != nil {
+			cells = row.Cells
+		}
+		cols := len(cells)
+		if len(was) > cols {
+			cols = len(was)
+		}
+		for c := 0; c < cols; c++ {
+			var old, now cellFingerprint
+			if c < len(was) {
+				old = was[c]
+			}
+			if c < len(cells) && cells[c] != nil {
+				now = fp.cell(cells[c])
+			}
+			switch {
+			case old.present && now.present:
+				if old != now {
+					changed(r, c, CellModified, old.format != now.format)
+				}
+			case now.present:
+				changed(r, c, CellAdded, now.format != fp.blank)
+			case old.present:
+				changed(r, c, CellRemoved, old.format != fp.blank)
+			}
+		}
+	}
+}
+
+// calcIdAttribute matches the calcId attribute of the calcPr element of
+// a workbook, after the start of the element.
+var calcIdAttribute = regexp.MustCompile(`(<(?:\w+:)?calcPr\b[^>]*?)\s+calcId="[^"]*"`)
+
+// writeMinimal writes f to writer as WithMinimalRewrite says, returning
+// false, having written nothing, if the changes of f need the whole
+// File written.
+func (f *File) writeMinimal(writer io.Writer, opts writeOptions) (bool, error) {
+	t := f.changes
+	if t == nil {
+		return false, errors.New("a minimal rewrite needs a File opened WithChangeTracking")
+	}
+	if opts.defaultFont != nil || opts.sharedStrings == SharedStringsShared || f.isCompatible() ||
+		f.FormulaErrors == FormulaErrorsWrapped || len(f.Sheets) != len(t.sheets) {
+		return false, nil
+	}
+	fp := newFingerprinter()
+	if fp.settings(f) != t.settings {
+		return false, nil
+	}
+	changed := make(map[string][]cellPosition)
+	for i, sheet := range f.Sheets {
+		if sheet != t.sheets[i] || sheet.tracked == nil || fp.layout(sheet) != sheet.tracked.layout {
+			return false, nil
+		}
+		formatChanged := false
+		var positions []cellPosition
+		fp.diff(sheet, sheet.tracked, func(r, c int, kind ChangeKind, format bool) {
+			formatChanged = formatChanged || format
+			positions = append(positions, cellPosition{row: r, col: c})
+		})
+		if formatChanged {
+			return false, nil
+		}
+		if len(positions) > 0 {
+			changed[sheet.tracked.part] = positions
+		}
+	}
+	if err := f.checkWrite(); err != nil {
+		return false, err
+	}
+	r, closer, ok, err := t.source.open()
+	if !ok || err != nil {
+		return false, err
+	}
+	defer closer.Close()
+
+	patched := make(map[string][]byte, len(changed))
+	var largestPart int64
+	for _, part := range r.File {
+		size := int64(part.UncompressedSize64)
+		if positions, ok := changed[part.Name]; ok {
+			data, err := readZipPart(part)
+			if err != nil {
+				return false, err
+			}
+			data, ok, err = patchWorksheet(data, f.sheetForPart(part.Name), positions)
+			if !ok || err != nil {
+				return false, err
+			}
+			patched[part.Name] = data
+			size = int64(len(data))
+		} else if part.Name == workbookPart && len(changed) > 0 {
+			data, err := readZipPart(part)
+			if err != nil {
+				return false, err
+			}
+			// The workbook is left to be recalculated, as a workbook
+			// written whole is, see CalcProperties.CalcId.
+			if calcIdAttribute.Match(data) {
+				data = calcIdAttribute.ReplaceAll(data, []byte("$1"))
+				patched[part.Name] = data
+				size = int64(len(data))
+			}
+		}
+		if size > largestPart {
+			largestPart = size
+		}
+	}
+	for part := range changed {
+		if _, ok := patched[part]; !ok {
+			return false, nil
+		}
+	}
+	if !opts.zip64Allowed() {
+		if err := checkZip64(len(r.File), largestPart, 0); err != nil {
+			return true, err
+		}
+	}
+	method := zip.Deflate
+	if opts.compression != nil && *opts.compression == CompressionStore {
+		method = zip.Store
+	}
+	output := &throttledWriter{writer: writer}
+	zipWriter := zip.NewWriter(output)
+	for _, part := range r.File {
+		data, ok := patched[part.Name]
+		if !ok {
+			if err := copyZipPart(zipWriter, part); err != nil {
+				return true, err
+			}
+			continue
+		}
+		w, err := zipWriter.CreateHeader(&zip.FileHeader{Name: part.Name, Method: method})
+		if err != nil {
+			return true, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return true, err
+		}
+	}
+	if !opts.zip64Allowed() {
+		if err := zipWriter.Flush(); err != nil {
+			return true, err
+		}
+		if err := checkZip64(len(r.File), largestPart, output.bytesWritten()); err != nil {
+			return true, err
+		}
+	}
+	return true, zipWriter.Close()
+}
+
+// sheetForPart returns the Sheet read from the worksheet part with the
+// given name.
+func (f *File) sheetForPart(name string) *Sheet {
+	for _, sheet := range f.Sheets {
+		if sheet.tracked != nil && sheet.tracked.part == name {
+			return sheet
+		}
+	}
+	return nil
+}
+
+// readZipPart returns the content of the part f.
+func readZipPart(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+// saveOverSource saves f over the file it was read from, at path, to a
+// temporary file renamed once written, since a minimal rewrite reads
+// the file it replaces.  The file written is then the one read, unless
+// the whole File was written, after which minimal rewrites write the
+// whole File again.
+func (f *File) saveOverSource(path string, options []Option) error {
+	target, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(target.Name())
+	minimal, err := f.write(target, options)
+	if err != nil {
+		target.Close()
+		return err
+	}
+	if err := target.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(target.Name(), path); err != nil {
+		return err
+	}
+	if minimal {
+		if info, err := os.Stat(path); err == nil {
+			f.changes.source.info = info
+			f.changes.track(f)
+		}
+	}
+	return nil
+}
+
+// patchWorksheet returns data, the XML of the worksheet read for sheet,
+// with the cells at positions, sorted by row and column, written again
+// from sheet, the cells modified keeping their style, and its dimension
+// extended to the cells added.  It returns false
+// if the worksheet can't be patched, its sheet data being in a
+// namespace with a prefix, or a cell modified holding a formula shared
+// with other cells, which would lose it.
+func patchWorksheet(data []byte, sheet *Sheet, positions []cellPosition) ([]byte, bool, error) {
+	if sheet == nil {
+		return nil, false, nil
+	}
+	var rows []int
+	cols := make(map[int][]int)
+	for _, p := range positions {
+		if len(cols[p.row]) == 0 {
+			rows = append(rows, p.row)
+		}
+		cols[p.row] = append(cols[p.row], p.col)
+	}
+	sort.Ints(rows)
+
+	var out bytes.Buffer
+	inline := NewCappedSharedStringStore(0)
+	var pos int64
+	copyTo := func(offset int64) {
+		out.Write(data[pos:offset])
+		pos = offset
+	}
+	writeCell := func(r, c, xfId int) error {
+		row := sheet.row(r)
+		if row == nil || c >= len(row.Cells) || row.Cells[c] == nil {
+			return nil
+		}
+		xC := sheet.makeXLSXCell(row.Cells[c], c, r, xfId, inline)
+		return xml.NewEncoder(&out).EncodeElement(xC, xml.StartElement{Name: xml.Name{Local: "c"}})
+	}
+	writeRow := func(r int) error {
+		start := out.Len()
+		out.WriteString(`<row r="` + strconv.Itoa(r+1) + `">`)
+		cells := out.Len()
+		for _, c := range cols[r] {
+			if err := writeCell(r, c, 0); err != nil {
+				return err
+			}
+		}
+		if out.Len() == cells {
+			out.Truncate(start)
+			return nil
+		}
+		out.WriteString("</row>")
+		return nil
+	}
+	// openTag writes the start tag of the element at offset, ending at
+	// end, opening it if it is empty, and returns whether it was.
+	openTag := func(offset, end int64) bool {
+		tag := data[offset:end]
+		if !bytes.HasSuffix(tag, []byte("/>")) {
+			return false
+		}
+		copyTo(offset)
+		out.Write(bytes.TrimRight(tag[:len(tag)-2], " \t\r\n"))
+		out.WriteString(">")
+		pos = end
+		return true
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	depth, rowIndex, colIndex, nextRow := 0, -1, -1, 0
+	inSheetData, emptySheetData, inChangedRow, emptyRow := false, false, false, false
+	var pending []int
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 2 && t.Name.Local == "dimension":
+				copyTo(offset)
+				end := decoder.InputOffset()
+				tag := data[offset:end]
+				if ref := attrValue(t, "ref"); ref != "" {
+					if extended := extendDimension(ref, sheet, positions); extended != ref {
+						tag = bytes.Replace(tag, []byte(ref), []byte(extended), 1)
+					}
+				}
+				out.Write(tag)
+				pos = end
+			case depth == 2 && t.Name.Local == "sheetData":
+				if t.Name.Space != "" {
+					return nil, false, nil
+				}
+				inSheetData = true
+				emptySheetData = openTag(offset, decoder.InputOffset())
+			case depth == 3 && inSheetData && t.Name.Local == "row":
+				rowIndex++
+				if ref := attrValue(t, "r"); ref != "" {
+					if rowIndex, err = strconv.Atoi(ref); err != nil {
+						return nil, false, err
+					}
+					rowIndex--
+				}
+				copyTo(offset)
+				for ; nextRow < len(rows) && rows[nextRow] < rowIndex; nextRow++ {
+					if err := writeRow(rows[nextRow]); err != nil {
+						return nil, false, err
+					}
+				}
+				if nextRow < len(rows) && rows[nextRow] == rowIndex {
+					nextRow++
+					pending, inChangedRow, colIndex = cols[rowIndex], true, -1
+					emptyRow = openTag(offset, decoder.InputOffset())
+				}
+			case depth == 4 && inChangedRow && t.Name.Local == "c":
+				colIndex++
+				if ref := attrValue(t, "r"); ref != "" {
+					if colIndex, _, err = GetCoordsFromCellIDString(ref); err != nil {
+						return nil, false, err
+					}
+				}
+				copyTo(offset)
+				for ; len(pending) > 0 && pending[0] < colIndex; pending = pending[1:] {
+					if err := writeCell(rowIndex, pending[0], 0); err != nil {
+						return nil, false, err
+					}
+				}
+				if len(pending) > 0 && pending[0] == colIndex {
+					xfId, _ := strconv.Atoi(attrValue(t, "s"))
+					shared, err := skipCellElement(decoder)
+					if shared || err != nil {
+						return nil, false, err
+					}
+					depth--
+					pos = decoder.InputOffset()
+					if err := writeCell(rowIndex, colIndex, xfId); err != nil {
+						return nil, false, err
+					}
+					pending = pending[1:]
+				}
+			}
+		case xml.EndElement:
+			switch {
+			case depth == 3 && inChangedRow:
+				copyTo(offset)
+				for _, c := range pending {
+					if err := writeCell(rowIndex, c, 0); err != nil {
+						return nil, false, err
+					}
+				}
+				if emptyRow {
+					out.WriteString("</row>")
+				}
+				pending, inChangedRow = nil, false
+			case depth == 2 && inSheetData:
+				copyTo(offset)
+				for ; nextRow < len(rows); nextRow++ {
+					if err := writeRow(rows[nextRow]); err != nil {
+						return nil, false, err
+					}
+				}
+				if emptySheetData {
+					out.WriteString("</sheetData>")
+				}
+				inSheetData = false
+			}
+			depth--
+		}
+	}
+	copyTo(int64(len(data)))
+	return out.Bytes(), true, nil
+}
+
+// extendDimension returns ref, the dimension of the worksheet read for
+// sheet, extended to the cells at positions that sheet has.
+func extendDimension(ref string, sheet *Sheet, positions []cellPosition) string {
+	cells := ref
+	if !strings.Contains(cells, cellRangeChar) {
+		cells += cellRangeChar + cells
+	}
+	minCol, minRow, maxCol, maxRow, err := getMaxMinFromDimensionRef(cells)
+	if err != nil {
+		return ref
+	}
+	extended := false
+	for _, p := range positions {
+		row := sheet.row(p.row)
+		if row == nil || p.col >= len(row.Cells) || row.Cells[p.col] == nil {
+			continue
+		}
+		if p.col < minCol || p.row < minRow || p.col > maxCol || p.row > maxRow {
+			extended = true
+		}
+		if p.col < minCol {
+			minCol = p.col
+		}
+		if p.row < minRow {
+			minRow = p.row
+		}
+		if p.col > maxCol {
+			maxCol = p.col
+		}
+		if p.row > maxRow {
+			maxRow = p.row
+		}
+	}
+	if !extended {
+		return ref
+	}
+	return GetCellIDStringFromCoords(minCol, minRow) + cellRangeChar + GetCellIDStringFromCoords(maxCol, maxRow)
+}
+
+// skipCellElement skips the rest of the c element whose start tag was
+// just read, returning whether it holds the master formula of a shared
+// formula, the f element with the range of the cells sharing it.
+func skipCellElement(decoder *xml.Decoder) (bool, error) {
+	shared := false
+	for depth := 1; depth > 0; {
+		token, err := decoder.RawToken()
+		if err != nil {
+			return false, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 2 && t.Name.Local == "f" && attrValue(t, "t") == "shared" && attrValue(t, "ref") != "" {
+				shared = true
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return shared, nil
+}
+
+// attrValue returns the value of the attribute of start with the given
+// local name, or "".
+func attrValue(start xml.StartElement, name string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == name && attr.Name.Space == "" {
+			return attr.Value
+		}
+	}
+	return ""
+}
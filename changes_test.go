@@ -0,0 +1,251 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type ChangesSuite struct{}
+
+var _ = Suite(&ChangesSuite{})
+
+// changesAppXML is the extended properties of the file of changesXLSX,
+// which writing the whole File replaces.
+const changesAppXML = `<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties"><Application>Tracked</Application></Properties>`
+
+// changesXLSX returns an XLSX file of two sheets of two rows.
+func changesXLSX(c *C) []byte {
+	file := NewFile()
+	for _, name := range []string{"Edited", "Untouched"} {
+		sheet, err := file.AddSheet(name)
+		c.Assert(err, IsNil)
+		row := sheet.AddRow()
+		row.AddCell().Value = name
+		row.AddCell().SetInt(1)
+		row = sheet.AddRow()
+		row.AddCell().Value = "second"
+		row.AddCell().SetFormula("B1*2")
+	}
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	parts["docProps/app.xml"] = changesAppXML
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for name, data := range parts {
+		w, err := writer.Create(name)
+		c.Assert(err, IsNil)
+		_, err = w.Write([]byte(data))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(writer.Close(), IsNil)
+	return buffer.Bytes()
+}
+
+func (s *ChangesSuite) TestChanges(c *C) {
+	file, err := OpenBinaryWithOptions(changesXLSX(c))
+	c.Assert(err, IsNil)
+	c.Assert(file.Changes(), IsNil)
+
+	file, err = OpenBinaryWithOptions(changesXLSX(c), WithChangeTracking(true))
+	c.Assert(err, IsNil)
+	c.Assert(file.Changes(), HasLen, 0)
+	sheet := file.Sheets[0]
+	sheet.Cell(0, 0).Value = "Edited"
+	c.Assert(file.Changes(), HasLen, 0)
+	sheet.Cell(0, 0).SetString("changed")
+	sheet.Cell(1, 1).SetFormula("B1*3")
+	sheet.Rows[0].AddCell().SetInt(3)
+	sheet.Rows[1].Cells = sheet.Rows[1].Cells[:1]
+	added, err := file.AddSheet("Added")
+	c.Assert(err, IsNil)
+	added.AddRow().AddCell().Value = "new"
+	c.Assert(file.Changes(), DeepEquals, []CellChange{
+		{Sheet: "Edited", Row: 0, Col: 0, Kind: CellModified},
+		{Sheet: "Edited", Row: 0, Col: 2, Kind: CellAdded},
+		{Sheet: "Edited", Row: 1, Col: 1, Kind: CellRemoved},
+		{Sheet: "Added", Row: 0, Col: 0, Kind: CellAdded},
+	})
+
+	file.Sheets = file.Sheets[:1]
+	sheet.Cell(0, 0).SetString("Edited")
+	c.Assert(file.Changes(), DeepEquals, []CellChange{
+		{Sheet: "Edited", Row: 0, Col: 2, Kind: CellAdded},
+		{Sheet: "Edited", Row: 1, Col: 1, Kind: CellRemoved},
+		{Sheet: "Untouched", Row: 0, Col: 0, Kind: CellRemoved},
+		{Sheet: "Untouched", Row: 0, Col: 1, Kind: CellRemoved},
+		{Sheet: "Untouched", Row: 1, Col: 0, Kind: CellRemoved},
+		{Sheet: "Untouched", Row: 1, Col: 1, Kind: CellRemoved},
+	})
+}
+
+func (s *ChangesSuite) TestMinimalRewrite(c *C) {
+	data := changesXLSX(c)
+	_, source := readOptionsParts(c, data)
+	file, err := OpenBinaryWithOptions(data, WithChangeTracking(true))
+	c.Assert(err, IsNil)
+	sheet := file.Sheets[0]
+	sheet.Cell(0, 0).SetString("changed & more")
+	sheet.Cell(1, 1).SetFormula("B1*3")
+	sheet.Rows[0].AddCell().SetInt(3)
+	sheet.AddRow()
+	sheet.AddRow().AddCell().SetString("fourth")
+
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer, WithMinimalRewrite(true)), IsNil)
+	_, contents := readOptionsParts(c, buffer.Bytes())
+	c.Assert(contents, HasLen, len(source))
+	for name, part := range source {
+		if name != "xl/worksheets/sheet1.xml" {
+			c.Assert(contents[name], Equals, part)
+		}
+	}
+	edited := contents["xl/worksheets/sheet1.xml"]
+	c.Assert(strings.Contains(edited, `<c r="A1" s="1" t="inlineStr"><is><t>changed &amp; more</t></is></c>`), Equals, true)
+	c.Assert(strings.Contains(edited, `<dimension ref="A1:C4"></dimension>`), Equals, true)
+	c.Assert(strings.Contains(edited, `<c r="C1"><v>3</v></c></row>`), Equals, true)
+	c.Assert(strings.Contains(edited, `<f>B1*3</f>`), Equals, true)
+	c.Assert(strings.Contains(edited, `<row r="4"><c r="A4" t="inlineStr"><is><t>fourth</t></is></c></row></sheetData>`), Equals, true)
+
+	read, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(read.Sheets[0].Cell(0, 0).Value, Equals, "changed & more")
+	c.Assert(read.Sheets[0].Cell(0, 1).Value, Equals, "1")
+	c.Assert(read.Sheets[0].Cell(0, 2).Value, Equals, "3")
+	c.Assert(read.Sheets[0].Cell(1, 1).Formula(), Equals, "B1*3")
+	c.Assert(read.Sheets[0].Cell(3, 0).Value, Equals, "fourth")
+	c.Assert(read.Sheets[1].Cell(0, 0).Value, Equals, "Untouched")
+}
+
+func (s *ChangesSuite) TestMinimalRewriteFallsBack(c *C) {
+	file, err := OpenBinaryWithOptions(changesXLSX(c), WithChangeTracking(true))
+	c.Assert(err, IsNil)
+	file.Sheets[0].Cell(0, 0).SetString("changed")
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer, WithMinimalRewrite(true)), IsNil)
+	_, contents := readOptionsParts(c, buffer.Bytes())
+	c.Assert(contents["docProps/app.xml"], Equals, changesAppXML)
+
+	style := NewStyle()
+	style.Font.Bold = true
+	file.Sheets[0].Cell(0, 0).SetStyle(style)
+	buffer.Reset()
+	c.Assert(file.Write(&buffer, WithMinimalRewrite(true)), IsNil)
+	_, contents = readOptionsParts(c, buffer.Bytes())
+	c.Assert(contents["docProps/app.xml"], Not(Equals), changesAppXML)
+	reopened, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(reopened.Sheets[0].Cell(0, 0).GetStyle().Font.Bold, Equals, true)
+
+	file, err = OpenBinaryWithOptions(changesXLSX(c), WithChangeTracking(true))
+	c.Assert(err, IsNil)
+	file.Sheets[1].Cols[0].Width = 30
+	buffer.Reset()
+	c.Assert(file.Write(&buffer, WithMinimalRewrite(true)), IsNil)
+	_, contents = readOptionsParts(c, buffer.Bytes())
+	c.Assert(contents["docProps/app.xml"], Not(Equals), changesAppXML)
+
+	file, err = OpenBinaryWithOptions(changesXLSX(c))
+	c.Assert(err, IsNil)
+	c.Assert(file.Write(&buffer, WithMinimalRewrite(true)), ErrorMatches, "a minimal rewrite needs a File opened WithChangeTracking")
+	_, err = NewStreamFileBuilderWithOptions(&buffer, WithMinimalRewrite(true))
+	c.Assert(err, ErrorMatches, "a minimal rewrite only applies to a File opened WithChangeTracking")
+}
+
+func (s *ChangesSuite) TestSaveOverSource(c *C) {
+	path := filepath.Join(c.MkDir(), "tracked.xlsx")
+	file, err := OpenBinary(changesXLSX(c))
+	c.Assert(err, IsNil)
+	c.Assert(file.Save(path), IsNil)
+
+	file, err = OpenFileWithOptions(path, WithChangeTracking(true))
+	c.Assert(err, IsNil)
+	file.Sheets[1].Cell(1, 0).SetString("saved")
+	c.Assert(file.Save(path, WithMinimalRewrite(true)), IsNil)
+	c.Assert(file.Changes(), HasLen, 0)
+	file.Sheets[1].Cell(1, 0).SetString("saved again")
+	c.Assert(file.Changes(), HasLen, 1)
+	c.Assert(file.Save(path, WithMinimalRewrite(true)), IsNil)
+
+	read, err := OpenFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(read.Sheets[1].Cell(1, 0).Value, Equals, "saved again")
+	c.Assert(read.Sheets[1].Cell(1, 1).Formula(), Equals, "B1*2")
+	c.Assert(read.Sheets[0].Cell(0, 0).Value, Equals, "Edited")
+}
+
+func (s *ChangesSuite) TestMinimalRewriteDropsCalcId(c *C) {
+	data := unknownPartsXLSX(c, func(parts map[string]string) {
+		parts["xl/workbook.xml"] = strings.Replace(parts["xl/workbook.xml"], `<calcPr `, `<calcPr calcId="191029" `, 1)
+	})
+	file, err := OpenBinaryWithOptions(data, WithChangeTracking(true))
+	c.Assert(err, IsNil)
+	c.Assert(file.CalcProperties.CalcId, Equals, "191029")
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer, WithMinimalRewrite(true)), IsNil)
+	_, contents := readOptionsParts(c, buffer.Bytes())
+	c.Assert(strings.Contains(contents["xl/workbook.xml"], `calcId=`), Equals, true)
+
+	// Once cells change, the workbook is recalculated when opened.
+	file.Sheets[0].Cell(0, 0).SetString("changed")
+	buffer.Reset()
+	c.Assert(file.Write(&buffer, WithMinimalRewrite(true)), IsNil)
+	_, contents = readOptionsParts(c, buffer.Bytes())
+	c.Assert(strings.Contains(contents["xl/workbook.xml"], `<calcPr `), Equals, true)
+	c.Assert(strings.Contains(contents["xl/workbook.xml"], `calcId=`), Equals, false)
+	buffer.Reset()
+	c.Assert(file.Write(&buffer), IsNil)
+	_, contents = readOptionsParts(c, buffer.Bytes())
+	c.Assert(strings.Contains(contents["xl/workbook.xml"], `calcId=`), Equals, false)
+}
+
+// sharedFormulaXLSX returns a workbook whose cells B1:B3 share the
+// formula of B1, A1*2.
+func sharedFormulaXLSX(c *C) []byte {
+	return unknownPartsXLSX(c, func(parts map[string]string) {
+		sheet := parts["xl/worksheets/sheet1.xml"]
+		start, end := strings.Index(sheet, "<sheetData"), strings.Index(sheet, "</sheetData>")+len("</sheetData>")
+		parts["xl/worksheets/sheet1.xml"] = sheet[:start] + `<sheetData>` +
+			`<row r="1"><c r="A1"><v>1</v></c><c r="B1"><f t="shared" ref="B1:B3" si="0">A1*2</f><v>2</v></c></row>` +
+			`<row r="2"><c r="A2"><v>2</v></c><c r="B2"><f t="shared" si="0"/><v>4</v></c></row>` +
+			`<row r="3"><c r="A3"><v>3</v></c><c r="B3"><f t="shared" si="0"/><v>6</v></c></row>` +
+			`</sheetData>` + sheet[end:]
+		parts["xl/worksheets/sheet1.xml"] = strings.Replace(parts["xl/worksheets/sheet1.xml"], `ref="A1:A2"`, `ref="A1:B3"`, 1)
+	})
+}
+
+func (s *ChangesSuite) TestMinimalRewriteOfSharedFormula(c *C) {
+	file, err := OpenBinaryWithOptions(sharedFormulaXLSX(c), WithChangeTracking(true))
+	c.Assert(err, IsNil)
+	c.Assert(file.Sheets[0].Cell(1, 1).Formula(), Equals, "A2*2")
+	// Changing the cell holding the formula shared with B2:B3 mustn't
+	// leave them without it.
+	file.Sheets[0].Cell(0, 1).SetFormula("A1*3")
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer, WithMinimalRewrite(true)), IsNil)
+	file, err = OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	sheet := file.Sheets[0]
+	c.Assert(sheet.Cell(0, 1).Formula(), Equals, "A1*3")
+	c.Assert(sheet.Cell(1, 1).Formula(), Equals, "A2*2")
+	c.Assert(sheet.Cell(2, 1).Formula(), Equals, "A3*2")
+
+	// Changing a cell sharing the formula of another one leaves the
+	// others as they were.
+	file, err = OpenBinaryWithOptions(sharedFormulaXLSX(c), WithChangeTracking(true))
+	c.Assert(err, IsNil)
+	file.Sheets[0].Cell(1, 1).SetFormula("A2*5")
+	buffer.Reset()
+	c.Assert(file.Write(&buffer, WithMinimalRewrite(true)), IsNil)
+	_, contents := readOptionsParts(c, buffer.Bytes())
+	c.Assert(strings.Contains(contents["xl/worksheets/sheet1.xml"], `<f t="shared" ref="B1:B3" si="0">A1*2</f>`), Equals, true)
+	file, err = OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	sheet = file.Sheets[0]
+	c.Assert(sheet.Cell(0, 1).Formula(), Equals, "A1*2")
+	c.Assert(sheet.Cell(1, 1).Formula(), Equals, "A2*5")
+	c.Assert(sheet.Cell(2, 1).Formula(), Equals, "A3*2")
+}
@@ -4,6 +4,7 @@ package xlsx
 const ColWidth = 9.5
 const Excel2006MaxRowCount = 1048576
 const Excel2006MaxRowIndex = Excel2006MaxRowCount - 1
+const Excel2006MaxColumnCount = MaxColIndex + 1
 
 type Col struct {
 	Min            int
@@ -16,6 +17,9 @@ type Col struct {
 	parsedNumFmt   *parsedNumberFormat
 	style          *Style
 	DataValidation []*xlsxCellDataValidation
+	// inherited is the style of the column read from a file, taken by
+	// the cells without a style of their own, see Cell.EffectiveStyle.
+	inherited *inheritedStyle
 }
 
 // SetType will set the format string of a column based on the type that you want to set it to.
@@ -0,0 +1,32 @@
+package xlsx
+
+import "fmt"
+
+// Excel reads at most Excel2006MaxColumnCount columns per sheet, and
+// silently drops the cells past them, so a File holding wider sheets
+// can't be saved.  A StreamFileBuilder can instead continue the
+// columns of a wide sheet on follow-on sheets, see
+// StreamFileBuilder.SetSplitWideSheets.
+
+// ColumnLimitError is returned when a sheet has more columns than
+// Excel can read.
+type ColumnLimitError struct {
+	Sheet   string
+	Columns int
+}
+
+// Error returns a description of the ColumnLimitError.
+func (e *ColumnLimitError) Error() string {
+	return fmt.Sprintf("the sheet %q has %d columns, more than the %d that Excel can read", e.Sheet, e.Columns, Excel2006MaxColumnCount)
+}
+
+// checkColumnLimit returns a ColumnLimitError for the first sheet of
+// the File having more columns than Excel can read.
+func (f *File) checkColumnLimit() error {
+	for _, sheet := range f.Sheets {
+		if sheet.MaxCol > Excel2006MaxColumnCount {
+			return &ColumnLimitError{Sheet: sheet.Name, Columns: sheet.MaxCol}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type ColumnLimitSuite struct{}
+
+var _ = Suite(&ColumnLimitSuite{})
+
+func (s *ColumnLimitSuite) TestTooManyColumns(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Wide")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, Excel2006MaxColumnCount-1).SetString("last")
+	_, err = f.MarshallParts()
+	c.Assert(err, IsNil)
+
+	sheet.Cell(0, Excel2006MaxColumnCount).SetString("dropped")
+	_, err = f.MarshallParts()
+	c.Assert(err, NotNil)
+	limitErr, ok := err.(*ColumnLimitError)
+	c.Assert(ok, Equals, true)
+	c.Assert(limitErr.Sheet, Equals, "Wide")
+	c.Assert(limitErr.Columns, Equals, Excel2006MaxColumnCount+1)
+	c.Assert(err.Error(), Equals, `the sheet "Wide" has 16385 columns, more than the 16384 that Excel can read`)
+}
@@ -0,0 +1,88 @@
+package xlsx
+
+// Readers other than Excel, such as openpyxl and pandas, Google Sheets
+// or Numbers, support the core of the format but may fail on the
+// parts and extensions that Excel added over the years.  A File that
+// is Compatible is saved without them, leaving out or simplifying the
+// features listed by CompatibilityDowngrades.
+
+// Downgrade describes a feature of a File that is left out, or written
+// in a simpler form, when the File is saved Compatible.
+type Downgrade struct {
+	// Where is "workbook" or the name of a sheet.
+	Where string
+	// Feature describes the feature and what becomes of it, such as
+	// "sparklines are left out".
+	Feature string
+}
+
+// String returns a description of the Downgrade.
+func (d Downgrade) String() string {
+	return d.Where + ": " + d.Feature
+}
+
+// CompatibilityDowngrades returns the features of the File that are
+// left out or simplified when it is saved with Compatible or
+// NumbersCompatible set, so that they can be reported.
+func (f *File) CompatibilityDowngrades() []Downgrade {
+	var downgrades []Downgrade
+	for _, name := range f.RawPartNames() {
+		downgrades = append(downgrades, Downgrade{"workbook", "the part " + name + " is left out"})
+	}
+	if len(f.rawWorkbookExtensions) > 0 {
+		downgrades = append(downgrades, Downgrade{"workbook", "the extensions of the workbook, such as slicers, are left out"})
+	}
+	for _, sheet := range f.Sheets {
+		if len(sheet.SparklineGroups) > 0 {
+			downgrades = append(downgrades, Downgrade{sheet.Name, "sparklines are left out"})
+		}
+		if len(sheet.rawExtensions) > 0 {
+			downgrades = append(downgrades, Downgrade{sheet.Name, "the extensions of the sheet are left out"})
+		}
+		if sheet.rawTableParts != nil {
+			downgrades = append(downgrades, Downgrade{sheet.Name, "tables are left out"})
+		}
+		if sheet.rawDrawing != nil {
+			downgrades = append(downgrades, Downgrade{sheet.Name, "images and charts are left out"})
+		}
+		if sheet.rawLegacyDrawing != nil {
+			downgrades = append(downgrades, Downgrade{sheet.Name, "notes are left out"})
+		}
+		if sheet.hasDynamicArrayFormulas() {
+			downgrades = append(downgrades, Downgrade{sheet.Name, "dynamic array formulas are written as array formulas"})
+		}
+	}
+	if f.NumbersCompatible {
+		downgrades = append(downgrades, f.numbersDowngrades()...)
+	}
+	return downgrades
+}
+
+// hasDynamicArrayFormulas returns true if a cell of the Sheet holds a
+// dynamic array formula.
+func (s *Sheet) hasDynamicArrayFormulas() bool {
+	for _, row := range s.rowList() {
+		if row == nil {
+			continue
+		}
+		row.load()
+		for _, cell := range row.Cells {
+			if cell != nil && cell.IsDynamicArrayFormula() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// externalRelationships returns those of rels that point outside the
+// package, such as the targets of hyperlinks.
+func externalRelationships(rels []xlsxWorkbookRelation) []xlsxWorkbookRelation {
+	var external []xlsxWorkbookRelation
+	for _, rel := range rels {
+		if rel.TargetMode == "External" {
+			external = append(external, rel)
+		}
+	}
+	return external
+}
@@ -1,6 +1,11 @@
 package xlsx
 
-import . "gopkg.in/check.v1"
+import (
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
 
 type GoogleDocsExcelSuite struct{}
 
@@ -93,3 +98,142 @@ func (w *WpsBlankLineSuite) TestWpsBlankLine(c *C) {
 	}
 	c.Assert(val, Equals, expected)
 }
+
+type CompatibleSuite struct{}
+
+var _ = Suite(&CompatibleSuite{})
+
+func (s *CompatibleSuite) makeFile(c *C) *File {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sales")
+	c.Assert(err, IsNil)
+	row := sheet.AddRow()
+	row.AddCell().SetInt(1)
+	row.AddCell().SetDynamicArrayFormula("SEQUENCE(3)", "B1:B3")
+	_, err = sheet.AddExternalHyperlink("A1", "https://example.com/")
+	c.Assert(err, IsNil)
+	group := NewSparklineGroup(SparklineTypeLine)
+	group.Add("C1", "Sales!A1:A3")
+	c.Assert(sheet.AddSparklineGroup(group), IsNil)
+	c.Assert(f.SetRawPart("xl/vbaProject.bin", []byte("macros")), IsNil)
+	return f
+}
+
+func (s *CompatibleSuite) TestCompatibilityDowngrades(c *C) {
+	f := s.makeFile(c)
+	c.Assert(f.CompatibilityDowngrades(), DeepEquals, []Downgrade{
+		{"workbook", "the part xl/vbaProject.bin is left out"},
+		{"Sales", "sparklines are left out"},
+		{"Sales", "dynamic array formulas are written as array formulas"},
+	})
+	c.Assert(f.CompatibilityDowngrades()[1].String(), Equals, "Sales: sparklines are left out")
+}
+
+func (s *CompatibleSuite) TestCompatible(c *C) {
+	f := s.makeFile(c)
+	f.Compatible = true
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	_, ok := parts["xl/vbaProject.bin"]
+	c.Assert(ok, Equals, false)
+	_, ok = parts["xl/metadata.xml"]
+	c.Assert(ok, Equals, false)
+	worksheet := parts["xl/worksheets/sheet1.xml"]
+	c.Assert(strings.Contains(worksheet, "<extLst>"), Equals, false)
+	c.Assert(strings.Contains(worksheet, `cm="`), Equals, false)
+	c.Assert(strings.Contains(worksheet, `<f t="array" ref="B1:B3">`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/worksheets/_rels/sheet1.xml.rels"], "https://example.com/"), Equals, true)
+
+	var buffer bytes.Buffer
+	c.Assert(f.Write(&buffer), IsNil)
+	read, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(read.RawPartNames(), HasLen, 0)
+	c.Assert(read.Sheets[0].Hyperlinks[0].Target, Equals, "https://example.com/")
+
+	// The File itself is unchanged.
+	f.Compatible = false
+	parts, err = f.MarshallParts()
+	c.Assert(err, IsNil)
+	_, ok = parts["xl/vbaProject.bin"]
+	c.Assert(ok, Equals, true)
+}
+
+type NumbersCompatibleSuite struct{}
+
+var _ = Suite(&NumbersCompatibleSuite{})
+
+func (s *NumbersCompatibleSuite) makeFile(c *C) *File {
+	f := NewFile()
+	sheet, err := f.AddSheet("Prices")
+	c.Assert(err, IsNil)
+	sheet.SheetViews = []SheetView{{Pane: &Pane{YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft", State: "frozen"}}}
+	row := sheet.AddRow()
+	cell := row.AddCell()
+	cell.SetString("  Price ")
+	style := NewStyle()
+	style.Font.Name = "Calibri"
+	cell.SetStyle(style)
+	row.AddCell().SetString("")
+	row.AddCell().SetString("Total")
+	return f
+}
+
+func (s *NumbersCompatibleSuite) TestNumbersDowngrades(c *C) {
+	f := s.makeFile(c)
+	c.Assert(f.CompatibilityDowngrades(), HasLen, 0)
+	f.NumbersCompatible = true
+	c.Assert(f.CompatibilityDowngrades(), DeepEquals, []Downgrade{
+		{"Prices", "the font Calibri is replaced by Arial, or the font of WithNumbersFont"},
+	})
+}
+
+func (s *NumbersCompatibleSuite) TestNumbersCompatible(c *C) {
+	f := s.makeFile(c)
+	f.NumbersCompatible = true
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/styles.xml"], "Calibri"), Equals, false)
+	c.Assert(strings.Contains(parts["xl/styles.xml"], `<name val="Arial"/>`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/sharedStrings.xml"], `<t xml:space="preserve">  Price </t>`), Equals, true)
+	worksheet := parts["xl/worksheets/sheet1.xml"]
+	c.Assert(strings.Contains(worksheet, `<c r="B1" s="1"></c>`), Equals, true)
+	c.Assert(strings.Contains(worksheet, `<selection pane="bottomLeft" activeCell="A2" activeCellId="0" sqref="A2">`), Equals, true)
+
+	var buffer bytes.Buffer
+	c.Assert(f.Write(&buffer), IsNil)
+	read, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(read.Sheets[0].Cell(0, 0).Value, Equals, "  Price ")
+	c.Assert(read.Sheets[0].Cell(0, 1).Value, Equals, "")
+	c.Assert(read.Sheets[0].Cell(0, 2).Value, Equals, "Total")
+
+	// The fonts are replaced in the file written only, by the font
+	// given as an option if any.
+	c.Assert(f.styles.Fonts.Font[len(f.styles.Fonts.Font)-1].Name.Val, Equals, "Calibri")
+	buffer.Reset()
+	c.Assert(f.Write(&buffer, WithNumbersFont("Helvetica")), IsNil)
+	_, contents := readOptionsParts(c, buffer.Bytes())
+	c.Assert(strings.Contains(contents["xl/styles.xml"], "Calibri"), Equals, false)
+	c.Assert(strings.Contains(contents["xl/styles.xml"], `<name val="Helvetica"/>`), Equals, true)
+	c.Assert(WithNumbersFont("")(&writeOptions{}), ErrorMatches, "the font replacing those missing on Macs needs a name")
+
+	var streamed bytes.Buffer
+	builder, err := NewStreamFileBuilderWithOptions(&streamed, WithDefaultFont(11, "Calibri"), WithNumbersFont("Helvetica"))
+	c.Assert(err, IsNil)
+	c.Assert(builder.SetNumbersCompatible(true), IsNil)
+	c.Assert(builder.AddSheet("Prices", []string{"Price"}, nil), IsNil)
+	stream, err := builder.Build()
+	c.Assert(err, IsNil)
+	c.Assert(stream.Close(), IsNil)
+	_, contents = readOptionsParts(c, streamed.Bytes())
+	c.Assert(strings.Contains(contents["xl/styles.xml"], "Calibri"), Equals, false)
+	c.Assert(strings.Contains(contents["xl/styles.xml"], `<name val="Helvetica"/>`), Equals, true)
+
+	// Without the profile, the fonts and the empty string are kept.
+	f.NumbersCompatible = false
+	parts, err = f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/styles.xml"], "Calibri"), Equals, true)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `<c r="B1" s="1" t="s"></c>`), Equals, true)
+}
@@ -0,0 +1,152 @@
+package xlsx
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateAmbiguity is the way ParseDate reads the dates whose day and
+// month could each be the other, such as 3/4/2021, which is the 3rd of
+// April in most of the world and March the 4th in the United States.
+type DateAmbiguity int
+
+const (
+	// DateAmbiguityRejected makes ParseDate fail with an
+	// *AmbiguousDateError, so that the caller can ask which was meant.
+	DateAmbiguityRejected DateAmbiguity = iota
+	// DateAmbiguityDayFirst reads 3/4/2021 as the 3rd of April.
+	DateAmbiguityDayFirst
+	// DateAmbiguityMonthFirst reads 3/4/2021 as March the 4th.
+	DateAmbiguityMonthFirst
+)
+
+// AmbiguousDateError is returned by ParseDate for a date whose day and
+// month could each be the other, when the DateAmbiguity is
+// DateAmbiguityRejected.
+type AmbiguousDateError struct {
+	Value string
+}
+
+// Error returns a description of the AmbiguousDateError.
+func (e *AmbiguousDateError) Error() string {
+	return fmt.Sprintf("the date %q may be read day first or month first", e.Value)
+}
+
+// numericDate matches the dates written with digits only, such as
+// 3/4/2021, 03.04.21 or 2021-03-04, along with their time of day.
+var numericDate = regexp.MustCompile(`^(\d{1,4})[/.-](\d{1,2})[/.-](\d{1,4})(?:[ T](\d{1,2}):(\d{2})(?::(\d{2}))?)?$`)
+
+// isoDateLayouts are the layouts of the ISO 8601 dates read by
+// ParseDate besides those matched by numericDate.
+var isoDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+// namedMonthDateLayouts are the layouts of the dates giving the name of
+// their month read by ParseDate, which are never ambiguous.
+var namedMonthDateLayouts = []string{
+	"2 Jan 2006",
+	"2 January 2006",
+	"Jan 2, 2006",
+	"January 2, 2006",
+	"Jan 2 2006",
+	"January 2 2006",
+	"2-Jan-2006",
+	"2-Jan-06",
+}
+
+// ParseDate returns the date held by value, such as a cell of a column of dates filled in by hand or by different
+// tools, which may be a serial number like those Excel stores, 44259 being the 4th of March 2021, an ISO 8601 date
+// such as 2021-03-04 or 2021-03-04T14:30:00Z, a date written with digits such as 3/4/2021, 03.04.21 or 3-4-2021,
+// optionally followed by a time of day such as 14:30, or a date naming its month such as 4 Mar 2021 or March 4, 2021.
+// Dates written with digits are read day first or month first as ambiguity says when their day and month could each be
+// the other, and otherwise whichever way is valid. Years of two digits are those from 1930 to 2029, like Excel reads
+// them. Any number, even one such as 2021, is read as a serial number, as Excel does in workbooks using the 1900 date
+// system, or the 1904 date system if date1904 is true. The dates without a time zone are in UTC.
+func ParseDate(value string, date1904 bool, ambiguity DateAmbiguity) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if serial, err := strconv.ParseFloat(value, 64); err == nil {
+		if serial < 0 || math.IsInf(serial, 0) || math.IsNaN(serial) {
+			return time.Time{}, fmt.Errorf("%q isn't a date", value)
+		}
+		return TimeFromExcelTime(serial, date1904), nil
+	}
+	if match := numericDate.FindStringSubmatch(value); match != nil {
+		return parseNumericDate(value, match, ambiguity)
+	}
+	for _, layouts := range [][]string{isoDateLayouts, namedMonthDateLayouts} {
+		for _, layout := range layouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q isn't a date", value)
+}
+
+// parseNumericDate returns the date written with digits value, whose
+// parts were matched by numericDate.
+func parseNumericDate(value string, match []string, ambiguity DateAmbiguity) (time.Time, error) {
+	parts := make([]int, len(match)-1)
+	for i, part := range match[1:] {
+		// The parts are digits, if not left out.
+		parts[i], _ = strconv.Atoi(part)
+	}
+	var year, month, day int
+	switch {
+	case len(match[1]) > 2:
+		year, month, day = parts[0], parts[1], parts[2]
+		if len(match[1]) != 4 || len(match[3]) > 2 {
+			return time.Time{}, fmt.Errorf("%q isn't a date", value)
+		}
+	case len(match[3]) == 4 || len(match[3]) == 2:
+		year = parts[2]
+		if len(match[3]) == 2 {
+			year += 1900
+			if year < 1930 {
+				year += 100
+			}
+		}
+		dayFirst := parts[0] > 12
+		if parts[0] <= 12 && parts[1] <= 12 && parts[0] != parts[1] {
+			switch ambiguity {
+			case DateAmbiguityDayFirst:
+				dayFirst = true
+			case DateAmbiguityMonthFirst:
+				dayFirst = false
+			default:
+				return time.Time{}, &AmbiguousDateError{Value: value}
+			}
+		}
+		if dayFirst {
+			day, month = parts[0], parts[1]
+		} else {
+			month, day = parts[0], parts[1]
+		}
+	default:
+		return time.Time{}, fmt.Errorf("%q isn't a date", value)
+	}
+	hour, minute, second := parts[3], parts[4], parts[5]
+	t := time.Date(year, time.Month(month), day, hour, minute, second, 0, time.UTC)
+	if t.Year() != year || int(t.Month()) != month || t.Day() != day || t.Hour() != hour || t.Minute() != minute ||
+		t.Second() != second {
+		return time.Time{}, fmt.Errorf("%q isn't a date", value)
+	}
+	return t, nil
+}
+
+// Date returns the date held by the cell, which may be a serial number, whatever the number format of the cell, or
+// a date written as a string in any of the ways ParseDate reads, such as in a column of dates filled in by hand.
+// Serial numbers are read in the date system of the workbook of the cell.
+func (c *Cell) Date(ambiguity DateAmbiguity) (time.Time, error) {
+	c.attach()
+	return ParseDate(c.Value, c.date1904, ambiguity)
+}
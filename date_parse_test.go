@@ -0,0 +1,67 @@
+package xlsx
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type DateParseSuite struct{}
+
+var _ = Suite(&DateParseSuite{})
+
+func (s *DateParseSuite) TestParseDate(c *C) {
+	march4 := time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)
+	for _, value := range []string{"44259", " 44259 ", "2021-03-04", "2021/3/4", "2021-03-04T00:00:00Z", "4 Mar 2021",
+		"March 4, 2021", "4-Mar-21", "4/3/2021", "4.3.21"} {
+		t, err := ParseDate(value, false, DateAmbiguityDayFirst)
+		c.Assert(err, IsNil)
+		c.Assert(t.Equal(march4), Equals, true)
+	}
+	t, err := ParseDate("42797", true, DateAmbiguityRejected)
+	c.Assert(err, IsNil)
+	c.Assert(t, Equals, march4)
+	t, err = ParseDate("44259.5", false, DateAmbiguityRejected)
+	c.Assert(err, IsNil)
+	c.Assert(t, Equals, march4.Add(12*time.Hour))
+	t, err = ParseDate("2021-03-04 14:30", false, DateAmbiguityRejected)
+	c.Assert(err, IsNil)
+	c.Assert(t, Equals, time.Date(2021, 3, 4, 14, 30, 0, 0, time.UTC))
+	t, err = ParseDate("3/4/2021 14:30:15", false, DateAmbiguityMonthFirst)
+	c.Assert(err, IsNil)
+	c.Assert(t, Equals, time.Date(2021, 3, 4, 14, 30, 15, 0, time.UTC))
+	t, err = ParseDate("2021-03-04T14:30:00+01:00", false, DateAmbiguityRejected)
+	c.Assert(err, IsNil)
+	c.Assert(t.UTC(), Equals, time.Date(2021, 3, 4, 13, 30, 0, 0, time.UTC))
+
+	// The day and month that can't be each other aren't ambiguous.
+	for _, value := range []string{"25/12/2021", "12/25/2021", "12/25/21"} {
+		t, err := ParseDate(value, false, DateAmbiguityRejected)
+		c.Assert(err, IsNil)
+		c.Assert(t, Equals, time.Date(2021, 12, 25, 0, 0, 0, 0, time.UTC))
+	}
+	t, err = ParseDate("3/3/95", false, DateAmbiguityRejected)
+	c.Assert(err, IsNil)
+	c.Assert(t, Equals, time.Date(1995, 3, 3, 0, 0, 0, 0, time.UTC))
+
+	_, err = ParseDate("3/4/2021", false, DateAmbiguityRejected)
+	c.Assert(err, ErrorMatches, `the date "3/4/2021" may be read day first or month first`)
+	_, ok := err.(*AmbiguousDateError)
+	c.Assert(ok, Equals, true)
+	for _, value := range []string{"", "next week", "31/2/2021", "13/13/2021", "3/4/021", "-1", "2021-03-04 25:00"} {
+		_, err := ParseDate(value, false, DateAmbiguityDayFirst)
+		c.Assert(err, NotNil)
+	}
+}
+
+func (s *DateParseSuite) TestCellDate(c *C) {
+	cell := &Cell{}
+	cell.SetDate(time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC))
+	t, err := cell.Date(DateAmbiguityRejected)
+	c.Assert(err, IsNil)
+	c.Assert(t, Equals, time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC))
+	cell.SetString("3/4/2021")
+	t, err = cell.Date(DateAmbiguityMonthFirst)
+	c.Assert(err, IsNil)
+	c.Assert(t, Equals, time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC))
+}
@@ -0,0 +1,156 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// debugIndent is the indentation used for each level of nesting
+// when parts are pretty printed.
+const debugIndent = "  "
+
+// SaveDebug saves the File to an xlsx file at the provided path, just
+// like Save, but with every XML part pretty printed.  If listing is
+// not nil a list of the parts and their sizes is written to it.
+func (f *File) SaveDebug(path string, listing io.Writer) error {
+	target, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = f.WriteDebug(target, listing)
+	if err != nil {
+		target.Close()
+		return err
+	}
+	return target.Close()
+}
+
+// WriteDebug writes the File to io.Writer as xlsx, just like Write,
+// but with every XML part pretty printed, which makes it much easier
+// to diff the output against files written by Excel.  The other parts,
+// such as images, are written as they are.  If listing is
+// not nil a list of the parts and their sizes, sorted by name, is
+// written to it.  Files written this way are valid, but bigger than
+// they need to be.
+func (f *File) WriteDebug(writer io.Writer, listing io.Writer) error {
+	parts, err := f.MarshallParts()
+	if err != nil {
+		return err
+	}
+	partNames := make([]string, 0, len(parts))
+	for partName := range parts {
+		partNames = append(partNames, partName)
+	}
+	sort.Strings(partNames)
+
+	zipWriter := zip.NewWriter(writer)
+	for _, partName := range partNames {
+		part := parts[partName]
+		if isDebugXMLPart(partName) {
+			if part, err = indentXML(part); err != nil {
+				return fmt.Errorf("%s: %s", partName, err)
+			}
+		}
+		if listing != nil {
+			if _, err := fmt.Fprintf(listing, "%10d  %s\n", len(part), partName); err != nil {
+				return err
+			}
+		}
+		w, err := zipWriter.Create(partName)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte(part)); err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
+// isDebugXMLPart returns true if the part is an XML part, pretty
+// printed by WriteDebug.
+func isDebugXMLPart(partName string) bool {
+	return strings.HasSuffix(partName, ".xml") || strings.HasSuffix(partName, ".rels")
+}
+
+// indentXML pretty prints an XML document, putting every element on
+// a line of its own.  Unlike xml.Encoder it leaves namespace prefixes
+// alone, and the text of elements without children is kept as is.
+func indentXML(data string) (string, error) {
+	var out bytes.Buffer
+	var depth int
+	// pending holds the text read since the last element started,
+	// which is only written if the element turns out to have no
+	// children.
+	var pending []byte
+	var lastWasStart bool
+
+	name := func(n xml.Name) string {
+		if n.Space != "" {
+			return n.Space + ":" + n.Local
+		}
+		return n.Local
+	}
+	newline := func() {
+		if out.Len() > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString(strings.Repeat(debugIndent, depth))
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(data))
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			pending = nil
+			newline()
+			out.WriteString("<" + name(t.Name))
+			for _, attr := range t.Attr {
+				out.WriteString(" " + name(attr.Name) + `="`)
+				xml.EscapeText(&out, []byte(attr.Value))
+				out.WriteString(`"`)
+			}
+			out.WriteString(">")
+			depth++
+			lastWasStart = true
+		case xml.EndElement:
+			depth--
+			if lastWasStart {
+				xml.EscapeText(&out, pending)
+			} else {
+				newline()
+			}
+			out.WriteString("</" + name(t.Name) + ">")
+			pending = nil
+			lastWasStart = false
+		case xml.CharData:
+			if lastWasStart {
+				pending = append(pending, t...)
+			}
+		case xml.ProcInst:
+			newline()
+			out.WriteString("<?" + t.Target + " " + string(t.Inst) + "?>")
+		case xml.Comment:
+			newline()
+			out.WriteString("<!--" + string(t) + "-->")
+		case xml.Directive:
+			newline()
+			out.WriteString("<!" + string(t) + ">")
+		}
+	}
+	out.WriteString("\n")
+	return out.String(), nil
+}
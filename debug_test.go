@@ -0,0 +1,60 @@
+package xlsx
+
+import (
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type DebugSuite struct{}
+
+var _ = Suite(&DebugSuite{})
+
+func (s *DebugSuite) TestIndentXML(c *C) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://example.com" xmlns:r="http://example.com/r"><sheets><sheet name="A &amp; B" r:id="rId1"></sheet></sheets><t xml:space="preserve">  two  spaces </t></workbook>`
+	output, err := indentXML(input)
+	c.Assert(err, IsNil)
+	c.Assert(output, Equals, `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://example.com" xmlns:r="http://example.com/r">
+  <sheets>
+    <sheet name="A &amp; B" r:id="rId1"></sheet>
+  </sheets>
+  <t xml:space="preserve">  two  spaces </t>
+</workbook>
+`)
+}
+
+func (s *DebugSuite) TestWriteDebug(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetString("Hello")
+	sheet.Cell(0, 1).SetInt(42)
+
+	var buffer, listing bytes.Buffer
+	c.Assert(file.WriteDebug(&buffer, &listing), IsNil)
+	c.Assert(strings.Contains(listing.String(), "  xl/worksheets/sheet1.xml\n"), Equals, true)
+	c.Assert(strings.Count(listing.String(), "\n"), Equals, 10)
+
+	file, err = OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	output, err := file.ToSlice()
+	c.Assert(err, IsNil)
+	c.Assert(output, DeepEquals, [][][]string{{{"Hello", "42"}}})
+}
+
+func (s *DebugSuite) TestWriteDebugBinaryParts(c *C) {
+	png := "\x89PNG\r\n\x1a\n\xff\xfe"
+	data := unknownPartsXLSX(c, func(parts map[string]string) {
+		parts["xl/media/image1.png"] = png
+	})
+	file, err := OpenBinary(data)
+	c.Assert(err, IsNil)
+	var buffer bytes.Buffer
+	c.Assert(file.WriteDebug(&buffer, nil), IsNil)
+	_, parts := readOptionsParts(c, buffer.Bytes())
+	c.Assert(parts["xl/media/image1.png"], Equals, png)
+	c.Assert(strings.Contains(parts["xl/workbook.xml"], "\n  <sheets>"), Equals, true)
+}
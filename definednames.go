@@ -0,0 +1,61 @@
+package xlsx
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	// futureFunctionPrefix is carried by the names of functions added
+	// to Excel after 2007, both in formulas and in the hidden defined
+	// names Excel keeps for them.
+	futureFunctionPrefix = "_xlfn."
+
+	lambdaFormulaPrefix = "LAMBDA("
+)
+
+// FutureFunctionNames returns the names, without their _xlfn. prefix,
+// of the functions that the defined names of the File declare as
+// added after Excel 2007.  Excel writes such a hidden defined name for
+// every modern function used in the workbook.
+func (f *File) FutureFunctionNames() []string {
+	var names []string
+	for _, definedName := range f.DefinedNames {
+		if strings.HasPrefix(definedName.Name, futureFunctionPrefix) {
+			names = append(names, strings.TrimPrefix(definedName.Name, futureFunctionPrefix))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LambdaDefinitions returns the custom functions of the File, defined
+// with LAMBDA in the Name Manager, mapped from their names to their
+// definitions, for example "=LAMBDA(x, x*2)" for a function DOUBLE.
+// The _xlfn. prefixes are removed from the definitions.
+func (f *File) LambdaDefinitions() map[string]string {
+	lambdas := make(map[string]string)
+	for _, definedName := range f.DefinedNames {
+		definition := strings.Replace(definedName.Data, futureFunctionPrefix, "", -1)
+		definition = strings.Replace(definition, "_xlpm.", "", -1)
+		definition = strings.TrimPrefix(strings.TrimSpace(definition), "=")
+		if strings.HasPrefix(strings.ToUpper(definition), lambdaFormulaPrefix) {
+			lambdas[definedName.Name] = "=" + definition
+		}
+	}
+	return lambdas
+}
+
+// makeXLSXDefinedNames returns the defined names of the File as they
+// are written to the workbook, intended for internal use only.  Names
+// local to a sheet that no longer exists are dropped.
+func (f *File) makeXLSXDefinedNames() xlsxDefinedNames {
+	xDefinedNames := xlsxDefinedNames{}
+	for _, definedName := range f.DefinedNames {
+		if definedName.IsLocal() && definedName.LocalSheetID >= len(f.Sheets) {
+			continue
+		}
+		xDefinedNames.DefinedName = append(xDefinedNames.DefinedName, *definedName)
+	}
+	return xDefinedNames
+}
@@ -0,0 +1,59 @@
+package xlsx
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type DefinedNamesSuite struct{}
+
+var _ = Suite(&DefinedNamesSuite{})
+
+// makeDefinedNamesFile returns a File with defined names as Excel 365
+// writes them for a workbook using XLOOKUP and a LAMBDA function.
+func makeDefinedNamesFile(c *C) *File {
+	f := NewFile()
+	_, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	printArea := &xlsxDefinedName{Name: "_xlnm.Print_Area", Data: "Sheet1!$A$1:$C$3"}
+	printArea.SetLocalSheetID(0)
+	f.DefinedNames = append(f.DefinedNames,
+		&xlsxDefinedName{Name: "_xlfn.XLOOKUP", Hidden: true, Data: "#NAME?"},
+		&xlsxDefinedName{Name: "_xlfn.LAMBDA", Hidden: true, Data: "#NAME?"},
+		&xlsxDefinedName{Name: "DOUBLE", Comment: "Doubles a value", Data: "_xlfn.LAMBDA(_xlpm.x,_xlpm.x*2)"},
+		printArea,
+	)
+	return f
+}
+
+func (s *DefinedNamesSuite) TestFutureFunctionNames(c *C) {
+	f := makeDefinedNamesFile(c)
+	c.Assert(f.FutureFunctionNames(), DeepEquals, []string{"LAMBDA", "XLOOKUP"})
+}
+
+func (s *DefinedNamesSuite) TestLambdaDefinitions(c *C) {
+	f := makeDefinedNamesFile(c)
+	c.Assert(f.LambdaDefinitions(), DeepEquals, map[string]string{"DOUBLE": "=LAMBDA(x,x*2)"})
+}
+
+func (s *DefinedNamesSuite) TestDefinedNamesSurviveRoundTrip(c *C) {
+	f := makeDefinedNamesFile(c)
+	var buffer bytes.Buffer
+	c.Assert(f.Write(&buffer), IsNil)
+	f, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(f.DefinedNames, HasLen, 4)
+	c.Assert(f.FutureFunctionNames(), DeepEquals, []string{"LAMBDA", "XLOOKUP"})
+	c.Assert(f.DefinedNames[2].Data, Equals, "_xlfn.LAMBDA(_xlpm.x,_xlpm.x*2)")
+	c.Assert(f.DefinedNames[2].Comment, Equals, "Doubles a value")
+	c.Assert(f.DefinedNames[3].LocalSheetID, Equals, 0)
+	c.Assert(f.DefinedNames[3].IsLocal(), Equals, true)
+	c.Assert(f.DefinedNames[0].IsLocal(), Equals, false)
+}
+
+func (s *DefinedNamesSuite) TestNamesOfRemovedSheetsAreDropped(c *C) {
+	f := makeDefinedNamesFile(c)
+	f.DefinedNames[3].LocalSheetID = 1
+	c.Assert(f.makeXLSXDefinedNames().DefinedName, HasLen, 3)
+}
@@ -0,0 +1,117 @@
+package xlsx
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// DuplicateRowAction is what a StreamFile does with the rows written
+// to a sheet that are identical to one of the rows written just before
+// them, see StreamFileBuilder.SetDuplicateRowGuard.  Such rows are
+// usually the sign of an upstream bug, such as a page of query results
+// fetched twice.
+type DuplicateRowAction int
+
+const (
+	// FlagDuplicateRows writes the duplicate rows, which are reported
+	// by DuplicateRows and counted in the Summary.
+	FlagDuplicateRows DuplicateRowAction = iota + 1
+	// SkipDuplicateRows drops the duplicate rows, which are reported
+	// and counted all the same.
+	SkipDuplicateRows
+)
+
+// maxDuplicateRows is the number of duplicate rows reported by
+// StreamFile.DuplicateRows, the others being only counted, so that a
+// sheet of duplicates doesn't fill the memory with their report.
+const maxDuplicateRows = 1000
+
+// DuplicateRow describes a duplicate row found by the duplicate row
+// guard of a StreamFile.  Row is the number the row has in the sheet,
+// or would have had if it was skipped, and Original the number of the
+// identical row written before it.  Rows are numbered from 1, the
+// header being the first row.
+type DuplicateRow struct {
+	Sheet    string
+	Row      int
+	Original int
+	Skipped  bool
+}
+
+// duplicateRowWindow holds the rows most recently written to a sheet,
+// to find the rows identical to one of them.  Rows are looked up by a
+// 64 bit hash of their cells, and compared cell by cell with the row
+// of the same hash, if any.
+type duplicateRowWindow struct {
+	// hashes, rows and cells are a ring of the hashes, numbers and
+	// cells of the rows in the window, next being the slot of the
+	// next row.
+	hashes []uint64
+	rows   []int
+	cells  [][]string
+	next   int
+	// latest holds the slot of the latest row in the window with a
+	// given hash.
+	latest map[uint64]int
+}
+
+// newDuplicateRowWindow creates a duplicateRowWindow holding the size
+// rows most recently written.
+func newDuplicateRowWindow(size int) *duplicateRowWindow {
+	return &duplicateRowWindow{
+		hashes: make([]uint64, size),
+		rows:   make([]int, size),
+		cells:  make([][]string, size),
+		latest: make(map[uint64]int),
+	}
+}
+
+// find returns the number of the row in the window identical to cells,
+// or false if there is none, along with the hash of cells.
+func (w *duplicateRowWindow) find(cells []string) (int, uint64, bool) {
+	hash := hashRow(cells)
+	slot, ok := w.latest[hash]
+	if !ok || !equalRows(w.cells[slot], cells) {
+		return 0, hash, false
+	}
+	return w.rows[slot], hash, true
+}
+
+// add adds the row with the given number, hash and cells to the
+// window, dropping the oldest row once it is full.
+func (w *duplicateRowWindow) add(row int, hash uint64, cells []string) {
+	if slot, ok := w.latest[w.hashes[w.next]]; ok && slot == w.next && w.rows[w.next] != 0 {
+		delete(w.latest, w.hashes[w.next])
+	}
+	w.hashes[w.next] = hash
+	w.rows[w.next] = row
+	w.cells[w.next] = append(w.cells[w.next][:0], cells...)
+	w.latest[hash] = w.next
+	w.next = (w.next + 1) % len(w.rows)
+}
+
+// equalRows returns true if the rows a and b have the same cells.
+func equalRows(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// hashRow returns the hash of cells, each of them prefixed by its
+// length so that rows whose cells join into the same string differ.
+func hashRow(cells []string) uint64 {
+	hash := fnv.New64a()
+	var length [binary.MaxVarintLen64]byte
+	for _, cell := range cells {
+		n := binary.PutUvarint(length[:], uint64(len(cell)))
+		hash.Write(length[:n])
+		hash.Write([]byte(cell))
+	}
+	return hash.Sum64()
+}
@@ -0,0 +1,42 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type DuplicateRowsSuite struct{}
+
+var _ = Suite(&DuplicateRowsSuite{})
+
+func (s *DuplicateRowsSuite) TestDuplicateRowWindow(c *C) {
+	window := newDuplicateRowWindow(2)
+	rows := [][]string{{"a", "b"}, {"c", "d"}, {"e", "f"}}
+	for i, cells := range rows {
+		_, hash, ok := window.find(cells)
+		c.Assert(ok, Equals, false)
+		window.add(i+2, hash, cells)
+	}
+	// The first row has dropped out of the window.
+	_, _, ok := window.find(rows[0])
+	c.Assert(ok, Equals, false)
+	row, hash, ok := window.find(rows[1])
+	c.Assert(ok, Equals, true)
+	c.Assert(row, Equals, 3)
+	window.add(5, hash, rows[1])
+	window.add(6, hashRow([]string{"g"}), []string{"g"})
+	// The latest of the identical rows stays in the window.
+	row, _, ok = window.find(rows[1])
+	c.Assert(ok, Equals, true)
+	c.Assert(row, Equals, 5)
+
+	// A row of the same hash as another one but other cells isn't a
+	// duplicate of it.
+	window.add(7, hashRow([]string{"h"}), []string{"i"})
+	_, _, ok = window.find([]string{"h"})
+	c.Assert(ok, Equals, false)
+}
+
+func (s *DuplicateRowsSuite) TestHashRow(c *C) {
+	c.Assert(hashRow([]string{"ab", "c"}), Not(Equals), hashRow([]string{"a", "bc"}))
+	c.Assert(hashRow([]string{"a", "b"}), Equals, hashRow([]string{"a", "b"}))
+}
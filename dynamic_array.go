@@ -0,0 +1,264 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+const (
+	// formulaTypeArray is the type of array formulas, dynamic or
+	// not.
+	formulaTypeArray = "array"
+
+	// dynamicArrayCellMetadata is the index of the cell metadata
+	// marking dynamic array formulas in the metadata part written
+	// by this package, see File.addDynamicArrayMetadata.
+	dynamicArrayCellMetadata = 1
+
+	metadataPartName             = "xl/metadata.xml"
+	metadataContentType          = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheetMetadata+xml"
+	relationshipTypeMetadata     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/sheetMetadata"
+	templateDynamicArrayMetadata = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<metadata xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:xda="http://schemas.microsoft.com/office/spreadsheetml/2017/dynamicarray"><metadataTypes count="1"><metadataType name="XLDAPR" minSupportedVersion="120000" copy="1" pasteAll="1" pasteValues="1" merge="1" splitFirst="1" rowColShift="1" clearFormats="1" clearComments="1" assign="1" coerce="1" cellMeta="1"/></metadataTypes><futureMetadata name="XLDAPR" count="1"><bk><extLst><ext uri="{bdbb8cdc-fa1e-496e-a857-3c3f30c029c3}"><xda:dynamicArrayProperties fDynamic="1" fCollapsed="0"/></ext></extLst></bk></futureMetadata><cellMetadata count="1"><bk><rc t="1" v="0"/></bk></cellMetadata></metadata>`
+)
+
+// futureFunctions maps the functions added to Excel after 2007 to the
+// prefix they must carry in a file.  Without the prefix Excel shows
+// #NAME? instead of calling them.
+var futureFunctions = map[string]string{
+	"AGGREGATE":      "_xlfn.",
+	"ARRAYTOTEXT":    "_xlfn.",
+	"BYCOL":          "_xlfn.",
+	"BYROW":          "_xlfn.",
+	"CHOOSECOLS":     "_xlfn.",
+	"CHOOSEROWS":     "_xlfn.",
+	"CONCAT":         "_xlfn.",
+	"DROP":           "_xlfn.",
+	"EXPAND":         "_xlfn.",
+	"FILTER":         "_xlfn._xlws.",
+	"HSTACK":         "_xlfn.",
+	"IFNA":           "_xlfn.",
+	"IFS":            "_xlfn.",
+	"LAMBDA":         "_xlfn.",
+	"LET":            "_xlfn.",
+	"MAKEARRAY":      "_xlfn.",
+	"MAP":            "_xlfn.",
+	"MAXIFS":         "_xlfn.",
+	"MINIFS":         "_xlfn.",
+	"PERCENTILE.EXC": "_xlfn.",
+	"PERCENTILE.INC": "_xlfn.",
+	"RANDARRAY":      "_xlfn.",
+	"REDUCE":         "_xlfn.",
+	"SCAN":           "_xlfn.",
+	"SEQUENCE":       "_xlfn.",
+	"SORT":           "_xlfn._xlws.",
+	"SORTBY":         "_xlfn.",
+	"STDEV.P":        "_xlfn.",
+	"STDEV.S":        "_xlfn.",
+	"SWITCH":         "_xlfn.",
+	"TAKE":           "_xlfn.",
+	"TEXTAFTER":      "_xlfn.",
+	"TEXTBEFORE":     "_xlfn.",
+	"TEXTJOIN":       "_xlfn.",
+	"TEXTSPLIT":      "_xlfn.",
+	"TOCOL":          "_xlfn.",
+	"TOROW":          "_xlfn.",
+	"UNIQUE":         "_xlfn.",
+	"VALUETOTEXT":    "_xlfn.",
+	"VSTACK":         "_xlfn.",
+	"WRAPCOLS":       "_xlfn.",
+	"WRAPROWS":       "_xlfn.",
+	"XLOOKUP":        "_xlfn.",
+	"XMATCH":         "_xlfn.",
+}
+
+// AddFormulaFunctionPrefixes returns formula with the _xlfn. (and for
+// some functions _xlws.) prefixes that Excel stores in front of the
+// functions added since Excel 2007, such as XLOOKUP, FILTER or LET.
+// Functions that already carry a prefix, and text inside string
+// literals, are left alone.
+func AddFormulaFunctionPrefixes(formula string) string {
+	var out bytes.Buffer
+	var inString, inSheetName bool
+	start := -1
+	isNameChar := func(b byte) bool {
+		return b == '_' || b == '.' || (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9')
+	}
+	for i := 0; i < len(formula); i++ {
+		ch := formula[i]
+		switch {
+		case inString:
+			inString = ch != '"'
+			out.WriteByte(ch)
+			continue
+		case inSheetName:
+			inSheetName = ch != '\''
+			out.WriteByte(ch)
+			continue
+		}
+		if isNameChar(ch) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			name := formula[start:i]
+			if ch == '(' {
+				if prefix, ok := futureFunctions[strings.ToUpper(name)]; ok {
+					out.WriteString(prefix)
+				}
+			}
+			out.WriteString(name)
+			start = -1
+		}
+		switch ch {
+		case '"':
+			inString = true
+		case '\'':
+			inSheetName = true
+		}
+		out.WriteByte(ch)
+	}
+	if start >= 0 {
+		out.WriteString(formula[start:])
+	}
+	return out.String()
+}
+
+// SetDynamicArrayFormula sets a formula whose results spill over the
+// cells in ref, a range starting at this cell such as "B2:B20", as
+// done by Excel 365 for functions like FILTER or SORT.  The prefixes
+// needed by modern functions are added to the formula.  Older versions
+// of Excel show the formula as a legacy array formula, as does Excel
+// 365 for a cell of a File read with metadata marking no dynamic array
+// formulas.
+func (c *Cell) SetDynamicArrayFormula(formula, ref string) {
+	c.changing()
+	c.formula = AddFormulaFunctionPrefixes(formula)
+	c.cellType = CellTypeNumeric
+	c.formulaType = formulaTypeArray
+	c.formulaRef = ref
+	c.cellMetadata = dynamicArrayCellMetadata
+	c.valueMetadata = 0
+	if c.Row != nil && c.Row.Sheet != nil {
+		c.cellMetadata = c.Row.Sheet.File.dynamicArrayCellMetadata()
+	}
+}
+
+// IsDynamicArrayFormula returns true if the cell holds a dynamic
+// array formula, set by SetDynamicArrayFormula or read from a file.
+func (c *Cell) IsDynamicArrayFormula() bool {
+	c.attach()
+	return c.formulaType == formulaTypeArray && c.cellMetadata != 0
+}
+
+// FormulaRef returns the range covered by the results of an array
+// formula, or an empty string for other cells.
+func (c *Cell) FormulaRef() string {
+	c.attach()
+	return c.formulaRef
+}
+
+// clearArrayFormula turns the cell back into a plain formula cell,
+// dropping the metadata of its value as well.
+func (c *Cell) clearArrayFormula() {
+	c.formulaType = ""
+	c.formulaRef = ""
+	c.cellMetadata = 0
+	c.valueMetadata = 0
+}
+
+// addDynamicArrayMetadata adds to parts the metadata part marking the
+// dynamic array formulas set by SetDynamicArrayFormula, along with its
+// content type and relationship, if the File has such formulas and
+// wasn't read with a metadata part of its own.
+func (f *File) addDynamicArrayMetadata(parts map[string]string, types *xlsxTypes, workbookRels *xlsxWorkbookRels) {
+	if _, exists := parts[metadataPartName]; exists || !f.hasDynamicArrayFormulas() {
+		return
+	}
+	parts[metadataPartName] = templateDynamicArrayMetadata
+	types.Overrides = append(types.Overrides, xlsxOverride{
+		PartName:    "/" + metadataPartName,
+		ContentType: metadataContentType,
+	})
+	usedIds := make(map[string]bool, len(workbookRels.Relationships))
+	for _, rel := range workbookRels.Relationships {
+		usedIds[rel.Id] = true
+	}
+	id := ""
+	for n := len(usedIds) + 1; id == "" || usedIds[id]; n++ {
+		id = fmt.Sprintf("rId%d", n)
+	}
+	workbookRels.Relationships = append(workbookRels.Relationships, xlsxWorkbookRelation{
+		Id:     id,
+		Type:   relationshipTypeMetadata,
+		Target: "metadata.xml",
+	})
+}
+
+// hasDynamicArrayFormulas returns true if a cell of the File holds a
+// dynamic array formula.
+func (f *File) hasDynamicArrayFormulas() bool {
+	for _, sheet := range f.Sheets {
+		for _, row := range sheet.rowList() {
+			if row == nil {
+				continue
+			}
+			row.load()
+			for _, cell := range row.Cells {
+				if cell != nil && cell.IsDynamicArrayFormula() {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// xlsxMetadata maps the parts of the metadata element needed to find
+// the cell metadata marking dynamic array formulas.
+type xlsxMetadata struct {
+	MetadataTypes []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"metadataTypes>metadataType"`
+	CellMetadata []struct {
+		Records []struct {
+			Type int `xml:"t,attr"`
+		} `xml:"rc"`
+	} `xml:"cellMetadata>bk"`
+}
+
+// dynamicArrayCellMetadata returns the index of the cell metadata
+// marking dynamic array formulas: that of the XLDAPR metadata of the
+// metadata part read with the File, or that of the part written by
+// addDynamicArrayMetadata if there is none.  It returns 0 if the part
+// read has no XLDAPR cell metadata.
+func (f *File) dynamicArrayCellMetadata() int {
+	if f == nil {
+		return dynamicArrayCellMetadata
+	}
+	data, exists := f.rawParts[metadataPartName]
+	if !exists {
+		return dynamicArrayCellMetadata
+	}
+	var metadata xlsxMetadata
+	if err := xml.Unmarshal(data, &metadata); err != nil {
+		return 0
+	}
+	for i, metadataType := range metadata.MetadataTypes {
+		if metadataType.Name != "XLDAPR" {
+			continue
+		}
+		for j, block := range metadata.CellMetadata {
+			for _, record := range block.Records {
+				if record.Type == i+1 {
+					return j + 1
+				}
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,107 @@
+package xlsx
+
+import (
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type DynamicArraySuite struct{}
+
+var _ = Suite(&DynamicArraySuite{})
+
+func (s *DynamicArraySuite) TestAddFormulaFunctionPrefixes(c *C) {
+	c.Assert(AddFormulaFunctionPrefixes(`XLOOKUP(A1,B:B,C:C)`), Equals, `_xlfn.XLOOKUP(A1,B:B,C:C)`)
+	c.Assert(AddFormulaFunctionPrefixes(`SUM(filter(A:A,B:B>1))`), Equals, `SUM(_xlfn._xlws.filter(A:A,B:B>1))`)
+	c.Assert(AddFormulaFunctionPrefixes(`_xlfn.UNIQUE(A1:A9)`), Equals, `_xlfn.UNIQUE(A1:A9)`)
+	c.Assert(AddFormulaFunctionPrefixes(`"SORT("&'LET(x'!A1`), Equals, `"SORT("&'LET(x'!A1`)
+	c.Assert(AddFormulaFunctionPrefixes(`STDEV.S(A1:A3)+SEQUENCE`), Equals, `_xlfn.STDEV.S(A1:A3)+SEQUENCE`)
+}
+
+func (s *DynamicArraySuite) TestDynamicArrayFormulaIsWritten(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	cell := sheet.Cell(0, 1)
+	cell.SetDynamicArrayFormula("SORT(A1:A3)", "B1:B3")
+	c.Assert(cell.IsDynamicArrayFormula(), Equals, true)
+	c.Assert(cell.FormulaRef(), Equals, "B1:B3")
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `<c r="B1" s="1" cm="1"><f t="array" ref="B1:B3">_xlfn._xlws.SORT(A1:A3)</f></c>`), Equals, true)
+	c.Assert(parts["xl/metadata.xml"], Equals, templateDynamicArrayMetadata)
+	c.Assert(strings.Contains(parts["xl/_rels/workbook.xml.rels"], `Target="metadata.xml" Type="`+relationshipTypeMetadata+`"`), Equals, true)
+	c.Assert(strings.Contains(parts["[Content_Types].xml"], metadataContentType), Equals, true)
+
+	// Writing the File leaves it as it was.
+	c.Assert(file.RawPartNames(), HasLen, 0)
+	parts, err = file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Count(parts["xl/_rels/workbook.xml.rels"], relationshipTypeMetadata), Equals, 1)
+
+	cell.SetFormula("A1*2")
+	c.Assert(cell.IsDynamicArrayFormula(), Equals, false)
+	cell.SetDynamicArrayFormula("SORT(A1:A3)", "B1:B3")
+	cell.SetString("sorted")
+	c.Assert(cell.IsDynamicArrayFormula(), Equals, false)
+	c.Assert(cell.FormulaRef(), Equals, "")
+	parts, err = file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `cm=`), Equals, false)
+	c.Assert(parts["xl/metadata.xml"], Equals, "")
+}
+
+func (s *DynamicArraySuite) TestDynamicArrayFormulaOfMetadataRead(c *C) {
+	// The metadata part written by Excel for a workbook holding rich
+	// values as well marks dynamic arrays with its second cell
+	// metadata.
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	c.Assert(file.SetRawPart(metadataPartName, []byte(`<metadata xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`+
+		`<metadataTypes count="2"><metadataType name="XLRICHVALUE"/><metadataType name="XLDAPR"/></metadataTypes>`+
+		`<cellMetadata count="2"><bk><rc t="1" v="0"/></bk><bk><rc t="2" v="0"/></bk></cellMetadata></metadata>`)), IsNil)
+	cell := sheet.Cell(0, 0)
+	cell.SetDynamicArrayFormula("SEQUENCE(3)", "A1:A3")
+	c.Assert(cell.IsDynamicArrayFormula(), Equals, true)
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `<c r="A1" s="1" cm="2">`), Equals, true)
+
+	// Without dynamic array metadata, the formula is written as a
+	// legacy array formula.
+	c.Assert(file.SetRawPart(metadataPartName, []byte(`<metadata xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`+
+		`<metadataTypes count="1"><metadataType name="XLRICHVALUE"/></metadataTypes>`+
+		`<cellMetadata count="1"><bk><rc t="1" v="0"/></bk></cellMetadata></metadata>`)), IsNil)
+	cell.SetDynamicArrayFormula("SEQUENCE(3)", "A1:A3")
+	c.Assert(cell.IsDynamicArrayFormula(), Equals, false)
+	parts, err = file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `<c r="A1" s="1"><f t="array" ref="A1:A3">`), Equals, true)
+}
+
+func (s *DynamicArraySuite) TestDynamicArrayFormulaRoundTrip(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetDynamicArrayFormula("UNIQUE(C1:C9)", "A1:A4")
+	sheet.Cell(1, 1).SetFormula("_xlfn.XLOOKUP(1,C:C,D:D)")
+
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	reread, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	cell := reread.Sheets[0].Cell(0, 0)
+	c.Assert(cell.Formula(), Equals, "_xlfn.UNIQUE(C1:C9)")
+	c.Assert(cell.IsDynamicArrayFormula(), Equals, true)
+	c.Assert(cell.FormulaRef(), Equals, "A1:A4")
+	c.Assert(reread.Sheets[0].Cell(1, 1).Formula(), Equals, "_xlfn.XLOOKUP(1,C:C,D:D)")
+	c.Assert(reread.RawPartNames(), DeepEquals, []string{"xl/metadata.xml"})
+
+	// Saving again must not add a second metadata relationship.
+	parts, err := reread.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Count(parts["xl/_rels/workbook.xml.rels"], relationshipTypeMetadata), Equals, 1)
+}
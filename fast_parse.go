@@ -0,0 +1,101 @@
+package xlsx
+
+import (
+	"strconv"
+)
+
+// exactPowersOfTen are the powers of ten that a float64 represents
+// exactly.
+var exactPowersOfTen = [...]float64{
+	1e0, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10, 1e11,
+	1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18, 1e19, 1e20, 1e21, 1e22,
+}
+
+// maxFastDigits is the number of digits whose value always fits in the
+// 53 bit mantissa of a float64.
+const maxFastDigits = 15
+
+// parseFloat is strconv.ParseFloat(s, 64) with a fast path for the
+// plain decimal numbers, such as "42" or "-3.25", that make up nearly
+// all the numeric values of a workbook.  A number of at most 15 digits
+// and an exact power of ten divide into a correctly rounded float64,
+// so the result is identical.  Anything else, errors included, is left
+// to strconv.
+func parseFloat(s string) (float64, error) {
+	i := 0
+	negative := false
+	if len(s) > 0 && s[0] == '-' {
+		negative = true
+		i++
+	}
+	var mantissa uint64
+	digits, decimals := 0, -1
+	for ; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch >= '0' && ch <= '9':
+			mantissa = mantissa*10 + uint64(ch-'0')
+			digits++
+			if decimals >= 0 {
+				decimals++
+			}
+		case ch == '.' && decimals < 0:
+			decimals = 0
+		default:
+			return strconv.ParseFloat(s, 64)
+		}
+	}
+	if digits == 0 || digits > maxFastDigits || decimals == 0 {
+		return strconv.ParseFloat(s, 64)
+	}
+	f := float64(mantissa)
+	if decimals > 0 {
+		f /= exactPowersOfTen[decimals]
+	}
+	if negative {
+		f = -f
+	}
+	return f, nil
+}
+
+// parseIndex is strconv.Atoi with a fast path for the small positive
+// numbers used as indexes, such as shared string references.
+func parseIndex(s string) (int, error) {
+	if len(s) == 0 || len(s) > 9 {
+		return strconv.Atoi(s)
+	}
+	n := 0
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch < '0' || ch > '9' {
+			return strconv.Atoi(s)
+		}
+		n = n*10 + int(ch-'0')
+	}
+	return n, nil
+}
+
+// parseCellID is a fast path of GetCoordsFromCellIDString for plain
+// cell references such as "AB12", made of upper case letters followed
+// by digits.  ok is false for anything else.
+func parseCellID(cellIDString string) (x, y int, ok bool) {
+	i := 0
+	for ; i < len(cellIDString) && i < 4; i++ {
+		ch := cellIDString[i]
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		x = x*26 + int(ch-'A'+1)
+	}
+	if i == 0 || i == len(cellIDString) || len(cellIDString)-i > 9 {
+		return 0, 0, false
+	}
+	for ; i < len(cellIDString); i++ {
+		ch := cellIDString[i]
+		if ch < '0' || ch > '9' {
+			return 0, 0, false
+		}
+		y = y*10 + int(ch-'0')
+	}
+	return x - 1, y - 1, true
+}
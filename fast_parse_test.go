@@ -0,0 +1,82 @@
+package xlsx
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+
+	. "gopkg.in/check.v1"
+)
+
+type FastParseSuite struct{}
+
+var _ = Suite(&FastParseSuite{})
+
+// checkParseFloat asserts that parseFloat agrees with strconv.
+func checkParseFloat(c *C, s string) {
+	expected, expectedErr := strconv.ParseFloat(s, 64)
+	actual, err := parseFloat(s)
+	if expectedErr != nil {
+		c.Assert(err, DeepEquals, expectedErr)
+		return
+	}
+	c.Assert(err, IsNil)
+	c.Assert(math.Float64bits(actual), Equals, math.Float64bits(expected))
+}
+
+func (s *FastParseSuite) TestParseFloat(c *C) {
+	for _, value := range []string{
+		"0", "-0", "42", "-42", "3.25", "-3.25", "0.1", "0.3", "1.005",
+		"123456789012345", "1234567890123456", "0.000000000000001",
+		"12345678.1234567", "9007199254740993", "1e10", "1E-5", "+5",
+		".5", "5.", "", "-", "abc", "1.2.3", "--1", " 1", "NaN", "Inf",
+		"00012", "1.50000",
+	} {
+		checkParseFloat(c, value)
+	}
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		value := random.NormFloat64() * math.Pow(10, float64(random.Intn(20)-10))
+		for _, precision := range []int{-1, 0, 2, 6, 10} {
+			checkParseFloat(c, strconv.FormatFloat(value, 'f', precision, 64))
+		}
+	}
+}
+
+func (s *FastParseSuite) TestParseIndex(c *C) {
+	for _, value := range []string{"0", "7", "123456", "123456789", "1234567890", "", "-1", "1a", " 1"} {
+		expected, expectedErr := strconv.Atoi(value)
+		actual, err := parseIndex(value)
+		c.Assert(err, DeepEquals, expectedErr)
+		c.Assert(actual, Equals, expected)
+	}
+}
+
+func (s *FastParseSuite) TestParseCellID(c *C) {
+	for _, ref := range []string{"A1", "Z9", "AA10", "AZ100", "XFD1048576", "BA3"} {
+		x, y, ok := parseCellID(ref)
+		c.Assert(ok, Equals, true)
+		c.Assert(GetCellIDStringFromCoords(x, y), Equals, ref)
+	}
+	for _, ref := range []string{"", "A", "1", "$A$1", "a1", "A1B", "ABCDE1", "A1234567890"} {
+		_, _, ok := parseCellID(ref)
+		c.Assert(ok, Equals, false)
+	}
+	x, y, err := GetCoordsFromCellIDString("$B$3")
+	c.Assert(err, IsNil)
+	c.Assert(x, Equals, 1)
+	c.Assert(y, Equals, 2)
+}
+
+func (s *FastParseSuite) TestNumberFormatIsCachedPerStyle(c *C) {
+	styles := newXlsxStyleSheet(nil)
+	styles.CellXfs = xlsxCellXfs{Count: 2, Xf: []xlsxXf{{}, {NumFmtId: 9}}}
+	format, parsed := styles.getNumberFormat(1)
+	c.Assert(format, Equals, "0%")
+	c.Assert(styles.numFmtCache, HasLen, 1)
+	again, parsedAgain := styles.getNumberFormat(1)
+	c.Assert(again, Equals, format)
+	c.Assert(parsedAgain, Equals, parsed)
+	styles.reset()
+	c.Assert(styles.numFmtCache, HasLen, 0)
+}
@@ -10,7 +10,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
-	"unicode/utf8"
+	"sync"
 )
 
 // File is a high level structure providing a slice of Sheet structs
@@ -24,6 +24,110 @@ type File struct {
 	Sheet          map[string]*Sheet
 	theme          *theme
 	DefinedNames   []*xlsxDefinedName
+	coreProperties *xlsxCoreProperties
+	// numberFormats caches the number formats parsed for the cells
+	// without an up to date parsed number format of their own, see
+	// Cell.getNumberFormat, guarded by numberFormatsLock since cells
+	// may be read from several goroutines, see File.RLock.
+	numberFormatsLock sync.RWMutex
+	numberFormats     map[string]*parsedNumberFormat
+	// rawParts holds the parts of a loaded file that aren't
+	// modelled by this package, so that they survive a save.
+	rawParts         map[string][]byte
+	rawContentTypes  map[string]string
+	rawRelationships []xlsxWorkbookRelation
+	// provenance holds the provenance tags of the File once they are
+	// read or set, see Provenance.
+	provenance *provenanceTags
+	// rawWorkbookExtensions holds the extensions of the workbook
+	// read from a file, such as its slicer caches.
+	rawWorkbookExtensions []xlsxExt
+	// sheetNames holds the folded names of the sheets, see
+	// foldSheetName, for the Files whose sheets are only ever added
+	// by AddSheet and AppendSheet, such as that of a
+	// StreamFileBuilder, so that looking a name up doesn't take
+	// longer with every sheet added.  It is nil otherwise, since the
+	// sheets of other Files may be renamed or removed.
+	sheetNames map[string]bool
+	// memoryBudget limits the memory taken while the File is
+	// loaded, if set.
+	memoryBudget *memoryBudget
+	// Strict makes saving fail with a TextError, rather than
+	// replace the characters that can't be represented, when a
+	// text such as a sheet name holds control characters or isn't
+	// valid UTF-8.
+	Strict bool
+	// InvalidUTF8 is the way text that isn't valid UTF-8 is dealt
+	// with when saving, the invalid bytes being replaced by U+FFFD by
+	// default.
+	InvalidUTF8 InvalidUTF8Policy
+	// LineBreaks is the way the line breaks of the text of cells are
+	// dealt with when saving, the Windows and old Mac ones being
+	// replaced by line feeds by default.
+	LineBreaks LineBreakPolicy
+	// PlainLargeIntegers writes the integers of 12 digits or more,
+	// such as order ids, with the "0" number format rather than the
+	// general one, in which Excel shows them in scientific notation.
+	// They remain numbers.  The cells with another number format are
+	// left alone.
+	PlainLargeIntegers bool
+	// NullAs is the way null values are written to cells, such as
+	// the invalid sql.Null values written by Row.WriteSlice and
+	// Row.WriteStruct, as empty cells by default.
+	NullAs NullPolicy
+	// FormulaErrors is the way formulas that may result in an error
+	// value are dealt with when saving, see IfErrorFormula.
+	FormulaErrors FormulaErrorPolicy
+	// FormulaErrorDefault is the fallback of the formulas wrapped in
+	// IFERROR when FormulaErrors is FormulaErrorsWrapped, an empty
+	// string by default.
+	FormulaErrorDefault string
+	// CalcProperties are the calculation settings of the workbook,
+	// such as manual calculation.
+	CalcProperties CalcProperties
+	// Protection is the protection of the structure and windows of
+	// the workbook, see Protect, nil if it isn't protected.
+	Protection *WorkbookProtection
+	// Theme is the theme written with the workbook, see BuiltinTheme,
+	// the default Office theme if nil.
+	Theme *Theme
+	// Compatible saves the File without the parts and extensions
+	// that readers other than Excel may not support, see
+	// CompatibilityDowngrades.
+	Compatible bool
+	// NumbersCompatible saves the File Compatible, and in a form that
+	// Numbers on the Mac opens without complaint, replacing the fonts
+	// that aren't installed on Macs by Arial, or the font given
+	// WithNumbersFont, in the file written.
+	NumbersCompatible bool
+	// WriteRefTable, if not nil, holds the shared strings the File
+	// is saved with.  Strings already in it keep their index and new
+	// strings are added to it, so that parts of an export saved by
+	// different processes use the same indices, see
+	// RefTable.MarshalBinary.
+	WriteRefTable *RefTable
+	// SharedStringStore, if not nil, is the dictionary of the
+	// strings shared by the cells when saving, instead of
+	// WriteRefTable or a new RefTable, see TempFileSharedStringStore
+	// for strings that don't fit in memory.
+	SharedStringStore SharedStringStore
+	// lock is the lock of the File, see Lock and RLock.
+	lock sync.RWMutex
+	// skippedParts lists the parts of the file read which won't
+	// survive a round trip, see SkippedParts.
+	skippedParts []SkippedPart
+	// preserve is true while a file is read with WithPreserve, which
+	// keeps what the workbook has that isn't modelled in
+	// preservedWorkbook, the relationships of the package to the raw
+	// parts in preservedPackageRels, and the content type of the
+	// workbook in workbookContentType.
+	preserve             bool
+	preservedWorkbook    *preservedPart
+	preservedPackageRels []xlsxWorkbookRelation
+	workbookContentType  string
+	// changes holds what the File read WithChangeTracking was, see
+	// Changes.
+	changes *changeTracker
 }
 
 const NoRowLimit int = -1
@@ -54,6 +158,21 @@ func OpenFileWithRowLimit(fileName string, rowLimit int) (file *File, err error)
 	return ReadZipWithRowLimit(z, rowLimit)
 }
 
+// OpenFileWithLimit is like OpenFile, except that loading is aborted
+// with a *MemoryLimitError once the loaded File would take more than
+// about maxBytes bytes of memory.  Rows, strings and parts are
+// accounted for before they are allocated, so that a single oversized
+// upload can't exhaust the memory of a shared process.
+func OpenFileWithLimit(fileName string, maxBytes int64) (file *File, err error) {
+	var z *zip.ReadCloser
+	z, err = zip.OpenReader(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+	return readZipReader(&z.Reader, openOptions{rowLimit: NoRowLimit, budget: &memoryBudget{limit: maxBytes}})
+}
+
 // OpenBinary() take bytes of an XLSX file and returns a populated
 // xlsx.File struct for it.
 func OpenBinary(bs []byte) (*File, error) {
@@ -117,48 +236,109 @@ func FileToSliceUnmerged(path string) ([][][]string, error) {
 	return f.ToSliceUnmerged()
 }
 
-// Save the File to an xlsx file at the provided path.
-func (f *File) Save(path string) (err error) {
+// Save the File to an xlsx file at the provided path, written as the options say, see File.Write.
+func (f *File) Save(path string, options ...Option) (err error) {
+	if f.changes != nil && f.changes.source.isFile(path) {
+		return f.saveOverSource(path, options)
+	}
 	target, err := os.Create(path)
 	if err != nil {
 		return err
 	}
-	err = f.Write(target)
+	err = f.Write(target, options...)
 	if err != nil {
 		return err
 	}
 	return target.Close()
 }
 
-// Write the File to io.Writer as xlsx
-func (f *File) Write(writer io.Writer) (err error) {
-	parts, err := f.MarshallParts()
+// Write the File to io.Writer as xlsx, as the options say, such as WithCompression. WithFlushInterval only applies to
+// a StreamFile.
+func (f *File) Write(writer io.Writer, options ...Option) (err error) {
+	_, err = f.write(writer, options)
+	return err
+}
+
+// write writes the File to writer as the options say, returning true if it was written by a minimal rewrite.
+func (f *File) write(writer io.Writer, options []Option) (bool, error) {
+	opts, err := newWriteOptions(options)
+	if err != nil {
+		return false, err
+	}
+	if opts.flushSet {
+		return false, errors.New("a flush interval only applies to a StreamFile")
+	}
+	if opts.minimalRewrite {
+		if minimal, err := f.writeMinimal(writer, opts); minimal || err != nil {
+			return minimal, err
+		}
+	}
+	// The shared strings, which can be many more than fit in memory
+	// with a SharedStringStore keeping them on disk, are written
+	// straight from the store into the zip.
+	parts, sharedStrings, err := f.marshallPartsWithoutSST(opts)
 	if err != nil {
-		return
+		return false, err
 	}
-	zipWriter := zip.NewWriter(writer)
+	method := zip.Deflate
+	if opts.compression != nil && *opts.compression == CompressionStore {
+		method = zip.Store
+	}
+	var largestPart int64
+	for _, part := range parts {
+		if int64(len(part)) > largestPart {
+			largestPart = int64(len(part))
+		}
+	}
+	if !opts.zip64Allowed() {
+		if err := checkZip64(len(parts)+1, largestPart, 0); err != nil {
+			return false, err
+		}
+	}
+	output := &throttledWriter{writer: writer}
+	zipWriter := zip.NewWriter(output)
 	for partName, part := range parts {
-		w, err := zipWriter.Create(partName)
+		w, err := zipWriter.CreateHeader(&zip.FileHeader{Name: partName, Method: method})
 		if err != nil {
-			return err
+			return false, err
 		}
 		_, err = w.Write([]byte(part))
 		if err != nil {
-			return err
+			return false, err
+		}
+	}
+	w, err := zipWriter.CreateHeader(&zip.FileHeader{Name: sharedStringsPart, Method: method})
+	if err != nil {
+		return false, err
+	}
+	sst := &throttledWriter{writer: w}
+	if err := writeXLSXSST(sst, sharedStrings); err != nil {
+		return false, err
+	}
+	if sst.bytesWritten() > largestPart {
+		largestPart = sst.bytesWritten()
+	}
+	if !opts.zip64Allowed() {
+		if err := zipWriter.Flush(); err != nil {
+			return false, err
+		}
+		if err := checkZip64(len(parts)+1, largestPart, output.bytesWritten()); err != nil {
+			return false, err
 		}
 	}
-	return zipWriter.Close()
+	return false, zipWriter.Close()
 }
 
 // Add a new Sheet, with the provided name, to a File. 
 // The maximum sheet name length is 31 characters. If the sheet name length is exceeded an error is thrown.
 // These special characters are also not allowed: : \ / ? * [ ]
 func (f *File) AddSheet(sheetName string) (*Sheet, error) {
-	if _, exists := f.Sheet[sheetName]; exists {
+	if f.hasSheetNamed(sheetName) {
 		return nil, fmt.Errorf("duplicate sheet name '%s'.", sheetName)
 	}
-	if utf8.RuneCountInString(sheetName) > 31 {
-		return nil, fmt.Errorf("sheet name must be 31 or fewer characters long.  It is currently '%d' characters long", utf8.RuneCountInString(sheetName))
+	// Excel counts characters in UTF-16, most emoji taking two.
+	if excelLength(sheetName) > 31 {
+		return nil, fmt.Errorf("sheet name must be 31 or fewer characters long.  It is currently '%d' characters long", excelLength(sheetName))
 	}
 	// Iterate over the runes
 	for _, r := range sheetName {
@@ -174,12 +354,15 @@ func (f *File) AddSheet(sheetName string) (*Sheet, error) {
 	}
 	f.Sheet[sheetName] = sheet
 	f.Sheets = append(f.Sheets, sheet)
+	if f.sheetNames != nil {
+		f.sheetNames[foldSheetName(sheetName)] = true
+	}
 	return sheet, nil
 }
 
 // Appends an existing Sheet, with the provided name, to a File
 func (f *File) AppendSheet(sheet Sheet, sheetName string) (*Sheet, error) {
-	if _, exists := f.Sheet[sheetName]; exists {
+	if f.hasSheetNamed(sheetName) {
 		return nil, fmt.Errorf("duplicate sheet name '%s'.", sheetName)
 	}
 	sheet.Name = sheetName
@@ -187,13 +370,59 @@ func (f *File) AppendSheet(sheet Sheet, sheetName string) (*Sheet, error) {
 	sheet.Selected = len(f.Sheets) == 0
 	f.Sheet[sheetName] = &sheet
 	f.Sheets = append(f.Sheets, &sheet)
+	if f.sheetNames != nil {
+		f.sheetNames[foldSheetName(sheetName)] = true
+	}
 	return &sheet, nil
 }
 
+// hasSheetNamed returns true if the File has a sheet with the given
+// name.  Names are compared regardless of case, as Excel does: a
+// workbook with sheets named "Data" and "DATA" makes it ask to be
+// repaired.
+func (f *File) hasSheetNamed(sheetName string) bool {
+	if f.sheetNames != nil {
+		return f.sheetNames[foldSheetName(sheetName)]
+	}
+	for _, sheet := range f.Sheets {
+		if strings.EqualFold(sheet.Name, sheetName) {
+			return true
+		}
+	}
+	return false
+}
+
+// foldSheetName returns sheetName folded so that the names
+// strings.EqualFold finds equal, such as "Data" and "DATA", are too.
+func foldSheetName(sheetName string) string {
+	return strings.ToLower(strings.ToUpper(sheetName))
+}
+
+// uniqueSheetName returns sheetName if the File has no sheet with that
+// name, or else the name followed by the lowest number, in the
+// "Data (2)" form used by Excel, that makes it unique.  The name is cut
+// short if needed to keep within 31 characters.
+func (f *File) uniqueSheetName(sheetName string) string {
+	if !f.hasSheetNamed(sheetName) {
+		return sheetName
+	}
+	for n := 2; ; n++ {
+		suffix := " (" + strconv.Itoa(n) + ")"
+		base := []rune(sheetName)
+		for excelLength(string(base)) > 31-len(suffix) {
+			base = base[:len(base)-1]
+		}
+		if candidate := string(base) + suffix; !f.hasSheetNamed(candidate) {
+			return candidate
+		}
+	}
+}
+
 func (f *File) makeWorkbook() xlsxWorkbook {
 	return xlsxWorkbook{
-		FileVersion: xlsxFileVersion{AppName: "Go XLSX"},
-		WorkbookPr:  xlsxWorkbookPr{ShowObjects: "all"},
+		FileVersion:        xlsxFileVersion{AppName: "Go XLSX"},
+		WorkbookPr:         xlsxWorkbookPr{ShowObjects: "all"},
+		WorkbookProtection: f.Protection.makeXLSXWorkbookProtection(),
 		BookViews: xlsxBookViews{
 			WorkBookView: []xlsxWorkBookView{
 				{
@@ -208,13 +437,9 @@ func (f *File) makeWorkbook() xlsxWorkbook {
 				},
 			},
 		},
-		Sheets: xlsxSheets{Sheet: make([]xlsxSheet, len(f.Sheets))},
-		CalcPr: xlsxCalcPr{
-			IterateCount: 100,
-			RefMode:      "A1",
-			Iterate:      false,
-			IterateDelta: 0.001,
-		},
+		Sheets:       xlsxSheets{Sheet: make([]xlsxSheet, len(f.Sheets))},
+		DefinedNames: f.makeXLSXDefinedNames(),
+		CalcPr:       f.CalcProperties.makeXLSXCalcPr(),
 	}
 }
 
@@ -239,9 +464,43 @@ func replaceRelationshipsNameSpace(workbookMarshal string) string {
 // Construct a map of file name to XML content representing the file
 // in terms of the structure of an XLSX file.
 func (f *File) MarshallParts() (map[string]string, error) {
+	return f.marshallParts(writeOptions{})
+}
+
+// marshallParts makes the parts of the file like MarshallParts, with
+// the default font and the shared strings of opts.
+func (f *File) marshallParts(opts writeOptions) (map[string]string, error) {
+	parts, sharedStrings, err := f.marshallPartsWithoutSST(opts)
+	if err != nil {
+		return parts, err
+	}
+	var sst bytes.Buffer
+	if err := writeXLSXSST(&sst, sharedStrings); err != nil {
+		return parts, err
+	}
+	parts[sharedStringsPart] = sst.String()
+	return parts, nil
+}
+
+// marshallPartsWithoutSST makes the parts of the file like
+// marshallParts, but for the shared strings part, and returns the
+// store holding the shared strings of the cells, to be written by
+// writeXLSXSST once the other parts are made.
+func (f *File) marshallPartsWithoutSST(opts writeOptions) (map[string]string, SharedStringStore, error) {
 	var parts map[string]string
 	var refTable *RefTable = NewSharedStringRefTable()
 	refTable.isWrite = true
+	if f.WriteRefTable != nil {
+		refTable = f.WriteRefTable
+		refTable.makeWritable()
+	}
+	var sharedStrings SharedStringStore = refTable
+	if f.SharedStringStore != nil {
+		sharedStrings = f.SharedStringStore
+	}
+	if opts.sharedStrings == SharedStringsInline {
+		sharedStrings = NewCappedSharedStringStore(0)
+	}
 	var workbookRels WorkBookRels = make(WorkBookRels)
 	var err error
 	var workbook xlsxWorkbook
@@ -265,10 +524,43 @@ func (f *File) MarshallParts() (map[string]string, error) {
 	f.styles.reset()
 	if len(f.Sheets) == 0 {
 		err := errors.New("Workbook must contains atleast one worksheet")
-		return nil, err
+		return nil, nil, err
+	}
+	if err := f.checkWrite(); err != nil {
+		return nil, nil, err
 	}
 	for _, sheet := range f.Sheets {
-		xSheet := sheet.makeXLSXSheet(refTable, f.styles)
+		xSheet := sheet.makeXLSXSheet(sharedStrings, f.styles)
+		if err := f.cellStoreErr(); err != nil {
+			return parts, nil, err
+		}
+		sparklineExt, err := makeXLSXSparklineExt(sheet.SparklineGroups)
+		if err != nil {
+			return parts, nil, err
+		}
+		var exts []xlsxExt
+		if sparklineExt != nil {
+			exts = append(exts, *sparklineExt)
+		}
+		for _, ext := range sheet.rawExtensions {
+			exts = append(exts, makeRawExtension(ext, nil))
+		}
+		rels := f.keptRelationships(sheet.rawRelationships, "xl/worksheets")
+		if !f.isCompatible() {
+			xSheet.ExtLst = makeXLSXExtLst(exts)
+			xSheet.TableParts = sheet.rawTableParts
+		} else {
+			rels = externalRelationships(rels)
+		}
+		xSheet.Hyperlinks, rels = makeXLSXHyperlinks(sheet.Hyperlinks, rels)
+		// The images and charts of a drawing and the notes of a legacy
+		// drawing can be removed along with their parts.
+		if sheet.rawDrawing != nil && hasRelationship(rels, sheet.rawDrawing.Id) {
+			xSheet.Drawing = sheet.rawDrawing
+		}
+		if sheet.rawLegacyDrawing != nil && hasRelationship(rels, sheet.rawLegacyDrawing.Id) {
+			xSheet.LegacyDrawing = sheet.rawLegacyDrawing
+		}
 		rId := fmt.Sprintf("rId%d", sheetIndex)
 		sheetId := strconv.Itoa(sheetIndex)
 		sheetPath := fmt.Sprintf("worksheets/sheet%d.xml", sheetIndex)
@@ -279,58 +571,141 @@ func (f *File) MarshallParts() (map[string]string, error) {
 				PartName:    "/" + partName,
 				ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"})
 		workbookRels[rId] = sheetPath
+		state := "visible"
+		if sheet.VeryHidden {
+			state = sheetStateVeryHidden
+		} else if sheet.Hidden {
+			state = sheetStateHidden
+		}
 		workbook.Sheets.Sheet[sheetIndex-1] = xlsxSheet{
-			Name:    sheet.Name,
+			Name:    f.validUTF8(sheet.Name),
 			SheetId: sheetId,
 			Id:      rId,
-			State:   "visible"}
+			State:   state}
 		parts[partName], err = marshal(xSheet)
 		if err != nil {
-			return parts, err
+			return parts, nil, err
+		}
+		if !f.isCompatible() {
+			parts[partName], err = insertPreservedElements(parts[partName], sheet.preserved, worksheetElementOrder, nil)
+			if err != nil {
+				return parts, nil, err
+			}
+		}
+		if len(rels) > 0 {
+			relsName := fmt.Sprintf("xl/worksheets/_rels/sheet%d.xml.rels", sheetIndex)
+			parts[relsName], err = marshal(xlsxWorkbookRels{Relationships: rels})
+			if err != nil {
+				return parts, nil, err
+			}
 		}
 		sheetIndex++
 	}
 
+	xWRel := workbookRels.MakeXLSXWorkbookRels()
+	var renamedIds map[string]string
+	if !f.isCompatible() {
+		if err := f.addProvenance(); err != nil {
+			return parts, nil, err
+		}
+		renamedIds = f.addRawParts(parts, &types, &xWRel)
+		f.addDynamicArrayMetadata(parts, &types, &xWRel)
+		var workbookExts []xlsxExt
+		for _, ext := range f.rawWorkbookExtensions {
+			workbookExts = append(workbookExts, makeRawExtension(ext, renamedIds))
+		}
+		workbook.ExtLst = makeXLSXExtLst(workbookExts)
+	}
+
 	workbookMarshal, err := marshal(workbook)
 	if err != nil {
-		return parts, err
+		return parts, nil, err
 	}
 	workbookMarshal = replaceRelationshipsNameSpace(workbookMarshal)
 	parts["xl/workbook.xml"] = workbookMarshal
 	if err != nil {
-		return parts, err
+		return parts, nil, err
 	}
 
 	parts["_rels/.rels"] = TEMPLATE__RELS_DOT_RELS
+	if !f.isCompatible() {
+		parts["xl/workbook.xml"], err = insertPreservedElements(parts["xl/workbook.xml"], f.preservedWorkbook, workbookElementOrder, renamedIds)
+		if err != nil {
+			return parts, nil, err
+		}
+		parts["_rels/.rels"] = f.addPreservedPackageRelationships(parts["_rels/.rels"])
+		if f.workbookContentType != "" {
+			for i := range types.Overrides {
+				if types.Overrides[i].PartName == "/xl/workbook.xml" {
+					types.Overrides[i].ContentType = f.workbookContentType
+				}
+			}
+		}
+	}
 	parts["docProps/app.xml"] = TEMPLATE_DOCPROPS_APP
 	// TODO - do this properly, modification and revision information
 	parts["docProps/core.xml"] = TEMPLATE_DOCPROPS_CORE
+	if f.coreProperties != nil {
+		parts["docProps/core.xml"] = f.coreProperties.makeXML()
+	}
 	parts["xl/theme/theme1.xml"] = TEMPLATE_XL_THEME_THEME
-
-	xSST := refTable.makeXLSXSST()
-	parts["xl/sharedStrings.xml"], err = marshal(xSST)
-	if err != nil {
-		return parts, err
+	if f.Theme != nil {
+		parts["xl/theme/theme1.xml"], err = f.Theme.makeXML()
+		if err != nil {
+			return parts, nil, err
+		}
 	}
 
-	xWRel := workbookRels.MakeXLSXWorkbookRels()
-
 	parts["xl/_rels/workbook.xml.rels"], err = marshal(xWRel)
 	if err != nil {
-		return parts, err
+		return parts, nil, err
 	}
 
 	parts["[Content_Types].xml"], err = marshal(types)
 	if err != nil {
-		return parts, err
+		return parts, nil, err
 	}
 
-	parts["xl/styles.xml"], err = f.styles.Marshal()
+	if opts.defaultFont != nil {
+		f.styles.replaceDefaultFont(opts.defaultFont)
+	}
+	fonts := f.styles.Fonts
+	if f.NumbersCompatible {
+		fonts = fonts.withMacFonts(opts.macFont())
+	}
+	parts["xl/styles.xml"], err = f.styles.marshal(fonts)
 	if err != nil {
-		return parts, err
+		return parts, nil, err
 	}
 
-	return parts, nil
+	return parts, sharedStrings, nil
+}
+
+// checkWrite returns an error if the File can't be written as its settings say, such as a Sensitive sheet that isn't
+// protected.
+func (f *File) checkWrite() error {
+	if f.Strict {
+		if err := f.checkText(); err != nil {
+			return err
+		}
+	}
+	if f.InvalidUTF8 == InvalidUTF8Rejected {
+		if err := f.walkText(checkUTF8); err != nil {
+			return err
+		}
+	}
+	if err := f.checkSensitiveSheets(); err != nil {
+		return err
+	}
+	if err := f.checkColumnLimit(); err != nil {
+		return err
+	}
+	if f.FormulaErrors == FormulaErrorsRejected {
+		if err := f.checkFormulaErrors(); err != nil {
+			return err
+		}
+	}
+	return f.cellStoreErr()
 }
 
 // Return the raw data contained in the File as three
@@ -350,10 +725,11 @@ func (f *File) ToSlice() (output [][][]string, err error) {
 	output = [][][]string{}
 	for _, sheet := range f.Sheets {
 		s := [][]string{}
-		for _, row := range sheet.Rows {
+		for _, row := range sheet.rowList() {
 			if row == nil {
 				continue
 			}
+			row.load()
 			r := []string{}
 			for _, cell := range row.Cells {
 				str, err := cell.FormattedValue()
@@ -391,7 +767,8 @@ func (f *File) ToSliceUnmerged() (output [][][]string, err error) {
 	}
 
 	for s, sheet := range f.Sheets {
-		for r, row := range sheet.Rows {
+		for r, row := range sheet.rowList() {
+			row.load()
 			for c, cell := range row.Cells {
 				if cell.HMerge > 0 {
 					for i := c + 1; i <= c+cell.HMerge; i++ {
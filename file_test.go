@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	. "gopkg.in/check.v1"
 )
@@ -353,6 +354,33 @@ func (l *FileSuite) TestAddSheetWithDuplicateName(c *C) {
 	c.Assert(err, ErrorMatches, "duplicate sheet name 'MySheet'.")
 }
 
+// Test that AddSheet compares sheet names regardless of case, as Excel does
+func (l *FileSuite) TestAddSheetWithDuplicateNameInOtherCase(c *C) {
+	f := NewFile()
+	_, err := f.AddSheet("MySheet")
+	c.Assert(err, IsNil)
+	_, err = f.AddSheet("MYSHEET")
+	c.Assert(err, ErrorMatches, "duplicate sheet name 'MYSHEET'.")
+	_, err = f.AppendSheet(Sheet{}, "mysheet")
+	c.Assert(err, ErrorMatches, "duplicate sheet name 'mysheet'.")
+}
+
+func (l *FileSuite) TestUniqueSheetName(c *C) {
+	f := NewFile()
+	c.Assert(f.uniqueSheetName("Data"), Equals, "Data")
+	_, err := f.AddSheet("Data")
+	c.Assert(err, IsNil)
+	c.Assert(f.uniqueSheetName("DATA"), Equals, "DATA (2)")
+	_, err = f.AddSheet("Data (2)")
+	c.Assert(err, IsNil)
+	c.Assert(f.uniqueSheetName("data"), Equals, "data (3)")
+
+	long := strings.Repeat("x", 31)
+	_, err = f.AddSheet(long)
+	c.Assert(err, IsNil)
+	c.Assert(f.uniqueSheetName(long), Equals, strings.Repeat("x", 27)+" (2)")
+}
+
 // Test that we can append a sheet to a File
 func (l *FileSuite) TestAppendSheet(c *C) {
 	var f *File
@@ -85,7 +85,7 @@ func (fullFormat *parsedNumberFormat) FormatValue(cell *Cell) (string, error) {
 	case CellTypeInline:
 		fallthrough
 	case CellTypeStringFormula:
-		textFormat := cell.parsedNumFmt.textFormat
+		textFormat := fullFormat.textFormat
 		// This switch statement is only for String formats
 		switch textFormat.reducedFormatString {
 		case builtInNumFmt[builtInNumFmtIndex_GENERAL]: // General is literally "general"
@@ -124,7 +124,7 @@ func (fullFormat *parsedNumberFormat) formatNumericCell(cell *Cell) (string, err
 		return fullFormat.parseTime(rawValue, cell.date1904)
 	}
 	var numberFormat *formatOptions
-	floatVal, floatErr := strconv.ParseFloat(rawValue, 64)
+	floatVal, floatErr := parseFloat(rawValue)
 	if floatErr != nil {
 		return rawValue, floatErr
 	}
@@ -184,12 +184,23 @@ func (fullFormat *parsedNumberFormat) formatNumericCell(cell *Cell) (string, err
 		formattedNum = fmt.Sprintf("%.3f", floatVal)
 	case "0.0000", "#,##0.0000":
 		formattedNum = fmt.Sprintf("%.4f", floatVal)
-	case "0.00e+00", "##0.0e+0":
-		formattedNum = fmt.Sprintf("%e", floatVal)
 	case "":
 		// Do nothing.
 	default:
-		return rawValue, nil
+		if strings.Contains(numberFormat.reducedFormatString, "/") {
+			formattedNum = formatFraction(floatVal, numberFormat.reducedFormatString)
+		} else if exponentIndex(numberFormat.reducedFormatString) >= 0 {
+			formattedNum = formatExponent(floatVal, numberFormat.reducedFormatString)
+		} else if strings.Trim(numberFormat.reducedFormatString, "0") == "" {
+			// Zero padded formats, such as "00000" for ZIP codes, show integers with at least as many digits as the
+			// format has zeros.
+			formattedNum = fmt.Sprintf("%0*.0f", len(numberFormat.reducedFormatString), math.Abs(floatVal))
+			if floatVal <= -0.5 {
+				formattedNum = "-" + formattedNum
+			}
+		} else {
+			return rawValue, nil
+		}
 	}
 	return numberFormat.prefix + formattedNum + numberFormat.suffix, nil
 }
@@ -198,7 +209,7 @@ func generalNumericScientific(value string, allowScientific bool) (string, error
 	if strings.TrimSpace(value) == "" {
 		return "", nil
 	}
-	f, err := strconv.ParseFloat(value, 64)
+	f, err := parseFloat(value)
 	if err != nil {
 		return value, err
 	}
@@ -426,6 +437,15 @@ func splitFormatAndSuffixFormat(format string) (string, string) {
 				break
 			}
 		}
+		if found && format[i] == '/' {
+			// The denominator of a fraction may be fixed, as in # ?/16
+			for i+1 < len(format) && format[i+1] >= '0' && format[i+1] <= '9' {
+				i++
+			}
+		} else if !found && curReducedFormat[0] == ' ' && isFractionNumerator(curReducedFormat[1:]) {
+			// The space separating the whole part from the fraction, as in # ?/?
+			found = true
+		}
 		if !found {
 			break
 		}
@@ -506,7 +526,7 @@ func parseLiterals(format string) (string, string, bool, error) {
 
 // parseTime returns a string parsed using time.Time
 func (fullFormat *parsedNumberFormat) parseTime(value string, date1904 bool) (string, error) {
-	f, err := strconv.ParseFloat(value, 64)
+	f, err := parseFloat(value)
 	if err != nil {
 		return value, err
 	}
@@ -536,7 +556,9 @@ func (fullFormat *parsedNumberFormat) parseTime(value string, date1904 bool) (st
 		{":mm", ":04"},
 		{"mm", "01"},
 		{"am/pm", "pm"},
+		{"AM/PM", "PM"},
 		{"m/", "1/"},
+		{"-m-", "-1-"},
 		{"%%%%", "January"},
 		{"&&&&", "Monday"},
 	}
@@ -0,0 +1,105 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormulaErrorPolicy is the way the formulas of a File are dealt with
+// when it is saved, so that sheets don't show error values such as
+// #DIV/0! to their readers, see File.FormulaErrors.
+type FormulaErrorPolicy int
+
+const (
+	// FormulaErrorsShown leaves the formulas as they are.
+	FormulaErrorsShown FormulaErrorPolicy = iota
+	// FormulaErrorsWrapped wraps the formulas that aren't already
+	// wrapped in IFERROR, with File.FormulaErrorDefault as their
+	// value in case of error.  The cells themselves are unchanged.
+	FormulaErrorsWrapped
+	// FormulaErrorsRejected makes saving fail with a FormulaError
+	// for the first formula that isn't wrapped in IFERROR.
+	FormulaErrorsRejected
+)
+
+// FormulaError is returned when saving a File whose FormulaErrors are
+// FormulaErrorsRejected and which holds a formula not wrapped in
+// IFERROR.
+type FormulaError struct {
+	// Cell is the reference of the cell, such as "Sheet1!B2".
+	Cell    string
+	Formula string
+}
+
+// Error returns a description of the FormulaError.
+func (e *FormulaError) Error() string {
+	return fmt.Sprintf("the formula of %s isn't wrapped in IFERROR: %s", e.Cell, e.Formula)
+}
+
+// IfErrorFormula returns formula wrapped in IFERROR, so that it results
+// in fallback, a formula expression such as 0 or "n/a", rather than in
+// an error value.  An empty fallback stands for the empty string.
+// Formulas already wrapped are returned as they are.
+func IfErrorFormula(formula, fallback string) string {
+	formula = strings.TrimPrefix(strings.TrimSpace(formula), "=")
+	if isIfErrorFormula(formula) {
+		return formula
+	}
+	if fallback == "" {
+		fallback = `""`
+	}
+	return "IFERROR(" + formula + "," + fallback + ")"
+}
+
+// isIfErrorFormula returns true if the whole of formula is a call to
+// IFERROR.
+func isIfErrorFormula(formula string) bool {
+	formula = strings.TrimPrefix(strings.TrimSpace(formula), "=")
+	if len(formula) < len("IFERROR(") || !strings.EqualFold(formula[:len("IFERROR(")], "IFERROR(") {
+		return false
+	}
+	depth := 0
+	for i := len("IFERROR"); i < len(formula); i++ {
+		switch formula[i] {
+		case '"', '\'':
+			// Strings and quoted sheet names may hold parentheses,
+			// and double their quotes.
+			end := strings.IndexByte(formula[i+1:], formula[i])
+			if end < 0 {
+				return false
+			}
+			i += end + 1
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i == len(formula)-1
+			}
+		}
+	}
+	return false
+}
+
+// checkFormulaErrors returns a FormulaError for the first formula of
+// the File that isn't wrapped in IFERROR.
+func (f *File) checkFormulaErrors() error {
+	for _, sheet := range f.Sheets {
+		for y, row := range sheet.rowList() {
+			if row == nil {
+				continue
+			}
+			row.load()
+			for x, cell := range row.Cells {
+				if cell == nil || cell.formula == "" || isIfErrorFormula(cell.formula) {
+					continue
+				}
+				return &FormulaError{
+					Cell:    sheet.Name + "!" + GetCellIDStringFromCoords(x, y),
+					Formula: cell.formula,
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package xlsx
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type FormulaErrorsSuite struct{}
+
+var _ = Suite(&FormulaErrorsSuite{})
+
+func (s *FormulaErrorsSuite) TestIfErrorFormula(c *C) {
+	c.Assert(IfErrorFormula("A1/B1", ""), Equals, `IFERROR(A1/B1,"")`)
+	c.Assert(IfErrorFormula("=A1/B1", "0"), Equals, `IFERROR(A1/B1,0)`)
+	c.Assert(IfErrorFormula(`IFERROR(A1/B1,"n/a")`, "0"), Equals, `IFERROR(A1/B1,"n/a")`)
+	c.Assert(IfErrorFormula(`IFERROR(A1,0)+B1`, "0"), Equals, `IFERROR(IFERROR(A1,0)+B1,0)`)
+}
+
+func (s *FormulaErrorsSuite) TestIsIfErrorFormula(c *C) {
+	c.Assert(isIfErrorFormula(`IFERROR(A1/B1,"")`), Equals, true)
+	c.Assert(isIfErrorFormula(` =iferror(VLOOKUP(A1,'Data (2)'!A:B,2,FALSE),"(none)")`), Equals, true)
+	c.Assert(isIfErrorFormula(`IFERROR(A1,"say ""hi"")")`), Equals, true)
+	c.Assert(isIfErrorFormula(`IFERROR(A1,0)*2`), Equals, false)
+	c.Assert(isIfErrorFormula(`IFERRORS(A1)`), Equals, false)
+	c.Assert(isIfErrorFormula(`SUM(A1:A3)`), Equals, false)
+	c.Assert(isIfErrorFormula(`IFERROR(A1,")`), Equals, false)
+}
+
+func (s *FormulaErrorsSuite) makeFile(c *C) *File {
+	f := NewFile()
+	sheet, err := f.AddSheet("Report")
+	c.Assert(err, IsNil)
+	row := sheet.AddRow()
+	row.AddCell().SetFormula(`IFERROR(1/0,"-")`)
+	cell := row.AddCell()
+	cell.SetFormula("1/0")
+	c.Assert(cell.SetError(ErrorValueDivZero), IsNil)
+	return f
+}
+
+func (s *FormulaErrorsSuite) TestFormulaErrorsWrapped(c *C) {
+	f := s.makeFile(c)
+	f.FormulaErrors = FormulaErrorsWrapped
+	f.FormulaErrorDefault = "0"
+	var buffer bytes.Buffer
+	c.Assert(f.Write(&buffer), IsNil)
+	c.Assert(f.Sheets[0].Cell(0, 1).Formula(), Equals, "1/0")
+
+	f, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheets[0].Cell(0, 0).Formula(), Equals, `IFERROR(1/0,"-")`)
+	cell := f.Sheets[0].Cell(0, 1)
+	c.Assert(cell.Formula(), Equals, "IFERROR(1/0,0)")
+	c.Assert(cell.Type(), Equals, CellTypeNumeric)
+	c.Assert(cell.Value, Equals, "")
+}
+
+func (s *FormulaErrorsSuite) TestFormulaErrorsRejected(c *C) {
+	f := s.makeFile(c)
+	f.FormulaErrors = FormulaErrorsRejected
+	var buffer bytes.Buffer
+	err := f.Write(&buffer)
+	formulaError, ok := err.(*FormulaError)
+	c.Assert(ok, Equals, true)
+	c.Assert(formulaError.Cell, Equals, "Report!B1")
+	c.Assert(err, ErrorMatches, "the formula of Report!B1 isn't wrapped in IFERROR: 1/0")
+
+	f.Sheets[0].Cell(0, 1).SetFormula(IfErrorFormula("1/0", ""))
+	c.Assert(f.Write(&buffer), IsNil)
+}
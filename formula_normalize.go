@@ -0,0 +1,139 @@
+package xlsx
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+)
+
+// FormulaOptions sets how Cell.NormalizedFormula normalizes the formula
+// of a cell.
+type FormulaOptions struct {
+	// QualifySheetNames prefixes the references to the cells, ranges,
+	// columns and rows of the sheet of the cell, such as A1, $B$2:C9 or
+	// D:D, with the name of the sheet, quoted if needed, such as
+	// 'Q1 Sales'!A1, so that every reference of the formula names its
+	// sheet.
+	QualifySheetNames bool
+}
+
+// NormalizedFormula returns the formula of the cell, like Formula,
+// normalized as set by options, for the tools analysing the formulas of
+// a file.  The formulas of the cells sharing the formula of another
+// cell, which Excel stores once, are read with their references moved
+// to their own cell, so that they need no further expansion.
+func (c *Cell) NormalizedFormula(options FormulaOptions) string {
+	c.attach()
+	formula := c.formula
+	if options.QualifySheetNames && formula != "" && c.Row != nil && c.Row.Sheet != nil {
+		formula = QualifyFormulaReferences(formula, c.Row.Sheet.Name)
+	}
+	return formula
+}
+
+// The kinds of the references told apart by referenceKind.
+const (
+	noReference = iota
+	cellReference
+	columnReference
+	rowReference
+)
+
+var (
+	cellReferencePattern   = regexp.MustCompile(`^\$?([A-Z]{1,3})\$?([0-9]{1,7})$`)
+	columnReferencePattern = regexp.MustCompile(`^\$?([A-Z]{1,3})$`)
+	rowReferencePattern    = regexp.MustCompile(`^\$?([0-9]{1,7})$`)
+)
+
+// QualifyFormulaReferences returns formula with the references to the
+// cells, ranges, columns and rows it makes without naming their sheet,
+// such as A1, $B$2:C9, D:D or 3:5, prefixed with the name of the sheet
+// sheetName, quoted if needed.  The references already naming their
+// sheet, those of other workbooks, the names of functions, defined names
+// and tables, and string literals are left alone.
+func QualifyFormulaReferences(formula, sheetName string) string {
+	prefix := quoteSheetName(sheetName) + externalSheetBangChar
+	isRefChar := func(b byte) bool {
+		return b == '_' || b == '.' || b == '$' || (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') ||
+			(b >= '0' && b <= '9')
+	}
+	tokenEnd := func(i int) int {
+		for i < len(formula) && isRefChar(formula[i]) {
+			i++
+		}
+		return i
+	}
+	var out bytes.Buffer
+	var inString, inSheetName bool
+	bracketDepth := 0
+	for i := 0; i < len(formula); {
+		ch := formula[i]
+		switch {
+		case inString:
+			inString = ch != '"'
+		case inSheetName:
+			inSheetName = ch != '\''
+		case bracketDepth > 0:
+			// Table references, such as Table1[Amount], and the
+			// workbooks of external references, such as [1].
+			if ch == '[' {
+				bracketDepth++
+			} else if ch == ']' {
+				bracketDepth--
+			}
+		case ch == '"':
+			inString = true
+		case ch == '\'':
+			inSheetName = true
+		case ch == '[':
+			bracketDepth++
+		case isRefChar(ch):
+			end := tokenEnd(i)
+			kind := referenceKind(formula[i:end])
+			// A range is a single reference, whose end isn't qualified.
+			refEnd := end
+			if kind != noReference && end < len(formula) && formula[end] == ':' {
+				if next := tokenEnd(end + 1); referenceKind(formula[end+1:next]) == kind {
+					refEnd = next
+				}
+			}
+			isReference := kind == cellReference || (kind != noReference && refEnd > end)
+			qualified := i > 0 && formula[i-1] == '!'
+			if isReference && !qualified && (end == len(formula) || (formula[end] != '(' && formula[end] != '!')) {
+				out.WriteString(prefix)
+			}
+			out.WriteString(formula[i:refEnd])
+			i = refEnd
+			continue
+		}
+		out.WriteByte(ch)
+		i++
+	}
+	return out.String()
+}
+
+// referenceKind returns the kind of reference token is, such as
+// cellReference for $B$2, or noReference if it isn't one.
+func referenceKind(token string) int {
+	validColumn := func(letters string) bool {
+		return ColLettersToIndex(letters) < Excel2006MaxColumnCount
+	}
+	validRow := func(digits string) bool {
+		row, err := strconv.Atoi(digits)
+		return err == nil && row >= 1 && row <= Excel2006MaxRowCount
+	}
+	if match := cellReferencePattern.FindStringSubmatch(token); match != nil {
+		if validColumn(match[1]) && validRow(match[2]) {
+			return cellReference
+		}
+	} else if match := columnReferencePattern.FindStringSubmatch(token); match != nil {
+		if validColumn(match[1]) {
+			return columnReference
+		}
+	} else if match := rowReferencePattern.FindStringSubmatch(token); match != nil {
+		if validRow(match[1]) {
+			return rowReference
+		}
+	}
+	return noReference
+}
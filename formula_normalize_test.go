@@ -0,0 +1,46 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type FormulaNormalizeSuite struct{}
+
+var _ = Suite(&FormulaNormalizeSuite{})
+
+func (s *FormulaNormalizeSuite) TestQualifyFormulaReferences(c *C) {
+	for formula, expected := range map[string]string{
+		"A1+B2":                           "Data!A1+Data!B2",
+		"SUM($A$1:B10)*2":                 "SUM(Data!$A$1:B10)*2",
+		"SUM(D:D)+SUM(3:5)":               "SUM(Data!D:D)+SUM(Data!3:5)",
+		"Sheet2!A1+'Q1 Sales'!B2:C3":      "Sheet2!A1+'Q1 Sales'!B2:C3",
+		"LOG10(A1)":                       "LOG10(Data!A1)",
+		`IF(A1="B2",1.5,2)`:               `IF(Data!A1="B2",1.5,2)`,
+		"SUM(Table1[Amount])+[1]Other!A1": "SUM(Table1[Amount])+[1]Other!A1",
+		"TaxRate*A1":                      "TaxRate*Data!A1",
+		"_xlfn.XLOOKUP(A1,B:B,C:C)":       "_xlfn.XLOOKUP(Data!A1,Data!B:B,Data!C:C)",
+		"XFE1+A1048577+A0":                "XFE1+A1048577+A0",
+		"IF(ISERROR(A1),#REF!,A1)":        "IF(ISERROR(Data!A1),#REF!,Data!A1)",
+	} {
+		c.Assert(QualifyFormulaReferences(formula, "Data"), Equals, expected)
+	}
+	c.Assert(QualifyFormulaReferences("A1", "Q1 Sales"), Equals, "'Q1 Sales'!A1")
+}
+
+func (s *FormulaNormalizeSuite) TestNormalizedFormula(c *C) {
+	// The formulas of B1:B2 are stored as one shared formula.
+	data := unknownPartsXLSX(c, func(parts map[string]string) {
+		parts["xl/worksheets/sheet1.xml"] = `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			`<row r="1"><c r="A1"><v>2</v></c><c r="B1"><f t="shared" ref="B1:B2" si="0">A1*10</f><v>20</v></c></row>` +
+			`<row r="2"><c r="A2"><v>3</v></c><c r="B2"><f t="shared" si="0"/><v>30</v></c></row>` +
+			`</sheetData></worksheet>`
+	})
+	file, err := OpenBinary(data)
+	c.Assert(err, IsNil)
+	cell := file.Sheets[0].Cell(1, 1)
+	c.Assert(cell.Formula(), Equals, "A2*10")
+	c.Assert(cell.NormalizedFormula(FormulaOptions{}), Equals, "A2*10")
+	c.Assert(cell.NormalizedFormula(FormulaOptions{QualifySheetNames: true}), Equals, "Sheet1!A2*10")
+	c.Assert(file.Sheets[0].Cell(1, 0).NormalizedFormula(FormulaOptions{QualifySheetNames: true}), Equals, "")
+}
@@ -242,3 +242,20 @@ func (f *Fuzzy) TestRandomBrokenParts(c *C) {
 	}
 	c.Succeed()
 }
+
+// Random column indices, well beyond the last column of a worksheet,
+// and random letter codes make the round trip through the column
+// letter helpers.
+func (f *Fuzzy) TestRandomColumnLetters(c *C) {
+	rand.Seed(*randseed)
+	for i := 0; i < 100000; i++ {
+		index := rand.Intn(1 << 24)
+		c.Assert(ColLettersToIndex(ColIndexToLetters(index)), Equals, index)
+
+		code := make([]byte, 1+rand.Intn(5))
+		for j := range code {
+			code[j] = byte('A' + rand.Intn(26))
+		}
+		c.Assert(ColIndexToLetters(ColLettersToIndex(string(code))), Equals, string(code))
+	}
+}
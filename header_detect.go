@@ -0,0 +1,81 @@
+package xlsx
+
+import "strings"
+
+// headerDetectRows is the number of rows at the top of a sheet among
+// which HeaderDetect looks for the header row.
+const headerDetectRows = 20
+
+// HeaderDetect returns the index, starting at 0, of the row of sheet likely to hold the headers of its columns, along
+// with the headers, for imports of sheets whose headers follow a title, notes or blank rows rather than being in the
+// first row. The header row is the first of the top rows of the sheet whose cells are text, none of them the same,
+// and which fills at least half of the columns filled by the widest of these rows, rows with a single cell, such as
+// titles, being skipped when other rows are wider. The headers are trimmed, those of the columns without one being
+// empty. -1 and nil are returned if no row is found.
+func HeaderDetect(sheet *Sheet) (int, []string) {
+	rows := len(sheet.rowList())
+	if rows > headerDetectRows {
+		rows = headerDetectRows
+	}
+	width := 0
+	for i := 0; i < rows; i++ {
+		if n := filledCells(sheet.row(i)); n > width {
+			width = n
+		}
+	}
+	for i := 0; i < rows; i++ {
+		row := sheet.row(i)
+		n := filledCells(row)
+		if n == 0 || 2*n < width || (n == 1 && width > 1) || !isHeaderRow(row) {
+			continue
+		}
+		headers := make([]string, 0, len(row.Cells))
+		last := 0
+		for _, cell := range row.Cells {
+			header := ""
+			if cell != nil {
+				header = strings.TrimSpace(cell.Value)
+			}
+			headers = append(headers, header)
+			if header != "" {
+				last = len(headers)
+			}
+		}
+		return i, headers[:last]
+	}
+	return -1, nil
+}
+
+// filledCells returns the number of cells of row which aren't blank.
+func filledCells(row *Row) int {
+	if row == nil {
+		return 0
+	}
+	n := 0
+	for _, cell := range row.Cells {
+		if cell != nil && strings.TrimSpace(cell.Value) != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// isHeaderRow returns true if the cells of row which aren't blank hold
+// text, none of them the same.
+func isHeaderRow(row *Row) bool {
+	seen := make(map[string]bool, len(row.Cells))
+	for _, cell := range row.Cells {
+		if cell == nil {
+			continue
+		}
+		value := strings.TrimSpace(cell.Value)
+		if value == "" {
+			continue
+		}
+		if cell.formula != "" || (cell.Type() != CellTypeString && cell.Type() != CellTypeInline) || seen[value] {
+			return false
+		}
+		seen[value] = true
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type HeaderDetectSuite struct{}
+
+var _ = Suite(&HeaderDetectSuite{})
+
+// addValues adds a row holding values to sheet, numbers being written
+// as numbers, nil leaving a blank cell.
+func addValues(sheet *Sheet, values ...interface{}) {
+	row := sheet.AddRow()
+	for _, value := range values {
+		cell := row.AddCell()
+		if value != nil {
+			cell.SetValue(value)
+		}
+	}
+}
+
+func (s *HeaderDetectSuite) TestHeaderDetect(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Upload")
+	c.Assert(err, IsNil)
+	addValues(sheet, "Sales by region")
+	addValues(sheet)
+	addValues(sheet, "Exported on 2021-03-04", nil, nil)
+	addValues(sheet, " Region ", "Quarter", nil, "Total")
+	addValues(sheet, "North", "Q1", 3, 1250.5)
+	addValues(sheet, "South", "Q1", 2, 980)
+	index, headers := HeaderDetect(sheet)
+	c.Assert(index, Equals, 3)
+	c.Assert(headers, DeepEquals, []string{"Region", "Quarter", "", "Total"})
+
+	// Headers in the first row are found there.
+	sheet, err = file.AddSheet("Plain")
+	c.Assert(err, IsNil)
+	addValues(sheet, "Id", "Name")
+	addValues(sheet, "A-1", "Pen")
+	index, headers = HeaderDetect(sheet)
+	c.Assert(index, Equals, 0)
+	c.Assert(headers, DeepEquals, []string{"Id", "Name"})
+
+	// Rows of numbers or repeated values aren't headers.
+	sheet, err = file.AddSheet("Data")
+	c.Assert(err, IsNil)
+	addValues(sheet, 1, 2, 3)
+	addValues(sheet, "x", "x", "y")
+	index, headers = HeaderDetect(sheet)
+	c.Assert(index, Equals, -1)
+	c.Assert(headers, IsNil)
+
+	sheet, err = file.AddSheet("Empty")
+	c.Assert(err, IsNil)
+	index, _ = HeaderDetect(sheet)
+	c.Assert(index, Equals, -1)
+}
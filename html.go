@@ -0,0 +1,257 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Many reports are already produced as HTML tables.  AddSheetFromHTML
+// mirrors such a table in a sheet: the cells of its rows become cells,
+// header cells and the text in b, strong, i, em and u elements are
+// bold, italic or underlined, the align attribute of cells sets their
+// horizontal alignment, and cells spanning several columns or rows are
+// merged.  Styles apply to whole cells, and the text of the cells is
+// kept as strings.  The HTML is read the lenient way browsers read it,
+// with optional end tags and HTML entities, but scripts and comments
+// aren't interpreted.
+
+// ErrNoHTMLTable is returned by AddSheetFromHTML when the HTML doesn't
+// hold a table.
+var ErrNoHTMLTable = errors.New("the HTML holds no table")
+
+// htmlCellStyle is the style of a cell read from an HTML table.
+type htmlCellStyle struct {
+	bold, italic, underline bool
+	align                   string
+}
+
+// htmlTableReader reads the first table of an HTML document into a
+// Sheet.
+type htmlTableReader struct {
+	sheet *Sheet
+	// row is the index of the current row, -1 before the first one,
+	// and col the index of the next cell in it.
+	row, col int
+	// cell is the cell being read, nil between cells, along with its
+	// text and style.
+	cell  *Cell
+	text  bytes.Buffer
+	style htmlCellStyle
+	// covered holds the cells covered by the cells spanning several
+	// rows, as "row,col".
+	covered map[string]bool
+	styles  map[htmlCellStyle]*Style
+}
+
+// AddSheetFromHTML adds a sheet called name holding the first table of
+// the HTML read from r.  Tables nested in its cells are read as text.
+func (f *File) AddSheetFromHTML(name string, r io.Reader) (*Sheet, error) {
+	sheet, err := f.AddSheet(name)
+	if err != nil {
+		return nil, err
+	}
+	reader := &htmlTableReader{
+		sheet:   sheet,
+		row:     -1,
+		covered: make(map[string]bool),
+		styles:  make(map[htmlCellStyle]*Style),
+	}
+	if err := reader.read(r); err != nil {
+		f.removeLastSheet()
+		return nil, err
+	}
+	return sheet, nil
+}
+
+// removeLastSheet removes the sheet added last to the File.
+func (f *File) removeLastSheet() {
+	last := f.Sheets[len(f.Sheets)-1]
+	f.Sheets = f.Sheets[:len(f.Sheets)-1]
+	delete(f.Sheet, last.Name)
+}
+
+// read reads the first table of the HTML read from r.
+func (tr *htmlTableReader) read(r io.Reader) error {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+	// depth is the number of tables open, the table read being the
+	// first one.
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			if name == "table" {
+				depth++
+				if depth == 1 {
+					continue
+				}
+			}
+			if depth == 0 {
+				continue
+			}
+			if depth == 1 {
+				switch name {
+				case "tr":
+					tr.endCell()
+					tr.row++
+					tr.col = 0
+					continue
+				case "td", "th":
+					tr.startCell(name == "th", t.Attr)
+					continue
+				}
+			}
+			tr.startInline(name)
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			if depth == 1 {
+				switch name {
+				case "table":
+					tr.endCell()
+					return nil
+				case "tr", "td", "th":
+					tr.endCell()
+					continue
+				}
+			}
+			if name == "table" {
+				depth--
+			}
+			if depth > 0 && (name == "tr" || name == "p" || name == "div") {
+				tr.text.WriteString(" ")
+			}
+		case xml.CharData:
+			if tr.cell != nil {
+				// Line breaks in the HTML are white space, only
+				// br elements break lines.
+				tr.text.WriteString(strings.Map(htmlSpace, string(t)))
+			}
+		}
+	}
+	if depth == 0 {
+		return ErrNoHTMLTable
+	}
+	tr.endCell()
+	return nil
+}
+
+// startCell starts a cell of the current row, header cells being bold.
+func (tr *htmlTableReader) startCell(header bool, attrs []xml.Attr) {
+	tr.endCell()
+	if tr.row < 0 {
+		// A cell outside of any row starts the first one.
+		tr.row = 0
+	}
+	for tr.covered[strconv.Itoa(tr.row)+","+strconv.Itoa(tr.col)] {
+		tr.col++
+	}
+	colspan, rowspan := 1, 1
+	tr.style = htmlCellStyle{bold: header}
+	for _, attr := range attrs {
+		switch strings.ToLower(attr.Name.Local) {
+		case "colspan":
+			colspan = htmlSpan(attr.Value)
+		case "rowspan":
+			rowspan = htmlSpan(attr.Value)
+		case "align":
+			switch align := strings.ToLower(strings.TrimSpace(attr.Value)); align {
+			case "left", "center", "right", "justify":
+				tr.style.align = align
+			}
+		}
+	}
+	tr.cell = tr.sheet.Cell(tr.row, tr.col)
+	tr.cell.HMerge = colspan - 1
+	tr.cell.VMerge = rowspan - 1
+	for r := tr.row; r < tr.row+rowspan; r++ {
+		for c := tr.col; c < tr.col+colspan; c++ {
+			tr.covered[strconv.Itoa(r)+","+strconv.Itoa(c)] = true
+		}
+	}
+	tr.col += colspan
+}
+
+// startInline notes the style of an element inside the current cell.
+func (tr *htmlTableReader) startInline(name string) {
+	if tr.cell == nil {
+		return
+	}
+	switch name {
+	case "b", "strong", "th":
+		tr.style.bold = true
+	case "i", "em":
+		tr.style.italic = true
+	case "u":
+		tr.style.underline = true
+	case "br":
+		tr.text.WriteString("\n")
+	}
+}
+
+// endCell sets the text and style of the cell being read, if any.
+func (tr *htmlTableReader) endCell() {
+	if tr.cell == nil {
+		return
+	}
+	tr.cell.SetString(htmlText(tr.text.String()))
+	if tr.style != (htmlCellStyle{}) {
+		style, ok := tr.styles[tr.style]
+		if !ok {
+			style = NewStyle()
+			style.Font.Bold = tr.style.bold
+			style.Font.Italic = tr.style.italic
+			style.Font.Underline = tr.style.underline
+			style.ApplyFont = tr.style.bold || tr.style.italic || tr.style.underline
+			if tr.style.align != "" {
+				style.Alignment.Horizontal = tr.style.align
+				style.ApplyAlignment = true
+			}
+			tr.styles[tr.style] = style
+		}
+		tr.cell.SetStyle(style)
+	}
+	tr.cell = nil
+	tr.text.Reset()
+}
+
+// htmlSpan returns the number of columns or rows given by a colspan or
+// rowspan attribute, 1 if it isn't a positive number.
+func htmlSpan(value string) int {
+	span, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || span < 1 {
+		return 1
+	}
+	return span
+}
+
+// htmlSpace maps the line breaks and tabs of HTML text to spaces.
+func htmlSpace(r rune) rune {
+	if r == '\n' || r == '\r' || r == '\t' {
+		return ' '
+	}
+	return r
+}
+
+// htmlText collapses the white space of the text of an HTML cell, as
+// browsers show it, keeping the line breaks of br elements.
+func htmlText(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
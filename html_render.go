@@ -0,0 +1,270 @@
+package xlsx
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ToHTML and WriteHTML render the cells of a sheet as an HTML table,
+// for previews of workbooks in a browser.  The styles of the cells are
+// approximated with inline CSS: fonts, solid fills, borders and
+// alignment are kept, while patterns, gradients and conditional
+// formats are not.  Values are formatted by their number format, and
+// hidden rows and columns are left out.  Cells merged from outside the
+// range are rendered on their own.
+
+// htmlBorderStyles maps the styles of the borders of cells to CSS.
+var htmlBorderStyles = map[string]string{
+	"thin":             "1px solid",
+	"hair":             "1px dotted",
+	"dotted":           "1px dotted",
+	"dashed":           "1px dashed",
+	"dashDot":          "1px dashed",
+	"dashDotDot":       "1px dashed",
+	"medium":           "2px solid",
+	"mediumDashed":     "2px dashed",
+	"mediumDashDot":    "2px dashed",
+	"mediumDashDotDot": "2px dashed",
+	"slantDashDot":     "2px dashed",
+	"thick":            "3px solid",
+	"double":           "3px double",
+}
+
+// ToHTML returns the cells of ref, such as "B2:D10", as an HTML table,
+// or those of the whole Sheet if ref is empty.
+func (s *Sheet) ToHTML(ref string) (string, error) {
+	var out bytes.Buffer
+	if err := s.WriteHTML(&out, ref); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// WriteHTML writes the cells of ref, such as "B2:D10", as an HTML table
+// to w, or those of the whole Sheet if ref is empty.
+func (s *Sheet) WriteHTML(w io.Writer, ref string) error {
+	minCol, minRow, maxCol, maxRow, err := s.rangeBounds(ref)
+	if err != nil {
+		return err
+	}
+	font := DefaultFont()
+	var out bytes.Buffer
+	fmt.Fprintf(&out, `<table style="border-collapse:collapse;font-family:%s;font-size:%dpt">`,
+		html.EscapeString(cssString(font.Name)), font.Size)
+	out.WriteString("\n<colgroup>")
+	for col := minCol; col <= maxCol; col++ {
+		if c := s.colAt(col); c != nil && c.Hidden {
+			continue
+		}
+		fmt.Fprintf(&out, `<col style="width:%dpx">`, htmlColumnWidth(s.colAt(col)))
+	}
+	out.WriteString("</colgroup>\n")
+	covered := make(map[[2]int]bool)
+	for row := minRow; row <= maxRow; row++ {
+		var r *Row
+		if row < len(s.rowList()) {
+			r = s.rowList()[row]
+		}
+		if r != nil {
+			r.load()
+			if r.Hidden {
+				continue
+			}
+		}
+		out.WriteString("<tr>")
+		for col := minCol; col <= maxCol; col++ {
+			if c := s.colAt(col); c != nil && c.Hidden || covered[[2]int{row, col}] {
+				continue
+			}
+			var cell *Cell
+			if r != nil && col < len(r.Cells) {
+				cell = r.Cells[col]
+			}
+			if cell == nil {
+				out.WriteString("<td></td>")
+				continue
+			}
+			out.WriteString("<td")
+			lastCol, lastRow := mergeEnd(cell.HMerge, col, maxCol), mergeEnd(cell.VMerge, row, maxRow)
+			colSpan, rowSpan := 0, 0
+			for i := col; i <= lastCol; i++ {
+				if c := s.colAt(i); c == nil || !c.Hidden {
+					colSpan++
+				}
+			}
+			for i := row; i <= lastRow; i++ {
+				if i >= len(s.rowList()) || s.rowList()[i] == nil || !s.rowList()[i].Hidden {
+					rowSpan++
+				}
+				for j := col; j <= lastCol; j++ {
+					covered[[2]int{i, j}] = true
+				}
+			}
+			if colSpan > 1 {
+				fmt.Fprintf(&out, ` colspan="%d"`, colSpan)
+			}
+			if rowSpan > 1 {
+				fmt.Fprintf(&out, ` rowspan="%d"`, rowSpan)
+			}
+			if css := cellCSS(cell, font); css != "" {
+				fmt.Fprintf(&out, ` style="%s"`, html.EscapeString(css))
+			}
+			out.WriteString(">")
+			value, err := cell.FormattedValue()
+			if err != nil {
+				value = cell.Value
+			}
+			out.WriteString(strings.Replace(html.EscapeString(value), "\n", "<br>", -1))
+			out.WriteString("</td>")
+		}
+		out.WriteString("</tr>\n")
+	}
+	out.WriteString("</table>\n")
+	_, err = io.WriteString(w, out.String())
+	return err
+}
+
+// rangeBounds returns the zero based bounds of ref, a range such as
+// "B2:D10" or a single cell, or those of the whole Sheet if ref is
+// empty.
+func (s *Sheet) rangeBounds(ref string) (minCol, minRow, maxCol, maxRow int, err error) {
+	if ref == "" {
+		return 0, 0, s.MaxCol - 1, s.MaxRow - 1, nil
+	}
+	if !strings.Contains(ref, cellRangeChar) {
+		ref += cellRangeChar + ref
+	}
+	minCol, minRow, maxCol, maxRow, err = getMaxMinFromDimensionRef(ref)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	if minCol > maxCol || minRow > maxRow {
+		return 0, 0, 0, 0, fmt.Errorf("invalid range %q", ref)
+	}
+	return minCol, minRow, maxCol, maxRow, nil
+}
+
+// colAt returns the Col of the Sheet at index, or nil if it has none.
+func (s *Sheet) colAt(index int) *Col {
+	if index < len(s.Cols) {
+		return s.Cols[index]
+	}
+	return nil
+}
+
+// htmlColumnWidth returns the width in pixels of a column, given in
+// characters, Excel adding 5 pixels of padding to 7 pixels a character.
+func htmlColumnWidth(col *Col) int {
+	width := ColWidth
+	if col != nil && col.Width > 0 {
+		width = col.Width
+	}
+	return int(width*7 + 5)
+}
+
+// mergeEnd returns the index of the last column or row that a cell at
+// index, merged with merge more of them, covers up to last.
+func mergeEnd(merge, index, last int) int {
+	if merge < 0 {
+		merge = 0
+	}
+	if index+merge > last {
+		return last
+	}
+	return index + merge
+}
+
+// cellCSS returns the CSS approximating the style of a cell, leaving
+// out the font of the table, defaultFont.
+func cellCSS(cell *Cell, defaultFont *Font) string {
+	var css []string
+	style := cell.style
+	align := ""
+	if style != nil {
+		font := style.Font
+		if font.Name != "" && font.Name != defaultFont.Name {
+			css = append(css, "font-family:"+cssString(font.Name))
+		}
+		if font.Size > 0 && font.Size != defaultFont.Size {
+			css = append(css, "font-size:"+strconv.Itoa(font.Size)+"pt")
+		}
+		if font.Bold {
+			css = append(css, "font-weight:bold")
+		}
+		if font.Italic {
+			css = append(css, "font-style:italic")
+		}
+		if font.Underline {
+			css = append(css, "text-decoration:underline")
+		}
+		if color := cssColor(font.Color); color != "" {
+			css = append(css, "color:"+color)
+		}
+		if style.Fill.PatternType == "solid" {
+			if color := cssColor(style.Fill.FgColor); color != "" {
+				css = append(css, "background-color:"+color)
+			}
+		}
+		border := style.Border
+		css = appendBorderCSS(css, "left", border.Left, border.LeftColor)
+		css = appendBorderCSS(css, "right", border.Right, border.RightColor)
+		css = appendBorderCSS(css, "top", border.Top, border.TopColor)
+		css = appendBorderCSS(css, "bottom", border.Bottom, border.BottomColor)
+		align = style.Alignment.Horizontal
+		switch style.Alignment.Vertical {
+		case "top":
+			css = append(css, "vertical-align:top")
+		case "center":
+			css = append(css, "vertical-align:middle")
+		}
+		if style.Alignment.WrapText {
+			css = append(css, "white-space:pre-wrap")
+		}
+	}
+	switch align {
+	case "left", "right", "center", "justify":
+		css = append(css, "text-align:"+align)
+	case "centerContinuous":
+		css = append(css, "text-align:center")
+	case "", "general":
+		// Excel aligns numbers to the right by default.
+		if cell.Type() == CellTypeNumeric || cell.Type() == CellTypeDate {
+			css = append(css, "text-align:right")
+		}
+	}
+	return strings.Join(css, ";")
+}
+
+// appendBorderCSS appends the CSS of the border of a side of a cell to
+// css.
+func appendBorderCSS(css []string, side, style, color string) []string {
+	border, ok := htmlBorderStyles[style]
+	if !ok {
+		return css
+	}
+	if color = cssColor(color); color == "" {
+		color = "#000000"
+	}
+	return append(css, "border-"+side+":"+border+" "+color)
+}
+
+// cssColor converts an ARGB or RGB color, such as "FFFF0000", into its
+// CSS form, "#FF0000".  It returns an empty string for anything else.
+func cssColor(color string) string {
+	if len(color) == 8 {
+		color = color[2:]
+	}
+	if !isRGBColor(color) {
+		return ""
+	}
+	return "#" + strings.ToUpper(color)
+}
+
+// cssString quotes a font name for CSS.
+func cssString(name string) string {
+	return `'` + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(name) + `'`
+}
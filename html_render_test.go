@@ -0,0 +1,65 @@
+package xlsx
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type HTMLRenderSuite struct{}
+
+var _ = Suite(&HTMLRenderSuite{})
+
+func (s *HTMLRenderSuite) TestToHTML(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Report")
+	c.Assert(err, IsNil)
+	row := sheet.AddRow()
+	header := row.AddCell()
+	header.Value = "Sales & <costs>"
+	header.Merge(1, 0)
+	style := NewStyle()
+	style.Font.Bold = true
+	style.Font.Color = "FFFF0000"
+	style.Fill = *NewFill("solid", "FFFFFF00", "")
+	style.Border = *NewBorder("none", "none", "none", "thin")
+	style.Alignment.Horizontal = "center"
+	header.SetStyle(style)
+	row.AddCell()
+	row = sheet.AddRow()
+	row.AddCell().Value = "two\nlines"
+	row.AddCell().SetInt(42)
+
+	out, err := sheet.ToHTML("")
+	c.Assert(err, IsNil)
+	c.Assert(strings.HasPrefix(out, `<table style="border-collapse:collapse;font-family:&#39;Verdana&#39;;font-size:12pt">`), Equals, true)
+	c.Assert(strings.Count(out, `<col style="width:71px">`), Equals, 2)
+	c.Assert(strings.Contains(out, `<tr><td colspan="2" style="font-weight:bold;color:#FF0000;background-color:#FFFF00;border-bottom:1px solid #000000;text-align:center">Sales &amp; &lt;costs&gt;</td></tr>`), Equals, true)
+	c.Assert(strings.Contains(out, `<tr><td>two<br>lines</td><td style="text-align:right">42</td></tr>`), Equals, true)
+}
+
+func (s *HTMLRenderSuite) TestToHTMLRange(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Report")
+	c.Assert(err, IsNil)
+	for i := 0; i < 3; i++ {
+		row := sheet.AddRow()
+		for j := 0; j < 3; j++ {
+			row.AddCell().Value = string(rune('a'+j)) + string(rune('1'+i))
+		}
+	}
+	sheet.Row(1).Hidden = true
+	sheet.Col(2).Hidden = true
+	sheet.Rows[0].Cells[1].Merge(5, 5)
+
+	out, err := sheet.ToHTML("B1:C3")
+	c.Assert(err, IsNil)
+	c.Assert(out, Matches, `(?s)<table[^>]*>\n<colgroup><col style="width:71px"></colgroup>\n<tr><td rowspan="2">b1</td></tr>\n<tr></tr>\n</table>\n`)
+
+	out, err = sheet.ToHTML("A3")
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(out, "<tr><td>a3</td></tr>"), Equals, true)
+
+	_, err = sheet.ToHTML("C3:A1")
+	c.Assert(err, NotNil)
+}
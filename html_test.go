@@ -0,0 +1,65 @@
+package xlsx
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type HTMLSuite struct{}
+
+var _ = Suite(&HTMLSuite{})
+
+func (s *HTMLSuite) TestAddSheetFromHTML(c *C) {
+	html := `<html><body><p>Sales&nbsp;report</p>
+<table border="1">
+  <thead>
+    <tr><th colspan="2">Region</th><th>Total</th></tr>
+  </thead>
+  <tbody>
+    <tr><td rowspan="2">North</td><td>Q1</td><td align="right">1 200</td>
+    <tr><td>Q2</td><td align="right"><b>1 350</b></td>
+    <tr><td>South</td><td><i>Q1</i><br>first
+        quarter</td><td>&amp; 980</td></tr>
+  </tbody>
+</table></body></html>`
+	f := NewFile()
+	sheet, err := f.AddSheetFromHTML("Sales", strings.NewReader(html))
+	c.Assert(err, IsNil)
+	c.Assert(sheet.Name, Equals, "Sales")
+	c.Assert(sheet.MaxRow, Equals, 4)
+	c.Assert(sheet.MaxCol, Equals, 3)
+
+	region := sheet.Cell(0, 0)
+	c.Assert(region.Value, Equals, "Region")
+	c.Assert(region.HMerge, Equals, 1)
+	c.Assert(region.GetStyle().Font.Bold, Equals, true)
+	c.Assert(sheet.Cell(0, 2).Value, Equals, "Total")
+
+	north := sheet.Cell(1, 0)
+	c.Assert(north.Value, Equals, "North")
+	c.Assert(north.VMerge, Equals, 1)
+	c.Assert(north.GetStyle().Font.Bold, Equals, false)
+	c.Assert(sheet.Cell(1, 2).GetStyle().Alignment.Horizontal, Equals, "right")
+	// The cell below North is covered by it.
+	c.Assert(sheet.Cell(2, 0).Value, Equals, "")
+	c.Assert(sheet.Cell(2, 1).Value, Equals, "Q2")
+	c.Assert(sheet.Cell(2, 2).Value, Equals, "1 350")
+	c.Assert(sheet.Cell(2, 2).GetStyle().Font.Bold, Equals, true)
+
+	c.Assert(sheet.Cell(3, 0).Value, Equals, "South")
+	c.Assert(sheet.Cell(3, 1).Value, Equals, "Q1\nfirst quarter")
+	c.Assert(sheet.Cell(3, 1).GetStyle().Font.Italic, Equals, true)
+	c.Assert(sheet.Cell(3, 2).Value, Equals, "& 980")
+
+	_, err = f.MarshallParts()
+	c.Assert(err, IsNil)
+}
+
+func (s *HTMLSuite) TestAddSheetFromHTMLWithoutTable(c *C) {
+	f := NewFile()
+	_, err := f.AddSheetFromHTML("Empty", strings.NewReader("<p>No data</p>"))
+	c.Assert(err, Equals, ErrNoHTMLTable)
+	c.Assert(f.Sheets, HasLen, 0)
+	c.Assert(f.Sheet["Empty"], IsNil)
+}
@@ -0,0 +1,346 @@
+package xlsx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const relationshipTypeHyperlink = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink"
+
+// Hyperlink makes the cells in Ref, a cell or range such as "A2" or
+// "A2:C2", link to a location of the workbook or to an external
+// target, such as a web page, a mail address or a file.
+type Hyperlink struct {
+	Ref string
+	// Location is the place of the workbook linked to, such as
+	// "'Sales 2019'!B4" or a defined name, see HyperlinkLocation.
+	Location string
+	// Target is the external target linked to, as stored in the
+	// file, see AddExternalHyperlink.
+	Target string
+	// Display is the text shown for the link, which Excel takes from
+	// the cell.
+	Display string
+	// Tooltip is shown when the mouse hovers over the link.
+	Tooltip string
+}
+
+// HyperlinkLocation returns the location of the cell or range ref of
+// the sheet named sheetName, to be used as the Location of a Hyperlink.
+// The sheet name is quoted if needed.
+func HyperlinkLocation(sheetName, ref string) string {
+	return quoteSheetName(sheetName) + externalSheetBangChar + ref
+}
+
+// quoteSheetName returns the sheet name as written in a reference,
+// between single quotes, doubled inside the name, unless the name is
+// made of letters, digits, underscores and dots only and doesn't start
+// with a digit.
+func quoteSheetName(sheetName string) string {
+	needsQuotes := sheetName == ""
+	for i, r := range sheetName {
+		switch {
+		case r == '_' || r == '.' || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z'):
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			needsQuotes = true
+		}
+	}
+	if !needsQuotes {
+		return sheetName
+	}
+	return "'" + strings.Replace(sheetName, "'", "''", -1) + "'"
+}
+
+// AddHyperlink makes the cells in ref link to location, a place of the
+// workbook such as returned by HyperlinkLocation, and returns the new
+// Hyperlink so that its display text or tooltip can be set.
+func (s *Sheet) AddHyperlink(ref, location string) *Hyperlink {
+	hyperlink := &Hyperlink{Ref: ref, Location: location}
+	s.Hyperlinks = append(s.Hyperlinks, hyperlink)
+	return hyperlink
+}
+
+// AddExternalHyperlink makes the cells in ref link to target, and
+// returns the new Hyperlink.  The target may be a URL such as
+// "https://example.com/", a mail link such as "mailto:bob@example.com"
+// (see MailtoHyperlinkTarget), a file URL, a Windows path such as
+// "C:\Reports\May.xlsx", a UNC path such as "\\server\share\May.xlsx"
+// or a path relative to the workbook.  Paths are turned into file URLs
+// the way Excel stores them, and the characters that can't appear in a
+// URL, such as spaces, are percent-encoded.
+func (s *Sheet) AddExternalHyperlink(ref, target string) (*Hyperlink, error) {
+	encoded, err := encodeHyperlinkTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	hyperlink := &Hyperlink{Ref: ref, Target: encoded}
+	s.Hyperlinks = append(s.Hyperlinks, hyperlink)
+	return hyperlink, nil
+}
+
+// MailtoHyperlinkTarget returns the target of a link writing a mail to
+// address, with the given subject and body if they aren't empty.  They
+// are percent-encoded, spaces as %20 rather than +, which mail clients
+// would show, and line breaks as CRLF.
+func MailtoHyperlinkTarget(address, subject, body string) string {
+	target := "mailto:" + percentEncode(address, isMailtoAddressByte)
+	var fields []string
+	if subject != "" {
+		fields = append(fields, "subject="+percentEncode(subject, isUnreservedByte))
+	}
+	if body != "" {
+		body = strings.Replace(body, "\r\n", "\n", -1)
+		body = strings.Replace(body, "\n", "\r\n", -1)
+		fields = append(fields, "body="+percentEncode(body, isUnreservedByte))
+	}
+	if len(fields) > 0 {
+		target += "?" + strings.Join(fields, "&")
+	}
+	return target
+}
+
+// encodeHyperlinkTarget validates target and returns it as stored in
+// a file, see AddExternalHyperlink.
+func encodeHyperlinkTarget(target string) (string, error) {
+	switch {
+	case target == "":
+		return "", errors.New("empty hyperlink target")
+	case strings.HasPrefix(target, `\\`):
+		// UNC paths are kept as they are behind the scheme.
+		return "file:///" + percentEncode(target, isURLByte), nil
+	case len(target) > 2 && isLetter(target[0]) && target[1] == ':' && (target[2] == '\\' || target[2] == '/'):
+		return "file:///" + percentEncode(target, isURLByte), nil
+	}
+	scheme := ""
+	if i := strings.Index(target, ":"); i > 0 {
+		scheme = strings.ToLower(target[:i])
+	}
+	encoded := percentEncode(target, isURLByte)
+	switch scheme {
+	case "mailto":
+		address := encoded[len("mailto:"):]
+		if i := strings.Index(address, "?"); i >= 0 {
+			address = address[:i]
+		}
+		for _, recipient := range strings.Split(address, ",") {
+			if err := checkMailAddress(recipient); err != nil {
+				return "", fmt.Errorf("invalid mailto hyperlink target '%s': %s", target, err)
+			}
+		}
+	case "file":
+		if !strings.HasPrefix(encoded[len("file:"):], "//") {
+			return "", fmt.Errorf("invalid file hyperlink target '%s': the path must start with //", target)
+		}
+	}
+	if _, err := url.Parse(encoded); err != nil {
+		return "", fmt.Errorf("invalid hyperlink target '%s': %s", target, err)
+	}
+	return encoded, nil
+}
+
+// checkMailAddress returns an error if the percent-encoded address
+// isn't of the form local@domain.
+func checkMailAddress(address string) error {
+	decoded, err := url.PathUnescape(address)
+	if err != nil {
+		return err
+	}
+	at := strings.LastIndex(decoded, "@")
+	if at <= 0 || at == len(decoded)-1 {
+		return fmt.Errorf("'%s' isn't a mail address", decoded)
+	}
+	if strings.ContainsAny(decoded, " \t\r\n<>") {
+		return fmt.Errorf("'%s' isn't a mail address", decoded)
+	}
+	return nil
+}
+
+// percentEncode percent-encodes the bytes of s for which keep returns
+// false.  A % already starting an escape is kept, so that encoding is
+// idempotent.
+func percentEncode(s string, keep func(byte) bool) string {
+	var encoded bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		if keep(b) || (b == '%' && i+2 < len(s) && isHexDigit(s[i+1]) && isHexDigit(s[i+2])) {
+			encoded.WriteByte(b)
+			continue
+		}
+		fmt.Fprintf(&encoded, "%%%02X", b)
+	}
+	return encoded.String()
+}
+
+func isLetter(b byte) bool {
+	return (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z')
+}
+
+// isUnreservedByte returns true for the bytes that never need to be
+// percent-encoded in a URL.
+func isUnreservedByte(b byte) bool {
+	return isLetter(b) || (b >= '0' && b <= '9') || b == '-' || b == '.' || b == '_' || b == '~'
+}
+
+// isURLByte returns true for the bytes that may appear as they are in
+// a URL or a Windows path, which Excel keeps with its backslashes.
+func isURLByte(b byte) bool {
+	return isUnreservedByte(b) || strings.IndexByte(`:/?#[]@!$&'()*+,;=\`, b) >= 0
+}
+
+// isMailtoAddressByte returns true for the bytes that may appear as
+// they are in the address of a mailto URL.
+func isMailtoAddressByte(b byte) bool {
+	return isUnreservedByte(b) || strings.IndexByte(`@!$'()*+,;=`, b) >= 0
+}
+
+// makeXLSXHyperlinks converts the Hyperlinks of a sheet into their XML
+// representation, intended for internal use only.  The relationships
+// to the targets of the links replace those read with the sheet among
+// rels, which is returned updated.
+func makeXLSXHyperlinks(hyperlinks []*Hyperlink, rels []xlsxWorkbookRelation) (*xlsxHyperlinks, []xlsxWorkbookRelation) {
+	var kept []xlsxWorkbookRelation
+	for _, rel := range rels {
+		if rel.Type != relationshipTypeHyperlink {
+			kept = append(kept, rel)
+		}
+	}
+	xHyperlinks := &xlsxHyperlinks{}
+	nextId := 1
+	for _, hyperlink := range hyperlinks {
+		xHyperlink := xlsxHyperlink{
+			Ref:      hyperlink.Ref,
+			Location: hyperlink.Location,
+			Display:  hyperlink.Display,
+			Tooltip:  hyperlink.Tooltip,
+		}
+		if hyperlink.Target != "" {
+			for hasRelationship(kept, "rId"+strconv.Itoa(nextId)) {
+				nextId++
+			}
+			xHyperlink.Id = "rId" + strconv.Itoa(nextId)
+			kept = append(kept, xlsxWorkbookRelation{
+				Id:         xHyperlink.Id,
+				Target:     hyperlink.Target,
+				Type:       relationshipTypeHyperlink,
+				TargetMode: "External",
+			})
+		} else if hyperlink.Location == "" {
+			// A link to nowhere.
+			continue
+		}
+		xHyperlinks.Hyperlink = append(xHyperlinks.Hyperlink, xHyperlink)
+	}
+	if len(xHyperlinks.Hyperlink) == 0 {
+		return nil, kept
+	}
+	return xHyperlinks, kept
+}
+
+// readHyperlinks converts the XML representation of a sheet's
+// hyperlinks into Hyperlinks, taking their targets from the
+// relationships of the sheet.
+func readHyperlinks(xHyperlinks *xlsxHyperlinks, rels []xlsxWorkbookRelation) []*Hyperlink {
+	if xHyperlinks == nil {
+		return nil
+	}
+	var hyperlinks []*Hyperlink
+	for _, xHyperlink := range xHyperlinks.Hyperlink {
+		hyperlink := &Hyperlink{
+			Ref:      xHyperlink.Ref,
+			Location: xHyperlink.Location,
+			Display:  xHyperlink.Display,
+			Tooltip:  xHyperlink.Tooltip,
+		}
+		for _, rel := range rels {
+			if xHyperlink.Id != "" && rel.Id == xHyperlink.Id {
+				hyperlink.Target = rel.Target
+			}
+		}
+		hyperlinks = append(hyperlinks, hyperlink)
+	}
+	return hyperlinks
+}
+
+// hasRelationship returns true if rels holds a relationship with the
+// given id.
+func hasRelationship(rels []xlsxWorkbookRelation, id string) bool {
+	for _, rel := range rels {
+		if rel.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// hyperlinkTargets returns the targets of the links of the cells of a
+// row written to a StreamFile, as stored in the file, or nil if none of
+// the cells has a link.
+func hyperlinkTargets(cells []StreamCell) ([]string, error) {
+	var targets []string
+	for i, cell := range cells {
+		if cell.Hyperlink == "" {
+			continue
+		}
+		target, err := encodeHyperlinkTarget(cell.Hyperlink)
+		if err != nil {
+			return nil, fmt.Errorf("cell %d of the row: %v", i, err)
+		}
+		if targets == nil {
+			targets = make([]string, len(cells))
+		}
+		targets[i] = target
+	}
+	return targets, nil
+}
+
+// addHyperlinks adds the links of the cells of the row just written to
+// the current sheet of a StreamFile, whose targets, the formula columns
+// aside, are given by targets.
+func (sf *StreamFile) addHyperlinks(targets []string) {
+	dataIndex := 0
+	for colIndex := 0; colIndex < sf.currentSheet.totalColumnCount() && dataIndex < len(targets); colIndex++ {
+		if sf.currentSheet.isFormulaColumn(colIndex) {
+			continue
+		}
+		target := targets[dataIndex]
+		dataIndex++
+		if target == "" {
+			continue
+		}
+		ss, col := sf.currentSheet.column(colIndex)
+		id, ok := ss.hyperlinkIds[target]
+		if !ok {
+			id = sf.addSheetRelationship(ss.index-1, relationshipTypeHyperlink, target, true)
+			if ss.hyperlinkIds == nil {
+				ss.hyperlinkIds = make(map[string]string)
+			}
+			ss.hyperlinkIds[target] = id
+		}
+		ss.hyperlinks = append(ss.hyperlinks, xlsxHyperlink{Ref: GetCellIDStringFromCoords(col, ss.rowCount-1), Id: id})
+	}
+}
+
+// insertStreamHyperlinks returns the end of the XML of a streamed
+// sheet, suffix, with the hyperlinks element holding hyperlinks, which
+// comes before the print options.
+func insertStreamHyperlinks(suffix string, hyperlinks []xlsxHyperlink) string {
+	var out bytes.Buffer
+	out.WriteString(`<hyperlinks xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+	for _, hyperlink := range hyperlinks {
+		out.WriteString(`<hyperlink ref="` + hyperlink.Ref + `" r:id="` + hyperlink.Id + `"></hyperlink>`)
+	}
+	out.WriteString(`</hyperlinks>`)
+	i := strings.Index(suffix, "<printOptions")
+	if i < 0 {
+		i = strings.LastIndex(suffix, "</worksheet>")
+	}
+	if i < 0 {
+		i = len(suffix)
+	}
+	return suffix[:i] + out.String() + suffix[i:]
+}
@@ -0,0 +1,149 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type HyperlinkSuite struct{}
+
+var _ = Suite(&HyperlinkSuite{})
+
+func (s *HyperlinkSuite) TestHyperlinkLocation(c *C) {
+	c.Assert(HyperlinkLocation("Sheet1", "A1"), Equals, "Sheet1!A1")
+	c.Assert(HyperlinkLocation("Sales 2019", "B4:C8"), Equals, "'Sales 2019'!B4:C8")
+	c.Assert(HyperlinkLocation("2019", "A1"), Equals, "'2019'!A1")
+	c.Assert(HyperlinkLocation("Bob's", "A1"), Equals, "'Bob''s'!A1")
+}
+
+// A table of contents links to the other sheets, and the links make
+// the round trip through a file.
+func (s *HyperlinkSuite) TestInternalHyperlinksRoundTrip(c *C) {
+	f := NewFile()
+	toc, err := f.AddSheet("Contents")
+	c.Assert(err, IsNil)
+	_, err = f.AddSheet("Sales 2019")
+	c.Assert(err, IsNil)
+	toc.Cell(0, 0).SetString("Sales 2019")
+	link := toc.AddHyperlink("A1", HyperlinkLocation("Sales 2019", "A1"))
+	link.Tooltip = "Go to the sales"
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"],
+		`<hyperlinks><hyperlink ref="A1" location="&#39;Sales 2019&#39;!A1" tooltip="Go to the sales"></hyperlink></hyperlinks>`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet2.xml"], "<hyperlinks>"), Equals, false)
+
+	var buffer bytes.Buffer
+	c.Assert(f.Write(&buffer), IsNil)
+	f, err = OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheets[0].Hyperlinks, DeepEquals, []*Hyperlink{
+		{Ref: "A1", Location: "'Sales 2019'!A1", Tooltip: "Go to the sales"},
+	})
+	c.Assert(f.Sheets[1].Hyperlinks, HasLen, 0)
+}
+
+// The targets of links outside of the workbook are read from the
+// relationships of the sheet, which are made again from the links
+// when the sheet is written.
+func (s *HyperlinkSuite) TestHyperlinksWithRelationship(c *C) {
+	var xHyperlinks xlsxHyperlinks
+	err := xml.Unmarshal([]byte(`<hyperlinks xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><hyperlink ref="B2" r:id="rId2"/><hyperlink ref="B3" location="Sheet1!A1" display="Top"/></hyperlinks>`), &xHyperlinks)
+	c.Assert(err, IsNil)
+	rels := []xlsxWorkbookRelation{
+		{Id: "rId1", Target: "../drawings/vmlDrawing1.vml", Type: "http://schemas.openxmlformats.org/officeDocument/2006/relationships/vmlDrawing"},
+		{Id: "rId2", Target: "https://example.com/", Type: relationshipTypeHyperlink, TargetMode: "External"},
+	}
+	hyperlinks := readHyperlinks(&xHyperlinks, rels)
+	c.Assert(hyperlinks, DeepEquals, []*Hyperlink{
+		{Ref: "B2", Target: "https://example.com/"},
+		{Ref: "B3", Location: "Sheet1!A1", Display: "Top"},
+	})
+
+	hyperlinks[0].Target = "https://example.org/"
+	xHyperlinksOut, relsOut := makeXLSXHyperlinks(hyperlinks, rels)
+	c.Assert(xHyperlinksOut.Hyperlink, DeepEquals, []xlsxHyperlink{
+		{Ref: "B2", Id: "rId2"},
+		{Ref: "B3", Location: "Sheet1!A1", Display: "Top"},
+	})
+	c.Assert(relsOut, DeepEquals, []xlsxWorkbookRelation{
+		rels[0],
+		{Id: "rId2", Target: "https://example.org/", Type: relationshipTypeHyperlink, TargetMode: "External"},
+	})
+
+	xHyperlinksOut, relsOut = makeXLSXHyperlinks(nil, rels)
+	c.Assert(xHyperlinksOut, IsNil)
+	c.Assert(relsOut, DeepEquals, rels[:1])
+}
+
+func (s *HyperlinkSuite) TestEncodeHyperlinkTarget(c *C) {
+	cases := map[string]string{
+		"https://example.com/a b?q=1#top":         "https://example.com/a%20b?q=1#top",
+		"https://example.com/caf%C3%A9":           "https://example.com/caf%C3%A9",
+		"https://example.com/café":                "https://example.com/caf%C3%A9",
+		"mailto:bob@example.com":                  "mailto:bob@example.com",
+		"mailto:bob@example.com,ann@example.com":  "mailto:bob@example.com,ann@example.com",
+		"mailto:bob@example.com?subject=Hi there": "mailto:bob@example.com?subject=Hi%20there",
+		"file:///C:/My Reports/May.xlsx":          "file:///C:/My%20Reports/May.xlsx",
+		`C:\My Reports\May.xlsx`:                  `file:///C:\My%20Reports\May.xlsx`,
+		`\\server\share\May 2019.xlsx`:            `file:///\\server\share\May%202019.xlsx`,
+		`Reports\May.xlsx`:                        `Reports\May.xlsx`,
+		"100% done.xlsx":                          "100%25%20done.xlsx",
+	}
+	for target, expected := range cases {
+		encoded, err := encodeHyperlinkTarget(target)
+		c.Assert(err, IsNil)
+		c.Assert(encoded, Equals, expected)
+	}
+	for _, target := range []string{"", "mailto:", "mailto:bob", "mailto:@example.com", "mailto:bob@", "mailto:bob@example.com,ann", "file:C:/May.xlsx"} {
+		_, err := encodeHyperlinkTarget(target)
+		c.Assert(err, NotNil)
+	}
+}
+
+func (s *HyperlinkSuite) TestMailtoHyperlinkTarget(c *C) {
+	c.Assert(MailtoHyperlinkTarget("bob@example.com", "", ""), Equals, "mailto:bob@example.com")
+	c.Assert(MailtoHyperlinkTarget("bob+sales@example.com", "Q&A: 50% off?", "Hello Bob,\nSee you"), Equals,
+		"mailto:bob+sales@example.com?subject=Q%26A%3A%2050%25%20off%3F&body=Hello%20Bob%2C%0D%0ASee%20you")
+	target := MailtoHyperlinkTarget("bob@example.com", "Hi", "")
+	encoded, err := encodeHyperlinkTarget(target)
+	c.Assert(err, IsNil)
+	c.Assert(encoded, Equals, target)
+}
+
+// Mail links written to a file are read back as they were.
+func (s *HyperlinkSuite) TestExternalHyperlinksRoundTrip(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Contacts")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetString("bob@example.com")
+	_, err = sheet.AddExternalHyperlink("A1", MailtoHyperlinkTarget("bob@example.com", "Hello there", ""))
+	c.Assert(err, IsNil)
+	_, err = sheet.AddExternalHyperlink("A2", `\\server\share\Bob.xlsx`)
+	c.Assert(err, IsNil)
+	sheet.AddHyperlink("A3", "Contacts!A1")
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/worksheets/_rels/sheet1.xml.rels"],
+		`<Relationship Id="rId1" Target="mailto:bob@example.com?subject=Hello%20there" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" TargetMode="External"></Relationship>`), Equals, true)
+
+	var buffer bytes.Buffer
+	c.Assert(f.Write(&buffer), IsNil)
+	f, err = OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheets[0].Hyperlinks, DeepEquals, []*Hyperlink{
+		{Ref: "A1", Target: "mailto:bob@example.com?subject=Hello%20there"},
+		{Ref: "A2", Target: `file:///\\server\share\Bob.xlsx`},
+		{Ref: "A3", Location: "Contacts!A1"},
+	})
+
+	// Saving again doesn't duplicate the relationships.
+	parts, err = f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Count(parts["xl/worksheets/_rels/sheet1.xml.rels"], "<Relationship "), Equals, 2)
+}
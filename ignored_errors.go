@@ -0,0 +1,91 @@
+package xlsx
+
+// IgnoredError suppresses Excel's error checking, and so the green
+// triangle shown in the corner of flagged cells, for the cells in
+// Ref.  Ref is a cell or range such as "A2:A100", several of which
+// may be separated by spaces.
+type IgnoredError struct {
+	Ref string
+	// NumberStoredAsText ignores numbers stored in text cells, for
+	// example identifiers with leading zeros.
+	NumberStoredAsText bool
+	// EvalError ignores formulas that evaluate to an error.
+	EvalError bool
+	// TwoDigitTextYear ignores dates stored as text with a two
+	// digit year.
+	TwoDigitTextYear bool
+	// Formula ignores formulas that are inconsistent with the
+	// formulas around them.
+	Formula bool
+	// FormulaRange ignores formulas that omit adjacent cells.
+	FormulaRange bool
+	// UnlockedFormula ignores unlocked cells containing formulas.
+	UnlockedFormula bool
+	// EmptyCellReference ignores formulas referring to empty cells.
+	EmptyCellReference bool
+	// ListDataValidation ignores values that fail a list validation.
+	ListDataValidation bool
+	// CalculatedColumn ignores cells that differ from the formula of
+	// their table column.
+	CalculatedColumn bool
+}
+
+// IgnoreNumberStoredAsText is a convenience wrapper around
+// AddIgnoredError that stops Excel from flagging numbers stored as
+// text in the cells in ref.
+func (s *Sheet) IgnoreNumberStoredAsText(ref string) {
+	s.AddIgnoredError(&IgnoredError{Ref: ref, NumberStoredAsText: true})
+}
+
+// AddIgnoredError adds an IgnoredError to the Sheet.
+func (s *Sheet) AddIgnoredError(ignoredError *IgnoredError) {
+	s.IgnoredErrors = append(s.IgnoredErrors, ignoredError)
+}
+
+// makeXLSXIgnoredErrors converts the IgnoredErrors of a sheet into
+// their XML representation, intended for internal use only
+func makeXLSXIgnoredErrors(ignoredErrors []*IgnoredError) *xlsxIgnoredErrors {
+	if len(ignoredErrors) == 0 {
+		return nil
+	}
+	xIgnoredErrors := &xlsxIgnoredErrors{}
+	for _, ie := range ignoredErrors {
+		xIgnoredErrors.IgnoredError = append(xIgnoredErrors.IgnoredError, xlsxIgnoredError{
+			Sqref:              ie.Ref,
+			EvalError:          ie.EvalError,
+			TwoDigitTextYear:   ie.TwoDigitTextYear,
+			NumberStoredAsText: ie.NumberStoredAsText,
+			Formula:            ie.Formula,
+			FormulaRange:       ie.FormulaRange,
+			UnlockedFormula:    ie.UnlockedFormula,
+			EmptyCellReference: ie.EmptyCellReference,
+			ListDataValidation: ie.ListDataValidation,
+			CalculatedColumn:   ie.CalculatedColumn,
+		})
+	}
+	return xIgnoredErrors
+}
+
+// readIgnoredErrors converts the XML representation of a sheet's
+// ignored errors into IgnoredErrors.
+func readIgnoredErrors(xIgnoredErrors *xlsxIgnoredErrors) []*IgnoredError {
+	if xIgnoredErrors == nil {
+		return nil
+	}
+	var ignoredErrors []*IgnoredError
+	for _, xie := range xIgnoredErrors.IgnoredError {
+		ignoredErrors = append(ignoredErrors, &IgnoredError{
+			Ref:                xie.Sqref,
+			EvalError:          xie.EvalError,
+			TwoDigitTextYear:   xie.TwoDigitTextYear,
+			NumberStoredAsText: xie.NumberStoredAsText,
+			Formula:            xie.Formula,
+			FormulaRange:       xie.FormulaRange,
+			UnlockedFormula:    xie.UnlockedFormula,
+			EmptyCellReference: xie.EmptyCellReference,
+			ListDataValidation: xie.ListDataValidation,
+			CalculatedColumn:   xie.CalculatedColumn,
+		})
+	}
+	return ignoredErrors
+}
@@ -0,0 +1,56 @@
+package xlsx
+
+import (
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type IgnoredErrorsSuite struct{}
+
+var _ = Suite(&IgnoredErrorsSuite{})
+
+func (s *IgnoredErrorsSuite) TestMarshalIgnoredErrors(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetString("0000123")
+	sheet.IgnoreNumberStoredAsText("A1:A100")
+	sheet.AddIgnoredError(&IgnoredError{Ref: "B1 D1", EvalError: true, Formula: true})
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.HasSuffix(parts["xl/worksheets/sheet1.xml"],
+		`</headerFooter><ignoredErrors><ignoredError sqref="A1:A100" numberStoredAsText="true"></ignoredError>`+
+			`<ignoredError sqref="B1 D1" evalError="true" formula="true"></ignoredError></ignoredErrors></worksheet>`), Equals, true)
+}
+
+func (s *IgnoredErrorsSuite) TestIgnoredErrorsRoundTrip(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetString("0000123")
+	sheet.IgnoreNumberStoredAsText("A1")
+
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	file, err = OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(file.Sheets[0].IgnoredErrors, DeepEquals, []*IgnoredError{{Ref: "A1", NumberStoredAsText: true}})
+}
+
+func (s *IgnoredErrorsSuite) TestStreamIgnoredErrors(c *C) {
+	var buffer bytes.Buffer
+	builder := NewStreamFileBuilder(&buffer)
+	c.Assert(builder.AddSheet("Sheet1", []string{"Id"}, nil), IsNil)
+	builder.AddIgnoredError(0, &IgnoredError{Ref: "A2:A1048576", NumberStoredAsText: true})
+	streamFile, err := builder.Build()
+	c.Assert(err, IsNil)
+	c.Assert(streamFile.Write([]string{"0000123"}), IsNil)
+	c.Assert(streamFile.Close(), IsNil)
+
+	file, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(file.Sheets[0].IgnoredErrors, DeepEquals, []*IgnoredError{{Ref: "A2:A1048576", NumberStoredAsText: true}})
+}
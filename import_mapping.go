@@ -0,0 +1,241 @@
+package xlsx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Imports from the same customer usually come as sheets laid out the
+// same way, whose columns are matched to the fields of the records
+// imported once, when the first upload is reviewed.  An ImportMapping
+// records that matching, and is stored as JSON, with Write, to be read
+// back with ReadImportMapping and applied to the next uploads with
+// ApplyMapping.
+
+// The transforms of the values of a ColumnMapping, applied to their
+// text before it is read in the format of the column.
+const (
+	TransformNone   = ""
+	TransformTrim   = "trim"
+	TransformUpper  = "upper"
+	TransformLower  = "lower"
+	TransformDigits = "digits"
+)
+
+// The formats of the values of a ColumnMapping, which are read as a
+// string, an int, a float64, a bool or a time.Time.
+const (
+	MappingFormatString = "string"
+	MappingFormatInt    = "int"
+	MappingFormatFloat  = "float"
+	MappingFormatBool   = "bool"
+	MappingFormatDate   = "date"
+)
+
+// ColumnMapping maps the column of a sheet with the given header to a
+// field of the records imported.
+type ColumnMapping struct {
+	// Header is the header of the column, matched whatever its case
+	// and the spaces around it.
+	Header string `json:"header"`
+	// Field is the name of the field of the records holding the
+	// values of the column.
+	Field string `json:"field"`
+	// Transform is applied to the text of the values, such as
+	// TransformDigits keeping the digits of phone numbers only.
+	Transform string `json:"transform,omitempty"`
+	// Format is the way the values are read, as strings if empty.
+	Format string `json:"format,omitempty"`
+	// Optional lets the column be missing from the sheet, its field
+	// being left out of the records.
+	Optional bool `json:"optional,omitempty"`
+}
+
+// ImportMapping maps the columns of the sheets imported from a source,
+// such as a customer, to the fields of the records imported.
+type ImportMapping struct {
+	Columns []ColumnMapping `json:"columns"`
+	// DateAmbiguity is the way the dates written with digits are
+	// read, see ParseDate.
+	DateAmbiguity DateAmbiguity `json:"dateAmbiguity,omitempty"`
+}
+
+// ReadImportMapping reads an ImportMapping written as JSON by ImportMapping.Write, and checks it like Validate.
+func ReadImportMapping(r io.Reader) (ImportMapping, error) {
+	var mapping ImportMapping
+	if err := json.NewDecoder(r).Decode(&mapping); err != nil {
+		return ImportMapping{}, err
+	}
+	if err := mapping.Validate(); err != nil {
+		return ImportMapping{}, err
+	}
+	return mapping, nil
+}
+
+// Write writes the mapping to w as JSON, to be read back with ReadImportMapping.
+func (m ImportMapping) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Validate returns an error if a column of the mapping has no header or field, if two columns have the same header or
+// field, or if a transform or format is unknown.
+func (m ImportMapping) Validate() error {
+	headers := make(map[string]bool, len(m.Columns))
+	fields := make(map[string]bool, len(m.Columns))
+	for i, column := range m.Columns {
+		header := foldHeader(column.Header)
+		switch {
+		case header == "":
+			return fmt.Errorf("the column mapping %d has no header", i)
+		case column.Field == "":
+			return fmt.Errorf("the column '%s' isn't mapped to a field", column.Header)
+		case headers[header]:
+			return fmt.Errorf("the column '%s' is mapped twice", column.Header)
+		case fields[column.Field]:
+			return fmt.Errorf("the field '%s' is mapped twice", column.Field)
+		}
+		headers[header], fields[column.Field] = true, true
+		switch column.Transform {
+		case TransformNone, TransformTrim, TransformUpper, TransformLower, TransformDigits:
+		default:
+			return fmt.Errorf("unknown transform '%s' of column '%s'", column.Transform, column.Header)
+		}
+		switch column.Format {
+		case "", MappingFormatString, MappingFormatInt, MappingFormatFloat, MappingFormatBool, MappingFormatDate:
+		default:
+			return fmt.Errorf("unknown format '%s' of column '%s'", column.Format, column.Header)
+		}
+	}
+	return nil
+}
+
+// ApplyMapping returns the records held by the rows of sheet below its header row, found by HeaderDetect, each
+// mapping the fields of mapping to the values of their columns. The fields of the empty cells are left out, and the
+// rows whose mapped cells are all empty are skipped. An error is returned if a column that isn't optional is missing,
+// or if a value can't be read in the format of its column, naming its cell.
+func ApplyMapping(sheet *Sheet, mapping ImportMapping) ([]map[string]interface{}, error) {
+	if err := mapping.Validate(); err != nil {
+		return nil, err
+	}
+	headerRow, headers := HeaderDetect(sheet)
+	if headerRow < 0 {
+		return nil, fmt.Errorf("no header row found in sheet '%s'", sheet.Name)
+	}
+	colIndexes := make(map[string]int, len(headers))
+	for i, header := range headers {
+		if _, ok := colIndexes[foldHeader(header)]; !ok {
+			colIndexes[foldHeader(header)] = i
+		}
+	}
+	mapped := make([]int, len(mapping.Columns))
+	for i, column := range mapping.Columns {
+		colIndex, ok := colIndexes[foldHeader(column.Header)]
+		if !ok && !column.Optional {
+			return nil, fmt.Errorf("no column '%s' in sheet '%s'", column.Header, sheet.Name)
+		}
+		if !ok {
+			colIndex = -1
+		}
+		mapped[i] = colIndex
+	}
+	var records []map[string]interface{}
+	for rowIndex := headerRow + 1; rowIndex < len(sheet.rowList()); rowIndex++ {
+		row := sheet.row(rowIndex)
+		if row == nil {
+			continue
+		}
+		record := make(map[string]interface{})
+		for i, column := range mapping.Columns {
+			if mapped[i] < 0 || mapped[i] >= len(row.Cells) || row.Cells[mapped[i]] == nil {
+				continue
+			}
+			cell := row.Cells[mapped[i]]
+			value, ok, err := mapValue(cell, column, mapping.DateAmbiguity)
+			if err != nil {
+				return nil, fmt.Errorf("cell %s: %v", GetCellIDStringFromCoords(mapped[i], rowIndex), err)
+			}
+			if ok {
+				record[column.Field] = value
+			}
+		}
+		if len(record) > 0 {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// foldHeader returns header as it is matched with the headers of a
+// ColumnMapping, trimmed and in lower case.
+func foldHeader(header string) string {
+	return strings.ToLower(strings.TrimSpace(header))
+}
+
+// mapValue returns the value of cell as column says, or false if the
+// cell is empty once transformed.
+func mapValue(cell *Cell, column ColumnMapping, ambiguity DateAmbiguity) (interface{}, bool, error) {
+	text := cell.Value
+	if column.Format == "" || column.Format == MappingFormatString {
+		// Strings are read the way they are shown, such as ids
+		// with leading zeros.
+		if formatted, err := cell.FormattedValue(); err == nil {
+			text = formatted
+		}
+	}
+	switch column.Transform {
+	case TransformTrim:
+		text = strings.TrimSpace(text)
+	case TransformUpper:
+		text = strings.ToUpper(text)
+	case TransformLower:
+		text = strings.ToLower(text)
+	case TransformDigits:
+		text = strings.Map(func(r rune) rune {
+			if unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, text)
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, false, nil
+	}
+	switch column.Format {
+	case MappingFormatInt:
+		f, err := parseFloat(strings.TrimSpace(text))
+		if err != nil || f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+			return nil, false, fmt.Errorf("%q isn't a whole number", text)
+		}
+		return int(f), true, nil
+	case MappingFormatFloat:
+		f, err := parseFloat(strings.TrimSpace(text))
+		if err != nil {
+			return nil, false, fmt.Errorf("%q isn't a number", text)
+		}
+		return f, true, nil
+	case MappingFormatBool:
+		switch strings.ToLower(strings.TrimSpace(text)) {
+		case "1", "true", "yes", "y":
+			return true, true, nil
+		case "0", "false", "no", "n":
+			return false, true, nil
+		}
+		return nil, false, fmt.Errorf("%q isn't a boolean", text)
+	case MappingFormatDate:
+		t, err := ParseDate(text, cell.date1904, ambiguity)
+		if err != nil {
+			return nil, false, err
+		}
+		return t, true, nil
+	}
+	return text, true, nil
+}
@@ -0,0 +1,78 @@
+package xlsx
+
+import (
+	"bytes"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type ImportMappingSuite struct{}
+
+var _ = Suite(&ImportMappingSuite{})
+
+func (s *ImportMappingSuite) TestApplyMapping(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Upload")
+	c.Assert(err, IsNil)
+	addValues(sheet, "Customer export")
+	addValues(sheet, "Customer No", " PHONE ", "Joined", "Orders", "Active", "Notes")
+	addValues(sheet, "00123", "+44 (20) 7946-0958", "04/03/2021", 12, "yes", "first")
+	addValues(sheet, nil, nil, nil, nil, nil, "only notes")
+	addValues(sheet, "00124", nil, "25/12/2020", 3.0, "no")
+
+	mapping := ImportMapping{
+		Columns: []ColumnMapping{
+			{Header: "customer no", Field: "id"},
+			{Header: "Phone", Field: "phone", Transform: TransformDigits},
+			{Header: "Joined", Field: "joined", Format: MappingFormatDate},
+			{Header: "Orders", Field: "orders", Format: MappingFormatInt},
+			{Header: "Active", Field: "active", Format: MappingFormatBool},
+			{Header: "Region", Field: "region", Optional: true},
+		},
+		DateAmbiguity: DateAmbiguityDayFirst,
+	}
+	var buffer bytes.Buffer
+	c.Assert(mapping.Write(&buffer), IsNil)
+	stored, err := ReadImportMapping(&buffer)
+	c.Assert(err, IsNil)
+	c.Assert(stored, DeepEquals, mapping)
+
+	records, err := ApplyMapping(sheet, stored)
+	c.Assert(err, IsNil)
+	c.Assert(records, DeepEquals, []map[string]interface{}{
+		{
+			"id": "00123", "phone": "442079460958", "joined": time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC),
+			"orders": 12, "active": true,
+		},
+		{"id": "00124", "joined": time.Date(2020, 12, 25, 0, 0, 0, 0, time.UTC), "orders": 3, "active": false},
+	})
+
+	mapping.Columns[5].Optional = false
+	_, err = ApplyMapping(sheet, mapping)
+	c.Assert(err, ErrorMatches, "no column 'Region' in sheet 'Upload'")
+	mapping.Columns = mapping.Columns[:5]
+	mapping.Columns[0].Format = MappingFormatInt
+	mapping.Columns[0].Header = "Phone"
+	mapping.Columns[1].Header = "Customer No"
+	_, err = ApplyMapping(sheet, mapping)
+	c.Assert(err, ErrorMatches, `cell B3: "\+44 \(20\) 7946-0958" isn't a whole number`)
+}
+
+func (s *ImportMappingSuite) TestValidate(c *C) {
+	for _, tc := range []struct {
+		columns []ColumnMapping
+		err     string
+	}{
+		{[]ColumnMapping{{Header: " ", Field: "id"}}, "the column mapping 0 has no header"},
+		{[]ColumnMapping{{Header: "Id"}}, "the column 'Id' isn't mapped to a field"},
+		{[]ColumnMapping{{Header: "Id", Field: "id"}, {Header: " ID", Field: "other"}}, "the column ' ID' is mapped twice"},
+		{[]ColumnMapping{{Header: "Id", Field: "id"}, {Header: "No", Field: "id"}}, "the field 'id' is mapped twice"},
+		{[]ColumnMapping{{Header: "Id", Field: "id", Transform: "reverse"}}, "unknown transform 'reverse' of column 'Id'"},
+		{[]ColumnMapping{{Header: "Id", Field: "id", Format: "decimal"}}, "unknown format 'decimal' of column 'Id'"},
+	} {
+		c.Assert(ImportMapping{Columns: tc.columns}.Validate(), ErrorMatches, tc.err)
+	}
+	_, err := ReadImportMapping(bytes.NewBufferString(`{"columns": [{"header": "Id", "field": "id", "format": "decimal"}]}`))
+	c.Assert(err, ErrorMatches, "unknown format 'decimal' of column 'Id'")
+}
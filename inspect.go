@@ -0,0 +1,118 @@
+package xlsx
+
+import (
+	"strings"
+)
+
+// Part name prefixes that hold review data in an XLSX package.
+// Shared workbooks keep their tracked changes under xl/revisions,
+// legacy notes live in xl/commentsN.xml and modern, threaded comments
+// are split between xl/threadedComments and the xl/persons part that
+// lists their authors.
+var (
+	revisionPartPrefixes = []string{"xl/revisions/"}
+	commentPartPrefixes  = []string{"xl/comments", "xl/threadedComments/", "xl/persons/"}
+)
+
+// DocumentInspection lists the content of a File that may carry
+// personal information or hidden review data, much like the report
+// produced by Excel's Document Inspector.
+type DocumentInspection struct {
+	// Creator and LastModifiedBy are taken from the document's core
+	// properties.
+	Creator        string
+	LastModifiedBy string
+	// RevisionParts are the parts holding tracked changes.
+	RevisionParts []string
+	// CommentParts are the parts holding notes, threaded comments
+	// and the people that wrote them.
+	CommentParts []string
+	// HiddenSheets are the names of the sheets that are hidden.
+	HiddenSheets []string
+	// HiddenRows and HiddenCols count the hidden rows and columns
+	// across all sheets.
+	HiddenRows int
+	HiddenCols int
+}
+
+// HasPersonalInformation returns true if the inspected File names
+// any of the people that created or edited it.
+func (di *DocumentInspection) HasPersonalInformation() bool {
+	return di.Creator != "" || di.LastModifiedBy != ""
+}
+
+// HasReviewData returns true if the inspected File contains tracked
+// changes or comments.
+func (di *DocumentInspection) HasReviewData() bool {
+	return len(di.RevisionParts) > 0 || len(di.CommentParts) > 0
+}
+
+// HasHiddenContent returns true if the inspected File contains
+// hidden sheets, rows or columns.
+func (di *DocumentInspection) HasHiddenContent() bool {
+	return len(di.HiddenSheets) > 0 || di.HiddenRows > 0 || di.HiddenCols > 0
+}
+
+// Inspect reports the personal information, review data and hidden
+// content found in the File, so that it can be checked before the
+// File is redistributed.
+func (f *File) Inspect() *DocumentInspection {
+	di := &DocumentInspection{}
+	if f.coreProperties != nil {
+		di.Creator = f.coreProperties.Creator
+		di.LastModifiedBy = f.coreProperties.LastModifiedBy
+	}
+	for _, name := range f.RawPartNames() {
+		if hasAnyPrefix(name, revisionPartPrefixes) {
+			di.RevisionParts = append(di.RevisionParts, name)
+		} else if hasAnyPrefix(name, commentPartPrefixes) {
+			di.CommentParts = append(di.CommentParts, name)
+		}
+	}
+	for _, sheet := range f.Sheets {
+		if sheet.Hidden {
+			di.HiddenSheets = append(di.HiddenSheets, sheet.Name)
+		}
+		for _, row := range sheet.rowList() {
+			if row != nil && row.Hidden {
+				di.HiddenRows++
+			}
+		}
+		for _, col := range sheet.Cols {
+			if col != nil && col.Hidden {
+				di.HiddenCols++
+			}
+		}
+	}
+	return di
+}
+
+// RemovePersonalInformation clears the names of the people that
+// created and last modified the File from its document properties.
+func (f *File) RemovePersonalInformation() {
+	if f.coreProperties == nil {
+		return
+	}
+	f.coreProperties.Creator = ""
+	f.coreProperties.LastModifiedBy = ""
+}
+
+// RemoveReviewData drops the tracked changes and comments that were
+// read along with the File, so that they will not be written when
+// the File is saved.
+func (f *File) RemoveReviewData() {
+	for _, name := range f.RawPartNames() {
+		if hasAnyPrefix(name, revisionPartPrefixes) || hasAnyPrefix(name, commentPartPrefixes) {
+			f.DeleteRawPart(name)
+		}
+	}
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
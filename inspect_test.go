@@ -0,0 +1,121 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+
+	. "gopkg.in/check.v1"
+)
+
+type InspectSuite struct{}
+
+var _ = Suite(&InspectSuite{})
+
+// addPartsToXLSX copies the XLSX file at path into memory, adding the
+// given extra parts along the way, and opens the result.
+func addPartsToXLSX(c *C, path string, extra map[string]string) *File {
+	source, err := zip.OpenReader(path)
+	c.Assert(err, IsNil)
+	defer source.Close()
+	var buffer bytes.Buffer
+	target := zip.NewWriter(&buffer)
+	for _, f := range source.File {
+		w, err := target.Create(f.Name)
+		c.Assert(err, IsNil)
+		rc, err := f.Open()
+		c.Assert(err, IsNil)
+		_, err = io.Copy(w, rc)
+		c.Assert(err, IsNil)
+		rc.Close()
+	}
+	for name, data := range extra {
+		w, err := target.Create(name)
+		c.Assert(err, IsNil)
+		_, err = w.Write([]byte(data))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(target.Close(), IsNil)
+	file, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	return file
+}
+
+func (s *InspectSuite) TestInspectReadsPersonalInformation(c *C) {
+	file, err := OpenFile("./testdocs/testfile.xlsx")
+	c.Assert(err, IsNil)
+	di := file.Inspect()
+	c.Assert(di.Creator, Equals, "TealeG")
+	c.Assert(di.LastModifiedBy, Equals, "TealeG")
+	c.Assert(di.HasPersonalInformation(), Equals, true)
+	c.Assert(di.HasReviewData(), Equals, false)
+
+	file.RemovePersonalInformation()
+	di = file.Inspect()
+	c.Assert(di.Creator, Equals, "")
+	c.Assert(di.HasPersonalInformation(), Equals, false)
+}
+
+func (s *InspectSuite) TestRemovePersonalInformationIsSaved(c *C) {
+	file, err := OpenFile("./testdocs/testfile.xlsx")
+	c.Assert(err, IsNil)
+	file.coreProperties.Title = "Orders & returns"
+	file.RemovePersonalInformation()
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+
+	file, err = OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	di := file.Inspect()
+	c.Assert(di.Creator, Equals, "")
+	c.Assert(di.LastModifiedBy, Equals, "")
+	c.Assert(di.HasPersonalInformation(), Equals, false)
+	// The other properties are kept.
+	c.Assert(file.coreProperties.Title, Equals, "Orders & returns")
+	c.Assert(file.coreProperties.Created, Not(Equals), "")
+}
+
+func (s *InspectSuite) TestPersonalInformationIsSaved(c *C) {
+	file, err := OpenFile("./testdocs/testfile.xlsx")
+	c.Assert(err, IsNil)
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	file, err = OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(file.Inspect().LastModifiedBy, Equals, "TealeG")
+}
+
+func (s *InspectSuite) TestInspectFindsReviewData(c *C) {
+	file := addPartsToXLSX(c, "./testdocs/testfile.xlsx", map[string]string{
+		"xl/revisions/revisionHeaders.xml":         "<headers/>",
+		"xl/comments1.xml":                         "<comments/>",
+		"xl/threadedComments/threadedComment1.xml": "<ThreadedComments/>",
+	})
+	di := file.Inspect()
+	c.Assert(di.RevisionParts, DeepEquals, []string{"xl/revisions/revisionHeaders.xml"})
+	c.Assert(di.CommentParts, HasLen, 2)
+	c.Assert(di.HasReviewData(), Equals, true)
+
+	file.RemoveReviewData()
+	di = file.Inspect()
+	c.Assert(di.HasReviewData(), Equals, false)
+}
+
+func (s *InspectSuite) TestInspectFindsHiddenContent(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Visible")
+	c.Assert(err, IsNil)
+	row := sheet.AddRow()
+	row.AddCell().SetString("a")
+	row.Hidden = true
+	sheet.Col(0).Hidden = true
+	hidden, err := file.AddSheet("Hidden")
+	c.Assert(err, IsNil)
+	hidden.Hidden = true
+
+	di := file.Inspect()
+	c.Assert(di.HiddenSheets, DeepEquals, []string{"Hidden"})
+	c.Assert(di.HiddenRows, Equals, 1)
+	c.Assert(di.HiddenCols, Equals, 1)
+	c.Assert(di.HasHiddenContent(), Equals, true)
+}
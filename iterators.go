@@ -0,0 +1,116 @@
+//go:build go1.23
+// +build go1.23
+
+package xlsx
+
+import (
+	"io"
+	"iter"
+)
+
+// The Rows, Cells and Sheets fields are slices, so the iterators over
+// them, which can be used in for-range loops and stopped with a break,
+// are called AllRows, AllCells and AllSheets.  Those of Sheet and Row
+// load the rows kept in a cell store, see Sheet.SetCellStore, one at a
+// time, and those of StreamSheetReader and StreamFileReader read the
+// rows of a workbook as they go, without holding them all in memory.
+
+// AllSheets returns an iterator over the index and the Sheet of each
+// sheet of the File, in order.
+func (f *File) AllSheets() iter.Seq2[int, *Sheet] {
+	return func(yield func(int, *Sheet) bool) {
+		for i, sheet := range f.Sheets {
+			if !yield(i, sheet) {
+				return
+			}
+		}
+	}
+}
+
+// AllRows returns an iterator over the index, starting at 0, and the Row
+// of each row of the Sheet, skipping the missing rows. The cells of rows
+// evicted to the cell store of the Sheet are loaded back as they come.
+func (s *Sheet) AllRows() iter.Seq2[int, *Row] {
+	return func(yield func(int, *Row) bool) {
+		for i := 0; i < len(s.rowList()); i++ {
+			row := s.row(i)
+			if row == nil {
+				continue
+			}
+			if !yield(i, row) {
+				return
+			}
+		}
+	}
+}
+
+// AllCells returns an iterator over the index, starting at 0, and the
+// Cell of each cell of the Row, skipping the missing cells.
+func (r *Row) AllCells() iter.Seq2[int, *Cell] {
+	return func(yield func(int, *Cell) bool) {
+		r.load()
+		for i, cell := range r.Cells {
+			if cell == nil {
+				continue
+			}
+			if !yield(i, cell) {
+				return
+			}
+		}
+	}
+}
+
+// AllRows returns an iterator over the index and the values of each
+// remaining row of the sheet, as read by ReadRow. The iteration stops at
+// the first error reading the sheet, which Err returns afterwards.
+func (ssr *StreamSheetReader) AllRows() iter.Seq2[int, []string] {
+	return func(yield func(int, []string) bool) {
+		for ssr.err == nil {
+			rowIndex := ssr.nextRow
+			cells, err := ssr.ReadRow()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				ssr.err = err
+				return
+			}
+			if !yield(rowIndex, cells) {
+				return
+			}
+		}
+	}
+}
+
+// Err returns the error which stopped AllRows, if any.
+func (ssr *StreamSheetReader) Err() error {
+	return ssr.err
+}
+
+// AllSheets returns an iterator over the index and a StreamSheetReader
+// of each sheet of the workbook, in order, which is closed once the body
+// of the loop is done with it. The iteration stops at the first sheet
+// that can't be read, whose error Err returns afterwards.
+func (sfr *StreamFileReader) AllSheets() iter.Seq2[int, *StreamSheetReader] {
+	return func(yield func(int, *StreamSheetReader) bool) {
+		for i := range sfr.sheetFiles {
+			ssr, err := sfr.Sheet(i)
+			if err != nil {
+				sfr.err = err
+				return
+			}
+			more := yield(i, ssr)
+			if err := ssr.Close(); err != nil && sfr.err == nil {
+				sfr.err = err
+			}
+			if !more || sfr.err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Err returns the error which stopped AllSheets, if any.
+func (sfr *StreamFileReader) Err() error {
+	return sfr.err
+}
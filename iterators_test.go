@@ -0,0 +1,107 @@
+//go:build go1.23
+// +build go1.23
+
+package xlsx
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type IteratorsSuite struct{}
+
+var _ = Suite(&IteratorsSuite{})
+
+func iteratorsFile(c *C) *File {
+	file := NewFile()
+	sheet, err := file.AddSheet("First")
+	c.Assert(err, IsNil)
+	row := sheet.AddRow()
+	row.AddCell().Value = "A1"
+	row.AddCell()
+	row.AddCell().Value = "C1"
+	sheet.Row(2).AddCell().Value = "A3"
+	sheet.Row(3).AddCell().Value = "A4"
+	_, err = file.AddSheet("Second")
+	c.Assert(err, IsNil)
+	return file
+}
+
+func (s *IteratorsSuite) TestInMemory(c *C) {
+	file := iteratorsFile(c)
+	var names []string
+	for i, sheet := range file.AllSheets() {
+		c.Assert(sheet, Equals, file.Sheets[i])
+		names = append(names, sheet.Name)
+	}
+	c.Assert(names, DeepEquals, []string{"First", "Second"})
+
+	var cells []string
+	for rowIndex, row := range file.Sheets[0].AllRows() {
+		for colIndex, cell := range row.AllCells() {
+			if cell.Value == "" {
+				continue
+			}
+			c.Assert(cell.Value, Equals, GetCellIDStringFromCoords(colIndex, rowIndex))
+			cells = append(cells, cell.Value)
+		}
+		if rowIndex == 2 {
+			break
+		}
+	}
+	c.Assert(cells, DeepEquals, []string{"A1", "C1", "A3"})
+}
+
+func (s *IteratorsSuite) TestCellStore(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Stored")
+	c.Assert(err, IsNil)
+	c.Assert(sheet.SetCellStore(NewMemoryCellStore(), 1), IsNil)
+	for i := 0; i < 3; i++ {
+		sheet.AddRow().AddCell().SetInt(i)
+	}
+	var values []string
+	for _, row := range sheet.AllRows() {
+		for _, cell := range row.AllCells() {
+			values = append(values, cell.Value)
+		}
+	}
+	c.Assert(values, DeepEquals, []string{"0", "1", "2"})
+}
+
+func (s *IteratorsSuite) TestStreaming(c *C) {
+	var buffer bytes.Buffer
+	c.Assert(iteratorsFile(c).Write(&buffer), IsNil)
+	reader, err := NewStreamFileReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	c.Assert(err, IsNil)
+	defer reader.Close()
+
+	var names []string
+	var rowIndexes []int
+	var rows [][]string
+	for i, sheet := range reader.AllSheets() {
+		names = append(names, sheet.Name)
+		if i > 0 {
+			continue
+		}
+		for rowIndex, cells := range sheet.AllRows() {
+			rowIndexes = append(rowIndexes, rowIndex)
+			rows = append(rows, cells)
+			if rowIndex == 2 {
+				break
+			}
+		}
+		c.Assert(sheet.Err(), IsNil)
+	}
+	c.Assert(reader.Err(), IsNil)
+	c.Assert(names, DeepEquals, []string{"First", "Second"})
+	c.Assert(rowIndexes, DeepEquals, []int{0, 1, 2})
+	c.Assert(rows, DeepEquals, [][]string{{"A1", "", "C1"}, {}, {"A3"}})
+
+	for _, sheet := range reader.AllSheets() {
+		c.Assert(sheet.Name, Equals, "First")
+		break
+	}
+	c.Assert(reader.Err(), IsNil)
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"path"
+	"runtime"
 	"strconv"
 	"strings"
 )
@@ -54,8 +55,14 @@ func getRangeFromString(rangeString string) (lower int, upper int, error error)
 	return lower, upper, error
 }
 
+// MaxColIndex is the zero based index of XFD, the last column of a
+// worksheet in Excel.
+const MaxColIndex = 16383
+
 // ColLettersToIndex is used to convert a character based column
-// reference to a zero based numeric column identifier.
+// reference to a zero based numeric column identifier.  Letters are
+// accepted in either case, and any number of them may be given, "XFD"
+// mapping to MaxColIndex.
 func ColLettersToIndex(letters string) int {
 	sum, mul, n := 0, 1, 0
 	for i := len(letters) - 1; i >= 0; i, mul, n = i-1, mul*26, 1 {
@@ -97,12 +104,12 @@ func formatColumnName(colId []int) string {
 			// range 0-25, all other numbers are 1-26,
 			// hence we use a differente offset for the
 			// last part.
-			result += string(part + 65)
+			result += string(rune(part + 65))
 		} else {
 			// Don't output leading 0s, as there is no
 			// representation of 0 in this format.
 			if part > 0 {
-				result += string(part + 64)
+				result += string(rune(part + 64))
 			}
 		}
 	}
@@ -111,16 +118,15 @@ func formatColumnName(colId []int) string {
 
 func smooshBase26Slice(b26 []int) []int {
 	// Smoosh values together, eliminating 0s from all but the
-	// least significant part.
+	// least significant part.  Borrowing from a greater part can
+	// take it down to 0, or to -1 if it was 0 already, so the parts
+	// are fixed from the least significant up for the borrows to
+	// carry on, as they must from five letter codes on.
 	lastButOnePart := len(b26) - 2
 	for i := lastButOnePart; i > 0; i-- {
-		part := b26[i]
-		if part == 0 {
-			greaterPart := b26[i-1]
-			if greaterPart > 0 {
-				b26[i-1] = greaterPart - 1
-				b26[i] = 26
-			}
+		if b26[i] <= 0 {
+			b26[i] += 26
+			b26[i-1]--
 		}
 	}
 	return b26
@@ -143,7 +149,9 @@ func intToBase26(x int) (parts []int) {
 }
 
 // ColIndexToLetters is used to convert a zero based, numeric column
-// indentifier into a character code.
+// indentifier into a character code, from "A" up to "ZZ" and on to
+// three letter codes such as "XFD" for MaxColIndex.  It is the inverse
+// of ColLettersToIndex.
 func ColIndexToLetters(colRef int) string {
 	parts := intToBase26(colRef)
 	return formatColumnName(smooshBase26Slice(parts))
@@ -180,6 +188,9 @@ func intOnlyMapF(rune rune) rune {
 // coordinates from a cell name in Excel format, e.g. the cellIDString
 // "A1" returns 0, 0 and the "B3" return 1, 2.
 func GetCoordsFromCellIDString(cellIDString string) (x, y int, error error) {
+	if x, y, ok := parseCellID(cellIDString); ok {
+		return x, y, nil
+	}
 	var letterPart string = strings.Map(letterOnlyMapF, cellIDString)
 	y, error = strconv.Atoi(strings.Map(intOnlyMapF, cellIDString))
 	if error != nil {
@@ -242,10 +253,19 @@ func calculateMaxMinFromWorksheet(worksheet *xlsxWorksheet) (minx, miny, maxx, m
 	miny = maxVal
 	maxy = 0
 	maxx = 0
+	// Rows and cells without a reference follow the previous ones.
+	y = -1
 	for _, row := range worksheet.SheetData.Row {
+		if row.R > 0 {
+			y = row.R - 1
+		} else {
+			y++
+		}
+		x = -1
 		for _, cell := range row.C {
-			x, y, err = GetCoordsFromCellIDString(cell.R)
-			if err != nil {
+			if cell.R == "" {
+				x++
+			} else if x, y, err = GetCoordsFromCellIDString(cell.R); err != nil {
 				return -1, -1, -1, -1, err
 			}
 			if x < minx {
@@ -290,7 +310,7 @@ func makeRowFromSpan(spans string, sheet *Sheet) *Row {
 	error = nil
 	row.Cells = make([]*Cell, upper)
 	for i := 0; i < upper; i++ {
-		cell = new(Cell)
+		cell = &Cell{Row: row}
 		cell.Value = ""
 		row.Cells[i] = cell
 	}
@@ -326,7 +346,7 @@ func makeRowFromRaw(rawrow xlsxRow, sheet *Sheet) *Row {
 
 	row.Cells = make([]*Cell, upper)
 	for i := 0; i < upper; i++ {
-		cell = new(Cell)
+		cell = &Cell{Row: row}
 		cell.Value = ""
 		row.Cells[i] = cell
 	}
@@ -468,15 +488,22 @@ func shiftCell(cellID string, dx, dy int) string {
 func fillCellData(rawCell xlsxC, refTable *RefTable, sharedFormulas map[int]sharedFormula, cell *Cell) {
 	val := strings.Trim(rawCell.V, " \t\n\r")
 	cell.formula = formulaForCell(rawCell, sharedFormulas)
+	if rawCell.F != nil && rawCell.F.T == formulaTypeArray {
+		cell.formulaType = rawCell.F.T
+		cell.formulaRef = rawCell.F.Ref
+	}
+	cell.cellMetadata = rawCell.Cm
+	cell.valueMetadata = rawCell.Vm
 	switch rawCell.T {
 	case "s": // Shared String
 		cell.cellType = CellTypeString
 		if val != "" {
-			ref, err := strconv.Atoi(val)
+			ref, err := parseIndex(val)
 			if err != nil {
 				panic(err)
 			}
 			cell.Value = refTable.ResolveSharedString(ref)
+			cell.sharedStringIndex = ref + 1
 		}
 	case "inlineStr":
 		cell.cellType = CellTypeInline
@@ -505,18 +532,29 @@ func fillCellData(rawCell xlsxC, refTable *RefTable, sharedFormulas map[int]shar
 	}
 }
 
-// fillCellDataFromInlineString attempts to get inline string data and put it into a Cell.
+// fillCellDataFromInlineString attempts to get inline string data and put it into a Cell.  The text of the is element
+// is followed by that of its rich text runs, if any, as written by other streaming writers.  The white space around
+// the text is trimmed unless it is preserved.  The few writers putting the text of inline strings in a v element
+// rather than an is element are read as well.
 func fillCellDataFromInlineString(rawcell xlsxC, cell *Cell) {
 	cell.Value = ""
-	if rawcell.Is != nil {
-		if rawcell.Is.T != "" {
-			cell.Value = strings.Trim(rawcell.Is.T, " \t\n\r")
-		} else {
-			for _, r := range rawcell.Is.R {
-				cell.Value += r.T
-			}
+	if rawcell.Is == nil {
+		cell.Value = unescapeXString(rawcell.V)
+		return
+	}
+	text := rawcell.Is.T
+	if !rawcell.Is.preserved {
+		text = strings.Trim(text, " \t\n\r")
+	}
+	if len(rawcell.Is.R) > 0 {
+		var value bytes.Buffer
+		value.WriteString(text)
+		for _, r := range rawcell.Is.R {
+			value.WriteString(r.T)
 		}
+		text = value.String()
 	}
+	cell.Value = unescapeXString(text)
 }
 
 // readRowsFromSheet is an internal helper function that extracts the
@@ -548,6 +586,7 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet, rowLi
 
 	rowCount = maxRow + 1
 	colCount = maxCol + 1
+	file.memoryBudget.mustCharge(sheetMemory(rowCount, colCount))
 	rows = make([]*Row, rowCount)
 	cols = make([]*Col, colCount)
 	for i := range cols {
@@ -575,6 +614,9 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet, rowLi
 				if file.styles != nil {
 					col.style = file.styles.getStyle(rawcol.Style)
 					col.numFmt, col.parsedNumFmt = file.styles.getNumberFormat(rawcol.Style)
+					if rawcol.Style > 0 {
+						col.inherited = &inheritedStyle{style: col.style, numFmt: col.numFmt}
+					}
 				}
 			}
 		}
@@ -588,6 +630,7 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet, rowLi
 		for rawrow.R > (insertRowIndex + 1) {
 			// Put an empty Row into the array
 			if insertRowIndex < numRows {
+				file.memoryBudget.mustCharge(rowMemory(0))
 				rows[insertRowIndex] = makeEmptyRow(sheet)
 			}
 			insertRowIndex++
@@ -598,6 +641,7 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet, rowLi
 		} else {
 			row = makeRowFromRaw(rawrow, sheet)
 		}
+		file.memoryBudget.mustCharge(rowMemory(len(row.Cells)))
 
 		row.Hidden = rawrow.Hidden
 		height, err := strconv.ParseFloat(rawrow.Ht, 64)
@@ -606,6 +650,22 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet, rowLi
 		}
 		row.isCustom = rawrow.CustomHeight
 		row.OutlineLevel = rawrow.OutlineLevel
+		// The cells without a style of their own take that of the
+		// row, if it has one, or else of their column.
+		var rowStyle *inheritedStyle
+		if rawrow.CustomFormat && file.styles != nil {
+			rowStyle = &inheritedStyle{style: file.styles.getStyle(rawrow.S)}
+			rowStyle.numFmt, _ = file.styles.getNumberFormat(rawrow.S)
+		}
+		inherit := func(x int) *inheritedStyle {
+			if rowStyle != nil || x >= len(cols) {
+				return rowStyle
+			}
+			return cols[x].inherited
+		}
+		for x, cell := range row.Cells {
+			cell.inherited = inherit(x)
+		}
 
 		insertColIndex = minCol
 		for _, rawcell := range rawrow.C {
@@ -613,7 +673,11 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet, rowLi
 			if err != nil {
 				panic(err.Error())
 			}
-			x, _, _ := GetCoordsFromCellIDString(rawcell.R)
+			// Cells without a reference follow the previous one.
+			x := insertColIndex
+			if rawcell.R != "" {
+				x, _, _ = GetCoordsFromCellIDString(rawcell.R)
+			}
 
 			// K1000000: Prevent panic when the range specified in the spreadsheet
 			//           view exceeds the actual number of columns in the dataset.
@@ -623,7 +687,7 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet, rowLi
 			for x > insertColIndex {
 				// Put an empty Cell into the array
 				if insertColIndex < len(row.Cells) {
-					row.Cells[insertColIndex] = new(Cell)
+					row.Cells[insertColIndex] = &Cell{Row: row, inherited: inherit(insertColIndex)}
 				}
 				insertColIndex++
 			}
@@ -637,6 +701,9 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet, rowLi
 				if file.styles != nil {
 					cell.style = file.styles.getStyle(rawcell.S)
 					cell.NumFmt, cell.parsedNumFmt = file.styles.getNumberFormat(rawcell.S)
+					if rawcell.S > 0 {
+						cell.inherited = nil
+					}
 				}
 				cell.date1904 = file.Date1904
 				// Cell is considered hidden if the row or the column of this cell is hidden
@@ -652,6 +719,7 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet, rowLi
 
 	// insert trailing empty rows for the rest of the file
 	for ; insertRowIndex < rowCount; insertRowIndex++ {
+		file.memoryBudget.mustCharge(rowMemory(0))
 		rows[insertRowIndex] = makeEmptyRow(sheet)
 	}
 	return rows, cols, colCount, rowCount
@@ -680,6 +748,10 @@ func readSheetViews(xSheetViews xlsxSheetViews) []SheetView {
 			pane.State = xlsxPane.State
 			sheetView.Pane = pane
 		}
+		sheetView.TopLeftCell = xSheetView.TopLeftCell
+		if len(xSheetView.Selection) > 0 {
+			sheetView.ActiveCell = xSheetView.Selection[0].ActiveCell
+		}
 		sheetViews = append(sheetViews, sheetView)
 	}
 	return sheetViews
@@ -705,6 +777,17 @@ func readSheetFromFile(sc chan *indexedSheet, index int, rsheet xlsxSheet, fi *F
 		}
 	}()
 
+	// The parsed worksheet takes about as much memory as its XML,
+	// until its rows have been read.
+	var xmlSize int64
+	if f := worksheetFileForSheet(rsheet, fi.worksheets, sheetXMLMap); f != nil {
+		xmlSize = int64(f.UncompressedSize64)
+	}
+	if err := fi.memoryBudget.charge(xmlSize); err != nil {
+		result.Error = err
+		sc <- result
+		return err
+	}
 	worksheet, err := getWorksheetFromSheet(rsheet, fi.worksheets, sheetXMLMap, rowLimit)
 	if err != nil {
 		result.Error = err
@@ -714,8 +797,42 @@ func readSheetFromFile(sc chan *indexedSheet, index int, rsheet xlsxSheet, fi *F
 	sheet := new(Sheet)
 	sheet.File = fi
 	sheet.Rows, sheet.Cols, sheet.MaxCol, sheet.MaxRow = readRowsFromSheet(worksheet, fi, sheet, rowLimit)
+	fi.memoryBudget.release(xmlSize)
 	sheet.Hidden = rsheet.State == sheetStateHidden || rsheet.State == sheetStateVeryHidden
+	sheet.VeryHidden = rsheet.State == sheetStateVeryHidden
 	sheet.SheetViews = readSheetViews(worksheet.SheetViews)
+	sheet.Protection = readSheetProtection(worksheet.SheetProtection, worksheet.ProtectedRanges)
+	sheet.Properties = readSheetProperties(worksheet.SheetPr, fi.styles)
+	sheet.IgnoredErrors = readIgnoredErrors(worksheet.IgnoredErrors)
+	sheet.AutoFilter = readAutoFilter(worksheet.AutoFilter)
+	sheet.SparklineGroups, err = readSparklineGroups(worksheet.ExtLst)
+	if err != nil {
+		result.Error = err
+		sc <- result
+		return err
+	}
+	sheet.rawExtensions = readRawExtensions(worksheet.ExtLst, sparklineExtURI)
+	if fi.preserve {
+		sheet.preserved, err = readPreservedPart(worksheetFileForSheet(rsheet, fi.worksheets, sheetXMLMap), modelledWorksheetElements, worksheetElementOrder)
+		if err != nil {
+			result.Error = err
+			sc <- result
+			return err
+		}
+	}
+	if fi.changes != nil {
+		sheet.tracked = &trackedSheet{part: worksheetFileForSheet(rsheet, fi.worksheets, sheetXMLMap).Name}
+	}
+	sheet.rawTableParts = worksheet.TableParts
+	sheet.rawDrawing = worksheet.Drawing
+	sheet.rawLegacyDrawing = worksheet.LegacyDrawing
+	sheet.rawRelationships, err = readRelationshipsFromZipFile(worksheetRelsFileForSheet(rsheet, fi.worksheets, sheetXMLMap))
+	if err != nil {
+		result.Error = err
+		sc <- result
+		return err
+	}
+	sheet.Hyperlinks = readHyperlinks(worksheet.Hyperlinks, sheet.rawRelationships)
 
 	sheet.SheetFormat.DefaultColWidth = worksheet.SheetFormatPr.DefaultColWidth
 	sheet.SheetFormat.DefaultRowHeight = worksheet.SheetFormatPr.DefaultRowHeight
@@ -729,13 +846,17 @@ func readSheetFromFile(sc chan *indexedSheet, index int, rsheet xlsxSheet, fi *F
 
 				minCol, minRow, err := GetCoordsFromCellIDString(parts[0])
 				if nil != err {
-					return fmt.Errorf("data validation %s", err.Error())
+					result.Error = fmt.Errorf("data validation %s", err.Error())
+					sc <- result
+					return result.Error
 				}
 
 				if 2 == len(parts) {
 					maxCol, maxRow, err := GetCoordsFromCellIDString(parts[1])
 					if nil != err {
-						return fmt.Errorf("data validation %s", err.Error())
+						result.Error = fmt.Errorf("data validation %s", err.Error())
+						sc <- result
+						return result.Error
 					}
 
 					if minCol == maxCol && minRow == maxRow {
@@ -791,10 +912,13 @@ func readSheetsFromZipFile(f *zip.File, file *File, sheetXMLMap map[string]strin
 		return nil, nil, err
 	}
 	file.Date1904 = workbook.WorkbookPr.Date1904
+	file.CalcProperties = readCalcProperties(workbook.CalcPr)
+	file.Protection = readWorkbookProtection(workbook.WorkbookProtection)
 
 	for entryNum := range workbook.DefinedNames.DefinedName {
 		file.DefinedNames = append(file.DefinedNames, &workbook.DefinedNames.DefinedName[entryNum])
 	}
+	file.rawWorkbookExtensions = readRawExtensions(workbook.ExtLst)
 
 	// Only try and read sheets that have corresponding files.
 	// Notably this excludes chartsheets don't right now
@@ -809,15 +933,33 @@ func readSheetsFromZipFile(f *zip.File, file *File, sheetXMLMap map[string]strin
 	sheets := make([]*Sheet, sheetCount)
 	sheetChan := make(chan *indexedSheet, sheetCount)
 
+	// Sheets are independent of each other, so they are read by a
+	// pool of workers.  Once a sheet fails no more sheets are
+	// started.
+	workers := runtime.NumCPU()
+	if workers > sheetCount {
+		workers = sheetCount
+	}
+	jobs := make(chan int)
+	done := make(chan struct{})
+	defer close(done)
 	go func() {
-		defer close(sheetChan)
-		err = nil
-		for i, rawsheet := range workbookSheets {
-			if err := readSheetFromFile(sheetChan, i, rawsheet, file, sheetXMLMap, rowLimit); err != nil {
+		defer close(jobs)
+		for i := range workbookSheets {
+			select {
+			case jobs <- i:
+			case <-done:
 				return
 			}
 		}
 	}()
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range jobs {
+				readSheetFromFile(sheetChan, i, workbookSheets[i], file, sheetXMLMap, rowLimit)
+			}
+		}()
+	}
 
 	for j := 0; j < sheetCount; j++ {
 		sheet := <-sheetChan
@@ -906,6 +1048,27 @@ func readThemeFromZipFile(f *zip.File) (*theme, error) {
 	return newTheme(themeXml), nil
 }
 
+// readCorePropertiesFromZipFile() is an internal helper function to
+// extract the document's core properties from the docProps/core.xml
+// file within the XLSX zip file.  The part is optional, so a nil
+// zip.File results in nil properties.
+func readCorePropertiesFromZipFile(f *zip.File) (*xlsxCoreProperties, error) {
+	if f == nil {
+		return nil, nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	coreProperties := new(xlsxCoreProperties)
+	err = xml.NewDecoder(rc).Decode(coreProperties)
+	if err != nil {
+		return nil, err
+	}
+	return coreProperties, nil
+}
+
 type WorkBookRels map[string]string
 
 func (w *WorkBookRels) MakeXLSXWorkbookRels() xlsxWorkbookRels {
@@ -920,7 +1083,7 @@ func (w *WorkBookRels) MakeXLSXWorkbookRels() xlsxWorkbookRels {
 		xWorkbookRels.Relationships[index-1] = xlsxWorkbookRelation{
 			Id:     k,
 			Target: v,
-			Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"}
+			Type:   relationshipTypeWorksheet}
 	}
 
 	relCount++
@@ -928,21 +1091,21 @@ func (w *WorkBookRels) MakeXLSXWorkbookRels() xlsxWorkbookRels {
 	xWorkbookRels.Relationships[relCount-1] = xlsxWorkbookRelation{
 		Id:     sheetId,
 		Target: "sharedStrings.xml",
-		Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings"}
+		Type:   relationshipTypeSharedStrings}
 
 	relCount++
 	sheetId = fmt.Sprintf("rId%d", relCount)
 	xWorkbookRels.Relationships[relCount-1] = xlsxWorkbookRelation{
 		Id:     sheetId,
 		Target: "theme/theme1.xml",
-		Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme"}
+		Type:   relationshipTypeTheme}
 
 	relCount++
 	sheetId = fmt.Sprintf("rId%d", relCount)
 	xWorkbookRels.Relationships[relCount-1] = xlsxWorkbookRelation{
 		Id:     sheetId,
 		Target: "styles.xml",
-		Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"}
+		Type:   relationshipTypeStyles}
 
 	return xWorkbookRels
 }
@@ -950,9 +1113,12 @@ func (w *WorkBookRels) MakeXLSXWorkbookRels() xlsxWorkbookRels {
 // readWorkbookRelationsFromZipFile is an internal helper function to
 // extract a map of relationship ID strings to the name of the
 // worksheet.xml file they refer to.  The resulting map can be used to
-// reliably derefence the worksheets in the XLSX file.
-func readWorkbookRelationsFromZipFile(workbookRels *zip.File) (WorkBookRels, error) {
+// reliably derefence the worksheets in the XLSX file.  The
+// relationships to parts that this package doesn't model are
+// returned as well, so that they can be kept when the file is saved.
+func readWorkbookRelationsFromZipFile(workbookRels *zip.File) (WorkBookRels, []xlsxWorkbookRelation, error) {
 	var sheetXMLMap WorkBookRels
+	var rawRelationships []xlsxWorkbookRelation
 	var wbRelationships *xlsxWorkbookRels
 	var rc io.ReadCloser
 	var decoder *xml.Decoder
@@ -960,22 +1126,28 @@ func readWorkbookRelationsFromZipFile(workbookRels *zip.File) (WorkBookRels, err
 
 	rc, err = workbookRels.Open()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	decoder = xml.NewDecoder(rc)
 	wbRelationships = new(xlsxWorkbookRels)
 	err = decoder.Decode(wbRelationships)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	sheetXMLMap = make(WorkBookRels)
 	for _, rel := range wbRelationships.Relationships {
-		if strings.HasSuffix(rel.Target, ".xml") && rel.Type == "http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" {
-			_, filename := path.Split(rel.Target)
-			sheetXMLMap[rel.Id] = strings.Replace(filename, ".xml", "", 1)
+		switch rel.Type {
+		case relationshipTypeWorksheet:
+			if strings.HasSuffix(rel.Target, ".xml") {
+				_, filename := path.Split(rel.Target)
+				sheetXMLMap[rel.Id] = strings.Replace(filename, ".xml", "", 1)
+			}
+		case relationshipTypeSharedStrings, relationshipTypeStyles, relationshipTypeTheme:
+		default:
+			rawRelationships = append(rawRelationships, rel)
 		}
 	}
-	return sheetXMLMap, nil
+	return sheetXMLMap, rawRelationships, nil
 }
 
 // ReadZip() takes a pointer to a zip.ReadCloser and returns a
@@ -1004,6 +1176,13 @@ func ReadZipReader(r *zip.Reader) (*File, error) {
 // rowLimit is the number of rows that should be read from the file. If rowLimit is -1, no limit is applied.
 // You can specify this with the constant NoRowLimit.
 func ReadZipReaderWithRowLimit(r *zip.Reader, rowLimit int) (*File, error) {
+	return readZipReader(r, openOptions{rowLimit: rowLimit})
+}
+
+// readZipReader reads an XLSX from r as o says, aborting if the File
+// takes more memory than allowed by its budget.
+func readZipReader(r *zip.Reader, o openOptions) (*File, error) {
+	rowLimit, budget := o.rowLimit, o.budget
 	var err error
 	var file *File
 	var reftable *RefTable
@@ -1018,8 +1197,13 @@ func ReadZipReaderWithRowLimit(r *zip.Reader, rowLimit int) (*File, error) {
 	var workbook *zip.File
 	var workbookRels *zip.File
 	var worksheets map[string]*zip.File
+	var coreProperties *zip.File
+	var contentTypes *zip.File
+	var rawParts []*zip.File
+	var packageRels *zip.File
 
 	file = NewFile()
+	file.memoryBudget = budget
 	// file.numFmtRefTable = make(map[int]xlsxNumFmt, 1)
 	worksheets = make(map[string]*zip.File, len(r.File))
 	for _, v = range r.File {
@@ -1034,18 +1218,47 @@ func ReadZipReaderWithRowLimit(r *zip.Reader, rowLimit int) (*File, error) {
 			styles = v
 		case "xl/theme/theme1.xml":
 			themeFile = v
+		case "docProps/core.xml":
+			coreProperties = v
+		case "[Content_Types].xml":
+			contentTypes = v
+		case "_rels/.rels":
+			// The relationships of the package are always written
+			// from a template.
+			packageRels = v
+		case "docProps/app.xml":
+			// These are always written from templates.
+		case "xl/calcChain.xml":
+			// Excel rebuilds the calculation chain, while a stale
+			// one makes it repair the file.
 		default:
-			if len(v.Name) > 17 {
-				if v.Name[0:13] == "xl/worksheets" {
-					worksheets[v.Name[14:len(v.Name)-4]] = v
-				}
+			if strings.HasPrefix(v.Name, "xl/worksheets/_rels/") && strings.HasSuffix(v.Name, ".rels") {
+				worksheets[v.Name[14:len(v.Name)-5]] = v
+			} else if len(v.Name) > 17 && v.Name[0:13] == "xl/worksheets" {
+				worksheets[v.Name[14:len(v.Name)-4]] = v
+			} else if !strings.HasSuffix(v.Name, "/") {
+				rawParts = append(rawParts, v)
 			}
 		}
 	}
 	if workbookRels == nil {
 		return nil, fmt.Errorf("xl/_rels/workbook.xml.rels not found in input xlsx.")
 	}
-	sheetXMLMap, err = readWorkbookRelationsFromZipFile(workbookRels)
+	sheetXMLMap, file.rawRelationships, err = readWorkbookRelationsFromZipFile(workbookRels)
+	if err != nil {
+		return nil, err
+	}
+	if o.unknownParts != UnknownPartsIgnored {
+		skipped, err := findSkippedParts(r, sheetXMLMap, file.rawRelationships, o.preserve)
+		if err != nil {
+			return nil, err
+		}
+		if len(skipped) > 0 && o.unknownParts == UnknownPartsRejected {
+			return nil, &UnknownPartsError{Parts: skipped}
+		}
+		file.skippedParts = skipped
+	}
+	err = readRawPartsFromZipFiles(rawParts, contentTypes, file)
 	if err != nil {
 		return nil, err
 	}
@@ -1053,6 +1266,11 @@ func ReadZipReaderWithRowLimit(r *zip.Reader, rowLimit int) (*File, error) {
 		return nil, fmt.Errorf("Input xlsx contains no worksheets.")
 	}
 	file.worksheets = worksheets
+	if sharedStrings != nil {
+		if err = budget.charge(int64(sharedStrings.UncompressedSize64)); err != nil {
+			return nil, err
+		}
+	}
 	reftable, err = readSharedStringsFromZipFile(sharedStrings)
 	if err != nil {
 		return nil, err
@@ -1074,10 +1292,32 @@ func ReadZipReaderWithRowLimit(r *zip.Reader, rowLimit int) (*File, error) {
 
 		file.styles = style
 	}
+	file.coreProperties, err = readCorePropertiesFromZipFile(coreProperties)
+	if err != nil {
+		return nil, err
+	}
+	if o.preserve {
+		file.preserve = true
+		if file.preservedWorkbook, err = readPreservedPart(workbook, modelledWorkbookElements, workbookElementOrder); err != nil {
+			return nil, err
+		}
+		if packageRels != nil {
+			if file.preservedPackageRels, err = readPreservedPackageRelationships(packageRels); err != nil {
+				return nil, err
+			}
+		}
+		if file.workbookContentType, err = readWorkbookContentType(contentTypes); err != nil {
+			return nil, err
+		}
+	}
+	if o.trackChanges {
+		file.changes = &changeTracker{source: o.source}
+	}
 	sheetsByName, sheets, err = readSheetsFromZipFile(workbook, file, sheetXMLMap, rowLimit)
 	if err != nil {
 		return nil, err
 	}
+	file.preserve = false
 	if sheets == nil {
 		readerErr := new(XLSXReaderError)
 		readerErr.Err = "No sheets found in XLSX File"
@@ -1085,6 +1325,10 @@ func ReadZipReaderWithRowLimit(r *zip.Reader, rowLimit int) (*File, error) {
 	}
 	file.Sheet = sheetsByName
 	file.Sheets = sheets
+	file.memoryBudget = nil
+	if file.changes != nil {
+		file.changes.track(file)
+	}
 	return file, nil
 }
 
@@ -1,9 +1,12 @@
 package xlsx
 
 import (
+	"archive/zip"
 	"bytes"
 	"encoding/xml"
+	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -51,6 +54,37 @@ func (l *LibSuite) TestReadWithInlineStrings(c *C) {
 	c.Assert(val, Equals, "HL Retail - North America - Activity by Day - MTD")
 }
 
+// Inline strings are read whether their text is in a t element, in rich
+// text runs, or in a v element.
+func (l *LibSuite) TestReadRichInlineStrings(c *C) {
+	sheetxml := bytes.NewBufferString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetData>
+    <row r="1">
+      <c r="A1" t="inlineStr"><is><t>
+        plain
+      </t></is></c>
+      <c r="B1" t="inlineStr"><is><t xml:space="preserve"> kept </t></is></c>
+      <c r="C1" t="inlineStr"><is><r><rPr><b/></rPr><t>Bold</t></r><r><t xml:space="preserve"> and plain</t></r></is></c>
+      <c r="D1" t="inlineStr"><is><t/><r><t>after empty t</t></r><rPh sb="0" eb="1"><t>phonetic</t></rPh></is></c>
+      <c r="E1" t="inlineStr"><v>in v</v></c>
+      <c r="F1" t="inlineStr"><is><r><t>escaped _x0009_ tab</t></r></is></c>
+    </row>
+  </sheetData>
+</worksheet>`)
+	worksheet := new(xlsxWorksheet)
+	c.Assert(xml.NewDecoder(sheetxml).Decode(worksheet), IsNil)
+	file := new(File)
+	file.referenceTable = NewSharedStringRefTable()
+	rows, _, _, _ := readRowsFromSheet(worksheet, file, new(Sheet), NoRowLimit)
+	values := []string{}
+	for _, cell := range rows[0].Cells {
+		c.Assert(cell.Type(), Equals, CellTypeInline)
+		values = append(values, cell.Value)
+	}
+	c.Assert(values, DeepEquals, []string{"plain", " kept ", "Bold and plain", "after empty t", "in v", "escaped \t tab"})
+}
+
 // which they are contained from the XLSX file, even when the
 // worksheet files have arbitrary, non-numeric names.
 func (l *LibSuite) TestReadWorkbookRelationsFromZipFileWithFunnyNames(c *C) {
@@ -94,6 +128,10 @@ func (l *LibSuite) TestSmooshBase26Slice(c *C) {
 	input := []int{20, 0, 1}
 	expected := []int{19, 26, 1}
 	c.Assert(smooshBase26Slice(input), DeepEquals, expected)
+	// Borrows carry on through several parts.
+	input = []int{1, 0, 0, 5}
+	expected = []int{0, 25, 26, 5}
+	c.Assert(smooshBase26Slice(input), DeepEquals, expected)
 }
 
 // formatColumnName converts slices of base26 integers to alphabetical
@@ -164,6 +202,32 @@ func (l *LibSuite) TestNumericToLetters(c *C) {
 
 }
 
+// Every column of a worksheet makes the round trip through its letters,
+// which are checked against a count from "A" to "XFD".  See also
+// TestRandomColumnLetters for longer codes.
+func (l *LibSuite) TestColumnLettersRoundTrip(c *C) {
+	letters := []byte("A")
+	for index := 0; index <= MaxColIndex; index++ {
+		c.Assert(ColIndexToLetters(index), Equals, string(letters))
+		c.Assert(ColLettersToIndex(string(letters)), Equals, index)
+		c.Assert(ColLettersToIndex(strings.ToLower(string(letters))), Equals, index)
+		// Count up in letters, carrying from Z to A.
+		i := len(letters) - 1
+		for i >= 0 && letters[i] == 'Z' {
+			letters[i] = 'A'
+			i--
+		}
+		if i < 0 {
+			letters = append([]byte("A"), letters...)
+		} else {
+			letters[i]++
+		}
+	}
+	c.Assert(ColIndexToLetters(MaxColIndex), Equals, "XFD")
+	c.Assert(ColIndexToLetters(702), Equals, "AAA")
+	c.Assert(ColIndexToLetters(701), Equals, "ZZ")
+}
+
 func (l *LibSuite) TestLetterOnlyMapFunction(c *C) {
 	var input string = "ABC123"
 	var output string = strings.Map(letterOnlyMapF, input)
@@ -252,6 +316,22 @@ func (l *LibSuite) TestCalculateMaxMinFromWorksheet(c *C) {
 	c.Assert(maxy, Equals, 1)
 }
 
+// Rows and cells may leave out their references, as written by
+// StreamFileBuilder.SetOmitCellReferences.
+func (l *LibSuite) TestCalculateMaxMinFromWorksheetWithoutReferences(c *C) {
+	worksheet := &xlsxWorksheet{SheetData: xlsxSheetData{Row: []xlsxRow{
+		{R: 1, C: []xlsxC{{R: "A1"}, {R: "B1"}}},
+		{C: []xlsxC{{}, {}, {}}},
+		{C: []xlsxC{{}}},
+	}}}
+	minx, miny, maxx, maxy, err := calculateMaxMinFromWorksheet(worksheet)
+	c.Assert(err, IsNil)
+	c.Assert(minx, Equals, 0)
+	c.Assert(miny, Equals, 0)
+	c.Assert(maxx, Equals, 2)
+	c.Assert(maxy, Equals, 2)
+}
+
 func (l *LibSuite) TestGetRangeFromString(c *C) {
 	var rangeString string
 	var lower, upper int
@@ -1082,6 +1162,73 @@ func (l *LibSuite) TestReadRowsFromSheetWithHiddenColumn(c *C) {
 	c.Assert(cell2.Hidden, Equals, true)
 }
 
+func (l *LibSuite) TestReadRowsFromSheetWithInheritedStyles(c *C) {
+	var stylesXML = bytes.NewBufferString(`
+		<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+		<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+			<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+			<fills count="1"><fill><patternFill patternType="none"/></fill></fills>
+			<borders count="1"><border><left/><right/><top/><bottom/></border></borders>
+			<cellXfs count="3">
+				<xf numFmtId="0" fontId="0" fillId="0" borderId="0"/>
+				<xf numFmtId="14" fontId="0" fillId="0" borderId="0" applyNumberFormat="1"/>
+				<xf numFmtId="0" fontId="1" fillId="0" borderId="0" applyFont="1"/>
+			</cellXfs>
+		</styleSheet>`)
+	var sheetxml = bytes.NewBufferString(`
+		<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+		<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+			<dimension ref="A1:C2"/>
+			<cols>
+				<col min="2" max="2" style="1"/>
+			</cols>
+			<sheetData>
+				<row r="1">
+					<c r="A1"><v>1</v></c>
+					<c r="B1"><v>44197</v></c>
+					<c r="C1" s="2"><v>3</v></c>
+				</row>
+				<row r="2" s="2" customFormat="1">
+					<c r="A2"><v>4</v></c>
+					<c r="C2" s="1"><v>44198</v></c>
+				</row>
+			</sheetData>
+		</worksheet>`)
+	worksheet := new(xlsxWorksheet)
+	err := xml.NewDecoder(sheetxml).Decode(worksheet)
+	c.Assert(err, IsNil)
+	styles := newXlsxStyleSheet(nil)
+	err = xml.NewDecoder(stylesXML).Decode(styles)
+	c.Assert(err, IsNil)
+	buildNumFmtRefTable(styles)
+	file := new(File)
+	file.styles = styles
+	sheet := new(Sheet)
+	rows, _, _, _ := readRowsFromSheet(worksheet, file, sheet, NoRowLimit)
+
+	// The date in the column styled as a date has no style of its
+	// own, which only its effective style shows.
+	date := rows[0].Cells[1]
+	c.Assert(date.GetNumberFormat(), Equals, "general")
+	c.Assert(date.EffectiveNumberFormat(), Equals, "mm-dd-yy")
+	c.Assert(rows[0].Cells[0].EffectiveNumberFormat(), Equals, "general")
+	c.Assert(rows[0].Cells[2].EffectiveStyle().Font.Bold, Equals, true)
+
+	// The row style takes precedence over the column style, the
+	// style of a cell over both.
+	c.Assert(rows[1].Cells[0].GetStyle().Font.Bold, Equals, false)
+	c.Assert(rows[1].Cells[0].EffectiveStyle().Font.Bold, Equals, true)
+	c.Assert(rows[1].Cells[1].EffectiveStyle().Font.Bold, Equals, true)
+	c.Assert(rows[1].Cells[1].EffectiveNumberFormat(), Equals, "general")
+	c.Assert(rows[1].Cells[2].EffectiveStyle().Font.Bold, Equals, false)
+	c.Assert(rows[1].Cells[2].EffectiveNumberFormat(), Equals, "mm-dd-yy")
+
+	// A style set since is the cell's own.
+	style := NewStyle()
+	rows[1].Cells[0].SetStyle(style)
+	c.Assert(rows[1].Cells[0].EffectiveStyle(), Equals, style)
+}
+
 // When converting the xlsxRow to a Row we create a as many cells as we find.
 func (l *LibSuite) TestReadRowFromRaw(c *C) {
 	var rawRow xlsxRow
@@ -1700,3 +1847,53 @@ func TestFuzzCrashers(t *testing.T) {
 		}
 	}
 }
+
+func (l *LibSuite) TestReadSheetsInParallel(c *C) {
+	f := NewFile()
+	for i := 0; i < 12; i++ {
+		sheet, err := f.AddSheet(fmt.Sprintf("Sheet%d", i))
+		c.Assert(err, IsNil)
+		sheet.Cell(i, 0).SetInt(i)
+	}
+	var buffer bytes.Buffer
+	c.Assert(f.Write(&buffer), IsNil)
+
+	reread, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(reread.Sheets, HasLen, 12)
+	for i, sheet := range reread.Sheets {
+		c.Assert(sheet.Name, Equals, fmt.Sprintf("Sheet%d", i))
+		c.Assert(reread.Sheet[sheet.Name], Equals, sheet)
+		c.Assert(sheet.Cell(i, 0).Value, Equals, strconv.Itoa(i))
+	}
+}
+
+// An error in one of the sheets read in parallel is returned rather
+// than leaving the reader waiting for the sheet.
+func (l *LibSuite) TestReadSheetsInParallelWithError(c *C) {
+	var sheets, rels bytes.Buffer
+	parts := map[string]string{}
+	for i := 1; i <= 8; i++ {
+		fmt.Fprintf(&sheets, `<sheet name="Sheet%d" sheetId="%d" r:id="rId%d"/>`, i, i, i)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+		validations := ""
+		if i == 5 {
+			validations = `<dataValidations count="1"><dataValidation type="whole" sqref="A0:B"/></dataValidations>`
+		}
+		parts[fmt.Sprintf("xl/worksheets/sheet%d.xml", i)] = `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData><row r="1"><c r="A1"><v>1</v></c></row></sheetData>` + validations + `</worksheet>`
+	}
+	parts["xl/workbook.xml"] = `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` + sheets.String() + `</sheets></workbook>`
+	parts["xl/_rels/workbook.xml.rels"] = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + rels.String() + `</Relationships>`
+	var buffer bytes.Buffer
+	w := zip.NewWriter(&buffer)
+	for name, data := range parts {
+		part, err := w.Create(name)
+		c.Assert(err, IsNil)
+		_, err = part.Write([]byte(data))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(w.Close(), IsNil)
+
+	_, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, ErrorMatches, "data validation .*")
+}
@@ -0,0 +1,130 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LocaleFormats holds the number formats used for currencies and dates
+// in a locale, to be set with Cell.SetFormat or
+// Cell.SetFloatWithFormat.  The separators in the formats are always
+// "," for thousands and "." for decimals, as they are stored in a
+// file: Excel displays them according to the settings of the reader,
+// so "#,##0.00" shows as 1.234,50 on a German system.
+type LocaleFormats struct {
+	// Tag is the locale tag, such as "de-DE".
+	Tag string
+	// Currency is the ISO 4217 code of the currency of the locale,
+	// such as "EUR".
+	Currency string
+	// CurrencyFormat formats amounts in Currency, see
+	// CurrencyNumberFormat.
+	CurrencyFormat string
+	DateFormat     string
+	TimeFormat     string
+	DateTimeFormat string
+}
+
+// locale describes the conventions of a locale that the formats are
+// made from.
+type locale struct {
+	// lcid is the Windows locale id of the locale, in hexadecimal,
+	// as found in the currency annotations of number formats.
+	lcid     string
+	currency string
+	// symbolFirst is true if the currency symbol precedes amounts.
+	symbolFirst bool
+	dateFormat  string
+	timeFormat  string
+}
+
+// locales are the locales known to LocaleNumberFormats, by tag.
+var locales = map[string]locale{
+	"en-US": {lcid: "409", currency: "USD", symbolFirst: true, dateFormat: "m/d/yyyy", timeFormat: "h:mm AM/PM"},
+	"en-GB": {lcid: "809", currency: "GBP", symbolFirst: true, dateFormat: "dd/mm/yyyy", timeFormat: "hh:mm"},
+	"en-IE": {lcid: "1809", currency: "EUR", symbolFirst: true, dateFormat: "dd/mm/yyyy", timeFormat: "hh:mm"},
+	"de-DE": {lcid: "407", currency: "EUR", dateFormat: `dd\.mm\.yyyy`, timeFormat: "hh:mm"},
+	"de-AT": {lcid: "C07", currency: "EUR", dateFormat: `dd\.mm\.yyyy`, timeFormat: "hh:mm"},
+	"fr-FR": {lcid: "40C", currency: "EUR", dateFormat: "dd/mm/yyyy", timeFormat: "hh:mm"},
+	"es-ES": {lcid: "C0A", currency: "EUR", dateFormat: "dd/mm/yyyy", timeFormat: "h:mm"},
+	"it-IT": {lcid: "410", currency: "EUR", dateFormat: "dd/mm/yyyy", timeFormat: "hh:mm"},
+	"nl-NL": {lcid: "413", currency: "EUR", symbolFirst: true, dateFormat: "d-m-yyyy", timeFormat: "hh:mm"},
+	"ja-JP": {lcid: "411", currency: "JPY", symbolFirst: true, dateFormat: "yyyy/m/d", timeFormat: "h:mm"},
+}
+
+// currency describes how amounts in a currency are written.
+type currency struct {
+	symbol   string
+	decimals int
+}
+
+// currencies are the currencies known to CurrencyNumberFormat, by ISO
+// 4217 code.
+var currencies = map[string]currency{
+	"USD": {symbol: "$", decimals: 2},
+	"EUR": {symbol: "€", decimals: 2},
+	"GBP": {symbol: "£", decimals: 2},
+	"JPY": {symbol: "¥", decimals: 0},
+}
+
+// LocaleNumberFormats returns the currency and date formats of the
+// locale with the given tag, such as "en-US" or "de_DE", which is
+// matched regardless of case.
+func LocaleNumberFormats(tag string) (LocaleFormats, error) {
+	tag, loc, err := findLocale(tag)
+	if err != nil {
+		return LocaleFormats{}, err
+	}
+	currencyFormat, err := CurrencyNumberFormat(loc.currency, tag)
+	if err != nil {
+		return LocaleFormats{}, err
+	}
+	return LocaleFormats{
+		Tag:            tag,
+		Currency:       loc.currency,
+		CurrencyFormat: currencyFormat,
+		DateFormat:     loc.dateFormat,
+		TimeFormat:     loc.timeFormat,
+		DateTimeFormat: loc.dateFormat + " " + loc.timeFormat,
+	}, nil
+}
+
+// CurrencyNumberFormat returns the number format of amounts in the
+// currency with the given ISO 4217 code, such as "USD", "EUR", "GBP" or
+// "JPY", written the way they are in the locale with the given tag:
+// "[$€-407]" follows amounts in Germany, "[$$-409]" precedes them in
+// the United States.  Negative amounts get a minus sign.
+func CurrencyNumberFormat(currencyCode, tag string) (string, error) {
+	cur, ok := currencies[strings.ToUpper(currencyCode)]
+	if !ok {
+		return "", fmt.Errorf("unknown currency '%s'", currencyCode)
+	}
+	_, loc, err := findLocale(tag)
+	if err != nil {
+		return "", err
+	}
+	number := "#,##0"
+	if cur.decimals > 0 {
+		number += "." + strings.Repeat("0", cur.decimals)
+	}
+	symbol := "[$" + cur.symbol + "-" + loc.lcid + "]"
+	positive := number + `\ ` + symbol
+	if loc.symbolFirst {
+		positive = symbol + number
+	}
+	return positive + ";-" + positive, nil
+}
+
+// findLocale returns the locale with the given tag along with its
+// canonical tag.
+func findLocale(tag string) (string, locale, error) {
+	canonical := strings.Replace(tag, "_", "-", -1)
+	if i := strings.Index(canonical, "-"); i >= 0 {
+		canonical = strings.ToLower(canonical[:i]) + "-" + strings.ToUpper(canonical[i+1:])
+	}
+	loc, ok := locales[canonical]
+	if !ok {
+		return "", locale{}, fmt.Errorf("unknown locale '%s'", tag)
+	}
+	return canonical, loc, nil
+}
@@ -0,0 +1,98 @@
+package xlsx
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type LocaleFormatsSuite struct{}
+
+var _ = Suite(&LocaleFormatsSuite{})
+
+func (s *LocaleFormatsSuite) TestLocaleNumberFormats(c *C) {
+	formats, err := LocaleNumberFormats("de_de")
+	c.Assert(err, IsNil)
+	c.Assert(formats, DeepEquals, LocaleFormats{
+		Tag:            "de-DE",
+		Currency:       "EUR",
+		CurrencyFormat: `#,##0.00\ [$€-407];-#,##0.00\ [$€-407]`,
+		DateFormat:     `dd\.mm\.yyyy`,
+		TimeFormat:     "hh:mm",
+		DateTimeFormat: `dd\.mm\.yyyy hh:mm`,
+	})
+	formats, err = LocaleNumberFormats("en-US")
+	c.Assert(err, IsNil)
+	c.Assert(formats.CurrencyFormat, Equals, "[$$-409]#,##0.00;-[$$-409]#,##0.00")
+	c.Assert(formats.DateTimeFormat, Equals, "m/d/yyyy h:mm AM/PM")
+
+	_, err = LocaleNumberFormats("xx-YY")
+	c.Assert(err, ErrorMatches, "unknown locale 'xx-YY'")
+}
+
+func (s *LocaleFormatsSuite) TestCurrencyNumberFormat(c *C) {
+	cases := []struct {
+		currency, tag, format string
+	}{
+		{"USD", "en-US", "[$$-409]#,##0.00;-[$$-409]#,##0.00"},
+		{"GBP", "en-GB", "[$£-809]#,##0.00;-[$£-809]#,##0.00"},
+		{"EUR", "fr-FR", `#,##0.00\ [$€-40C];-#,##0.00\ [$€-40C]`},
+		{"eur", "nl-NL", "[$€-413]#,##0.00;-[$€-413]#,##0.00"},
+		{"JPY", "ja-JP", "[$¥-411]#,##0;-[$¥-411]#,##0"},
+		{"USD", "de-DE", `#,##0.00\ [$$-407];-#,##0.00\ [$$-407]`},
+	}
+	for _, tc := range cases {
+		format, err := CurrencyNumberFormat(tc.currency, tc.tag)
+		c.Assert(err, IsNil)
+		c.Assert(format, Equals, tc.format)
+	}
+	_, err := CurrencyNumberFormat("XXX", "en-US")
+	c.Assert(err, ErrorMatches, "unknown currency 'XXX'")
+}
+
+// The formats of every locale are understood when formatting cells.
+func (s *LocaleFormatsSuite) TestFormattedValues(c *C) {
+	dateTimes := map[string]string{
+		"en-US": "3/7/2019 3:04 PM",
+		"en-GB": "07/03/2019 15:04",
+		"en-IE": "07/03/2019 15:04",
+		// The formatter of this package shows escaped characters
+		// with their backslash.
+		"de-DE": `07\.03\.2019 15:04`,
+		"de-AT": `07\.03\.2019 15:04`,
+		"fr-FR": "07/03/2019 15:04",
+		"es-ES": "07/03/2019 15:04",
+		"it-IT": "07/03/2019 15:04",
+		"nl-NL": "7-3-2019 15:04",
+		"ja-JP": "2019/3/7 15:04",
+	}
+	cell := &Cell{}
+	for tag := range locales {
+		formats, err := LocaleNumberFormats(tag)
+		c.Assert(err, IsNil)
+		cell.SetFloatWithFormat(-1234.5, formats.CurrencyFormat)
+		value, err := cell.FormattedValue()
+		c.Assert(err, IsNil)
+		c.Assert(value[0], Equals, byte('-'))
+		cell.SetDateWithOptions(time.Date(2019, 3, 7, 15, 4, 0, 0, time.UTC), DateTimeOptions{
+			Location:        time.UTC,
+			ExcelTimeFormat: formats.DateTimeFormat,
+		})
+		value, err = cell.FormattedValue()
+		c.Assert(err, IsNil)
+		c.Assert(value, Equals, dateTimes[tag])
+	}
+	// The formatter of this package doesn't show thousands separators.
+	formats, err := LocaleNumberFormats("de-DE")
+	c.Assert(err, IsNil)
+	cell.SetFloatWithFormat(1234.5, formats.CurrencyFormat)
+	value, err := cell.FormattedValue()
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "1234.50 €")
+	formats, err = LocaleNumberFormats("ja-JP")
+	c.Assert(err, IsNil)
+	cell.SetFloatWithFormat(1234, formats.CurrencyFormat)
+	value, err = cell.FormattedValue()
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "¥1234")
+}
@@ -0,0 +1,47 @@
+package xlsx
+
+// A File and its Sheets, Rows and Cells aren't safe for concurrent use
+// as they are, such as by the handlers of a web server sharing a
+// template workbook loaded once.  The lock of the File lets them share
+// it: readers hold RLock while they read it, any number of them at
+// once, and writers hold Lock while they change it.
+//
+// Reading a File means reading the fields of its Sheets, Rows, Cols
+// and Cells, such as Cell.Value, and calling the methods that don't
+// change them: Cell.String, Cell.FormattedValue, Cell.Float, Cell.Int,
+// Cell.Bool, Cell.Date, Cell.Type, Cell.Formula, File.ToSlice,
+// HeaderDetect and ApplyMapping, for instance.  The other methods are
+// writes, including Sheet.Row and Sheet.Cell, which add rows and cells
+// when asked for those past the end of the Sheet, Cell.GetStyle and
+// Cell.EffectiveStyle, which give a Cell without a style a new one,
+// and File.Write and File.Save, which prepare the styles and shared
+// strings of the File.  A Sheet with a cell store, see
+// Sheet.SetCellStore, moves rows in and out of the store as they are
+// read, so that even reading it is a write.
+//
+// The methods of the package don't take the lock themselves, which is
+// up to the callers, so that a File that isn't shared doesn't pay for
+// it.
+
+// RLock locks the File for reading, see Lock.  Any number of readers
+// may hold the lock at once, but not while a writer holds it.
+func (f *File) RLock() {
+	f.lock.RLock()
+}
+
+// RUnlock undoes a call to RLock.
+func (f *File) RUnlock() {
+	f.lock.RUnlock()
+}
+
+// Lock locks the File for writing, waiting until the readers and
+// writers holding the lock are done, such as to fill a template shared
+// by concurrent requests or to save it.
+func (f *File) Lock() {
+	f.lock.Lock()
+}
+
+// Unlock undoes a call to Lock.
+func (f *File) Unlock() {
+	f.lock.Unlock()
+}
@@ -0,0 +1,106 @@
+package xlsx
+
+import (
+	"sync"
+
+	. "gopkg.in/check.v1"
+)
+
+type LockingSuite struct{}
+
+var _ = Suite(&LockingSuite{})
+
+// TestSharedTemplate reads a File from several goroutines holding
+// RLock while another fills it holding Lock, which the race detector
+// checks when the tests are run with -race.
+func (s *LockingSuite) TestSharedTemplate(c *C) {
+	file, err := OpenFile("./testdocs/testfile.xlsx")
+	c.Assert(err, IsNil)
+	sheet := file.Sheets[0]
+	var wg sync.WaitGroup
+	results := make([][][][]string, 4)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			file.RLock()
+			defer file.RUnlock()
+			output, err := file.ToSlice()
+			if err == nil {
+				HeaderDetect(sheet)
+				for _, row := range sheet.Rows {
+					for _, cell := range row.Cells {
+						_ = cell.String()
+					}
+				}
+			}
+			results[i] = output
+		}(i)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		file.Lock()
+		defer file.Unlock()
+		sheet.Cell(0, 0).SetString("Filled")
+	}()
+	wg.Wait()
+	for _, output := range results {
+		c.Assert(output, HasLen, len(file.Sheets))
+		c.Assert(output[0][1], DeepEquals, []string{"Baz", "Quuk"})
+	}
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "Filled")
+}
+
+// TestReadingDoesntChangeCells checks that reading cells filled in
+// memory, whose number formats aren't parsed yet, leaves them alone.
+func (s *LockingSuite) TestReadingDoesntChangeCells(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetString("Id")
+	sheet.Cell(1, 0).SetFloatWithFormat(0.25, "0%")
+	sheet.Cell(1, 1).SetInt(12)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			file.RLock()
+			defer file.RUnlock()
+			file.ToSlice()
+		}()
+	}
+	wg.Wait()
+	for _, cell := range sheet.Rows[1].Cells {
+		c.Assert(cell.parsedNumFmt, IsNil)
+	}
+	c.Assert(sheet.Cell(1, 0).String(), Equals, "25%")
+}
+
+// TestNumberFormatsParsedOnce checks that the formats of the cells
+// without a parsed number format of their own are parsed once for
+// their File, and shared.
+func (s *LockingSuite) TestNumberFormatsParsedOnce(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	first, second := sheet.Cell(0, 0), sheet.Cell(1, 0)
+	first.NumFmt, second.NumFmt = "0.000%", "0.000%"
+	parsed := first.getNumberFormat()
+	c.Assert(second.getNumberFormat() == parsed, Equals, true)
+	c.Assert(first.getNumberFormat() == parsed, Equals, true)
+	c.Assert(first.parsedNumFmt, IsNil)
+	c.Assert(f.numberFormats, HasLen, 1)
+
+	other := NewFile()
+	otherSheet, err := other.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	otherCell := otherSheet.Cell(0, 0)
+	otherCell.NumFmt = "0.000%"
+	c.Assert(otherCell.getNumberFormat() == parsed, Equals, false)
+
+	// Cells outside of a File aren't cached.
+	outside := &Cell{NumFmt: "0.000%"}
+	c.Assert(outside.getNumberFormat() == outside.getNumberFormat(), Equals, false)
+}
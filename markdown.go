@@ -0,0 +1,68 @@
+package xlsx
+
+import (
+	"bytes"
+	"strings"
+)
+
+// markdownEscaper escapes the text of cells for the cells of a
+// Markdown table, which are on a single line and delimited by pipes.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`|`, `\|`,
+	"\r\n", "<br>",
+	"\n", "<br>",
+)
+
+// ToMarkdown returns the cells of ref, such as "B2:D10", as a GitHub
+// flavored Markdown table, or those of the whole Sheet if ref is empty.
+// The first row of the range is the header of the table.  Values are
+// formatted by their number format, hidden rows and columns are left
+// out, and merged cells are written as their first cell followed by
+// empty ones.  An empty range returns an empty string.
+func (s *Sheet) ToMarkdown(ref string) (string, error) {
+	minCol, minRow, maxCol, maxRow, err := s.rangeBounds(ref)
+	if err != nil {
+		return "", err
+	}
+	var cols []int
+	for col := minCol; col <= maxCol; col++ {
+		if c := s.colAt(col); c == nil || !c.Hidden {
+			cols = append(cols, col)
+		}
+	}
+	if len(cols) == 0 {
+		return "", nil
+	}
+	var out bytes.Buffer
+	header := true
+	for row := minRow; row <= maxRow; row++ {
+		var r *Row
+		if row < len(s.rowList()) {
+			r = s.rowList()[row]
+		}
+		if r != nil {
+			r.load()
+			if r.Hidden {
+				continue
+			}
+		}
+		out.WriteString("|")
+		for _, col := range cols {
+			value := ""
+			if r != nil && col < len(r.Cells) && r.Cells[col] != nil {
+				cell := r.Cells[col]
+				if value, err = cell.FormattedValue(); err != nil {
+					value = cell.Value
+				}
+			}
+			out.WriteString(" " + markdownEscaper.Replace(value) + " |")
+		}
+		out.WriteString("\n")
+		if header {
+			out.WriteString("|" + strings.Repeat(" --- |", len(cols)) + "\n")
+			header = false
+		}
+	}
+	return out.String(), nil
+}
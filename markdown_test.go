@@ -0,0 +1,48 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type MarkdownSuite struct{}
+
+var _ = Suite(&MarkdownSuite{})
+
+func (s *MarkdownSuite) TestToMarkdown(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Prices")
+	c.Assert(err, IsNil)
+	row := sheet.AddRow()
+	row.AddCell().Value = "Item"
+	row.AddCell().Value = "Price"
+	row.AddCell().Value = "Note"
+	row = sheet.AddRow()
+	row.AddCell().Value = "Pens | pencils"
+	row.AddCell().SetFloatWithFormat(2.5, "0.00")
+	row.AddCell().Value = "two\nlines"
+	row = sheet.AddRow()
+	row.AddCell().Value = "Ink"
+	sheet.Row(3).AddCell().Value = "hidden"
+	sheet.Row(3).Hidden = true
+
+	out, err := sheet.ToMarkdown("")
+	c.Assert(err, IsNil)
+	c.Assert(out, Equals, "| Item | Price | Note |\n"+
+		"| --- | --- | --- |\n"+
+		`| Pens \| pencils | 2.50 | two<br>lines |`+"\n"+
+		"| Ink |  |  |\n")
+
+	sheet.Col(1).Hidden = true
+	out, err = sheet.ToMarkdown("B2:C3")
+	c.Assert(err, IsNil)
+	c.Assert(out, Equals, "| two<br>lines |\n| --- |\n|  |\n")
+
+	_, err = sheet.ToMarkdown("C3:A1")
+	c.Assert(err, NotNil)
+
+	empty, err := file.AddSheet("Empty")
+	c.Assert(err, IsNil)
+	out, err = empty.ToMarkdown("")
+	c.Assert(err, IsNil)
+	c.Assert(out, Equals, "")
+}
@@ -0,0 +1,56 @@
+package xlsx
+
+// PhoneNumberFormat and SSNFormat are the number formats of Excel's
+// special formats for phone numbers and social security numbers, for
+// the columns holding them as numbers, see StreamFileBuilder.AddStyle
+// and Cell.SetFormat.
+const (
+	// PhoneNumberFormat shows 5550123 as 555-0123 and 2025550123 as
+	// (202) 555-0123.
+	PhoneNumberFormat = `[<=9999999]###-####;(###) ###-####`
+	// SSNFormat shows 123456789 as 123-45-6789, keeping its leading
+	// zeros.
+	SSNFormat = "000-00-0000"
+)
+
+// maskCharacter is the character replacing the characters hidden by
+// the masks made by MaskDigits.
+const maskCharacter = '*'
+
+// Mask is a transform of the values of a column of a streamed sheet,
+// such as one hiding most of the digits of phone numbers and
+// identifiers, see StreamFileBuilder.SetColumnMask.
+type Mask func(value string) string
+
+// MaskDigits returns a Mask replacing every digit of the values but the
+// last keep ones with '*', keeping the other characters, so that
+// "123-45-6789" becomes "***-**-6789" with 4 digits kept.  Values with
+// no more than keep digits, such as "N/A", are left as they are.
+func MaskDigits(keep int) Mask {
+	return func(value string) string {
+		runes := []rune(value)
+		kept := 0
+		for i := len(runes) - 1; i >= 0; i-- {
+			if runes[i] < '0' || runes[i] > '9' {
+				continue
+			}
+			if kept < keep {
+				kept++
+				continue
+			}
+			runes[i] = maskCharacter
+		}
+		return string(runes)
+	}
+}
+
+// mask returns the Mask of the given column of the sheet, or of its
+// follow-on sheets, or nil if the values of the column are written as
+// they are.
+func (ss *streamSheet) mask(colIndex int) Mask {
+	sheet, colIndex := ss.column(colIndex)
+	if colIndex < len(sheet.masks) {
+		return sheet.masks[colIndex]
+	}
+	return nil
+}
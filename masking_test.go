@@ -0,0 +1,20 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type MaskingSuite struct{}
+
+var _ = Suite(&MaskingSuite{})
+
+func (s *MaskingSuite) TestMaskDigits(c *C) {
+	mask := MaskDigits(4)
+	c.Assert(mask("123-45-6789"), Equals, "***-**-6789")
+	c.Assert(mask("(202) 555-0123"), Equals, "(***) ***-0123")
+	c.Assert(mask("2025550123"), Equals, "******0123")
+	c.Assert(mask("N/A"), Equals, "N/A")
+	c.Assert(mask("x12"), Equals, "x12")
+	c.Assert(mask(""), Equals, "")
+	c.Assert(MaskDigits(0)("Tél. 01 23"), Equals, "Tél. ** **")
+}
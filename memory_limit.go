@@ -0,0 +1,81 @@
+package xlsx
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// The approximate sizes in bytes of the structures of a File on 64-bit
+// platforms, used to estimate the memory it takes.
+const (
+	pointerBytes = 8
+	stringBytes  = 16
+	fileBytes    = 496
+	sheetBytes   = 416
+	rowBytes     = 104
+	colBytes     = 104
+	cellBytes    = 192
+	styleBytes   = 320
+)
+
+// MemoryLimitError is returned when loading a file would take more
+// memory than allowed by OpenFileWithLimit.
+type MemoryLimitError struct {
+	// Limit is the number of bytes allowed.
+	Limit int64
+	// Needed is the approximate number of bytes used when loading
+	// was aborted.
+	Needed int64
+}
+
+func (e *MemoryLimitError) Error() string {
+	return fmt.Sprintf("loading the file needs more than %d bytes of memory (at least %d)", e.Limit, e.Needed)
+}
+
+// memoryBudget tracks the approximate memory taken by a File while it
+// is loaded.  A nil memoryBudget allows any amount.
+type memoryBudget struct {
+	limit int64
+	used  int64
+}
+
+// charge accounts for n more bytes, and returns a *MemoryLimitError if
+// the limit is exceeded.
+func (b *memoryBudget) charge(n int64) error {
+	if b == nil {
+		return nil
+	}
+	if used := atomic.AddInt64(&b.used, n); used > b.limit {
+		return &MemoryLimitError{Limit: b.limit, Needed: used}
+	}
+	return nil
+}
+
+// release gives back n bytes charged before.  Releasing memory can't
+// exceed the limit, so there is no error.
+func (b *memoryBudget) release(n int64) {
+	if b == nil {
+		return
+	}
+	atomic.AddInt64(&b.used, -n)
+}
+
+// mustCharge is like charge but panics with the error instead, for
+// use while reading rows, where errors are raised by panicking.
+func (b *memoryBudget) mustCharge(n int64) {
+	if err := b.charge(n); err != nil {
+		panic(err)
+	}
+}
+
+// sheetMemory approximates the memory taken by the rows and columns
+// of a sheet, before the rows are filled.
+func sheetMemory(rows, cols int) int64 {
+	return int64(rows*pointerBytes + cols*(pointerBytes+colBytes))
+}
+
+// rowMemory approximates the memory taken by a row of cells, values
+// aside.
+func rowMemory(cells int) int64 {
+	return int64(rowBytes + cells*(pointerBytes+cellBytes))
+}
@@ -0,0 +1,67 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+type MemoryLimitSuite struct{}
+
+var _ = Suite(&MemoryLimitSuite{})
+
+func (s *MemoryLimitSuite) TestOpenFileWithinLimit(c *C) {
+	f, err := OpenFileWithLimit("./testdocs/testfile.xlsx", 10*1024*1024)
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheets, HasLen, 3)
+	c.Assert(f.memoryBudget, IsNil)
+}
+
+func (s *MemoryLimitSuite) TestOpenFileOverLimit(c *C) {
+	_, err := OpenFileWithLimit("./testdocs/testfile.xlsx", 1024)
+	c.Assert(err, NotNil)
+	limitErr, ok := err.(*MemoryLimitError)
+	c.Assert(ok, Equals, true)
+	c.Assert(limitErr.Limit, Equals, int64(1024))
+	c.Assert(limitErr.Needed > 1024, Equals, true)
+}
+
+// A sheet claiming to span every row and column is rejected before
+// its rows are allocated.
+func (s *MemoryLimitSuite) TestOpenFileWithHugeDimension(c *C) {
+	parts := map[string]string{
+		"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Huge" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/worksheets/sheet1.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><dimension ref="A1:XFD1048576"/><sheetData><row r="1"><c r="A1"><v>1</v></c></row></sheetData></worksheet>`,
+	}
+	path := filepath.Join(c.MkDir(), "huge.xlsx")
+	out, err := os.Create(path)
+	c.Assert(err, IsNil)
+	w := zip.NewWriter(out)
+	for name, data := range parts {
+		part, err := w.Create(name)
+		c.Assert(err, IsNil)
+		_, err = part.Write([]byte(data))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(w.Close(), IsNil)
+	c.Assert(out.Close(), IsNil)
+
+	_, err = OpenFileWithLimit(path, 1024*1024)
+	_, ok := err.(*MemoryLimitError)
+	c.Assert(ok, Equals, true)
+}
+
+func (s *MemoryLimitSuite) TestMemoryBudget(c *C) {
+	var budget *memoryBudget
+	c.Assert(budget.charge(1<<40), IsNil)
+	budget = &memoryBudget{limit: 100}
+	c.Assert(budget.charge(60), IsNil)
+	budget.release(10)
+	c.Assert(budget.charge(60), ErrorMatches, "loading the file needs more than 100 bytes of memory \\(at least 110\\)")
+}
@@ -0,0 +1,437 @@
+package xlsx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Merging a File into another, such as a template into a report,
+// brings together three namespaces that can collide: the names of the
+// sheets, the defined names, and the named styles, which cells refer
+// to by their index in the stylesheet of their File.  Rather than let
+// the last one win, File.Merge reports each collision as a
+// MergeConflict, and resolves it with the MergeStrategy asked for.
+
+// ConflictKind is the kind of a MergeConflict.
+type ConflictKind int
+
+const (
+	// ConflictSheetName is a sheet of the File merged whose name is,
+	// regardless of case, that of a sheet of the File merged into.
+	ConflictSheetName ConflictKind = iota
+	// ConflictDefinedName is a defined name of the File merged whose
+	// name is, regardless of case, that of a defined name of the same
+	// scope in the File merged into.
+	ConflictDefinedName
+	// ConflictNamedStyle is a named style index of the cells of the
+	// File merged that stands for another named style, or none, in the
+	// File merged into.
+	ConflictNamedStyle
+)
+
+// String returns the name of the ConflictKind.
+func (k ConflictKind) String() string {
+	switch k {
+	case ConflictSheetName:
+		return "sheet name"
+	case ConflictDefinedName:
+		return "defined name"
+	case ConflictNamedStyle:
+		return "named style"
+	}
+	return fmt.Sprintf("ConflictKind(%d)", int(k))
+}
+
+// MergeStrategy is the way File.Merge resolves the conflicts it finds.
+type MergeStrategy int
+
+const (
+	// MergeFail merges nothing if there is any conflict, and returns
+	// a *MergeConflictError, the default.
+	MergeFail MergeStrategy = iota
+	// MergeKeepExisting keeps what the File merged into has: the
+	// sheets and the defined names in conflict are left out, and the
+	// cells keep their named style index.
+	MergeKeepExisting
+	// MergeReplace lets the File merged win: its sheets and defined
+	// names in conflict replace those of the File merged into, and the
+	// cells lose their named style.
+	MergeReplace
+	// MergeRename keeps both: the sheets in conflict are renamed in
+	// the "Data (2)" form used by Excel, the defined names are
+	// suffixed in the "Total_2" form, and the cells take the named
+	// style of the same name in the File merged into, or else lose
+	// their named style.
+	MergeRename
+)
+
+// MergeConflict is a collision found merging a File into another.
+type MergeConflict struct {
+	Kind ConflictKind
+	// Name is the name of the sheet, of the defined name or of the
+	// named style of the File merged.
+	Name string
+	// Sheet is the name of the sheet of the File merged into that a
+	// defined name is local to, or "" for a global defined name.
+	Sheet string
+	// Existing is the name of what Name collides with in the File
+	// merged into, "" for a named style index it has no named style
+	// at.
+	Existing string
+	// Resolution is the strategy that resolved the conflict, MergeFail
+	// if it wasn't.
+	Resolution MergeStrategy
+	// Renamed is the name MergeRename gave the sheet or the defined
+	// name, or the named style it gave the cells, "" if they lost it.
+	Renamed string
+}
+
+// MergeConflictError is returned by File.Merge, with MergeFail, when
+// the Files merged have conflicts.
+type MergeConflictError struct {
+	Conflicts []MergeConflict
+}
+
+// Error returns a description of the MergeConflictError, naming its
+// first conflict.
+func (e *MergeConflictError) Error() string {
+	first := e.Conflicts[0]
+	if len(e.Conflicts) == 1 {
+		return fmt.Sprintf("the %s '%s' is in conflict", first.Kind, first.Name)
+	}
+	return fmt.Sprintf("%d conflicts merging the files, such as the %s '%s'", len(e.Conflicts), first.Kind, first.Name)
+}
+
+// MergeConflicts returns the conflicts File.Merge would find merging
+// other into the File, without merging anything.
+func (f *File) MergeConflicts(other *File) []MergeConflict {
+	return f.planMerge(other, MergeFail).conflicts
+}
+
+// Merge moves the sheets and the defined names of other into the File,
+// after its own, resolving their conflicts with strategy, and returns
+// the conflicts found.  With MergeFail, the File is left as it was
+// when there is any, and a *MergeConflictError is returned.
+//
+// The sheets are moved rather than copied, and other mustn't be used
+// afterwards.  The formulas of the sheets and of the defined names
+// aren't rewritten for the sheets renamed, and the rest of other, such
+// as its raw parts and its stylesheet, isn't merged.
+func (f *File) Merge(other *File, strategy MergeStrategy) ([]MergeConflict, error) {
+	if strategy < MergeFail || strategy > MergeRename {
+		return nil, fmt.Errorf("invalid merge strategy %d", int(strategy))
+	}
+	if other == f {
+		return nil, fmt.Errorf("a File can't be merged into itself")
+	}
+	plan := f.planMerge(other, strategy)
+	if strategy == MergeFail {
+		if len(plan.conflicts) > 0 {
+			return plan.conflicts, &MergeConflictError{Conflicts: plan.conflicts}
+		}
+	}
+	for _, move := range plan.sheets {
+		sheet := move.sheet
+		sheet.File = f
+		sheet.Selected = false
+		for r := range sheet.rowList() {
+			if row := sheet.row(r); row != nil {
+				row.copyForSnapshots()
+				for _, cell := range row.Cells {
+					if cell != nil {
+						// The index is into the shared strings of other.
+						cell.sharedStringIndex = 0
+					}
+				}
+			}
+		}
+		if move.index < len(f.Sheets) {
+			delete(f.Sheet, f.Sheets[move.index].Name)
+			f.Sheets[move.index] = sheet
+		} else {
+			sheet.Name = move.name
+			f.Sheets = append(f.Sheets, sheet)
+			if f.sheetNames != nil {
+				f.sheetNames[foldSheetName(move.name)] = true
+			}
+		}
+		f.Sheet[sheet.Name] = sheet
+	}
+	if len(f.Sheets) > 0 && f.selectedSheet() == nil {
+		f.Sheets[0].Selected = true
+	}
+	for _, name := range plan.names {
+		if name.replace >= 0 {
+			f.DefinedNames[name.replace] = name.definedName
+		} else {
+			f.DefinedNames = append(f.DefinedNames, name.definedName)
+		}
+	}
+	plan.restyle(f)
+	return plan.conflicts, nil
+}
+
+// selectedSheet returns the first selected sheet of the File, or nil.
+func (f *File) selectedSheet() *Sheet {
+	for _, sheet := range f.Sheets {
+		if sheet.Selected {
+			return sheet
+		}
+	}
+	return nil
+}
+
+// mergePlan is what File.Merge does with the sheets, the defined names
+// and the named styles of the File merged.
+type mergePlan struct {
+	conflicts []MergeConflict
+	sheets    []sheetMove
+	names     []nameMove
+	// namedStyles maps the named style indexes in conflict to the
+	// index the cells take, -1 to lose their named style.
+	namedStyles map[int]int
+}
+
+// sheetMove is a sheet of the File merged, with its name and index in
+// the File merged into: the index of the sheet it replaces, or one past
+// the last.
+type sheetMove struct {
+	sheet *Sheet
+	name  string
+	index int
+}
+
+// nameMove is a defined name of the File merged, scoped to the File
+// merged into, with the index of the defined name it replaces, or -1.
+type nameMove struct {
+	definedName *xlsxDefinedName
+	replace     int
+}
+
+// planMerge returns what merging other into the File does with
+// strategy, and the conflicts found.
+func (f *File) planMerge(other *File, strategy MergeStrategy) *mergePlan {
+	plan := &mergePlan{namedStyles: make(map[int]int)}
+	// sheetIndexes maps the index of each sheet of other to its index
+	// in f, -1 for those left out.
+	sheetIndexes := make([]int, len(other.Sheets))
+	taken := &File{Sheet: make(map[string]*Sheet), Sheets: append([]*Sheet(nil), f.Sheets...)}
+	next := len(f.Sheets)
+	for i, sheet := range other.Sheets {
+		existing := -1
+		for j, s := range taken.Sheets {
+			if strings.EqualFold(s.Name, sheet.Name) {
+				existing = j
+				break
+			}
+		}
+		if existing < 0 {
+			plan.sheets = append(plan.sheets, sheetMove{sheet: sheet, name: sheet.Name, index: next})
+			taken.Sheets = append(taken.Sheets, &Sheet{Name: sheet.Name})
+			sheetIndexes[i] = next
+			next++
+			continue
+		}
+		conflict := MergeConflict{Kind: ConflictSheetName, Name: sheet.Name, Existing: taken.Sheets[existing].Name, Resolution: strategy}
+		switch strategy {
+		case MergeFail:
+			sheetIndexes[i] = existing
+		case MergeKeepExisting:
+			sheetIndexes[i] = -1
+		case MergeReplace:
+			if existing >= len(f.Sheets) {
+				// Two sheets of other differ only by case: the
+				// last one wins.
+				sheetIndexes[i] = existing
+				for k, move := range plan.sheets {
+					if move.index == existing {
+						plan.sheets[k].sheet = sheet
+						plan.sheets[k].name = sheet.Name
+					}
+				}
+			} else {
+				plan.sheets = append(plan.sheets, sheetMove{sheet: sheet, name: sheet.Name, index: existing})
+				sheetIndexes[i] = existing
+			}
+		case MergeRename:
+			conflict.Renamed = taken.uniqueSheetName(sheet.Name)
+			plan.sheets = append(plan.sheets, sheetMove{sheet: sheet, name: conflict.Renamed, index: next})
+			taken.Sheets = append(taken.Sheets, &Sheet{Name: conflict.Renamed})
+			sheetIndexes[i] = next
+			next++
+		}
+		plan.conflicts = append(plan.conflicts, conflict)
+	}
+
+	names := append([]*xlsxDefinedName(nil), f.DefinedNames...)
+	for _, definedName := range other.DefinedNames {
+		moved := *definedName
+		if moved.IsLocal() {
+			local := moved.LocalSheetID
+			if local < 0 || local >= len(sheetIndexes) || sheetIndexes[local] < 0 {
+				continue
+			}
+			moved.SetLocalSheetID(sheetIndexes[local])
+		}
+		existing := findDefinedName(names, &moved)
+		if existing < 0 {
+			plan.names = append(plan.names, nameMove{definedName: &moved, replace: -1})
+			names = append(names, &moved)
+			continue
+		}
+		conflict := MergeConflict{Kind: ConflictDefinedName, Name: moved.Name, Existing: names[existing].Name, Resolution: strategy}
+		if moved.IsLocal() {
+			conflict.Sheet = taken.Sheets[moved.LocalSheetID].Name
+		}
+		switch strategy {
+		case MergeReplace:
+			plan.names = append(plan.names, nameMove{definedName: &moved, replace: existing})
+			names[existing] = &moved
+		case MergeRename:
+			base := moved.Name
+			for n := 2; findDefinedName(names, &moved) >= 0; n++ {
+				moved.Name = base + "_" + strconv.Itoa(n)
+			}
+			conflict.Renamed = moved.Name
+			plan.names = append(plan.names, nameMove{definedName: &moved, replace: -1})
+			names = append(names, &moved)
+		}
+		plan.conflicts = append(plan.conflicts, conflict)
+	}
+
+	seen := make(map[int]bool)
+	for _, move := range plan.sheets {
+		forEachMergedStyle(move.sheet, func(style *Style) {
+			if style.NamedStyleIndex == nil || seen[*style.NamedStyleIndex] {
+				return
+			}
+			index := *style.NamedStyleIndex
+			seen[index] = true
+			// A File without named styles, such as a new one, has
+			// nothing for the named styles of other to collide with.
+			name, named := other.namedStyleName(index)
+			if !named || !f.hasNamedStyles() {
+				return
+			}
+			existing, ok := f.namedStyleName(index)
+			if ok && existing == name {
+				return
+			}
+			conflict := MergeConflict{Kind: ConflictNamedStyle, Name: name, Existing: existing, Resolution: strategy}
+			switch strategy {
+			case MergeReplace:
+				plan.namedStyles[index] = -1
+			case MergeRename:
+				plan.namedStyles[index] = -1
+				if renamed, ok := f.namedStyleIndex(name); ok {
+					plan.namedStyles[index] = renamed
+					conflict.Renamed = name
+				}
+			}
+			plan.conflicts = append(plan.conflicts, conflict)
+		})
+	}
+	return plan
+}
+
+// restyle gives the styles of the sheets moved into f the named style
+// indexes the plan resolved their conflicts with.
+func (plan *mergePlan) restyle(f *File) {
+	if len(plan.namedStyles) == 0 {
+		return
+	}
+	done := make(map[*Style]bool)
+	for _, move := range plan.sheets {
+		forEachMergedStyle(move.sheet, func(style *Style) {
+			if done[style] || style.NamedStyleIndex == nil {
+				return
+			}
+			done[style] = true
+			index, ok := plan.namedStyles[*style.NamedStyleIndex]
+			if !ok {
+				return
+			}
+			if index < 0 {
+				style.NamedStyleIndex = nil
+			} else {
+				style.NamedStyleIndex = &index
+			}
+		})
+	}
+}
+
+// forEachMergedStyle calls fn with the styles of the cells and of the
+// columns of sheet.
+func forEachMergedStyle(sheet *Sheet, fn func(*Style)) {
+	for _, col := range sheet.Cols {
+		if col != nil && col.style != nil {
+			fn(col.style)
+		}
+	}
+	for r := range sheet.rowList() {
+		row := sheet.row(r)
+		if row == nil {
+			continue
+		}
+		for _, cell := range row.Cells {
+			if cell == nil {
+				continue
+			}
+			if cell.style != nil {
+				fn(cell.style)
+			}
+			if cell.inherited != nil && cell.inherited.style != nil {
+				fn(cell.inherited.style)
+			}
+		}
+	}
+}
+
+// findDefinedName returns the index in names of the defined name of
+// the name of wanted, regardless of case, and of its scope, or -1.
+func findDefinedName(names []*xlsxDefinedName, wanted *xlsxDefinedName) int {
+	for i, definedName := range names {
+		if !strings.EqualFold(definedName.Name, wanted.Name) || definedName.IsLocal() != wanted.IsLocal() {
+			continue
+		}
+		if definedName.LocalSheetID == wanted.LocalSheetID {
+			return i
+		}
+	}
+	return -1
+}
+
+// namedStyleName returns the name of the named style at index in the
+// stylesheet of the File, and whether there is one.
+func (f *File) namedStyleName(index int) (string, bool) {
+	if f.styles == nil || f.styles.CellStyles == nil {
+		return "", false
+	}
+	for _, cellStyle := range f.styles.CellStyles.CellStyle {
+		if cellStyle.XfId == index {
+			return cellStyle.Name, true
+		}
+	}
+	return "", false
+}
+
+// hasNamedStyles returns true if the stylesheet of the File has named
+// styles.
+func (f *File) hasNamedStyles() bool {
+	return f.styles != nil && f.styles.CellStyles != nil && len(f.styles.CellStyles.CellStyle) > 0
+}
+
+// namedStyleIndex returns the index of the named style of that name in
+// the stylesheet of the File, and whether there is one.
+func (f *File) namedStyleIndex(name string) (int, bool) {
+	if f.styles == nil || f.styles.CellStyles == nil {
+		return 0, false
+	}
+	for _, cellStyle := range f.styles.CellStyles.CellStyle {
+		if strings.EqualFold(cellStyle.Name, name) {
+			return cellStyle.XfId, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,170 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type MergeSuite struct{}
+
+var _ = Suite(&MergeSuite{})
+
+// mergeFiles returns a File of the sheets "Summary" and "Data", and a
+// File to merge into it, whose sheet "data", and defined names "total"
+// and "Area", local to "data", are in conflict with those of the first.
+func mergeFiles(c *C) (*File, *File) {
+	into := NewFile()
+	for _, name := range []string{"Summary", "Data"} {
+		sheet, err := into.AddSheet(name)
+		c.Assert(err, IsNil)
+		sheet.AddRow().AddCell().Value = "into " + name
+	}
+	into.DefinedNames = append(into.DefinedNames,
+		&xlsxDefinedName{Name: "Total", Data: "Data!$A$1"},
+		&xlsxDefinedName{Name: "Area", Data: "Data!$A$1:$B$2", LocalSheetID: 1})
+
+	other := NewFile()
+	for _, name := range []string{"data", "Extra"} {
+		sheet, err := other.AddSheet(name)
+		c.Assert(err, IsNil)
+		sheet.AddRow().AddCell().Value = "other " + name
+	}
+	area := &xlsxDefinedName{Name: "Area", Data: "data!$C$1:$D$2"}
+	area.SetLocalSheetID(0)
+	other.DefinedNames = append(other.DefinedNames,
+		&xlsxDefinedName{Name: "total", Data: "data!$B$1"},
+		area,
+		&xlsxDefinedName{Name: "Other", Data: "Extra!$A$1"})
+	return into, other
+}
+
+// sheetNamesOf returns the names of the sheets of file.
+func sheetNamesOf(file *File) []string {
+	var names []string
+	for _, sheet := range file.Sheets {
+		names = append(names, sheet.Name)
+	}
+	return names
+}
+
+func (s *MergeSuite) TestMergeFail(c *C) {
+	into, other := mergeFiles(c)
+	expected := []MergeConflict{
+		{Kind: ConflictSheetName, Name: "data", Existing: "Data"},
+		{Kind: ConflictDefinedName, Name: "total", Existing: "Total"},
+		{Kind: ConflictDefinedName, Name: "Area", Sheet: "Data", Existing: "Area"},
+	}
+	c.Assert(into.MergeConflicts(other), DeepEquals, expected)
+	conflicts, err := into.Merge(other, MergeFail)
+	c.Assert(conflicts, DeepEquals, expected)
+	c.Assert(err, ErrorMatches, "3 conflicts merging the files, such as the sheet name 'data'")
+	conflictErr, ok := err.(*MergeConflictError)
+	c.Assert(ok, Equals, true)
+	c.Assert(conflictErr.Conflicts, DeepEquals, expected)
+	c.Assert(sheetNamesOf(into), DeepEquals, []string{"Summary", "Data"})
+	c.Assert(into.DefinedNames, HasLen, 2)
+
+	_, err = into.Merge(other, MergeStrategy(7))
+	c.Assert(err, ErrorMatches, "invalid merge strategy 7")
+
+	into, _ = mergeFiles(c)
+	extra := NewFile()
+	sheet, err := extra.AddSheet("Extra")
+	c.Assert(err, IsNil)
+	sheet.AddRow().AddCell().SetInt(5)
+	conflicts, err = into.Merge(extra, MergeFail)
+	c.Assert(err, IsNil)
+	c.Assert(conflicts, HasLen, 0)
+	c.Assert(sheetNamesOf(into), DeepEquals, []string{"Summary", "Data", "Extra"})
+	c.Assert(into.Sheet["Extra"].File, Equals, into)
+	c.Assert(into.Sheet["Extra"].Selected, Equals, false)
+}
+
+func (s *MergeSuite) TestMergeKeepExisting(c *C) {
+	into, other := mergeFiles(c)
+	conflicts, err := into.Merge(other, MergeKeepExisting)
+	c.Assert(err, IsNil)
+	c.Assert(conflicts, DeepEquals, []MergeConflict{
+		{Kind: ConflictSheetName, Name: "data", Existing: "Data", Resolution: MergeKeepExisting},
+		{Kind: ConflictDefinedName, Name: "total", Existing: "Total", Resolution: MergeKeepExisting},
+	})
+	c.Assert(sheetNamesOf(into), DeepEquals, []string{"Summary", "Data", "Extra"})
+	c.Assert(into.Sheet["Data"].Cell(0, 0).Value, Equals, "into Data")
+	c.Assert(into.DefinedNames, HasLen, 3)
+	c.Assert(into.DefinedNames[0].Data, Equals, "Data!$A$1")
+	c.Assert(into.DefinedNames[1].Data, Equals, "Data!$A$1:$B$2")
+	c.Assert(into.DefinedNames[2].Name, Equals, "Other")
+}
+
+func (s *MergeSuite) TestMergeReplace(c *C) {
+	into, other := mergeFiles(c)
+	conflicts, err := into.Merge(other, MergeReplace)
+	c.Assert(err, IsNil)
+	c.Assert(conflicts, HasLen, 3)
+	c.Assert(sheetNamesOf(into), DeepEquals, []string{"Summary", "data", "Extra"})
+	c.Assert(into.Sheet["Data"], IsNil)
+	c.Assert(into.Sheet["data"].Cell(0, 0).Value, Equals, "other data")
+	c.Assert(into.DefinedNames, HasLen, 3)
+	c.Assert(into.DefinedNames[0].Data, Equals, "data!$B$1")
+	c.Assert(into.DefinedNames[1].Data, Equals, "data!$C$1:$D$2")
+	c.Assert(into.DefinedNames[1].LocalSheetID, Equals, 1)
+	c.Assert(into.DefinedNames[2].Name, Equals, "Other")
+}
+
+func (s *MergeSuite) TestMergeRename(c *C) {
+	into, other := mergeFiles(c)
+	conflicts, err := into.Merge(other, MergeRename)
+	c.Assert(err, IsNil)
+	c.Assert(conflicts, DeepEquals, []MergeConflict{
+		{Kind: ConflictSheetName, Name: "data", Existing: "Data", Resolution: MergeRename, Renamed: "data (2)"},
+		{Kind: ConflictDefinedName, Name: "total", Existing: "Total", Resolution: MergeRename, Renamed: "total_2"},
+	})
+	c.Assert(sheetNamesOf(into), DeepEquals, []string{"Summary", "Data", "data (2)", "Extra"})
+	c.Assert(into.Sheet["data (2)"].Cell(0, 0).Value, Equals, "other data")
+	c.Assert(into.DefinedNames, HasLen, 5)
+	c.Assert(into.DefinedNames[2].Name, Equals, "total_2")
+	c.Assert(into.DefinedNames[3].Name, Equals, "Area")
+	c.Assert(into.DefinedNames[3].LocalSheetID, Equals, 2)
+}
+
+func (s *MergeSuite) TestMergeNamedStyles(c *C) {
+	namedStyles := func(names ...string) *xlsxStyleSheet {
+		styles := newXlsxStyleSheet(nil)
+		styles.CellStyles = &xlsxCellStyles{Count: len(names)}
+		for i, name := range names {
+			styles.CellStyles.CellStyle = append(styles.CellStyles.CellStyle, xlsxCellStyle{Name: name, XfId: i})
+		}
+		return styles
+	}
+	merge := func(strategy MergeStrategy) (*File, []MergeConflict) {
+		into := NewFile()
+		into.styles = namedStyles("Normal", "Good", "Heading")
+		other := NewFile()
+		other.styles = namedStyles("Normal", "Heading")
+		sheet, err := other.AddSheet("Styled")
+		c.Assert(err, IsNil)
+		row := sheet.AddRow()
+		for _, index := range []int{0, 1} {
+			index := index
+			style := NewStyle()
+			style.NamedStyleIndex = &index
+			row.AddCell().SetStyle(style)
+		}
+		conflicts, err := into.Merge(other, strategy)
+		c.Assert(err, IsNil)
+		return into, conflicts
+	}
+
+	into, conflicts := merge(MergeKeepExisting)
+	c.Assert(conflicts, DeepEquals, []MergeConflict{
+		{Kind: ConflictNamedStyle, Name: "Heading", Existing: "Good", Resolution: MergeKeepExisting},
+	})
+	c.Assert(*into.Sheets[0].Cell(0, 1).GetStyle().NamedStyleIndex, Equals, 1)
+
+	into, _ = merge(MergeReplace)
+	c.Assert(*into.Sheets[0].Cell(0, 0).GetStyle().NamedStyleIndex, Equals, 0)
+	c.Assert(into.Sheets[0].Cell(0, 1).GetStyle().NamedStyleIndex, IsNil)
+
+	into, conflicts = merge(MergeRename)
+	c.Assert(conflicts[0].Renamed, Equals, "Heading")
+	c.Assert(*into.Sheets[0].Cell(0, 1).GetStyle().NamedStyleIndex, Equals, 2)
+}
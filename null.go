@@ -0,0 +1,77 @@
+package xlsx
+
+// NullPolicy is the way null values, such as invalid sql.NullString
+// values or the JSON nulls of records, are written to the cells of a
+// File, see File.NullAs, so that every report renders them alike.  By
+// default they are written as empty cells.
+type NullPolicy struct {
+	// Text is written in place of the nulls, such as "NULL" or "N/A".
+	Text string
+	// Style, if set, is the style of the cells holding nulls, such as
+	// a grey fill telling them apart from empty strings.
+	Style *Style
+}
+
+// NullAsEmpty, NullAsNULL and NullAsNA are the usual ways of writing
+// nulls.
+var (
+	NullAsEmpty = NullPolicy{}
+	NullAsNULL  = NullPolicy{Text: "NULL"}
+	NullAsNA    = NullPolicy{Text: "N/A"}
+)
+
+// nullPolicy returns the NullPolicy of the File of the Row, the default
+// one if the Row isn't in a File.
+func (r *Row) nullPolicy() NullPolicy {
+	if r.Sheet == nil || r.Sheet.File == nil {
+		return NullAsEmpty
+	}
+	return r.Sheet.File.NullAs
+}
+
+// addNullCell adds a cell holding a null to the Row, written as the
+// NullPolicy of its File says.
+func (r *Row) addNullCell() *Cell {
+	policy := r.nullPolicy()
+	cell := r.AddCell()
+	cell.SetString(policy.Text)
+	if policy.Style != nil {
+		cell.SetStyle(policy.Style)
+	}
+	return cell
+}
+
+// writeNullable writes a row of cells to the current sheet like write,
+// the cells at the indexes where nulls is true being written as the
+// NullPolicy of the file says whatever the types of their columns.
+// nulls may be nil if no cell is null.
+func (sf *StreamFile) writeNullable(cells []string, nulls []bool) error {
+	cells, skip, err := sf.checkRow(cells)
+	if err != nil || skip {
+		return err
+	}
+	cells, types, err := sf.typeRow(cells)
+	if err != nil {
+		return err
+	}
+	var xfIds []int
+	if nulls != nil {
+		cells = append([]string(nil), cells...)
+	}
+	for i, null := range nulls {
+		if !null || i >= len(cells) {
+			continue
+		}
+		cells[i] = sf.xlsxFile.NullAs.Text
+		if types != nil {
+			types[i] = CellTypeString
+		}
+		if sf.nullStyleId > 0 {
+			if xfIds == nil {
+				xfIds = make([]int, len(cells))
+			}
+			xfIds[i] = sf.cellStyleIds[sf.nullStyleId-1]
+		}
+	}
+	return sf.writeRow(cells, types, xfIds, nil, nil)
+}
@@ -0,0 +1,72 @@
+package xlsx
+
+import (
+	"bytes"
+	"database/sql"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type NullSuite struct{}
+
+var _ = Suite(&NullSuite{})
+
+func (s *NullSuite) TestRowNullAs(c *C) {
+	f := NewFile()
+	f.NullAs = NullAsNULL
+	sheet, _ := f.AddSheet("Test1")
+	row := sheet.AddRow()
+	values := []interface{}{sql.NullString{String: "Smith", Valid: true}, sql.NullInt64{}, nil}
+	c.Assert(row.WriteSlice(&values, -1), Equals, 3)
+	c.Assert(row.Cells[0].Value, Equals, "Smith")
+	c.Assert(row.Cells[1].Value, Equals, "NULL")
+	c.Assert(row.Cells[2].Value, Equals, "NULL")
+
+	style := NewStyle()
+	style.Fill = *NewFill("solid", "FFD9D9D9", "FFD9D9D9")
+	f.NullAs = NullPolicy{Style: style}
+	row = sheet.AddRow()
+	record := struct {
+		Name   sql.NullString
+		Rating sql.NullFloat64
+	}{Name: sql.NullString{String: "Eric", Valid: true}}
+	c.Assert(row.WriteStruct(&record, -1), Equals, 2)
+	c.Assert(row.Cells[1].Value, Equals, "")
+	c.Assert(row.Cells[1].GetStyle().Fill.FgColor, Equals, "FFD9D9D9")
+}
+
+func (s *NullSuite) TestStreamNullAs(c *C) {
+	style := NewStyle()
+	style.Font.Italic = true
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	c.Assert(builder.SetNullAs(NullPolicy{Text: "N/A", Style: style}), IsNil)
+	c.Assert(builder.AddSheetWithTypes("Items", []string{"name", "price", "color"},
+		[]ColumnType{ColumnTypeString, ColumnTypeFloat}), IsNil)
+	stream, err := builder.Build()
+	c.Assert(err, IsNil)
+	records := "{\"name\": \"Pens\", \"price\": null}\n{\"name\": \"Ink\", \"price\": 4.5, \"color\": null}\n{\"name\": \"Tape\"}\n"
+	adapter := NewNDJSONAdapter(strings.NewReader(records), RecordOptions{Columns: []string{"name", "price", "color"}})
+	c.Assert(stream.WriteRecords(adapter), IsNil)
+	c.Assert(stream.WriteTyped([]StreamCell{NewStringStreamCell("Glue"), NewNullStreamCell(), NewStringStreamCell("")}), IsNil)
+	c.Assert(stream.Close(), IsNil)
+	c.Assert(builder.SetNullAs(NullAsEmpty), Equals, BuiltStreamFileBuilderError)
+
+	f, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	sheets, err := f.ToSlice()
+	c.Assert(err, IsNil)
+	// Missing keys are left empty, unlike nulls.
+	c.Assert(sheets[0], DeepEquals, [][]string{
+		{"name", "price", "color"},
+		{"Pens", "N/A", ""},
+		{"Ink", "4.5", "N/A"},
+		{"Tape", "", ""},
+		{"Glue", "N/A", ""},
+	})
+	sheet := f.Sheets[0]
+	c.Assert(sheet.Cell(1, 1).GetStyle().Font.Italic, Equals, true)
+	c.Assert(sheet.Cell(2, 1).GetStyle().Font.Italic, Equals, false)
+	c.Assert(sheet.Cell(4, 1).GetStyle().Font.Italic, Equals, true)
+}
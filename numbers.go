@@ -0,0 +1,122 @@
+package xlsx
+
+// Numbers on the Mac opens files saved Compatible, but a few details
+// of them still get in the way: fonts that aren't installed on Macs
+// make it show a dialog about missing fonts, and the selection of a
+// sheet with frozen panes must be in the pane that is active.  A File
+// that is NumbersCompatible is saved Compatible with these details
+// fixed as well.
+
+// defaultNumbersFont is the font replacing, in files saved
+// NumbersCompatible, the fonts that aren't installed on Macs, unless
+// WithNumbersFont gives another one.  Arial is installed on both Macs
+// and PCs.
+const defaultNumbersFont = "Arial"
+
+// macFonts holds the fonts that are installed on Macs along with
+// Numbers, and which are also commonly found on PCs.
+var macFonts = map[string]bool{
+	"American Typewriter":   true,
+	"Andale Mono":           true,
+	"Arial":                 true,
+	"Arial Black":           true,
+	"Arial Narrow":          true,
+	"Arial Rounded MT Bold": true,
+	"Avenir":                true,
+	"Baskerville":           true,
+	"Comic Sans MS":         true,
+	"Courier":               true,
+	"Courier New":           true,
+	"Futura":                true,
+	"Geneva":                true,
+	"Georgia":               true,
+	"Gill Sans":             true,
+	"Helvetica":             true,
+	"Helvetica Neue":        true,
+	"Impact":                true,
+	"Menlo":                 true,
+	"Monaco":                true,
+	"Optima":                true,
+	"Palatino":              true,
+	"Tahoma":                true,
+	"Times":                 true,
+	"Times New Roman":       true,
+	"Trebuchet MS":          true,
+	"Verdana":               true,
+}
+
+// isMacFont returns true if the font called name is installed on Macs.
+func isMacFont(name string) bool {
+	return macFonts[name]
+}
+
+// isCompatible returns true if the File is saved without the parts and
+// extensions that readers other than Excel may not support.
+func (f *File) isCompatible() bool {
+	return f.Compatible || f.NumbersCompatible
+}
+
+// numbersDowngrades returns the features of the File that are changed
+// when it is saved NumbersCompatible.
+func (f *File) numbersDowngrades() []Downgrade {
+	var downgrades []Downgrade
+	replaced := make(map[string]bool)
+	replace := func(where string, style *Style) {
+		if style == nil || style.Font.Name == "" || isMacFont(style.Font.Name) || replaced[style.Font.Name] {
+			return
+		}
+		replaced[style.Font.Name] = true
+		downgrades = append(downgrades, Downgrade{where, "the font " + style.Font.Name + " is replaced by " + defaultNumbersFont +
+			", or the font of WithNumbersFont"})
+	}
+	for _, sheet := range f.Sheets {
+		for _, col := range sheet.Cols {
+			if col != nil {
+				replace(sheet.Name, col.style)
+			}
+		}
+		for _, row := range sheet.rowList() {
+			if row == nil {
+				continue
+			}
+			row.load()
+			for _, cell := range row.Cells {
+				if cell != nil {
+					replace(sheet.Name, cell.style)
+				}
+			}
+		}
+	}
+	return downgrades
+}
+
+// withMacFonts returns a copy of the fonts with those that aren't
+// installed on Macs replaced by the font called name.
+func (fonts xlsxFonts) withMacFonts(name string) xlsxFonts {
+	fonts.Font = append([]xlsxFont(nil), fonts.Font...)
+	for i := range fonts.Font {
+		if !isMacFont(fonts.Font[i].Name.Val) {
+			fonts.Font[i].Name.Val = name
+		}
+	}
+	return fonts
+}
+
+// makeNumbersSheetViews fixes the sheet views of worksheet for Numbers,
+// which keeps the selection of a sheet with frozen panes in the pane
+// that is active, starting at its top left cell.
+func makeNumbersSheetViews(worksheet *xlsxWorksheet) {
+	for i := range worksheet.SheetViews.SheetView {
+		sheetView := &worksheet.SheetViews.SheetView[i]
+		if sheetView.Pane == nil || sheetView.Pane.ActivePane == "" {
+			continue
+		}
+		for j := range sheetView.Selection {
+			sheetView.Selection[j].Pane = sheetView.Pane.ActivePane
+			if sheetView.Pane.TopLeftCell != "" {
+				sheetView.Selection[j].ActiveCell = sheetView.Pane.TopLeftCell
+				sheetView.Selection[j].SQRef = sheetView.Pane.TopLeftCell
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// OpenOption is a setting of the way a file is read, given to OpenFileWithOptions, OpenBinaryWithOptions or
+// OpenReaderAtWithOptions, such as WithUnknownParts(UnknownPartsRejected).
+type OpenOption func(*openOptions) error
+
+// openOptions holds the settings of the open options given.
+type openOptions struct {
+	rowLimit     int
+	budget       *memoryBudget
+	unknownParts UnknownParts
+	preserve     bool
+	trackChanges bool
+	// source is what the file is read from, kept by a File read
+	// WithChangeTracking.
+	source changeSource
+}
+
+// WithRowLimit reads only the first rowLimit rows of each sheet, like OpenFileWithRowLimit, or every row with
+// NoRowLimit, the default.
+func WithRowLimit(rowLimit int) OpenOption {
+	return func(o *openOptions) error {
+		if rowLimit < 0 && rowLimit != NoRowLimit {
+			return fmt.Errorf("invalid row limit %d", rowLimit)
+		}
+		o.rowLimit = rowLimit
+		return nil
+	}
+}
+
+// WithMemoryLimit aborts the reading of the file with a *MemoryLimitError once the File would take more than about
+// maxBytes bytes of memory, like OpenFileWithLimit.
+func WithMemoryLimit(maxBytes int64) OpenOption {
+	return func(o *openOptions) error {
+		if maxBytes <= 0 {
+			return fmt.Errorf("invalid memory limit of %d bytes", maxBytes)
+		}
+		o.budget = &memoryBudget{limit: maxBytes}
+		return nil
+	}
+}
+
+// WithUnknownParts sets the way the parts of the file which won't survive a round trip, see SkippedPart, are dealt
+// with: ignored, the default, listed by File.SkippedParts, or rejected with an *UnknownPartsError.
+func WithUnknownParts(mode UnknownParts) OpenOption {
+	return func(o *openOptions) error {
+		switch mode {
+		case UnknownPartsIgnored, UnknownPartsListed, UnknownPartsRejected:
+		default:
+			return fmt.Errorf("invalid unknown parts mode %d", mode)
+		}
+		o.unknownParts = mode
+		return nil
+	}
+}
+
+// WithPreserve keeps what the workbook and the worksheets of the file have that this package doesn't model, such as
+// pivot caches, conditional formatting or form controls, along with the namespaces they use, the relationships of the
+// package to the parts it doesn't model, such as custom properties, and the content type of workbooks with macros.
+// They are written back as they were read when the File is saved, besides the raw parts every File keeps, so that
+// changing a cell doesn't lose the charts, pivot tables or macros of the file. What refers to cells isn't updated
+// when rows or columns are added or removed, and nothing is written back by files saved Compatible.
+func WithPreserve(preserve bool) OpenOption {
+	return func(o *openOptions) error {
+		o.preserve = preserve
+		return nil
+	}
+}
+
+// WithChangeTracking keeps a fingerprint of each cell of the file read, so that File.Changes lists the cells changed
+// since, and the File can be saved WithMinimalRewrite. The fingerprints take some 24 bytes a cell. A File opened by
+// OpenReaderAtWithOptions keeps its io.ReaderAt, which must stay readable for minimal rewrites, while one opened by
+// OpenFileWithOptions opens its file again, and is written whole if the file was changed since.
+func WithChangeTracking(track bool) OpenOption {
+	return func(o *openOptions) error {
+		o.trackChanges = track
+		return nil
+	}
+}
+
+// newOpenOptions returns the settings of options.
+func newOpenOptions(options []OpenOption) (openOptions, error) {
+	o := openOptions{rowLimit: NoRowLimit}
+	for _, option := range options {
+		if err := option(&o); err != nil {
+			return openOptions{}, err
+		}
+	}
+	return o, nil
+}
+
+// OpenFileWithOptions takes the name of an XLSX file and returns a populated xlsx.File struct for it, read as the
+// options say.
+func OpenFileWithOptions(fileName string, options ...OpenOption) (*File, error) {
+	o, err := newOpenOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	z, err := zip.OpenReader(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+	if o.trackChanges {
+		if o.source.info, err = os.Stat(fileName); err != nil {
+			return nil, err
+		}
+		o.source.path = fileName
+	}
+	return readZipReader(&z.Reader, o)
+}
+
+// OpenBinaryWithOptions takes the bytes of an XLSX file and returns a populated xlsx.File struct for it, read as the
+// options say.
+func OpenBinaryWithOptions(bs []byte, options ...OpenOption) (*File, error) {
+	return OpenReaderAtWithOptions(bytes.NewReader(bs), int64(len(bs)), options...)
+}
+
+// OpenReaderAtWithOptions takes an io.ReaderAt of an XLSX file of size bytes and returns a populated xlsx.File struct
+// for it, read as the options say.
+func OpenReaderAtWithOptions(r io.ReaderAt, size int64, options ...OpenOption) (*File, error) {
+	o, err := newOpenOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	o.source = changeSource{readerAt: r, size: size}
+	return readZipReader(z, o)
+}
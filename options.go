@@ -0,0 +1,253 @@
+package xlsx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Option is a setting of the way a file is written, given to
+// NewStreamFileBuilderWithOptions, StreamFileBuilder.SetOptions,
+// File.Write or File.Save, such as WithCompression(CompressionStore).
+// The settings left out keep their defaults, and the later of two
+// options setting the same thing wins.
+type Option func(*writeOptions) error
+
+// writeOptions holds the settings of the options given, nil or false
+// for those left out.
+type writeOptions struct {
+	compression   *StreamCompression
+	defaultFont   *Font
+	numbersFont   string
+	zip64         *bool
+	sharedStrings SharedStringsMode
+	// flushSet is true if the flush interval, flushRows and
+	// flushBytes, was given.
+	flushSet   bool
+	flushRows  int
+	flushBytes int
+	// minimalRewrite is true if the File is to be written by
+	// rewriting only the worksheets whose cells changed.
+	minimalRewrite bool
+}
+
+// SharedStringsMode is the way the strings of the cells are written,
+// see WithSharedStringsMode.
+type SharedStringsMode int
+
+const (
+	// SharedStringsDefault writes the strings the default way: to the
+	// shared strings for a File, and inline in their cells for a
+	// StreamFile, unless StreamFileBuilder.SetGoogleSheetsCompatible
+	// was called.
+	SharedStringsDefault SharedStringsMode = iota
+	// SharedStringsShared writes the strings to the shared strings,
+	// which makes files with repeated strings smaller, but keeps every
+	// distinct string of a StreamFile in memory until it is closed,
+	// unless StreamFileBuilder.SetSharedStringLimit caps them.
+	SharedStringsShared
+	// SharedStringsInline writes the strings inline in their cells.
+	SharedStringsInline
+)
+
+// ErrZip64Required is returned when writing a file that needs ZIP64
+// records, which WithZip64(false) rules out.
+var ErrZip64Required = errors.New("the file needs ZIP64 records, with 65,535 parts or more, or 4 GiB or more of a part or of the file")
+
+const (
+	// zip64PartLimit and zip64SizeLimit are the number of parts and
+	// the size of a part or of the file from which ZIP64 records are
+	// written.
+	zip64PartLimit = 0xffff
+	zip64SizeLimit = 0xffffffff
+)
+
+// WithCompression sets the way the parts of the file are compressed, see StreamFileBuilder.SetCompression. A File
+// saved whole writes CompressionDeflateStreaming like CompressionDeflate.
+func WithCompression(compression StreamCompression) Option {
+	return func(o *writeOptions) error {
+		if err := validateStreamCompression(compression); err != nil {
+			return err
+		}
+		o.compression = &compression
+		return nil
+	}
+}
+
+// WithDefaultFont sets the default font of the file, which the cells without a style and the styles left at the font
+// of NewStyle, see SetDefaultFont, are shown in, without changing the default font of the package.
+func WithDefaultFont(size int, name string) Option {
+	return func(o *writeOptions) error {
+		if size < 1 || name == "" {
+			return fmt.Errorf("invalid default font '%s' of size %d", name, size)
+		}
+		o.defaultFont = NewFont(size, name)
+		return nil
+	}
+}
+
+// WithNumbersFont sets the font replacing, in files saved NumbersCompatible, the fonts that aren't installed on Macs,
+// Arial by default.
+func WithNumbersFont(name string) Option {
+	return func(o *writeOptions) error {
+		if name == "" {
+			return errors.New("the font replacing those missing on Macs needs a name")
+		}
+		o.numbersFont = name
+		return nil
+	}
+}
+
+// WithZip64 sets whether the file may use ZIP64 records, which the zip writer adds, only when needed, to files with
+// 65,535 parts or more, or with 4 GiB or more of a part or of the file. They are allowed by default. Some old zip
+// readers don't know them, and with WithZip64(false) writing such a file fails with ErrZip64Required instead. The size
+// of the file is only known once written, so it is checked before the end of the file is written.
+func WithZip64(allowed bool) Option {
+	return func(o *writeOptions) error {
+		o.zip64 = &allowed
+		return nil
+	}
+}
+
+// WithSharedStringsMode sets the way the strings of the cells are written.
+func WithSharedStringsMode(mode SharedStringsMode) Option {
+	return func(o *writeOptions) error {
+		switch mode {
+		case SharedStringsDefault, SharedStringsShared, SharedStringsInline:
+		default:
+			return fmt.Errorf("unknown shared strings mode %d", mode)
+		}
+		o.sharedStrings = mode
+		return nil
+	}
+}
+
+// WithFlushInterval sets how often the rows of a StreamFile are flushed, see StreamFileBuilder.SetFlushInterval. It
+// doesn't apply to a File saved whole, which is written at once.
+func WithFlushInterval(rows, bytes int) Option {
+	return func(o *writeOptions) error {
+		if rows < 0 || bytes < 0 {
+			return fmt.Errorf("invalid flush interval of %d rows and %d bytes, which can't be negative", rows, bytes)
+		}
+		o.flushSet, o.flushRows, o.flushBytes = true, rows, bytes
+		return nil
+	}
+}
+
+// WithMinimalRewrite saves a File opened WithChangeTracking by copying every part of the file read as it is, compressed
+// data included, except the worksheets whose cells changed, see File.Changes, in which only the changed cells are
+// rewritten, their strings inline. Editing a few cells of a large file is then about as fast as copying it. Changes
+// beyond the values and formulas of cells, such as styles, merges, rows, columns, sheets or settings of the File, or
+// the options WithDefaultFont and WithSharedStringsMode(SharedStringsShared), need the whole File written, which is
+// then done instead. Only a File saved whole may be written this way.
+func WithMinimalRewrite(minimal bool) Option {
+	return func(o *writeOptions) error {
+		o.minimalRewrite = minimal
+		return nil
+	}
+}
+
+// NewStreamFileBuilderWithOptions creates a StreamFileBuilder writing to writer, like NewStreamFileBuilder, set up with
+// the options.
+func NewStreamFileBuilderWithOptions(writer io.Writer, options ...Option) (*StreamFileBuilder, error) {
+	sb := NewStreamFileBuilder(writer)
+	if err := sb.SetOptions(options...); err != nil {
+		return nil, err
+	}
+	return sb, nil
+}
+
+// SetOptions sets up the StreamFileBuilder with the options, such as a builder made by
+// NewStreamFileBuilderFromExisting. The options given by a setter too, such as WithCompression and SetCompression, are
+// the same settings, the later call winning. No option is set if one of them is invalid.
+func (sb *StreamFileBuilder) SetOptions(options ...Option) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	o, err := newWriteOptions(options)
+	if err != nil {
+		return err
+	}
+	if o.minimalRewrite {
+		return errors.New("a minimal rewrite only applies to a File opened WithChangeTracking")
+	}
+	if o.compression != nil {
+		if err := sb.SetCompression(*o.compression); err != nil {
+			return err
+		}
+	}
+	if o.flushSet {
+		if err := sb.SetFlushInterval(o.flushRows, o.flushBytes); err != nil {
+			return err
+		}
+	}
+	if o.defaultFont != nil {
+		sb.defaultFont = o.defaultFont
+	}
+	if o.numbersFont != "" {
+		sb.numbersFont = o.numbersFont
+	}
+	if o.sharedStrings != SharedStringsDefault {
+		sb.sharedStrings = o.sharedStrings
+	}
+	if o.zip64 != nil {
+		sb.noZip64 = !*o.zip64
+	}
+	return nil
+}
+
+// newWriteOptions returns the settings of options.
+func newWriteOptions(options []Option) (writeOptions, error) {
+	var o writeOptions
+	for _, option := range options {
+		if err := option(&o); err != nil {
+			return writeOptions{}, err
+		}
+	}
+	return o, nil
+}
+
+// macFont returns the font replacing those that aren't installed on
+// Macs, see WithNumbersFont.
+func (o writeOptions) macFont() string {
+	if o.numbersFont == "" {
+		return defaultNumbersFont
+	}
+	return o.numbersFont
+}
+
+// zip64Allowed returns false if the options rule out ZIP64 records.
+func (o writeOptions) zip64Allowed() bool {
+	return o.zip64 == nil || *o.zip64
+}
+
+// checkZip64 returns ErrZip64Required if a file with the given number
+// of parts, largest part and size needs ZIP64 records.
+func checkZip64(parts int, largestPart, size int64) error {
+	if parts >= zip64PartLimit || largestPart >= zip64SizeLimit || size >= zip64SizeLimit {
+		return ErrZip64Required
+	}
+	return nil
+}
+
+// replaceDefaultFont makes font the default font of the style sheet:
+// the fonts left at the default font of the package are replaced by it,
+// and a style sheet without fonts is given it as its first font, which
+// the cells without a style use.
+func (styles *xlsxStyleSheet) replaceDefaultFont(font *Font) {
+	defaultFont := DefaultFont()
+	for i := range styles.Fonts.Font {
+		xFont := &styles.Fonts.Font[i]
+		if xFont.Name.Val == defaultFont.Name && xFont.Sz.Val == strconv.Itoa(defaultFont.Size) {
+			xFont.Name.Val = font.Name
+			xFont.Sz.Val = strconv.Itoa(font.Size)
+		}
+	}
+	if len(styles.Fonts.Font) == 0 {
+		style := NewStyle()
+		style.Font = *font
+		xFont, _, _, _ := style.makeXLSXStyleElements()
+		styles.addFont(xFont)
+	}
+}
@@ -0,0 +1,117 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type OptionsSuite struct{}
+
+var _ = Suite(&OptionsSuite{})
+
+// readOptionsParts returns the method and the contents of each part of
+// the file held by data.
+func readOptionsParts(c *C, data []byte) (map[string]uint16, map[string]string) {
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	c.Assert(err, IsNil)
+	methods := make(map[string]uint16)
+	contents := make(map[string]string)
+	for _, zipFile := range zipReader.File {
+		reader, err := zipFile.Open()
+		c.Assert(err, IsNil)
+		content, err := ioutil.ReadAll(reader)
+		c.Assert(err, IsNil)
+		methods[zipFile.Name] = zipFile.Method
+		contents[zipFile.Name] = string(content)
+	}
+	return methods, contents
+}
+
+func (s *OptionsSuite) TestStreamFileBuilder(c *C) {
+	var buffer bytes.Buffer
+	builder, err := NewStreamFileBuilderWithOptions(&buffer,
+		WithCompression(CompressionStore),
+		WithDefaultFont(11, "Calibri"),
+		WithSharedStringsMode(SharedStringsShared),
+		WithFlushInterval(100, 0),
+		WithZip64(false),
+	)
+	c.Assert(err, IsNil)
+	c.Assert(builder.flushRows, Equals, 100)
+	c.Assert(builder.AddSheet("Sheet1", []string{"Name", "Count"}, nil), IsNil)
+	stream, err := builder.Build()
+	c.Assert(err, IsNil)
+	c.Assert(builder.SetOptions(WithZip64(true)), Equals, BuiltStreamFileBuilderError)
+	c.Assert(stream.Write([]string{"Taco", "1"}), IsNil)
+	c.Assert(stream.Write([]string{"Taco", "2"}), IsNil)
+	c.Assert(stream.Close(), IsNil)
+
+	methods, contents := readOptionsParts(c, buffer.Bytes())
+	c.Assert(methods["xl/worksheets/sheet1.xml"], Equals, zip.Store)
+	c.Assert(strings.Contains(contents["xl/worksheets/sheet1.xml"], "inlineStr"), Equals, false)
+	c.Assert(strings.Count(contents["xl/sharedStrings.xml"], "<t>Taco</t>"), Equals, 1)
+	c.Assert(strings.Contains(contents["xl/styles.xml"], `val="Calibri"`), Equals, true)
+	c.Assert(strings.Contains(contents["xl/styles.xml"], `val="Verdana"`), Equals, false)
+
+	file, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	output, err := file.ToSlice()
+	c.Assert(err, IsNil)
+	c.Assert(output, DeepEquals, [][][]string{{{"Name", "Count"}, {"Taco", "1"}, {"Taco", "2"}}})
+}
+
+func (s *OptionsSuite) TestInvalidOptions(c *C) {
+	_, err := NewStreamFileBuilderWithOptions(&bytes.Buffer{}, WithCompression(StreamCompression(9)))
+	c.Assert(err, ErrorMatches, "unknown compression 9")
+	_, err = NewStreamFileBuilderWithOptions(&bytes.Buffer{}, WithDefaultFont(0, "Calibri"))
+	c.Assert(err, ErrorMatches, "invalid default font 'Calibri' of size 0")
+	_, err = NewStreamFileBuilderWithOptions(&bytes.Buffer{}, WithSharedStringsMode(SharedStringsMode(7)))
+	c.Assert(err, ErrorMatches, "unknown shared strings mode 7")
+
+	builder := NewStreamFileBuilder(&bytes.Buffer{})
+	err = builder.SetOptions(WithCompression(CompressionStore), WithFlushInterval(-1, 0))
+	c.Assert(err, ErrorMatches, "invalid flush interval of -1 rows and 0 bytes, which can't be negative")
+	c.Assert(builder.compression, Equals, CompressionDeflate)
+}
+
+func (s *OptionsSuite) TestFileWrite(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	row := sheet.AddRow()
+	row.AddCell().SetString("Taco")
+	row.AddCell().SetInt(1)
+
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer, WithCompression(CompressionStore), WithSharedStringsMode(SharedStringsInline),
+		WithDefaultFont(11, "Calibri"), WithZip64(false)), IsNil)
+	methods, contents := readOptionsParts(c, buffer.Bytes())
+	c.Assert(methods["xl/worksheets/sheet1.xml"], Equals, zip.Store)
+	c.Assert(strings.Contains(contents["xl/worksheets/sheet1.xml"], `t="inlineStr"`), Equals, true)
+	c.Assert(strings.Contains(contents["xl/styles.xml"], `val="Calibri"`), Equals, true)
+	c.Assert(strings.Contains(contents["xl/styles.xml"], `val="Verdana"`), Equals, false)
+	read, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	output, err := read.ToSlice()
+	c.Assert(err, IsNil)
+	c.Assert(output, DeepEquals, [][][]string{{{"Taco", "1"}}})
+
+	buffer.Reset()
+	c.Assert(file.Write(&buffer), IsNil)
+	methods, contents = readOptionsParts(c, buffer.Bytes())
+	c.Assert(methods["xl/worksheets/sheet1.xml"], Equals, zip.Deflate)
+	c.Assert(strings.Contains(contents["xl/worksheets/sheet1.xml"], `t="s"`), Equals, true)
+
+	c.Assert(file.Write(&buffer, WithFlushInterval(10, 0)), ErrorMatches, "a flush interval only applies to a StreamFile")
+}
+
+func (s *OptionsSuite) TestCheckZip64(c *C) {
+	c.Assert(checkZip64(zip64PartLimit-1, zip64SizeLimit-1, zip64SizeLimit-1), IsNil)
+	c.Assert(checkZip64(zip64PartLimit, 0, 0), Equals, ErrZip64Required)
+	c.Assert(checkZip64(1, zip64SizeLimit, 0), Equals, ErrZip64Required)
+	c.Assert(checkZip64(1, 0, zip64SizeLimit), Equals, ErrZip64Required)
+}
@@ -0,0 +1,235 @@
+package xlsx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// date1904Offset is the number of days from the start of the 1900 date
+// system to that of the 1904 one.
+const date1904Offset = 1462
+
+// PipelineTransform is called by a Pipeline with each row read from a
+// sheet, the name of the sheet, the index of the row from 0 and its
+// cells, as read by StreamSheetReader.ReadTypedRow.  It returns the
+// cells to write instead, which may be cells itself after changing
+// them, or SkipRow to drop the row.  Any other error stops the Pipeline.
+type PipelineTransform func(sheet string, rowIndex int, cells []StreamCell) ([]StreamCell, error)
+
+// PipelineError is returned when a Pipeline fails on a row, Row being
+// the index of the row read from the sheet.
+type PipelineError struct {
+	Sheet string
+	Row   int
+	Err   error
+}
+
+// Error returns a description of the PipelineError.
+func (e *PipelineError) Error() string {
+	return fmt.Sprintf("sheet %s, row %d: %v", e.Sheet, e.Row, e.Err)
+}
+
+// Unwrap returns the error of the row.
+func (e *PipelineError) Unwrap() error {
+	return e.Err
+}
+
+// Pipeline copies the sheets of a workbook read by a StreamFileReader to
+// a StreamFileBuilder, passing every row through a PipelineTransform,
+// such as to clean or redact the rows of files too large to be loaded.
+// Both ends stream, so the rows are processed in constant memory.
+//
+// The first row with cells the transform keeps in a sheet is the header
+// row of the sheet written, the empty rows before it being dropped.  It
+// gives the number of columns of the sheet: the rows after it may have
+// fewer cells, the others being left empty, but not more.  A sheet
+// without such a row is written empty.  The header row is written
+// as text, and the other rows with the types of their cells, see
+// StreamFile.WriteTyped.  The dates of a workbook using the 1904 date
+// system are moved to the 1900 system of the StreamFile before being
+// transformed.  The styles, the column widths and the other settings of
+// the sheets read aren't copied, those set on the builder being used.
+type Pipeline struct {
+	reader    *StreamFileReader
+	builder   *StreamFileBuilder
+	transform PipelineTransform
+	summary   StreamSummary
+}
+
+// pipelineSheet is a sheet a Pipeline writes, with the index of the
+// header row read from it, -1 if it has none, and the number of its
+// columns.
+type pipelineSheet struct {
+	name      string
+	headerRow int
+	columns   int
+}
+
+// NewPipeline returns a Pipeline copying the sheets of reader to
+// builder, which mustn't have sheets, through transform.
+func NewPipeline(reader *StreamFileReader, builder *StreamFileBuilder, transform PipelineTransform) *Pipeline {
+	return &Pipeline{reader: reader, builder: builder, transform: transform}
+}
+
+// Run copies the sheets, then closes the StreamFile built.  The output is
+// left unfinished if it fails.
+func (p *Pipeline) Run() error {
+	return p.RunContext(context.Background())
+}
+
+// RunContext copies the sheets like Run, unless the context is done
+// before it has finished, whose error is then returned.
+func (p *Pipeline) RunContext(ctx context.Context) error {
+	if len(p.builder.xlsxFile.Sheets) > 0 {
+		return fmt.Errorf("the builder of a Pipeline mustn't have sheets")
+	}
+	sheets := make([]pipelineSheet, len(p.reader.sheetNames))
+	for i := range sheets {
+		header, err := p.readHeader(i, &sheets[i])
+		if err != nil {
+			return err
+		}
+		if err = p.builder.AddSheet(sheets[i].name, header, nil); err != nil {
+			return err
+		}
+	}
+	sf, err := p.builder.Build()
+	if err != nil {
+		return err
+	}
+	for i := range sheets {
+		if i > 0 {
+			if err = sf.NextSheet(); err != nil {
+				return err
+			}
+		}
+		if err = p.copyRows(ctx, sf, i, sheets[i]); err != nil {
+			return err
+		}
+	}
+	if err = sf.CloseContext(ctx); err != nil {
+		return err
+	}
+	p.summary = sf.Summary()
+	return nil
+}
+
+// Summary returns the summary of the StreamFile written by Run.
+func (p *Pipeline) Summary() StreamSummary {
+	return p.summary
+}
+
+// readHeader reads the rows of the sheet at index up to the first one
+// with cells the transform keeps, setting up sheet, and returns its
+// values.
+func (p *Pipeline) readHeader(index int, sheet *pipelineSheet) ([]string, error) {
+	ssr, err := p.reader.Sheet(index)
+	if err != nil {
+		return nil, err
+	}
+	defer ssr.Close()
+	sheet.name = ssr.Name
+	sheet.headerRow = -1
+	for {
+		rowIndex := ssr.nextRow
+		cells, err := p.readRow(ssr)
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		cells, err = p.transform(ssr.Name, rowIndex, cells)
+		if err == SkipRow {
+			continue
+		}
+		if err != nil {
+			return nil, &PipelineError{Sheet: ssr.Name, Row: rowIndex, Err: err}
+		}
+		if len(cells) == 0 {
+			continue
+		}
+		sheet.headerRow = rowIndex
+		sheet.columns = len(cells)
+		header := make([]string, len(cells))
+		for i, cell := range cells {
+			header[i] = cell.Value
+		}
+		return header, nil
+	}
+}
+
+// copyRows writes the rows of the sheet at index after its header row to
+// the current sheet of sf.
+func (p *Pipeline) copyRows(ctx context.Context, sf *StreamFile, index int, sheet pipelineSheet) error {
+	if sheet.headerRow < 0 {
+		return nil
+	}
+	ssr, err := p.reader.Sheet(index)
+	if err != nil {
+		return err
+	}
+	defer ssr.Close()
+	for {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		rowIndex := ssr.nextRow
+		cells, err := p.readRow(ssr)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// The rows up to the header row have been transformed.
+		if rowIndex <= sheet.headerRow {
+			continue
+		}
+		cells, err = p.transform(sheet.name, rowIndex, cells)
+		if err == SkipRow {
+			continue
+		}
+		if err == nil {
+			err = p.writeRow(sf, sheet, cells)
+		}
+		if err != nil {
+			return &PipelineError{Sheet: sheet.name, Row: rowIndex, Err: err}
+		}
+	}
+}
+
+// readRow reads the next row of ssr, moving its dates to the 1900 date
+// system.
+func (p *Pipeline) readRow(ssr *StreamSheetReader) ([]StreamCell, error) {
+	cells, err := ssr.ReadTypedRow()
+	if err != nil || !ssr.date1904 {
+		return cells, err
+	}
+	for i, cell := range cells {
+		if cell.Type == CellTypeDate && cell.Formula == "" {
+			if serial, err := parseFloat(cell.Value); err == nil {
+				cells[i].Value = strconv.FormatFloat(serial+date1904Offset, 'f', -1, 64)
+			}
+		}
+	}
+	return cells, nil
+}
+
+// writeRow writes the cells of a row to the current sheet of sf, leaving
+// out the columns after them.
+func (p *Pipeline) writeRow(sf *StreamFile, sheet pipelineSheet, cells []StreamCell) error {
+	if len(cells) > sheet.columns {
+		return fmt.Errorf("%d cells, more than the %d columns of the header row", len(cells), sheet.columns)
+	}
+	if len(cells) == sheet.columns {
+		return sf.WriteTyped(cells)
+	}
+	sparse := make(map[int]StreamCell, len(cells))
+	for i, cell := range cells {
+		sparse[i] = cell
+	}
+	return sf.WriteSparse(sparse)
+}
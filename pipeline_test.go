@@ -0,0 +1,130 @@
+package xlsx
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type PipelineSuite struct{}
+
+var _ = Suite(&PipelineSuite{})
+
+// pipelineXLSX returns an XLSX file of a sheet of orders, with a header
+// row below an empty row, and of an empty sheet.
+func pipelineXLSX(c *C) []byte {
+	file := NewFile()
+	sheet, err := file.AddSheet("Orders")
+	c.Assert(err, IsNil)
+	sheet.AddRow()
+	header := sheet.AddRow()
+	for _, title := range []string{"Name", "Amount", "Date", "Double"} {
+		header.AddCell().Value = title
+	}
+	for i, name := range []string{"first", "drop", "last"} {
+		row := sheet.AddRow()
+		row.AddCell().Value = name
+		row.AddCell().SetFloat(float64(i) + 0.5)
+		row.AddCell().SetDate(time.Date(2024, 3, i+1, 0, 0, 0, 0, time.UTC))
+		row.AddCell().SetFormula("B" + string(rune('3'+i)) + "*2")
+	}
+	_, err = file.AddSheet("Empty")
+	c.Assert(err, IsNil)
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	return buffer.Bytes()
+}
+
+func (s *PipelineSuite) TestRun(c *C) {
+	data := pipelineXLSX(c)
+	reader, err := NewStreamFileReader(bytes.NewReader(data), int64(len(data)))
+	c.Assert(err, IsNil)
+	defer reader.Close()
+	var output bytes.Buffer
+	var rows []int
+	pipeline := NewPipeline(reader, NewStreamFileBuilder(&output), func(sheet string, rowIndex int, cells []StreamCell) ([]StreamCell, error) {
+		rows = append(rows, rowIndex)
+		if len(cells) > 0 && cells[0].Value == "drop" {
+			return nil, SkipRow
+		}
+		if len(cells) > 0 {
+			cells[0].Value = strings.ToUpper(cells[0].Value)
+		}
+		return cells, nil
+	})
+	c.Assert(pipeline.Run(), IsNil)
+	c.Assert(rows, DeepEquals, []int{0, 1, 2, 3, 4})
+	c.Assert(pipeline.Summary().Sheets, DeepEquals, []StreamSheetSummary{
+		{Name: "Orders", Rows: 3, Cells: 12},
+		{Name: "Empty"},
+	})
+
+	file, err := OpenBinary(output.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(file.Sheets, HasLen, 2)
+	sheet := file.Sheets[0]
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "NAME")
+	c.Assert(sheet.Cell(1, 0).Value, Equals, "FIRST")
+	c.Assert(sheet.Cell(1, 1).Type(), Equals, CellTypeNumeric)
+	c.Assert(sheet.Cell(1, 1).Value, Equals, "0.5")
+	c.Assert(sheet.Cell(2, 0).Value, Equals, "LAST")
+	c.Assert(sheet.Cell(2, 1).Value, Equals, "2.5")
+	c.Assert(sheet.Cell(2, 2).IsTime(), Equals, true)
+	date, err := sheet.Cell(2, 2).GetTime(false)
+	c.Assert(err, IsNil)
+	c.Assert(date, Equals, time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC))
+	c.Assert(sheet.Cell(2, 3).Formula(), Equals, "B5*2")
+	c.Assert(file.Sheets[1].MaxRow, Equals, 0)
+}
+
+func (s *PipelineSuite) TestRunErrors(c *C) {
+	data := pipelineXLSX(c)
+	reader, err := NewStreamFileReader(bytes.NewReader(data), int64(len(data)))
+	c.Assert(err, IsNil)
+	defer reader.Close()
+	var output bytes.Buffer
+	pipeline := NewPipeline(reader, NewStreamFileBuilder(&output), func(sheet string, rowIndex int, cells []StreamCell) ([]StreamCell, error) {
+		if rowIndex == 3 {
+			return append(cells, NewStringStreamCell("extra")), nil
+		}
+		return cells, nil
+	})
+	err = pipeline.Run()
+	c.Assert(err, ErrorMatches, "sheet Orders, row 3: 5 cells, more than the 4 columns of the header row")
+	pipelineErr, ok := err.(*PipelineError)
+	c.Assert(ok, Equals, true)
+	c.Assert(pipelineErr.Row, Equals, 3)
+
+	failure := errors.New("rejected")
+	pipeline = NewPipeline(reader, NewStreamFileBuilder(&output), func(sheet string, rowIndex int, cells []StreamCell) ([]StreamCell, error) {
+		return nil, failure
+	})
+	err = pipeline.Run()
+	pipelineErr, ok = err.(*PipelineError)
+	c.Assert(ok, Equals, true)
+	c.Assert(pipelineErr.Err, Equals, failure)
+}
+
+func (s *PipelineSuite) TestReadTypedRow(c *C) {
+	data := pipelineXLSX(c)
+	reader, err := NewStreamFileReader(bytes.NewReader(data), int64(len(data)))
+	c.Assert(err, IsNil)
+	defer reader.Close()
+	sheet, err := reader.Sheet(0)
+	c.Assert(err, IsNil)
+	defer sheet.Close()
+	for i := 0; i < 3; i++ {
+		_, err = sheet.ReadTypedRow()
+		c.Assert(err, IsNil)
+	}
+	cells, err := sheet.ReadTypedRow()
+	c.Assert(err, IsNil)
+	c.Assert(cells, HasLen, 4)
+	c.Assert(cells[0], Equals, StreamCell{Value: "drop"})
+	c.Assert(cells[1], Equals, StreamCell{Value: "1.5", Type: CellTypeNumeric})
+	c.Assert(cells[2].Type, Equals, CellTypeDate)
+	c.Assert(cells[3].Formula, Equals, "B4*2")
+}
@@ -0,0 +1,309 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A file read with WithPreserve keeps, besides its raw parts, what its
+// workbook and worksheets have that this package doesn't model, such
+// as the pivot caches of the workbook or the conditional formatting and
+// controls of the sheets, as the XML they were read from.  The elements
+// are written back where the schema puts them, with the namespaces
+// declared on the root elements they were read from, so that changing
+// a cell doesn't lose the pivot tables, charts or macros of the file.
+
+// worksheetElementOrder and workbookElementOrder are the children of
+// the root elements of worksheets and of the workbook, in the order of
+// the schema.
+var (
+	worksheetElementOrder = elementOrder("sheetPr", "dimension", "sheetViews", "sheetFormatPr", "cols", "sheetData",
+		"sheetCalcPr", "sheetProtection", "protectedRanges", "scenarios", "autoFilter", "sortState", "dataConsolidate",
+		"customSheetViews", "mergeCells", "phoneticPr", "conditionalFormatting", "dataValidations", "hyperlinks",
+		"printOptions", "pageMargins", "pageSetup", "headerFooter", "rowBreaks", "colBreaks", "customProperties",
+		"cellWatches", "ignoredErrors", "smartTags", "drawing", "legacyDrawing", "legacyDrawingHF", "drawingHF",
+		"picture", "oleObjects", "controls", "webPublishItems", "tableParts", "extLst")
+	workbookElementOrder = elementOrder("fileVersion", "fileSharing", "workbookPr", "workbookProtection", "bookViews",
+		"sheets", "functionGroups", "externalReferences", "definedNames", "calcPr", "oleSize", "customWorkbookViews",
+		"pivotCaches", "smartTagPr", "smartTagTypes", "webPublishing", "fileRecoveryPr", "webPublishObjects", "extLst")
+)
+
+// modelledWorksheetElements and modelledWorkbookElements are the
+// children of the root elements that this package reads and writes.
+var (
+	modelledWorksheetElements = modelledElements(reflect.TypeOf(xlsxWorksheet{}))
+	modelledWorkbookElements  = modelledElements(reflect.TypeOf(xlsxWorkbook{}))
+)
+
+// elementOrder maps each of names to its position.
+func elementOrder(names ...string) map[string]int {
+	order := make(map[string]int, len(names))
+	for i, name := range names {
+		order[name] = i
+	}
+	return order
+}
+
+// modelledElements returns the local names of the elements mapped by
+// the fields of the struct type t.
+func modelledElements(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("xml")
+		if t.Field(i).Name == "XMLName" || tag == "" || tag == "-" || strings.Contains(tag, ",attr") {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		names[name[strings.LastIndex(name, " ")+1:]] = true
+	}
+	return names
+}
+
+// preservedPart holds what a part read with WithPreserve has that this
+// package doesn't model.
+type preservedPart struct {
+	// rootAttrs holds the namespace declarations and the
+	// mc:Ignorable attribute of the root element.
+	rootAttrs []preservedAttr
+	elements  []preservedElement
+}
+
+// preservedAttr is an attribute of the root element of a part, whose
+// name has its prefix, such as xmlns:x14ac.
+type preservedAttr struct {
+	name  string
+	value string
+}
+
+// preservedElement is a child of the root element of a part, kept as
+// the XML it was read from, which goes after the elements of the part
+// whose position in the schema isn't after order.
+type preservedElement struct {
+	order int
+	xml   string
+}
+
+// readPreservedPart reads the part f, returning the children of its root element whose local names aren't among
+// modelled, positioned as order says, along with the namespace declarations of the root element. The elements whose
+// names aren't in order, such as mc:AlternateContent, keep their position after the element before them.
+func readPreservedPart(f *zip.File, modelled map[string]bool, order map[string]int) (*preservedPart, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return nil, err
+	}
+	part := &preservedPart{}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	depth, lastOrder := 0, -1
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			return part, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				for _, attr := range t.Attr {
+					if attr.Name.Space == "xmlns" || attr.Name.Space == "mc" && attr.Name.Local == "Ignorable" {
+						part.rootAttrs = append(part.rootAttrs, preservedAttr{name: attr.Name.Space + ":" + attr.Name.Local, value: attr.Value})
+					}
+				}
+			} else if depth == 1 {
+				if position, ok := order[t.Name.Local]; ok && t.Name.Space == "" {
+					lastOrder = position
+				}
+				if !modelled[t.Name.Local] || t.Name.Space != "" {
+					if err := skipRawElement(decoder); err != nil {
+						return nil, err
+					}
+					part.elements = append(part.elements, preservedElement{
+						order: lastOrder,
+						xml:   string(data[offset:decoder.InputOffset()]),
+					})
+					continue
+				}
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// skipRawElement reads the tokens of decoder up to the end of the
+// element just started.
+func skipRawElement(decoder *xml.Decoder) error {
+	for depth := 1; depth > 0; {
+		token, err := decoder.RawToken()
+		if err != nil {
+			return err
+		}
+		switch token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return nil
+}
+
+// insertPreservedElements returns data, the XML of a part written, with the elements and root attributes of part
+// added, the elements going before the first child of the root element coming after them in order, and the
+// relationship ids they refer to renamed as renamedIds says.
+func insertPreservedElements(data string, part *preservedPart, order map[string]int, renamedIds map[string]string) (string, error) {
+	if part == nil || len(part.elements) == 0 && len(part.rootAttrs) == 0 {
+		return data, nil
+	}
+	decoder := xml.NewDecoder(strings.NewReader(data))
+	var rootStart, rootEnd, closeStart int64 = -1, -1, -1
+	var childStarts []int64
+	var childOrders []int
+	depth, lastOrder := 0, -1
+	for closeStart < 0 {
+		offset := decoder.InputOffset()
+		token, err := decoder.RawToken()
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				rootStart, rootEnd = offset, decoder.InputOffset()
+			} else if depth == 1 {
+				if position, ok := order[t.Name.Local]; ok {
+					lastOrder = position
+				}
+				childStarts = append(childStarts, offset)
+				childOrders = append(childOrders, lastOrder)
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				closeStart = offset
+			}
+		}
+	}
+	var b bytes.Buffer
+	rootTag := data[rootStart:rootEnd]
+	b.WriteString(data[:rootStart])
+	b.WriteString(strings.TrimSuffix(rootTag, ">"))
+	for _, attr := range part.rootAttrs {
+		if !strings.Contains(rootTag, " "+attr.name+"=") {
+			b.WriteString(" " + attr.name + `="` + xmlAttrEscape(attr.value) + `"`)
+		}
+	}
+	b.WriteString(">")
+	elements := part.elements
+	writeElements := func(before int) {
+		for len(elements) > 0 && elements[0].order < before {
+			element := elements[0].xml
+			if len(renamedIds) > 0 {
+				element = relationshipIdPattern.ReplaceAllStringFunc(element, func(match string) string {
+					if renamed, ok := renamedIds[relationshipIdPattern.FindStringSubmatch(match)[1]]; ok {
+						return `r:id="` + renamed + `"`
+					}
+					return match
+				})
+			}
+			b.WriteString(element)
+			elements = elements[1:]
+		}
+	}
+	pos := rootEnd
+	for i, start := range childStarts {
+		b.WriteString(data[pos:start])
+		pos = start
+		writeElements(childOrders[i])
+	}
+	b.WriteString(data[pos:closeStart])
+	writeElements(len(order) + 1)
+	b.WriteString(data[closeStart:])
+	return b.String(), nil
+}
+
+// readPreservedPackageRelationships returns the relationships of the
+// package read from f, _rels/.rels, other than those to the parts it
+// always has, which are written again.
+func readPreservedPackageRelationships(f *zip.File) ([]xlsxWorkbookRelation, error) {
+	rels, err := readPackageRelationships(f)
+	if err != nil {
+		return nil, err
+	}
+	var preserved []xlsxWorkbookRelation
+	for _, rel := range rels {
+		switch rel.Type {
+		case relationshipTypeOfficeDocument, relationshipTypeCoreProperties, relationshipTypeExtendedProperties:
+		default:
+			preserved = append(preserved, rel)
+		}
+	}
+	return preserved, nil
+}
+
+// addPreservedPackageRelationships adds the relationships of the package read with WithPreserve to rels, the XML of
+// the relationships of the package written, unless they point at a part the File no longer has. The relationships
+// whose ids are taken are renamed.
+func (f *File) addPreservedPackageRelationships(rels string) string {
+	var added bytes.Buffer
+	n := 1
+	for _, rel := range f.keptRelationships(f.preservedPackageRels, "") {
+		for rel.Id == "" || strings.Contains(rels, `Id="`+rel.Id+`"`) || strings.Contains(added.String(), `Id="`+rel.Id+`"`) {
+			rel.Id = "rId" + strconv.Itoa(n)
+			n++
+		}
+		added.WriteString(`  <Relationship Id="` + xmlAttrEscape(rel.Id) + `" Type="` + xmlAttrEscape(rel.Type) +
+			`" Target="` + xmlAttrEscape(rel.Target) + `"`)
+		if rel.TargetMode != "" {
+			added.WriteString(` TargetMode="` + xmlAttrEscape(rel.TargetMode) + `"`)
+		}
+		added.WriteString("/>\n")
+	}
+	return strings.Replace(rels, "</Relationships>", added.String()+"</Relationships>", 1)
+}
+
+// xmlAttrEscape returns s escaped to be the value of an attribute.
+func xmlAttrEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// readWorkbookContentType returns the content type of the workbook
+// given by f, [Content_Types].xml, such as that of the workbooks with
+// macros, or "" if it has none.
+func readWorkbookContentType(f *zip.File) (string, error) {
+	if f == nil {
+		return "", nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	var types xlsxTypes
+	if err := xml.NewDecoder(rc).Decode(&types); err != nil {
+		return "", err
+	}
+	for _, override := range types.Overrides {
+		if override.PartName == "/xl/workbook.xml" {
+			return override.ContentType, nil
+		}
+	}
+	return "", nil
+}
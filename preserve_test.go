@@ -0,0 +1,100 @@
+package xlsx
+
+import (
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type PreserveSuite struct{}
+
+var _ = Suite(&PreserveSuite{})
+
+const (
+	preservedAlternateContent = `<mc:AlternateContent xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006"><mc:Choice Requires="x15"><x15ac:absPath xmlns:x15ac="http://schemas.microsoft.com/office/spreadsheetml/2010/11/ac" url="C:\reports\"/></mc:Choice></mc:AlternateContent>`
+	preservedPivotCaches      = `<pivotCaches><pivotCache cacheId="7" r:id="rId5"/></pivotCaches>`
+	preservedFormatting       = `<conditionalFormatting sqref="A1:A9"><cfRule type="cellIs" dxfId="0" priority="1" operator="greaterThan"><formula>10</formula></cfRule></conditionalFormatting>`
+	preservedRowBreaks        = `<rowBreaks count="1" manualBreakCount="1"><brk id="20" max="16383" man="1"/></rowBreaks>`
+	macroContentType          = "application/vnd.ms-excel.sheet.macroEnabled.main+xml"
+)
+
+// addPreservedParts adds to parts what WithPreserve keeps: elements of
+// the workbook and of the worksheet this package doesn't model, the
+// custom properties of the package, and the macros of the workbook.
+func addPreservedParts(parts map[string]string) {
+	parts["xl/workbook.xml"] = strings.Replace(parts["xl/workbook.xml"], `</workbookPr>`, `</workbookPr>`+preservedAlternateContent, 1)
+	parts["xl/workbook.xml"] = strings.Replace(parts["xl/workbook.xml"], `</workbook>`, preservedPivotCaches+`</workbook>`, 1)
+	parts["xl/_rels/workbook.xml.rels"] = strings.Replace(parts["xl/_rels/workbook.xml.rels"], "</Relationships>",
+		`<Relationship Id="rId5" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotCacheDefinition" Target="pivotCache/pivotCacheDefinition1.xml"/>`+
+			`<Relationship Id="rId6" Type="http://schemas.microsoft.com/office/2006/relationships/vbaProject" Target="vbaProject.bin"/></Relationships>`, 1)
+	parts["xl/pivotCache/pivotCacheDefinition1.xml"] = `<pivotCacheDefinition/>`
+	parts["xl/vbaProject.bin"] = "macros"
+	parts["[Content_Types].xml"] = strings.Replace(parts["[Content_Types].xml"],
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml", macroContentType, 1)
+	parts["xl/worksheets/sheet1.xml"] = strings.Replace(parts["xl/worksheets/sheet1.xml"], `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`,
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:x14ac="http://schemas.microsoft.com/office/spreadsheetml/2009/9/ac">`, 1)
+	parts["xl/worksheets/sheet1.xml"] = strings.Replace(parts["xl/worksheets/sheet1.xml"], `</sheetData>`, `</sheetData>`+preservedFormatting, 1)
+	parts["xl/worksheets/sheet1.xml"] = strings.Replace(parts["xl/worksheets/sheet1.xml"], `</headerFooter>`, `</headerFooter>`+preservedRowBreaks, 1)
+	parts["docProps/custom.xml"] = `<Properties/>`
+	parts["_rels/.rels"] = strings.Replace(parts["_rels/.rels"], "</Relationships>",
+		`<Relationship Id="rId3" Type="`+relationshipTypeCustomProperties+`" Target="docProps/custom.xml"/></Relationships>`, 1)
+}
+
+func (s *PreserveSuite) TestRoundTrip(c *C) {
+	data := unknownPartsXLSX(c, addPreservedParts)
+	file, err := OpenBinaryWithOptions(data, WithPreserve(true), WithUnknownParts(UnknownPartsListed))
+	c.Assert(err, IsNil)
+	c.Assert(file.SkippedParts(), IsNil)
+	file.Sheets[0].Cell(0, 0).Value = "changed"
+
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	_, contents := readOptionsParts(c, buffer.Bytes())
+	workbook := contents["xl/workbook.xml"]
+	c.Assert(strings.Contains(workbook, `</workbookPr>`+preservedAlternateContent+`<workbookProtection>`), Equals, true)
+	c.Assert(strings.Contains(workbook, `</calcPr>`+preservedPivotCaches+`</workbook>`), Equals, true)
+	sheet := contents["xl/worksheets/sheet1.xml"]
+	c.Assert(strings.Contains(sheet, ` xmlns:x14ac="http://schemas.microsoft.com/office/spreadsheetml/2009/9/ac">`), Equals, true)
+	c.Assert(strings.Contains(sheet, `</sheetData>`+preservedFormatting+`<printOptions`), Equals, true)
+	c.Assert(strings.Contains(sheet, `</headerFooter>`+preservedRowBreaks+`</worksheet>`), Equals, true)
+	c.Assert(strings.Contains(contents["_rels/.rels"], `Target="docProps/custom.xml"`), Equals, true)
+	c.Assert(strings.Contains(contents["[Content_Types].xml"], `<Override PartName="/xl/workbook.xml" ContentType="`+macroContentType+`">`), Equals, true)
+	c.Assert(contents["xl/vbaProject.bin"], Equals, "macros")
+
+	read, err := OpenBinaryWithOptions(buffer.Bytes(), WithPreserve(true))
+	c.Assert(err, IsNil)
+	c.Assert(read.Sheets[0].Cell(0, 0).Value, Equals, "changed")
+	buffer.Reset()
+	c.Assert(read.Write(&buffer), IsNil)
+	_, rewritten := readOptionsParts(c, buffer.Bytes())
+	c.Assert(rewritten["xl/workbook.xml"], Equals, workbook)
+	c.Assert(rewritten["xl/worksheets/sheet1.xml"], Equals, sheet)
+	c.Assert(rewritten["_rels/.rels"], Equals, contents["_rels/.rels"])
+}
+
+func (s *PreserveSuite) TestWithoutPreserve(c *C) {
+	file, err := OpenBinaryWithOptions(unknownPartsXLSX(c, addPreservedParts), WithUnknownParts(UnknownPartsListed))
+	c.Assert(err, IsNil)
+	c.Assert(file.SkippedParts(), DeepEquals, []SkippedPart{
+		{Name: "docProps/custom.xml", Reason: "the package relationships are written without it"},
+	})
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	_, contents := readOptionsParts(c, buffer.Bytes())
+	c.Assert(strings.Contains(contents["xl/workbook.xml"], "pivotCaches"), Equals, false)
+	c.Assert(strings.Contains(contents["xl/worksheets/sheet1.xml"], "conditionalFormatting"), Equals, false)
+	c.Assert(strings.Contains(contents["_rels/.rels"], "custom.xml"), Equals, false)
+	c.Assert(strings.Contains(contents["[Content_Types].xml"], macroContentType), Equals, false)
+}
+
+func (s *PreserveSuite) TestCompatible(c *C) {
+	file, err := OpenBinaryWithOptions(unknownPartsXLSX(c, addPreservedParts), WithPreserve(true))
+	c.Assert(err, IsNil)
+	file.Compatible = true
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	_, contents := readOptionsParts(c, buffer.Bytes())
+	c.Assert(strings.Contains(contents["xl/workbook.xml"], "pivotCaches"), Equals, false)
+	c.Assert(strings.Contains(contents["xl/worksheets/sheet1.xml"], "x14ac"), Equals, false)
+}
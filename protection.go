@@ -0,0 +1,421 @@
+package xlsx
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+)
+
+const (
+	// The hashing algorithm, salt length and spin count used by
+	// Excel when protecting a sheet with a password.
+	protectionAlgorithmSHA512  = "SHA-512"
+	protectionSaltLength       = 16
+	DefaultProtectionSpinCount = 100000
+)
+
+// SheetProtection describes the protection of a Sheet against
+// editing.  A Sheet is protected as soon as its Protection is not
+// nil, the password is optional.  The Allow* fields list the edits
+// that remain possible while the sheet is protected.
+type SheetProtection struct {
+	// AlgorithmName, HashValue, SaltValue and SpinCount hold a
+	// password hashed by SetPassword.
+	AlgorithmName string
+	HashValue     string
+	SaltValue     string
+	SpinCount     int
+	// LegacyPassword holds a password hashed by SetLegacyPassword,
+	// as a hexadecimal string.
+	LegacyPassword string
+
+	Objects                  bool
+	Scenarios                bool
+	AllowFormatCells         bool
+	AllowFormatColumns       bool
+	AllowFormatRows          bool
+	AllowInsertColumns       bool
+	AllowInsertRows          bool
+	AllowInsertHyperlinks    bool
+	AllowDeleteColumns       bool
+	AllowDeleteRows          bool
+	AllowSelectLockedCells   bool
+	AllowSort                bool
+	AllowAutoFilter          bool
+	AllowPivotTables         bool
+	AllowSelectUnlockedCells bool
+	// EditableRanges are the ranges of cells, such as "C2:D100",
+	// that can be edited without the password while the sheet is
+	// protected, such as the columns of a form to be filled in.
+	EditableRanges []string
+}
+
+// WorkbookProtection describes the protection of the structure and
+// windows of a File.  A File is protected as soon as its Protection is
+// not nil and locks its structure or windows, the password is
+// optional.
+type WorkbookProtection struct {
+	// AlgorithmName, HashValue, SaltValue and SpinCount hold a
+	// password hashed by SetPassword.
+	AlgorithmName string
+	HashValue     string
+	SaltValue     string
+	SpinCount     int
+	// LegacyPassword holds a password hashed by SetLegacyPassword,
+	// as a hexadecimal string.
+	LegacyPassword string
+
+	// LockStructure stops sheets from being added, removed,
+	// renamed, moved, hidden or shown.
+	LockStructure bool
+	// LockWindows stops the windows of the workbook from being
+	// moved, resized or closed.
+	LockWindows bool
+}
+
+// NewSheetProtection creates a SheetProtection without a password
+// that, like Excel's default, only allows cells to be selected.
+func NewSheetProtection() *SheetProtection {
+	return &SheetProtection{
+		AllowSelectLockedCells:   true,
+		AllowSelectUnlockedCells: true,
+	}
+}
+
+// Protect protects the Sheet with a password, hashed with SHA-512 as
+// done by current versions of Excel.  An empty password protects the
+// Sheet without requiring a password to unprotect it.
+func (s *Sheet) Protect(password string) error {
+	protection := NewSheetProtection()
+	if password != "" {
+		if err := protection.SetPassword(password); err != nil {
+			return err
+		}
+	}
+	s.Protection = protection
+	return nil
+}
+
+// Unprotect removes any protection from the Sheet.
+func (s *Sheet) Unprotect() {
+	s.Protection = nil
+}
+
+// SetPassword hashes password with SHA-512, a random salt and
+// DefaultProtectionSpinCount iterations, replacing any previous
+// password.
+func (sp *SheetProtection) SetPassword(password string) error {
+	saltValue, hashValue, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	sp.AlgorithmName = protectionAlgorithmSHA512
+	sp.SaltValue = saltValue
+	sp.SpinCount = DefaultProtectionSpinCount
+	sp.HashValue = hashValue
+	sp.LegacyPassword = ""
+	return nil
+}
+
+// SetLegacyPassword hashes password with the 16-bit algorithm used by
+// Excel 2007 and older, replacing any previous password.  This hash is
+// trivially broken and should only be used where old readers must be
+// supported.
+func (sp *SheetProtection) SetLegacyPassword(password string) {
+	sp.AlgorithmName = ""
+	sp.HashValue = ""
+	sp.SaltValue = ""
+	sp.SpinCount = 0
+	sp.LegacyPassword = hashPasswordLegacy(password)
+}
+
+// HasPassword returns true if a password is needed to lift the
+// protection.
+func (sp *SheetProtection) HasPassword() bool {
+	return sp.HashValue != "" || sp.LegacyPassword != ""
+}
+
+// CheckPassword returns true if password lifts the protection.  An
+// error is returned if the protection was hashed with an algorithm
+// other than SHA-512, or if the stored hash is malformed.
+func (sp *SheetProtection) CheckPassword(password string) (bool, error) {
+	if sp.HashValue != "" && sp.AlgorithmName != protectionAlgorithmSHA512 {
+		return false, fmt.Errorf("unsupported sheet protection algorithm '%s'", sp.AlgorithmName)
+	}
+	return checkPassword(password, sp.SaltValue, sp.HashValue, sp.SpinCount, sp.LegacyPassword)
+}
+
+// Protect locks the structure of the File, with a password hashed with
+// SHA-512 as done by current versions of Excel, so that its sheets
+// can't be added, removed, renamed or shown.  An empty password locks
+// the structure without requiring a password to unlock it.
+func (f *File) Protect(password string) error {
+	protection := &WorkbookProtection{LockStructure: true}
+	if password != "" {
+		if err := protection.SetPassword(password); err != nil {
+			return err
+		}
+	}
+	f.Protection = protection
+	return nil
+}
+
+// Unprotect removes any protection from the File.
+func (f *File) Unprotect() {
+	f.Protection = nil
+}
+
+// SetPassword hashes password with SHA-512, a random salt and
+// DefaultProtectionSpinCount iterations, replacing any previous
+// password.
+func (wp *WorkbookProtection) SetPassword(password string) error {
+	saltValue, hashValue, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	wp.AlgorithmName = protectionAlgorithmSHA512
+	wp.SaltValue = saltValue
+	wp.SpinCount = DefaultProtectionSpinCount
+	wp.HashValue = hashValue
+	wp.LegacyPassword = ""
+	return nil
+}
+
+// SetLegacyPassword hashes password with the 16-bit algorithm used by
+// Excel 2007 and older, replacing any previous password.  This hash is
+// trivially broken and should only be used where old readers must be
+// supported.
+func (wp *WorkbookProtection) SetLegacyPassword(password string) {
+	wp.AlgorithmName = ""
+	wp.HashValue = ""
+	wp.SaltValue = ""
+	wp.SpinCount = 0
+	wp.LegacyPassword = hashPasswordLegacy(password)
+}
+
+// HasPassword returns true if a password is needed to lift the
+// protection.
+func (wp *WorkbookProtection) HasPassword() bool {
+	return wp.HashValue != "" || wp.LegacyPassword != ""
+}
+
+// CheckPassword returns true if password lifts the protection.  An
+// error is returned if the protection was hashed with an algorithm
+// other than SHA-512, or if the stored hash is malformed.
+func (wp *WorkbookProtection) CheckPassword(password string) (bool, error) {
+	if wp.HashValue != "" && wp.AlgorithmName != protectionAlgorithmSHA512 {
+		return false, fmt.Errorf("unsupported workbook protection algorithm '%s'", wp.AlgorithmName)
+	}
+	return checkPassword(password, wp.SaltValue, wp.HashValue, wp.SpinCount, wp.LegacyPassword)
+}
+
+// hashPassword hashes password with SHA-512, a random salt and
+// DefaultProtectionSpinCount iterations, returning the salt and the
+// hash encoded in base 64.
+func hashPassword(password string) (string, string, error) {
+	salt := make([]byte, protectionSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", "", err
+	}
+	hash := hashPasswordSHA512(password, salt, DefaultProtectionSpinCount)
+	return base64.StdEncoding.EncodeToString(salt), base64.StdEncoding.EncodeToString(hash), nil
+}
+
+// checkPassword returns true if password matches the SHA-512 hash,
+// given with its salt in base 64, or failing that the legacy hash, or
+// if there is no hash at all.
+func checkPassword(password, saltValue, hashValue string, spinCount int, legacyPassword string) (bool, error) {
+	if hashValue != "" {
+		salt, err := base64.StdEncoding.DecodeString(saltValue)
+		if err != nil {
+			return false, err
+		}
+		hash, err := base64.StdEncoding.DecodeString(hashValue)
+		if err != nil {
+			return false, err
+		}
+		return bytes.Equal(hashPasswordSHA512(password, salt, spinCount), hash), nil
+	}
+	if legacyPassword != "" {
+		return hashPasswordLegacy(password) == legacyPassword, nil
+	}
+	return true, nil
+}
+
+// hashPasswordSHA512 implements the password hash from ECMA-376 Part
+// 1, 18.2.28: the salt followed by the UTF-16LE password is hashed,
+// then the hash is repeatedly rehashed together with the little
+// endian iteration number.
+func hashPasswordSHA512(password string, salt []byte, spinCount int) []byte {
+	encoded := utf16.Encode([]rune(password))
+	input := make([]byte, 0, len(salt)+2*len(encoded))
+	input = append(input, salt...)
+	for _, u := range encoded {
+		input = append(input, byte(u), byte(u>>8))
+	}
+	hash := sha512.Sum512(input)
+	iteration := make([]byte, 4)
+	for i := 0; i < spinCount; i++ {
+		binary.LittleEndian.PutUint32(iteration, uint32(i))
+		hash = sha512.Sum512(append(hash[:], iteration...))
+	}
+	return hash[:]
+}
+
+// hashPasswordLegacy implements the 16-bit password verifier from
+// ECMA-376 Part 4, 3.3.1.81.  The characters are taken from the last
+// one, each rotating the 15 bits of the hash left by one bit before it
+// is added, so that passwords of any length are hashed as Excel does.
+func hashPasswordLegacy(password string) string {
+	var hash uint16
+	rotate := func() {
+		hash = ((hash >> 14) & 1) | ((hash << 1) & 0x7fff)
+	}
+	for i := len(password) - 1; i >= 0; i-- {
+		rotate()
+		hash ^= uint16(password[i])
+	}
+	rotate()
+	hash ^= uint16(len(password))
+	hash ^= 0xCE4B
+	return fmt.Sprintf("%04X", hash)
+}
+
+// makeXLSXSheetProtection converts a SheetProtection into its XML
+// representation, intended for internal use only
+func (sp *SheetProtection) makeXLSXSheetProtection() *xlsxSheetProtection {
+	allow := func(allowed bool) *bool {
+		// These attributes default to true, so they only need
+		// to be written when an edit is allowed.
+		if !allowed {
+			return nil
+		}
+		return new(bool)
+	}
+	return &xlsxSheetProtection{
+		Password:            sp.LegacyPassword,
+		AlgorithmName:       sp.AlgorithmName,
+		HashValue:           sp.HashValue,
+		SaltValue:           sp.SaltValue,
+		SpinCount:           sp.SpinCount,
+		Sheet:               true,
+		Objects:             sp.Objects,
+		Scenarios:           sp.Scenarios,
+		FormatCells:         allow(sp.AllowFormatCells),
+		FormatColumns:       allow(sp.AllowFormatColumns),
+		FormatRows:          allow(sp.AllowFormatRows),
+		InsertColumns:       allow(sp.AllowInsertColumns),
+		InsertRows:          allow(sp.AllowInsertRows),
+		InsertHyperlinks:    allow(sp.AllowInsertHyperlinks),
+		DeleteColumns:       allow(sp.AllowDeleteColumns),
+		DeleteRows:          allow(sp.AllowDeleteRows),
+		SelectLockedCells:   !sp.AllowSelectLockedCells,
+		Sort:                allow(sp.AllowSort),
+		AutoFilter:          allow(sp.AllowAutoFilter),
+		PivotTables:         allow(sp.AllowPivotTables),
+		SelectUnlockedCells: !sp.AllowSelectUnlockedCells,
+	}
+}
+
+// makeXLSXProtectedRanges converts the EditableRanges of a
+// SheetProtection into their XML representation, intended for internal
+// use only
+func (sp *SheetProtection) makeXLSXProtectedRanges() *xlsxProtectedRanges {
+	if len(sp.EditableRanges) == 0 {
+		return nil
+	}
+	xRanges := &xlsxProtectedRanges{}
+	for i, ref := range sp.EditableRanges {
+		xRanges.ProtectedRange = append(xRanges.ProtectedRange, xlsxProtectedRange{
+			Name:  fmt.Sprintf("Editable%d", i+1),
+			Sqref: ref,
+		})
+	}
+	return xRanges
+}
+
+// readSheetProtection converts the XML representation of a sheet's
+// protection and of the ranges that can be edited without the
+// password into a SheetProtection.  A nil result is returned when the
+// sheet is not protected.  The protected ranges requiring a password
+// of their own are left out.
+func readSheetProtection(xProtection *xlsxSheetProtection, xRanges *xlsxProtectedRanges) *SheetProtection {
+	if xProtection == nil || !xProtection.Sheet {
+		return nil
+	}
+	allowed := func(denied *bool) bool {
+		return denied != nil && !*denied
+	}
+	var editableRanges []string
+	if xRanges != nil {
+		for _, xRange := range xRanges.ProtectedRange {
+			if xRange.Password == "" && xRange.HashValue == "" {
+				editableRanges = append(editableRanges, xRange.Sqref)
+			}
+		}
+	}
+	return &SheetProtection{
+		AlgorithmName:            xProtection.AlgorithmName,
+		HashValue:                xProtection.HashValue,
+		SaltValue:                xProtection.SaltValue,
+		SpinCount:                xProtection.SpinCount,
+		LegacyPassword:           xProtection.Password,
+		Objects:                  xProtection.Objects,
+		Scenarios:                xProtection.Scenarios,
+		AllowFormatCells:         allowed(xProtection.FormatCells),
+		AllowFormatColumns:       allowed(xProtection.FormatColumns),
+		AllowFormatRows:          allowed(xProtection.FormatRows),
+		AllowInsertColumns:       allowed(xProtection.InsertColumns),
+		AllowInsertRows:          allowed(xProtection.InsertRows),
+		AllowInsertHyperlinks:    allowed(xProtection.InsertHyperlinks),
+		AllowDeleteColumns:       allowed(xProtection.DeleteColumns),
+		AllowDeleteRows:          allowed(xProtection.DeleteRows),
+		AllowSelectLockedCells:   !xProtection.SelectLockedCells,
+		AllowSort:                allowed(xProtection.Sort),
+		AllowAutoFilter:          allowed(xProtection.AutoFilter),
+		AllowPivotTables:         allowed(xProtection.PivotTables),
+		AllowSelectUnlockedCells: !xProtection.SelectUnlockedCells,
+		EditableRanges:           editableRanges,
+	}
+}
+
+// makeXLSXWorkbookProtection converts a WorkbookProtection into its
+// XML representation, intended for internal use only.  A nil
+// WorkbookProtection gives an empty element.
+func (wp *WorkbookProtection) makeXLSXWorkbookProtection() xlsxWorkbookProtection {
+	if wp == nil {
+		return xlsxWorkbookProtection{}
+	}
+	return xlsxWorkbookProtection{
+		WorkbookPassword:      wp.LegacyPassword,
+		LockStructure:         wp.LockStructure,
+		LockWindows:           wp.LockWindows,
+		WorkbookAlgorithmName: wp.AlgorithmName,
+		WorkbookHashValue:     wp.HashValue,
+		WorkbookSaltValue:     wp.SaltValue,
+		WorkbookSpinCount:     wp.SpinCount,
+	}
+}
+
+// readWorkbookProtection converts the XML representation of the
+// protection of a workbook into a WorkbookProtection.  A nil result is
+// returned when neither the structure nor the windows of the workbook
+// are locked.
+func readWorkbookProtection(xProtection xlsxWorkbookProtection) *WorkbookProtection {
+	if !xProtection.LockStructure && !xProtection.LockWindows {
+		return nil
+	}
+	return &WorkbookProtection{
+		AlgorithmName:  xProtection.WorkbookAlgorithmName,
+		HashValue:      xProtection.WorkbookHashValue,
+		SaltValue:      xProtection.WorkbookSaltValue,
+		SpinCount:      xProtection.WorkbookSpinCount,
+		LegacyPassword: xProtection.WorkbookPassword,
+		LockStructure:  xProtection.LockStructure,
+		LockWindows:    xProtection.LockWindows,
+	}
+}
@@ -0,0 +1,150 @@
+package xlsx
+
+import (
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type ProtectionSuite struct{}
+
+var _ = Suite(&ProtectionSuite{})
+
+func (s *ProtectionSuite) TestHashPasswordLegacy(c *C) {
+	// Known values, as written by Excel.
+	c.Assert(hashPasswordLegacy("password"), Equals, "83AF")
+	c.Assert(hashPasswordLegacy(""), Equals, "CE4B")
+	c.Assert(hashPasswordLegacy("test"), Equals, "CBEB")
+	// Past 15 characters the bits of the first ones wrap around, the
+	// values being those of the verifier of MS-OFFCRYPTO 2.3.7.1.
+	c.Assert(hashPasswordLegacy("The quick brown fox jumps over the lazy dog"), Equals, "CF84")
+	c.Assert(hashPasswordLegacy("abcdefghijklmnopqrstuvwxyz0123456789"), Equals, "F3CA")
+}
+
+func (s *ProtectionSuite) TestCheckPassword(c *C) {
+	protection := NewSheetProtection()
+	ok, err := protection.CheckPassword("anything")
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+
+	c.Assert(protection.SetPassword("secret"), IsNil)
+	c.Assert(protection.HasPassword(), Equals, true)
+	c.Assert(protection.AlgorithmName, Equals, "SHA-512")
+	c.Assert(protection.SpinCount, Equals, DefaultProtectionSpinCount)
+	ok, err = protection.CheckPassword("secret")
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+	ok, err = protection.CheckPassword("Secret")
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, false)
+
+	protection.SetLegacyPassword("secret")
+	c.Assert(protection.HashValue, Equals, "")
+	ok, err = protection.CheckPassword("secret")
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+
+	protection.LegacyPassword = ""
+	protection.AlgorithmName = "MD5"
+	protection.HashValue = "abc"
+	_, err = protection.CheckPassword("secret")
+	c.Assert(err, NotNil)
+}
+
+func (s *ProtectionSuite) TestMarshalSheetProtection(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetString("locked")
+	sheet.Protection = NewSheetProtection()
+	sheet.Protection.SetLegacyPassword("password")
+	sheet.Protection.AllowFormatColumns = true
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"],
+		`</sheetData><sheetProtection password="83AF" sheet="true" formatColumns="false"></sheetProtection>`), Equals, true)
+}
+
+func (s *ProtectionSuite) TestSheetProtectionRoundTrip(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetString("locked")
+	c.Assert(sheet.Protect("secret"), IsNil)
+	sheet.Protection.AllowSort = true
+	sheet.Protection.AllowSelectLockedCells = false
+	_, err = file.AddSheet("Sheet2")
+	c.Assert(err, IsNil)
+
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	file, err = OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+
+	c.Assert(file.Sheet["Sheet2"].Protection, IsNil)
+	protection := file.Sheet["Sheet1"].Protection
+	c.Assert(protection, NotNil)
+	c.Assert(protection.AllowSort, Equals, true)
+	c.Assert(protection.AllowFormatCells, Equals, false)
+	c.Assert(protection.AllowSelectLockedCells, Equals, false)
+	c.Assert(protection.AllowSelectUnlockedCells, Equals, true)
+	ok, err := protection.CheckPassword("secret")
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+}
+
+func (s *ProtectionSuite) TestEditableRangesRoundTrip(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetString("locked")
+	c.Assert(sheet.Protect(""), IsNil)
+	sheet.Protection.EditableRanges = []string{"B2:B100", "D2"}
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `</sheetProtection><protectedRanges>`+
+		`<protectedRange name="Editable1" sqref="B2:B100"></protectedRange>`+
+		`<protectedRange name="Editable2" sqref="D2"></protectedRange></protectedRanges>`), Equals, true)
+
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	file, err = OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(file.Sheet["Sheet1"].Protection.EditableRanges, DeepEquals, []string{"B2:B100", "D2"})
+}
+
+func (s *ProtectionSuite) TestWorkbookProtectionRoundTrip(c *C) {
+	file := NewFile()
+	_, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/workbook.xml"], `<workbookProtection></workbookProtection>`), Equals, true)
+
+	c.Assert(file.Protect("secret"), IsNil)
+	file.Protection.LockWindows = true
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	file, err = OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	protection := file.Protection
+	c.Assert(protection, NotNil)
+	c.Assert(protection.LockStructure, Equals, true)
+	c.Assert(protection.LockWindows, Equals, true)
+	c.Assert(protection.HasPassword(), Equals, true)
+	ok, err := protection.CheckPassword("secret")
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+	ok, err = protection.CheckPassword("other")
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, false)
+
+	protection.SetLegacyPassword("password")
+	parts, err = file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/workbook.xml"],
+		`<workbookProtection workbookPassword="83AF" lockStructure="true" lockWindows="true"></workbookProtection>`), Equals, true)
+}
@@ -0,0 +1,342 @@
+package xlsx
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ProtoRowCodec converts the protobuf messages of a type to the rows of
+// a sheet and back, so that services can export and import datasets of
+// messages without mapping each message type by hand.  It reads the
+// protobuf tags of the structs generated by protoc-gen-go, which spares
+// the package a dependency on the protobuf module.
+//
+// The columns are the scalar fields of the message, in the order of
+// their field numbers, named after their names in the .proto file.  The
+// fields of nested messages are flattened into columns named
+// "parent.child", an empty nested message reading back as nil.  Enums
+// are written as their numbers, bytes in base64, and proto3 optional
+// fields are left empty when unset.  Repeated, map and oneof fields
+// have no column.
+type ProtoRowCodec struct {
+	messageType reflect.Type
+	fields      []protoField
+}
+
+// protoField is a column of a ProtoRowCodec: a scalar field of the
+// message, reached through the fields at index, which go through the
+// pointers to nested messages.
+type protoField struct {
+	name  string
+	index []int
+	kind  reflect.Kind
+	// pointer is true for proto3 optional fields, and bytes for bytes
+	// fields.
+	pointer bool
+	bytes   bool
+}
+
+// errNotProtoMessage is returned when the message given to a
+// ProtoRowCodec isn't a pointer to a generated message struct.
+var errNotProtoMessage = errors.New("the message must be a pointer to a struct generated by protoc-gen-go")
+
+// NewProtoRowCodec creates a ProtoRowCodec for the messages of the type
+// of message, a pointer to a generated message struct such as
+// &pb.Order{}.
+func NewProtoRowCodec(message interface{}) (*ProtoRowCodec, error) {
+	t := reflect.TypeOf(message)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil, errNotProtoMessage
+	}
+	pc := &ProtoRowCodec{messageType: t.Elem()}
+	if err := pc.addFields(t.Elem(), "", nil, map[reflect.Type]bool{}); err != nil {
+		return nil, err
+	}
+	if len(pc.fields) == 0 {
+		return nil, fmt.Errorf("%s has no scalar protobuf fields", t.Elem())
+	}
+	return pc, nil
+}
+
+// addFields adds the columns of the fields of the message struct t,
+// whose names are prefixed by prefix and which are reached through the
+// fields at index.  seen holds the messages being flattened, which
+// can't be nested in themselves.
+func (pc *ProtoRowCodec) addFields(t reflect.Type, prefix string, index []int, seen map[reflect.Type]bool) error {
+	if seen[t] {
+		return fmt.Errorf("%s is nested in itself, and can't be flattened into columns", t)
+	}
+	seen[t] = true
+	defer delete(seen, t)
+	type numberedField struct {
+		number int
+		name   string
+		index  int
+	}
+	var numbered []numberedField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("protobuf")
+		if tag == "" || field.PkgPath != "" {
+			continue
+		}
+		number, name, ok := parseProtobufTag(tag)
+		if !ok {
+			return fmt.Errorf("invalid protobuf tag %q on %s.%s", tag, t, field.Name)
+		}
+		numbered = append(numbered, numberedField{number, name, i})
+	}
+	sort.Slice(numbered, func(i, j int) bool { return numbered[i].number < numbered[j].number })
+	for _, nf := range numbered {
+		field := t.Field(nf.index)
+		fieldIndex := append(append([]int(nil), index...), nf.index)
+		name := prefix + nf.name
+		ft := field.Type
+		switch {
+		case ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Uint8:
+			pc.fields = append(pc.fields, protoField{name: name, index: fieldIndex, kind: reflect.Slice, bytes: true})
+		case ft.Kind() == reflect.Slice || ft.Kind() == reflect.Map:
+			// Repeated and map fields have no column.
+		case ft.Kind() == reflect.Ptr && ft.Elem().Kind() == reflect.Struct:
+			if err := pc.addFields(ft.Elem(), name+".", fieldIndex, seen); err != nil {
+				return err
+			}
+		case ft.Kind() == reflect.Ptr && isProtoScalar(ft.Elem().Kind()):
+			pc.fields = append(pc.fields, protoField{name: name, index: fieldIndex, kind: ft.Elem().Kind(), pointer: true})
+		case isProtoScalar(ft.Kind()):
+			pc.fields = append(pc.fields, protoField{name: name, index: fieldIndex, kind: ft.Kind()})
+		}
+	}
+	return nil
+}
+
+// parseProtobufTag returns the field number and name given by the
+// protobuf tag of a generated field, such as
+// "varint,1,opt,name=id,proto3".
+func parseProtobufTag(tag string) (int, string, bool) {
+	parts := strings.Split(tag, ",")
+	if len(parts) < 2 {
+		return 0, "", false
+	}
+	number, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, "", false
+	}
+	for _, part := range parts[2:] {
+		if strings.HasPrefix(part, "name=") {
+			return number, part[len("name="):], true
+		}
+	}
+	return 0, "", false
+}
+
+// isProtoScalar returns true for the kinds of the Go types of protobuf
+// scalars and enums.
+func isProtoScalar(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.String, reflect.Bool, reflect.Int32, reflect.Int64, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// Headers returns the names of the columns, for the header row of the
+// sheet.
+func (pc *ProtoRowCodec) Headers() []string {
+	headers := make([]string, len(pc.fields))
+	for i, field := range pc.fields {
+		headers[i] = field.name
+	}
+	return headers
+}
+
+// ColumnTypes returns the types of the columns, for
+// StreamFileBuilder.AddSheetWithTypes, so that numbers and booleans are
+// written as such.  64 bit integers are kept as text, as spreadsheets
+// would round those past 2^53.
+func (pc *ProtoRowCodec) ColumnTypes() []ColumnType {
+	types := make([]ColumnType, len(pc.fields))
+	for i, field := range pc.fields {
+		switch field.kind {
+		case reflect.Int32, reflect.Uint32:
+			types[i] = ColumnTypeInt
+		case reflect.Float32, reflect.Float64:
+			types[i] = ColumnTypeFloat
+		case reflect.Bool:
+			types[i] = ColumnTypeBool
+		default:
+			types[i] = ColumnTypeString
+		}
+	}
+	return types
+}
+
+// Row returns the cells of the row holding message, a pointer to a
+// message of the type of the codec.
+func (pc *ProtoRowCodec) Row(message interface{}) ([]string, error) {
+	v, err := pc.messageValue(message)
+	if err != nil {
+		return nil, err
+	}
+	cells := make([]string, len(pc.fields))
+	for i, field := range pc.fields {
+		fv, ok := fieldByIndex(v, field.index)
+		if !ok {
+			continue
+		}
+		if field.pointer {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		cells[i] = formatProtoValue(fv, field)
+	}
+	return cells, nil
+}
+
+// Message returns a new message, as a pointer to its struct, holding the
+// cells of a row written by Row.
+func (pc *ProtoRowCodec) Message(cells []string) (interface{}, error) {
+	message := reflect.New(pc.messageType).Interface()
+	if err := pc.ReadRow(cells, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// ReadRow sets the fields of message, a pointer to a message of the type
+// of the codec, to the cells of a row written by Row.  Empty cells leave
+// their fields unset, and nested messages without any cell set stay
+// nil.
+func (pc *ProtoRowCodec) ReadRow(cells []string, message interface{}) error {
+	v, err := pc.messageValue(message)
+	if err != nil {
+		return err
+	}
+	if len(cells) != len(pc.fields) {
+		return fmt.Errorf("the row has %d cells instead of %d", len(cells), len(pc.fields))
+	}
+	for i, field := range pc.fields {
+		if cells[i] == "" {
+			continue
+		}
+		fv := allocFieldByIndex(v, field.index)
+		if field.pointer {
+			fv.Set(reflect.New(fv.Type().Elem()))
+			fv = fv.Elem()
+		}
+		if err := parseProtoValue(fv, field, cells[i]); err != nil {
+			return fmt.Errorf("column %s: %v", field.name, err)
+		}
+	}
+	return nil
+}
+
+// messageValue returns the struct that message points to, which must be
+// of the type of the codec.
+func (pc *ProtoRowCodec) messageValue(message interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(message)
+	if !v.IsValid() || v.Kind() != reflect.Ptr || v.IsNil() {
+		return reflect.Value{}, errNotProtoMessage
+	}
+	if v.Elem().Type() != pc.messageType {
+		return reflect.Value{}, fmt.Errorf("the message is a %s, not a %s", v.Elem().Type(), pc.messageType)
+	}
+	return v.Elem(), nil
+}
+
+// fieldByIndex returns the field of v at index, or false if one of the
+// nested messages on the way is nil.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, fieldIndex := range index {
+		if i > 0 {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(fieldIndex)
+	}
+	return v, true
+}
+
+// allocFieldByIndex returns the field of v at index, making the nested
+// messages on the way that are nil.
+func allocFieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, fieldIndex := range index {
+		if i > 0 {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(fieldIndex)
+	}
+	return v
+}
+
+// formatProtoValue returns the text of the value v of field.
+func formatProtoValue(v reflect.Value, field protoField) string {
+	switch {
+	case field.bytes:
+		return base64.StdEncoding.EncodeToString(v.Bytes())
+	case field.kind == reflect.String:
+		return v.String()
+	case field.kind == reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case field.kind == reflect.Int32 || field.kind == reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case field.kind == reflect.Uint32 || field.kind == reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case field.kind == reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32)
+	}
+	return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+}
+
+// parseProtoValue sets v, the value of field, to the value of text.
+// Booleans may also be "1" or "0", as they are read from a sheet.
+func parseProtoValue(v reflect.Value, field protoField, text string) error {
+	switch {
+	case field.bytes:
+		b, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return fmt.Errorf("%q isn't base64", text)
+		}
+		v.SetBytes(b)
+	case field.kind == reflect.String:
+		v.SetString(text)
+	case field.kind == reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("%q isn't a boolean", text)
+		}
+		v.SetBool(b)
+	case field.kind == reflect.Int32 || field.kind == reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q isn't a %d bit integer", text, v.Type().Bits())
+		}
+		v.SetInt(n)
+	case field.kind == reflect.Uint32 || field.kind == reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q isn't a %d bit unsigned integer", text, v.Type().Bits())
+		}
+		v.SetUint(n)
+	default:
+		f, err := strconv.ParseFloat(text, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q isn't a number", text)
+		}
+		v.SetFloat(f)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package xlsx
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type ProtoRowsSuite struct{}
+
+var _ = Suite(&ProtoRowsSuite{})
+
+// testStatus, testAddress and testOrder look like the types that
+// protoc-gen-go generates.
+type testStatus int32
+
+type testAddress struct {
+	state         struct{}
+	City          string `protobuf:"bytes,1,opt,name=city,proto3" json:"city,omitempty"`
+	Zip           string `protobuf:"bytes,2,opt,name=zip,proto3" json:"zip,omitempty"`
+	unknownFields []byte
+}
+
+type testOrder struct {
+	state     struct{}
+	sizeCache int32
+	Note      *string      `protobuf:"bytes,7,opt,name=note,proto3,oneof" json:"note,omitempty"`
+	Id        int64        `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Item      string       `protobuf:"bytes,2,opt,name=item,proto3" json:"item,omitempty"`
+	Quantity  int32        `protobuf:"varint,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	Price     float64      `protobuf:"fixed64,4,opt,name=price,proto3" json:"price,omitempty"`
+	Shipped   bool         `protobuf:"varint,5,opt,name=shipped,proto3" json:"shipped,omitempty"`
+	Status    testStatus   `protobuf:"varint,6,opt,name=status,proto3,enum=shop.Status" json:"status,omitempty"`
+	Tags      []string     `protobuf:"bytes,8,rep,name=tags,proto3" json:"tags,omitempty"`
+	Address   *testAddress `protobuf:"bytes,9,opt,name=address,proto3" json:"address,omitempty"`
+	Token     []byte       `protobuf:"bytes,10,opt,name=token,proto3" json:"token,omitempty"`
+}
+
+func (s *ProtoRowsSuite) TestProtoRowCodec(c *C) {
+	_, err := NewProtoRowCodec(testOrder{})
+	c.Assert(err, Equals, errNotProtoMessage)
+	codec, err := NewProtoRowCodec(&testOrder{})
+	c.Assert(err, IsNil)
+	c.Assert(codec.Headers(), DeepEquals, []string{"id", "item", "quantity", "price", "shipped", "status", "note", "address.city", "address.zip", "token"})
+	c.Assert(codec.ColumnTypes(), DeepEquals, []ColumnType{
+		ColumnTypeString, ColumnTypeString, ColumnTypeInt, ColumnTypeFloat, ColumnTypeBool,
+		ColumnTypeInt, ColumnTypeString, ColumnTypeString, ColumnTypeString, ColumnTypeString,
+	})
+
+	note := "fragile"
+	orders := []*testOrder{
+		{Id: 9007199254740993, Item: "Pens", Quantity: 3, Price: 2.5, Shipped: true, Status: 2, Note: &note,
+			Tags: []string{"office"}, Address: &testAddress{City: "Lyon", Zip: "69001"}, Token: []byte{1, 2}},
+		{Id: 2, Item: "Ink"},
+	}
+	row, err := codec.Row(orders[0])
+	c.Assert(err, IsNil)
+	c.Assert(row, DeepEquals, []string{"9007199254740993", "Pens", "3", "2.5", "true", "2", "fragile", "Lyon", "69001", "AQI="})
+	row, err = codec.Row(orders[1])
+	c.Assert(err, IsNil)
+	c.Assert(row, DeepEquals, []string{"2", "Ink", "0", "0", "false", "0", "", "", "", ""})
+	_, err = codec.Row(&testAddress{})
+	c.Assert(err, ErrorMatches, "the message is a xlsx.testAddress, not a xlsx.testOrder")
+
+	// The rows make the round trip through a streamed file.
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	c.Assert(builder.AddSheetWithTypes("Orders", codec.Headers(), codec.ColumnTypes()), IsNil)
+	stream, err := builder.Build()
+	c.Assert(err, IsNil)
+	for _, order := range orders {
+		row, err := codec.Row(order)
+		c.Assert(err, IsNil)
+		c.Assert(stream.Write(row), IsNil)
+	}
+	c.Assert(stream.Close(), IsNil)
+	reader, err := NewStreamFileReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	c.Assert(err, IsNil)
+	sheet, err := reader.Sheet(0)
+	c.Assert(err, IsNil)
+	defer sheet.Close()
+	var read []*testOrder
+	c.Assert(sheet.Rows(func(rowIndex int, cells []string) error {
+		if rowIndex == 0 {
+			return nil
+		}
+		message, err := codec.Message(cells)
+		read = append(read, message.(*testOrder))
+		return err
+	}), IsNil)
+	orders[0].Tags = nil
+	c.Assert(read, DeepEquals, orders)
+
+	err = codec.ReadRow([]string{"x", "", "", "", "", "", "", "", "", ""}, &testOrder{})
+	c.Assert(err, ErrorMatches, `column id: "x" isn't a 64 bit integer`)
+	err = codec.ReadRow([]string{"1"}, &testOrder{})
+	c.Assert(err, ErrorMatches, "the row has 1 cells instead of 10")
+}
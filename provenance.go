@@ -0,0 +1,295 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ProvenanceNamespace is the namespace of the custom XML parts
+	// holding the provenance tags of a file, see Provenance, for the
+	// tools reading them without this package.
+	ProvenanceNamespace       = "https://github.com/tealeg/xlsx/provenance"
+	relationshipTypeCustomXml = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/customXml"
+)
+
+// Provenance tags a cell of a sheet, such as "C12", or a whole row, such
+// as "12:12", with an opaque string, such as the id of the record of a
+// source system the cell or the row was written from, so that audit
+// trails can map the cells of a file back to their sources.  The tags
+// are kept in a custom XML part of the file, which Excel keeps when it
+// saves the file, without showing it.
+type Provenance struct {
+	Sheet string
+	Ref   string
+	Tag   string
+}
+
+// xlsxProvenance is the custom XML part holding the provenance tags of
+// a file.  Its XMLName has no tag, so that the parts of other
+// namespaces can be read and told apart.
+type xlsxProvenance struct {
+	XMLName xml.Name
+	Tags    []xlsxProvenanceTag `xml:"tag"`
+}
+
+type xlsxProvenanceTag struct {
+	Sheet string `xml:"sheet,attr"`
+	Ref   string `xml:"ref,attr"`
+	Tag   string `xml:",chardata"`
+}
+
+// provenanceTags are the provenance tags of a File, once read from its
+// parts or set.
+type provenanceTags struct {
+	tags []Provenance
+	// index holds the index in tags of the tag of each cell or row,
+	// keyed by sheet and reference.
+	index map[string]int
+	// parts are the names of the parts the tags were read from.
+	parts []string
+}
+
+// Provenance returns the provenance tags of the File, those of the file
+// it was opened from, in their order, followed by those set since.
+func (f *File) Provenance() ([]Provenance, error) {
+	if err := f.loadProvenance(); err != nil {
+		return nil, err
+	}
+	var tags []Provenance
+	for _, tag := range f.provenance.tags {
+		if tag.Tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// SetProvenance tags the cell or row ref of the given sheet, such as
+// "C12" or "12:12", with tag, replacing the tag it had, if any.  An
+// empty tag removes that of the cell or row.  The tags are written to
+// the file when it is saved.
+func (f *File) SetProvenance(sheet, ref, tag string) error {
+	if _, ok := f.Sheet[sheet]; !ok {
+		return fmt.Errorf("no sheet %q to tag", sheet)
+	}
+	ref, err := normalizeProvenanceRef(ref)
+	if err != nil {
+		return err
+	}
+	if err := f.loadProvenance(); err != nil {
+		return err
+	}
+	key := sheet + "!" + ref
+	if i, ok := f.provenance.index[key]; ok {
+		f.provenance.tags[i].Tag = tag
+		return nil
+	}
+	if tag != "" {
+		f.provenance.index[key] = len(f.provenance.tags)
+		f.provenance.tags = append(f.provenance.tags, Provenance{Sheet: sheet, Ref: ref, Tag: tag})
+	}
+	return nil
+}
+
+// normalizeProvenanceRef returns ref, the reference of a cell or a row
+// tagged, in upper case, or an error if it isn't one.
+func normalizeProvenanceRef(ref string) (string, error) {
+	ref = strings.ToUpper(ref)
+	if i := strings.Index(ref, cellRangeChar); i >= 0 {
+		row, err := strconv.Atoi(ref[:i])
+		if err != nil || ref[:i] != ref[i+1:] || row < 1 || row > Excel2006MaxRowCount {
+			return "", fmt.Errorf("invalid row %q to tag", ref)
+		}
+		return ref, nil
+	}
+	col, row, err := GetCoordsFromCellIDString(ref)
+	if err != nil || col < 0 || row < 0 || col >= Excel2006MaxColumnCount || row >= Excel2006MaxRowCount || GetCellIDStringFromCoords(col, row) != ref {
+		return "", fmt.Errorf("invalid cell %q to tag", ref)
+	}
+	return ref, nil
+}
+
+// loadProvenance reads the provenance tags of the custom XML parts of
+// the File, unless they are already read.
+func (f *File) loadProvenance() error {
+	if f.provenance != nil {
+		return nil
+	}
+	provenance := &provenanceTags{index: make(map[string]int)}
+	for _, rel := range f.keptRelationships(f.rawRelationships, "xl") {
+		if rel.Type != relationshipTypeCustomXml {
+			continue
+		}
+		partName := resolveTarget("xl", rel.Target)
+		xProvenance := &xlsxProvenance{}
+		if err := xml.Unmarshal(f.rawParts[partName], xProvenance); err != nil {
+			return err
+		}
+		if xProvenance.XMLName.Space != ProvenanceNamespace || xProvenance.XMLName.Local != "provenance" {
+			continue
+		}
+		provenance.parts = append(provenance.parts, partName)
+		for _, tag := range xProvenance.Tags {
+			key := tag.Sheet + "!" + tag.Ref
+			if i, ok := provenance.index[key]; ok {
+				provenance.tags[i].Tag = tag.Tag
+				continue
+			}
+			provenance.index[key] = len(provenance.tags)
+			provenance.tags = append(provenance.tags, Provenance{Sheet: tag.Sheet, Ref: tag.Ref, Tag: tag.Tag})
+		}
+	}
+	f.provenance = provenance
+	return nil
+}
+
+// addProvenance writes the provenance tags of the File, if they were
+// read or set, to the first of the parts they were read from, or to a
+// new custom XML part, the other parts being removed.
+func (f *File) addProvenance() error {
+	if f.provenance == nil {
+		return nil
+	}
+	tags, err := f.Provenance()
+	if err != nil {
+		return err
+	}
+	parts := f.provenance.parts
+	if len(tags) == 0 {
+		for _, name := range parts {
+			f.DeleteRawPart(name)
+		}
+		f.provenance.parts = nil
+		return nil
+	}
+	if len(parts) > 1 {
+		for _, name := range parts[1:] {
+			f.DeleteRawPart(name)
+		}
+	} else if len(parts) == 0 {
+		name := unusedRawPartName(f, "customXml/item", ".xml")
+		if err := f.SetRawPart(name, nil); err != nil {
+			return err
+		}
+		f.rawRelationships = append(f.rawRelationships, xlsxWorkbookRelation{
+			Type:   relationshipTypeCustomXml,
+			Target: "../" + name,
+		})
+		parts = []string{name}
+	}
+	f.provenance.parts = parts[:1]
+	return f.setRawXMLPart(parts[0], makeXLSXProvenance(tags))
+}
+
+// makeXLSXProvenance returns the custom XML part holding tags.
+func makeXLSXProvenance(tags []Provenance) *xlsxProvenance {
+	xProvenance := &xlsxProvenance{
+		XMLName: xml.Name{Space: ProvenanceNamespace, Local: "provenance"},
+		Tags:    make([]xlsxProvenanceTag, len(tags)),
+	}
+	for i, tag := range tags {
+		xProvenance.Tags[i] = xlsxProvenanceTag{Sheet: tag.Sheet, Ref: tag.Ref, Tag: tag.Tag}
+	}
+	return xProvenance
+}
+
+// SetRowProvenance tags the row last written to the current sheet, and
+// to the sheets continuing its columns, see
+// StreamFileBuilder.SetSplitWideSheets, with tag, such as the id of the
+// record it was written from.  The tags are written to a custom XML part
+// of the file by Close, and can be read back with File.Provenance.
+func (sf *StreamFile) SetRowProvenance(tag string) error {
+	ss, err := sf.provenanceSheet()
+	if err != nil {
+		return err
+	}
+	ref := strconv.Itoa(ss.rowCount)
+	ref += cellRangeChar + ref
+	sf.addProvenance(ss, ref, tag)
+	for _, followOn := range ss.followOns {
+		sf.addProvenance(followOn, ref, tag)
+	}
+	return nil
+}
+
+// SetCellProvenance tags the cell at colIndex, from 0, of the row last
+// written to the current sheet with tag, like SetRowProvenance.  The
+// columns of the sheets continuing those of the current sheet follow
+// its own.
+func (sf *StreamFile) SetCellProvenance(colIndex int, tag string) error {
+	ss, err := sf.provenanceSheet()
+	if err != nil {
+		return err
+	}
+	if colIndex < 0 || colIndex >= Excel2006MaxColumnCount {
+		return fmt.Errorf("invalid column %d to tag", colIndex)
+	}
+	ss, col := ss.column(colIndex)
+	sf.addProvenance(ss, GetCellIDStringFromCoords(col, ss.rowCount-1), tag)
+	return nil
+}
+
+// provenanceSheet returns the current sheet, whose last row is tagged,
+// or an error if there is no row to tag.
+func (sf *StreamFile) provenanceSheet() (*streamSheet, error) {
+	if sf.err != nil {
+		return nil, sf.err
+	}
+	if sf.currentSheet == nil {
+		return nil, NoCurrentSheetError
+	}
+	if sf.currentSheet.rowCount == 0 {
+		return nil, fmt.Errorf("no row written to the sheet %q to tag", sf.xlsxFile.Sheets[sf.currentSheet.index-1].Name)
+	}
+	return sf.currentSheet, nil
+}
+
+// addProvenance tags the cell or row ref of ss with tag.
+func (sf *StreamFile) addProvenance(ss *streamSheet, ref, tag string) {
+	sf.provenance = append(sf.provenance, Provenance{Sheet: sf.xlsxFile.Sheets[ss.index-1].Name, Ref: ref, Tag: tag})
+}
+
+// writeProvenance writes the provenance tags of the file, if any, to a
+// custom XML part, which the workbook refers to.
+func (sf *StreamFile) writeProvenance() error {
+	if len(sf.provenance) == 0 {
+		return nil
+	}
+	name := unusedRawPartName(sf.xlsxFile, "customXml/item", ".xml")
+	if err := sf.writePart(name, makeXLSXProvenance(sf.provenance)); err != nil {
+		return err
+	}
+	usedIds := relationshipIds(sf.workbookRelsPart)
+	nextId := 1
+	for usedIds["rId"+strconv.Itoa(nextId)] {
+		nextId++
+	}
+	sf.workbookRelsPart = strings.Replace(sf.workbookRelsPart, `</Relationships>`, `<Relationship Id="rId`+
+		strconv.Itoa(nextId)+`" Target="../`+name+`" Type="`+relationshipTypeCustomXml+`"></Relationship></Relationships>`, 1)
+	return nil
+}
+
+// SetRowProvenance tags the row last written to the sheet, like
+// StreamFile.SetRowProvenance.
+func (s *SpooledSheet) SetRowProvenance(tag string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ClosedSpooledSheetError
+	}
+	return s.file.SetRowProvenance(tag)
+}
+
+// SetCellProvenance tags a cell of the row last written to the sheet,
+// like StreamFile.SetCellProvenance.
+func (s *SpooledSheet) SetCellProvenance(colIndex int, tag string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ClosedSpooledSheetError
+	}
+	return s.file.SetCellProvenance(colIndex, tag)
+}
@@ -0,0 +1,79 @@
+package xlsx
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type ProvenanceSuite struct{}
+
+var _ = Suite(&ProvenanceSuite{})
+
+// saveAndOpen returns the File read back from file, once saved.
+func (s *ProvenanceSuite) saveAndOpen(c *C, file *File) *File {
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	read, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	return read
+}
+
+func (s *ProvenanceSuite) TestSetProvenance(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Orders")
+	c.Assert(err, IsNil)
+	sheet.AddRow().AddCell().SetString("Order")
+	sheet.AddRow().AddCell().SetInt(1)
+	c.Assert(file.SetProvenance("Orders", "a2", "crm:1"), IsNil)
+	c.Assert(file.SetProvenance("Orders", "2:2", "erp:order/1"), IsNil)
+	c.Assert(file.SetProvenance("Orders", "B1", "dropped"), IsNil)
+	c.Assert(file.SetProvenance("Orders", "B1", ""), IsNil)
+	c.Assert(file.SetProvenance("Missing", "A1", "x"), ErrorMatches, `no sheet "Missing" to tag`)
+	c.Assert(file.SetProvenance("Orders", "2:3", "x"), ErrorMatches, `invalid row "2:3" to tag`)
+	c.Assert(file.SetProvenance("Orders", "A0", "x"), ErrorMatches, `invalid cell "A0" to tag`)
+
+	read := s.saveAndOpen(c, file)
+	c.Assert(read.RawPartNames(), DeepEquals, []string{"customXml/item1.xml"})
+	tags, err := read.Provenance()
+	c.Assert(err, IsNil)
+	c.Assert(tags, DeepEquals, []Provenance{
+		{Sheet: "Orders", Ref: "A2", Tag: "crm:1"},
+		{Sheet: "Orders", Ref: "2:2", Tag: "erp:order/1"},
+	})
+
+	// The tags are changed in the part they were read from.
+	c.Assert(read.SetProvenance("Orders", "A2", "crm:2"), IsNil)
+	read = s.saveAndOpen(c, read)
+	c.Assert(read.RawPartNames(), DeepEquals, []string{"customXml/item1.xml"})
+	tags, err = read.Provenance()
+	c.Assert(err, IsNil)
+	c.Assert(tags[0].Tag, Equals, "crm:2")
+
+	// Removing every tag removes the part.
+	c.Assert(read.SetProvenance("Orders", "A2", ""), IsNil)
+	c.Assert(read.SetProvenance("Orders", "2:2", ""), IsNil)
+	read = s.saveAndOpen(c, read)
+	c.Assert(read.RawPartNames(), HasLen, 0)
+	tags, err = read.Provenance()
+	c.Assert(err, IsNil)
+	c.Assert(tags, HasLen, 0)
+}
+
+func (s *ProvenanceSuite) TestOtherCustomXmlParts(c *C) {
+	file := NewFile()
+	_, err := file.AddSheet("Orders")
+	c.Assert(err, IsNil)
+	c.Assert(file.SetRawPart("customXml/item1.xml", []byte(`<?xml version="1.0"?><root xmlns="urn:other"><tag>kept</tag></root>`)), IsNil)
+	file.rawRelationships = append(file.rawRelationships, xlsxWorkbookRelation{Type: relationshipTypeCustomXml, Target: "../customXml/item1.xml"})
+	c.Assert(file.SetProvenance("Orders", "A1", "crm:1"), IsNil)
+
+	read := s.saveAndOpen(c, file)
+	c.Assert(read.RawPartNames(), DeepEquals, []string{"customXml/item1.xml", "customXml/item2.xml"})
+	data, err := read.RawPart("customXml/item1.xml")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Matches, `.*urn:other.*`)
+	tags, err := read.Provenance()
+	c.Assert(err, IsNil)
+	c.Assert(tags, DeepEquals, []Provenance{{Sheet: "Orders", Ref: "A1", Tag: "crm:1"}})
+}
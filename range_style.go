@@ -0,0 +1,179 @@
+package xlsx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// rangeStyle is the style given to a range of cells of a Sheet by
+// SetRangeStyle, from minCol and minRow to maxCol and maxRow, zero
+// based.  wholeRows is true for a range of whole rows, whose style is
+// written as that of the rows, rather than of blank cells.
+type rangeStyle struct {
+	minCol, minRow, maxCol, maxRow int
+	wholeRows                      bool
+	style                          *Style
+}
+
+// SetRangeStyle gives the cells of the range ref of the Sheet, such as
+// "A1:H100", the style, shared by all of them rather than copied to each,
+// so that it is written once, as a single cell format.  The cells the
+// Sheet holds are given the style, those it doesn't hold are not added to
+// it but written blank with the style when the File is saved, and so are
+// the cells added to the range later without a style of their own.  The
+// style of whole columns, such as "B:D", or of whole rows, such as
+// "2:4", is written as the default of the columns or rows instead, for
+// the cells left blank.
+func (s *Sheet) SetRangeStyle(ref string, style *Style) error {
+	if style == nil {
+		return fmt.Errorf("no style to give the range %q", ref)
+	}
+	rs, err := parseStyleRange(ref)
+	if err != nil {
+		return err
+	}
+	rs.style = style
+	for r := rs.minRow; r <= rs.maxRow && r < len(s.rowList()); r++ {
+		row := s.row(r)
+		if row == nil {
+			continue
+		}
+		for c := rs.minCol; c <= rs.maxCol && c < len(row.Cells); c++ {
+			if cell := row.Cells[c]; cell != nil {
+				cell.SetStyle(style)
+			}
+		}
+	}
+	if rs.minRow == 0 && rs.maxRow == Excel2006MaxRowCount-1 {
+		// Whole columns take the style as theirs.
+		for c := rs.minCol; c <= rs.maxCol; c++ {
+			s.Col(c).SetStyle(style)
+		}
+		return nil
+	}
+	s.rangeStyles = append(s.rangeStyles, rs)
+	return nil
+}
+
+// parseStyleRange returns the range of the reference ref of a cell, of
+// a range of cells, or of whole columns or rows.
+func parseStyleRange(ref string) (rangeStyle, error) {
+	parts := strings.Split(strings.ToUpper(ref), cellRangeChar)
+	if len(parts) == 1 {
+		parts = append(parts, parts[0])
+	}
+	if len(parts) != 2 {
+		return rangeStyle{}, fmt.Errorf("invalid range %q", ref)
+	}
+	var rs rangeStyle
+	switch {
+	case isColumnLetters(parts[0]) && isColumnLetters(parts[1]):
+		rs.minCol, rs.maxCol = ColLettersToIndex(parts[0]), ColLettersToIndex(parts[1])
+		rs.maxRow = Excel2006MaxRowCount - 1
+	case isRowNumber(parts[0]) && isRowNumber(parts[1]):
+		minRow, _ := strconv.Atoi(parts[0])
+		maxRow, _ := strconv.Atoi(parts[1])
+		rs.minRow, rs.maxRow = minRow-1, maxRow-1
+		rs.maxCol = Excel2006MaxColumnCount - 1
+		rs.wholeRows = true
+	default:
+		var ok bool
+		if rs.minCol, rs.minRow, ok = parseCellID(strings.Replace(parts[0], "$", "", -1)); !ok {
+			return rangeStyle{}, fmt.Errorf("invalid range %q", ref)
+		}
+		if rs.maxCol, rs.maxRow, ok = parseCellID(strings.Replace(parts[1], "$", "", -1)); !ok {
+			return rangeStyle{}, fmt.Errorf("invalid range %q", ref)
+		}
+	}
+	if rs.minCol > rs.maxCol {
+		rs.minCol, rs.maxCol = rs.maxCol, rs.minCol
+	}
+	if rs.minRow > rs.maxRow {
+		rs.minRow, rs.maxRow = rs.maxRow, rs.minRow
+	}
+	if rs.minCol < 0 || rs.minRow < 0 || rs.maxCol >= Excel2006MaxColumnCount || rs.maxRow >= Excel2006MaxRowCount {
+		return rangeStyle{}, fmt.Errorf("the range %q is out of the bounds of a sheet", ref)
+	}
+	return rs, nil
+}
+
+// isColumnLetters returns true if s is the letters of a column, such as
+// "AB", without a row.
+func isColumnLetters(s string) bool {
+	return s != "" && strings.Trim(strings.Replace(s, "$", "", -1), "ABCDEFGHIJKLMNOPQRSTUVWXYZ") == ""
+}
+
+// isRowNumber returns true if s is the number of a row, from 1, without
+// a column.
+func isRowNumber(s string) bool {
+	n, err := strconv.Atoi(strings.Replace(s, "$", "", -1))
+	return err == nil && n >= 1
+}
+
+// rangeStyleAt returns the style of the range last given one holding the
+// cell at the zero based col and row, or nil if there is none.
+func (s *Sheet) rangeStyleAt(col, row int) *Style {
+	for i := len(s.rangeStyles) - 1; i >= 0; i-- {
+		rs := s.rangeStyles[i]
+		if col >= rs.minCol && col <= rs.maxCol && row >= rs.minRow && row <= rs.maxRow {
+			return rs.style
+		}
+	}
+	return nil
+}
+
+// rowRangeStyle returns the style of the range of whole rows last given
+// one holding the zero based row, or nil if there is none.
+func (s *Sheet) rowRangeStyle(row int) *Style {
+	for i := len(s.rangeStyles) - 1; i >= 0; i-- {
+		rs := s.rangeStyles[i]
+		if rs.wholeRows && row >= rs.minRow && row <= rs.maxRow {
+			return rs.style
+		}
+	}
+	return nil
+}
+
+// rangeStyleRows returns the number of rows reached by the ranges given
+// a style.
+func (s *Sheet) rangeStyleRows() int {
+	rows := 0
+	for _, rs := range s.rangeStyles {
+		if rs.maxRow+1 > rows {
+			rows = rs.maxRow + 1
+		}
+	}
+	return rows
+}
+
+// blankRangeStyledCells returns the c elements of the cells of the zero
+// based row, from the column from on, that the Sheet doesn't hold, and
+// that a range of cells gives a style, written by xfId.
+func (s *Sheet) blankRangeStyledCells(row, from int, xfId func(*Style) int) []xlsxC {
+	maxCol := -1
+	for _, rs := range s.rangeStyles {
+		if !rs.wholeRows && row >= rs.minRow && row <= rs.maxRow && rs.maxCol > maxCol {
+			maxCol = rs.maxCol
+		}
+	}
+	var cells []xlsxC
+	for c := from; c <= maxCol; c++ {
+		// The cells of the rows given a style last take it from the
+		// row.
+		var style *Style
+		for i := len(s.rangeStyles) - 1; i >= 0; i-- {
+			rs := s.rangeStyles[i]
+			if c >= rs.minCol && c <= rs.maxCol && row >= rs.minRow && row <= rs.maxRow {
+				if !rs.wholeRows {
+					style = rs.style
+				}
+				break
+			}
+		}
+		if style != nil {
+			cells = append(cells, xlsxC{R: GetCellIDStringFromCoords(c, row), S: xfId(style)})
+		}
+	}
+	return cells
+}
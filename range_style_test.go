@@ -0,0 +1,111 @@
+package xlsx
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type RangeStyleSuite struct{}
+
+var _ = Suite(&RangeStyleSuite{})
+
+// boldStyle returns a new bold style with a yellow fill.
+func boldStyle() *Style {
+	style := NewStyle()
+	style.Font.Bold = true
+	style.Fill = *NewFill("solid", "FFFFFF00", "FFFFFF00")
+	style.ApplyFont = true
+	style.ApplyFill = true
+	return style
+}
+
+func (s *RangeStyleSuite) TestSetRangeStyle(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	for r := 0; r < 2; r++ {
+		row := sheet.AddRow()
+		row.AddCell().Value = "a"
+		row.AddCell().Value = "b"
+	}
+	style := boldStyle()
+	c.Assert(sheet.SetRangeStyle("B2:C3", style), IsNil)
+	c.Assert(sheet.Cell(1, 1).GetStyle(), Equals, style)
+	c.Assert(sheet.Cell(0, 1).GetStyle(), Not(Equals), style)
+	c.Assert(sheet.MaxRow, Equals, 2)
+
+	worksheet := sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+	rows := worksheet.SheetData.Row
+	c.Assert(rows, HasLen, 3)
+	c.Assert(rows[1].C, HasLen, 3)
+	c.Assert(rows[1].C[2].R, Equals, "C2")
+	c.Assert(rows[2].C, HasLen, 2)
+	c.Assert(rows[2].C[0].R, Equals, "B3")
+	xfId := rows[1].C[1].S
+	c.Assert(xfId, Not(Equals), rows[0].C[1].S)
+	c.Assert(rows[1].C[2].S, Equals, xfId)
+	c.Assert(rows[2].C[0].S, Equals, xfId)
+	c.Assert(rows[2].C[1].S, Equals, xfId)
+	c.Assert(worksheet.Dimension.Ref, Equals, "A1:C3")
+
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	read, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	readSheet := read.Sheet["Sheet1"]
+	c.Assert(readSheet.Cell(2, 2).GetStyle().Font.Bold, Equals, true)
+	c.Assert(readSheet.Cell(1, 1).GetStyle().Font.Bold, Equals, true)
+	c.Assert(readSheet.Cell(1, 0).GetStyle().Font.Bold, Equals, false)
+}
+
+func (s *RangeStyleSuite) TestSetRangeStyleCellsAddedLater(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	style := boldStyle()
+	c.Assert(sheet.SetRangeStyle("A1:B1", style), IsNil)
+	sheet.AddRow().AddCell().Value = "a"
+
+	worksheet := sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+	cells := worksheet.SheetData.Row[0].C
+	c.Assert(cells, HasLen, 2)
+	c.Assert(cells[0].V, Equals, "0")
+	c.Assert(cells[0].S, Not(Equals), 0)
+	c.Assert(cells[1].S, Equals, cells[0].S)
+}
+
+func (s *RangeStyleSuite) TestSetRangeStyleWholeColumnsAndRows(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	sheet.AddRow().AddCell().Value = "a"
+	style := boldStyle()
+	c.Assert(sheet.SetRangeStyle("B:C", style), IsNil)
+	c.Assert(sheet.Col(1).GetStyle(), Equals, style)
+	c.Assert(sheet.Col(2).GetStyle(), Equals, style)
+	c.Assert(sheet.Col(0).GetStyle(), Not(Equals), style)
+
+	c.Assert(sheet.SetRangeStyle("2:3", style), IsNil)
+	worksheet := sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+	rows := worksheet.SheetData.Row
+	c.Assert(rows, HasLen, 3)
+	c.Assert(rows[0].CustomFormat, Equals, false)
+	for _, row := range rows[1:] {
+		c.Assert(row.C, HasLen, 0)
+		c.Assert(row.CustomFormat, Equals, true)
+		c.Assert(row.S, Equals, worksheet.Cols.Col[1].Style)
+	}
+	c.Assert(rows[2].R, Equals, 3)
+}
+
+func (s *RangeStyleSuite) TestSetRangeStyleInvalid(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	c.Assert(sheet.SetRangeStyle("A1:B2", nil), ErrorMatches, `no style to give the range "A1:B2"`)
+	for _, ref := range []string{"", "A1:B", "A1:B2:C3", "1A"} {
+		c.Assert(sheet.SetRangeStyle(ref, NewStyle()), ErrorMatches, `invalid range ".*"`)
+	}
+	c.Assert(sheet.SetRangeStyle("A1:XFE1", NewStyle()), ErrorMatches, `the range "A1:XFE1" is out of the bounds of a sheet`)
+}
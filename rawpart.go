@@ -0,0 +1,336 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// generatedPartNames are the parts that are always written from the
+// model of a File, worksheets aside.  They can't be set as raw parts
+// since they would be overwritten on save.
+var generatedPartNames = map[string]bool{
+	"[Content_Types].xml":        true,
+	"_rels/.rels":                true,
+	"docProps/app.xml":           true,
+	"docProps/core.xml":          true,
+	"xl/workbook.xml":            true,
+	"xl/_rels/workbook.xml.rels": true,
+	"xl/sharedStrings.xml":       true,
+	"xl/styles.xml":              true,
+	"xl/theme/theme1.xml":        true,
+}
+
+// defaultRawContentTypes are the content types assumed for new raw
+// parts, by file extension.  Parts with any other extension need their
+// content type set with SetRawPartContentType.
+var defaultRawContentTypes = map[string]string{
+	"bin":  "application/vnd.ms-office.vbaProject",
+	"emf":  "image/x-emf",
+	"gif":  "image/gif",
+	"jpeg": "image/jpeg",
+	"jpg":  "image/jpeg",
+	"png":  "image/png",
+	"wmf":  "image/x-wmf",
+}
+
+// isGeneratedPart returns true if the part with the given name is
+// written from the model of a File.
+func isGeneratedPart(name string) bool {
+	return generatedPartNames[name] || strings.HasPrefix(name, "xl/worksheets/")
+}
+
+// RawPartNames returns the sorted names of the raw parts of the File.
+// These are the parts of a loaded file that this package doesn't
+// model, such as drawings, slicers or VBA projects, along with any
+// part added by SetRawPart.
+func (f *File) RawPartNames() []string {
+	names := make([]string, 0, len(f.rawParts))
+	for name := range f.rawParts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RawPart returns the content of the raw part with the given name,
+// for example "xl/vbaProject.bin".  Raw parts are kept unchanged when
+// the File is saved.
+func (f *File) RawPart(name string) ([]byte, error) {
+	data, ok := f.rawParts[name]
+	if !ok {
+		return nil, fmt.Errorf("raw part '%s' not found", name)
+	}
+	return data, nil
+}
+
+// SetRawPart adds or replaces the raw part with the given name.  The
+// content type of a new part is guessed from its extension, XML
+// being the default, and can be changed with SetRawPartContentType.
+// Parts written from the model of the File, such as the workbook, the
+// worksheets or the styles, can't be set.
+func (f *File) SetRawPart(name string, data []byte) error {
+	name = strings.TrimPrefix(name, "/")
+	if isGeneratedPart(name) {
+		return fmt.Errorf("part '%s' is generated and can't be set", name)
+	}
+	if f.rawParts == nil {
+		f.rawParts = make(map[string][]byte)
+		f.rawContentTypes = make(map[string]string)
+	}
+	if _, exists := f.rawParts[name]; !exists {
+		ext := strings.TrimPrefix(path.Ext(name), ".")
+		f.rawContentTypes[name] = defaultRawContentTypes[strings.ToLower(ext)]
+	}
+	f.rawParts[name] = data
+	return nil
+}
+
+// DeleteRawPart removes the raw part with the given name, if any, so
+// that it will not be written when the File is saved.
+func (f *File) DeleteRawPart(name string) {
+	delete(f.rawParts, name)
+	delete(f.rawContentTypes, name)
+}
+
+// RawPartContentType returns the content type of a raw part.  An
+// empty string is returned for XML parts that use the default
+// content type.
+func (f *File) RawPartContentType(name string) string {
+	return f.rawContentTypes[name]
+}
+
+// SetRawPartContentType sets the content type of an existing raw
+// part.
+func (f *File) SetRawPartContentType(name, contentType string) error {
+	if _, ok := f.rawParts[name]; !ok {
+		return fmt.Errorf("raw part '%s' not found", name)
+	}
+	f.rawContentTypes[name] = contentType
+	return nil
+}
+
+// addRawParts adds the raw parts of the File, and the workbook
+// relationships that point at them, to the parts being written.
+// Relationships keep their original Id unless it is already taken by
+// a generated relationship, in which case they are renamed; the
+// renamed Ids are returned, keyed by the original Id.
+func (f *File) addRawParts(parts map[string]string, types *xlsxTypes, workbookRels *xlsxWorkbookRels) map[string]string {
+	for _, name := range f.RawPartNames() {
+		if _, exists := parts[name]; exists {
+			continue
+		}
+		parts[name] = string(f.rawParts[name])
+		if contentType := f.rawContentTypes[name]; contentType != "" {
+			types.Overrides = append(types.Overrides, xlsxOverride{
+				PartName:    "/" + name,
+				ContentType: contentType,
+			})
+		}
+	}
+	usedIds := make(map[string]bool, len(workbookRels.Relationships))
+	for _, rel := range workbookRels.Relationships {
+		usedIds[rel.Id] = true
+	}
+	renamedIds := make(map[string]string)
+	for _, rel := range f.keptRelationships(f.rawRelationships, "xl") {
+		if rel.Id == "" || usedIds[rel.Id] {
+			id := rel.Id
+			for n := len(usedIds) + 1; rel.Id == id || usedIds[rel.Id]; n++ {
+				rel.Id = fmt.Sprintf("rId%d", n)
+			}
+			renamedIds[id] = rel.Id
+		}
+		usedIds[rel.Id] = true
+		workbookRels.Relationships = append(workbookRels.Relationships, rel)
+	}
+	return renamedIds
+}
+
+// keptRelationships returns those of the given relationships, of a
+// part in the directory base, that are external or point at a raw
+// part that is still present.
+func (f *File) keptRelationships(rels []xlsxWorkbookRelation, base string) []xlsxWorkbookRelation {
+	var kept []xlsxWorkbookRelation
+	for _, rel := range rels {
+		if rel.TargetMode != "External" {
+			if _, exists := f.rawParts[resolveTarget(base, rel.Target)]; !exists {
+				continue
+			}
+		}
+		kept = append(kept, rel)
+	}
+	return kept
+}
+
+// resolveTarget returns the name of the part that a relationship
+// target points at.  Targets are relative to the directory base of
+// the part owning the relationship, unless they are absolute.
+func resolveTarget(base, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return strings.TrimPrefix(target, "/")
+	}
+	return path.Join(base, target)
+}
+
+// extensionNamespaces are the namespace prefixes commonly used inside
+// extensions.  The declarations of these prefixes are usually made on
+// the root element, which isn't kept, so they are repeated on every
+// raw extension that uses them.
+var extensionNamespaces = map[string]string{
+	"mc":  "http://schemas.openxmlformats.org/markup-compatibility/2006",
+	"r":   "http://schemas.openxmlformats.org/officeDocument/2006/relationships",
+	"x14": x14Namespace,
+	"x15": "http://schemas.microsoft.com/office/spreadsheetml/2010/11/main",
+	"xm":  xmNamespace,
+	"xr":  "http://schemas.microsoft.com/office/spreadsheetml/2014/revision",
+}
+
+// relationshipIdPattern matches the relationship Ids referred to from
+// the content of an extension.
+var relationshipIdPattern = regexp.MustCompile(`r:id="([^"]*)"`)
+
+// readRawExtensions returns the extensions in extLst, other than
+// those with one of the modelled URIs, so that they can be written
+// back unchanged.
+func readRawExtensions(extLst *xlsxExtLst, modelled ...string) []xlsxExt {
+	if extLst == nil {
+		return nil
+	}
+	var exts []xlsxExt
+next:
+	for _, ext := range extLst.Ext {
+		for _, uri := range modelled {
+			if ext.URI == uri {
+				continue next
+			}
+		}
+		exts = append(exts, ext)
+	}
+	return exts
+}
+
+// makeRawExtension prepares an extension that was read from a file to
+// be written again: the namespace prefixes it uses are declared, and
+// the relationship Ids it refers to are renamed as given.
+func makeRawExtension(ext xlsxExt, renamedIds map[string]string) xlsxExt {
+	declared := make(map[string]bool)
+	var attrs []xml.Attr
+	for _, attr := range ext.Namespaces {
+		if attr.Name.Space == "xmlns" {
+			declared[attr.Name.Local] = true
+			attr.Name = xml.Name{Local: "xmlns:" + attr.Name.Local}
+		}
+		attrs = append(attrs, attr)
+	}
+	prefixes := make([]string, 0, len(extensionNamespaces))
+	for prefix := range extensionNamespaces {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		if declared[prefix] {
+			continue
+		}
+		if strings.Contains(ext.Content, "<"+prefix+":") || strings.Contains(ext.Content, " "+prefix+":") {
+			attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: extensionNamespaces[prefix]})
+		}
+	}
+	ext.Namespaces = attrs
+	if len(renamedIds) > 0 {
+		ext.Content = relationshipIdPattern.ReplaceAllStringFunc(ext.Content, func(match string) string {
+			id := relationshipIdPattern.FindStringSubmatch(match)[1]
+			if renamed, ok := renamedIds[id]; ok {
+				return `r:id="` + renamed + `"`
+			}
+			return match
+		})
+	}
+	return ext
+}
+
+// makeXLSXExtLst combines the given extensions into an extLst
+// element, or returns nil if there are none.
+func makeXLSXExtLst(exts []xlsxExt) *xlsxExtLst {
+	if len(exts) == 0 {
+		return nil
+	}
+	return &xlsxExtLst{Ext: exts}
+}
+
+// readRelationshipsFromZipFile is an internal helper function that
+// reads the relationships of a part other than the workbook.
+func readRelationshipsFromZipFile(f *zip.File) ([]xlsxWorkbookRelation, error) {
+	if f == nil {
+		return nil, nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	rels := xlsxWorkbookRels{}
+	if err := xml.NewDecoder(rc).Decode(&rels); err != nil {
+		return nil, err
+	}
+	return rels.Relationships, nil
+}
+
+// readRawPartsFromZipFiles is an internal helper function that reads
+// the content of the parts this package doesn't model, along with
+// their content types, into the File.
+func readRawPartsFromZipFiles(rawParts []*zip.File, contentTypes *zip.File, file *File) error {
+	file.rawParts = make(map[string][]byte, len(rawParts))
+	file.rawContentTypes = make(map[string]string, len(rawParts))
+	types := xlsxTypes{}
+	if contentTypes != nil {
+		rc, err := contentTypes.Open()
+		if err != nil {
+			return err
+		}
+		err = xml.NewDecoder(rc).Decode(&types)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	overrides := make(map[string]string, len(types.Overrides))
+	for _, override := range types.Overrides {
+		overrides[strings.TrimPrefix(override.PartName, "/")] = override.ContentType
+	}
+	defaults := make(map[string]string, len(types.Defaults))
+	for _, def := range types.Defaults {
+		defaults[strings.ToLower(def.Extension)] = def.ContentType
+	}
+	for _, f := range rawParts {
+		if err := file.memoryBudget.charge(int64(f.UncompressedSize64)); err != nil {
+			return err
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		file.rawParts[f.Name] = data
+		contentType, ok := overrides[f.Name]
+		if !ok {
+			ext := strings.ToLower(strings.TrimPrefix(path.Ext(f.Name), "."))
+			// XML and relationship parts are covered by the
+			// defaults this package always writes.
+			if ext != "xml" && ext != "rels" {
+				contentType = defaults[ext]
+			}
+		}
+		file.rawContentTypes[f.Name] = contentType
+	}
+	return nil
+}
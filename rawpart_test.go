@@ -0,0 +1,54 @@
+package xlsx
+
+import (
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type RawPartSuite struct{}
+
+var _ = Suite(&RawPartSuite{})
+
+func (s *RawPartSuite) TestRawPartsSurviveRoundTrip(c *C) {
+	file := addPartsToXLSX(c, "./testdocs/testfile.xlsx", map[string]string{
+		"xl/drawings/drawing1.xml": "<xdr:wsDr/>",
+	})
+	data, err := file.RawPart("xl/drawings/drawing1.xml")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "<xdr:wsDr/>")
+
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	reread, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	data, err = reread.RawPart("xl/drawings/drawing1.xml")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "<xdr:wsDr/>")
+}
+
+func (s *RawPartSuite) TestSetRawPart(c *C) {
+	file := NewFile()
+	_, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	c.Assert(file.SetRawPart("xl/media/image1.png", []byte("png")), IsNil)
+	c.Assert(file.RawPartContentType("xl/media/image1.png"), Equals, "image/png")
+	c.Assert(file.SetRawPartContentType("xl/missing.xml", "text/xml"), NotNil)
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/media/image1.png"], Equals, "png")
+	c.Assert(strings.Contains(parts["[Content_Types].xml"], `PartName="/xl/media/image1.png"`), Equals, true)
+
+	file.DeleteRawPart("xl/media/image1.png")
+	c.Assert(file.RawPartNames(), HasLen, 0)
+	_, err = file.RawPart("xl/media/image1.png")
+	c.Assert(err, NotNil)
+}
+
+func (s *RawPartSuite) TestSetRawPartRejectsGeneratedParts(c *C) {
+	file := NewFile()
+	c.Assert(file.SetRawPart("xl/styles.xml", nil), ErrorMatches, "part 'xl/styles.xml' is generated and can't be set")
+	c.Assert(file.SetRawPart("/xl/worksheets/sheet1.xml", nil), NotNil)
+}
@@ -27,6 +27,7 @@ func (r *Row) ReadStruct(ptr interface{}) error {
 	if ptr == nil {
 		return errNilInterface
 	}
+	r.load()
 	//check if the type implements XLSXUnmarshaler. If so,
 	//just let it do the work.
 	unmarshaller, ok := ptr.(XLSXUnmarshaler)
@@ -0,0 +1,321 @@
+package xlsx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Data lakes mostly export records, as NDJSON or as streams of maps,
+// rather than rows.  A RecordAdapter turns such records into the rows
+// of a streamed sheet: its Headers are the columns of the sheet, found
+// among the keys of the first records unless they are given, and each
+// record read is a row holding its values under their columns.  Avro
+// and other formats whose decoders return maps are read with
+// NewMapAdapter.
+
+// ColumnOrder is the order of the columns that a RecordAdapter finds
+// among the keys of the records.
+type ColumnOrder int
+
+const (
+	// ColumnOrderFirstSeen orders the columns as their keys are first
+	// seen, in the order of the keys of the JSON objects.  The keys of
+	// a map, which have no order, are sorted among those first seen
+	// in the same record.
+	ColumnOrderFirstSeen ColumnOrder = iota
+	// ColumnOrderSorted sorts the columns by name, so that the
+	// columns don't depend on the records read first.
+	ColumnOrderSorted
+)
+
+// defaultRecordSampleSize is the number of records read ahead to find
+// the columns by default.
+const defaultRecordSampleSize = 100
+
+// RecordOptions sets how a RecordAdapter makes rows of records.
+type RecordOptions struct {
+	// Columns are the columns of the rows, in order.  If empty, the
+	// columns are the keys of the first SampleSize records, 100 if 0,
+	// in the given Order.
+	Columns    []string
+	Order      ColumnOrder
+	SampleSize int
+	// RejectUnknownKeys makes ReadRow fail on the records with keys
+	// that aren't among the columns, which are otherwise left out.
+	RejectUnknownKeys bool
+}
+
+// RecordAdapter reads records and returns them as rows.
+type RecordAdapter struct {
+	next    func() (record, error)
+	options RecordOptions
+	columns []string
+	// sample holds the records read ahead to find the columns, which
+	// are returned first.
+	sample []record
+	err    error
+}
+
+// record is a record read by a RecordAdapter, keys being its keys in
+// order.
+type record struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// NewNDJSONAdapter creates a RecordAdapter reading the records of r, a
+// JSON object on each line.  Blank lines are skipped, and numbers are
+// kept as they are written.
+func NewNDJSONAdapter(r io.Reader, options RecordOptions) *RecordAdapter {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+	line := 0
+	next := func() (record, error) {
+		for scanner.Scan() {
+			line++
+			data := bytes.TrimSpace(scanner.Bytes())
+			if len(data) == 0 {
+				continue
+			}
+			rec, err := decodeJSONRecord(data)
+			if err != nil {
+				return record{}, fmt.Errorf("line %d: %v", line, err)
+			}
+			return rec, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return record{}, err
+		}
+		return record{}, io.EOF
+	}
+	return &RecordAdapter{next: next, options: options}
+}
+
+// NewMapAdapter creates a RecordAdapter reading the records returned by
+// next, which returns io.EOF after the last one.
+func NewMapAdapter(next func() (map[string]interface{}, error), options RecordOptions) *RecordAdapter {
+	return &RecordAdapter{
+		next: func() (record, error) {
+			values, err := next()
+			if err != nil {
+				return record{}, err
+			}
+			keys := make([]string, 0, len(values))
+			for key := range values {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			return record{keys: keys, values: values}, nil
+		},
+		options: options,
+	}
+}
+
+// decodeJSONRecord decodes a JSON object, keeping the order of its
+// keys.
+func decodeJSONRecord(data []byte) (record, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	token, err := decoder.Token()
+	if err != nil {
+		return record{}, err
+	}
+	if token != json.Delim('{') {
+		return record{}, fmt.Errorf("the record isn't a JSON object")
+	}
+	rec := record{values: make(map[string]interface{})}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return record{}, err
+		}
+		key := token.(string)
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return record{}, err
+		}
+		if _, ok := rec.values[key]; !ok {
+			rec.keys = append(rec.keys, key)
+		}
+		rec.values[key] = value
+	}
+	if _, err := decoder.Token(); err != nil {
+		return record{}, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return record{}, fmt.Errorf("the line holds more than a JSON object")
+	}
+	return rec, nil
+}
+
+// Headers returns the columns of the rows, reading the first records to
+// find them unless they were given.
+func (ra *RecordAdapter) Headers() ([]string, error) {
+	if ra.columns != nil {
+		return ra.columns, nil
+	}
+	if len(ra.options.Columns) > 0 {
+		ra.columns = ra.options.Columns
+		return ra.columns, nil
+	}
+	sampleSize := ra.options.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = defaultRecordSampleSize
+	}
+	seen := make(map[string]bool)
+	columns := []string{}
+	for len(ra.sample) < sampleSize {
+		rec, err := ra.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		ra.sample = append(ra.sample, rec)
+		for _, key := range rec.keys {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+	}
+	if ra.options.Order == ColumnOrderSorted {
+		sort.Strings(columns)
+	}
+	ra.columns = columns
+	return ra.columns, nil
+}
+
+// ReadRow returns the row holding the values of the next record, or
+// io.EOF after the last one.  The values are written as text: numbers
+// as they were read, booleans as "true" or "false", times in RFC 3339,
+// nulls and missing keys as empty cells, and objects and arrays as
+// JSON.
+func (ra *RecordAdapter) ReadRow() ([]string, error) {
+	row, _, err := ra.readRow()
+	return row, err
+}
+
+// readRow returns the row holding the values of the next record like
+// ReadRow, along with the cells holding nulls, nil if there are none.
+func (ra *RecordAdapter) readRow() ([]string, []bool, error) {
+	if ra.err != nil {
+		return nil, nil, ra.err
+	}
+	if _, err := ra.Headers(); err != nil {
+		ra.err = err
+		return nil, nil, err
+	}
+	var rec record
+	if len(ra.sample) > 0 {
+		rec, ra.sample = ra.sample[0], ra.sample[1:]
+	} else {
+		var err error
+		if rec, err = ra.next(); err != nil {
+			ra.err = err
+			return nil, nil, err
+		}
+	}
+	row := make([]string, len(ra.columns))
+	var nulls []bool
+	known := 0
+	for i, column := range ra.columns {
+		value, ok := rec.values[column]
+		if !ok {
+			continue
+		}
+		known++
+		if value == nil {
+			if nulls == nil {
+				nulls = make([]bool, len(ra.columns))
+			}
+			nulls[i] = true
+			continue
+		}
+		text, err := recordValueText(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("key %q: %v", column, err)
+		}
+		row[i] = text
+	}
+	if ra.options.RejectUnknownKeys && known < len(rec.values) {
+		for _, key := range rec.keys {
+			if _, ok := indexOf(ra.columns, key); !ok {
+				return nil, nil, fmt.Errorf("the key %q isn't among the columns", key)
+			}
+		}
+	}
+	return row, nulls, nil
+}
+
+// indexOf returns the index of s in list.
+func indexOf(list []string, s string) (int, bool) {
+	for i, item := range list {
+		if item == s {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// recordValueText returns the text of a value of a record.
+func recordValueText(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// WriteRecords writes the rows of the records that ra reads to the
+// current sheet, whose headers should be those of ra, up to the last
+// record.  Null values are written as the NullPolicy of the file says,
+// see StreamFileBuilder.SetNullAs, while missing keys are left empty.  It stops at the first record that can't be read or written,
+// returning a RowError giving its index among the records written by
+// this call.
+func (sf *StreamFile) WriteRecords(ra *RecordAdapter) error {
+	if sf.err != nil {
+		return sf.err
+	}
+	for i := 0; ; i++ {
+		row, nulls, err := ra.readRow()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return &RowError{Row: i, Err: err}
+		}
+		if err := sf.writeNullable(row, nulls); err != nil {
+			sf.err = &RowError{Row: i, Err: err}
+			return sf.err
+		}
+	}
+	return sf.flush()
+}
@@ -0,0 +1,88 @@
+package xlsx
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type RecordsSuite struct{}
+
+var _ = Suite(&RecordsSuite{})
+
+const testNDJSON = `{"id": 1, "name": "Pens", "price": 2.50, "tags": ["office"]}
+
+{"name": "Ink", "id": 12345678901234567890, "shipped": true, "price": null}
+`
+
+func (s *RecordsSuite) TestNDJSONAdapter(c *C) {
+	adapter := NewNDJSONAdapter(strings.NewReader(testNDJSON), RecordOptions{})
+	headers, err := adapter.Headers()
+	c.Assert(err, IsNil)
+	c.Assert(headers, DeepEquals, []string{"id", "name", "price", "tags", "shipped"})
+	row, err := adapter.ReadRow()
+	c.Assert(err, IsNil)
+	c.Assert(row, DeepEquals, []string{"1", "Pens", "2.50", `["office"]`, ""})
+	row, err = adapter.ReadRow()
+	c.Assert(err, IsNil)
+	c.Assert(row, DeepEquals, []string{"12345678901234567890", "Ink", "", "", "true"})
+	_, err = adapter.ReadRow()
+	c.Assert(err, Equals, io.EOF)
+
+	// The columns found in a smaller sample are sorted, and the
+	// keys of later records that aren't among them rejected.
+	adapter = NewNDJSONAdapter(strings.NewReader(testNDJSON), RecordOptions{Order: ColumnOrderSorted, SampleSize: 1, RejectUnknownKeys: true})
+	headers, err = adapter.Headers()
+	c.Assert(err, IsNil)
+	c.Assert(headers, DeepEquals, []string{"id", "name", "price", "tags"})
+	_, err = adapter.ReadRow()
+	c.Assert(err, IsNil)
+	_, err = adapter.ReadRow()
+	c.Assert(err, ErrorMatches, `the key "shipped" isn't among the columns`)
+
+	adapter = NewNDJSONAdapter(strings.NewReader("{\"id\": 1}\n[1, 2]\n"), RecordOptions{Columns: []string{"id"}})
+	row, err = adapter.ReadRow()
+	c.Assert(err, IsNil)
+	c.Assert(row, DeepEquals, []string{"1"})
+	_, err = adapter.ReadRow()
+	c.Assert(err, ErrorMatches, "line 2: the record isn't a JSON object")
+}
+
+func (s *RecordsSuite) TestMapAdapter(c *C) {
+	records := []map[string]interface{}{
+		{"name": "Pens", "count": 3},
+		{"name": "Ink", "color": "blue", "weight": 0.5},
+	}
+	next := func() (map[string]interface{}, error) {
+		if len(records) == 0 {
+			return nil, io.EOF
+		}
+		record := records[0]
+		records = records[1:]
+		return record, nil
+	}
+	adapter := NewMapAdapter(next, RecordOptions{})
+	headers, err := adapter.Headers()
+	c.Assert(err, IsNil)
+	c.Assert(headers, DeepEquals, []string{"count", "name", "color", "weight"})
+
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	c.Assert(builder.AddSheet("Items", headers, nil), IsNil)
+	stream, err := builder.Build()
+	c.Assert(err, IsNil)
+	c.Assert(stream.WriteRecords(adapter), IsNil)
+	c.Assert(stream.Close(), IsNil)
+
+	f, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	sheets, err := f.ToSlice()
+	c.Assert(err, IsNil)
+	c.Assert(sheets[0], DeepEquals, [][]string{
+		{"count", "name", "color", "weight"},
+		{"3", "Pens", "", ""},
+		{"", "Ink", "blue", "0.5"},
+	})
+}
@@ -1,5 +1,10 @@
 package xlsx
 
+import (
+	"bytes"
+	"encoding/gob"
+)
+
 type RefTable struct {
 	indexedStrings []string
 	knownStrings   map[string]int
@@ -20,15 +25,16 @@ func NewSharedStringRefTable() *RefTable {
 func MakeSharedStringRefTable(source *xlsxSST) *RefTable {
 	reftable := NewSharedStringRefTable()
 	reftable.isWrite = false
+	reftable.indexedStrings = make([]string, 0, len(source.SI))
 	for _, si := range source.SI {
 		if len(si.R) > 0 {
-			newString := ""
+			var newString bytes.Buffer
 			for j := 0; j < len(si.R); j++ {
-				newString = newString + si.R[j].T
+				newString.WriteString(si.R[j].T)
 			}
-			reftable.AddString(newString)
+			reftable.AddString(unescapeXString(newString.String()))
 		} else {
-			reftable.AddString(si.T)
+			reftable.AddString(unescapeXString(si.T))
 		}
 	}
 	return reftable
@@ -42,7 +48,7 @@ func (rt *RefTable) makeXLSXSST() xlsxSST {
 	sst.UniqueCount = sst.Count
 	for _, ref := range rt.indexedStrings {
 		si := xlsxSI{}
-		si.T = ref
+		si.T = escapeXString(ref)
 		sst.SI = append(sst.SI, si)
 	}
 	return sst
@@ -51,23 +57,28 @@ func (rt *RefTable) makeXLSXSST() xlsxSST {
 // Resolvesharedstring() looks up a string value by numeric index from
 // a provided reference table (just a slice of strings in the correct
 // order).  This function only exists to provide clarity or purpose
-// via it's name.
+// via it's name.  The string isn't copied, cells read from a file
+// share the memory of the strings of its table.
 func (rt *RefTable) ResolveSharedString(index int) string {
 	return rt.indexedStrings[index]
 }
 
 // AddString adds a string to the reference table and return it's
 // numeric index.  If the string already exists then it simply returns
-// the existing index.
+// the existing index.  Only tables being written look for existing
+// strings, which spares tables read from a file an index of all their
+// strings.
 func (rt *RefTable) AddString(str string) int {
-	if rt.isWrite {
-		index, ok := rt.knownStrings[str]
-		if ok {
-			return index
-		}
+	if !rt.isWrite {
+		rt.indexedStrings = append(rt.indexedStrings, str)
+		return len(rt.indexedStrings) - 1
+	}
+	index, ok := rt.knownStrings[str]
+	if ok {
+		return index
 	}
 	rt.indexedStrings = append(rt.indexedStrings, str)
-	index := len(rt.indexedStrings) - 1
+	index = len(rt.indexedStrings) - 1
 	rt.knownStrings[str] = index
 	return index
 }
@@ -75,3 +86,63 @@ func (rt *RefTable) AddString(str string) int {
 func (rt *RefTable) Length() int {
 	return len(rt.indexedStrings)
 }
+
+// storedRefTable is the form in which a RefTable is serialized by
+// MarshalBinary.
+type storedRefTable struct {
+	Strings []string
+	IsWrite bool
+}
+
+// MarshalBinary serializes the RefTable, so that it can be restored by
+// UnmarshalBinary in another process, for example to split an export
+// across processes or to resume it while keeping the indices of the
+// strings already written.
+func (rt *RefTable) MarshalBinary() ([]byte, error) {
+	var buffer bytes.Buffer
+	stored := storedRefTable{Strings: rt.indexedStrings, IsWrite: rt.isWrite}
+	if err := gob.NewEncoder(&buffer).Encode(stored); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalBinary restores a RefTable serialized by MarshalBinary,
+// replacing the strings of the RefTable.
+func (rt *RefTable) UnmarshalBinary(data []byte) error {
+	var stored storedRefTable
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&stored); err != nil {
+		return err
+	}
+	rt.indexedStrings = stored.Strings
+	rt.isWrite = false
+	rt.knownStrings = make(map[string]int)
+	if stored.IsWrite {
+		rt.makeWritable()
+	}
+	return nil
+}
+
+// makeWritable makes AddString look for the strings already in the
+// RefTable, as it does for the tables being written.  A string found
+// more than once keeps its first index.
+func (rt *RefTable) makeWritable() {
+	if rt.isWrite {
+		return
+	}
+	if rt.knownStrings == nil {
+		rt.knownStrings = make(map[string]int)
+	}
+	for index := len(rt.indexedStrings) - 1; index >= 0; index-- {
+		rt.knownStrings[rt.indexedStrings[index]] = index
+	}
+	rt.isWrite = true
+}
+
+// SharedStrings returns the table of the strings shared by the cells
+// of a File read from a file, or nil if it has none.  Strings can be
+// looked up by index without being copied, see Cell.SharedStringIndex.
+// The table isn't used when saving the File and mustn't be changed.
+func (f *File) SharedStrings() *RefTable {
+	return f.referenceTable
+}
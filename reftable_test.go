@@ -123,3 +123,87 @@ func (s *RefTableSuite) TestRefTableWriteAddString(c *C) {
 	c.Assert(index2, Equals, 0)
 	c.Assert(refTable.ResolveSharedString(0), Equals, "Foo")
 }
+
+// Tables read from a file don't index their strings, and join the
+// runs of rich text strings.
+func (s *RefTableSuite) TestMakeSharedStringRefTableWithRuns(c *C) {
+	sst := &xlsxSST{SI: []xlsxSI{
+		{T: "Foo"},
+		{R: []xlsxR{{T: "Bold"}, {T: " and "}, {T: "italic"}}},
+		{T: "Foo"},
+	}}
+	reftable := MakeSharedStringRefTable(sst)
+	c.Assert(reftable.Length(), Equals, 3)
+	c.Assert(reftable.ResolveSharedString(1), Equals, "Bold and italic")
+	c.Assert(reftable.ResolveSharedString(2), Equals, "Foo")
+	c.Assert(reftable.knownStrings, HasLen, 0)
+}
+
+func (s *RefTableSuite) TestSharedStringIndex(c *C) {
+	f, err := OpenFile("./testdocs/testfile.xlsx")
+	c.Assert(err, IsNil)
+	table := f.SharedStrings()
+	c.Assert(table, NotNil)
+	cell := f.Sheets[0].Cell(0, 0)
+	index, ok := cell.SharedStringIndex()
+	c.Assert(ok, Equals, true)
+	c.Assert(table.ResolveSharedString(index), Equals, cell.Value)
+
+	cell.Value = "changed"
+	_, ok = cell.SharedStringIndex()
+	c.Assert(ok, Equals, false)
+	c.Assert(NewFile().SharedStrings(), IsNil)
+}
+
+// A RefTable can be serialized and restored, keeping the indices of
+// its strings.
+func (s *RefTableSuite) TestRefTableMarshalBinary(c *C) {
+	refTable := NewSharedStringRefTable()
+	refTable.isWrite = true
+	c.Assert(refTable.AddString("Foo"), Equals, 0)
+	c.Assert(refTable.AddString("Bar"), Equals, 1)
+	data, err := refTable.MarshalBinary()
+	c.Assert(err, IsNil)
+
+	restored := &RefTable{}
+	c.Assert(restored.UnmarshalBinary(data), IsNil)
+	c.Assert(restored.Length(), Equals, 2)
+	c.Assert(restored.AddString("Bar"), Equals, 1)
+	c.Assert(restored.AddString("Baz"), Equals, 2)
+
+	c.Assert(restored.UnmarshalBinary([]byte("not a table")), NotNil)
+}
+
+// Files saved with the same WriteRefTable use the same indices for
+// their strings.
+func (s *RefTableSuite) TestWriteRefTable(c *C) {
+	first := NewFile()
+	sheet, err := first.AddSheet("Part 1")
+	c.Assert(err, IsNil)
+	sheet.AddRow().AddCell().SetString("Foo")
+	first.WriteRefTable = NewSharedStringRefTable()
+	_, err = first.MarshallParts()
+	c.Assert(err, IsNil)
+	data, err := first.WriteRefTable.MarshalBinary()
+	c.Assert(err, IsNil)
+
+	second := NewFile()
+	sheet, err = second.AddSheet("Part 2")
+	c.Assert(err, IsNil)
+	row := sheet.AddRow()
+	row.AddCell().SetString("Bar")
+	row.AddCell().SetString("Foo")
+	second.WriteRefTable = &RefTable{}
+	c.Assert(second.WriteRefTable.UnmarshalBinary(data), IsNil)
+	parts, err := second.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<c r="A1" s="1" t="s"><v>1</v></c><c r="B1" s="1" t="s"><v>0</v></c>.*`)
+	c.Assert(parts["xl/sharedStrings.xml"], Matches, `(?s).*<si><t>Foo</t></si><si><t>Bar</t></si></sst>`)
+
+	var buffer bytes.Buffer
+	c.Assert(second.Write(&buffer), IsNil)
+	read, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(read.Sheets[0].Cell(0, 0).Value, Equals, "Bar")
+	c.Assert(read.Sheets[0].Cell(0, 1).Value, Equals, "Foo")
+}
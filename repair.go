@@ -0,0 +1,676 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	rootRelationshipsPart      = "_rels/.rels"
+	contentTypeRelationships   = "application/vnd.openxmlformats-package.relationships+xml"
+	contentTypeWorkbook        = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"
+	contentTypeMacroWorkbook   = "application/vnd.ms-excel.sheet.macroEnabled.main+xml"
+	contentTypeUnknownPart     = "application/octet-stream"
+	relationshipTypeVBAProject = "http://schemas.microsoft.com/office/2006/relationships/vbaProject"
+)
+
+// repairContentTypes are the content types of the parts Repair gives a
+// content type to, by the type of the relationship to them.
+var repairContentTypes = map[string]string{
+	relationshipTypeOfficeDocument:     contentTypeWorkbook,
+	relationshipTypeWorksheet:          "application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml",
+	relationshipTypeSharedStrings:      "application/vnd.openxmlformats-officedocument.spreadsheetml.sharedStrings+xml",
+	relationshipTypeStyles:             "application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml",
+	relationshipTypeTheme:              "application/vnd.openxmlformats-officedocument.theme+xml",
+	relationshipTypeCoreProperties:     "application/vnd.openxmlformats-package.core-properties+xml",
+	relationshipTypeExtendedProperties: "application/vnd.openxmlformats-officedocument.extended-properties+xml",
+	relationshipTypeCustomProperties:   contentTypeCustomProperties,
+	relationshipTypeDrawing:            contentTypeDrawing,
+	relationshipTypeComments:           contentTypeComments,
+	relationshipTypeVMLDrawing:         contentTypeVMLDrawing,
+	relationshipTypeThreadedComment:    "application/vnd.ms-excel.threadedcomments+xml",
+	relationshipTypePerson:             "application/vnd.ms-excel.person+xml",
+	relationshipTypeMetadata:           metadataContentType,
+	relationshipTypeVBAProject:         defaultRawContentTypes["bin"],
+	"http://schemas.openxmlformats.org/officeDocument/2006/relationships/chartsheet": "application/vnd.openxmlformats-officedocument.spreadsheetml.chartsheet+xml",
+	"http://schemas.openxmlformats.org/officeDocument/2006/relationships/chart":      "application/vnd.openxmlformats-officedocument.drawingml.chart+xml",
+	"http://schemas.openxmlformats.org/officeDocument/2006/relationships/table":      "application/vnd.openxmlformats-officedocument.spreadsheetml.table+xml",
+	"http://schemas.openxmlformats.org/officeDocument/2006/relationships/calcChain":  "application/vnd.openxmlformats-officedocument.spreadsheetml.calcChain+xml",
+}
+
+var (
+	// The patterns below find the elements Repair fixes in the text of
+	// the parts, so that everything else is kept as it is.  The elements
+	// of worksheets may have a namespace prefix.
+	repairRelationshipPattern = regexp.MustCompile(`<Relationship\b[^>]*?(?:/>|>\s*</Relationship\s*>)`)
+	repairSheetPattern        = regexp.MustCompile(`<(?:\w+:)?sheet\b[^>]*?(?:/>|>\s*</(?:\w+:)?sheet\s*>)`)
+	repairDefinedNamePattern  = regexp.MustCompile(`(?s)<(?:\w+:)?definedName\b[^>]*?(?:/>|>.*?</(?:\w+:)?definedName\s*>)`)
+	repairRowPattern          = regexp.MustCompile(`(?s)<(?:\w+:)?row\b[^>]*?(?:/>|>.*?</(?:\w+:)?row\s*>)`)
+	repairCellPattern         = regexp.MustCompile(`<(?:\w+:)?c\b[^>]*>`)
+	repairDimensionPattern    = regexp.MustCompile(`<(?:\w+:)?dimension\b[^>]*>`)
+	repairIdAttr              = repairAttrPattern(`Id`)
+	repairTypeAttr            = repairAttrPattern(`Type`)
+	repairTargetAttr          = repairAttrPattern(`Target`)
+	repairTargetModeAttr      = repairAttrPattern(`TargetMode`)
+	repairRelationshipIdAttr  = repairAttrPattern(`\w+:id`)
+	repairLocalSheetIdAttr    = repairAttrPattern(`localSheetId`)
+	repairRAttr               = repairAttrPattern(`r`)
+	repairRefAttr             = repairAttrPattern(`ref`)
+)
+
+// repairAttrPattern returns the pattern of the attribute name of an
+// element, whose value is its first or second submatch.
+func repairAttrPattern(name string) *regexp.Regexp {
+	return regexp.MustCompile(`\s` + name + `\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+}
+
+// repairAttr returns the value of the attribute of element matched by
+// pattern, unescaped, and false if it has none.
+func repairAttr(element string, pattern *regexp.Regexp) (string, bool) {
+	match := pattern.FindStringSubmatchIndex(element)
+	if match == nil {
+		return "", false
+	}
+	if match[2] >= 0 {
+		return html.UnescapeString(element[match[2]:match[3]]), true
+	}
+	return html.UnescapeString(element[match[4]:match[5]]), true
+}
+
+// setRepairAttr returns element with the value of the attribute matched
+// by pattern, which it has, replaced by value, or with the attribute
+// name added if it has none.
+func setRepairAttr(element string, pattern *regexp.Regexp, name, value string) string {
+	match := pattern.FindStringSubmatchIndex(element)
+	if match == nil {
+		end := strings.IndexAny(element, " \t\r\n/>")
+		return element[:end] + ` ` + name + `="` + value + `"` + element[end:]
+	}
+	start, end := match[2], match[3]
+	if start < 0 {
+		start, end = match[4], match[5]
+	}
+	return element[:start] + value + element[end:]
+}
+
+// RepairFix is a problem Repair found in a part of a file, and fixed.
+type RepairFix struct {
+	Part    string
+	Problem string
+}
+
+// String returns a description of the RepairFix.
+func (fix RepairFix) String() string {
+	return fix.Part + ": " + fix.Problem
+}
+
+// RepairReport lists what Repair fixed in a file.
+type RepairReport struct {
+	// Fixes are the problems fixed, in the order they were found.
+	Fixes []RepairFix
+	// RemovedParts are the parts left out of the repaired file, such as
+	// the relationships of a missing part.
+	RemovedParts []string
+}
+
+// Repaired returns true if anything at all was fixed.
+func (rr *RepairReport) Repaired() bool {
+	return len(rr.Fixes) > 0
+}
+
+// Repair reads the XLSX file of the given size from r, fixes the common
+// corruptions of the files written by other programs, and writes the
+// repaired file to w, along with a report of the fixes.  It fixes:
+//
+//   - part names using backslashes as separators, or starting with a
+//     slash,
+//   - a missing package relationships part or content types part, and
+//     parts missing from the content types,
+//   - relationships to missing parts, and the relationships parts of
+//     missing parts, which are removed along with the sheets of the
+//     workbook referring to them,
+//   - rows numbered the same as or lower than the row before them, which
+//     are numbered after it, along with the references of their cells,
+//     so that no row is lost,
+//   - dimensions of worksheets that aren't valid references or that
+//     don't cover all of the cells, which are set to the range of the
+//     cells.
+//
+// The parts are read and fixed as text, so that everything else is kept
+// as it is, the parts that aren't fixed being copied unchanged.  An
+// error is returned if the file can't be read as a zip file, or if it
+// has no workbook or no sheet left to repair.
+func Repair(r io.ReaderAt, size int64, w io.Writer) (*RepairReport, error) {
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	p := &repairPackage{
+		parts:    make(map[string][]byte),
+		files:    make(map[string]*zip.File),
+		relTypes: make(map[string]string),
+		report:   &RepairReport{},
+	}
+	if err := p.read(zipReader); err != nil {
+		return nil, err
+	}
+	workbook, err := p.repairRootRelationships()
+	if err != nil {
+		return nil, err
+	}
+	p.removeOrphanRelationships()
+	if err := p.removeOrphanSheets(workbook); err != nil {
+		return nil, err
+	}
+	for _, name := range p.names {
+		if p.relTypes[name] == relationshipTypeWorksheet {
+			p.repairWorksheet(name)
+		}
+	}
+	if err := p.repairContentTypes(workbook); err != nil {
+		return nil, err
+	}
+	return p.report, p.write(w)
+}
+
+// repairPackage holds the parts of a file being repaired.
+type repairPackage struct {
+	// names are the names of the parts, in the order they are written.
+	names []string
+	// parts holds the content of each part, and files the part of the
+	// file read of each part that is left unchanged.
+	parts map[string][]byte
+	files map[string]*zip.File
+	// relTypes holds the type of the relationship to each part.
+	relTypes map[string]string
+	report   *RepairReport
+}
+
+// fix adds a fix of the given part to the report.
+func (p *repairPackage) fix(part, format string, args ...interface{}) {
+	p.report.Fixes = append(p.report.Fixes, RepairFix{Part: part, Problem: fmt.Sprintf(format, args...)})
+}
+
+// has returns true if the package has a part with the given name.
+func (p *repairPackage) has(name string) bool {
+	_, ok := p.parts[name]
+	return ok
+}
+
+// set sets the content of the part with the given name, added after the
+// others if it is new.
+func (p *repairPackage) set(name string, data []byte) {
+	if !p.has(name) {
+		p.names = append(p.names, name)
+	}
+	p.parts[name] = data
+	delete(p.files, name)
+}
+
+// remove removes the part with the given name.
+func (p *repairPackage) remove(name string) {
+	for i, n := range p.names {
+		if n == name {
+			p.names = append(p.names[:i], p.names[i+1:]...)
+			break
+		}
+	}
+	delete(p.parts, name)
+	delete(p.files, name)
+	p.report.RemovedParts = append(p.report.RemovedParts, name)
+}
+
+// read reads the parts of zipReader, fixing the names using backslashes
+// or starting with a slash, and leaving out the directories and the parts given twice.
+func (p *repairPackage) read(zipReader *zip.Reader) error {
+	for _, f := range zipReader.File {
+		name := strings.TrimPrefix(strings.Replace(f.Name, `\`, "/", -1), "/")
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		if name != f.Name {
+			p.fix(name, "named %q", f.Name)
+		}
+		if p.has(name) {
+			p.fix(name, "given twice, the first one kept")
+			continue
+		}
+		data, err := readZipPart(f)
+		if err != nil {
+			return err
+		}
+		p.names = append(p.names, name)
+		p.parts[name] = data
+		if name == f.Name {
+			p.files[name] = f
+		}
+	}
+	return nil
+}
+
+// repairRootRelationships writes the package relationships part if it
+// is missing, and returns the name of the workbook part.
+func (p *repairPackage) repairRootRelationships() (string, error) {
+	if p.has(rootRelationshipsPart) {
+		for _, rel := range repairRelationshipPattern.FindAllString(string(p.parts[rootRelationshipsPart]), -1) {
+			if relType, _ := repairAttr(rel, repairTypeAttr); relType == relationshipTypeOfficeDocument {
+				target, _ := repairAttr(rel, repairTargetAttr)
+				if workbook, ok := p.resolve("", target); ok {
+					return workbook, nil
+				}
+			}
+		}
+	}
+	workbook := ""
+	for _, name := range p.names {
+		if name == workbookPart || (workbook == "" && path.Base(name) == "workbook.xml") {
+			workbook = name
+		}
+	}
+	if workbook == "" {
+		return "", fmt.Errorf("no workbook to repair")
+	}
+	rel := `<Relationship Id="%s" Type="` + relationshipTypeOfficeDocument + `" Target="` + workbook + `"/>`
+	if rels := string(p.parts[rootRelationshipsPart]); strings.Contains(rels, `</Relationships>`) {
+		usedIds := relationshipIds(rels)
+		nextId := 1
+		for usedIds["rId"+strconv.Itoa(nextId)] {
+			nextId++
+		}
+		p.fix(rootRelationshipsPart, "no relationship to the workbook %s, added", workbook)
+		p.set(rootRelationshipsPart, []byte(strings.Replace(rels, `</Relationships>`,
+			fmt.Sprintf(rel, "rId"+strconv.Itoa(nextId))+`</Relationships>`, 1)))
+		return workbook, nil
+	}
+	rels := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + fmt.Sprintf(rel, "rId1")
+	for i, part := range []struct{ name, relType string }{
+		{"docProps/core.xml", relationshipTypeCoreProperties},
+		{"docProps/app.xml", relationshipTypeExtendedProperties},
+	} {
+		if p.has(part.name) {
+			rels += `<Relationship Id="rId` + strconv.Itoa(i+2) + `" Type="` + part.relType + `" Target="` + part.name + `"/>`
+		}
+	}
+	if p.has(rootRelationshipsPart) {
+		p.fix(rootRelationshipsPart, "can't be read, rewritten")
+	} else {
+		p.fix(rootRelationshipsPart, "missing, written")
+	}
+	p.set(rootRelationshipsPart, []byte(rels+`</Relationships>`))
+	return workbook, nil
+}
+
+// resolve returns the name of the part target refers to from the
+// directory base, and false if there is no such part.
+func (p *repairPackage) resolve(base, target string) (string, bool) {
+	name := resolveTarget(base, target)
+	if p.has(name) {
+		return name, true
+	}
+	if unescaped, err := url.PathUnescape(name); err == nil && p.has(unescaped) {
+		return unescaped, true
+	}
+	return name, false
+}
+
+// relationshipsSource returns the name of the part whose relationships
+// are in the relationships part name, "" for the package, and false if
+// name isn't a relationships part.  The source of the package
+// relationships is ".".
+func relationshipsSource(name string) (string, bool) {
+	dir, base := path.Split(name)
+	if path.Base(dir) != "_rels" || !strings.HasSuffix(base, ".rels") {
+		return "", false
+	}
+	return path.Join(path.Dir(strings.TrimSuffix(dir, "/")), strings.TrimSuffix(base, ".rels")), true
+}
+
+// removeOrphanRelationships removes the relationships parts of missing
+// parts and the relationships to missing parts, and notes the type of
+// the relationships to the parts left.
+func (p *repairPackage) removeOrphanRelationships() {
+	for _, name := range append([]string(nil), p.names...) {
+		source, ok := relationshipsSource(name)
+		if !ok {
+			continue
+		}
+		if source != "." && !p.has(source) {
+			p.fix(name, "relationships of the missing part %s, removed", source)
+			p.remove(name)
+			continue
+		}
+		base := path.Dir(source)
+		if source == "." {
+			base = ""
+		}
+		rels := string(p.parts[name])
+		changed := false
+		rels = repairRelationshipPattern.ReplaceAllStringFunc(rels, func(rel string) string {
+			if mode, _ := repairAttr(rel, repairTargetModeAttr); mode == "External" {
+				return rel
+			}
+			target, _ := repairAttr(rel, repairTargetAttr)
+			relType, _ := repairAttr(rel, repairTypeAttr)
+			part, ok := p.resolve(base, target)
+			if !ok {
+				id, _ := repairAttr(rel, repairIdAttr)
+				p.fix(name, "relationship %s to the missing part %s, removed", id, part)
+				changed = true
+				return ""
+			}
+			p.relTypes[part] = relType
+			return rel
+		})
+		if changed {
+			p.set(name, []byte(rels))
+		}
+	}
+}
+
+// removeOrphanSheets removes the sheets of the workbook whose
+// relationship is missing, along with their defined names.
+func (p *repairPackage) removeOrphanSheets(workbook string) error {
+	relsName := path.Join(path.Dir(workbook), "_rels", path.Base(workbook)+".rels")
+	ids := make(map[string]bool)
+	for _, rel := range repairRelationshipPattern.FindAllString(string(p.parts[relsName]), -1) {
+		id, _ := repairAttr(rel, repairIdAttr)
+		ids[id] = true
+	}
+	content := string(p.parts[workbook])
+	var removed []int
+	index := 0
+	content = repairSheetPattern.ReplaceAllStringFunc(content, func(sheet string) string {
+		defer func() { index++ }()
+		if id, _ := repairAttr(sheet, repairRelationshipIdAttr); !ids[id] {
+			name, _ := repairAttr(sheet, repairAttrPattern(`name`))
+			p.fix(workbook, "sheet %q refers to the missing relationship %s, removed", name, id)
+			removed = append(removed, index)
+			return ""
+		}
+		return sheet
+	})
+	if len(removed) == 0 {
+		return nil
+	}
+	if len(removed) == index {
+		return fmt.Errorf("no sheet left to repair")
+	}
+	// The defined names local to the sheets removed are removed, and
+	// those of the sheets after them follow their index.
+	content = repairDefinedNamePattern.ReplaceAllStringFunc(content, func(definedName string) string {
+		value, ok := repairAttr(definedName, repairLocalSheetIdAttr)
+		if !ok {
+			return definedName
+		}
+		localSheetId, err := strconv.Atoi(value)
+		if err != nil {
+			return definedName
+		}
+		shift := 0
+		for _, i := range removed {
+			if i == localSheetId {
+				return ""
+			}
+			if i < localSheetId {
+				shift++
+			}
+		}
+		if shift == 0 {
+			return definedName
+		}
+		return setRepairAttr(definedName, repairLocalSheetIdAttr, "localSheetId", strconv.Itoa(localSheetId-shift))
+	})
+	p.set(workbook, []byte(content))
+	return nil
+}
+
+// repairWorksheet numbers the rows of the worksheet part name numbered
+// the same as or lower than the row before them after it, and sets its
+// dimension to the range of its cells if it isn't valid or doesn't
+// cover them.
+func (p *repairPackage) repairWorksheet(name string) {
+	content := string(p.parts[name])
+	changed := false
+	minCol, minRow, maxCol, maxRow := -1, -1, -1, -1
+	var repaired bytes.Buffer
+	last, prev := 0, 0
+	for _, match := range repairRowPattern.FindAllStringIndex(content, -1) {
+		row := content[match[0]:match[1]]
+		startTag := row[:strings.IndexByte(row, '>')+1]
+		number := prev + 1
+		if value, ok := repairAttr(startTag, repairRAttr); ok {
+			n, err := strconv.Atoi(value)
+			switch {
+			case err != nil || n < 1:
+				p.fix(name, "row numbered %q, numbered %d", value, number)
+			case n <= prev:
+				p.fix(name, "row %d after row %d, numbered %d", n, prev, number)
+			default:
+				number = n
+			}
+			if number != n && number <= Excel2006MaxRowCount {
+				row = p.renumberRow(row, startTag, number)
+				repaired.WriteString(content[last:match[0]])
+				repaired.WriteString(row)
+				last = match[1]
+				changed = true
+			}
+		}
+		prev = number
+		col := -1
+		for _, cell := range repairCellPattern.FindAllString(row, -1) {
+			col++
+			if ref, ok := repairAttr(cell, repairRAttr); ok {
+				if x, _, ok := parseCellID(ref); ok {
+					col = x
+				}
+			}
+			if minCol < 0 || col < minCol {
+				minCol = col
+			}
+			if col > maxCol {
+				maxCol = col
+			}
+			if minRow < 0 {
+				minRow = number - 1
+			}
+			maxRow = number - 1
+		}
+	}
+	if changed {
+		repaired.WriteString(content[last:])
+		content = repaired.String()
+	}
+	dimension := repairDimensionPattern.FindStringIndex(content)
+	if dimension != nil {
+		element := content[dimension[0]:dimension[1]]
+		ref, _ := repairAttr(element, repairRefAttr)
+		cellsRef := "A1"
+		if maxRow >= 0 {
+			cellsRef = GetCellIDStringFromCoords(minCol, minRow)
+			if maxCol != minCol || maxRow != minRow {
+				cellsRef += cellRangeChar + GetCellIDStringFromCoords(maxCol, maxRow)
+			}
+		}
+		if !dimensionCovers(ref, minCol, minRow, maxCol, maxRow) {
+			p.fix(name, "dimension %q, set to %q", ref, cellsRef)
+			content = content[:dimension[0]] + setRepairAttr(element, repairRefAttr, "ref", cellsRef) + content[dimension[1]:]
+			changed = true
+		}
+	}
+	if changed {
+		p.set(name, []byte(content))
+	}
+}
+
+// renumberRow returns the row element row, starting with startTag,
+// numbered number, along with the references of its cells.
+func (p *repairPackage) renumberRow(row, startTag string, number int) string {
+	rest := repairCellPattern.ReplaceAllStringFunc(row[len(startTag):], func(cell string) string {
+		ref, ok := repairAttr(cell, repairRAttr)
+		if !ok {
+			return cell
+		}
+		x, _, ok := parseCellID(ref)
+		if !ok {
+			return cell
+		}
+		return setRepairAttr(cell, repairRAttr, "r", GetCellIDStringFromCoords(x, number-1))
+	})
+	return setRepairAttr(startTag, repairRAttr, "r", strconv.Itoa(number)) + rest
+}
+
+// dimensionCovers returns true if ref is a valid dimension covering the
+// cells from minCol and minRow to maxCol and maxRow, which are -1 if
+// there is no cell.
+func dimensionCovers(ref string, minCol, minRow, maxCol, maxRow int) bool {
+	parts := strings.Split(ref, cellRangeChar)
+	if len(parts) > 2 {
+		return false
+	}
+	refMinCol, refMinRow, ok := parseCellID(parts[0])
+	if !ok {
+		return false
+	}
+	refMaxCol, refMaxRow := refMinCol, refMinRow
+	if len(parts) == 2 {
+		if refMaxCol, refMaxRow, ok = parseCellID(parts[1]); !ok {
+			return false
+		}
+	}
+	if refMaxCol < refMinCol || refMaxRow < refMinRow || refMaxCol >= Excel2006MaxColumnCount || refMaxRow >= Excel2006MaxRowCount {
+		return false
+	}
+	return maxRow < 0 || (refMinCol <= minCol && refMinRow <= minRow && refMaxCol >= maxCol && refMaxRow >= maxRow)
+}
+
+// repairContentTypes writes the content types part if it is missing or
+// can't be read, removes the overrides of missing parts, and adds those
+// of the parts with no content type.
+func (p *repairPackage) repairContentTypes(workbook string) error {
+	types := xlsxTypes{}
+	// changed is true if the part is to be written, and rewritten if it
+	// is written from scratch, the content types added then going
+	// without saying.
+	changed, rewritten := false, false
+	if data, ok := p.parts[contentTypesPart]; !ok {
+		p.fix(contentTypesPart, "missing, written")
+		changed, rewritten = true, true
+	} else if err := xml.Unmarshal(data, &types); err != nil {
+		p.fix(contentTypesPart, "can't be read, rewritten: %v", err)
+		types = xlsxTypes{}
+		changed, rewritten = true, true
+	}
+	defaults := make(map[string]string)
+	for _, d := range types.Defaults {
+		defaults[strings.ToLower(d.Extension)] = d.ContentType
+	}
+	for _, d := range []xlsxDefault{{"rels", contentTypeRelationships}, {"xml", "application/xml"}} {
+		if _, ok := defaults[d.Extension]; !ok {
+			types.Defaults = append(types.Defaults, d)
+			defaults[d.Extension] = d.ContentType
+			changed = true
+		}
+	}
+	partNames := make(map[string]string, len(p.names))
+	for _, name := range p.names {
+		partNames["/"+strings.ToLower(name)] = name
+	}
+	overrides := make(map[string]bool)
+	kept := types.Overrides[:0]
+	for _, override := range types.Overrides {
+		if _, ok := partNames[strings.ToLower(override.PartName)]; !ok {
+			p.fix(contentTypesPart, "content type of the missing part %s, removed", override.PartName)
+			changed = true
+			continue
+		}
+		overrides[strings.ToLower(override.PartName)] = true
+		kept = append(kept, override)
+	}
+	types.Overrides = kept
+	for _, name := range p.names {
+		if name == contentTypesPart || overrides["/"+strings.ToLower(name)] {
+			continue
+		}
+		ext := strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))
+		contentType, known := repairContentTypes[p.relTypes[name]]
+		if name == workbook {
+			contentType, known = contentTypeWorkbook, true
+			if p.has("xl/vbaProject.bin") {
+				contentType = contentTypeMacroWorkbook
+			}
+		}
+		if !known {
+			if _, ok := defaults[ext]; ok {
+				continue
+			}
+			if contentType, known = defaultRawContentTypes[ext]; !known && ext == "vml" {
+				contentType, known = contentTypeVMLDrawing, true
+			}
+			if known {
+				types.Defaults = append(types.Defaults, xlsxDefault{Extension: ext, ContentType: contentType})
+				defaults[ext] = contentType
+				if !rewritten {
+					p.fix(contentTypesPart, "no content type for the extension %q, added", ext)
+				}
+				changed = true
+				continue
+			}
+			contentType = contentTypeUnknownPart
+		}
+		if defaults[ext] == contentType {
+			continue
+		}
+		types.Overrides = append(types.Overrides, xlsxOverride{PartName: "/" + name, ContentType: contentType})
+		if !rewritten {
+			p.fix(contentTypesPart, "no content type for the part %s, added", name)
+		}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	data, err := xml.Marshal(types)
+	if err != nil {
+		return err
+	}
+	if !p.has(contentTypesPart) {
+		// The content types are written first, as by Excel.
+		p.names = append([]string{contentTypesPart}, p.names...)
+		p.parts[contentTypesPart] = nil
+	}
+	p.set(contentTypesPart, append([]byte(xml.Header), data...))
+	return nil
+}
+
+// write writes the parts of the package to w as a zip file, those left
+// unchanged being copied with their compression.
+func (p *repairPackage) write(w io.Writer) error {
+	zipWriter := zip.NewWriter(w)
+	for _, name := range p.names {
+		if f, ok := p.files[name]; ok {
+			if err := copyZipPart(zipWriter, f); err != nil {
+				return err
+			}
+			continue
+		}
+		partWriter, err := zipWriter.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+		if err != nil {
+			return err
+		}
+		if _, err := partWriter.Write(p.parts[name]); err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
@@ -0,0 +1,134 @@
+package xlsx
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type RepairSuite struct{}
+
+var _ = Suite(&RepairSuite{})
+
+// repair repairs the XLSX file data, and returns the repaired file,
+// opened, its parts and the report of the fixes.
+func repair(c *C, data []byte) (*File, map[string]string, *RepairReport) {
+	var buffer bytes.Buffer
+	report, err := Repair(bytes.NewReader(data), int64(len(data)), &buffer)
+	c.Assert(err, IsNil)
+	file, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	_, parts := readOptionsParts(c, buffer.Bytes())
+	return file, parts, report
+}
+
+// repairProblems returns the descriptions of the fixes of report, sorted,
+// since the parts of the files written by unknownPartsXLSX are in no
+// particular order.
+func repairProblems(report *RepairReport) []string {
+	var problems []string
+	for _, fix := range report.Fixes {
+		problems = append(problems, fix.String())
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+func (s *RepairSuite) TestRepairSoundFile(c *C) {
+	data := unknownPartsXLSX(c, nil)
+	_, parts, report := repair(c, data)
+	c.Assert(report.Repaired(), Equals, false)
+	c.Assert(report.RemovedParts, HasLen, 0)
+	_, original := readOptionsParts(c, data)
+	c.Assert(parts, DeepEquals, original)
+}
+
+func (s *RepairSuite) TestRepairMissingContentTypes(c *C) {
+	data := unknownPartsXLSX(c, func(parts map[string]string) {
+		delete(parts, "[Content_Types].xml")
+	})
+	file, parts, report := repair(c, data)
+	c.Assert(repairProblems(report), DeepEquals, []string{"[Content_Types].xml: missing, written"})
+	c.Assert(parts["[Content_Types].xml"], Matches, `(?s).*<Override PartName="/xl/worksheets/sheet1.xml" `+
+		`ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet\+xml"></Override>.*`)
+	c.Assert(parts["[Content_Types].xml"], Matches, `(?s).*<Override PartName="/xl/workbook.xml" `+
+		`ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main\+xml"></Override>.*`)
+	c.Assert(file.Sheet["Sheet1"].Cell(1, 0).Value, Equals, "second")
+}
+
+func (s *RepairSuite) TestRepairPartsMissingFromContentTypes(c *C) {
+	data := unknownPartsXLSX(c, func(parts map[string]string) {
+		parts["[Content_Types].xml"] = strings.Replace(parts["[Content_Types].xml"],
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"></Override>`, "", 1)
+		parts["xl/media/image1.png"] = "png"
+	})
+	_, parts, report := repair(c, data)
+	c.Assert(repairProblems(report), DeepEquals, []string{
+		`[Content_Types].xml: no content type for the extension "png", added`,
+		`[Content_Types].xml: no content type for the part xl/styles.xml, added`,
+	})
+	c.Assert(parts["[Content_Types].xml"], Matches, `(?s).*<Default Extension="png" ContentType="image/png"></Default>.*`)
+}
+
+func (s *RepairSuite) TestRepairDuplicateRowsAndDimension(c *C) {
+	data := unknownPartsXLSX(c, func(parts map[string]string) {
+		sheet := parts["xl/worksheets/sheet1.xml"]
+		sheet = strings.Replace(sheet, `<row r="2"><c r="A2"`, `<row r="1"><c r="A1"`, 1)
+		parts["xl/worksheets/sheet1.xml"] = strings.Replace(sheet, `<dimension ref="A1:A2">`, `<dimension ref="A1:A">`, 1)
+	})
+	file, parts, report := repair(c, data)
+	c.Assert(repairProblems(report), DeepEquals, []string{
+		`xl/worksheets/sheet1.xml: dimension "A1:A", set to "A1:A2"`,
+		`xl/worksheets/sheet1.xml: row 1 after row 1, numbered 2`,
+	})
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches,
+		`(?s).*<dimension ref="A1:A2">.*<row r="1"><c r="A1" s="1" t="s"><v>0</v></c></row><row r="2"><c r="A2" s="1" t="s"><v>1</v></c></row>.*`)
+	sheet := file.Sheet["Sheet1"]
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "first")
+	c.Assert(sheet.Cell(1, 0).Value, Equals, "second")
+}
+
+func (s *RepairSuite) TestRepairDimensionTooSmall(c *C) {
+	data := unknownPartsXLSX(c, func(parts map[string]string) {
+		parts["xl/worksheets/sheet1.xml"] = strings.Replace(parts["xl/worksheets/sheet1.xml"],
+			`<dimension ref="A1:A2">`, `<dimension ref="A1">`, 1)
+	})
+	file, _, report := repair(c, data)
+	c.Assert(repairProblems(report), DeepEquals, []string{`xl/worksheets/sheet1.xml: dimension "A1", set to "A1:A2"`})
+	c.Assert(file.Sheet["Sheet1"].MaxRow, Equals, 2)
+}
+
+func (s *RepairSuite) TestRepairOrphanRelationships(c *C) {
+	data := unknownPartsXLSX(c, func(parts map[string]string) {
+		parts["xl/_rels/workbook.xml.rels"] = strings.Replace(parts["xl/_rels/workbook.xml.rels"], "</Relationships>",
+			`<Relationship Id="rId9" Target="worksheets/sheet2.xml" Type="`+relationshipTypeWorksheet+`"/></Relationships>`, 1)
+		parts["xl/workbook.xml"] = strings.Replace(parts["xl/workbook.xml"], "</sheets><definedNames>",
+			`<sheet name="Lost" sheetId="2" r:id="rId9"/></sheets><definedNames>`+
+				`<definedName name="Area" localSheetId="1">Lost!$A$1</definedName>`, 1)
+		parts["xl/worksheets/_rels/sheet3.xml.rels"] = `<Relationships/>`
+		parts["xl\\media\\image1.png"] = "png"
+	})
+	file, parts, report := repair(c, data)
+	c.Assert(repairProblems(report), DeepEquals, []string{
+		`[Content_Types].xml: no content type for the extension "png", added`,
+		`xl/_rels/workbook.xml.rels: relationship rId9 to the missing part xl/worksheets/sheet2.xml, removed`,
+		`xl/media/image1.png: named "xl\\media\\image1.png"`,
+		`xl/workbook.xml: sheet "Lost" refers to the missing relationship rId9, removed`,
+		`xl/worksheets/_rels/sheet3.xml.rels: relationships of the missing part xl/worksheets/sheet3.xml, removed`,
+	})
+	c.Assert(report.RemovedParts, DeepEquals, []string{"xl/worksheets/_rels/sheet3.xml.rels"})
+	c.Assert(parts["xl/workbook.xml"], Not(Matches), `(?s).*(Lost|Area).*`)
+	c.Assert(file.Sheets, HasLen, 1)
+	c.Assert(file.Sheets[0].Name, Equals, "Sheet1")
+}
+
+func (s *RepairSuite) TestRepairNoSheetLeft(c *C) {
+	data := unknownPartsXLSX(c, func(parts map[string]string) {
+		delete(parts, "xl/worksheets/sheet1.xml")
+	})
+	var buffer bytes.Buffer
+	_, err := Repair(bytes.NewReader(data), int64(len(data)), &buffer)
+	c.Assert(err, ErrorMatches, "no sheet left to repair")
+}
@@ -0,0 +1,194 @@
+package xlsx
+
+import (
+	"strings"
+)
+
+// volatileFunctions are the worksheet functions that Excel
+// recalculates on every change to the workbook, regardless of whether
+// their inputs changed.
+var volatileFunctions = map[string]bool{
+	"CELL":        true,
+	"INDIRECT":    true,
+	"INFO":        true,
+	"NOW":         true,
+	"OFFSET":      true,
+	"RAND":        true,
+	"RANDARRAY":   true,
+	"RANDBETWEEN": true,
+	"TODAY":       true,
+}
+
+// Part name prefixes of external workbook links and macros.
+var (
+	externalLinkPartPrefixes = []string{"xl/externalLinks/"}
+	macroPartPrefixes        = []string{"xl/vbaProject.bin", "xl/macrosheets/", "xl/dialogsheets/"}
+)
+
+// FormulaFinding identifies a formula cell that was flagged by
+// AnalyzeRisks.
+type FormulaFinding struct {
+	Sheet   string
+	Cell    string
+	Formula string
+	// Functions are the flagged functions used by the formula, in
+	// upper case and without any _xlfn. prefix.
+	Functions []string
+}
+
+// RiskReport lists the content of a File that an automated intake
+// pipeline may want to treat with suspicion.
+type RiskReport struct {
+	// VolatileFormulas are the formulas calling volatile functions
+	// such as NOW, RAND or INDIRECT.
+	VolatileFormulas []FormulaFinding
+	// ExternalFormulas are the formulas referring to other workbooks.
+	ExternalFormulas []FormulaFinding
+	// ExternalLinkParts are the parts describing links to other
+	// workbooks.
+	ExternalLinkParts []string
+	// MacroParts are the parts holding VBA projects or macro sheets.
+	MacroParts []string
+}
+
+// HasMacros returns true if the analysed File contains macros.
+func (rr *RiskReport) HasMacros() bool {
+	return len(rr.MacroParts) > 0
+}
+
+// HasExternalLinks returns true if the analysed File refers to other
+// workbooks.
+func (rr *RiskReport) HasExternalLinks() bool {
+	return len(rr.ExternalLinkParts) > 0 || len(rr.ExternalFormulas) > 0
+}
+
+// IsRisky returns true if anything at all was flagged.
+func (rr *RiskReport) IsRisky() bool {
+	return rr.HasMacros() || rr.HasExternalLinks() || len(rr.VolatileFormulas) > 0
+}
+
+// AnalyzeRisks scans the formulas and parts of a File for volatile
+// functions, links to external workbooks and macros.
+func (f *File) AnalyzeRisks() *RiskReport {
+	rr := &RiskReport{}
+	for _, name := range f.RawPartNames() {
+		if hasAnyPrefix(name, externalLinkPartPrefixes) {
+			rr.ExternalLinkParts = append(rr.ExternalLinkParts, name)
+		} else if hasAnyPrefix(name, macroPartPrefixes) {
+			rr.MacroParts = append(rr.MacroParts, name)
+		}
+	}
+	for _, sheet := range f.Sheets {
+		for r, row := range sheet.rowList() {
+			if row == nil {
+				continue
+			}
+			row.load()
+			for c, cell := range row.Cells {
+				if cell == nil || cell.formula == "" {
+					continue
+				}
+				finding := FormulaFinding{
+					Sheet:   sheet.Name,
+					Cell:    GetCellIDStringFromCoords(c, r),
+					Formula: cell.formula,
+				}
+				functions, external := scanFormula(cell.formula)
+				for _, function := range functions {
+					if volatileFunctions[function] {
+						finding.Functions = append(finding.Functions, function)
+					}
+				}
+				if len(finding.Functions) > 0 {
+					rr.VolatileFormulas = append(rr.VolatileFormulas, finding)
+				}
+				if external {
+					finding.Functions = nil
+					rr.ExternalFormulas = append(rr.ExternalFormulas, finding)
+				}
+			}
+		}
+	}
+	return rr
+}
+
+// scanFormula returns the names of the functions called by a formula,
+// in upper case and with any _xlfn. or _xlws. prefix removed, and
+// whether the formula refers to another workbook.  String literals
+// are skipped, and table references such as Table1[Column] are told
+// apart from external references such as [1]Sheet1!A1.
+func scanFormula(formula string) (functions []string, external bool) {
+	var inString, inSheetName bool
+	var tableDepth int
+	start := -1
+	isNameChar := func(b byte) bool {
+		return b == '_' || b == '.' || (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9')
+	}
+	for i := 0; i < len(formula); i++ {
+		ch := formula[i]
+		switch {
+		case inString:
+			if ch == '"' {
+				inString = false
+			}
+			continue
+		case inSheetName:
+			if ch == '\'' {
+				inSheetName = false
+			}
+			continue
+		}
+		if isNameChar(ch) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if ch == '(' && start >= 0 && tableDepth == 0 {
+			name := strings.ToUpper(formula[start:i])
+			name = strings.TrimPrefix(name, "_XLFN.")
+			name = strings.TrimPrefix(name, "_XLWS.")
+			functions = append(functions, name)
+		}
+		switch ch {
+		case '"':
+			inString = true
+		case '\'':
+			inSheetName = true
+			if i+1 < len(formula) && formula[i+1] == '[' {
+				external = true
+			}
+		case '[':
+			if tableDepth == 0 && start < 0 && isExternalReference(formula[i:]) {
+				external = true
+				// Skip over the workbook index or name.
+				i += strings.IndexByte(formula[i:], ']')
+			} else {
+				tableDepth++
+			}
+		case ']':
+			if tableDepth > 0 {
+				tableDepth--
+			}
+		}
+		start = -1
+	}
+	return functions, external
+}
+
+// isExternalReference returns true if the formula starting at the
+// bracket ref is a reference to another workbook, its index or name in
+// brackets followed by a sheet, if any, and "!", as in "[1]Sheet1!A1".
+// A structured reference to a table, such as "[@Col]", isn't.
+func isExternalReference(ref string) bool {
+	end := strings.IndexByte(ref, ']')
+	if end < 2 || ref[1] == '@' || ref[1] == '#' || strings.IndexByte(ref[1:end], '[') >= 0 {
+		return false
+	}
+	i := end + 1
+	for i < len(ref) && (ref[i] == '_' || ref[i] == '.' || ref[i] >= '0' && ref[i] <= '9' ||
+		ref[i] >= 'A' && ref[i] <= 'Z' || ref[i] >= 'a' && ref[i] <= 'z' || ref[i] >= 0x80) {
+		i++
+	}
+	return i < len(ref) && ref[i] == '!'
+}
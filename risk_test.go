@@ -0,0 +1,69 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type RiskSuite struct{}
+
+var _ = Suite(&RiskSuite{})
+
+func (s *RiskSuite) TestScanFormula(c *C) {
+	testCases := []struct {
+		formula   string
+		functions []string
+		external  bool
+	}{
+		{formula: "SUM(A1:A3)", functions: []string{"SUM"}},
+		{formula: "IF(NOW()>A1,rand(),0)", functions: []string{"IF", "NOW", "RAND"}},
+		{formula: `CONCATENATE("TODAY()",B1)`, functions: []string{"CONCATENATE"}},
+		{formula: "_xlfn.RANDARRAY(3)", functions: []string{"RANDARRAY"}},
+		{formula: "SUM(Table1[[#This Row],[Amount]])", functions: []string{"SUM"}},
+		{formula: "[1]Sheet1!A1*2", external: true},
+		{formula: "SUM('[Budget.xlsx]Q1 Sales'!B2:B9)", functions: []string{"SUM"}, external: true},
+		{formula: "'Sheet (1)'!A1", functions: nil},
+		{formula: "[@Col]*2"},
+		{formula: "SUM([@A],[@B])", functions: []string{"SUM"}},
+		{formula: "[#This Row]"},
+		{formula: "[[#This Row],[Amount]]*IF([@Rate]>0,1,0)", functions: []string{"IF"}},
+		{formula: "[Amount]*2"},
+		{formula: "[1]!Rate*ROUND(A1,0)", functions: []string{"ROUND"}, external: true},
+		{formula: "MAX([Budget.xlsx]Q1!B2,[@Max])", functions: []string{"MAX"}, external: true},
+	}
+	for _, testCase := range testCases {
+		functions, external := scanFormula(testCase.formula)
+		c.Assert(functions, DeepEquals, testCase.functions)
+		c.Assert(external, Equals, testCase.external)
+	}
+}
+
+func (s *RiskSuite) TestAnalyzeRisks(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	row := sheet.AddRow()
+	row.AddCell().SetFormula("SUM(B1:B2)")
+	row.AddCell().SetFormula("INDIRECT(\"A\"&ROW())+OFFSET(A1,1,1)")
+	row.AddCell().SetFormula("[2]Rates!A1")
+
+	rr := file.AnalyzeRisks()
+	c.Assert(rr.VolatileFormulas, HasLen, 1)
+	c.Assert(rr.VolatileFormulas[0].Cell, Equals, "B1")
+	c.Assert(rr.VolatileFormulas[0].Functions, DeepEquals, []string{"INDIRECT", "OFFSET"})
+	c.Assert(rr.ExternalFormulas, HasLen, 1)
+	c.Assert(rr.ExternalFormulas[0].Cell, Equals, "C1")
+	c.Assert(rr.HasExternalLinks(), Equals, true)
+	c.Assert(rr.HasMacros(), Equals, false)
+	c.Assert(rr.IsRisky(), Equals, true)
+}
+
+func (s *RiskSuite) TestAnalyzeRisksFindsMacrosAndLinks(c *C) {
+	file := addPartsToXLSX(c, "./testdocs/testfile.xlsx", map[string]string{
+		"xl/vbaProject.bin":                  "",
+		"xl/externalLinks/externalLink1.xml": "<externalLink/>",
+	})
+	rr := file.AnalyzeRisks()
+	c.Assert(rr.MacroParts, DeepEquals, []string{"xl/vbaProject.bin"})
+	c.Assert(rr.ExternalLinkParts, DeepEquals, []string{"xl/externalLinks/externalLink1.xml"})
+	c.Assert(rr.IsRisky(), Equals, true)
+}
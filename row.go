@@ -7,19 +7,34 @@ type Row struct {
 	Height       float64
 	OutlineLevel uint8
 	isCustom     bool
+	// storeKey is the key of the cells of the Row in the cell store
+	// of its Sheet, where they are while evicted is true.
+	storeKey string
+	evicted  bool
+	// storedSum is the checksum of the cells stored under storeKey,
+	// and loads the number of times they were loaded back.
+	storedSum uint64
+	loads     int
+	// snapshot is shared by the snapshots of the Sheet made since the
+	// Row last changed, which copy it before it changes again.
+	snapshot *rowSnapshot
 }
 
 func (r *Row) SetHeight(ht float64) {
+	r.copyForSnapshots()
 	r.Height = ht
 	r.isCustom = true
 }
 
 func (r *Row) SetHeightCM(ht float64) {
+	r.copyForSnapshots()
 	r.Height = ht * 28.3464567 // Convert CM to postscript points
 	r.isCustom = true
 }
 
 func (r *Row) AddCell() *Cell {
+	r.load()
+	r.copyForSnapshots()
 	cell := NewCell(r)
 	r.Cells = append(r.Cells, cell)
 	r.Sheet.maybeAddCol(len(r.Cells))
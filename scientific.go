@@ -0,0 +1,225 @@
+package xlsx
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// FractionNumberFormat returns the number format showing numbers as a
+// whole part and a fraction whose denominator has at most the given
+// number of digits, from 1 to 3: "# ?/?", "# ??/??" or "# ???/???".
+func FractionNumberFormat(digits int) (string, error) {
+	if digits < 1 || digits > 3 {
+		return "", fmt.Errorf("fractions have denominators of 1 to 3 digits, not %d", digits)
+	}
+	placeholders := strings.Repeat("?", digits)
+	return "# " + placeholders + "/" + placeholders, nil
+}
+
+// FractionNumberFormatWithDenominator returns the number format showing
+// numbers as a whole part and a fraction with the given denominator,
+// such as "# ?/8" for eighths or "# ??/100" for hundredths.
+func FractionNumberFormatWithDenominator(denominator int) (string, error) {
+	if denominator < 2 || denominator > 999 {
+		return "", fmt.Errorf("fractions have denominators from 2 to 999, not %d", denominator)
+	}
+	d := strconv.Itoa(denominator)
+	return "# " + strings.Repeat("?", len(d)) + "/" + d, nil
+}
+
+// ExponentNumberFormat returns the number format showing numbers in
+// scientific notation with the given number of decimals, such as
+// "0.00E+00" for 2 decimals.  With engineering true, the exponent is
+// a multiple of 3 instead, such as in "##0.0E+0", so that 12345 shows
+// as 12.3E+3.
+func ExponentNumberFormat(decimals int, engineering bool) (string, error) {
+	if decimals < 0 || decimals > 30 {
+		return "", fmt.Errorf("number formats have 0 to 30 decimals, not %d", decimals)
+	}
+	mantissa := "0"
+	if engineering {
+		mantissa = "##0"
+	}
+	if decimals > 0 {
+		mantissa += "." + strings.Repeat("0", decimals)
+	}
+	if engineering {
+		return mantissa + "E+0", nil
+	}
+	return mantissa + "E+00", nil
+}
+
+// isLargeInteger returns true if value is an integer of 12 digits or
+// more, at least 1e11, which the general number format shows in
+// scientific notation, see File.PlainLargeIntegers.
+func isLargeInteger(value string) bool {
+	digits := strings.TrimPrefix(value, "-")
+	if len(digits) < 12 || digits[0] == '0' {
+		return false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// plainIntegerNumFmt returns the number format a numeric cell with the
+// given value and number format is written with: "0" for the large
+// integers in the general format when the File has PlainLargeIntegers,
+// numFmt otherwise.
+func (f *File) plainIntegerNumFmt(value, numFmt string) string {
+	if f == nil || !f.PlainLargeIntegers || !isLargeInteger(value) {
+		return numFmt
+	}
+	if numFmt != "" && !strings.EqualFold(numFmt, builtInNumFmt[builtInNumFmtIndex_GENERAL]) {
+		return numFmt
+	}
+	return builtInNumFmt[builtInNumFmtIndex_INT]
+}
+
+// isFractionNumerator returns true if format starts with the
+// numerator of a fraction, such as "??/" in "??/??".
+func isFractionNumerator(format string) bool {
+	rest := strings.TrimLeft(format, "#0?")
+	return len(rest) < len(format) && strings.HasPrefix(rest, "/")
+}
+
+// formatFraction formats value with a reduced fraction format, such as
+// "# ?/?", "?/8" or "0 ??/??".  As in Excel, the question marks of the
+// numerator and denominator are padded with spaces so that fractions
+// line up, and a whole part of zero is left out unless it is written
+// with a 0.
+func formatFraction(value float64, format string) string {
+	slash := strings.Index(format, "/")
+	numeratorFormat, denominatorFormat := format[:slash], format[slash+1:]
+	wholeFormat := ""
+	if space := strings.LastIndex(numeratorFormat, " "); space >= 0 {
+		wholeFormat = strings.TrimSpace(numeratorFormat[:space])
+		numeratorFormat = numeratorFormat[space+1:]
+	}
+
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	whole := 0.0
+	if wholeFormat != "" {
+		whole = math.Floor(value)
+		value -= whole
+	}
+	var numerator, denominator int
+	if fixed, err := strconv.Atoi(denominatorFormat); err == nil && fixed > 0 {
+		denominator = fixed
+		numerator = int(math.Floor(value*float64(denominator) + 0.5))
+	} else {
+		numerator, denominator = approximateFraction(value, int(math.Pow10(len(denominatorFormat)))-1)
+	}
+	if wholeFormat != "" && numerator == denominator {
+		whole++
+		numerator = 0
+	}
+
+	width := func(placeholders string) int {
+		if strings.Contains(placeholders, "?") {
+			return len(placeholders)
+		}
+		return 0
+	}
+	var fraction string
+	if numerator == 0 && wholeFormat != "" {
+		// Only the whole part is shown, the fraction being replaced
+		// by as many spaces as it would take.
+		fraction = strings.Repeat(" ", width(numeratorFormat)+1+width(denominatorFormat))
+	} else {
+		fraction = fmt.Sprintf("%*d/%-*d", width(numeratorFormat), numerator, width(denominatorFormat), denominator)
+	}
+	switch {
+	case wholeFormat == "":
+		return sign + fraction
+	case whole == 0 && numerator == 0:
+		return "0 " + fraction
+	case whole == 0 && !strings.Contains(wholeFormat, "0"):
+		return sign + " " + fraction
+	}
+	return sign + strconv.FormatFloat(whole, 'f', 0, 64) + " " + fraction
+}
+
+// approximateFraction returns the fraction closest to value, which is
+// at least 0, whose denominator is at most maxDenominator, choosing the
+// smallest denominator among equally close fractions.
+func approximateFraction(value float64, maxDenominator int) (int, int) {
+	bestNumerator, bestDenominator := int(math.Floor(value+0.5)), 1
+	bestError := math.Abs(value - float64(bestNumerator))
+	for denominator := 2; denominator <= maxDenominator && bestError > 0; denominator++ {
+		numerator := int(math.Floor(value*float64(denominator) + 0.5))
+		if err := math.Abs(value - float64(numerator)/float64(denominator)); err < bestError {
+			bestNumerator, bestDenominator, bestError = numerator, denominator, err
+		}
+	}
+	return bestNumerator, bestDenominator
+}
+
+// exponentIndex returns the index of the exponent of a reduced format
+// in scientific notation, such as "E+" in "0.00E+00", or -1.
+func exponentIndex(format string) int {
+	for i := 0; i+1 < len(format); i++ {
+		if (format[i] == 'E' || format[i] == 'e') && (format[i+1] == '+' || format[i+1] == '-') {
+			return i
+		}
+	}
+	return -1
+}
+
+// formatExponent formats value with a reduced format in scientific
+// notation, such as "0.00E+00".  When the mantissa has more than one
+// integer digit, such as in the engineering format "##0.0E+0", the
+// exponent is a multiple of their number.  The exponent shows its sign
+// if the format has E+, only when negative if it has E-.
+func formatExponent(value float64, format string) string {
+	e := exponentIndex(format)
+	mantissaFormat, exponentFormat := format[:e], format[e+2:]
+	integerFormat, decimalFormat := mantissaFormat, ""
+	if dot := strings.Index(mantissaFormat, "."); dot >= 0 {
+		integerFormat, decimalFormat = mantissaFormat[:dot], mantissaFormat[dot+1:]
+	}
+	group := len(strings.Replace(integerFormat, ",", "", -1))
+	if group < 1 {
+		group = 1
+	}
+	decimals := len(decimalFormat)
+
+	exponent := 0
+	mantissa := value
+	if value != 0 {
+		exponent = int(math.Floor(math.Log10(math.Abs(value))))
+		exponent = int(math.Floor(float64(exponent)/float64(group))) * group
+		mantissa = value / math.Pow10(exponent)
+		// Rounding may carry the mantissa over to the next power.
+		rounded, _ := strconv.ParseFloat(strconv.FormatFloat(mantissa, 'f', decimals, 64), 64)
+		if math.Abs(rounded) >= math.Pow10(group) {
+			exponent += group
+			mantissa = value / math.Pow10(exponent)
+		}
+	}
+	formatted := strconv.FormatFloat(mantissa, 'f', decimals, 64)
+	// Optional decimals, written #, are dropped when they are zeros.
+	if optional := len(decimalFormat) - len(strings.TrimRight(decimalFormat, "#")); optional > 0 {
+		kept := len(formatted) - optional
+		formatted = formatted[:kept] + strings.TrimRight(formatted[kept:], "0")
+		formatted = strings.TrimSuffix(formatted, ".")
+	}
+
+	exponentSign := ""
+	if exponent < 0 {
+		exponentSign = "-"
+		exponent = -exponent
+	} else if format[e+1] == '+' {
+		exponentSign = "+"
+	}
+	return fmt.Sprintf("%s%c%s%0*d", formatted, format[e], exponentSign, len(exponentFormat), exponent)
+}
@@ -0,0 +1,121 @@
+package xlsx
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type ScientificSuite struct{}
+
+var _ = Suite(&ScientificSuite{})
+
+func (s *ScientificSuite) TestFractionNumberFormat(c *C) {
+	format, err := FractionNumberFormat(1)
+	c.Assert(err, IsNil)
+	c.Assert(format, Equals, builtInNumFmt[12])
+	format, err = FractionNumberFormat(2)
+	c.Assert(err, IsNil)
+	c.Assert(format, Equals, builtInNumFmt[13])
+	format, err = FractionNumberFormat(3)
+	c.Assert(err, IsNil)
+	c.Assert(format, Equals, "# ???/???")
+	_, err = FractionNumberFormat(4)
+	c.Assert(err, ErrorMatches, "fractions have denominators of 1 to 3 digits, not 4")
+
+	format, err = FractionNumberFormatWithDenominator(8)
+	c.Assert(err, IsNil)
+	c.Assert(format, Equals, "# ?/8")
+	format, err = FractionNumberFormatWithDenominator(100)
+	c.Assert(err, IsNil)
+	c.Assert(format, Equals, "# ???/100")
+	_, err = FractionNumberFormatWithDenominator(1)
+	c.Assert(err, NotNil)
+}
+
+func (s *ScientificSuite) TestExponentNumberFormat(c *C) {
+	format, err := ExponentNumberFormat(2, false)
+	c.Assert(err, IsNil)
+	c.Assert(format, Equals, "0.00E+00")
+	format, err = ExponentNumberFormat(0, false)
+	c.Assert(err, IsNil)
+	c.Assert(format, Equals, "0E+00")
+	format, err = ExponentNumberFormat(1, true)
+	c.Assert(err, IsNil)
+	c.Assert(format, Equals, "##0.0E+0")
+	_, err = ExponentNumberFormat(-1, true)
+	c.Assert(err, NotNil)
+}
+
+// The values are those shown by Excel.
+func (s *ScientificSuite) TestFormattedValue(c *C) {
+	cases := []struct {
+		format   string
+		value    float64
+		expected string
+	}{
+		{"# ?/?", 1.5, "1 1/2"},
+		{"# ?/?", 0.75, " 3/4"},
+		{"# ?/?", -2.25, "-2 1/4"},
+		{"# ?/?", 3, "3    "},
+		{"# ?/?", 0, "0    "},
+		{"# ?/?", 1.99, "2    "},
+		{"# ??/??", 3.14159, "3 14/99"},
+		{"# ??/??", 0.3333, "  1/3 "},
+		{"# ???/???", 3.14159, "3  16/113"},
+		{"0 ?/?", 0.5, "0 1/2"},
+		{"?/?", 1.5, "3/2"},
+		{"# ?/8", 2.3, "2 2/8"},
+		{"# ??/16", 0.5, "  8/16"},
+		{"# ??/100", 1.07, "1  7/100"},
+		{"0.00E+00", 12345.678, "1.23E+04"},
+		{"0.00E+00", -0.000123, "-1.23E-04"},
+		{"0.00E+00", 0, "0.00E+00"},
+		{"0.00E+00", 9.999, "1.00E+01"},
+		{"0E-00", 12345, "1E04"},
+		{"0.0#E+0", 1.5, "1.5E+0"},
+		{"##0.0E+0", 12345, "12.3E+3"},
+		{"##0.0E+0", 123456, "123.5E+3"},
+		{"##0.0E+0", 0.00123, "1.2E-3"},
+		{"##0.0E+0", 999999, "1.0E+6"},
+		{"##0.00E+00", 4.7e-8, "47.00E-09"},
+	}
+	cell := &Cell{}
+	for _, tc := range cases {
+		cell.SetFloatWithFormat(tc.value, tc.format)
+		value, err := cell.FormattedValue()
+		c.Assert(err, IsNil)
+		c.Assert(value, Equals, tc.expected)
+	}
+}
+
+func (s *ScientificSuite) TestPlainLargeIntegers(c *C) {
+	f := NewFile()
+	f.PlainLargeIntegers = true
+	sheet, err := f.AddSheet("Orders")
+	c.Assert(err, IsNil)
+	row := sheet.AddRow()
+	row.AddCell().SetInt64(123456789012)
+	row.AddCell().SetInt64(-98765432109876)
+	row.AddCell().SetInt(12345678901)
+	row.AddCell().SetFloat(123456789012.5)
+	cell := row.AddCell()
+	cell.SetInt64(123456789012)
+	cell.NumFmt = "#,##0"
+	var buffer bytes.Buffer
+	c.Assert(f.Write(&buffer), IsNil)
+
+	f, err = OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	cells := f.Sheets[0].Rows[0].Cells
+	c.Assert(cells[0].NumFmt, Equals, "0")
+	c.Assert(cells[0].Type(), Equals, CellTypeNumeric)
+	c.Assert(cells[1].NumFmt, Equals, "0")
+	c.Assert(cells[2].NumFmt, Equals, "general")
+	c.Assert(cells[3].NumFmt, Equals, "general")
+	c.Assert(cells[4].NumFmt, Equals, "#,##0")
+
+	c.Assert(isLargeInteger("100000000000"), Equals, true)
+	c.Assert(isLargeInteger("099999999999"), Equals, false)
+	c.Assert(isLargeInteger("1e+11"), Equals, false)
+}
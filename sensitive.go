@@ -0,0 +1,50 @@
+package xlsx
+
+import "fmt"
+
+// Sheet protection keeps the readers of a workbook from editing a
+// sheet, but not from reading it: the cells of a protected sheet are
+// stored in plain text and any program reading the file can see them.
+// Only encrypting the whole workbook with a password keeps them from
+// being read, as Excel can't encrypt a single sheet.  Sheets holding
+// personal data are better moved to a workbook of their own, which
+// can then be encrypted without getting in the way of the readers of
+// the rest of the data.
+//
+// Marking a Sheet as Sensitive is a guardrail for the developers
+// handling such data: a File holding a Sensitive sheet that isn't
+// protected with a password can't be saved.
+
+// SensitiveSheetError is returned when saving a File holding a
+// Sensitive sheet that isn't protected with a password.
+type SensitiveSheetError struct {
+	Sheet string
+}
+
+// Error returns a description of the SensitiveSheetError.
+func (e *SensitiveSheetError) Error() string {
+	return fmt.Sprintf("the sheet %q is sensitive and must be protected with a password", e.Sheet)
+}
+
+// SensitiveSheets returns the names of the Sensitive sheets of the
+// File.
+func (f *File) SensitiveSheets() []string {
+	var names []string
+	for _, sheet := range f.Sheets {
+		if sheet.Sensitive {
+			names = append(names, sheet.Name)
+		}
+	}
+	return names
+}
+
+// checkSensitiveSheets returns a SensitiveSheetError for the first
+// Sensitive sheet of the File that isn't protected with a password.
+func (f *File) checkSensitiveSheets() error {
+	for _, sheet := range f.Sheets {
+		if sheet.Sensitive && (sheet.Protection == nil || !sheet.Protection.HasPassword()) {
+			return &SensitiveSheetError{Sheet: sheet.Name}
+		}
+	}
+	return nil
+}
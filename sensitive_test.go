@@ -0,0 +1,40 @@
+package xlsx
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type SensitiveSuite struct{}
+
+var _ = Suite(&SensitiveSuite{})
+
+func (s *SensitiveSuite) TestSensitiveSheets(c *C) {
+	f := NewFile()
+	public, err := f.AddSheet("Summary")
+	c.Assert(err, IsNil)
+	public.AddRow().AddCell().SetString("Total")
+	people, err := f.AddSheet("People")
+	c.Assert(err, IsNil)
+	people.AddRow().AddCell().SetString("Jane Doe")
+	c.Assert(f.SensitiveSheets(), HasLen, 0)
+	people.Sensitive = true
+	c.Assert(f.SensitiveSheets(), DeepEquals, []string{"People"})
+
+	var buffer bytes.Buffer
+	err = f.Write(&buffer)
+	c.Assert(err, NotNil)
+	sensitiveErr, ok := err.(*SensitiveSheetError)
+	c.Assert(ok, Equals, true)
+	c.Assert(sensitiveErr.Sheet, Equals, "People")
+	c.Assert(err.Error(), Equals, `the sheet "People" is sensitive and must be protected with a password`)
+
+	// A protection without a password isn't enough.
+	c.Assert(people.Protect(""), IsNil)
+	_, err = f.MarshallParts()
+	c.Assert(err, NotNil)
+
+	people.Protection.SetLegacyPassword("secret")
+	c.Assert(f.Write(&buffer), IsNil)
+}
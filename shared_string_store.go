@@ -0,0 +1,247 @@
+package xlsx
+
+import (
+	"bufio"
+	"container/list"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// SharedStringStore is the dictionary of the strings shared by the
+// cells of a File being saved, see File.SharedStringStore.  By default
+// the strings are kept in memory in a RefTable, other stores can keep
+// them on disk or only share some of them, for workbooks whose strings
+// don't fit in memory.
+type SharedStringStore interface {
+	// Index returns the index of str among the shared strings,
+	// adding str to them if needed.  It returns false if str is to
+	// be written inline in its cell instead.  A store failing to add
+	// str returns false, and reports its error from Each.
+	Index(str string) (int, bool)
+	// Length returns the number of shared strings.
+	Length() int
+	// Each calls fn with each of the shared strings, in the order of
+	// their index.
+	Each(fn func(str string) error) error
+	// Close releases the resources held by the store.
+	Close() error
+}
+
+// Index returns the index of str in the RefTable, adding str to it if
+// needed.  Strings are always shared.
+func (rt *RefTable) Index(str string) (int, bool) {
+	return rt.AddString(str), true
+}
+
+// Each calls fn with each of the strings of the RefTable.
+func (rt *RefTable) Each(fn func(str string) error) error {
+	for _, str := range rt.indexedStrings {
+		if err := fn(str); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close does nothing, the strings of a RefTable being released along
+// with it.
+func (rt *RefTable) Close() error {
+	return nil
+}
+
+// writeXLSXSST writes the shared strings part holding the strings of
+// store to w, one string at a time as store.Each reads them, so that
+// the strings are never all held in memory at once.
+func writeXLSXSST(w io.Writer, store SharedStringStore) error {
+	count := strconv.Itoa(store.Length())
+	_, err := io.WriteString(w, xml.Header+`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"`+
+		` count="`+count+`" uniqueCount="`+count+`">`)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	si := xml.StartElement{Name: xml.Name{Local: "si"}}
+	err = store.Each(func(str string) error {
+		return encoder.EncodeElement(xlsxSI{T: escapeXString(str)}, si)
+	})
+	if err != nil {
+		return err
+	}
+	if err := encoder.Flush(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, `</sst>`)
+	return err
+}
+
+// CappedSharedStringStore is a SharedStringStore keeping at most a
+// given number of strings in memory.  Once it is full, the strings that
+// aren't in it yet are written inline in their cells, which makes the
+// file larger but bounds the memory used by the dictionary.
+type CappedSharedStringStore struct {
+	table      *RefTable
+	maxStrings int
+}
+
+// NewCappedSharedStringStore creates a CappedSharedStringStore holding
+// at most maxStrings strings.
+func NewCappedSharedStringStore(maxStrings int) *CappedSharedStringStore {
+	table := NewSharedStringRefTable()
+	table.isWrite = true
+	return &CappedSharedStringStore{table: table, maxStrings: maxStrings}
+}
+
+// Index returns the index of str, or false if str isn't in the store
+// and the store is full.
+func (cs *CappedSharedStringStore) Index(str string) (int, bool) {
+	if index, ok := cs.table.knownStrings[str]; ok {
+		return index, true
+	}
+	if cs.table.Length() >= cs.maxStrings {
+		return 0, false
+	}
+	return cs.table.AddString(str), true
+}
+
+// Length returns the number of strings in the store.
+func (cs *CappedSharedStringStore) Length() int {
+	return cs.table.Length()
+}
+
+// Each calls fn with each of the strings in the store.
+func (cs *CappedSharedStringStore) Each(fn func(str string) error) error {
+	return cs.table.Each(fn)
+}
+
+// Close drops the strings of the store.
+func (cs *CappedSharedStringStore) Close() error {
+	cs.table = NewSharedStringRefTable()
+	cs.table.isWrite = true
+	return nil
+}
+
+// TempFileSharedStringStore is a SharedStringStore appending its
+// strings to a temporary file.  Only the most recently used strings
+// are looked up in memory, a string that is used again after dropping
+// out of them being added once more under a new index, which the
+// format allows.  Closing the store removes the file.
+type TempFileSharedStringStore struct {
+	file      *os.File
+	writer    *bufio.Writer
+	count     int
+	cacheSize int
+	// recent holds the most recently used strings first, as
+	// cachedStrings, and cache their elements by string.
+	recent *list.List
+	cache  map[string]*list.Element
+	err    error
+}
+
+// cachedString is a string of a TempFileSharedStringStore that can be
+// looked up in memory.
+type cachedString struct {
+	str   string
+	index int
+}
+
+// NewTempFileSharedStringStore creates a TempFileSharedStringStore
+// writing to a new temporary file in dir, or in the default directory
+// for temporary files if dir is empty, and looking up the cacheSize
+// most recently used strings in memory.
+func NewTempFileSharedStringStore(dir string, cacheSize int) (*TempFileSharedStringStore, error) {
+	if cacheSize < 1 {
+		return nil, fmt.Errorf("at least one string must be looked up in memory, not %d", cacheSize)
+	}
+	file, err := ioutil.TempFile(dir, "xlsx-strings-")
+	if err != nil {
+		return nil, err
+	}
+	return &TempFileSharedStringStore{
+		file:      file,
+		writer:    bufio.NewWriter(file),
+		cacheSize: cacheSize,
+		recent:    list.New(),
+		cache:     make(map[string]*list.Element),
+	}, nil
+}
+
+// Index returns the index of str, appending it to the file unless it
+// is among the most recently used strings.
+func (ts *TempFileSharedStringStore) Index(str string) (int, bool) {
+	if ts.err != nil {
+		return 0, false
+	}
+	if element, ok := ts.cache[str]; ok {
+		ts.recent.MoveToFront(element)
+		return element.Value.(*cachedString).index, true
+	}
+	var length [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(length[:], uint64(len(str)))
+	if _, err := ts.writer.Write(length[:n]); err != nil {
+		ts.err = err
+		return 0, false
+	}
+	if _, err := ts.writer.WriteString(str); err != nil {
+		ts.err = err
+		return 0, false
+	}
+	index := ts.count
+	ts.count++
+	ts.cache[str] = ts.recent.PushFront(&cachedString{str: str, index: index})
+	if ts.recent.Len() > ts.cacheSize {
+		oldest := ts.recent.Back()
+		ts.recent.Remove(oldest)
+		delete(ts.cache, oldest.Value.(*cachedString).str)
+	}
+	return index, true
+}
+
+// Length returns the number of strings in the store.
+func (ts *TempFileSharedStringStore) Length() int {
+	return ts.count
+}
+
+// Each reads the strings back from the file and calls fn with each of
+// them.
+func (ts *TempFileSharedStringStore) Each(fn func(str string) error) error {
+	if ts.err != nil {
+		return ts.err
+	}
+	if err := ts.writer.Flush(); err != nil {
+		return err
+	}
+	if _, err := ts.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	// The strings added later are appended to the end of the file.
+	defer ts.file.Seek(0, io.SeekEnd)
+	reader := bufio.NewReader(ts.file)
+	for i := 0; i < ts.count; i++ {
+		length, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return err
+		}
+		str := make([]byte, length)
+		if _, err := io.ReadFull(reader, str); err != nil {
+			return err
+		}
+		if err := fn(string(str)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes and removes the file of the store.
+func (ts *TempFileSharedStringStore) Close() error {
+	err := ts.file.Close()
+	if removeErr := os.Remove(ts.file.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
@@ -0,0 +1,129 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type SharedStringStoreSuite struct{}
+
+var _ = Suite(&SharedStringStoreSuite{})
+
+func (s *SharedStringStoreSuite) strings(c *C, store SharedStringStore) []string {
+	var result []string
+	c.Assert(store.Each(func(str string) error {
+		result = append(result, str)
+		return nil
+	}), IsNil)
+	return result
+}
+
+func (s *SharedStringStoreSuite) TestCappedSharedStringStore(c *C) {
+	store := NewCappedSharedStringStore(2)
+	index, shared := store.Index("Foo")
+	c.Assert(index, Equals, 0)
+	c.Assert(shared, Equals, true)
+	index, shared = store.Index("Bar")
+	c.Assert(index, Equals, 1)
+	c.Assert(shared, Equals, true)
+	_, shared = store.Index("Baz")
+	c.Assert(shared, Equals, false)
+	index, shared = store.Index("Foo")
+	c.Assert(index, Equals, 0)
+	c.Assert(shared, Equals, true)
+	c.Assert(store.Length(), Equals, 2)
+	c.Assert(s.strings(c, store), DeepEquals, []string{"Foo", "Bar"})
+	c.Assert(store.Close(), IsNil)
+	c.Assert(store.Length(), Equals, 0)
+}
+
+func (s *SharedStringStoreSuite) TestTempFileSharedStringStore(c *C) {
+	_, err := NewTempFileSharedStringStore(c.MkDir(), 0)
+	c.Assert(err, NotNil)
+
+	store, err := NewTempFileSharedStringStore(c.MkDir(), 2)
+	c.Assert(err, IsNil)
+	for _, str := range []string{"Foo", "Bar", "Foo", "Baz", "Bar"} {
+		_, shared := store.Index(str)
+		c.Assert(shared, Equals, true)
+	}
+	// Bar dropped out of the recently used strings when Baz was
+	// added, and is added again.
+	c.Assert(store.Length(), Equals, 4)
+	c.Assert(s.strings(c, store), DeepEquals, []string{"Foo", "Bar", "Baz", "Bar"})
+	index, _ := store.Index(strings.Repeat("long ", 100))
+	c.Assert(index, Equals, 4)
+	c.Assert(s.strings(c, store)[4], Equals, strings.Repeat("long ", 100))
+	c.Assert(store.Close(), IsNil)
+}
+
+func (s *SharedStringStoreSuite) TestFileSharedStringStore(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	row := sheet.AddRow()
+	row.AddCell().SetString("Foo")
+	row.AddCell().SetString(" Bar ")
+	row.AddCell().SetString("Foo")
+	f.SharedStringStore = NewCappedSharedStringStore(1)
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `<c r="B1" s="1" t="inlineStr"><is><t xml:space="preserve"> Bar </t></is></c>`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/sharedStrings.xml"], `count="1"`), Equals, true)
+
+	store, err := NewTempFileSharedStringStore(c.MkDir(), 10)
+	c.Assert(err, IsNil)
+	defer store.Close()
+	f.SharedStringStore = store
+	var buffer bytes.Buffer
+	c.Assert(f.Write(&buffer), IsNil)
+	read, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(read.Sheets[0].Cell(0, 0).Value, Equals, "Foo")
+	c.Assert(read.Sheets[0].Cell(0, 1).Value, Equals, " Bar ")
+	c.Assert(read.Sheets[0].Cell(0, 2).Value, Equals, "Foo")
+}
+
+// writtenSizesStore is a RefTable noting how much of the shared
+// strings part was written each time a string was read from it.
+type writtenSizesStore struct {
+	*RefTable
+	written *bytes.Buffer
+	sizes   []int
+}
+
+func (ws *writtenSizesStore) Each(fn func(str string) error) error {
+	return ws.RefTable.Each(func(str string) error {
+		err := fn(str)
+		ws.sizes = append(ws.sizes, ws.written.Len())
+		return err
+	})
+}
+
+func (s *SharedStringStoreSuite) TestWriteXLSXSST(c *C) {
+	table := NewSharedStringRefTable()
+	table.isWrite = true
+	var buffer bytes.Buffer
+	store := &writtenSizesStore{RefTable: table, written: &buffer}
+	for _, str := range []string{"Foo", " Bar ", "<&>", strings.Repeat("long ", 2000), "Baz"} {
+		store.Index(str)
+	}
+	c.Assert(writeXLSXSST(&buffer, store), IsNil)
+
+	// The strings are written as the store reads them, rather than
+	// once they have all been read.
+	c.Assert(store.sizes, HasLen, 5)
+	c.Assert(store.sizes[4] > 10000, Equals, true)
+	c.Assert(store.sizes[4] < buffer.Len(), Equals, true)
+
+	sst := xlsxSST{Count: 5, UniqueCount: 5}
+	for _, str := range table.indexedStrings {
+		sst.SI = append(sst.SI, xlsxSI{T: str})
+	}
+	body, err := xml.Marshal(sst)
+	c.Assert(err, IsNil)
+	c.Assert(buffer.String(), Equals, xml.Header+string(body))
+}
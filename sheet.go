@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 // Sheet is a high level structure intended to provide user access to
@@ -20,12 +21,71 @@ type Sheet struct {
 	SheetViews  []SheetView
 	SheetFormat SheetFormat
 	AutoFilter  *AutoFilter
+	Protection  *SheetProtection
+	// IgnoredErrors lists the cells for which Excel's error
+	// checking is turned off.
+	IgnoredErrors []*IgnoredError
+	// SparklineGroups holds the sparklines drawn in the cells of
+	// the Sheet.
+	SparklineGroups []*SparklineGroup
+	// Hyperlinks holds the links of the cells of the Sheet.
+	Hyperlinks []*Hyperlink
+	// Sensitive marks a Sheet holding personal or confidential data,
+	// which keeps the File from being saved unless the Sheet is
+	// protected with a password, see SensitiveSheetError.
+	Sensitive bool
+	// Properties holds the code name, tab color, outline and page
+	// setup settings of the Sheet.
+	Properties SheetProperties
+	// VeryHidden hides the Sheet, whether or not it is Hidden, so
+	// that it can't be shown from the menus of Excel but only by
+	// VBA code.
+	VeryHidden bool
+
+	// rangeStyles are the styles given to ranges of cells by
+	// SetRangeStyle, in the order they were given.
+	rangeStyles []rangeStyle
+	// The relationships, table parts, drawings and extensions read
+	// along with the Sheet, which are written back as they are so that
+	// parts such as slicers, images or notes survive a round trip.
+	rawRelationships []xlsxWorkbookRelation
+	rawTableParts    *xlsxTableParts
+	rawDrawing       *xlsxDrawing
+	rawLegacyDrawing *xlsxLegacyDrawing
+	rawExtensions    []xlsxExt
+
+	// preserved holds what the sheet read with WithPreserve has that
+	// isn't modelled.
+	preserved *preservedPart
+	// tracked holds what the sheet read WithChangeTracking was, see
+	// File.Changes.
+	tracked *trackedSheet
+
+	// cellStore holds the cells of the rows evicted from memory,
+	// see SetCellStore.
+	cellStore *sheetCellStore
+	// streamOutlineLevels is the number of outline levels of the rows
+	// streamed to the Sheet, see StreamSheetOptions.OutlineLevels,
+	// which aren't among its Rows.
+	streamOutlineLevels uint8
 }
 
 type SheetView struct {
 	Pane *Pane
+	// TopLeftCell is the cell in the top left corner of the view, such
+	// as "A40" to open the Sheet scrolled down to row 40, A1 if empty.
+	// With frozen panes, the scroll position is the TopLeftCell of the
+	// Pane instead.
+	TopLeftCell string
+	// ActiveCell is the cell selected when the Sheet is opened, A1 if
+	// empty.
+	ActiveCell string
 }
 
+// Pane divides the view of a Sheet.  XSplit and YSplit are the number
+// of columns and rows of frozen panes, or the position of the split of
+// split panes, from the top left corner of the view, in twentieths of a
+// point.  TopLeftCell is the first cell shown in the bottom right pane.
 type Pane struct {
 	XSplit      float64
 	YSplit      float64
@@ -44,42 +104,54 @@ type SheetFormat struct {
 type AutoFilter struct {
 	TopLeftCell     string
 	BottomRightCell string
+	// Columns holds the criteria applied to the columns of the
+	// range, see ApplyAutoFilter.
+	Columns []*FilterColumn
+	// Sort records the order the rows of the range are sorted in.
+	Sort []*SortCondition
 }
 
 // Add a new Row to a Sheet
 func (s *Sheet) AddRow() *Row {
 	row := &Row{Sheet: s}
-	s.Rows = append(s.Rows, row)
-	if len(s.Rows) > s.MaxRow {
-		s.MaxRow = len(s.Rows)
+	s.setRowList(append(s.rowList(), row))
+	if len(s.rowList()) > s.MaxRow {
+		s.MaxRow = len(s.rowList())
 	}
+	row.load()
 	return row
 }
 
 // Add a new Row to a Sheet at a specific index
 func (s *Sheet) AddRowAtIndex(index int) (*Row, error) {
-	if index < 0 || index > len(s.Rows) {
+	if index < 0 || index > len(s.rowList()) {
 		return nil, errors.New("AddRowAtIndex: index out of bounds")
 	}
 	row := &Row{Sheet: s}
-	s.Rows = append(s.Rows, nil)
+	rows := append(s.rowList(), nil)
 
-	if index < len(s.Rows) {
-		copy(s.Rows[index+1:], s.Rows[index:])
+	if index < len(rows) {
+		copy(rows[index+1:], rows[index:])
 	}
-	s.Rows[index] = row
-	if len(s.Rows) > s.MaxRow {
-		s.MaxRow = len(s.Rows)
+	rows[index] = row
+	s.setRowList(rows)
+	if len(rows) > s.MaxRow {
+		s.MaxRow = len(rows)
 	}
+	row.load()
 	return row, nil
 }
 
 // Removes a row at a specific index
 func (s *Sheet) RemoveRowAtIndex(index int) error {
-	if index < 0 || index >= len(s.Rows) {
+	rows := s.rowList()
+	if index < 0 || index >= len(rows) {
 		return errors.New("RemoveRowAtIndex: index out of bounds")
 	}
-	s.Rows = append(s.Rows[:index], s.Rows[index+1:]...)
+	if s.cellStore != nil && rows[index] != nil {
+		s.cellStore.forget(rows[index])
+	}
+	s.setRowList(append(rows[:index], rows[index+1:]...))
 	return nil
 }
 
@@ -90,7 +162,7 @@ func (s *Sheet) maybeAddRow(rowCount int) {
 		for i := 0; i < loopCnt; i++ {
 
 			row := &Row{Sheet: s}
-			s.Rows = append(s.Rows, row)
+			s.setRowList(append(s.rowList(), row))
 		}
 		s.MaxRow = rowCount
 	}
@@ -99,7 +171,7 @@ func (s *Sheet) maybeAddRow(rowCount int) {
 // Make sure we always have as many Rows as we do cells.
 func (s *Sheet) Row(idx int) *Row {
 	s.maybeAddRow(idx + 1)
-	return s.Rows[idx]
+	return s.row(idx)
 }
 
 // Make sure we always have as many Cols as we do cells.
@@ -141,11 +213,11 @@ func (s *Sheet) Col(idx int) *Col {
 func (sh *Sheet) Cell(row, col int) *Cell {
 
 	// If the user requests a row beyond what we have, then extend.
-	for len(sh.Rows) <= row {
+	for len(sh.rowList()) <= row {
 		sh.AddRow()
 	}
 
-	r := sh.Rows[row]
+	r := sh.row(row)
 	for len(r.Cells) <= col {
 		r.AddCell()
 	}
@@ -175,7 +247,8 @@ func (s *Sheet) SetColWidth(startcol, endcol int, width float64) error {
 func (s *Sheet) handleMerged() {
 	merged := make(map[string]*Cell)
 
-	for r, row := range s.Rows {
+	for r, row := range s.rowList() {
+		row.load()
 		for c, cell := range row.Cells {
 			if cell.HMerge > 0 || cell.VMerge > 0 {
 				coord := GetCellIDStringFromCoords(c, r)
@@ -201,7 +274,7 @@ func (s *Sheet) handleMerged() {
 }
 
 // Dump sheet to its XML representation, intended for internal use only
-func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxWorksheet {
+func (s *Sheet) makeXLSXSheet(refTable SharedStringStore, styles *xlsxStyleSheet) *xlsxWorksheet {
 	worksheet := newXlsxWorksheet()
 	xSheet := xlsxSheetData{}
 	maxRow := 0
@@ -229,6 +302,24 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 	if s.Selected {
 		worksheet.SheetViews.SheetView[0].TabSelected = true
 	}
+	if s.File != nil && s.File.NumbersCompatible {
+		makeNumbersSheetViews(worksheet)
+	}
+	for index, sheetView := range s.SheetViews {
+		xSheetView := &worksheet.SheetViews.SheetView[index]
+		if sheetView.TopLeftCell != "" {
+			xSheetView.TopLeftCell = sheetView.TopLeftCell
+		}
+		if sheetView.ActiveCell != "" && len(xSheetView.Selection) > 0 {
+			// The selection is in the pane that is active, if any.
+			selection := &xSheetView.Selection[0]
+			selection.ActiveCell = sheetView.ActiveCell
+			selection.SQRef = sheetView.ActiveCell
+			if xSheetView.Pane != nil && xSheetView.Pane.ActivePane != "" {
+				selection.Pane = xSheetView.Pane.ActivePane
+			}
+		}
+	}
 
 	if s.SheetFormat.DefaultRowHeight != 0 {
 		worksheet.SheetFormatPr.DefaultRowHeight = s.SheetFormat.DefaultRowHeight
@@ -296,7 +387,30 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 		}
 	}
 
-	for r, row := range s.Rows {
+	// The cell formats of the styles are looked up once per style and
+	// number format, since many cells often share a style, such as
+	// those given one by SetRangeStyle.
+	type styleXf struct {
+		style    *Style
+		numFmtId int
+	}
+	xfIds := make(map[styleXf]int)
+	cellXfId := func(style *Style, numFmtId int) int {
+		key := styleXf{style, numFmtId}
+		if XfId, ok := xfIds[key]; ok {
+			return XfId
+		}
+		XfId := handleStyleForXLSX(style, numFmtId, styles)
+		xfIds[key] = XfId
+		return XfId
+	}
+	generalNumFmtId := styles.newNumFmt(builtInNumFmt[builtInNumFmtIndex_GENERAL]).NumFmtId
+	blankXfId := func(style *Style) int {
+		return cellXfId(style, generalNumFmtId)
+	}
+
+	for r, row := range s.rowList() {
+		row.load()
 		if r > maxRow {
 			maxRow = r
 		}
@@ -307,6 +421,10 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 			xRow.Ht = fmt.Sprintf("%g", row.Height)
 		}
 		xRow.OutlineLevel = row.OutlineLevel
+		if style := s.rowRangeStyle(r); style != nil {
+			xRow.S = blankXfId(style)
+			xRow.CustomFormat = true
+		}
 		if row.OutlineLevel > maxLevelRow {
 			maxLevelRow = row.OutlineLevel
 		}
@@ -314,54 +432,31 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 			XfId := colsXfIdList[c]
 
 			// generate NumFmtId and add new NumFmt
-			xNumFmt := styles.newNumFmt(cell.NumFmt)
+			numFmt := cell.NumFmt
+			if cell.cellType == CellTypeNumeric {
+				numFmt = s.File.plainIntegerNumFmt(cell.Value, numFmt)
+			}
+			xNumFmt := styles.newNumFmt(numFmt)
 
 			style := cell.style
+			if style == nil {
+				style = s.rangeStyleAt(c, r)
+			}
 			if style != nil {
-				XfId = handleStyleForXLSX(style, xNumFmt.NumFmtId, styles)
-			} else if len(cell.NumFmt) > 0 && !compareFormatString(s.Cols[c].numFmt, cell.NumFmt) {
+				XfId = cellXfId(style, xNumFmt.NumFmtId)
+			} else if len(numFmt) > 0 && !compareFormatString(s.Cols[c].numFmt, numFmt) {
 				XfId = handleNumFmtIdForXLSX(xNumFmt.NumFmtId, styles)
 			}
 
+			if s.File.wrapsLineBreaks() && (cell.cellType == CellTypeString || cell.cellType == CellTypeInline) &&
+				strings.ContainsAny(cell.Value, "\r\n") {
+				XfId = styles.wrappedCellXf(XfId)
+			}
+
 			if c > maxCell {
 				maxCell = c
 			}
-			xC := xlsxC{
-				S: XfId,
-				R: GetCellIDStringFromCoords(c, r),
-			}
-			if cell.formula != "" {
-				xC.F = &xlsxF{Content: cell.formula}
-			}
-			switch cell.cellType {
-			case CellTypeInline:
-				// Inline strings are turned into shared strings since they are more efficient.
-				// This is what Excel does as well.
-				fallthrough
-			case CellTypeString:
-				if len(cell.Value) > 0 {
-					xC.V = strconv.Itoa(refTable.AddString(cell.Value))
-				}
-				xC.T = "s"
-			case CellTypeNumeric:
-				// Numeric is the default, so the type can be left blank
-				xC.V = cell.Value
-			case CellTypeBool:
-				xC.V = cell.Value
-				xC.T = "b"
-			case CellTypeError:
-				xC.V = cell.Value
-				xC.T = "e"
-			case CellTypeDate:
-				xC.V = cell.Value
-				xC.T = "d"
-			case CellTypeStringFormula:
-				xC.V = cell.Value
-				xC.T = "str"
-			default:
-				panic(errors.New("unknown cell type cannot be marshaled"))
-			}
-
+			xC := s.makeXLSXCell(cell, c, r, XfId, refTable)
 			xRow.C = append(xRow.C, xC)
 			if nil != cell.DataValidation {
 				if nil == worksheet.DataValidations {
@@ -386,9 +481,36 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 				worksheet.MergeCells.Cells = append(worksheet.MergeCells.Cells, mc)
 			}
 		}
+		if blank := s.blankRangeStyledCells(r, len(row.Cells), blankXfId); len(blank) > 0 {
+			xRow.C = append(xRow.C, blank...)
+			if c, _, _ := parseCellID(blank[len(blank)-1].R); c > maxCell {
+				maxCell = c
+			}
+		}
+		xSheet.Row = append(xSheet.Row, xRow)
+	}
+	// The rows past those of the sheet given a style are written with
+	// their blank cells.
+	for r := len(s.rowList()); r < s.rangeStyleRows(); r++ {
+		xRow := xlsxRow{R: r + 1, C: s.blankRangeStyledCells(r, 0, blankXfId)}
+		if style := s.rowRangeStyle(r); style != nil {
+			xRow.S = blankXfId(style)
+			xRow.CustomFormat = true
+		} else if len(xRow.C) == 0 {
+			continue
+		}
+		if len(xRow.C) > 0 {
+			if c, _, _ := parseCellID(xRow.C[len(xRow.C)-1].R); c > maxCell {
+				maxCell = c
+			}
+		}
+		maxRow = r
 		xSheet.Row = append(xSheet.Row, xRow)
 	}
 
+	if maxLevelRow < s.streamOutlineLevels {
+		maxLevelRow = s.streamOutlineLevels
+	}
 	// Update sheet format with the freshly determined max levels
 	s.SheetFormat.OutlineLevelCol = maxLevelCol
 	s.SheetFormat.OutlineLevelRow = maxLevelRow
@@ -401,8 +523,16 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 	}
 
 	if s.AutoFilter != nil {
-		worksheet.AutoFilter = &xlsxAutoFilter{Ref: fmt.Sprintf("%v:%v", s.AutoFilter.TopLeftCell, s.AutoFilter.BottomRightCell)}
+		worksheet.AutoFilter = s.AutoFilter.makeXLSXAutoFilter()
+		worksheet.SheetPr.FilterMode = len(s.AutoFilter.Columns) > 0
+	}
+
+	if s.Protection != nil {
+		worksheet.SheetProtection = s.Protection.makeXLSXSheetProtection()
+		worksheet.ProtectedRanges = s.Protection.makeXLSXProtectedRanges()
 	}
+	s.Properties.makeXLSXSheetPr(&worksheet.SheetPr)
+	worksheet.IgnoredErrors = makeXLSXIgnoredErrors(s.IgnoredErrors)
 
 	worksheet.SheetData = xSheet
 	dimension := xlsxDimension{}
@@ -414,6 +544,78 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 	return worksheet
 }
 
+// makeXLSXCell returns the c element of cell, at the zero based column
+// c and row r, with the style XfId, its strings shared by refTable.
+func (s *Sheet) makeXLSXCell(cell *Cell, c, r, XfId int, refTable SharedStringStore) xlsxC {
+	xC := xlsxC{
+		S:  XfId,
+		R:  GetCellIDStringFromCoords(c, r),
+		Cm: cell.cellMetadata,
+		Vm: cell.valueMetadata,
+	}
+	if s.File != nil && s.File.isCompatible() {
+		// Without metadata, dynamic arrays are written as
+		// array formulas.
+		xC.Cm = 0
+		xC.Vm = 0
+	}
+	wrapped := false
+	if cell.formula != "" {
+		formula := s.File.validUTF8(cell.formula)
+		if s.File != nil && s.File.FormulaErrors == FormulaErrorsWrapped && !isIfErrorFormula(formula) {
+			formula = IfErrorFormula(formula, s.File.FormulaErrorDefault)
+			wrapped = true
+		}
+		xC.F = &xlsxF{Content: formula, T: cell.formulaType, Ref: cell.formulaRef}
+	}
+	switch cell.cellType {
+	case CellTypeInline:
+		// Inline strings are turned into shared strings since they are more efficient.
+		// This is what Excel does as well.
+		fallthrough
+	case CellTypeString:
+		if len(cell.Value) > 0 {
+			value := s.File.cellText(cell.Value)
+			index, shared := refTable.Index(value)
+			if !shared {
+				xC.Is = &xlsxSI{T: escapeXString(value)}
+				xC.T = "inlineStr"
+				break
+			}
+			xC.V = strconv.Itoa(index)
+		} else if s.File != nil && s.File.NumbersCompatible {
+			// Numbers rejects shared strings without an
+			// index, so empty strings are left blank.
+			break
+		}
+		xC.T = "s"
+	case CellTypeNumeric:
+		// Numeric is the default, so the type can be left blank
+		xC.V = cell.Value
+	case CellTypeBool:
+		xC.V = cell.Value
+		xC.T = "b"
+	case CellTypeError:
+		xC.V = cell.Value
+		xC.T = "e"
+	case CellTypeDate:
+		xC.V = cell.Value
+		xC.T = "d"
+	case CellTypeStringFormula:
+		xC.V = cell.Value
+		xC.T = "str"
+	default:
+		panic(errors.New("unknown cell type cannot be marshaled"))
+	}
+	if wrapped && cell.cellType == CellTypeError {
+		// The error is no longer the result of the
+		// formula, which is left to be calculated.
+		xC.V = ""
+		xC.T = ""
+	}
+	return xC
+}
+
 func handleStyleForXLSX(style *Style, NumFmtId int, styles *xlsxStyleSheet) (XfId int) {
 	xFont, xFill, xBorder, xCellXf := style.makeXLSXStyleElements()
 	fontId := styles.addFont(xFont)
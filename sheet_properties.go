@@ -0,0 +1,67 @@
+package xlsx
+
+// SheetProperties holds the settings of a Sheet written to its sheetPr
+// element.  The zero value stands for the defaults of Excel.
+type SheetProperties struct {
+	// CodeName is the name by which VBA code refers to the sheet,
+	// which must be kept for the macros of a template bound to it
+	// to keep working.
+	CodeName string
+	// TabColor is the ARGB color of the tab of the sheet, such as
+	// "FFFF0000", empty for the default.
+	TabColor string
+	// SummaryRowsAbove puts the summary rows of outlined rows above
+	// their detail rather than below, and SummaryColumnsLeft puts
+	// the summary columns of outlined columns to the left of their
+	// detail rather than to the right.
+	SummaryRowsAbove   bool
+	SummaryColumnsLeft bool
+	// FitToPage scales the sheet to fit the number of pages wide
+	// and tall given by its page setup when printed.
+	FitToPage bool
+}
+
+// makeXLSXSheetPr sets the SheetProperties on sheetPr, the XML
+// representation of the properties of a sheet, intended for internal
+// use only.
+func (sp SheetProperties) makeXLSXSheetPr(sheetPr *xlsxSheetPr) {
+	sheetPr.CodeName = sp.CodeName
+	if sp.TabColor != "" {
+		sheetPr.TabColor = &xlsxColor{RGB: sp.TabColor}
+	}
+	if sp.SummaryRowsAbove || sp.SummaryColumnsLeft {
+		sheetPr.OutlinePr = &xlsxOutlinePr{}
+		if sp.SummaryRowsAbove {
+			sheetPr.OutlinePr.SummaryBelow = new(bool)
+		}
+		if sp.SummaryColumnsLeft {
+			sheetPr.OutlinePr.SummaryRight = new(bool)
+		}
+	}
+	if len(sheetPr.PageSetUpPr) == 0 {
+		sheetPr.PageSetUpPr = make([]xlsxPageSetUpPr, 1)
+	}
+	sheetPr.PageSetUpPr[0].FitToPage = sp.FitToPage
+}
+
+// readSheetProperties converts the XML representation of the
+// properties of a sheet into SheetProperties.  Theme colors of the tab
+// are resolved through styles, when the file has any.
+func readSheetProperties(sheetPr xlsxSheetPr, styles *xlsxStyleSheet) SheetProperties {
+	sp := SheetProperties{CodeName: sheetPr.CodeName}
+	if sheetPr.TabColor != nil {
+		if styles != nil {
+			sp.TabColor = styles.argbValue(*sheetPr.TabColor)
+		} else {
+			sp.TabColor = sheetPr.TabColor.RGB
+		}
+	}
+	if outlinePr := sheetPr.OutlinePr; outlinePr != nil {
+		sp.SummaryRowsAbove = outlinePr.SummaryBelow != nil && !*outlinePr.SummaryBelow
+		sp.SummaryColumnsLeft = outlinePr.SummaryRight != nil && !*outlinePr.SummaryRight
+	}
+	for _, pageSetUpPr := range sheetPr.PageSetUpPr {
+		sp.FitToPage = sp.FitToPage || pageSetUpPr.FitToPage
+	}
+	return sp
+}
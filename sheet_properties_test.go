@@ -0,0 +1,74 @@
+package xlsx
+
+import (
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type SheetPropertiesSuite struct{}
+
+var _ = Suite(&SheetPropertiesSuite{})
+
+func (s *SheetPropertiesSuite) TestMarshalSheetProperties(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetString("data")
+	sheet.Properties = SheetProperties{
+		CodeName:         "Sheet1Code",
+		TabColor:         "FFFF0000",
+		SummaryRowsAbove: true,
+		FitToPage:        true,
+	}
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"],
+		`<sheetPr codeName="Sheet1Code" filterMode="false"><tabColor rgb="FFFF0000"></tabColor><outlinePr summaryBelow="false"></outlinePr><pageSetUpPr fitToPage="true"></pageSetUpPr></sheetPr>`), Equals, true)
+
+	// The defaults leave the sheetPr element as it was.
+	sheet.Properties = SheetProperties{}
+	parts, err = file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"],
+		`<sheetPr filterMode="false"><pageSetUpPr fitToPage="false"></pageSetUpPr></sheetPr>`), Equals, true)
+}
+
+func (s *SheetPropertiesSuite) TestSheetPropertiesRoundTrip(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetString("data")
+	properties := SheetProperties{
+		CodeName:           "Report",
+		TabColor:           "FF00B050",
+		SummaryColumnsLeft: true,
+		FitToPage:          true,
+	}
+	sheet.Properties = properties
+	_, err = file.AddSheet("Sheet2")
+	c.Assert(err, IsNil)
+
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	file, err = OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(file.Sheets[0].Properties, Equals, properties)
+	c.Assert(file.Sheets[1].Properties, Equals, SheetProperties{})
+}
+
+func (s *SheetPropertiesSuite) TestReadSheetProperties(c *C) {
+	theme := 4
+	sheetPr := xlsxSheetPr{
+		CodeName:  "Sheet1",
+		TabColor:  &xlsxColor{Theme: &theme},
+		OutlinePr: &xlsxOutlinePr{SummaryBelow: new(bool)},
+	}
+	properties := readSheetProperties(sheetPr, nil)
+	c.Assert(properties.CodeName, Equals, "Sheet1")
+	c.Assert(properties.TabColor, Equals, "")
+	c.Assert(properties.SummaryRowsAbove, Equals, true)
+	c.Assert(properties.SummaryColumnsLeft, Equals, false)
+}
@@ -0,0 +1,266 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	relationshipTypeSlicer      = "http://schemas.microsoft.com/office/2007/relationships/slicer"
+	relationshipTypeSlicerCache = "http://schemas.microsoft.com/office/2007/relationships/slicerCache"
+	contentTypeSlicer           = "application/vnd.ms-excel.slicer+xml"
+	contentTypeSlicerCache      = "application/vnd.ms-excel.slicerCache+xml"
+	// slicerListExtensionURI and slicerCachesExtensionURI are the uris
+	// of the extensions of the worksheets and of the workbook listing
+	// the slicers bound to tables and their caches.
+	slicerListExtensionURI   = "{3A4CF648-6AED-40f4-86FF-DC5316D8AED3}"
+	slicerCachesExtensionURI = "{46BE6895-7355-4a93-B00E-2C351335B9C9}"
+	// slicerWidth and slicerHeight are the size, in English Metric
+	// Units, Excel gives to a new slicer.
+	slicerWidth  = 1828800
+	slicerHeight = 2524125
+)
+
+// slicerPartPrefixes are the name prefixes of the parts describing
+// slicers and the caches behind them.
+var slicerPartPrefixes = []string{"xl/slicers/", "xl/slicerCaches/"}
+
+// HasSlicers returns true if the File holds slicers, read from a file
+// or added by Sheet.AddTableSlicer.  Slicers are kept as they are when
+// the File is saved, along with the tables, relationships and defined
+// names they depend on.
+func (f *File) HasSlicers() bool {
+	for name := range f.rawParts {
+		if hasAnyPrefix(name, slicerPartPrefixes) {
+			return true
+		}
+	}
+	return false
+}
+
+// xlsxSlicerTable maps the parts of a table that a slicer is bound to.
+type xlsxSlicerTable struct {
+	Id           int    `xml:"id,attr"`
+	Name         string `xml:"name,attr"`
+	DisplayName  string `xml:"displayName,attr"`
+	TableColumns struct {
+		TableColumn []struct {
+			Id   int    `xml:"id,attr"`
+			Name string `xml:"name,attr"`
+		} `xml:"tableColumn"`
+	} `xml:"tableColumns"`
+}
+
+// AddTableSlicer adds to the Sheet a slicer filtering the column called
+// column of the Excel Table called table, its top left corner at
+// anchorCell, such as "E2".  The table must have been read along with
+// the File, on any of its sheets, since tables can't be created by this
+// package.  The slicer is shown by a drawing of its own, so a Sheet
+// which already has a drawing can't be given a slicer.  The slicer
+// starts with every item of the column selected; its cache, the defined
+// name Excel expects for it and the relationships between the parts
+// are added along with it.
+func (s *Sheet) AddTableSlicer(table, column, anchorCell string) error {
+	f := s.File
+	if f == nil {
+		return fmt.Errorf("the sheet '%s' doesn't belong to a file", s.Name)
+	}
+	if s.rawDrawing != nil {
+		return fmt.Errorf("the sheet '%s' already has a drawing", s.Name)
+	}
+	col, row, ok := parseCellID(anchorCell)
+	if !ok || row < 0 {
+		return fmt.Errorf("invalid anchor cell '%s'", anchorCell)
+	}
+	xTable, err := f.findSlicerTable(table)
+	if err != nil {
+		return err
+	}
+	columnId := 0
+	for _, tableColumn := range xTable.TableColumns.TableColumn {
+		if tableColumn.Name == column {
+			columnId = tableColumn.Id
+			break
+		}
+	}
+	if columnId == 0 {
+		return fmt.Errorf("the table '%s' has no column '%s'", table, column)
+	}
+	cacheName, slicerName := f.unusedSlicerNames(column)
+
+	cachePath := unusedRawPartName(f, "xl/slicerCaches/slicerCache", ".xml")
+	cache := xml.Header + `<slicerCacheDefinition xmlns="` + x14Namespace + `"` +
+		` xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006" mc:Ignorable="x"` +
+		` xmlns:x="http://schemas.openxmlformats.org/spreadsheetml/2006/main"` +
+		` name="` + escapeAttr(cacheName) + `" sourceName="` + escapeAttr(column) + `">` +
+		`<extLst><x:ext uri="{2F2917AC-EB37-4324-AD4E-5DD8C200BD13}" xmlns:x15="` + extensionNamespaces["x15"] + `">` +
+		`<x15:tableSlicerCache tableId="` + strconv.Itoa(xTable.Id) + `" column="` + strconv.Itoa(columnId) + `"/>` +
+		`</x:ext></extLst></slicerCacheDefinition>`
+	if err := f.setRawPartOfType(cachePath, []byte(cache), contentTypeSlicerCache); err != nil {
+		return err
+	}
+	slicerPath := unusedRawPartName(f, "xl/slicers/slicer", ".xml")
+	slicer := xml.Header + `<slicers xmlns="` + x14Namespace + `"` +
+		` xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006" mc:Ignorable="x"` +
+		` xmlns:x="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<slicer name="` + escapeAttr(slicerName) + `" cache="` + escapeAttr(cacheName) + `"` +
+		` caption="` + escapeAttr(column) + `" rowHeight="241300"/></slicers>`
+	if err := f.setRawPartOfType(slicerPath, []byte(slicer), contentTypeSlicer); err != nil {
+		return err
+	}
+	drawingPath := unusedRawPartName(f, "xl/drawings/drawing", ".xml")
+	if err := f.setRawPartOfType(drawingPath, []byte(makeSlicerDrawing(slicerName, col, row)), contentTypeDrawing); err != nil {
+		return err
+	}
+
+	// The caches are listed by the workbook, which refers to them by
+	// relationship, and the slicers by the sheet showing them.
+	cacheRel := xlsxWorkbookRelation{
+		Id:     unusedRelationshipId(f.rawRelationships),
+		Type:   relationshipTypeSlicerCache,
+		Target: strings.TrimPrefix(cachePath, "xl/"),
+	}
+	f.rawRelationships = append(f.rawRelationships, cacheRel)
+	f.rawWorkbookExtensions = addToRawExtension(f.rawWorkbookExtensions, slicerCachesExtensionURI,
+		"</x15:slicerCaches>", `<x15:slicerCaches><x14:slicerCache r:id="`+cacheRel.Id+`"/></x15:slicerCaches>`)
+
+	slicerRel := xlsxWorkbookRelation{
+		Id:     unusedRelationshipId(s.rawRelationships),
+		Type:   relationshipTypeSlicer,
+		Target: "../slicers/" + strings.TrimPrefix(slicerPath, "xl/slicers/"),
+	}
+	s.rawRelationships = append(s.rawRelationships, slicerRel)
+	drawingRel := xlsxWorkbookRelation{
+		Id:     unusedRelationshipId(s.rawRelationships),
+		Type:   relationshipTypeDrawing,
+		Target: "../drawings/" + strings.TrimPrefix(drawingPath, "xl/drawings/"),
+	}
+	s.rawRelationships = append(s.rawRelationships, drawingRel)
+	s.rawDrawing = &xlsxDrawing{Id: drawingRel.Id}
+	s.rawExtensions = addToRawExtension(s.rawExtensions, slicerListExtensionURI,
+		"</x14:slicerList>", `<x14:slicerList><x14:slicer r:id="`+slicerRel.Id+`"/></x14:slicerList>`)
+
+	f.DefinedNames = append(f.DefinedNames, &xlsxDefinedName{Name: cacheName, Data: "#N/A"})
+	return nil
+}
+
+// findSlicerTable returns the table of the File called name, looked up
+// by its name or its display name among the tables of its sheets.
+func (f *File) findSlicerTable(name string) (*xlsxSlicerTable, error) {
+	for _, sheet := range f.Sheets {
+		if sheet.rawTableParts == nil {
+			continue
+		}
+		for _, part := range sheet.rawTableParts.TablePart {
+			for _, rel := range sheet.rawRelationships {
+				if rel.Id != part.Id {
+					continue
+				}
+				data, ok := f.rawParts[resolveTarget("xl/worksheets", rel.Target)]
+				if !ok {
+					continue
+				}
+				table := &xlsxSlicerTable{}
+				if err := xml.Unmarshal(data, table); err != nil {
+					return nil, err
+				}
+				if table.Name == name || table.DisplayName == name {
+					return table, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("no table named '%s'", name)
+}
+
+// unusedSlicerNames returns the name of the cache of a new slicer of
+// the given column, which is also the name of a defined name and so
+// can't be taken, and the name of the slicer, numbered the way Excel
+// numbers the slicers of a column, as in "Region 1".
+func (f *File) unusedSlicerNames(column string) (string, string) {
+	var identifier bytes.Buffer
+	for _, r := range column {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			identifier.WriteRune(r)
+		} else {
+			identifier.WriteRune('_')
+		}
+	}
+	taken := make(map[string]bool, len(f.DefinedNames))
+	for _, definedName := range f.DefinedNames {
+		taken[strings.ToLower(definedName.Name)] = true
+	}
+	cacheName, slicerName := "Slicer_"+identifier.String(), column
+	for n := 1; taken[strings.ToLower(cacheName)]; n++ {
+		cacheName = "Slicer_" + identifier.String() + strconv.Itoa(n)
+		slicerName = column + " " + strconv.Itoa(n)
+	}
+	return cacheName, slicerName
+}
+
+// makeSlicerDrawing returns a drawing showing the slicer called name,
+// its top left corner at the cell at col and row.  Versions of Excel
+// that don't know slicers show a shape saying so in its place.
+func makeSlicerDrawing(name string, col, row int) string {
+	cx, cy := strconv.Itoa(slicerWidth), strconv.Itoa(slicerHeight)
+	name = escapeAttr(name)
+	return xml.Header + `<xdr:wsDr xmlns:xdr="http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing"` +
+		` xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">` +
+		`<xdr:oneCellAnchor editAs="oneCell"><xdr:from><xdr:col>` + strconv.Itoa(col) + `</xdr:col><xdr:colOff>0</xdr:colOff>` +
+		`<xdr:row>` + strconv.Itoa(row) + `</xdr:row><xdr:rowOff>0</xdr:rowOff></xdr:from>` +
+		`<xdr:ext cx="` + cx + `" cy="` + cy + `"/>` +
+		`<mc:AlternateContent xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006">` +
+		`<mc:Choice xmlns:sle15="http://schemas.microsoft.com/office/drawing/2012/slicer" Requires="sle15">` +
+		`<xdr:graphicFrame macro=""><xdr:nvGraphicFramePr><xdr:cNvPr id="2" name="` + name + `"/><xdr:cNvGraphicFramePr/></xdr:nvGraphicFramePr>` +
+		`<xdr:xfrm><a:off x="0" y="0"/><a:ext cx="0" cy="0"/></xdr:xfrm>` +
+		`<a:graphic><a:graphicData uri="http://schemas.microsoft.com/office/drawing/2010/slicer">` +
+		`<sle:slicer xmlns:sle="http://schemas.microsoft.com/office/drawing/2010/slicer" name="` + name + `"/>` +
+		`</a:graphicData></a:graphic></xdr:graphicFrame></mc:Choice>` +
+		`<mc:Fallback><xdr:sp macro="" textlink=""><xdr:nvSpPr><xdr:cNvPr id="0" name=""/><xdr:cNvSpPr><a:spLocks noTextEdit="1"/></xdr:cNvSpPr></xdr:nvSpPr>` +
+		`<xdr:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="` + cx + `" cy="` + cy + `"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></xdr:spPr>` +
+		`<xdr:txBody><a:bodyPr/><a:lstStyle/><a:p><a:r><a:rPr lang="en-US" sz="1100"/>` +
+		`<a:t>This shape represents a table slicer, which this version of Excel doesn't support.</a:t></a:r></a:p></xdr:txBody></xdr:sp>` +
+		`</mc:Fallback></mc:AlternateContent><xdr:clientData/></xdr:oneCellAnchor></xdr:wsDr>`
+}
+
+// setRawPartOfType sets the raw part called name to data, with the
+// given content type.
+func (f *File) setRawPartOfType(name string, data []byte, contentType string) error {
+	if err := f.SetRawPart(name, data); err != nil {
+		return err
+	}
+	return f.SetRawPartContentType(name, contentType)
+}
+
+// unusedRelationshipId returns an Id that none of rels has.
+func unusedRelationshipId(rels []xlsxWorkbookRelation) string {
+	n := len(rels) + 1
+	for hasRelationship(rels, "rId"+strconv.Itoa(n)) {
+		n++
+	}
+	return "rId" + strconv.Itoa(n)
+}
+
+// addToRawExtension adds the element list, whose closing tag is end,
+// to the extension of exts with the given uri, adding the extension if
+// there is none.  If the extension has the closing tag, the children of
+// list are added before it instead.
+func addToRawExtension(exts []xlsxExt, uri, end, list string) []xlsxExt {
+	for i := range exts {
+		if exts[i].URI != uri {
+			continue
+		}
+		if at := strings.LastIndex(exts[i].Content, end); at >= 0 {
+			children := list[strings.Index(list, ">")+1 : len(list)-len(end)]
+			exts[i].Content = exts[i].Content[:at] + children + exts[i].Content[at:]
+		} else {
+			exts[i].Content += list
+		}
+		return exts
+	}
+	return append(exts, xlsxExt{URI: uri, Content: list})
+}
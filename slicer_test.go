@@ -0,0 +1,180 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type SlicerSuite struct{}
+
+var _ = Suite(&SlicerSuite{})
+
+// makeSlicerXLSX builds a minimal workbook holding a slicer, laid out
+// the way Excel writes it.
+func makeSlicerXLSX(c *C) *File {
+	parts := map[string]string{
+		"[Content_Types].xml": `<?xml version="1.0" encoding="UTF-8"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/slicers/slicer1.xml" ContentType="application/vnd.ms-excel.slicer+xml"/><Override PartName="/xl/slicerCaches/slicerCache1.xml" ContentType="application/vnd.ms-excel.slicerCache+xml"/></Types>`,
+		"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Data" sheetId="1" r:id="rId1"/></sheets><definedNames><definedName name="Slicer_Region">#N/A</definedName></definedNames><extLst><ext uri="{46BE6895-7355-4a93-B00E-2C351335B9C9}" xmlns:x15="http://schemas.microsoft.com/office/spreadsheetml/2010/11/main"><x15:slicerCaches xmlns:x14="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main"><x14:slicerCache r:id="rId2"/></x15:slicerCaches></ext></extLst></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.microsoft.com/office/2007/relationships/slicerCache" Target="slicerCaches/slicerCache1.xml"/></Relationships>`,
+		"xl/worksheets/sheet1.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>Region</t></is></c></row></sheetData><extLst><ext uri="{3A4CF648-6AED-40f4-86FF-DC5316D8AED3}" xmlns:x14="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main"><x14:slicerList><x14:slicer r:id="rId1"/></x14:slicerList></ext></extLst></worksheet>`,
+		"xl/worksheets/_rels/sheet1.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.microsoft.com/office/2007/relationships/slicer" Target="../slicers/slicer1.xml"/></Relationships>`,
+		"xl/slicers/slicer1.xml":           `<slicers xmlns="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main"><slicer name="Region" cache="Slicer_Region" caption="Region" rowHeight="241300"/></slicers>`,
+		"xl/slicerCaches/slicerCache1.xml": `<slicerCacheDefinition xmlns="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main" name="Slicer_Region" sourceName="Region"/>`,
+	}
+	var buffer bytes.Buffer
+	w := zip.NewWriter(&buffer)
+	for name, data := range parts {
+		part, err := w.Create(name)
+		c.Assert(err, IsNil)
+		_, err = part.Write([]byte(data))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(w.Close(), IsNil)
+	file, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	return file
+}
+
+func (s *SlicerSuite) TestSlicersArePreserved(c *C) {
+	file := makeSlicerXLSX(c)
+	c.Assert(file.HasSlicers(), Equals, true)
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/slicers/slicer1.xml"], Not(Equals), "")
+	c.Assert(parts["xl/slicerCaches/slicerCache1.xml"], Not(Equals), "")
+	c.Assert(strings.Contains(parts["[Content_Types].xml"], `PartName="/xl/slicers/slicer1.xml" ContentType="application/vnd.ms-excel.slicer+xml"`), Equals, true)
+
+	// rId2 is taken by the shared strings, so the slicer cache
+	// relationship and the workbook's reference to it are renamed.
+	c.Assert(strings.Contains(parts["xl/_rels/workbook.xml.rels"], `Id="rId5" Target="slicerCaches/slicerCache1.xml"`), Equals, true)
+	workbook := parts["xl/workbook.xml"]
+	c.Assert(strings.Contains(workbook, `<definedName name="Slicer_Region">#N/A</definedName>`), Equals, true)
+	c.Assert(strings.Contains(workbook, `<x14:slicerCache r:id="rId5"/>`), Equals, true)
+	c.Assert(strings.Contains(workbook, `xmlns:x15="http://schemas.microsoft.com/office/spreadsheetml/2010/11/main"`), Equals, true)
+
+	worksheet := parts["xl/worksheets/sheet1.xml"]
+	c.Assert(strings.Contains(worksheet, `<x14:slicer r:id="rId1"/>`), Equals, true)
+	c.Assert(strings.Contains(worksheet, `xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/worksheets/_rels/sheet1.xml.rels"], `Target="../slicers/slicer1.xml"`), Equals, true)
+}
+
+func (s *SlicerSuite) TestSlicersSurviveRoundTrip(c *C) {
+	file := makeSlicerXLSX(c)
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	reread, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(reread.HasSlicers(), Equals, true)
+	c.Assert(reread.Sheets[0].rawRelationships, HasLen, 1)
+	c.Assert(reread.rawWorkbookExtensions, HasLen, 1)
+}
+
+// makeTableXLSX builds a minimal workbook holding a table called Sales,
+// whose columns are Region and Amount.
+func makeTableXLSX(c *C) *File {
+	parts := map[string]string{
+		"[Content_Types].xml": `<?xml version="1.0" encoding="UTF-8"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/tables/table1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.table+xml"/></Types>`,
+		"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Data" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/worksheets/sheet1.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><dimension ref="A1:B2"/><sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>Region</t></is></c><c r="B1" t="inlineStr"><is><t>Amount</t></is></c></row><row r="2"><c r="A2" t="inlineStr"><is><t>North</t></is></c><c r="B2"><v>10</v></c></row></sheetData><tableParts count="1"><tablePart r:id="rId1"/></tableParts></worksheet>`,
+		"xl/worksheets/_rels/sheet1.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/table" Target="../tables/table1.xml"/></Relationships>`,
+		"xl/tables/table1.xml": `<table xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" id="3" name="Sales" displayName="Sales" ref="A1:B2"><autoFilter ref="A1:B2"/><tableColumns count="2"><tableColumn id="1" name="Region"/><tableColumn id="2" name="Amount"/></tableColumns></table>`,
+	}
+	var buffer bytes.Buffer
+	w := zip.NewWriter(&buffer)
+	for name, data := range parts {
+		part, err := w.Create(name)
+		c.Assert(err, IsNil)
+		_, err = part.Write([]byte(data))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(w.Close(), IsNil)
+	file, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	return file
+}
+
+func (s *SlicerSuite) TestAddTableSlicer(c *C) {
+	file := makeTableXLSX(c)
+	c.Assert(file.HasSlicers(), Equals, false)
+	c.Assert(file.Sheets[0].AddTableSlicer("Sales", "Amount", "D2"), IsNil)
+	c.Assert(file.HasSlicers(), Equals, true)
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/slicerCaches/slicerCache1.xml"], `name="Slicer_Amount" sourceName="Amount"`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/slicerCaches/slicerCache1.xml"], `<x15:tableSlicerCache tableId="3" column="2"/>`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/slicers/slicer1.xml"], `<slicer name="Amount" cache="Slicer_Amount" caption="Amount" rowHeight="241300"/>`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/drawings/drawing1.xml"], `<xdr:col>3</xdr:col>`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/drawings/drawing1.xml"], `<sle:slicer xmlns:sle="http://schemas.microsoft.com/office/drawing/2010/slicer" name="Amount"/>`), Equals, true)
+	types := parts["[Content_Types].xml"]
+	c.Assert(strings.Contains(types, `PartName="/xl/slicers/slicer1.xml" ContentType="application/vnd.ms-excel.slicer+xml"`), Equals, true)
+	c.Assert(strings.Contains(types, `PartName="/xl/slicerCaches/slicerCache1.xml" ContentType="application/vnd.ms-excel.slicerCache+xml"`), Equals, true)
+	c.Assert(strings.Contains(types, `PartName="/xl/drawings/drawing1.xml" ContentType="application/vnd.openxmlformats-officedocument.drawing+xml"`), Equals, true)
+
+	// rId1 is taken by the sheet, so the cache relationship and the
+	// workbook's reference to it are renamed.
+	c.Assert(strings.Contains(parts["xl/_rels/workbook.xml.rels"], `Id="rId5" Target="slicerCaches/slicerCache1.xml"`), Equals, true)
+	workbook := parts["xl/workbook.xml"]
+	c.Assert(strings.Contains(workbook, `<definedName name="Slicer_Amount">#N/A</definedName>`), Equals, true)
+	c.Assert(strings.Contains(workbook, `<ext uri="{46BE6895-7355-4a93-B00E-2C351335B9C9}"`), Equals, true)
+	c.Assert(strings.Contains(workbook, `<x15:slicerCaches><x14:slicerCache r:id="rId5"/></x15:slicerCaches>`), Equals, true)
+
+	worksheet := parts["xl/worksheets/sheet1.xml"]
+	c.Assert(strings.Contains(worksheet, `<x14:slicerList><x14:slicer r:id="rId2"/></x14:slicerList>`), Equals, true)
+	c.Assert(strings.Contains(worksheet, `relationships:id="rId3"></drawing>`), Equals, true)
+	c.Assert(strings.Contains(worksheet, `relationships:id="rId1"></tablePart>`), Equals, true)
+	rels := parts["xl/worksheets/_rels/sheet1.xml.rels"]
+	c.Assert(strings.Contains(rels, `Target="../slicers/slicer1.xml"`), Equals, true)
+	c.Assert(strings.Contains(rels, `Target="../drawings/drawing1.xml"`), Equals, true)
+	c.Assert(strings.Contains(rels, `Target="../tables/table1.xml"`), Equals, true)
+}
+
+func (s *SlicerSuite) TestAddTableSlicerSurvivesRoundTrip(c *C) {
+	file := makeTableXLSX(c)
+	c.Assert(file.Sheets[0].AddTableSlicer("Sales", "Region", "D2"), IsNil)
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	reread, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(reread.HasSlicers(), Equals, true)
+	c.Assert(reread.rawWorkbookExtensions, HasLen, 1)
+
+	// A second slicer of the column is numbered, and listed along
+	// with the first.
+	sheet, err := reread.AddSheet("Slicers")
+	c.Assert(err, IsNil)
+	c.Assert(sheet.AddTableSlicer("Sales", "Region", "A1"), IsNil)
+	parts, err := reread.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/slicers/slicer2.xml"], `<slicer name="Region 1" cache="Slicer_Region1"`), Equals, true)
+	c.Assert(strings.Count(parts["xl/workbook.xml"], `<x14:slicerCache r:id=`), Equals, 2)
+	c.Assert(strings.Count(parts["xl/workbook.xml"], `{46BE6895-7355-4a93-B00E-2C351335B9C9}`), Equals, 1)
+	c.Assert(strings.Contains(parts["xl/workbook.xml"], `<definedName name="Slicer_Region1">#N/A</definedName>`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet2.xml"], `<x14:slicer r:id="rId1"/>`), Equals, true)
+}
+
+func (s *SlicerSuite) TestAddTableSlicerErrors(c *C) {
+	file := makeTableXLSX(c)
+	sheet := file.Sheets[0]
+	c.Assert(sheet.AddTableSlicer("Costs", "Region", "D2"), ErrorMatches, "no table named 'Costs'")
+	c.Assert(sheet.AddTableSlicer("Sales", "Country", "D2"), ErrorMatches, "the table 'Sales' has no column 'Country'")
+	c.Assert(sheet.AddTableSlicer("Sales", "Region", "2D"), ErrorMatches, "invalid anchor cell '2D'")
+	c.Assert(file.HasSlicers(), Equals, false)
+	c.Assert(sheet.AddTableSlicer("Sales", "Region", "D2"), IsNil)
+	c.Assert(sheet.AddTableSlicer("Sales", "Amount", "G2"), ErrorMatches, "the sheet 'Data' already has a drawing")
+}
@@ -0,0 +1,253 @@
+package xlsx
+
+import "sync"
+
+// SheetSnapshot is a read-only copy of a Sheet, as returned by
+// Sheet.Snapshot.  Nothing can change a SheetSnapshot once it has been
+// made, so any number of goroutines can read it at the same time, even
+// while the Sheet it was made from is being modified.
+type SheetSnapshot struct {
+	name   string
+	maxRow int
+	maxCol int
+	rows   []*rowSnapshot
+}
+
+// rowSnapshot is a Row as it was when the snapshots sharing it were
+// made.  Until the Row is changed, they read the Row itself, guarded by
+// mu, and then the copy of the Row made just before it changed.
+type rowSnapshot struct {
+	mu     sync.Mutex
+	row    *Row
+	hidden bool
+	height float64
+	cells  []*Cell
+}
+
+// CellSnapshot is a read-only view of a cell of a SheetSnapshot.  The
+// zero CellSnapshot stands for a cell that doesn't exist and reads as
+// empty.
+type CellSnapshot struct {
+	cell *Cell
+}
+
+// Snapshot returns a read-only copy of the Sheet, for example to
+// process it on another goroutine while the Sheet is being changed.
+// The copy is made on write: the snapshot shares the rows of the Sheet,
+// and a row is only copied the first time it is changed through the
+// methods of the Sheet, its Rows or its Cells, once for all the
+// snapshots sharing it.  Setting the fields of a Row or a Cell directly
+// while a snapshot is read races with the readers of the snapshot.
+// The rows evicted to the cell store of the Sheet are read back and
+// copied when the snapshot is made.  Making the snapshot mustn't race
+// with changes to the Sheet itself.
+func (s *Sheet) Snapshot() *SheetSnapshot {
+	rows := s.rowList()
+	ss := &SheetSnapshot{
+		name:   s.Name,
+		maxRow: s.MaxRow,
+		maxCol: s.MaxCol,
+		rows:   make([]*rowSnapshot, len(rows)),
+	}
+	for r, row := range rows {
+		switch {
+		case row == nil:
+		case row.evicted:
+			ss.rows[r] = s.cellStore.snapshot(row)
+		default:
+			if row.snapshot == nil {
+				row.snapshot = &rowSnapshot{row: row}
+			}
+			ss.rows[r] = row.snapshot
+		}
+	}
+	return ss
+}
+
+// copyForSnapshots copies the Row for the snapshots sharing it, if
+// any, before it is changed, intended for internal use only.
+func (r *Row) copyForSnapshots() {
+	if r == nil || r.snapshot == nil {
+		return
+	}
+	rs := r.snapshot
+	r.snapshot = nil
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.hidden = r.Hidden
+	rs.height = r.Height
+	rs.cells = make([]*Cell, len(r.Cells))
+	for c, cell := range r.Cells {
+		rs.cells[c] = snapshotCell(cell)
+	}
+	rs.row = nil
+}
+
+// changing readies c to be changed by one of its methods, copying its
+// row for the snapshots sharing it first, intended for internal use
+// only.
+func (c *Cell) changing() {
+	c.attach()
+	if c != nil {
+		c.Row.copyForSnapshots()
+	}
+}
+
+// snapshotCell returns a copy of cell sharing no memory that can be
+// modified with it, or nil if cell is nil.
+func snapshotCell(cell *Cell) *Cell {
+	if cell == nil {
+		return nil
+	}
+	copied := *cell
+	copied.Row = nil
+	copied.DataValidation = nil
+	copied.evictedCol = 0
+	if cell.style != nil {
+		style := *cell.style
+		if style.NamedStyleIndex != nil {
+			index := *style.NamedStyleIndex
+			style.NamedStyleIndex = &index
+		}
+		copied.style = &style
+	}
+	copied.parsedNumFmt = cell.getNumberFormat()
+	return &copied
+}
+
+// read calls f with the row as it was when the snapshot was made.  The
+// cells of a row that wasn't copied yet are copied as f asks for them.
+func (rs *rowSnapshot) read(f func(hidden bool, height float64, cells int, cell func(int) *Cell)) {
+	if rs == nil {
+		f(false, 0, 0, nil)
+		return
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.row == nil {
+		f(rs.hidden, rs.height, len(rs.cells), func(c int) *Cell { return rs.cells[c] })
+		return
+	}
+	f(rs.row.Hidden, rs.row.Height, len(rs.row.Cells), func(c int) *Cell { return snapshotCell(rs.row.Cells[c]) })
+}
+
+// row returns the rowSnapshot of the row at the given zero based index,
+// nil if the snapshot has no such row.
+func (ss *SheetSnapshot) row(row int) *rowSnapshot {
+	if row < 0 || row >= len(ss.rows) {
+		return nil
+	}
+	return ss.rows[row]
+}
+
+// Name returns the name of the Sheet when the snapshot was made.
+func (ss *SheetSnapshot) Name() string {
+	return ss.name
+}
+
+// MaxRow returns the number of rows of the snapshot.
+func (ss *SheetSnapshot) MaxRow() int {
+	return ss.maxRow
+}
+
+// MaxCol returns the number of columns of the snapshot.
+func (ss *SheetSnapshot) MaxCol() int {
+	return ss.maxCol
+}
+
+// RowHidden returns true if the row at the given zero based index is
+// hidden.
+func (ss *SheetSnapshot) RowHidden(row int) (hidden bool) {
+	ss.row(row).read(func(h bool, _ float64, _ int, _ func(int) *Cell) {
+		hidden = h
+	})
+	return hidden
+}
+
+// RowHeight returns the height of the row at the given zero based
+// index, or zero for the default height.
+func (ss *SheetSnapshot) RowHeight(row int) (height float64) {
+	ss.row(row).read(func(_ bool, h float64, _ int, _ func(int) *Cell) {
+		height = h
+	})
+	return height
+}
+
+// RowLen returns the number of cells of the row at the given zero
+// based index.
+func (ss *SheetSnapshot) RowLen(row int) (n int) {
+	ss.row(row).read(func(_ bool, _ float64, cells int, _ func(int) *Cell) {
+		n = cells
+	})
+	return n
+}
+
+// Cell returns the cell at the given zero based row and column
+// indexes.  Cells beyond the end of the snapshot read as empty.
+func (ss *SheetSnapshot) Cell(row, col int) (cs CellSnapshot) {
+	ss.row(row).read(func(_ bool, _ float64, cells int, cell func(int) *Cell) {
+		if col >= 0 && col < cells {
+			cs.cell = cell(col)
+		}
+	})
+	return cs
+}
+
+// Exists returns true if the cell was present in the Sheet.
+func (cs CellSnapshot) Exists() bool {
+	return cs.cell != nil
+}
+
+// Value returns the raw value of the cell.
+func (cs CellSnapshot) Value() string {
+	if cs.cell == nil {
+		return ""
+	}
+	return cs.cell.Value
+}
+
+// Formula returns the formula of the cell, if any.
+func (cs CellSnapshot) Formula() string {
+	if cs.cell == nil {
+		return ""
+	}
+	return cs.cell.formula
+}
+
+// Type returns the type of the cell.
+func (cs CellSnapshot) Type() CellType {
+	if cs.cell == nil {
+		return CellTypeString
+	}
+	return cs.cell.cellType
+}
+
+// NumberFormat returns the number format of the cell.
+func (cs CellSnapshot) NumberFormat() string {
+	if cs.cell == nil {
+		return ""
+	}
+	return cs.cell.NumFmt
+}
+
+// Style returns a copy of the style of the cell.
+func (cs CellSnapshot) Style() Style {
+	if cs.cell == nil || cs.cell.style == nil {
+		return *NewStyle()
+	}
+	return *cs.cell.style
+}
+
+// Hidden returns true if the row or column of the cell is hidden.
+func (cs CellSnapshot) Hidden() bool {
+	return cs.cell != nil && cs.cell.Hidden
+}
+
+// FormattedValue returns the value of the cell formatted with its
+// number format, see Cell.FormattedValue.
+func (cs CellSnapshot) FormattedValue() (string, error) {
+	if cs.cell == nil {
+		return "", nil
+	}
+	return cs.cell.FormattedValue()
+}
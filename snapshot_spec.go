@@ -0,0 +1,318 @@
+package xlsx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// SnapshotSpec records the values, number formats and formulas of the
+// cells of ranges of a known-good File, such as a report written by the
+// program under test, to verify the files it writes later against them,
+// see Verify.  It is saved as JSON, to be kept along with the tests, see
+// CheckSnapshotSpec.
+type SnapshotSpec struct {
+	Ranges []SnapshotSpecRange `json:"ranges"`
+}
+
+// SnapshotRange is a range of a sheet recorded in a SnapshotSpec, such
+// as "A1:F20", or the whole sheet if Range is empty.
+type SnapshotRange struct {
+	Sheet string `json:"sheet"`
+	Range string `json:"range,omitempty"`
+}
+
+// SnapshotSpecRange holds the cells of a range of a SnapshotSpec that
+// have a value or a formula, row by row.
+type SnapshotSpecRange struct {
+	SnapshotRange
+	Cells []SnapshotSpecCell `json:"cells"`
+}
+
+// SnapshotSpecCell is a cell of a SnapshotSpec, Cell being its
+// reference, such as "B3", and Type one of "string", "number", "bool",
+// "error" and "date".  Format is empty for the general number format.
+type SnapshotSpecCell struct {
+	Cell    string `json:"cell"`
+	Type    string `json:"type"`
+	Value   string `json:"value,omitempty"`
+	Format  string `json:"format,omitempty"`
+	Formula string `json:"formula,omitempty"`
+}
+
+// SnapshotTolerance is the difference between the cells of a File and
+// those of a SnapshotSpec that Verify lets pass.
+type SnapshotTolerance struct {
+	// Epsilon is the largest difference between the values of two
+	// numbers taken as equal, 0 for none.
+	Epsilon float64
+	// IgnoreTimes ignores the values of the dates and times, such as
+	// the time a report was written at, whose format is still
+	// compared.
+	IgnoreTimes bool
+	// IgnoreCells lists the cells whose values are ignored, such as
+	// "Summary!B2", the sheet name being given as it is, without
+	// quotes.
+	IgnoreCells []string
+}
+
+// SnapshotMismatch is a difference between a cell of a File and that of
+// a SnapshotSpec.  What is "sheet" for a sheet that is missing, or the
+// part of the cell that differs: "value", "type", "format" or "formula".
+// A cell missing from either side has an empty Expected or Actual value.
+type SnapshotMismatch struct {
+	Sheet    string
+	Cell     string
+	What     string
+	Expected string
+	Actual   string
+}
+
+// String returns a description of the SnapshotMismatch.
+func (m SnapshotMismatch) String() string {
+	if m.What == "sheet" {
+		return fmt.Sprintf("no sheet %q", m.Sheet)
+	}
+	return fmt.Sprintf("%s!%s: %s %q, expected %q", m.Sheet, m.Cell, m.What, m.Actual, m.Expected)
+}
+
+// SnapshotMismatchError is returned by Verify when a File doesn't match
+// a SnapshotSpec.
+type SnapshotMismatchError struct {
+	Mismatches []SnapshotMismatch
+}
+
+// Error returns a description of the SnapshotMismatchError.
+func (e *SnapshotMismatchError) Error() string {
+	lines := make([]string, len(e.Mismatches))
+	for i, mismatch := range e.Mismatches {
+		lines[i] = mismatch.String()
+	}
+	return fmt.Sprintf("%d differences from the snapshot spec:\n%s", len(e.Mismatches), strings.Join(lines, "\n"))
+}
+
+// snapshotTypeNames are the names of the types of the cells in a
+// SnapshotSpec, the formulas resulting in strings being strings.
+var snapshotTypeNames = map[CellType]string{
+	CellTypeString:        "string",
+	CellTypeStringFormula: "string",
+	CellTypeInline:        "string",
+	CellTypeNumeric:       "number",
+	CellTypeBool:          "bool",
+	CellTypeError:         "error",
+	CellTypeDate:          "date",
+}
+
+// RecordSnapshotSpec returns the SnapshotSpec of the given ranges of
+// file.
+func RecordSnapshotSpec(file *File, ranges ...SnapshotRange) (*SnapshotSpec, error) {
+	spec := &SnapshotSpec{}
+	for _, snapshotRange := range ranges {
+		sheet, ok := file.Sheet[snapshotRange.Sheet]
+		if !ok {
+			return nil, fmt.Errorf("no sheet %q to record", snapshotRange.Sheet)
+		}
+		cells, err := recordSnapshotCells(sheet, snapshotRange.Range)
+		if err != nil {
+			return nil, err
+		}
+		spec.Ranges = append(spec.Ranges, SnapshotSpecRange{SnapshotRange: snapshotRange, Cells: cells})
+	}
+	return spec, nil
+}
+
+// LoadSnapshotSpec reads a SnapshotSpec saved by Save.
+func LoadSnapshotSpec(r io.Reader) (*SnapshotSpec, error) {
+	spec := &SnapshotSpec{}
+	if err := json.NewDecoder(r).Decode(spec); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// Save writes the SnapshotSpec to w as indented JSON, one cell per line,
+// so that the changes of the spec read well in a diff.
+func (spec *SnapshotSpec) Save(w io.Writer) error {
+	data, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Verify compares the ranges of file with those of the SnapshotSpec,
+// and returns a SnapshotMismatchError listing their differences, if
+// any, beyond the tolerance.  The cells of file missing from the spec
+// are differences as well.
+func (spec *SnapshotSpec) Verify(file *File, tolerance SnapshotTolerance) error {
+	ignored := make(map[string]bool, len(tolerance.IgnoreCells))
+	for _, cell := range tolerance.IgnoreCells {
+		ignored[cell] = true
+	}
+	var mismatches []SnapshotMismatch
+	for _, specRange := range spec.Ranges {
+		sheet, ok := file.Sheet[specRange.Sheet]
+		if !ok {
+			mismatches = append(mismatches, SnapshotMismatch{Sheet: specRange.Sheet, What: "sheet"})
+			continue
+		}
+		cells, err := recordSnapshotCells(sheet, specRange.Range)
+		if err != nil {
+			return err
+		}
+		actual := make(map[string]SnapshotSpecCell, len(cells))
+		for _, cell := range cells {
+			actual[cell.Cell] = cell
+		}
+		for _, expected := range specRange.Cells {
+			cell, ok := actual[expected.Cell]
+			delete(actual, expected.Cell)
+			if !ok {
+				cell = SnapshotSpecCell{Cell: expected.Cell}
+			}
+			ignoreValue := ignored[sheet.Name+"!"+expected.Cell]
+			mismatches = append(mismatches, compareSnapshotCells(sheet.Name, expected, cell, ignoreValue, tolerance)...)
+		}
+		// The cells left are missing from the spec.
+		for _, cell := range cells {
+			if _, ok := actual[cell.Cell]; !ok || ignored[sheet.Name+"!"+cell.Cell] {
+				continue
+			}
+			mismatches = append(mismatches, SnapshotMismatch{Sheet: sheet.Name, Cell: cell.Cell, What: "value",
+				Actual: cell.Value + cell.Formula})
+		}
+	}
+	if len(mismatches) > 0 {
+		return &SnapshotMismatchError{Mismatches: mismatches}
+	}
+	return nil
+}
+
+// CheckSnapshotSpec verifies file against the SnapshotSpec saved at
+// path, such as in the tests of a program writing reports.  The spec of
+// the given ranges of file is recorded and saved there instead if update
+// is true, such as when a flag of the tests is set, or if there is no
+// file at path, the first run recording what later runs check.
+func CheckSnapshotSpec(path string, file *File, ranges []SnapshotRange, tolerance SnapshotTolerance, update bool) error {
+	if !update {
+		input, err := os.Open(path)
+		if err == nil {
+			defer input.Close()
+			spec, err := LoadSnapshotSpec(input)
+			if err != nil {
+				return fmt.Errorf("the snapshot spec %s can't be read: %v", path, err)
+			}
+			return spec.Verify(file, tolerance)
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+	}
+	spec, err := RecordSnapshotSpec(file, ranges...)
+	if err != nil {
+		return err
+	}
+	output, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = spec.Save(output); err != nil {
+		output.Close()
+		return err
+	}
+	return output.Close()
+}
+
+// recordSnapshotCells returns the cells of the given range of sheet, the
+// whole sheet if it is empty, that have a value or a formula.
+func recordSnapshotCells(sheet *Sheet, ref string) ([]SnapshotSpecCell, error) {
+	minCol, minRow, maxCol, maxRow := 0, 0, -1, len(sheet.rowList())-1
+	if ref != "" {
+		if !strings.Contains(ref, cellRangeChar) {
+			ref += cellRangeChar + ref
+		}
+		var err error
+		if minCol, minRow, maxCol, maxRow, err = getMaxMinFromDimensionRef(ref); err != nil {
+			return nil, fmt.Errorf("invalid range %q of sheet %q: %v", ref, sheet.Name, err)
+		}
+	}
+	var cells []SnapshotSpecCell
+	for r := minRow; r <= maxRow && r < len(sheet.rowList()); r++ {
+		row := sheet.row(r)
+		if row == nil {
+			continue
+		}
+		for c := minCol; c < len(row.Cells) && (maxCol < 0 || c <= maxCol); c++ {
+			cell := row.Cells[c]
+			if cell == nil || (cell.Value == "" && cell.formula == "") {
+				continue
+			}
+			format := cell.EffectiveNumberFormat()
+			if format == builtInNumFmt[builtInNumFmtIndex_GENERAL] {
+				format = ""
+			}
+			cells = append(cells, SnapshotSpecCell{
+				Cell:    GetCellIDStringFromCoords(c, r),
+				Type:    snapshotTypeNames[cell.cellType],
+				Value:   cell.Value,
+				Format:  format,
+				Formula: cell.formula,
+			})
+		}
+	}
+	return cells, nil
+}
+
+// compareSnapshotCells returns the differences between the cell of the
+// given sheet of a SnapshotSpec, expected, and that of a File, actual,
+// leaving out those the tolerance lets pass, and the values if
+// ignoreValue is true.
+func compareSnapshotCells(sheet string, expected, actual SnapshotSpecCell, ignoreValue bool, tolerance SnapshotTolerance) []SnapshotMismatch {
+	var mismatches []SnapshotMismatch
+	mismatch := func(what, want, got string) {
+		mismatches = append(mismatches, SnapshotMismatch{Sheet: sheet, Cell: expected.Cell, What: what,
+			Expected: want, Actual: got})
+	}
+	if actual.Type == "" {
+		mismatch("value", expected.Value+expected.Formula, "")
+		return mismatches
+	}
+	if expected.Type != actual.Type {
+		mismatch("type", expected.Type, actual.Type)
+	}
+	isTime := expected.Type == "date" || (expected.Type == "number" && isTimeFormat(expected.Format))
+	if !ignoreValue && !(tolerance.IgnoreTimes && isTime) && !snapshotValuesEqual(expected, actual, tolerance.Epsilon) {
+		mismatch("value", expected.Value, actual.Value)
+	}
+	if expected.Format != actual.Format {
+		mismatch("format", expected.Format, actual.Format)
+	}
+	if expected.Formula != actual.Formula {
+		mismatch("formula", expected.Formula, actual.Formula)
+	}
+	return mismatches
+}
+
+// snapshotValuesEqual returns true if the values of the cells are equal,
+// numbers differing by at most epsilon.
+func snapshotValuesEqual(expected, actual SnapshotSpecCell, epsilon float64) bool {
+	if expected.Value == actual.Value {
+		return true
+	}
+	if expected.Type != "number" || actual.Type != "number" {
+		return false
+	}
+	x, err := parseFloat(expected.Value)
+	if err != nil {
+		return false
+	}
+	y, err := parseFloat(actual.Value)
+	if err != nil {
+		return false
+	}
+	return math.Abs(x-y) <= epsilon
+}
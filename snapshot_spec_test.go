@@ -0,0 +1,119 @@
+package xlsx
+
+import (
+	"bytes"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SnapshotSpecSuite struct{}
+
+var _ = Suite(&SnapshotSpecSuite{})
+
+// snapshotSpecFile returns a File of a report, written at the given
+// time, with a total of the given amount.
+func snapshotSpecFile(c *C, written time.Time, amount float64) *File {
+	file := NewFile()
+	sheet, err := file.AddSheet("Report")
+	c.Assert(err, IsNil)
+	row := sheet.AddRow()
+	row.AddCell().SetString("Written")
+	row.AddCell().SetDateTime(written)
+	row = sheet.AddRow()
+	row.AddCell().SetString("Amount")
+	row.AddCell().SetFloatWithFormat(amount, "#,##0.00")
+	row = sheet.AddRow()
+	row.AddCell().SetString("Total")
+	row.AddCell().SetFormula("SUM(B2:B2)")
+	return file
+}
+
+func (s *SnapshotSpecSuite) TestRecordAndVerify(c *C) {
+	written := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	file := snapshotSpecFile(c, written, 1234.5)
+	spec, err := RecordSnapshotSpec(file, SnapshotRange{Sheet: "Report"})
+	c.Assert(err, IsNil)
+	c.Assert(spec.Ranges, HasLen, 1)
+	cells := spec.Ranges[0].Cells
+	c.Assert(cells, HasLen, 6)
+	c.Assert(cells[3], Equals, SnapshotSpecCell{Cell: "B2", Type: "number", Value: "1234.5", Format: "#,##0.00"})
+	c.Assert(cells[5].Formula, Equals, "SUM(B2:B2)")
+
+	var buf bytes.Buffer
+	c.Assert(spec.Save(&buf), IsNil)
+	loaded, err := LoadSnapshotSpec(&buf)
+	c.Assert(err, IsNil)
+	c.Assert(loaded, DeepEquals, spec)
+	c.Assert(loaded.Verify(file, SnapshotTolerance{}), IsNil)
+
+	// A later run, at another time, with a rounding difference.
+	later := snapshotSpecFile(c, written.Add(time.Hour), 1234.5000001)
+	err = loaded.Verify(later, SnapshotTolerance{})
+	c.Assert(err, NotNil)
+	mismatches := err.(*SnapshotMismatchError).Mismatches
+	c.Assert(mismatches, HasLen, 2)
+	c.Assert(mismatches[0].Cell, Equals, "B1")
+	c.Assert(mismatches[1], Equals, SnapshotMismatch{Sheet: "Report", Cell: "B2", What: "value",
+		Expected: "1234.5", Actual: "1234.5000001"})
+	c.Assert(loaded.Verify(later, SnapshotTolerance{Epsilon: 1e-6, IgnoreTimes: true}), IsNil)
+	c.Assert(loaded.Verify(later, SnapshotTolerance{Epsilon: 1e-6, IgnoreCells: []string{"Report!B1"}}), IsNil)
+}
+
+func (s *SnapshotSpecSuite) TestVerifyMismatches(c *C) {
+	written := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	spec, err := RecordSnapshotSpec(snapshotSpecFile(c, written, 10), SnapshotRange{Sheet: "Report", Range: "A2:B3"},
+		SnapshotRange{Sheet: "Report", Range: "A1"})
+	c.Assert(err, IsNil)
+	c.Assert(spec.Ranges[0].Cells, HasLen, 4)
+	c.Assert(spec.Ranges[1].Cells, HasLen, 1)
+
+	file := snapshotSpecFile(c, written, 10)
+	sheet := file.Sheet["Report"]
+	sheet.Cell(1, 1).SetString("10")
+	sheet.Cell(2, 1).SetFormula("SUM(B1:B2)")
+	sheet.Cell(2, 0).Value = ""
+	sheet.Cell(1, 2).SetString("new")
+	err = spec.Verify(file, SnapshotTolerance{})
+	c.Assert(err, NotNil)
+	mismatches := err.(*SnapshotMismatchError).Mismatches
+	c.Assert(mismatches, DeepEquals, []SnapshotMismatch{
+		{Sheet: "Report", Cell: "B2", What: "type", Expected: "number", Actual: "string"},
+		{Sheet: "Report", Cell: "A3", What: "value", Expected: "Total", Actual: ""},
+		{Sheet: "Report", Cell: "B3", What: "formula", Expected: "SUM(B2:B2)", Actual: "SUM(B1:B2)"},
+	})
+
+	spec.Ranges[1].Sheet = "Missing"
+	err = spec.Verify(file, SnapshotTolerance{})
+	mismatches = err.(*SnapshotMismatchError).Mismatches
+	c.Assert(mismatches[len(mismatches)-1], Equals, SnapshotMismatch{Sheet: "Missing", What: "sheet"})
+
+	// A cell written past those of the spec.
+	spec, err = RecordSnapshotSpec(snapshotSpecFile(c, written, 10), SnapshotRange{Sheet: "Report"})
+	c.Assert(err, IsNil)
+	file = snapshotSpecFile(c, written, 10)
+	file.Sheet["Report"].Cell(1, 2).SetString("new")
+	err = spec.Verify(file, SnapshotTolerance{})
+	c.Assert(err, NotNil)
+	c.Assert(err.(*SnapshotMismatchError).Mismatches, DeepEquals, []SnapshotMismatch{
+		{Sheet: "Report", Cell: "C2", What: "value", Expected: "", Actual: "new"},
+	})
+
+	_, err = RecordSnapshotSpec(file, SnapshotRange{Sheet: "Missing"})
+	c.Assert(err, ErrorMatches, `no sheet "Missing" to record`)
+}
+
+func (s *SnapshotSpecSuite) TestCheckSnapshotSpec(c *C) {
+	path := filepath.Join(c.MkDir(), "report.json")
+	written := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	ranges := []SnapshotRange{{Sheet: "Report"}}
+	tolerance := SnapshotTolerance{IgnoreTimes: true}
+	c.Assert(CheckSnapshotSpec(path, snapshotSpecFile(c, written, 10), ranges, tolerance, false), IsNil)
+	c.Assert(CheckSnapshotSpec(path, snapshotSpecFile(c, written.Add(time.Hour), 10), ranges, tolerance, false), IsNil)
+	err := CheckSnapshotSpec(path, snapshotSpecFile(c, written, 11), ranges, tolerance, false)
+	_, ok := err.(*SnapshotMismatchError)
+	c.Assert(ok, Equals, true)
+	c.Assert(CheckSnapshotSpec(path, snapshotSpecFile(c, written, 11), ranges, tolerance, true), IsNil)
+	c.Assert(CheckSnapshotSpec(path, snapshotSpecFile(c, written, 11), ranges, tolerance, false), IsNil)
+}
@@ -0,0 +1,148 @@
+package xlsx
+
+import (
+	"fmt"
+	"sync"
+
+	. "gopkg.in/check.v1"
+)
+
+type SnapshotSuite struct{}
+
+var _ = Suite(&SnapshotSuite{})
+
+func (s *SnapshotSuite) TestSnapshot(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Data")
+	c.Assert(err, IsNil)
+	row := sheet.AddRow()
+	row.Hidden = true
+	row.AddCell().SetFloatWithFormat(0.5, "0%")
+	cell := row.AddCell()
+	cell.SetFormula("A1*2")
+	cell.GetStyle().Font.Bold = true
+
+	snapshot := sheet.Snapshot()
+	c.Assert(snapshot.Name(), Equals, "Data")
+	c.Assert(snapshot.MaxRow(), Equals, 1)
+	c.Assert(snapshot.MaxCol(), Equals, 2)
+	c.Assert(snapshot.RowHidden(0), Equals, true)
+	c.Assert(snapshot.RowLen(0), Equals, 2)
+	value, err := snapshot.Cell(0, 0).FormattedValue()
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "50%")
+	c.Assert(snapshot.Cell(0, 1).Formula(), Equals, "A1*2")
+	c.Assert(snapshot.Cell(0, 1).Style().Font.Bold, Equals, true)
+
+	missing := snapshot.Cell(5, 5)
+	c.Assert(missing.Exists(), Equals, false)
+	c.Assert(missing.Value(), Equals, "")
+	c.Assert(snapshot.RowLen(5), Equals, 0)
+}
+
+func (s *SnapshotSuite) TestSnapshotIsNotChangedByTheSheet(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Data")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetString("before")
+	sheet.Cell(0, 0).GetStyle().Font.Bold = true
+
+	snapshot := sheet.Snapshot()
+	sheet.Name = "Renamed"
+	sheet.Cell(0, 0).SetString("after")
+	sheet.Cell(0, 0).GetStyle().Font.Bold = false
+	sheet.Cell(3, 3).SetString("new")
+
+	c.Assert(snapshot.Name(), Equals, "Data")
+	c.Assert(snapshot.Cell(0, 0).Value(), Equals, "before")
+	c.Assert(snapshot.Cell(0, 0).Style().Font.Bold, Equals, true)
+	c.Assert(snapshot.Cell(3, 3).Exists(), Equals, false)
+}
+
+func (s *SnapshotSuite) TestConcurrentReaders(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Data")
+	c.Assert(err, IsNil)
+	for r := 0; r < 50; r++ {
+		sheet.Cell(r, 0).SetFloatWithFormat(float64(r), "0.00")
+	}
+	snapshot := sheet.Snapshot()
+
+	var wg sync.WaitGroup
+	results := make([]string, 4)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for r := 0; r < snapshot.MaxRow(); r++ {
+				value, _ := snapshot.Cell(r, 0).FormattedValue()
+				results[i] = value
+			}
+		}(i)
+	}
+	for r := 0; r < 50; r++ {
+		sheet.Cell(r, 0).SetString(fmt.Sprint("changed ", r))
+	}
+	wg.Wait()
+	for _, result := range results {
+		c.Assert(result, Equals, "49.00")
+	}
+}
+
+func (s *SnapshotSuite) TestSnapshotCopiesRowsOnWrite(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Data")
+	c.Assert(err, IsNil)
+	held := sheet.Cell(0, 0)
+	held.SetString("first")
+	sheet.Cell(1, 0).SetString("second")
+
+	// The rows are shared until they change, and then copied once for
+	// all the snapshots sharing them.
+	snapshot := sheet.Snapshot()
+	again := sheet.Snapshot()
+	c.Assert(snapshot.rows[0] == again.rows[0], Equals, true)
+	c.Assert(snapshot.rows[0].row == sheet.Rows[0], Equals, true)
+	held.SetString("changed")
+	sheet.Rows[1].SetHeight(20)
+	sheet.Rows[1].AddCell().SetString("added")
+	c.Assert(snapshot.rows[0].row, IsNil)
+	c.Assert(sheet.Rows[0].snapshot, IsNil)
+	c.Assert(snapshot.Cell(0, 0).Value(), Equals, "first")
+	c.Assert(again.Cell(0, 0).Value(), Equals, "first")
+	c.Assert(snapshot.RowHeight(1), Equals, 0.0)
+	c.Assert(snapshot.RowLen(1), Equals, 1)
+
+	// A row not changed since the last snapshot is shared with the
+	// next one.
+	sheet.Cell(2, 0).SetString("third")
+	last := sheet.Snapshot()
+	c.Assert(last.rows[0] == snapshot.rows[0], Equals, false)
+	c.Assert(last.rows[0].row == sheet.Rows[0], Equals, true)
+	c.Assert(last.Cell(0, 0).Value(), Equals, "changed")
+	c.Assert(last.Cell(1, 1).Value(), Equals, "added")
+	c.Assert(snapshot.RowLen(2), Equals, 0)
+}
+
+func (s *SnapshotSuite) TestSnapshotOfStoredSheet(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Data")
+	c.Assert(err, IsNil)
+	c.Assert(sheet.SetCellStore(NewMemoryCellStore(), 1), IsNil)
+	for r := 0; r < 3; r++ {
+		sheet.Cell(r, 0).SetInt(r)
+	}
+	snapshot := sheet.Snapshot()
+	c.Assert(snapshot.rows[0].row, IsNil)
+	c.Assert(snapshot.rows[2].row == sheet.rowList()[2], Equals, true)
+
+	// Evicting a row shared with the snapshot copies it first.
+	for r := 0; r < 3; r++ {
+		sheet.Cell(r, 1).SetString("new")
+	}
+	c.Assert(snapshot.rows[2].row, IsNil)
+	for r := 0; r < 3; r++ {
+		c.Assert(snapshot.Cell(r, 0).Value(), Equals, fmt.Sprint(r))
+		c.Assert(snapshot.RowLen(r), Equals, 1)
+	}
+}
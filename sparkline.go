@@ -0,0 +1,413 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// sparklineExtURI identifies the worksheet extension holding
+	// sparkline groups.
+	sparklineExtURI = "{05C60535-1F16-4fd2-B633-F4F36F0B64E0}"
+
+	x14Namespace = "http://schemas.microsoft.com/office/spreadsheetml/2009/9/main"
+	xmNamespace  = "http://schemas.microsoft.com/office/excel/2006/main"
+)
+
+// SparklineType is the kind of chart drawn by a SparklineGroup.
+type SparklineType string
+
+// Sparkline types
+const (
+	SparklineTypeLine    SparklineType = "line"
+	SparklineTypeColumn  SparklineType = "column"
+	SparklineTypeWinLoss SparklineType = "stacked"
+)
+
+// Sparkline is a tiny chart, drawn inside the cell at Location, of the
+// values in DataRange, for example "Sheet1!B2:M2".
+type Sparkline struct {
+	Location  string
+	DataRange string
+}
+
+// SparklineGroup is a set of sparklines sharing the same type and
+// formatting, as Excel groups them.  Colors are ARGB hex strings such
+// as "FF376092".
+type SparklineGroup struct {
+	Type       SparklineType
+	Sparklines []Sparkline
+	// LineWeight is the width of line sparklines in points, zero
+	// meaning Excel's default of 0.75.
+	LineWeight    float64
+	ShowMarkers   bool
+	ShowHigh      bool
+	ShowLow       bool
+	ShowFirst     bool
+	ShowLast      bool
+	ShowNegative  bool
+	SeriesColor   string
+	NegativeColor string
+	MarkersColor  string
+	// DateRange is the range of the dates the values of the
+	// sparklines are plotted against, one per value, such as
+	// "Sheet1!B1:M1", which spaces their points by date rather than
+	// evenly, as on the date axis of a time series.  AddSparklineGroup
+	// detects it when it is empty, see detectSparklineDateRange.
+	DateRange string
+}
+
+// NewSparklineGroup creates an empty SparklineGroup of the given type
+// with Excel's default colors.
+func NewSparklineGroup(sparklineType SparklineType) *SparklineGroup {
+	return &SparklineGroup{
+		Type:          sparklineType,
+		SeriesColor:   "FF376092",
+		NegativeColor: "FFD00000",
+		MarkersColor:  "FFD00000",
+	}
+}
+
+// Add adds a sparkline of the values in dataRange, drawn in the cell
+// at location, to the SparklineGroup.
+func (sg *SparklineGroup) Add(location, dataRange string) {
+	sg.Sparklines = append(sg.Sparklines, Sparkline{Location: location, DataRange: dataRange})
+}
+
+// AddSparklineGroup adds a SparklineGroup to the Sheet.  An error is
+// returned if the group has an unknown type, has no sparklines, or if
+// the location of a sparkline is not a single cell.  The group is given
+// a date axis if its DateRange is empty and the values of its
+// sparklines, all over the same columns of rows, or rows of columns,
+// follow a row, or column, of dates.
+func (s *Sheet) AddSparklineGroup(group *SparklineGroup) error {
+	switch group.Type {
+	case SparklineTypeLine, SparklineTypeColumn, SparklineTypeWinLoss:
+	default:
+		return fmt.Errorf("unknown sparkline type '%s'", group.Type)
+	}
+	if len(group.Sparklines) == 0 {
+		return errors.New("sparkline group has no sparklines")
+	}
+	for _, sparkline := range group.Sparklines {
+		if _, _, err := GetCoordsFromCellIDString(sparkline.Location); err != nil {
+			return fmt.Errorf("sparkline location '%s': %s", sparkline.Location, err)
+		}
+		if sparkline.DataRange == "" {
+			return fmt.Errorf("sparkline at %s has no data range", sparkline.Location)
+		}
+	}
+	if group.DateRange == "" {
+		group.DateRange = s.detectSparklineDateRange(group)
+	}
+	s.SparklineGroups = append(s.SparklineGroups, group)
+	return nil
+}
+
+// detectSparklineDateRange returns the range of the dates the values of
+// the sparklines of group are plotted against, or "" if there is none.
+// The values of all of the sparklines must be in the same columns of
+// rows, or in the same rows of columns, of a single sheet.  The dates
+// are those of the nearest row above them, or column left of them, that
+// isn't blank, such as their headers, if all of its cells over the
+// values are dates.
+func (s *Sheet) detectSparklineDateRange(group *SparklineGroup) string {
+	var prefix string
+	var minCol, minRow, maxCol, maxRow int
+	horizontal := false
+	for i, sparkline := range group.Sparklines {
+		rangePrefix, ref := "", sparkline.DataRange
+		if bang := strings.LastIndex(ref, externalSheetBangChar); bang >= 0 {
+			rangePrefix, ref = ref[:bang+1], ref[bang+1:]
+		}
+		fromCol, fromRow, toCol, toRow, err := getMaxMinFromDimensionRef(strings.Replace(ref, "$", "", -1))
+		if err != nil || !strings.Contains(ref, cellRangeChar) || (fromRow != toRow && fromCol != toCol) {
+			return ""
+		}
+		if i == 0 {
+			prefix, minCol, minRow, maxCol, maxRow = rangePrefix, fromCol, fromRow, toCol, toRow
+			horizontal = fromRow == toRow
+			continue
+		}
+		if rangePrefix != prefix || horizontal != (fromRow == toRow) {
+			return ""
+		}
+		if horizontal && (fromCol != minCol || toCol != maxCol) || !horizontal && (fromRow != minRow || toRow != maxRow) {
+			return ""
+		}
+		if fromRow < minRow {
+			minRow = fromRow
+		}
+		if fromCol < minCol {
+			minCol = fromCol
+		}
+	}
+	sheet := s
+	if prefix != "" {
+		name := strings.TrimSuffix(prefix, externalSheetBangChar)
+		if strings.HasPrefix(name, "'") && strings.HasSuffix(name, "'") && len(name) > 1 {
+			name = strings.Replace(name[1:len(name)-1], "''", "'", -1)
+		}
+		if s.File == nil || s.File.Sheet[name] == nil {
+			return ""
+		}
+		sheet = s.File.Sheet[name]
+	}
+	// isDate returns 1 if the cell at col and row is a date, 0 if it is
+	// blank and -1 otherwise.
+	isDate := func(col, row int) int {
+		r := sheet.row(row)
+		if r == nil || col >= len(r.Cells) || r.Cells[col] == nil || r.Cells[col].Value == "" {
+			return 0
+		}
+		cell := r.Cells[col]
+		if _, err := cell.Float(); err != nil || !cell.IsTime() {
+			return -1
+		}
+		return 1
+	}
+	for {
+		if horizontal {
+			minRow--
+		} else {
+			minCol--
+		}
+		if minRow < 0 || minCol < 0 {
+			return ""
+		}
+		endCol, endRow, count := minCol, maxRow, maxRow-minRow+1
+		if horizontal {
+			endCol, endRow, count = maxCol, minRow, maxCol-minCol+1
+		}
+		dates, blanks := 0, 0
+		for i := 0; i < count; i++ {
+			col, row := minCol, minRow+i
+			if horizontal {
+				col, row = minCol+i, minRow
+			}
+			switch isDate(col, row) {
+			case 1:
+				dates++
+			case 0:
+				blanks++
+			}
+		}
+		switch {
+		case dates == count:
+			return prefix + GetCellIDStringFromCoords(minCol, minRow) + cellRangeChar + GetCellIDStringFromCoords(endCol, endRow)
+		case blanks != count:
+			return ""
+		}
+	}
+}
+
+// xlsxX14SparklineGroups directly maps the sparklineGroups element in
+// the namespace http://schemas.microsoft.com/office/spreadsheetml/2009/9/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxX14SparklineGroups struct {
+	XMLName        xml.Name                `xml:"sparklineGroups"`
+	SparklineGroup []xlsxX14SparklineGroup `xml:"sparklineGroup"`
+}
+
+// xlsxX14SparklineGroup directly maps the sparklineGroup element in
+// the namespace http://schemas.microsoft.com/office/spreadsheetml/2009/9/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxX14SparklineGroup struct {
+	Type                string            `xml:"type,attr,omitempty"`
+	LineWeight          float64           `xml:"lineWeight,attr,omitempty"`
+	DisplayEmptyCellsAs string            `xml:"displayEmptyCellsAs,attr,omitempty"`
+	Markers             bool              `xml:"markers,attr,omitempty"`
+	High                bool              `xml:"high,attr,omitempty"`
+	Low                 bool              `xml:"low,attr,omitempty"`
+	First               bool              `xml:"first,attr,omitempty"`
+	Last                bool              `xml:"last,attr,omitempty"`
+	Negative            bool              `xml:"negative,attr,omitempty"`
+	DateAxis            bool              `xml:"dateAxis,attr,omitempty"`
+	ColorSeries         *xlsxX14Color     `xml:"colorSeries"`
+	ColorNegative       *xlsxX14Color     `xml:"colorNegative"`
+	ColorMarkers        *xlsxX14Color     `xml:"colorMarkers"`
+	F                   string            `xml:"f,omitempty"`
+	Sparklines          xlsxX14Sparklines `xml:"sparklines"`
+}
+
+// xlsxX14Color directly maps the color elements of a sparklineGroup.
+type xlsxX14Color struct {
+	RGB string `xml:"rgb,attr"`
+}
+
+// xlsxX14Sparklines directly maps the sparklines element in the
+// namespace http://schemas.microsoft.com/office/spreadsheetml/2009/9/main
+type xlsxX14Sparklines struct {
+	Sparkline []xlsxX14Sparkline `xml:"sparkline"`
+}
+
+// xlsxX14Sparkline directly maps the sparkline element in the
+// namespace http://schemas.microsoft.com/office/spreadsheetml/2009/9/main,
+// whose children live in the namespace
+// http://schemas.microsoft.com/office/excel/2006/main
+type xlsxX14Sparkline struct {
+	F     string `xml:"f"`
+	Sqref string `xml:"sqref"`
+}
+
+// makeXLSXSparklineExt converts the SparklineGroups of a sheet into
+// the worksheet extension holding them, intended for internal use
+// only
+func makeXLSXSparklineExt(groups []*SparklineGroup) (*xlsxExt, error) {
+	if len(groups) == 0 {
+		return nil, nil
+	}
+	xGroups := xlsxX14SparklineGroups{}
+	color := func(rgb string) *xlsxX14Color {
+		if rgb == "" {
+			return nil
+		}
+		return &xlsxX14Color{RGB: rgb}
+	}
+	for _, group := range groups {
+		xGroup := xlsxX14SparklineGroup{
+			LineWeight:          group.LineWeight,
+			DisplayEmptyCellsAs: "gap",
+			Markers:             group.ShowMarkers,
+			High:                group.ShowHigh,
+			Low:                 group.ShowLow,
+			First:               group.ShowFirst,
+			Last:                group.ShowLast,
+			Negative:            group.ShowNegative,
+			ColorSeries:         color(group.SeriesColor),
+			ColorNegative:       color(group.NegativeColor),
+			ColorMarkers:        color(group.MarkersColor),
+		}
+		if group.Type != SparklineTypeLine {
+			xGroup.Type = string(group.Type)
+		}
+		if group.DateRange != "" {
+			xGroup.DateAxis = true
+			xGroup.F = group.DateRange
+		}
+		for _, sparkline := range group.Sparklines {
+			xGroup.Sparklines.Sparkline = append(xGroup.Sparklines.Sparkline, xlsxX14Sparkline{
+				F:     sparkline.DataRange,
+				Sqref: sparkline.Location,
+			})
+		}
+		xGroups.SparklineGroup = append(xGroups.SparklineGroup, xGroup)
+	}
+	body, err := xml.Marshal(xGroups)
+	if err != nil {
+		return nil, err
+	}
+	prefixes := map[string]string{"f": "xm", "sqref": "xm"}
+	content, err := prefixElements(body, prefixes, "x14", []xml.Attr{
+		{Name: xml.Name{Local: "xmlns:x14"}, Value: x14Namespace},
+		{Name: xml.Name{Local: "xmlns:xm"}, Value: xmNamespace},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &xlsxExt{URI: sparklineExtURI, Content: content}, nil
+}
+
+// prefixElements rewrites an XML fragment so that every element name
+// carries a namespace prefix, taken from prefixes or defaulting to
+// defaultPrefix, and adds the given namespace declarations to the
+// root element.  encoding/xml can't write prefixed names itself, and
+// Excel doesn't accept extensions written with default namespaces.
+func prefixElements(data []byte, prefixes map[string]string, defaultPrefix string, declarations []xml.Attr) (string, error) {
+	var out bytes.Buffer
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	encoder := xml.NewEncoder(&out)
+	prefixed := func(name xml.Name) xml.Name {
+		prefix, ok := prefixes[name.Local]
+		if !ok {
+			prefix = defaultPrefix
+		}
+		return xml.Name{Local: prefix + ":" + name.Local}
+	}
+	root := true
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			t.Name = prefixed(t.Name)
+			if root {
+				t.Attr = append(declarations, t.Attr...)
+				root = false
+			}
+			token = t
+		case xml.EndElement:
+			t.Name = prefixed(t.Name)
+			token = t
+		}
+		if err := encoder.EncodeToken(token); err != nil {
+			return "", err
+		}
+	}
+	if err := encoder.Flush(); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// readSparklineGroups converts the sparkline extension of a worksheet,
+// if any, into SparklineGroups.
+func readSparklineGroups(extLst *xlsxExtLst) ([]*SparklineGroup, error) {
+	if extLst == nil {
+		return nil, nil
+	}
+	var groups []*SparklineGroup
+	for _, ext := range extLst.Ext {
+		if ext.URI != sparklineExtURI {
+			continue
+		}
+		xGroups := xlsxX14SparklineGroups{}
+		// The namespace prefixes of the extension are usually
+		// declared on its ancestors, so match on local names only.
+		decoder := xml.NewDecoder(strings.NewReader(ext.Content))
+		if err := decoder.Decode(&xGroups); err != nil {
+			return nil, err
+		}
+		for _, xGroup := range xGroups.SparklineGroup {
+			group := &SparklineGroup{
+				Type:         SparklineType(xGroup.Type),
+				LineWeight:   xGroup.LineWeight,
+				ShowMarkers:  xGroup.Markers,
+				ShowHigh:     xGroup.High,
+				ShowLow:      xGroup.Low,
+				ShowFirst:    xGroup.First,
+				ShowLast:     xGroup.Last,
+				ShowNegative: xGroup.Negative,
+				DateRange:    xGroup.F,
+			}
+			if group.Type == "" {
+				group.Type = SparklineTypeLine
+			}
+			if xGroup.ColorSeries != nil {
+				group.SeriesColor = xGroup.ColorSeries.RGB
+			}
+			if xGroup.ColorNegative != nil {
+				group.NegativeColor = xGroup.ColorNegative.RGB
+			}
+			if xGroup.ColorMarkers != nil {
+				group.MarkersColor = xGroup.ColorMarkers.RGB
+			}
+			for _, xSparkline := range xGroup.Sparklines.Sparkline {
+				group.Add(xSparkline.Sqref, xSparkline.F)
+			}
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
@@ -0,0 +1,177 @@
+package xlsx
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SparklineSuite struct{}
+
+var _ = Suite(&SparklineSuite{})
+
+func (s *SparklineSuite) TestAddSparklineGroupValidates(c *C) {
+	sheet := &Sheet{}
+	group := NewSparklineGroup(SparklineType("pie"))
+	group.Add("F2", "Sheet1!A2:E2")
+	c.Assert(sheet.AddSparklineGroup(group), ErrorMatches, "unknown sparkline type 'pie'")
+
+	group = NewSparklineGroup(SparklineTypeLine)
+	c.Assert(sheet.AddSparklineGroup(group), ErrorMatches, "sparkline group has no sparklines")
+
+	group.Add("not a cell", "Sheet1!A2:E2")
+	c.Assert(sheet.AddSparklineGroup(group), NotNil)
+	c.Assert(sheet.SparklineGroups, HasLen, 0)
+}
+
+func (s *SparklineSuite) TestSparklinesAreWrittenAsExtension(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	sheet.AddRow().AddCell().SetInt(1)
+	group := NewSparklineGroup(SparklineTypeColumn)
+	group.ShowHigh = true
+	group.Add("F2", "Sheet1!A2:E2")
+	c.Assert(sheet.AddSparklineGroup(group), IsNil)
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	worksheet := parts["xl/worksheets/sheet1.xml"]
+	expected := `<extLst><ext uri="{05C60535-1F16-4fd2-B633-F4F36F0B64E0}">` +
+		`<x14:sparklineGroups xmlns:x14="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main" xmlns:xm="http://schemas.microsoft.com/office/excel/2006/main">` +
+		`<x14:sparklineGroup type="column" displayEmptyCellsAs="gap" high="true">` +
+		`<x14:colorSeries rgb="FF376092"></x14:colorSeries>` +
+		`<x14:colorNegative rgb="FFD00000"></x14:colorNegative>` +
+		`<x14:colorMarkers rgb="FFD00000"></x14:colorMarkers>` +
+		`<x14:sparklines><x14:sparkline><xm:f>Sheet1!A2:E2</xm:f><xm:sqref>F2</xm:sqref></x14:sparkline></x14:sparklines>` +
+		`</x14:sparklineGroup></x14:sparklineGroups></ext></extLst>`
+	c.Assert(strings.Contains(worksheet, expected), Equals, true)
+}
+
+func (s *SparklineSuite) TestSparklinesRoundTrip(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	sheet.AddRow().AddCell().SetInt(1)
+	group := NewSparklineGroup(SparklineTypeLine)
+	group.ShowMarkers = true
+	group.LineWeight = 1.5
+	group.Add("F2", "Sheet1!A2:E2")
+	group.Add("F3", "Sheet1!A3:E3")
+	c.Assert(sheet.AddSparklineGroup(group), IsNil)
+
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	reread, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	groups := reread.Sheets[0].SparklineGroups
+	c.Assert(groups, HasLen, 1)
+	c.Assert(groups[0].Type, Equals, SparklineTypeLine)
+	c.Assert(groups[0].ShowMarkers, Equals, true)
+	c.Assert(groups[0].LineWeight, Equals, 1.5)
+	c.Assert(groups[0].SeriesColor, Equals, "FF376092")
+	c.Assert(groups[0].Sparklines, DeepEquals, []Sparkline{
+		{Location: "F2", DataRange: "Sheet1!A2:E2"},
+		{Location: "F3", DataRange: "Sheet1!A3:E3"},
+	})
+}
+
+func (s *SparklineSuite) TestSparklineDateAxisIsDetected(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sales")
+	c.Assert(err, IsNil)
+	header := sheet.AddRow()
+	header.AddCell().Value = "Region"
+	for month := time.January; month <= time.March; month++ {
+		header.AddCell().SetDate(time.Date(2020, month, 1, 0, 0, 0, 0, time.UTC))
+	}
+	sheet.AddRow()
+	for _, region := range []string{"East", "West"} {
+		row := sheet.AddRow()
+		row.AddCell().Value = region
+		for i := 1; i <= 3; i++ {
+			row.AddCell().SetInt(i)
+		}
+	}
+	group := NewSparklineGroup(SparklineTypeLine)
+	group.Add("E3", "Sales!B3:D3")
+	group.Add("E4", "Sales!$B$4:$D$4")
+	c.Assert(sheet.AddSparklineGroup(group), IsNil)
+	c.Assert(group.DateRange, Equals, "Sales!B1:D1")
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<x14:sparklineGroup displayEmptyCellsAs="gap" dateAxis="true">`+
+		`.*</x14:colorMarkers><xm:f>Sales!B1:D1</xm:f><x14:sparklines>.*`)
+
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	reread, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(reread.Sheets[0].SparklineGroups[0].DateRange, Equals, "Sales!B1:D1")
+
+	// The dates of columns are those of the column left of them.
+	daily, err := file.AddSheet("Daily")
+	c.Assert(err, IsNil)
+	for day := 1; day <= 3; day++ {
+		row := daily.AddRow()
+		row.AddCell().SetDate(time.Date(2020, 1, day, 0, 0, 0, 0, time.UTC))
+		row.AddCell()
+		row.AddCell().SetInt(day)
+	}
+	vertical := NewSparklineGroup(SparklineTypeColumn)
+	vertical.Add("C4", "C1:C3")
+	c.Assert(daily.AddSparklineGroup(vertical), IsNil)
+	c.Assert(vertical.DateRange, Equals, "A1:A3")
+}
+
+func (s *SparklineSuite) TestSparklineDateAxisIsNotDetected(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	header := sheet.AddRow()
+	header.AddCell().SetDate(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	header.AddCell().Value = "Total"
+	row := sheet.AddRow()
+	row.AddCell().SetInt(1)
+	row.AddCell().SetInt(2)
+
+	for _, dataRanges := range [][]string{
+		// A header that isn't all dates.
+		{"Sheet1!A2:B2"},
+		// Sparklines over different columns.
+		{"Sheet1!A2:A2", "Sheet1!A3:B3"},
+		// A sheet that doesn't exist.
+		{"Other!A2:B2"},
+	} {
+		group := NewSparklineGroup(SparklineTypeLine)
+		for i, dataRange := range dataRanges {
+			group.Add(GetCellIDStringFromCoords(3, i+1), dataRange)
+		}
+		c.Assert(sheet.AddSparklineGroup(group), IsNil)
+		c.Assert(group.DateRange, Equals, "")
+	}
+
+	group := NewSparklineGroup(SparklineTypeLine)
+	group.DateRange = "Sheet1!A9:B9"
+	group.Add("D2", "Sheet1!A2:B2")
+	c.Assert(sheet.AddSparklineGroup(group), IsNil)
+	c.Assert(group.DateRange, Equals, "Sheet1!A9:B9")
+}
+
+func (s *SparklineSuite) TestReadSparklinesDeclaredOnAncestors(c *C) {
+	extLst := &xlsxExtLst{Ext: []xlsxExt{{
+		URI: sparklineExtURI,
+		Content: `<x14:sparklineGroups><x14:sparklineGroup type="stacked" negative="1">` +
+			`<x14:sparklines><x14:sparkline><xm:f>Data!B2:B9</xm:f><xm:sqref>C1</xm:sqref></x14:sparkline></x14:sparklines>` +
+			`</x14:sparklineGroup></x14:sparklineGroups>`,
+	}}}
+	groups, err := readSparklineGroups(extLst)
+	c.Assert(err, IsNil)
+	c.Assert(groups, HasLen, 1)
+	c.Assert(groups[0].Type, Equals, SparklineTypeWinLoss)
+	c.Assert(groups[0].ShowNegative, Equals, true)
+	c.Assert(groups[0].Sparklines, DeepEquals, []Sparkline{{Location: "C1", DataRange: "Data!B2:B9"}})
+}
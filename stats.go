@@ -0,0 +1,124 @@
+package xlsx
+
+// SheetStats describes the size of a Sheet, as returned by File.Stats.
+type SheetStats struct {
+	Name string
+	// Rows and Cols are the number of rows and columns spanned by
+	// the Sheet, empty ones included.
+	Rows int
+	Cols int
+	// Cells counts the cells holding a value or a formula.
+	Cells    int
+	Formulas int
+	// Styles counts the distinct combinations of style and number
+	// format used by the cells of the Sheet, empty cells included.
+	Styles int
+	// MemoryBytes approximates the memory used by the rows, cells
+	// and styles of the Sheet.
+	MemoryBytes int64
+}
+
+// FileStats describes the size of a File, as returned by File.Stats.
+type FileStats struct {
+	Sheets   []SheetStats
+	Cells    int
+	Formulas int
+	// Styles counts the distinct combinations of style and number
+	// format used across the whole File.
+	Styles int
+	// RawPartBytes is the size of the parts that are kept as they
+	// were read, such as drawings or VBA projects.
+	RawPartBytes int64
+	// MemoryBytes approximates the memory used by the File, its
+	// sheets and raw parts included.
+	MemoryBytes int64
+}
+
+// cellStyleKey identifies a style and number format pair by value,
+// since cells read from a file each get their own Style.
+type cellStyleKey struct {
+	style           Style
+	namedStyleIndex int
+	numFmt          string
+}
+
+// newCellStyleKey returns the key of the style of a cell.
+func newCellStyleKey(cell *Cell) cellStyleKey {
+	key := cellStyleKey{numFmt: cell.NumFmt, namedStyleIndex: -1}
+	if cell.style != nil {
+		key.style = *cell.style
+		key.style.NamedStyleIndex = nil
+		if cell.style.NamedStyleIndex != nil {
+			key.namedStyleIndex = *cell.style.NamedStyleIndex
+		}
+	}
+	return key
+}
+
+// Stats returns the size of the File and of each of its sheets, for
+// example to enforce quotas on uploaded workbooks.  Memory usage is an
+// estimate based on the size of the structures and strings held.
+func (f *File) Stats() *FileStats {
+	stats := &FileStats{}
+	fileStyles := make(map[cellStyleKey]bool)
+	for _, sheet := range f.Sheets {
+		sheetStats := sheet.stats(fileStyles)
+		stats.Sheets = append(stats.Sheets, sheetStats)
+		stats.Cells += sheetStats.Cells
+		stats.Formulas += sheetStats.Formulas
+		stats.MemoryBytes += sheetStats.MemoryBytes
+	}
+	stats.Styles = len(fileStyles)
+	for _, data := range f.rawParts {
+		stats.RawPartBytes += int64(len(data))
+	}
+	stats.MemoryBytes += fileBytes + stats.RawPartBytes
+	if f.referenceTable != nil {
+		for _, s := range f.referenceTable.indexedStrings {
+			stats.MemoryBytes += int64(stringBytes + len(s))
+		}
+	}
+	return stats
+}
+
+// stats returns the size of the Sheet, adding the styles used by its
+// cells to fileStyles.
+func (s *Sheet) stats(fileStyles map[cellStyleKey]bool) SheetStats {
+	stats := SheetStats{
+		Name:        s.Name,
+		Rows:        s.MaxRow,
+		Cols:        s.MaxCol,
+		MemoryBytes: int64(sheetBytes + len(s.Name)),
+	}
+	styles := make(map[cellStyleKey]bool)
+	seen := make(map[*Style]bool)
+	for _, row := range s.rowList() {
+		stats.MemoryBytes += pointerBytes
+		if row == nil {
+			continue
+		}
+		row.load()
+		stats.MemoryBytes += int64(rowBytes + cap(row.Cells)*pointerBytes)
+		for _, cell := range row.Cells {
+			if cell == nil {
+				continue
+			}
+			stats.MemoryBytes += int64(cellBytes + len(cell.Value) + len(cell.formula) + len(cell.NumFmt) + len(cell.formulaRef))
+			key := newCellStyleKey(cell)
+			styles[key] = true
+			fileStyles[key] = true
+			if cell.style != nil && !seen[cell.style] {
+				seen[cell.style] = true
+				stats.MemoryBytes += styleBytes
+			}
+			if cell.Value != "" || cell.formula != "" {
+				stats.Cells++
+			}
+			if cell.formula != "" {
+				stats.Formulas++
+			}
+		}
+	}
+	stats.Styles = len(styles)
+	return stats
+}
@@ -0,0 +1,52 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type StatsSuite struct{}
+
+var _ = Suite(&StatsSuite{})
+
+func (s *StatsSuite) TestStats(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Data")
+	c.Assert(err, IsNil)
+	bold := NewStyle()
+	bold.Font.Bold = true
+	for r := 0; r < 3; r++ {
+		row := sheet.AddRow()
+		row.AddCell().SetInt(r)
+		row.AddCell().SetString("text")
+		row.AddCell().SetStyle(bold)
+	}
+	sheet.Cell(3, 0).SetFormula("SUM(A1:A3)")
+	other, err := f.AddSheet("Other")
+	c.Assert(err, IsNil)
+	other.AddRow().AddCell().SetString("x")
+
+	stats := f.Stats()
+	c.Assert(stats.Sheets, HasLen, 2)
+	data := stats.Sheets[0]
+	c.Assert(data.Name, Equals, "Data")
+	c.Assert(data.Rows, Equals, 4)
+	c.Assert(data.Cols, Equals, 3)
+	c.Assert(data.Cells, Equals, 7)
+	c.Assert(data.Formulas, Equals, 1)
+	c.Assert(data.Styles, Equals, 3)
+	c.Assert(data.MemoryBytes > 0, Equals, true)
+	c.Assert(stats.Sheets[1].Cells, Equals, 1)
+	c.Assert(stats.Cells, Equals, 8)
+	c.Assert(stats.Formulas, Equals, 1)
+	c.Assert(stats.Styles, Equals, 3)
+	c.Assert(stats.MemoryBytes > data.MemoryBytes+stats.Sheets[1].MemoryBytes, Equals, true)
+}
+
+func (s *StatsSuite) TestStatsOfLoadedFile(c *C) {
+	f, err := OpenFile("./testdocs/testfile.xlsx")
+	c.Assert(err, IsNil)
+	stats := f.Stats()
+	c.Assert(stats.Sheets, HasLen, len(f.Sheets))
+	c.Assert(stats.Cells > 0, Equals, true)
+	c.Assert(stats.MemoryBytes > 0, Equals, true)
+}
@@ -0,0 +1,277 @@
+package xlsx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StreamCell is a cell written to a StreamFile by WriteTyped, whose
+// value is written as its Type says, so that the numbers and dates of
+// exported columns can be summed and sorted.  CellTypeString and
+// CellTypeInline cells are written as text, CellTypeNumeric cells as
+// numbers, CellTypeBool cells, whose Value is "1" or "0", as booleans,
+// and CellTypeDate cells, whose Value is a date serial number, as
+// numbers shown as dates.  The constructors below make the Value from
+// Go values.  StyleId is the id of a style added with
+// StreamFileBuilder.AddStyle, or 0 for the style the cell would have
+// had otherwise, which for date cells shows their date: a style of
+// their own needs a date number format.  A Null cell is written as the
+// NullPolicy of the file says, see NewNullStreamCell.  A cell with a
+// Hyperlink links to it, see NewHyperlinkStreamCell.  A cell with a
+// Formula holds it, see NewFormulaStreamCell.  A cell with a Note shows
+// it when the mouse hovers over the cell, see WithNote.
+type StreamCell struct {
+	Value     string
+	Type      CellType
+	StyleId   int
+	Null      bool
+	Hyperlink string
+	Formula   string
+	Note      string
+}
+
+// WithStyle returns a copy of the StreamCell given the style whose id,
+// as returned by StreamFileBuilder.AddStyle, is styleId.
+func (c StreamCell) WithStyle(styleId int) StreamCell {
+	c.StyleId = styleId
+	return c
+}
+
+// WithNote returns a copy of the StreamCell with note, a note shown
+// when the mouse hovers over the cell, such as the source of a figure.
+// The notes of a sheet are written along with its end, after its rows.
+func (c StreamCell) WithNote(note string) StreamCell {
+	c.Note = note
+	return c
+}
+
+// NewStringStreamCell returns a StreamCell holding the text value.
+func NewStringStreamCell(value string) StreamCell {
+	return StreamCell{Value: value, Type: CellTypeString}
+}
+
+// NewNullStreamCell returns a StreamCell holding a null, written as the
+// text of the NullPolicy of the file, with its style unless the cell is
+// given one of its own.
+func NewNullStreamCell() StreamCell {
+	return StreamCell{Type: CellTypeString, Null: true}
+}
+
+// NewHyperlinkStreamCell returns a StreamCell holding the text value,
+// which links to target, such as a URL or a mail link, see
+// Sheet.AddExternalHyperlink for the targets allowed.  The link is
+// written along with the end of the sheet, after its rows.
+func NewHyperlinkStreamCell(value, target string) StreamCell {
+	return StreamCell{Value: value, Type: CellTypeString, Hyperlink: target}
+}
+
+// NewFormulaStreamCell returns a StreamCell holding formula, such as
+// "=SUM(B2:B100)" for a totals row, which Excel computes when the file
+// is opened.  The cell has no cached value, which makes the workbook
+// recalculate its formulas when it is opened; a Value set on the cell
+// is written as the cached value of the formula, as a cell of its
+// Type, which may be changed for formulas giving text, booleans or
+// dates, and is shown by the readers that don't compute formulas.
+func NewFormulaStreamCell(formula string) StreamCell {
+	return StreamCell{Type: CellTypeNumeric, Formula: formula}
+}
+
+// NewIntegerStreamCell returns a StreamCell holding the number value.
+func NewIntegerStreamCell(value int) StreamCell {
+	return StreamCell{Value: strconv.Itoa(value), Type: CellTypeNumeric}
+}
+
+// NewFloatStreamCell returns a StreamCell holding the number value.
+func NewFloatStreamCell(value float64) StreamCell {
+	return StreamCell{Value: strconv.FormatFloat(value, 'f', -1, 64), Type: CellTypeNumeric}
+}
+
+// NewBoolStreamCell returns a StreamCell holding the boolean value.
+func NewBoolStreamCell(value bool) StreamCell {
+	if value {
+		return StreamCell{Value: "1", Type: CellTypeBool}
+	}
+	return StreamCell{Value: "0", Type: CellTypeBool}
+}
+
+// NewDateStreamCell returns a StreamCell holding the date of t, in UTC,
+// which is shown with DefaultDateFormat.
+func NewDateStreamCell(t time.Time) StreamCell {
+	serial := TimeToExcelTime(time.Unix(t.Unix(), 0).In(timeLocationUTC), false)
+	return StreamCell{Value: strconv.FormatFloat(serial, 'f', -1, 64), Type: CellTypeDate}
+}
+
+// checkStreamCellValue returns an error if value can't be written as a
+// cell of the given type.
+func checkStreamCellValue(value string, cellType CellType) error {
+	switch cellType {
+	case CellTypeString, CellTypeInline:
+		return nil
+	case CellTypeNumeric, CellTypeDate:
+		if _, ok := parseDecimalNumber(value); !ok {
+			return fmt.Errorf("%q isn't a number", value)
+		}
+		return nil
+	case CellTypeBool:
+		if value != "1" && value != "0" {
+			return fmt.Errorf("%q isn't a boolean, which is 1 or 0", value)
+		}
+		return nil
+	}
+	return fmt.Errorf("cells of type %d can't be streamed", cellType)
+}
+
+// parseDecimalNumber parses value as a finite decimal number, such as
+// "-3.14" or "1e6", written as it is in the XML of a cell, and returns
+// false for the values strconv.ParseFloat accepts but spreadsheets
+// don't, such as "NaN", "Inf" or hexadecimal numbers like "0x1p-2".
+func parseDecimalNumber(value string) (float64, bool) {
+	i := 0
+	if i < len(value) && (value[i] == '+' || value[i] == '-') {
+		i++
+	}
+	digits := 0
+	for ; i < len(value) && value[i] >= '0' && value[i] <= '9'; i++ {
+		digits++
+	}
+	if i < len(value) && value[i] == '.' {
+		for i++; i < len(value) && value[i] >= '0' && value[i] <= '9'; i++ {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return 0, false
+	}
+	if i < len(value) && (value[i] == 'e' || value[i] == 'E') {
+		i++
+		if i < len(value) && (value[i] == '+' || value[i] == '-') {
+			i++
+		}
+		exponent := i
+		for ; i < len(value) && value[i] >= '0' && value[i] <= '9'; i++ {
+		}
+		if i == exponent {
+			return 0, false
+		}
+	}
+	if i != len(value) {
+		return 0, false
+	}
+	// Numbers too large for a float64 are out of range.
+	f, err := strconv.ParseFloat(value, 64)
+	return f, err == nil
+}
+
+// ColumnType is the type of the values of a column of a streamed sheet,
+// see StreamFileBuilder.AddSheetWithTypes.  The strings written to the
+// column by Write are parsed and written as cells of that type.
+type ColumnType int
+
+const (
+	// ColumnTypeString writes the values as they are, as text.
+	ColumnTypeString ColumnType = iota
+	// ColumnTypeInt writes integers, such as "-42", as numbers.
+	ColumnTypeInt
+	// ColumnTypeFloat writes decimal numbers, such as "3.14" or
+	// "1e6", as numbers.
+	ColumnTypeFloat
+	// ColumnTypeDate writes dates, such as "2024-03-15" or RFC 3339
+	// times, as dates.
+	ColumnTypeDate
+	// ColumnTypeBool writes the values accepted by strconv.ParseBool,
+	// such as "true" or "0", as booleans.
+	ColumnTypeBool
+)
+
+// columnTypeZeroPadded is the type of the columns whose values keep
+// their leading zeros as numbers, see StreamFileBuilder.SetLeadingZeros.
+// It can't be given to AddSheetWithTypes.
+const columnTypeZeroPadded ColumnType = -1
+
+// maxZeroPaddedDigits is the most digits of the values written as
+// numbers to columnTypeZeroPadded columns, more than which Excel would
+// round them.
+const maxZeroPaddedDigits = 15
+
+// columnDateLayouts are the layouts of the values of ColumnTypeDate
+// columns.
+var columnDateLayouts = []string{
+	"2006-01-02",
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// String returns the name of ct, such as "int", as given in the
+// schema of a streamed workbook, see StreamFile.Schema.  The columns
+// keeping leading zeros hold integers.
+func (ct ColumnType) String() string {
+	switch ct {
+	case ColumnTypeInt, columnTypeZeroPadded:
+		return "int"
+	case ColumnTypeFloat:
+		return "float"
+	case ColumnTypeDate:
+		return "date"
+	case ColumnTypeBool:
+		return "bool"
+	}
+	return "string"
+}
+
+// cellType returns the CellType of the cells of columns of type ct,
+// which sets the number format of the column.  Decimal numbers are
+// left to the general format.
+func (ct ColumnType) cellType() *CellType {
+	switch ct {
+	case ColumnTypeInt:
+		return CellTypeNumeric.Ptr()
+	case ColumnTypeBool:
+		return CellTypeBool.Ptr()
+	}
+	return nil
+}
+
+// streamCell parses value as a value of a column of type ct.  Empty
+// values are written as empty strings whatever the type.
+func (ct ColumnType) streamCell(value string) (StreamCell, error) {
+	if value == "" || ct == ColumnTypeString {
+		return NewStringStreamCell(value), nil
+	}
+	switch ct {
+	case ColumnTypeInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return StreamCell{}, fmt.Errorf("%q isn't an integer", value)
+		}
+		return StreamCell{Value: strconv.FormatInt(n, 10), Type: CellTypeNumeric}, nil
+	case ColumnTypeFloat:
+		f, ok := parseDecimalNumber(value)
+		if !ok {
+			return StreamCell{}, fmt.Errorf("%q isn't a number", value)
+		}
+		return NewFloatStreamCell(f), nil
+	case ColumnTypeDate:
+		for _, layout := range columnDateLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return NewDateStreamCell(t), nil
+			}
+		}
+		return StreamCell{}, fmt.Errorf("%q isn't a date", value)
+	case columnTypeZeroPadded:
+		if len(value) > maxZeroPaddedDigits || strings.Trim(value, "0123456789") != "" {
+			return NewStringStreamCell(value), nil
+		}
+		n, _ := strconv.ParseInt(value, 10, 64)
+		return StreamCell{Value: strconv.FormatInt(n, 10), Type: CellTypeNumeric}, nil
+	case ColumnTypeBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return StreamCell{}, fmt.Errorf("%q isn't a boolean", value)
+		}
+		return NewBoolStreamCell(b), nil
+	}
+	return StreamCell{}, fmt.Errorf("unknown column type %d", ct)
+}
@@ -0,0 +1,149 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// maxColumnWidth is the widest a column can be, in characters.
+const maxColumnWidth = 255
+
+// autoWidthPadding is the width added to that of the widest value of a
+// column sized to its contents, in characters, for the margins of its
+// cells.
+const autoWidthPadding = 2
+
+// defaultColumnTypeWidths are the widths of the columns of each type,
+// in characters, unless set with StreamFileBuilder.SetColumnTypeWidth.
+// Excel shows dates in the short date format of its locale, such as
+// 03/15/2024, which doesn't fit in ColWidth, and booleans as TRUE or
+// FALSE.  The other types default to ColWidth.
+var defaultColumnTypeWidths = map[ColumnType]float64{
+	ColumnTypeDate: 12,
+	ColumnTypeBool: 7,
+}
+
+// setColumnTypeWidths gives the typed columns whose width wasn't set
+// the width of their type, but those of the sheets sized to their
+// contents.
+func (sb *StreamFileBuilder) setColumnTypeWidths() {
+	for sheetIndex, sheet := range sb.xlsxFile.Sheets {
+		if sheetIndex >= len(sb.columnTypes) || sb.autoColumnWidths[sheetIndex] {
+			continue
+		}
+		for colIndex, columnType := range sb.columnTypes[sheetIndex] {
+			if colIndex >= len(sheet.Cols) || sheet.Cols[colIndex].Width != 0 {
+				continue
+			}
+			width, ok := sb.columnTypeWidths[columnType]
+			if !ok {
+				width = defaultColumnTypeWidths[columnType]
+			}
+			sheet.Cols[colIndex].Width = width
+		}
+	}
+}
+
+// measure records the width of a value written to the column at
+// colIndex of a sheet sized to its contents, in characters, which for
+// text on several lines is that of its longest line.  Dates are shown
+// by their number format, and booleans as TRUE or FALSE.
+func (ss *streamSheet) measure(colIndex int, value string, cellType CellType) {
+	width := 0
+	switch cellType {
+	case CellTypeDate:
+		width = len(DefaultDateFormat)
+	case CellTypeBool:
+		width = len("FALSE")
+	default:
+		line := 0
+		for _, r := range value {
+			if r == '\n' {
+				line = 0
+				continue
+			}
+			if line++; line > width {
+				width = line
+			}
+		}
+	}
+	if colIndex < len(ss.widths) && width > ss.widths[colIndex] {
+		ss.widths[colIndex] = width
+	}
+}
+
+// autoColumnWidth returns the width of a column sized to its contents,
+// whose widest value is chars characters wide.
+func autoColumnWidth(chars int) float64 {
+	width := float64(chars + autoWidthPadding)
+	if width < ColWidth {
+		return ColWidth
+	}
+	if width > maxColumnWidth {
+		return maxColumnWidth
+	}
+	return width
+}
+
+// sizedSheetPrefix returns the start of the XML of a sheet, prefix,
+// with its columns sized to widths, the widths of their widest values,
+// leaving out the columns whose width was set.
+func sizedSheetPrefix(prefix string, widths []int) (string, error) {
+	start := strings.Index(prefix, "<cols>")
+	end := strings.Index(prefix, "</cols>")
+	if start < 0 || end < start {
+		return prefix, nil
+	}
+	end += len("</cols>")
+	cols := xlsxCols{}
+	if err := xml.Unmarshal([]byte(prefix[start:end]), &cols); err != nil {
+		return "", err
+	}
+	for i, col := range cols.Col {
+		if col.CustomWidth {
+			continue
+		}
+		chars := 0
+		for j := col.Min - 1; j < col.Max && j < len(widths); j++ {
+			if j >= 0 && widths[j] > chars {
+				chars = widths[j]
+			}
+		}
+		cols.Col[i].Width = autoColumnWidth(chars)
+		cols.Col[i].CustomWidth = true
+	}
+	var out bytes.Buffer
+	encoder := xml.NewEncoder(&out)
+	if err := encoder.EncodeElement(cols, xml.StartElement{Name: xml.Name{Local: "cols"}}); err != nil {
+		return "", err
+	}
+	if err := encoder.Flush(); err != nil {
+		return "", err
+	}
+	return prefix[:start] + out.String() + prefix[end:], nil
+}
+
+// writeBufferedSheet adds the part of ss, whose XML was kept in a buffer, and writes the XML to it, preceded by the
+// start of the XML of the sheet, with its columns sized, if it is sized to its contents.
+func (sf *StreamFile) writeBufferedSheet(ss *streamSheet) error {
+	buffer := ss.writer.(*bytes.Buffer)
+	sheetPath := sheetFilePathPrefix + strconv.Itoa(ss.index) + sheetFilePathSuffix
+	fileWriter, err := sf.createPart(sheetPath)
+	if err != nil {
+		return err
+	}
+	ss.writer = fileWriter
+	if ss.widths != nil {
+		prefix, err := sizedSheetPrefix(sf.sheetXmlPrefix[ss.index-1], ss.widths)
+		if err != nil {
+			return err
+		}
+		if err := ss.write(prefix); err != nil {
+			return err
+		}
+	}
+	_, err = buffer.WriteTo(fileWriter)
+	return err
+}
@@ -0,0 +1,131 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"fmt"
+	"io"
+)
+
+// StreamCompression is the way a StreamFile compresses its parts, see
+// StreamFileBuilder.SetCompression, or StreamFileBuilder.SetCompressor
+// for other compressors.
+type StreamCompression int
+
+const (
+	// CompressionDeflate compresses the parts with Deflate, the
+	// compressed data of a sheet reaching the writer a block at a time
+	// as the compressor fills its window.  It is the default.
+	CompressionDeflate StreamCompression = iota
+	// CompressionDeflateStreaming compresses the parts with Deflate,
+	// flushing the compressor whenever the StreamFile flushes its rows,
+	// by default after every row, see
+	// StreamFileBuilder.SetFlushInterval, so that the rows written
+	// reach the writer at once, such as for downloads streamed to a
+	// browser.  Each flush ends the current Deflate block, which makes
+	// the file a little larger.
+	CompressionDeflateStreaming
+	// CompressionStore stores the parts uncompressed, which is the
+	// fastest and makes the largest files.
+	CompressionStore
+)
+
+// PartCompressor compresses the parts of a StreamFile in place of the
+// Deflate compressor of archive/zip, see StreamFileBuilder.SetCompressor,
+// such as a faster Deflate implementation or an experimental method
+// like Zstandard, which the readers of the file must know.
+type PartCompressor interface {
+	// Method is the zip method recorded for the parts, such as
+	// zip.Deflate or 93 for Zstandard.
+	Method() uint16
+	// NewWriter returns a writer compressing the data of a part to w,
+	// which is closed once the part is done.  If it has a Flush
+	// method, such as that of compress/flate writers, it is flushed
+	// whenever the StreamFile flushes its rows.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+// deflateStreamingCompressor is the PartCompressor of the StreamFiles
+// using CompressionDeflateStreaming.
+type deflateStreamingCompressor struct{}
+
+func (deflateStreamingCompressor) Method() uint16 {
+	return zip.Deflate
+}
+
+func (deflateStreamingCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return flate.NewWriter(w, flate.DefaultCompression)
+}
+
+// partFlusher is the writer of a part flushed along with the rows.
+type partFlusher interface {
+	Flush() error
+}
+
+// streamCompressor is the compressor of the zip writer of a StreamFile
+// with a PartCompressor.  The zip writer opens a writer for each part,
+// which is kept in current, if it can be flushed, so that the
+// StreamFile can flush it.  The zip writer computes the CRC of the
+// parts as their data is written, so flushing needs nothing else.
+type streamCompressor struct {
+	partCompressor PartCompressor
+	current        partFlusher
+}
+
+// open is the zip.Compressor opening the writer of the next part, which
+// writes to w.
+func (sc *streamCompressor) open(w io.Writer) (io.WriteCloser, error) {
+	writer, err := sc.partCompressor.NewWriter(w)
+	if err != nil {
+		return nil, err
+	}
+	part := &streamCompressorPart{WriteCloser: writer, compressor: sc}
+	if flusher, ok := writer.(partFlusher); ok {
+		sc.current = flusher
+		part.flusher = flusher
+	}
+	return part, nil
+}
+
+// streamCompressorPart is the writer of a part, which stops being
+// flushed once it is closed.
+type streamCompressorPart struct {
+	io.WriteCloser
+	flusher    partFlusher
+	compressor *streamCompressor
+}
+
+func (scp *streamCompressorPart) Close() error {
+	if scp.flusher != nil && scp.compressor.current == scp.flusher {
+		scp.compressor.current = nil
+	}
+	return scp.WriteCloser.Close()
+}
+
+// flush writes the data held by the writer of the current part to the
+// zip writer.
+func (sc *streamCompressor) flush() error {
+	if sc.current == nil {
+		return nil
+	}
+	return sc.current.Flush()
+}
+
+// validateStreamCompression returns an error for the compressions that
+// aren't known.
+func validateStreamCompression(compression StreamCompression) error {
+	switch compression {
+	case CompressionDeflate, CompressionDeflateStreaming, CompressionStore:
+		return nil
+	}
+	return fmt.Errorf("unknown compression %d", compression)
+}
+
+// zipMethod returns the zip method of the parts compressed with
+// compression.
+func (compression StreamCompression) zipMethod() uint16 {
+	if compression == CompressionStore {
+		return zip.Store
+	}
+	return zip.Deflate
+}
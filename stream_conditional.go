@@ -0,0 +1,209 @@
+package xlsx
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ConditionalOperator is the comparison of the value of a cell made by
+// a rule returned by NewCellValueRule.
+type ConditionalOperator string
+
+const (
+	ConditionalLessThan           ConditionalOperator = "lessThan"
+	ConditionalLessThanOrEqual    ConditionalOperator = "lessThanOrEqual"
+	ConditionalEqual              ConditionalOperator = "equal"
+	ConditionalNotEqual           ConditionalOperator = "notEqual"
+	ConditionalGreaterThanOrEqual ConditionalOperator = "greaterThanOrEqual"
+	ConditionalGreaterThan        ConditionalOperator = "greaterThan"
+	// ConditionalBetween and ConditionalNotBetween compare the value
+	// with two values, the bounds being included in the range.
+	ConditionalBetween    ConditionalOperator = "between"
+	ConditionalNotBetween ConditionalOperator = "notBetween"
+)
+
+// ConditionalStyle is the style given to the cells matching a rule
+// returned by NewCellValueRule, on top of their own style. The colors
+// are ARGB colors such as "FF9C0006", the empty ones being left alone.
+type ConditionalStyle struct {
+	FontColor string
+	FillColor string
+	Bold      bool
+}
+
+// ConditionalFormat is a conditional formatting rule of the cells of a
+// streamed sheet, returned by NewColorScale, NewThreeColorScale,
+// NewDataBar and NewCellValueRule, see
+// StreamFileBuilder.AddConditionalFormat.
+type ConditionalFormat struct {
+	// kind is the type of the rule, colorScale, dataBar or cellIs.
+	kind     string
+	colors   []string
+	operator ConditionalOperator
+	values   []string
+	style    ConditionalStyle
+}
+
+// NewColorScale returns a rule filling the cells with a color going
+// from minColor for the lowest value of the range to maxColor for the
+// highest, ARGB colors such as "FFF8696B".
+func NewColorScale(minColor, maxColor string) ConditionalFormat {
+	return ConditionalFormat{kind: "colorScale", colors: []string{minColor, maxColor}}
+}
+
+// NewThreeColorScale returns a rule like NewColorScale, whose colors
+// go through midColor at the median value of the range.
+func NewThreeColorScale(minColor, midColor, maxColor string) ConditionalFormat {
+	return ConditionalFormat{kind: "colorScale", colors: []string{minColor, midColor, maxColor}}
+}
+
+// NewDataBar returns a rule drawing a bar of color, an ARGB color such
+// as "FF638EC6", in the cells, as long as their value is high within
+// the range.
+func NewDataBar(color string) ConditionalFormat {
+	return ConditionalFormat{kind: "dataBar", colors: []string{color}}
+}
+
+// NewCellValueRule returns a rule giving style to the cells whose value
+// compares with values as operator says, such as
+// NewCellValueRule(ConditionalLessThan, ConditionalStyle{FontColor:
+// "FFFF0000"}, "0") showing negative values in red. The values are
+// numbers, quoted strings such as `"Late"` or formulas, and there are
+// two of them for ConditionalBetween and ConditionalNotBetween.
+func NewCellValueRule(operator ConditionalOperator, style ConditionalStyle, values ...string) ConditionalFormat {
+	return ConditionalFormat{kind: "cellIs", operator: operator, values: values, style: style}
+}
+
+// streamConditionalFormat is a conditional format added to the columns
+// of a streamed sheet from startCol to endCol, whose cell value rule, if
+// it is one, has the differential style at dxfId.
+type streamConditionalFormat struct {
+	startCol, endCol int
+	format           ConditionalFormat
+	dxfId            int
+}
+
+// AddConditionalFormat adds format, a conditional formatting rule such as one returned by NewCellValueRule, to the
+// cells of the columns of a sheet from startCol to endCol, which start at 0, below its header row. The range covers the
+// rows written to the sheet, known once it is done, and the rules of a sheet take precedence in the order they are
+// added.
+func (sb *StreamFileBuilder) AddConditionalFormat(sheetIndex, startCol, endCol int, format ConditionalFormat) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if sheetIndex < 0 || sheetIndex >= len(sb.xlsxFile.Sheets) {
+		return fmt.Errorf("no sheet at index %d", sheetIndex)
+	}
+	sheet := sb.xlsxFile.Sheets[sheetIndex]
+	for _, colIndex := range []int{startCol, endCol} {
+		if colIndex < 0 || colIndex >= len(sheet.Cols) {
+			return fmt.Errorf("no column at index %d in sheet '%s'", colIndex, sheet.Name)
+		}
+	}
+	if endCol < startCol {
+		return fmt.Errorf("invalid columns from %d to %d", startCol, endCol)
+	}
+	cf := streamConditionalFormat{startCol: startCol, endCol: endCol, format: format}
+	switch format.kind {
+	case "colorScale", "dataBar":
+	case "cellIs":
+		values := 1
+		switch format.operator {
+		case ConditionalBetween, ConditionalNotBetween:
+			values = 2
+		case ConditionalLessThan, ConditionalLessThanOrEqual, ConditionalEqual, ConditionalNotEqual,
+			ConditionalGreaterThanOrEqual, ConditionalGreaterThan:
+		default:
+			return fmt.Errorf("invalid conditional operator '%s'", format.operator)
+		}
+		if len(format.values) != values {
+			return fmt.Errorf("the conditional operator %s takes %d values, not %d", format.operator, values, len(format.values))
+		}
+		cf.dxfId = len(sb.conditionalStyles)
+		sb.conditionalStyles = append(sb.conditionalStyles, format.style)
+	default:
+		return fmt.Errorf("invalid conditional format")
+	}
+	sb.conditionalFormats[sheetIndex] = append(sb.conditionalFormats[sheetIndex], cf)
+	return nil
+}
+
+// addConditionalStyles adds the differential styles of the cell value
+// rules to the style sheet among parts.
+func (sb *StreamFileBuilder) addConditionalStyles(parts map[string]string) {
+	if len(sb.conditionalStyles) == 0 {
+		return
+	}
+	var out bytes.Buffer
+	out.WriteString(`<dxfs count="` + strconv.Itoa(len(sb.conditionalStyles)) + `">`)
+	for _, style := range sb.conditionalStyles {
+		out.WriteString(`<dxf>`)
+		if style.Bold || style.FontColor != "" {
+			out.WriteString(`<font>`)
+			if style.Bold {
+				out.WriteString(`<b/>`)
+			}
+			if style.FontColor != "" {
+				out.WriteString(`<color rgb="` + escapeAttr(style.FontColor) + `"/>`)
+			}
+			out.WriteString(`</font>`)
+		}
+		if style.FillColor != "" {
+			// The fill of a differential style is its background
+			// color.
+			out.WriteString(`<fill><patternFill><bgColor rgb="` + escapeAttr(style.FillColor) + `"/></patternFill></fill>`)
+		}
+		out.WriteString(`</dxf>`)
+	}
+	out.WriteString(`</dxfs>`)
+	parts["xl/styles.xml"] = strings.Replace(parts["xl/styles.xml"], "</styleSheet>", out.String()+"</styleSheet>", 1)
+}
+
+// insertStreamConditionalFormats returns the end of the XML of a sheet, suffix, with the conditionalFormatting
+// elements of formats, which cover the rows of the sheet from firstRow to lastRow, starting at 0.
+func insertStreamConditionalFormats(suffix string, formats []streamConditionalFormat, firstRow, lastRow int) string {
+	var out bytes.Buffer
+	for i, cf := range formats {
+		ref := GetCellIDStringFromCoords(cf.startCol, firstRow) + cellRangeChar + GetCellIDStringFromCoords(cf.endCol, lastRow)
+		out.WriteString(`<conditionalFormatting sqref="` + ref + `"><cfRule type="` + cf.format.kind + `"`)
+		if cf.format.kind == "cellIs" {
+			out.WriteString(` dxfId="` + strconv.Itoa(cf.dxfId) + `"`)
+		}
+		out.WriteString(` priority="` + strconv.Itoa(i+1) + `"`)
+		if cf.format.kind == "cellIs" {
+			out.WriteString(` operator="` + string(cf.format.operator) + `">`)
+			for _, value := range cf.format.values {
+				out.WriteString(`<formula>` + escapeAttr(strings.TrimPrefix(value, "=")) + `</formula>`)
+			}
+		} else {
+			out.WriteString(`><` + cf.format.kind + `><cfvo type="min"/>`)
+			if len(cf.format.colors) == 3 {
+				out.WriteString(`<cfvo type="percentile" val="50"/>`)
+			}
+			out.WriteString(`<cfvo type="max"/>`)
+			for _, color := range cf.format.colors {
+				out.WriteString(`<color rgb="` + escapeAttr(color) + `"/>`)
+			}
+			out.WriteString(`</` + cf.format.kind + `>`)
+		}
+		out.WriteString(`</cfRule></conditionalFormatting>`)
+	}
+	// The conditional formats follow the merged cells, if any, and
+	// come before the elements below.
+	i := -1
+	if j := strings.Index(suffix, "</mergeCells>"); j >= 0 {
+		i = j + len("</mergeCells>")
+	} else {
+		for _, tag := range []string{"<dataValidations", "<hyperlinks", "<printOptions", "</worksheet>"} {
+			if j := strings.Index(suffix, tag); j >= 0 && (i < 0 || j < i) {
+				i = j
+			}
+		}
+	}
+	if i < 0 {
+		i = len(suffix)
+	}
+	return suffix[:i] + out.String() + suffix[i:]
+}
@@ -0,0 +1,141 @@
+package xlsx
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// WriteTimeoutError is returned by the methods of a StreamFile writing
+// to the writer of the file, such as NextSheet, Write once its rows fill
+// the buffers of the file, and Close, when the writer blocks past the
+// deadline set by StreamFile.SetWriteDeadline, or for longer than the
+// timeout set by StreamFileBuilder.SetWriteTimeout, as it does when the
+// client of a network connection stops reading.  The file is then left
+// unfinished, and its writer should be closed, which ends the write
+// still blocked, if any.
+type WriteTimeoutError struct {
+	// Deadline is the deadline reached, or the zero time if the
+	// writer stalled.
+	Deadline time.Time
+	// Stalled is the timeout of the writes a write blocked for longer
+	// than, or 0 if the deadline was reached.
+	Stalled time.Duration
+}
+
+func (e *WriteTimeoutError) Error() string {
+	if e.Stalled > 0 {
+		return fmt.Sprintf("the writer of the file stalled, a write blocking for more than %v", e.Stalled)
+	}
+	return fmt.Sprintf("the write deadline %s of the file was reached", e.Deadline.Format(time.RFC3339Nano))
+}
+
+// Timeout returns true, so that the error is a timeout for the code
+// checking the errors of the net package as well.
+func (e *WriteTimeoutError) Timeout() bool {
+	return true
+}
+
+// writeDeadline bounds the time the writes to the writer of a file may
+// block for, see throttledWriter.
+type writeDeadline struct {
+	// mu lets the deadline be set from another goroutine than that
+	// writing the file.
+	mu       sync.Mutex
+	timeout  time.Duration
+	deadline time.Time
+	// stalled is the error of the write that blocked for longer than
+	// the timeout, returned by every write after it, since the writer
+	// may still be blocked in it.
+	stalled error
+}
+
+// setDeadline sets the deadline of the writes, or removes it if it is
+// the zero time.
+func (d *writeDeadline) setDeadline(deadline time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.deadline = deadline
+}
+
+// limit returns the longest the next write may block for, or 0 if
+// there is no limit, and the error to return if it blocks for longer,
+// unless the write can't start at all, in which case err is returned.
+func (d *writeDeadline) limit() (limit time.Duration, timeoutErr, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stalled != nil {
+		return 0, nil, d.stalled
+	}
+	if !d.deadline.IsZero() {
+		limit = time.Until(d.deadline)
+		timeoutErr = &WriteTimeoutError{Deadline: d.deadline}
+		if limit <= 0 {
+			return 0, nil, timeoutErr
+		}
+	}
+	if d.timeout > 0 && (limit == 0 || d.timeout < limit) {
+		limit, timeoutErr = d.timeout, &WriteTimeoutError{Stalled: d.timeout}
+	}
+	return limit, timeoutErr, nil
+}
+
+// write writes p to w, returning the WriteTimeoutError of the limit
+// instead if the write blocks past it.  The writers with a
+// SetWriteDeadline method, such as net.Conn, are given the deadline of
+// the write, while the others are written to from a goroutine of their
+// own, left blocked if the write times out.
+func (d *writeDeadline) write(w io.Writer, p []byte) (int, error) {
+	limit, timeoutErr, err := d.limit()
+	if err != nil {
+		return 0, err
+	}
+	if limit == 0 {
+		return w.Write(p)
+	}
+	if deadliner, ok := w.(interface{ SetWriteDeadline(time.Time) error }); ok {
+		if err := deadliner.SetWriteDeadline(time.Now().Add(limit)); err != nil {
+			return 0, err
+		}
+		n, err := w.Write(p)
+		if timeout, ok := err.(interface{ Timeout() bool }); ok && timeout.Timeout() {
+			return n, timeoutErr
+		}
+		if resetErr := deadliner.SetWriteDeadline(time.Time{}); err == nil {
+			err = resetErr
+		}
+		return n, err
+	}
+	type result struct {
+		n   int
+		err error
+	}
+	done := make(chan result, 1)
+	// The write may go on after a timeout, while the buffer of the
+	// caller is reused.
+	data := append([]byte(nil), p...)
+	go func() {
+		n, err := w.Write(data)
+		done <- result{n, err}
+	}()
+	timer := time.NewTimer(limit)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.n, r.err
+	case <-timer.C:
+		d.mu.Lock()
+		d.stalled = timeoutErr
+		d.mu.Unlock()
+		return 0, timeoutErr
+	}
+}
+
+// validateWriteTimeout returns an error for a negative timeout.
+func validateWriteTimeout(timeout time.Duration) error {
+	if timeout < 0 {
+		return fmt.Errorf("the write timeout %v can't be negative", timeout)
+	}
+	return nil
+}
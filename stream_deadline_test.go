@@ -0,0 +1,129 @@
+package xlsx
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type DeadlineSuite struct{}
+
+var _ = Suite(&DeadlineSuite{})
+
+// stallingWriter is the writer of a network client that stops reading
+// once stalled, its writes blocking until released.
+type stallingWriter struct {
+	mu       sync.Mutex
+	buffer   bytes.Buffer
+	stalled  bool
+	released chan struct{}
+}
+
+func (w *stallingWriter) stall() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stalled = true
+}
+
+func (w *stallingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	stalled := w.stalled
+	w.mu.Unlock()
+	if stalled {
+		<-w.released
+	}
+	return w.buffer.Write(p)
+}
+
+// deadlineWriter is a writer with a write deadline, like a net.Conn,
+// whose writes all time out.
+type deadlineWriter struct {
+	deadlines []time.Time
+}
+
+func (w *deadlineWriter) SetWriteDeadline(deadline time.Time) error {
+	w.deadlines = append(w.deadlines, deadline)
+	return nil
+}
+
+func (w *deadlineWriter) Write(p []byte) (int, error) {
+	return 0, deadlineExceededError{}
+}
+
+// deadlineExceededError is the timeout error of a writer with a write
+// deadline, like that of a net.Conn.
+type deadlineExceededError struct{}
+
+func (deadlineExceededError) Error() string { return "i/o timeout" }
+func (deadlineExceededError) Timeout() bool { return true }
+
+// deadlineStream returns a StreamFile of a sheet writing to w with the
+// write timeout, and a first row written.
+func deadlineStream(c *C, w *stallingWriter, timeout time.Duration) *StreamFile {
+	builder := NewStreamFileBuilder(w)
+	c.Assert(builder.SetWriteTimeout(timeout), IsNil)
+	c.Assert(builder.AddSheet("Orders", []string{"Item"}, nil), IsNil)
+	stream, err := builder.Build()
+	c.Assert(err, IsNil)
+	c.Assert(stream.Write([]string{"Pens"}), IsNil)
+	return stream
+}
+
+func (s *DeadlineSuite) TestWriteTimeout(c *C) {
+	w := &stallingWriter{released: make(chan struct{})}
+	defer close(w.released)
+	stream := deadlineStream(c, w, 20*time.Millisecond)
+	w.stall()
+	start := time.Now()
+	err := stream.Close()
+	c.Assert(time.Since(start) < time.Second, Equals, true)
+	timeoutErr, ok := err.(*WriteTimeoutError)
+	c.Assert(ok, Equals, true)
+	c.Assert(timeoutErr.Stalled, Equals, 20*time.Millisecond)
+	c.Assert(timeoutErr.Timeout(), Equals, true)
+	c.Assert(err, ErrorMatches, "the writer of the file stalled, a write blocking for more than 20ms")
+
+	// The writes after it fail at once, the writer being still blocked.
+	_, err = stream.output.Write([]byte("more"))
+	c.Assert(err, Equals, error(timeoutErr))
+	c.Assert(NewStreamFileBuilder(w).SetWriteTimeout(-time.Second), ErrorMatches, "the write timeout -1s can't be negative")
+}
+
+func (s *DeadlineSuite) TestWriteTimeoutNotReached(c *C) {
+	w := &stallingWriter{released: make(chan struct{})}
+	stream := deadlineStream(c, w, time.Minute)
+	c.Assert(stream.Close(), IsNil)
+	file, err := OpenBinary(w.buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(file.Sheets[0].Cell(1, 0).Value, Equals, "Pens")
+}
+
+func (s *DeadlineSuite) TestWriteDeadline(c *C) {
+	w := &stallingWriter{released: make(chan struct{})}
+	stream := deadlineStream(c, w, 0)
+	deadline := time.Now().Add(-time.Second)
+	stream.SetWriteDeadline(deadline)
+	timeoutErr, ok := stream.Close().(*WriteTimeoutError)
+	c.Assert(ok, Equals, true)
+	c.Assert(timeoutErr.Deadline.Equal(deadline), Equals, true)
+	c.Assert(timeoutErr.Stalled, Equals, time.Duration(0))
+}
+
+func (s *DeadlineSuite) TestWriteDeadlineOfWriter(c *C) {
+	// A writer with a write deadline of its own is given that of each
+	// write, its timeouts being returned as WriteTimeoutErrors.
+	w := &deadlineWriter{}
+	builder := NewStreamFileBuilder(w)
+	c.Assert(builder.AddSheet("Orders", []string{"Item"}, nil), IsNil)
+	stream, err := builder.Build()
+	c.Assert(err, IsNil)
+	deadline := time.Now().Add(time.Hour)
+	stream.SetWriteDeadline(deadline)
+	timeoutErr, ok := stream.Close().(*WriteTimeoutError)
+	c.Assert(ok, Equals, true)
+	c.Assert(timeoutErr.Deadline.Equal(deadline), Equals, true)
+	c.Assert(len(w.deadlines) > 0, Equals, true)
+	c.Assert(w.deadlines[0].After(time.Now().Add(59*time.Minute)), Equals, true)
+}
@@ -0,0 +1,194 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// themePart is the part holding the theme of a workbook.
+const themePart = "xl/theme/theme1.xml"
+
+// NewStreamFileBuilderFromExisting returns a builder writing to writer a copy of the XLSX file read from r, whose size
+// is given, to whose sheets rows can be appended, such as for exports adding the rows of the day to a workbook. The file
+// is read into memory, and its sheets keep their rows, styles, images and other contents the way File.Write writes
+// them, along with the theme of the file. The rows streamed to one of its sheets follow its existing ones, with as many
+// cells as the sheet has columns, and are written with the default style unless the columns are styled with the
+// setters of the builder. Streaming starts on the first sheet, SkipToSheet moving on to the sheet to append to, and
+// sheets can be added after the existing ones. The file may also be a template, such as a branded workbook with a logo
+// and styled headers, whose sheets are filled from the rows set with SetStartRow.
+func NewStreamFileBuilderFromExisting(r io.ReaderAt, size int64, writer io.Writer) (*StreamFileBuilder, error) {
+	file, err := OpenReaderAt(r, size)
+	if err != nil {
+		return nil, err
+	}
+	sb := NewStreamFileBuilder(writer)
+	// File.Write writes the default theme, which the colors and
+	// fonts of the styles of the file may not be made for.
+	if sb.theme, err = readRawPart(r, size, themePart); err != nil {
+		return nil, err
+	}
+	file.sheetNames = make(map[string]bool)
+	for _, sheet := range file.Sheets {
+		file.sheetNames[foldSheetName(sheet.Name)] = true
+		sb.addSheetSettings()
+	}
+	sb.xlsxFile = file
+	sb.existingSheets = len(file.Sheets)
+	return sb, nil
+}
+
+// NewStreamFileBuilderFromExistingPath is like NewStreamFileBuilderFromExisting, reading the XLSX file at
+// existingPath. The file is read before the builder is returned, so the copy may be written to a new file renamed to
+// existingPath once closed, which keeps the existing file whole should writing the copy fail.
+func NewStreamFileBuilderFromExistingPath(existingPath string, writer io.Writer) (*StreamFileBuilder, error) {
+	f, err := os.Open(existingPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return NewStreamFileBuilderFromExisting(f, info.Size(), writer)
+}
+
+// readRawPart returns the content of the part called name of the XLSX file read from r, whose size is given, or the
+// empty string if it has no such part.
+func readRawPart(r io.ReaderAt, size int64, name string) (string, error) {
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return "", err
+	}
+	for _, part := range zipReader.File {
+		if part.Name != name {
+			continue
+		}
+		rc, err := part.Open()
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+		data, err := ioutil.ReadAll(rc)
+		return string(data), err
+	}
+	return "", nil
+}
+
+// SetStartRow sets the index of the row of the sheet at sheetIndex, starting at 0, from which its rows are streamed,
+// such as the row below the headers of a sheet of a template read by NewStreamFileBuilderFromExisting. The rows of the
+// sheet from rowIndex on are left out, the one at rowIndex, if any, being taken as the model of the streamed rows,
+// whose cells are styled like its cells, such as with the number formats of the columns. The cells of the columns
+// without a model are styled like their columns, if styled. Empty rows are added to a sheet with fewer rows.
+func (sb *StreamFileBuilder) SetStartRow(sheetIndex, rowIndex int) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if sheetIndex < 0 || sheetIndex >= len(sb.xlsxFile.Sheets) {
+		return fmt.Errorf("no sheet at index %d", sheetIndex)
+	}
+	if rowIndex < 0 {
+		return fmt.Errorf("invalid start row %d, which can't be negative", rowIndex)
+	}
+	sheet := sb.xlsxFile.Sheets[sheetIndex]
+	styled := func(colIndex int) {
+		for len(sb.styleIds[sheetIndex]) <= colIndex {
+			sb.styleIds[sheetIndex] = append(sb.styleIds[sheetIndex], 0)
+		}
+		// The style id is looked up once the styles are known,
+		// see addStreamStyles.
+		sb.styleIds[sheetIndex][colIndex] = 1
+	}
+	for colIndex, col := range sheet.Cols {
+		if col.inherited != nil {
+			styled(colIndex)
+		}
+	}
+	if model := sheet.row(rowIndex); model != nil {
+		for colIndex, cell := range model.Cells {
+			if cell == nil || colIndex >= len(sheet.Cols) {
+				continue
+			}
+			col := sheet.Cols[colIndex]
+			col.SetStyle(cell.EffectiveStyle())
+			col.numFmt = cell.EffectiveNumberFormat()
+			if col.Min == 0 {
+				// The columns of a sheet without column
+				// definitions cover nothing yet.
+				col.Min, col.Max = colIndex+1, colIndex+1
+			}
+			styled(colIndex)
+		}
+	}
+	for len(sheet.rowList()) > rowIndex {
+		if err := sheet.RemoveRowAtIndex(len(sheet.rowList()) - 1); err != nil {
+			return err
+		}
+	}
+	for len(sheet.rowList()) < rowIndex {
+		sheet.AddRow()
+	}
+	sheet.MaxRow = len(sheet.rowList())
+	return nil
+}
+
+// SkipToSheet moves on to the sheet with the given name, like calls to NextSheet, such as to append rows to a sheet
+// of a file read by NewStreamFileBuilderFromExisting. The sheets skipped are written as they are. The current sheet
+// can be given, in which case nothing happens, but not a sheet already left.
+func (sf *StreamFile) SkipToSheet(name string) error {
+	if sf.err != nil {
+		return sf.err
+	}
+	sheetIndex := -1
+	for i, sheet := range sf.xlsxFile.Sheets {
+		if sheet.Name == name {
+			sheetIndex = i + 1
+			break
+		}
+	}
+	if sheetIndex < 0 {
+		return fmt.Errorf("no sheet named '%s'", name)
+	}
+	if origin := sf.continuedSheets[sheetIndex]; origin != 0 {
+		if sf.currentSheet != nil && sf.currentSheet.index == sheetIndex {
+			return nil
+		}
+		return fmt.Errorf("the sheet '%s' continues the rows of sheet '%s'", name, sf.xlsxFile.Sheets[origin-1].Name)
+	}
+	if sf.currentSheet != nil {
+		// The rows of the current sheet may continue those of the
+		// sheet at position.
+		position := sf.settingsIndex(sf.currentSheet.index)
+		if sheetIndex < position || sheetIndex == position && position != sf.currentSheet.index {
+			return fmt.Errorf("the sheet '%s' was already written", name)
+		}
+	}
+	if sf.currentSheet != nil && sheetIndex <= sf.currentSheet.lastIndex() && sheetIndex != sf.currentSheet.index {
+		return fmt.Errorf("the sheet '%s' is written along with the current one", name)
+	}
+	for sf.currentSheet == nil || sf.settingsIndex(sf.currentSheet.index) < sheetIndex {
+		if err := sf.NextSheet(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeExistingDimensionTag returns the XML of a sheet of a file read by NewStreamFileBuilderFromExisting, data,
+// without its dimension tag, whose range comes from its rows rather than from the columns and rows of the sheet.
+func removeExistingDimensionTag(data string) (string, error) {
+	start := strings.Index(data, `<dimension ref="`)
+	if start < 0 {
+		return "", errors.New("unexpected Sheet XML: dimension tag not found")
+	}
+	end := strings.Index(data[start:], `"></dimension>`)
+	if end < 0 {
+		return "", errors.New("unexpected Sheet XML: dimension tag not found")
+	}
+	return data[:start] + data[start+end+len(`"></dimension>`):], nil
+}
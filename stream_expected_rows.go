@@ -0,0 +1,109 @@
+package xlsx
+
+import (
+	"fmt"
+)
+
+// ExpectedRowsError is returned when the number of rows written to a
+// sheet below its header row differs from the number expected, see
+// StreamFileBuilder.SetExpectedRows: as soon as one row too many is
+// written, or when the sheet is finished with too few.
+type ExpectedRowsError struct {
+	Sheet    string
+	Expected int
+	Written  int
+}
+
+// Error returns a description of the ExpectedRowsError.
+func (e *ExpectedRowsError) Error() string {
+	if e.Written > e.Expected {
+		return fmt.Sprintf("more than the %d rows expected were written to the sheet %q", e.Expected, e.Sheet)
+	}
+	return fmt.Sprintf("%d rows were written to the sheet %q, rather than the %d expected", e.Written, e.Sheet,
+		e.Expected)
+}
+
+// SetExpectedRows gives the number of rows that will be written to a sheet below its header row, so that the sheet
+// starts with the dimension tag giving the range of its cells, which Excel and other readers use to size the sheet
+// before reading its rows. Otherwise the tag is left out, or patched once the sheet is written to a seekable output,
+// see NewStreamFileBuilderForPath. The StreamFile fails with an ExpectedRowsError when a row past them is written to
+// the sheet, or when the sheet is finished with fewer rows, since its dimension would be wrong. The sheets continuing
+// the columns of a wide sheet, see SetSplitWideSheets, expect the same rows. The rows of a sheet of the file read by
+// NewStreamFileBuilderFromExisting can't be given, and the rows can't be more than Excel reads.
+func (sb *StreamFileBuilder) SetExpectedRows(sheetIndex, rows int) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if sheetIndex < 0 || sheetIndex >= len(sb.xlsxFile.Sheets) {
+		return fmt.Errorf("no sheet at index %d", sheetIndex)
+	}
+	if sheetIndex < sb.existingSheets {
+		return fmt.Errorf("the sheet at index %d is a sheet of the existing file", sheetIndex)
+	}
+	sheet := sb.xlsxFile.Sheets[sheetIndex]
+	if rows < 0 || len(sheet.Rows)+rows > Excel2006MaxRowCount {
+		return fmt.Errorf("invalid number of rows %d of sheet '%s'", rows, sheet.Name)
+	}
+	for i := sheetIndex; i <= sheetIndex+sb.followOns[sheetIndex]; i++ {
+		sb.expectedRows[i] = rows
+	}
+	return nil
+}
+
+// expectedRowCount returns the number of rows expected below the header
+// row of the sheet at sheetIndex, which starts at 1, or -1 if it isn't
+// known.
+func (sf *StreamFile) expectedRowCount(sheetIndex int) int {
+	if sheetIndex > len(sf.expectedRows) {
+		return -1
+	}
+	return sf.expectedRows[sheetIndex-1]
+}
+
+// startDimensionTag returns the dimension tag a new sheet at sheetIndex,
+// which starts at 1, starts with: that of the rows expected, if known,
+// or else the placeholder of its dimension, if any.
+func (sf *StreamFile) startDimensionTag(sheetIndex int) string {
+	rows := sf.expectedRowCount(sheetIndex)
+	if rows < 0 {
+		return sf.dimensionPlaceholder()
+	}
+	sheet := sf.xlsxFile.Sheets[sheetIndex-1]
+	return fmt.Sprintf(dimensionTag, dimensionRange(len(sheet.Cols), len(sheet.Rows)+rows))
+}
+
+// checkExpectedRows returns an ExpectedRowsError if ss, having written
+// its rows, or one more if more is true, has more or fewer rows than
+// expected.
+func (sf *StreamFile) checkExpectedRows(ss *streamSheet, more bool) error {
+	expected := sf.expectedRowCount(ss.index)
+	if expected < 0 {
+		return nil
+	}
+	sheet := sf.xlsxFile.Sheets[ss.index-1]
+	written := ss.rowCount - len(sheet.Rows)
+	if more {
+		written++
+	}
+	if written > expected || (!more && written < expected) {
+		return &ExpectedRowsError{Sheet: sheet.Name, Expected: expected, Written: written}
+	}
+	return nil
+}
+
+// dimensionRange returns the range of the dimension tag of a sheet of
+// the given numbers of columns and rows.
+func dimensionRange(cols, rows int) string {
+	x := cols - 1
+	y := rows - 1
+	if x < 0 {
+		x = 0
+	}
+	if y < 0 {
+		y = 0
+	}
+	if x == 0 && y == 0 {
+		return "A1"
+	}
+	return "A1:" + GetCellIDStringFromCoords(x, y)
+}
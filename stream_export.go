@@ -0,0 +1,357 @@
+package xlsx
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ExportSpooledError is returned by SpoolSheets and ResumeSpooledSheets
+// for the StreamFile of an Export, whose rows are exported in order.
+var ExportSpooledError = errors.New("the sheets of an export can't be spooled")
+
+// ExportWriters are the writers an Export writes the rows of its sheets
+// to, besides the XLSX file.  Either may be nil.
+type ExportWriters struct {
+	// CSV returns the writer of the CSV of the sheet of the given
+	// name, or a nil writer for a sheet not written as CSV.
+	CSV func(sheet string) (io.Writer, error)
+	// JSON is written a JSON object mapping the names of the sheets to
+	// the arrays of their rows, each an object mapping the headers of
+	// the sheet to the values of the row.
+	JSON io.Writer
+}
+
+// Export is a StreamFile writing the rows of its sheets as CSV and JSON
+// as it writes them to the XLSX file, see StreamFileBuilder.BuildExport,
+// so that a "download as Excel or CSV" feature runs its query once for
+// every format.  The rows are exported as they are written to the file,
+// whatever the method writing them: once passed through the RowHook,
+// with the masks and units of their columns applied, and leaving out
+// the rows skipped, such as the duplicate rows.  The formula columns
+// and the hidden sheets, such as those of the values of dropdowns,
+// aren't exported.
+//
+// The CSV of a sheet holds its header row, then its rows, the numbers
+// as they are written, the dates in ISO 8601, such as 2024-03-15 or
+// 2024-03-15T09:30:00, and the booleans as TRUE and FALSE, as Excel
+// writes them.  The JSON values of the cells are typed: numbers, with
+// the digits written to the file, booleans, and strings for the dates,
+// in ISO 8601, in the date system of the workbook, and the text, while
+// the cells left out of a row are null.  The rows of a sheet continued
+// on other sheets, see StreamFileBuilder.SetRowLimitRollover, are
+// exported as those of a single sheet.  The sheets of an Export can't
+// be spooled.
+type Export struct {
+	*StreamFile
+}
+
+// BuildExport builds the StreamFile like Build, as an Export also
+// writing the rows of its sheets to the writers.
+func (sb *StreamFileBuilder) BuildExport(writers ExportWriters) (*Export, error) {
+	sf, err := sb.Build()
+	if err != nil {
+		return nil, err
+	}
+	sf.export = &streamExport{csvWriters: writers.CSV, date1904: sf.xlsxFile.Date1904}
+	if writers.JSON != nil {
+		sf.export.json = bufio.NewWriter(writers.JSON)
+	}
+	if sf.currentSheet != nil {
+		if err := sf.export.startSheet(sf); err != nil {
+			sf.err = err
+			return nil, err
+		}
+	}
+	return &Export{StreamFile: sf}, nil
+}
+
+// Close closes the StreamFile, then ends the CSV and the JSON, unless
+// the StreamFile fails to close, in which case they are left unfinished
+// as well.
+func (e *Export) Close() error {
+	return e.CloseContext(context.Background())
+}
+
+// CloseContext closes the StreamFile like StreamFile.CloseContext, then
+// ends the CSV and the JSON like Close.
+func (e *Export) CloseContext(ctx context.Context) error {
+	if err := e.StreamFile.CloseContext(ctx); err != nil {
+		return err
+	}
+	return e.export.close()
+}
+
+// streamExport writes the rows of the sheets of a StreamFile as CSV and
+// JSON, see Export.
+type streamExport struct {
+	csvWriters func(sheet string) (io.Writer, error)
+	json       *bufio.Writer
+	// date1904 is true if the dates are in the 1904 date system of
+	// the workbook.
+	date1904 bool
+	// sheet is the index, from 1, of the sheet being exported, that of
+	// the sheet whose rows the current sheet continues if it does, or
+	// 0 before the first sheet.  skipped is true if the sheet is
+	// hidden.
+	sheet   int
+	skipped bool
+	// sheets is the number of sheets written to the JSON.
+	sheets int
+	// csv writes the CSV of the sheet, or is nil.
+	csv *csv.Writer
+	// columns are the indices of the columns exported, the formula
+	// columns aside, and jsonKeys their headers, encoded as JSON.
+	columns  []int
+	jsonKeys [][]byte
+	// rows is the number of rows of the sheet written to the JSON.
+	rows int
+	// values and types are the cells of the row being written, by
+	// column, and present tells those written.
+	values  []string
+	types   []CellType
+	present []bool
+	record  []string
+}
+
+// startSheet starts exporting the current sheet of sf, unless it
+// continues the rows of the sheet being exported.
+func (e *streamExport) startSheet(sf *StreamFile) error {
+	ss := sf.currentSheet
+	origin := sf.settingsIndex(ss.index)
+	if origin == e.sheet {
+		return nil
+	}
+	if err := e.endSheet(); err != nil {
+		return err
+	}
+	sheet := sf.xlsxFile.Sheets[origin-1]
+	e.sheet = origin
+	e.skipped = sheet.Hidden || sheet.VeryHidden
+	if e.skipped {
+		return nil
+	}
+	columns := ss.totalColumnCount()
+	e.values = make([]string, columns)
+	e.types = make([]CellType, columns)
+	e.present = make([]bool, columns)
+	e.columns, e.jsonKeys = e.columns[:0], e.jsonKeys[:0]
+	var headers []string
+	for colIndex := 0; colIndex < columns; colIndex++ {
+		if ss.isFormulaColumn(colIndex) {
+			continue
+		}
+		columnSheet, sheetColIndex := ss.column(colIndex)
+		header := ""
+		if sheetHeaders := streamHeaders(sf.xlsxFile.Sheets[columnSheet.index-1]); sheetColIndex < len(sheetHeaders) {
+			header = sheetHeaders[sheetColIndex]
+		}
+		key, err := json.Marshal(header)
+		if err != nil {
+			return err
+		}
+		e.columns = append(e.columns, colIndex)
+		e.jsonKeys = append(e.jsonKeys, key)
+		headers = append(headers, header)
+	}
+	e.record = make([]string, len(e.columns))
+	if e.csvWriters != nil {
+		w, err := e.csvWriters(sheet.Name)
+		if err != nil {
+			return err
+		}
+		if w != nil {
+			e.csv = csv.NewWriter(w)
+			if err := e.csv.Write(headers); err != nil {
+				return err
+			}
+		}
+	}
+	if e.json != nil {
+		if e.sheets == 0 {
+			e.json.WriteByte('{')
+		} else {
+			e.json.WriteByte(',')
+		}
+		e.sheets++
+		e.rows = 0
+		name, err := json.Marshal(sheet.Name)
+		if err != nil {
+			return err
+		}
+		e.json.Write(name)
+		e.json.WriteString(":[")
+	}
+	return nil
+}
+
+// cell sets the cell of the row being written in the given column, as
+// written to the file.
+func (e *streamExport) cell(colIndex int, value string, cellType CellType) {
+	if e.skipped || colIndex >= len(e.values) {
+		return
+	}
+	e.values[colIndex], e.types[colIndex], e.present[colIndex] = value, cellType, true
+}
+
+// endRow writes the row written to the file as CSV and JSON.
+func (e *streamExport) endRow() error {
+	if e.skipped {
+		return nil
+	}
+	defer func() {
+		for colIndex := range e.present {
+			e.present[colIndex] = false
+		}
+	}()
+	if e.csv != nil {
+		for i, colIndex := range e.columns {
+			e.record[i] = ""
+			if e.present[colIndex] {
+				e.record[i] = e.csvValue(e.values[colIndex], e.types[colIndex])
+			}
+		}
+		if err := e.csv.Write(e.record); err != nil {
+			return err
+		}
+	}
+	if e.json != nil {
+		if e.rows > 0 {
+			e.json.WriteByte(',')
+		}
+		e.rows++
+		e.json.WriteByte('{')
+		for i, colIndex := range e.columns {
+			if i > 0 {
+				e.json.WriteByte(',')
+			}
+			e.json.Write(e.jsonKeys[i])
+			e.json.WriteByte(':')
+			if !e.present[colIndex] {
+				e.json.WriteString("null")
+				continue
+			}
+			value, err := e.jsonValue(e.values[colIndex], e.types[colIndex])
+			if err != nil {
+				return err
+			}
+			e.json.Write(value)
+		}
+		e.json.WriteByte('}')
+	}
+	return nil
+}
+
+// endSheet ends the CSV of the sheet being exported, and its array of
+// rows in the JSON.
+func (e *streamExport) endSheet() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		err := e.csv.Error()
+		e.csv = nil
+		if err != nil {
+			return err
+		}
+	}
+	if e.json != nil && e.sheet != 0 && !e.skipped {
+		e.json.WriteByte(']')
+	}
+	return nil
+}
+
+// close ends the CSV and the JSON.
+func (e *streamExport) close() error {
+	if err := e.endSheet(); err != nil {
+		return err
+	}
+	if e.json == nil {
+		return nil
+	}
+	if e.sheets == 0 {
+		e.json.WriteByte('{')
+	}
+	e.json.WriteByte('}')
+	return e.json.Flush()
+}
+
+// csvValue returns the text of a cell of the given type in a CSV.
+func (e *streamExport) csvValue(value string, cellType CellType) string {
+	switch cellType {
+	case CellTypeBool:
+		if value == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	case CellTypeDate:
+		return e.date(value)
+	}
+	return value
+}
+
+// jsonValue returns the JSON of the value of a cell of the given type.
+// The numbers are written as they are, so that long integers such as
+// identifiers keep their digits.
+func (e *streamExport) jsonValue(value string, cellType CellType) ([]byte, error) {
+	switch cellType {
+	case CellTypeNumeric:
+		if number, ok := exportJSONNumber(value); ok {
+			return []byte(number), nil
+		}
+	case CellTypeBool:
+		return json.Marshal(value == "1")
+	case CellTypeDate:
+		value = e.date(value)
+	}
+	return json.Marshal(value)
+}
+
+// exportJSONNumber returns the decimal number value as a JSON number,
+// the same digits without a plus sign, the leading zeros of its integer
+// part or a decimal point without digits after it.
+func exportJSONNumber(value string) (string, bool) {
+	if _, ok := parseDecimalNumber(value); !ok {
+		return "", false
+	}
+	sign := ""
+	if value[0] == '-' || value[0] == '+' {
+		if value[0] == '-' {
+			sign = "-"
+		}
+		value = value[1:]
+	}
+	mantissa, exponent := value, ""
+	if i := strings.IndexAny(value, "eE"); i >= 0 {
+		mantissa, exponent = value[:i], value[i:]
+	}
+	integer, fraction := mantissa, ""
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		integer, fraction = mantissa[:i], mantissa[i+1:]
+	}
+	if integer = strings.TrimLeft(integer, "0"); integer == "" {
+		integer = "0"
+	}
+	number := sign + integer
+	if fraction != "" {
+		number += "." + fraction
+	}
+	return number + exponent, true
+}
+
+// date returns the date of the serial number value, in the date system
+// of the workbook, in ISO 8601, with its time unless it is midnight.
+func (e *streamExport) date(value string) string {
+	serial, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return value
+	}
+	t := TimeFromExcelTime(serial, e.date1904)
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02T15:04:05")
+}
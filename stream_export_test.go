@@ -0,0 +1,120 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type ExportSuite struct{}
+
+var _ = Suite(&ExportSuite{})
+
+// exportOutputs holds what an Export writes: the XLSX file, the CSV of
+// each sheet and the JSON.
+type exportOutputs struct {
+	xlsx bytes.Buffer
+	csv  map[string]*bytes.Buffer
+	json bytes.Buffer
+}
+
+// writers returns the writers of the CSV of every sheet and of the JSON.
+func (o *exportOutputs) writers() ExportWriters {
+	o.csv = map[string]*bytes.Buffer{}
+	return ExportWriters{
+		CSV: func(sheet string) (io.Writer, error) {
+			o.csv[sheet] = &bytes.Buffer{}
+			return o.csv[sheet], nil
+		},
+		JSON: &o.json,
+	}
+}
+
+func (s *ExportSuite) TestExport(c *C) {
+	var outputs exportOutputs
+	builder := NewStreamFileBuilder(&outputs.xlsx)
+	c.Assert(builder.AddSheetWithTypes("Orders", []string{"Item", "Price", "Total", "Ordered", "Paid"},
+		[]ColumnType{ColumnTypeString, ColumnTypeFloat, ColumnTypeFloat, ColumnTypeDate, ColumnTypeBool}), IsNil)
+	c.Assert(builder.SetColumnFormula(0, 2, "=B{row}*2"), IsNil)
+	c.Assert(builder.AddSheet("Notes", []string{"Note", "Phone"}, nil), IsNil)
+	c.Assert(builder.SetColumnMask(1, 1, MaskDigits(2)), IsNil)
+	export, err := builder.BuildExport(outputs.writers())
+	c.Assert(err, IsNil)
+
+	ordered := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	c.Assert(export.WriteTyped([]StreamCell{NewStringStreamCell("Pens, blue"), NewFloatStreamCell(2.5),
+		NewDateStreamCell(ordered), NewBoolStreamCell(true)}), IsNil)
+	c.Assert(export.Write([]string{"Tape", "1.25", "2024-03-16", "false"}), IsNil)
+	c.Assert(export.NextSheet(), IsNil)
+	c.Assert(export.BeginRow(), IsNil)
+	c.Assert(export.WriteCell("Call back"), IsNil)
+	c.Assert(export.WriteCell("555-1234"), IsNil)
+	c.Assert(export.EndRow(), IsNil)
+	c.Assert(export.WriteSparse(map[int]StreamCell{0: NewStringStreamCell("Nothing to add")}), IsNil)
+	_, err = export.SpoolSheets(nil)
+	c.Assert(err, Equals, ExportSpooledError)
+	c.Assert(export.Close(), IsNil)
+
+	c.Assert(outputs.csv["Orders"].String(), Equals, "Item,Price,Ordered,Paid\n"+
+		"\"Pens, blue\",2.5,2024-03-15,TRUE\n"+
+		"Tape,1.25,2024-03-16,FALSE\n")
+	c.Assert(outputs.csv["Notes"].String(), Equals, "Note,Phone\nCall back,***-**34\nNothing to add,\n")
+	c.Assert(outputs.json.String(), Equals, `{"Orders":[`+
+		`{"Item":"Pens, blue","Price":2.5,"Ordered":"2024-03-15","Paid":true},`+
+		`{"Item":"Tape","Price":1.25,"Ordered":"2024-03-16","Paid":false}],`+
+		`"Notes":[{"Note":"Call back","Phone":"***-**34"},{"Note":"Nothing to add","Phone":null}]}`)
+	var decoded map[string][]map[string]interface{}
+	c.Assert(json.Unmarshal(outputs.json.Bytes(), &decoded), IsNil)
+
+	f, err := OpenBinary(outputs.xlsx.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheet["Orders"].Cell(2, 0).Value, Equals, "Tape")
+	c.Assert(f.Sheet["Notes"].Cell(1, 1).Value, Equals, "***-**34")
+}
+
+func (s *ExportSuite) TestExportSkipsRowsAndHiddenSheets(c *C) {
+	var outputs exportOutputs
+	builder := NewStreamFileBuilder(&outputs.xlsx)
+	c.Assert(builder.AddSheet("Items", []string{"Item"}, nil), IsNil)
+	c.Assert(builder.AddSheet("Lookup", []string{"Code"}, nil), IsNil)
+	c.Assert(builder.AddSheet("Empty", []string{"Nothing"}, nil), IsNil)
+	builder.xlsxFile.Sheets[1].Hidden = true
+	c.Assert(builder.SetRowHook(func(sheet string, headers, cells []string) ([]string, error) {
+		if cells[0] == "skipped" {
+			return nil, SkipRow
+		}
+		return cells, nil
+	}), IsNil)
+	writers := outputs.writers()
+	writers.CSV = nil
+	export, err := builder.BuildExport(writers)
+	c.Assert(err, IsNil)
+	c.Assert(export.Write([]string{"kept"}), IsNil)
+	c.Assert(export.Write([]string{"skipped"}), IsNil)
+	c.Assert(export.NextSheet(), IsNil)
+	c.Assert(export.Write([]string{"hidden"}), IsNil)
+	// The last sheet, left unwritten, is exported without rows.
+	c.Assert(export.Close(), IsNil)
+	c.Assert(outputs.json.String(), Equals, `{"Items":[{"Item":"kept"}],"Empty":[]}`)
+}
+
+func (s *ExportSuite) TestExportNumbersAndDates1904(c *C) {
+	var outputs exportOutputs
+	builder := NewStreamFileBuilder(&outputs.xlsx)
+	c.Assert(builder.AddSheet("Accounts", []string{"Id", "Balance", "Opened"}, nil), IsNil)
+	builder.xlsxFile.Date1904 = true
+	export, err := builder.BuildExport(outputs.writers())
+	c.Assert(err, IsNil)
+	c.Assert(export.WriteTyped([]StreamCell{
+		{Value: "12345678901234567", Type: CellTypeNumeric},
+		{Value: "+007.50", Type: CellTypeNumeric},
+		{Value: "1.5", Type: CellTypeDate},
+	}), IsNil)
+	c.Assert(export.Close(), IsNil)
+	c.Assert(outputs.json.String(), Equals,
+		`{"Accounts":[{"Id":12345678901234567,"Balance":7.50,"Opened":"1904-01-02T12:00:00"}]}`)
+	c.Assert(outputs.csv["Accounts"].String(), Equals, "Id,Balance,Opened\n12345678901234567,+007.50,1904-01-02T12:00:00\n")
+}
@@ -2,10 +2,17 @@ package xlsx
 
 import (
 	"archive/zip"
+	"bytes"
+	"context"
 	"encoding/xml"
 	"errors"
+	"fmt"
 	"io"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
 type StreamFile struct {
@@ -15,7 +22,278 @@ type StreamFile struct {
 	zipWriter      *zip.Writer
 	currentSheet   *streamSheet
 	styleIds       [][]int
-	err            error
+	bandedStyleIds [][]int
+	// dateStyleId is the style of the date cells, see StreamCell.
+	dateStyleId int
+	// cellStyleIds holds the style ids of the styles added with
+	// StreamFileBuilder.AddStyle, by their id less one.
+	cellStyleIds []int
+	err          error
+	// omitCellReferences leaves out the r attributes of the rows and
+	// cells written, see StreamFileBuilder.SetOmitCellReferences.
+	omitCellReferences bool
+	// sharedStrings collects the strings written to the cells when
+	// they are written as shared strings, see
+	// StreamFileBuilder.SetGoogleSheetsCompatible.
+	sharedStrings *RefTable
+	// sharedStringLimit is the most strings the shared strings may
+	// hold, or 0, and inlineStrings the number of strings written
+	// inline past it, see StreamFileBuilder.SetSharedStringLimit.
+	sharedStringLimit int
+	inlineStrings     int
+	// rowHook is called for every row written, see
+	// StreamFileBuilder.SetRowHook.
+	rowHook RowHook
+	// partHook is called with the metadata parts written, see
+	// StreamFileBuilder.SetPartHook.
+	partHook PartHook
+	// sheetRels holds the relationships of each sheet, which are
+	// written once the sheet is done so that relationships can be
+	// added while it is streamed, see AddRelationship.  The parts
+	// added by AddPart wait in pendingParts for the current sheet to
+	// be done, and the content types are written by Close.
+	sheetRels    [][]xlsxWorkbookRelation
+	pendingParts []pendingPart
+	contentTypes xlsxTypes
+	// workbookPart and workbookRelsPart are the workbook and its
+	// relationships made by Build, which are written by Close along
+	// with the sheets added by AddSheet, those past the builtSheets
+	// first ones.
+	workbookPart     string
+	workbookRelsPart string
+	builtSheets      int
+	// rowCounts and cellCounts hold the number of rows and cells
+	// written to each sheet, header included, and partSizes the
+	// number of bytes written to each part, see Summary.
+	rowCounts  []int
+	cellCounts []int
+	partSizes  map[string]int64
+	// partHeaders holds the ZIP header of each part, whose compressed
+	// size is set once the part is done with, that is once the next
+	// part is created or the ZIP writer closed, openPart being the name
+	// of the part until then, see StreamSummary.StoredPartSizes.
+	partHeaders map[string]*zip.FileHeader
+	openPart    string
+	// followOns holds, for each sheet, the number of sheets after it
+	// continuing its columns, see
+	// StreamFileBuilder.SetSplitWideSheets.
+	followOns []int
+	// columnFormulas holds the formula templates of the columns of
+	// each sheet, see StreamFileBuilder.SetColumnFormula.
+	columnFormulas [][]string
+	// columnTypes holds the types of the values of the columns of
+	// each sheet, see StreamFileBuilder.AddSheetWithTypes.
+	columnTypes [][]ColumnType
+	// numericColumns is true for the columns of each sheet declared
+	// numeric by the cell types given to StreamFileBuilder.AddSheet,
+	// and typeWarnings holds the text written to them that isn't a
+	// number, see TypeWarnings.
+	numericColumns [][]bool
+	typeWarnings   []ColumnTypeWarning
+	// longTextPolicies holds the way the long text of each sheet is
+	// written, see StreamFileBuilder.SetLongTextPolicy, and
+	// longTextCells the cells it was written to.
+	longTextPolicies []LongTextPolicy
+	longTextCells    []LongTextCell
+	// localization translates the names of the sheets and the
+	// headers added by AddSheet, see
+	// StreamFileBuilder.SetLocalization.
+	localization *localization
+	// provenance holds the provenance tags of the cells and rows
+	// written, see SetRowProvenance.
+	provenance []Provenance
+	// wrappedXfIds holds the id of the wrapped twin of each style,
+	// given to the text holding line breaks, see
+	// StreamFileBuilder.SetLineBreaks.
+	wrappedXfIds []int
+	// columnMasks holds the masks of the columns of each sheet, see
+	// StreamFileBuilder.SetColumnMask.
+	columnMasks [][]Mask
+	// columnUnits holds the units of the columns of each sheet, see
+	// StreamFileBuilder.SetColumnUnit.
+	columnUnits [][]*Unit
+	// dateColumns holds, for each sheet, the date columns whose style
+	// has the date format, see
+	// StreamFileBuilder.SetNumberFormatsPerColumn.
+	dateColumns [][]bool
+	// conditionalFormats holds the conditional formats of each sheet,
+	// see StreamFileBuilder.AddConditionalFormat.
+	conditionalFormats [][]streamConditionalFormat
+	// maxRows is the number of rows a sheet can hold, past which its
+	// rows continue on a new sheet if rowLimitRollover is true, see
+	// StreamFileBuilder.SetRowLimitRollover, and continuedSheets maps
+	// the index of each of these sheets to that of the sheet whose
+	// rows it continues, both starting at 1.
+	maxRows          int
+	rowLimitRollover bool
+	continuedSheets  map[int]int
+	// duplicateWindow is the number of rows compared with each row
+	// written, and duplicateAction what becomes of duplicates, see
+	// StreamFileBuilder.SetDuplicateRowGuard.  The first duplicates
+	// found are kept in duplicateRows, and all of them counted by
+	// sheet in duplicateCounts.
+	duplicateWindow int
+	duplicateAction DuplicateRowAction
+	duplicateRows   []DuplicateRow
+	duplicateCounts map[string]int
+	// compression is the way the parts are compressed, and compressor
+	// that of the PartCompressor, if any, such as the Deflate
+	// compressor flushed along with the rows of
+	// CompressionDeflateStreaming, see StreamFileBuilder.SetCompression
+	// and StreamFileBuilder.SetCompressor.
+	compression StreamCompression
+	compressor  *streamCompressor
+	// rowThrottle paces the rows written, or is nil, see
+	// StreamFileBuilder.SetThrottle.
+	rowThrottle *throttle
+	// output is the writer of the zip writer, counting the bytes
+	// written to the writer of the file.
+	output *throttledWriter
+	// export writes the rows as CSV and JSON as well, or is nil, see
+	// Export.
+	export *streamExport
+	// noZip64 is true if the file can't use ZIP64 records, see
+	// WithZip64.
+	noZip64 bool
+	// seekable is the writer of the file if it can seek, from
+	// seekableBase, and dimensionRefs the ranges of the sheets written
+	// with a dimension placeholder, by their path, see
+	// patchDimensions.
+	seekable      seekableOutput
+	seekableBase  int64
+	dimensionRefs map[string]string
+	// progressHook is called every progressInterval rows written,
+	// the rows written since it was last called being counted in
+	// progressRows, see StreamFileBuilder.SetProgressHook.
+	progressHook     ProgressHook
+	progressInterval int
+	progressRows     int
+	// The rows are flushed once flushRows rows or flushBytes bytes of
+	// their XML, if not 0, were written since they were last flushed,
+	// unflushedRows and unflushedBytes being counted since then, see
+	// StreamFileBuilder.SetFlushInterval.
+	flushRows      int
+	flushBytes     int
+	unflushedRows  int
+	unflushedBytes int
+	// noteShapeBlocks is the number of blocks of shape ids taken by
+	// the drawings of the notes written so far.
+	noteShapeBlocks int
+	// structSheets maps the structs written by WriteStruct to the
+	// columns of the sheets added by
+	// StreamFileBuilder.AddSheetFromStruct.
+	structSheets []*structSheet
+	// largeIntegerStyleId is the id, as returned by
+	// StreamFileBuilder.AddStyle, of the style of the large integers
+	// written without a style, or 0, see
+	// StreamFileBuilder.SetPlainLargeIntegers.
+	largeIntegerStyleId int
+	// uncachedFormulas is true once a formula cell without a cached
+	// value has been written, the workbook then being set to
+	// recalculate its formulas when it is opened.
+	uncachedFormulas bool
+	// nullStyleId is the id, as returned by
+	// StreamFileBuilder.AddStyle, of the style of the cells holding
+	// nulls, or 0 if the NullPolicy of the file has none.
+	nullStyleId int
+	// autoColumnWidths is true for the sheets sized to their
+	// contents, see StreamFileBuilder.SetAutoColumnWidths.
+	autoColumnWidths []bool
+	// expectedRows is the number of rows expected below the header
+	// row of the sheets of the builder, see
+	// StreamFileBuilder.SetExpectedRows.
+	expectedRows []int
+	// autoFilterRefs holds the range of the autoFilter element in
+	// the suffix of each sheet with filter dropdowns, which covers
+	// the header row until the sheet is done, see
+	// StreamFileBuilder.AddSheetWithOptions.
+	autoFilterRefs []string
+	// spooledSheets holds the sheets written to spools of their own,
+	// each with a StreamFile for which spooled is true, and
+	// sharedStringsLock guards the shared strings once they are, see
+	// SpoolSheets.
+	spooledSheets     []*SpooledSheet
+	spooled           bool
+	sharedStringsLock *sync.Mutex
+}
+
+// StreamSummary describes what a StreamFile has written, so that
+// exporters can log it and check that an export is complete.
+type StreamSummary struct {
+	Sheets []StreamSheetSummary
+	// SharedStrings is the number of shared strings written by
+	// Close, when the strings of the cells are shared, see
+	// StreamFileBuilder.SetGoogleSheetsCompatible.
+	SharedStrings int
+	// InlineStrings is the number of strings written inline rather
+	// than shared, once the shared strings held the most strings
+	// allowed, see StreamFileBuilder.SetSharedStringLimit.
+	InlineStrings int
+	// PartSizes holds the number of bytes written to each part of
+	// the file, before compression.
+	PartSizes map[string]int64
+	// StoredPartSizes holds the number of bytes each part takes in
+	// the file, after compression, see CompressionRatio.  The part
+	// still being written is left out until Close.
+	StoredPartSizes map[string]int64
+	// TypeWarnings reports the text written to the columns declared
+	// numeric, see StreamFile.TypeWarnings.
+	TypeWarnings []ColumnTypeWarning
+	// LongTextCells reports the cells whose text was longer than a
+	// cell holds, see StreamFile.LongTextCells.
+	LongTextCells []LongTextCell
+	// MissingTranslations lists the message keys without text, see
+	// StreamFileBuilder.SetLocalization.
+	MissingTranslations []string
+}
+
+// CompressionRatio returns the number of bytes the parts of
+// StoredPartSizes take in the file over the number of bytes written to
+// them, such as 0.1 for sheets compressed to a tenth of their size, or 1
+// for those stored uncompressed, see StreamFileBuilder.SetCompression.
+// It is 0 if no part was written.
+func (s StreamSummary) CompressionRatio() float64 {
+	var raw, stored int64
+	for name, size := range s.StoredPartSizes {
+		raw += s.PartSizes[name]
+		stored += size
+	}
+	if raw == 0 {
+		return 0
+	}
+	return float64(stored) / float64(raw)
+}
+
+// StreamSheetSummary describes what a StreamFile has written to one of
+// its sheets.  Rows and Cells include the header.
+type StreamSheetSummary struct {
+	Name  string
+	Rows  int
+	Cells int
+	// DuplicateRows is the number of duplicate rows found, see
+	// StreamFileBuilder.SetDuplicateRowGuard.
+	DuplicateRows int
+}
+
+// partSizeWriter writes a part of a StreamFile, counting the bytes
+// written to it.
+type partSizeWriter struct {
+	writer io.Writer
+	sizes  map[string]int64
+	name   string
+}
+
+func (pw *partSizeWriter) Write(p []byte) (int, error) {
+	n, err := pw.writer.Write(p)
+	pw.sizes[pw.name] += int64(n)
+	return n, err
+}
+
+// pendingPart is a part added to a StreamFile, written once the current
+// sheet is done.
+type pendingPart struct {
+	name string
+	data []byte
 }
 
 type streamSheet struct {
@@ -26,19 +304,108 @@ type streamSheet struct {
 	// The number of columns in the sheet
 	columnCount int
 	// The writer to write to this sheet's file in the XLSX Zip file
-	writer   io.Writer
+	writer io.Writer
+	// row is the buffer in which the XML of the row being written is
+	// built, which is written to writer once the row is done.
+	row      *bytes.Buffer
 	styleIds []int
+	// cellOpenings holds, for each column, the start of the c element
+	// of its cells up to the row number, and cellOpeningEnds the rest
+	// of it, as they don't change from one row to the next.
+	cellOpenings    []string
+	cellOpeningEnds []string
+	// bandedStyleIds are the styles of the cells of every other row,
+	// see StreamFileBuilder.SetBandedRows, whose c elements end with
+	// bandedCellOpeningEnds.
+	bandedStyleIds        []int
+	bandedCellOpeningEnds []string
+	// headers are the cells of the header row, passed to the row
+	// hook.
+	headers []string
+	// rowNumber is the number of the row being written, empty if the
+	// cell references are omitted, and banded is true if it is one
+	// of the banded rows.  rowOpen is true while a row started by
+	// BeginRow is written, cellIndex being the column of its next
+	// cell.
+	rowNumber string
+	banded    bool
+	rowOpen   bool
+	cellIndex int
+	// formulas are the formula templates of the columns, empty for
+	// the columns whose cells are written by the caller.
+	formulas []string
+	// columnTypes are the types of the values of the columns, the
+	// columns past them holding strings.
+	columnTypes []ColumnType
+	// numericColumns is true for the columns declared numeric.
+	numericColumns []bool
+	// longTextPolicy is the way the text longer than a cell holds is
+	// written.
+	longTextPolicy LongTextPolicy
+	// masks are the masks of the values of the columns, nil for the
+	// columns written as they are.
+	masks []Mask
+	// units are the units the values of the columns are converted to,
+	// nil for the columns written as they are.
+	units []*Unit
+	// dateColumns are the columns whose style has the date format,
+	// given to their date cells.
+	dateColumns []bool
+	// duplicates holds the rows most recently written, when the
+	// duplicate row guard is on.
+	duplicates *duplicateRowWindow
+	// followOns are the sheets continuing the columns of this one,
+	// whose rows are written along with its rows.  Their XML is
+	// kept in a buffer until this sheet is done.
+	followOns []*streamSheet
+	// widths holds the number of characters of the widest value of
+	// each column, for the sheets sized to their contents, whose XML
+	// is kept in a buffer until they are done, see
+	// StreamFileBuilder.SetAutoColumnWidths.
+	widths []int
+	// hyperlinks holds the links of the cells written to the sheet,
+	// which are written along with its end, and hyperlinkIds the ids
+	// of the relationships to their targets, each target having one.
+	hyperlinks   []xlsxHyperlink
+	hyperlinkIds map[string]string
+	// merges holds the ranges of the cells merged with MergeCells,
+	// which are written along with the end of the sheet.
+	merges []mergeRange
+	// notes holds the notes of the cells written to the sheet, whose
+	// parts are written once it is done.
+	notes []streamNote
+	// outlineLevel is the outline level of the rows written to the
+	// sheet, see StreamFile.SetOutlineLevel.
+	outlineLevel int
 }
 
+// RowHook is called for every row written to a StreamFile, with the name
+// of its sheet, the cells of its header row and the cells of the row. It
+// returns the cells to write instead, which may be cells itself after
+// changing them, or SkipRow to drop the row. Any other error stops the
+// StreamFile and is returned by Write. This makes redaction and
+// transformation rules pluggable, and lets rows be audited as they are
+// written.
+type RowHook func(sheet string, headers, cells []string) ([]string, error)
+
 var (
 	NoCurrentSheetError     = errors.New("no Current Sheet")
 	WrongNumberOfRowsError  = errors.New("invalid number of cells passed to Write. All calls to Write on the same sheet must have the same number of cells")
 	AlreadyOnLastSheetError = errors.New("NextSheet() called, but already on last sheet")
+	RowInProgressError      = errors.New("a row started by BeginRow must be finished by EndRow first")
+	NoRowInProgressError    = errors.New("no row was started by BeginRow")
+	// SkipRow is returned by a RowHook to drop the row rather than
+	// write it.  It isn't returned as an error by Write.
+	SkipRow = errors.New("skip this row")
 )
 
+// defaultWriteChunkSize is the number of records WriteAllContext writes between flushes by default.
+const defaultWriteChunkSize = 1000
+
 // Write will write a row of cells to the current sheet. Every call to Write on the same sheet must contain the
-// same number of cells as the header provided when the sheet was created or an error will be returned. This function
-// will always trigger a flush on success. Currently the only supported data type is string data.
+// same number of cells as the header provided when the sheet was created or an error will be returned. The row is
+// flushed to the io on success, unless a flush interval was set with StreamFileBuilder.SetFlushInterval. Rows missing
+// some of their cells are written with WriteSparse. Currently the only supported data type is string data.
 func (sf *StreamFile) Write(cells []string) error {
 	if sf.err != nil {
 		return sf.err
@@ -48,167 +415,1767 @@ func (sf *StreamFile) Write(cells []string) error {
 		sf.err = err
 		return err
 	}
-	return sf.zipWriter.Flush()
+	return nil
+}
+
+// WriteStyled will write a row of cells to the current sheet like Write, giving each cell the style whose id, as returned
+// by StreamFileBuilder.AddStyle, is at the same index in styleIds, or the style it would have had otherwise for 0. The
+// RowHook, if any, is called with the cells, whose styles are kept.
+func (sf *StreamFile) WriteStyled(cells []string, styleIds []int) error {
+	if sf.err != nil {
+		return sf.err
+	}
+	err := sf.writeStyled(cells, styleIds)
+	if err != nil {
+		sf.err = err
+		return err
+	}
+	return nil
+}
+
+// WriteTyped will write a row of typed cells to the current sheet, like Write, so that numbers, dates and booleans are
+// written as such rather than as strings, see StreamCell. The RowHook, if any, is called with the values of the cells,
+// whose types and styles are kept.
+func (sf *StreamFile) WriteTyped(cells []StreamCell) error {
+	if sf.err != nil {
+		return sf.err
+	}
+	err := sf.writeTyped(cells)
+	if err != nil {
+		sf.err = err
+		return err
+	}
+	return nil
+}
+
+// WriteSparse writes a row of typed cells to the current sheet like WriteTyped, holding only the given cells, keyed by
+// the index of their column, starting at 0 for column A, such as for jagged data. The other columns are left without
+// cells, rather than given empty ones, apart from the formula columns, whose cells are written as in any row and
+// can't be given. The RowHook, if any, is called with the values of the cells, empty for the columns left out.
+func (sf *StreamFile) WriteSparse(cells map[int]StreamCell) error {
+	if sf.err != nil {
+		return sf.err
+	}
+	err := sf.writeSparse(cells)
+	if err != nil {
+		sf.err = err
+		return err
+	}
+	return nil
 }
 
+func (sf *StreamFile) writeSparse(cells map[int]StreamCell) error {
+	ss := sf.currentSheet
+	if ss == nil {
+		return NoCurrentSheetError
+	}
+	row := make([]StreamCell, ss.dataColumnCount())
+	present := make([]bool, len(row))
+	for colIndex, cell := range cells {
+		if colIndex < 0 || colIndex >= ss.totalColumnCount() {
+			return fmt.Errorf("no column at index %d in sheet '%s'", colIndex, sf.xlsxFile.Sheets[ss.index-1].Name)
+		}
+		if ss.isFormulaColumn(colIndex) {
+			return fmt.Errorf("the column at index %d in sheet '%s' holds a formula", colIndex, sf.xlsxFile.Sheets[ss.index-1].Name)
+		}
+		i := ss.dataIndex(colIndex)
+		row[i], present[i] = cell, true
+	}
+	return sf.writeTypedCells(row, present)
+}
+
+// WriteAll writes the records to the current sheet, one row each, and flushes them. It stops at the first record that
+// can't be written, returning a RowError giving its index, after which the StreamFile can no longer be used.
 func (sf *StreamFile) WriteAll(records [][]string) error {
 	if sf.err != nil {
 		return sf.err
 	}
-	for _, row := range records {
+	for i, row := range records {
 		err := sf.write(row)
 		if err != nil {
-			sf.err = err
-			return err
+			sf.err = &RowError{Row: i, Err: err}
+			return sf.err
 		}
 	}
-	return sf.zipWriter.Flush()
+	return sf.flush()
 }
 
-func (sf *StreamFile) write(cells []string) error {
-	if sf.currentSheet == nil {
-		return NoCurrentSheetError
+// WriteAllContext writes the records to the current sheet like WriteAll, flushing them every chunkSize records, 1000
+// if chunkSize is less than 1, so that the XML of a large batch isn't held until the end of the call. The context is
+// checked before each chunk: once it is done, WriteAllContext returns a RowError holding the error of the context and
+// the index of the first record that wasn't written. The records before it are written, and the StreamFile can still
+// be used or closed.
+func (sf *StreamFile) WriteAllContext(ctx context.Context, records [][]string, chunkSize int) error {
+	if sf.err != nil {
+		return sf.err
+	}
+	if chunkSize < 1 {
+		chunkSize = defaultWriteChunkSize
 	}
-	if len(cells) != sf.currentSheet.columnCount {
-		return WrongNumberOfRowsError
+	for i, row := range records {
+		if i%chunkSize == 0 {
+			if i > 0 {
+				if err := sf.flush(); err != nil {
+					sf.err = err
+					return err
+				}
+			}
+			if err := ctx.Err(); err != nil {
+				return &RowError{Row: i, Err: err}
+			}
+		}
+		if err := sf.write(row); err != nil {
+			sf.err = &RowError{Row: i, Err: err}
+			return sf.err
+		}
+	}
+	return sf.flush()
+}
+
+// WriteContext writes a row of cells to the current sheet like Write, unless the context is done, in which case its
+// error is returned and the row isn't written. The StreamFile can then still be used or closed, as after
+// WriteAllContext, so that an export whose client went away can be given up cleanly.
+func (sf *StreamFile) WriteContext(ctx context.Context, cells []string) error {
+	if sf.err != nil {
+		return sf.err
 	}
-	sf.currentSheet.rowCount++
-	if err := sf.currentSheet.write(`<row r="` + strconv.Itoa(sf.currentSheet.rowCount) + `">`); err != nil {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
-	for colIndex, cellData := range cells {
-		// documentation for the c.t (cell.Type) attribute:
-		// b (Boolean): Cell containing a boolean.
-		// d (Date): Cell contains a date in the ISO 8601 format.
-		// e (Error): Cell containing an error.
-		// inlineStr (Inline String): Cell containing an (inline) rich string, i.e., one not in the shared string table.
-		// If this cell type is used, then the cell value is in the is element rather than the v element in the cell (c element).
-		// n (Number): Cell containing a number.
-		// s (Shared String): Cell containing a shared string.
-		// str (String): Cell containing a formula string.
-		cellCoordinate := GetCellIDStringFromCoords(colIndex, sf.currentSheet.rowCount-1)
-		cellType := "inlineStr"
-		cellOpen := `<c r="` + cellCoordinate + `" t="` + cellType + `"`
-		// Add in the style id if the cell isn't using the default style
-		if colIndex < len(sf.currentSheet.styleIds) && sf.currentSheet.styleIds[colIndex] != 0 {
-			cellOpen += ` s="` + strconv.Itoa(sf.currentSheet.styleIds[colIndex]) + `"`
-		}
-		cellOpen += `><is><t>`
-		cellClose := `</t></is></c>`
+	return sf.Write(cells)
+}
 
-		if err := sf.currentSheet.write(cellOpen); err != nil {
-			return err
+// WriteAllContinueOnError writes the records to the current sheet like WriteAll, but skips the records that are
+// rejected, such as those with the wrong number of cells or refused by the RowHook, and goes on with the next ones.
+// The skipped records are reported by a RowErrors error once the others are written. Other errors, such as failures
+// to write the file, still stop the StreamFile and are returned as a RowError.
+func (sf *StreamFile) WriteAllContinueOnError(records [][]string) error {
+	if sf.err != nil {
+		return sf.err
+	}
+	var rejected RowErrors
+	for i, row := range records {
+		cells, skip, err := sf.checkRow(row)
+		if err != nil {
+			rejected = append(rejected, &RowError{Row: i, Err: err})
+			continue
 		}
-		if err := xml.EscapeText(sf.currentSheet.writer, []byte(cellData)); err != nil {
-			return err
+		if skip {
+			continue
 		}
-		if err := sf.currentSheet.write(cellClose); err != nil {
-			return err
+		cells, types, err := sf.typeRow(cells)
+		if err != nil {
+			rejected = append(rejected, &RowError{Row: i, Err: err})
+			continue
+		}
+		if err := sf.writeRow(cells, types, nil, nil, nil); err != nil {
+			sf.err = &RowError{Row: i, Err: err}
+			return sf.err
 		}
 	}
-	if err := sf.currentSheet.write(`</row>`); err != nil {
+	if err := sf.flush(); err != nil {
 		return err
 	}
-	return sf.zipWriter.Flush()
+	if len(rejected) > 0 {
+		return rejected
+	}
+	return nil
 }
 
-// Error reports any error that has occurred during a previous Write or Flush.
-func (sf *StreamFile) Error() error {
-	return sf.err
+// RowError is returned when writing a batch of records fails, Row being the index of the record in the batch.
+type RowError struct {
+	Row int
+	Err error
 }
 
-func (sf *StreamFile) Flush() {
-	if sf.err != nil {
-		sf.err = sf.zipWriter.Flush()
+// Error returns a description of the RowError.
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+// Unwrap returns the error of the row.
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// RowErrors is returned by WriteAllContinueOnError for the records that were skipped.
+type RowErrors []*RowError
+
+// Error returns a description of the first RowError and of the number of others.
+func (e RowErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
 	}
+	return fmt.Sprintf("%v, and %d other rows", e[0], len(e)-1)
 }
 
-// NextSheet will switch to the next sheet. Sheets are selected in the same order they were added.
-// Once you leave a sheet, you cannot return to it.
-func (sf *StreamFile) NextSheet() error {
-	if sf.err != nil {
-		return sf.err
+func (sf *StreamFile) write(cells []string) error {
+	return sf.writeNullable(cells, nil)
+}
+
+func (sf *StreamFile) writeStyled(cells []string, styleIds []int) error {
+	if len(styleIds) != len(cells) {
+		return fmt.Errorf("%d style ids given for %d cells", len(styleIds), len(cells))
 	}
-	var sheetIndex int
-	if sf.currentSheet != nil {
-		if sf.currentSheet.index >= len(sf.xlsxFile.Sheets) {
-			sf.err = AlreadyOnLastSheetError
-			return AlreadyOnLastSheetError
+	xfIds, err := sf.resolveStyleIds(styleIds)
+	if err != nil {
+		return err
+	}
+	cells, skip, err := sf.checkRow(cells)
+	if err != nil || skip {
+		return err
+	}
+	cells, types, err := sf.typeRow(cells)
+	if err != nil {
+		return err
+	}
+	return sf.writeRow(cells, types, xfIds, nil, nil)
+}
+
+// resolveStyleIds returns the ids in the style sheet of the styles of styleIds, ids returned by
+// StreamFileBuilder.AddStyle, or nil if none of the cells has a style of its own.
+func (sf *StreamFile) resolveStyleIds(styleIds []int) ([]int, error) {
+	var xfIds []int
+	for i, styleId := range styleIds {
+		if styleId == 0 {
+			continue
 		}
-		if err := sf.writeSheetEnd(); err != nil {
-			sf.currentSheet = nil
-			sf.err = err
-			return err
+		if styleId < 0 || styleId > len(sf.cellStyleIds) {
+			return nil, fmt.Errorf("cell %d of the row: style id %d wasn't added with AddStyle", i, styleId)
+		}
+		if xfIds == nil {
+			xfIds = make([]int, len(styleIds))
 		}
-		sheetIndex = sf.currentSheet.index
+		xfIds[i] = sf.cellStyleIds[styleId-1]
 	}
-	sheetIndex++
-	sf.currentSheet = &streamSheet{
-		index:       sheetIndex,
-		columnCount: len(sf.xlsxFile.Sheets[sheetIndex-1].Cols),
-		styleIds:    sf.styleIds[sheetIndex-1],
-		rowCount:    1,
+	return xfIds, nil
+}
+
+// typeRow parses the cells of a row checked by checkRow as the types of their columns, returning the values to write
+// and their cell types, or nil types if the columns of the current sheet all hold strings.
+func (sf *StreamFile) typeRow(cells []string) ([]string, []CellType, error) {
+	if !sf.currentSheet.hasColumnTypes() {
+		return cells, nil, nil
+	}
+	values := make([]string, len(cells))
+	types := make([]CellType, len(cells))
+	dataIndex := 0
+	for colIndex := 0; colIndex < sf.currentSheet.totalColumnCount(); colIndex++ {
+		if sf.currentSheet.isFormulaColumn(colIndex) {
+			continue
+		}
+		i := dataIndex
+		dataIndex++
+		cell, err := sf.currentSheet.columnType(colIndex).streamCell(cells[i])
+		if err != nil {
+			return nil, nil, fmt.Errorf("cell %s: %v", sf.cellRef(colIndex, sf.currentSheet.rowCount), err)
+		}
+		values[i] = cell.Value
+		types[i] = cell.Type
+	}
+	return values, types, nil
+}
+
+func (sf *StreamFile) writeTyped(cells []StreamCell) error {
+	return sf.writeTypedCells(cells, nil)
+}
+
+// writeTypedCells writes a row of typed cells to the current sheet, leaving out those for which present is false, see
+// writeRow.
+func (sf *StreamFile) writeTypedCells(cells []StreamCell, present []bool) error {
+	values := make([]string, len(cells))
+	types := make([]CellType, len(cells))
+	styleIds := make([]int, len(cells))
+	var formulas []string
+	for i, cell := range cells {
+		values[i] = cell.Value
+		types[i] = cell.Type
+		styleIds[i] = cell.StyleId
+		if cell.Formula != "" {
+			if formulas == nil {
+				formulas = make([]string, len(cells))
+			}
+			formulas[i] = cell.Formula
+		}
+		if cell.Null {
+			values[i] = sf.xlsxFile.NullAs.Text
+			types[i] = CellTypeString
+			if cell.StyleId == 0 {
+				styleIds[i] = sf.nullStyleId
+			}
+		}
 	}
-	sheetPath := sheetFilePathPrefix + strconv.Itoa(sf.currentSheet.index) + sheetFilePathSuffix
-	fileWriter, err := sf.zipWriter.Create(sheetPath)
+	xfIds, err := sf.resolveStyleIds(styleIds)
 	if err != nil {
-		sf.err = err
 		return err
 	}
-	sf.currentSheet.writer = fileWriter
-
-	if err := sf.writeSheetStart(); err != nil {
-		sf.err = err
+	targets, err := hyperlinkTargets(cells)
+	if err != nil {
+		return err
+	}
+	notes := streamNotes(cells)
+	values, skip, err := sf.checkRow(values)
+	if err != nil || skip {
 		return err
 	}
+	for i, value := range values {
+		if formulas != nil && formulas[i] != "" && value == "" {
+			// A formula without a cached value.
+			continue
+		}
+		if err := checkStreamCellValue(value, types[i]); err != nil {
+			return fmt.Errorf("cell %d of the row: %v", i, err)
+		}
+	}
+	if err := sf.writeRow(values, types, xfIds, formulas, present); err != nil {
+		return err
+	}
+	sf.addHyperlinks(targets)
+	sf.addNotes(notes)
 	return nil
 }
 
-// Close closes the Stream File.
-// Any sheets that have not yet been written to will have an empty sheet created for them.
-func (sf *StreamFile) Close() error {
-	if sf.err != nil {
-		return sf.err
+// checkRow returns the cells to write for a row of the current sheet, once passed through the row hook, or true if
+// the row is to be skipped. An error rejects the row, nothing being written.
+func (sf *StreamFile) checkRow(cells []string) ([]string, bool, error) {
+	if sf.currentSheet == nil {
+		return nil, false, NoCurrentSheetError
 	}
-	// If there are sheets that have not been written yet, call NextSheet() which will add files to the zip for them.
-	// XLSX readers may error if the sheets registered in the metadata are not present in the file.
-	if sf.currentSheet != nil {
-		for sf.currentSheet.index < len(sf.xlsxFile.Sheets) {
-			if err := sf.NextSheet(); err != nil {
-				sf.err = err
+	if sf.currentSheet.rowOpen {
+		return nil, false, RowInProgressError
+	}
+	if sf.rowHook != nil {
+		sheet := sf.xlsxFile.Sheets[sf.currentSheet.index-1]
+		var err error
+		cells, err = sf.rowHook(sheet.Name, sf.currentSheet.headers, cells)
+		if err == SkipRow {
+			return nil, true, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	if len(cells) != sf.currentSheet.dataColumnCount() {
+		return nil, false, WrongNumberOfRowsError
+	}
+	// Sheets without columns have no rows, the empty rows written to
+	// them are dropped.
+	if sf.currentSheet.totalColumnCount() == 0 {
+		return cells, true, nil
+	}
+	cells, err := sf.validRow(cells)
+	if err != nil {
+		return nil, false, err
+	}
+	return cells, sf.checkDuplicateRow(cells), nil
+}
+
+// validRow returns the cells of a row with their invalid UTF-8 dealt with as the InvalidUTF8 policy of the file says,
+// or a TextError for the first cell that isn't valid UTF-8 if it is to be rejected. The cells are copied before being
+// changed.
+func (sf *StreamFile) validRow(cells []string) ([]string, error) {
+	policy := sf.xlsxFile.InvalidUTF8
+	if policy == InvalidUTF8Replaced {
+		return cells, nil
+	}
+	copied := false
+	dataIndex := 0
+	for colIndex := 0; colIndex < sf.currentSheet.totalColumnCount(); colIndex++ {
+		if sf.currentSheet.isFormulaColumn(colIndex) {
+			continue
+		}
+		i := dataIndex
+		dataIndex++
+		if utf8.ValidString(cells[i]) {
+			continue
+		}
+		if policy == InvalidUTF8Rejected {
+			return nil, sf.cellTextError(colIndex, sf.currentSheet.rowCount, cells[i])
+		}
+		if !copied {
+			cells = append([]string(nil), cells...)
+			copied = true
+		}
+		cells[i] = policy.apply(cells[i])
+	}
+	return cells, nil
+}
+
+// cellTextError returns the TextError for the text of the cell of the current sheet in the given column and row, both
+// zero based.
+func (sf *StreamFile) cellTextError(colIndex, rowIndex int, text string) error {
+	return &TextError{Where: "cell " + sf.cellRef(colIndex, rowIndex), Text: text}
+}
+
+// cellRef returns the reference, such as Sheet1!B2, of the cell of the current sheet in the given column and row, both
+// zero based, which may be on one of its follow-on sheets.
+func (sf *StreamFile) cellRef(colIndex, rowIndex int) string {
+	ss, colIndex := sf.currentSheet.column(colIndex)
+	return sf.xlsxFile.Sheets[ss.index-1].Name + "!" + GetCellIDStringFromCoords(colIndex, rowIndex)
+}
+
+// checkDuplicateRow looks for a row identical to cells among the rows most recently written to the current sheet,
+// reporting it, and returns true if the row is to be skipped as a duplicate.
+func (sf *StreamFile) checkDuplicateRow(cells []string) bool {
+	ss := sf.currentSheet
+	if ss.duplicates == nil {
+		return false
+	}
+	row := ss.rowCount + 1
+	original, hash, ok := ss.duplicates.find(cells)
+	if ok {
+		skip := sf.duplicateAction == SkipDuplicateRows
+		sheet := sf.xlsxFile.Sheets[ss.index-1].Name
+		if len(sf.duplicateRows) < maxDuplicateRows {
+			sf.duplicateRows = append(sf.duplicateRows, DuplicateRow{
+				Sheet:    sheet,
+				Row:      row,
+				Original: original,
+				Skipped:  skip,
+			})
+		}
+		if sf.duplicateCounts == nil {
+			sf.duplicateCounts = make(map[string]int)
+		}
+		sf.duplicateCounts[sheet]++
+		if skip {
+			return true
+		}
+	}
+	ss.duplicates.add(row, hash, cells)
+	return false
+}
+
+// DuplicateRows returns the duplicate rows found so far by the duplicate row guard, see
+// StreamFileBuilder.SetDuplicateRowGuard, up to the first 1000 of them, the Summary counting them all.
+func (sf *StreamFile) DuplicateRows() []DuplicateRow {
+	return append([]DuplicateRow(nil), sf.duplicateRows...)
+}
+
+// writeRow writes a row of cells checked by checkRow to the current sheet, along with its formula cells. The cells are
+// of the given types, or strings if types is nil, and given the styles of xfIds, or those of their column if xfIds is
+// nil. The cells for which present is false are left out, present being nil if every cell is.
+func (sf *StreamFile) writeRow(cells []string, types []CellType, xfIds []int, formulas []string, present []bool) error {
+	if err := sf.startRow(); err != nil {
+		return err
+	}
+	cells, present = sf.splitLongText(cells, types, present)
+	for colIndex := 0; colIndex < sf.currentSheet.totalColumnCount(); colIndex++ {
+		if sf.currentSheet.isFormulaColumn(colIndex) {
+			if err := sf.writeFormulaCell(colIndex); err != nil {
 				return err
 			}
+			continue
 		}
-		// Write the end of the last sheet.
-		if err := sf.writeSheetEnd(); err != nil {
-			sf.err = err
+		cellType := CellTypeString
+		if types != nil {
+			cellType, types = types[0], types[1:]
+		}
+		xfId := 0
+		if xfIds != nil {
+			xfId, xfIds = xfIds[0], xfIds[1:]
+		}
+		formula := ""
+		if formulas != nil {
+			formula, formulas = formulas[0], formulas[1:]
+		}
+		if present != nil {
+			ok := present[0]
+			present = present[1:]
+			if !ok {
+				sf.writeMissingCell(colIndex)
+				cells = cells[1:]
+				continue
+			}
+		}
+		var err error
+		if formula != "" {
+			err = sf.writeCellFormula(colIndex, formula, cells[0], cellType, xfId)
+		} else {
+			err = sf.writeCell(colIndex, cells[0], cellType, xfId)
+		}
+		if err != nil {
 			return err
 		}
+		cells = cells[1:]
 	}
-	err := sf.zipWriter.Close()
-	if err != nil {
-		sf.err = err
-	}
-	return err
+	return sf.endRow()
 }
 
-// writeSheetStart will write the start of the Sheet's XML
-func (sf *StreamFile) writeSheetStart() error {
+// SetOutlineLevel sets the outline level of the rows written to the current sheet from then on, such as 1 for the
+// detail rows grouped under the summary row that follows them, and 0 for the summary row itself, so that the detail
+// rows can be collapsed. The level can be up to the outline levels of the sheet, see StreamSheetOptions.
+func (sf *StreamFile) SetOutlineLevel(level int) error {
+	if sf.err != nil {
+		return sf.err
+	}
 	if sf.currentSheet == nil {
 		return NoCurrentSheetError
 	}
-	return sf.currentSheet.write(sf.sheetXmlPrefix[sf.currentSheet.index-1])
+	if sf.currentSheet.rowOpen {
+		return RowInProgressError
+	}
+	sheet := sf.xlsxFile.Sheets[sf.currentSheet.index-1]
+	if level < 0 || level > int(sheet.streamOutlineLevels) {
+		return fmt.Errorf("invalid outline level %d of sheet '%s', which has %d outline levels", level, sheet.Name,
+			sheet.streamOutlineLevels)
+	}
+	sf.currentSheet.outlineLevel = level
+	for _, followOn := range sf.currentSheet.followOns {
+		followOn.outlineLevel = level
+	}
+	return nil
 }
 
-// writeSheetEnd will write the end of the Sheet's XML
-func (sf *StreamFile) writeSheetEnd() error {
-	if sf.currentSheet == nil {
-		return NoCurrentSheetError
+// startRow writes the start of a new row of the current sheet and of its follow-on sheets, or of the sheet continuing
+// its rows once it is full.
+func (sf *StreamFile) startRow() error {
+	if sf.currentSheet.rowCount >= sf.maxRows {
+		if err := sf.continueRows(); err != nil {
+			return err
+		}
+	}
+	sf.rowThrottle.wait(1)
+	if err := sf.startSheetRow(sf.currentSheet); err != nil {
+		return err
+	}
+	for _, followOn := range sf.currentSheet.followOns {
+		if err := sf.startSheetRow(followOn); err != nil {
+			return err
+		}
 	}
-	if err := sf.currentSheet.write(endSheetDataTag); err != nil {
+	return nil
+}
+
+// startSheetRow writes the start of a new row of ss.
+func (sf *StreamFile) startSheetRow(ss *streamSheet) error {
+	if err := sf.checkExpectedRows(ss, true); err != nil {
 		return err
 	}
-	return sf.currentSheet.write(sf.sheetXmlSuffix[sf.currentSheet.index-1])
+	ss.rowCount++
+	sf.rowCounts[ss.index-1] = ss.rowCount
+	row := ss.rowBuffer()
+	ss.rowNumber = ""
+	row.WriteString(`<row`)
+	if !sf.omitCellReferences {
+		ss.rowNumber = strconv.Itoa(ss.rowCount)
+		row.WriteString(` r="`)
+		row.WriteString(ss.rowNumber)
+		row.WriteString(`"`)
+	}
+	if ss.outlineLevel > 0 {
+		row.WriteString(` outlineLevel="`)
+		row.WriteString(strconv.Itoa(ss.outlineLevel))
+		row.WriteString(`"`)
+	}
+	row.WriteString(`>`)
+	// The header is the first row, banding starts with the second
+	// row after it.
+	ss.banded = ss.bandedStyleIds != nil && ss.rowCount%2 == 1
+	return nil
+}
+
+// writeCell writes the cell of the row being written in the given column, which may be on a follow-on sheet, as a cell
+// of the given type, with the style xfId, or that of the column if xfId is 0.
+func (sf *StreamFile) writeCell(colIndex int, cellData string, cellType CellType, xfId int) error {
+	rowColIndex := colIndex
+	ss, colIndex := sf.currentSheet.column(colIndex)
+	sf.cellCounts[ss.index-1]++
+	if cellType == CellTypeString || cellType == CellTypeInline {
+		sf.checkColumnType(ss, colIndex, cellData)
+	}
+	if mask := ss.mask(colIndex); mask != nil {
+		// The masked values are written as text whatever their type.
+		cellData, cellType = mask(cellData), CellTypeString
+	} else if unit := ss.unit(colIndex); unit != nil && cellType != CellTypeBool && cellType != CellTypeDate {
+		if converted, ok := unit.convert(cellData); ok {
+			cellData, cellType = converted, CellTypeNumeric
+		}
+	}
+	if sf.export != nil {
+		sf.export.cell(rowColIndex, cellData, cellType)
+	}
+	if cellType == CellTypeString || cellType == CellTypeInline {
+		if sf.xlsxFile.LineBreaks != LineBreaksKept {
+			cellData = NormalizeLineBreaks(cellData)
+		}
+		if isLongText(cellData) {
+			cellData = sf.fitLongText(ss, colIndex, cellData)
+		}
+	}
+	if ss.widths != nil {
+		ss.measure(colIndex, cellData, cellType)
+	}
+	if cellType == CellTypeNumeric || cellType == CellTypeBool || cellType == CellTypeDate {
+		return sf.writeValueCell(ss, colIndex, cellData, cellType, xfId)
+	}
+	// documentation for the c.t (cell.Type) attribute:
+	// b (Boolean): Cell containing a boolean.
+	// d (Date): Cell contains a date in the ISO 8601 format.
+	// e (Error): Cell containing an error.
+	// inlineStr (Inline String): Cell containing an (inline) rich string, i.e., one not in the shared string table.
+	// If this cell type is used, then the cell value is in the is element rather than the v element in the cell (c element).
+	// n (Number): Cell containing a number.
+	// s (Shared String): Cell containing a shared string.
+	// str (String): Cell containing a formula string.
+	// Strings are written as inline or shared strings, see
+	// makeCellOpenings, and the other cells by writeValueCell.
+	cellOpeningEnd := ss.cellOpeningEnds[colIndex]
+	if ss.banded {
+		cellOpeningEnd = ss.bandedCellOpeningEnds[colIndex]
+	}
+	if sf.wrappedXfIds != nil && strings.IndexByte(cellData, '\n') >= 0 {
+		xfId = sf.wrappedXfId(ss, colIndex, xfId)
+	}
+	if xfId != 0 {
+		cellOpeningEnd = ` t="inlineStr"` + styleAttribute(xfId) + `><is><t>`
+		if sf.sharedStrings != nil {
+			cellOpeningEnd = ` t="s"` + styleAttribute(xfId) + `><v>`
+		}
+		if !sf.omitCellReferences {
+			cellOpeningEnd = `"` + cellOpeningEnd
+		}
+	}
+	row := ss.rowBuffer()
+	if sf.sharedStrings != nil {
+		index, shared := sf.sharedStringIndex(cellData)
+		if shared {
+			row.WriteString(ss.cellOpenings[colIndex])
+			row.WriteString(ss.rowNumber)
+			row.WriteString(cellOpeningEnd)
+			writeInt(row, index)
+			row.WriteString(`</v></c>`)
+			return nil
+		}
+		cellOpeningEnd = inlineCellOpeningEnd(cellOpeningEnd)
+	}
+	row.WriteString(ss.cellOpenings[colIndex])
+	row.WriteString(ss.rowNumber)
+	row.WriteString(cellOpeningEnd)
+	writeEscapedText(row, escapeXString(cellData))
+	row.WriteString(`</t></is></c>`)
+	return nil
+}
+
+// wrappedXfId returns the id of the wrapped twin of the style of a text cell of ss in the given column, xfId if it
+// isn't 0, see StreamFileBuilder.SetLineBreaks.
+func (sf *StreamFile) wrappedXfId(ss *streamSheet, colIndex int, xfId int) int {
+	styleId := xfId
+	switch {
+	case xfId != 0:
+	case ss.banded:
+		styleId = ss.bandedStyleIds[colIndex]
+	case colIndex < len(ss.styleIds):
+		styleId = ss.styleIds[colIndex]
+	}
+	if styleId < len(sf.wrappedXfIds) {
+		return sf.wrappedXfIds[styleId]
+	}
+	return xfId
+}
+
+// writeValueCell writes the cell of ss in the given column of the row being written as a number or a boolean, date
+// cells being numbers with the date style unless given the style xfId.
+func (sf *StreamFile) writeValueCell(ss *streamSheet, colIndex int, cellData string, cellType CellType, xfId int) error {
+	row := ss.rowBuffer()
+	row.WriteString(ss.cellOpenings[colIndex])
+	row.WriteString(ss.rowNumber)
+	if !sf.omitCellReferences {
+		row.WriteByte('"')
+	}
+	styleId := sf.valueCellStyleId(ss, colIndex, cellData, cellType, xfId)
+	if cellType == CellTypeBool {
+		row.WriteString(` t="b"`)
+	}
+	writeStyleAttribute(row, styleId)
+	row.WriteString(`><v>`)
+	row.WriteString(cellData)
+	row.WriteString(`</v></c>`)
+	return nil
+}
+
+// valueCellStyleId returns the id of the style of a cell of ss written by writeValueCell or writeCellFormula, xfId if
+// it isn't 0.
+func (sf *StreamFile) valueCellStyleId(ss *streamSheet, colIndex int, cellData string, cellType CellType, xfId int) int {
+	styleId := 0
+	switch {
+	case xfId != 0:
+		styleId = xfId
+	case cellType == CellTypeDate && !ss.isDateColumn(colIndex):
+		styleId = sf.dateStyleId
+	case ss.banded:
+		styleId = ss.bandedStyleIds[colIndex]
+	case colIndex < len(ss.styleIds):
+		styleId = ss.styleIds[colIndex]
+	}
+	if styleId == 0 && cellType == CellTypeNumeric && sf.largeIntegerStyleId != 0 && isLargeInteger(cellData) {
+		styleId = sf.cellStyleIds[sf.largeIntegerStyleId-1]
+	}
+	return styleId
+}
+
+// writeCellFormula writes the cell of the row being written in the given column as a formula, such as "=SUM(B2:B9)",
+// with cellData as its cached value, written as a cell of the given type, shown until the formula is recalculated. A
+// formula without a cached value makes the workbook recalculate its formulas when it is opened.
+func (sf *StreamFile) writeCellFormula(colIndex int, formula, cellData string, cellType CellType, xfId int) error {
+	if sf.export != nil {
+		sf.export.cell(colIndex, cellData, cellType)
+	}
+	ss, colIndex := sf.currentSheet.column(colIndex)
+	sf.cellCounts[ss.index-1]++
+	if ss.widths != nil {
+		ss.measure(colIndex, cellData, cellType)
+	}
+	row := ss.rowBuffer()
+	row.WriteString(ss.cellOpenings[colIndex])
+	row.WriteString(ss.rowNumber)
+	if !sf.omitCellReferences {
+		row.WriteByte('"')
+	}
+	styleId := sf.valueCellStyleId(ss, colIndex, cellData, cellType, xfId)
+	if cellData != "" {
+		switch cellType {
+		case CellTypeBool:
+			row.WriteString(` t="b"`)
+		case CellTypeString, CellTypeInline, CellTypeStringFormula:
+			row.WriteString(` t="str"`)
+		}
+	}
+	writeStyleAttribute(row, styleId)
+	row.WriteString(`><f>`)
+	writeEscapedText(row, strings.TrimPrefix(formula, "="))
+	if cellData == "" {
+		sf.uncachedFormulas = true
+		row.WriteString(`</f></c>`)
+		return nil
+	}
+	row.WriteString(`</f><v>`)
+	writeEscapedText(row, cellData)
+	row.WriteString(`</v></c>`)
+	return nil
+}
+
+// writeMissingCell writes the cell of the row being written in the given column when it is left out, see WriteSparse.
+// Without cell references, the cells are placed in the columns in turn, so it is written as an empty cell, which keeps
+// the next cells in their columns.
+func (sf *StreamFile) writeMissingCell(colIndex int) {
+	if sf.omitCellReferences {
+		ss, _ := sf.currentSheet.column(colIndex)
+		ss.rowBuffer().WriteString(`<c></c>`)
+	}
+}
+
+// writeFormulaCell writes the cell of the row being written in the given formula column, its formula template being
+// expanded with the number of the row.
+func (sf *StreamFile) writeFormulaCell(colIndex int) error {
+	ss, colIndex := sf.currentSheet.column(colIndex)
+	sf.cellCounts[ss.index-1]++
+	cellOpeningEnd := ss.cellOpeningEnds[colIndex]
+	if ss.banded {
+		cellOpeningEnd = ss.bandedCellOpeningEnds[colIndex]
+	}
+	row := ss.rowBuffer()
+	row.WriteString(ss.cellOpenings[colIndex])
+	row.WriteString(ss.rowNumber)
+	row.WriteString(cellOpeningEnd)
+	writeEscapedText(row, strings.Replace(ss.formulas[colIndex], "{row}", strconv.Itoa(ss.rowCount), -1))
+	row.WriteString(`</f></c>`)
+	return nil
+}
+
+// writeFormulaCells writes the formula cells of the row started by BeginRow from its next column on, up to the next
+// column whose cell is written by the caller.
+func (sf *StreamFile) writeFormulaCells() error {
+	ss := sf.currentSheet
+	for ss.cellIndex < ss.totalColumnCount() && ss.isFormulaColumn(ss.cellIndex) {
+		if err := sf.writeFormulaCell(ss.cellIndex); err != nil {
+			return err
+		}
+		ss.cellIndex++
+	}
+	return nil
+}
+
+// endRow writes the row being written to its sheet and to its follow-on sheets, flushing the rows if it is time to,
+// see StreamFileBuilder.SetFlushInterval, and reporting the progress made if it is time to.
+func (sf *StreamFile) endRow() error {
+	if sf.export != nil {
+		if err := sf.export.endRow(); err != nil {
+			return err
+		}
+	}
+	if err := sf.endSheetRow(sf.currentSheet); err != nil {
+		return err
+	}
+	for _, followOn := range sf.currentSheet.followOns {
+		if err := sf.endSheetRow(followOn); err != nil {
+			return err
+		}
+	}
+	sf.unflushedRows++
+	if (sf.flushRows > 0 && sf.unflushedRows >= sf.flushRows) || (sf.flushBytes > 0 && sf.unflushedBytes >= sf.flushBytes) {
+		if err := sf.flush(); err != nil {
+			return err
+		}
+	}
+	sf.countProgress()
+	return nil
+}
+
+// endSheetRow writes the row of ss being written to the sheet, counting its bytes until the rows are flushed.
+func (sf *StreamFile) endSheetRow(ss *streamSheet) error {
+	ss.rowBuffer().WriteString(`</row>`)
+	size, err := ss.writeRowBuffer()
+	sf.unflushedBytes += size
+	return err
+}
+
+// BeginRow starts a row of the current sheet whose cells are then written one at a time with WriteCell, for rows so
+// wide that making a slice of all their cells is best avoided. The row must be finished with EndRow, once it has as
+// many cells as the header, formula columns aside, before anything else is written. The RowHook isn't called for these rows, since their
+// cells aren't all known at once.
+func (sf *StreamFile) BeginRow() error {
+	if sf.err != nil {
+		return sf.err
+	}
+	if sf.currentSheet == nil {
+		return NoCurrentSheetError
+	}
+	if sf.currentSheet.rowOpen {
+		return RowInProgressError
+	}
+	if sf.currentSheet.totalColumnCount() == 0 {
+		sf.err = WrongNumberOfRowsError
+		return sf.err
+	}
+	if err := sf.startRow(); err != nil {
+		sf.err = err
+		return err
+	}
+	sf.currentSheet.rowOpen = true
+	sf.currentSheet.cellIndex = 0
+	return nil
+}
+
+// WriteCell writes the next cell of the row started by BeginRow.
+func (sf *StreamFile) WriteCell(value string) error {
+	if sf.err != nil {
+		return sf.err
+	}
+	if sf.currentSheet == nil || !sf.currentSheet.rowOpen {
+		return NoRowInProgressError
+	}
+	if err := sf.writeFormulaCells(); err != nil {
+		sf.err = err
+		return err
+	}
+	if sf.currentSheet.cellIndex >= sf.currentSheet.totalColumnCount() {
+		sf.err = WrongNumberOfRowsError
+		return sf.err
+	}
+	if !utf8.ValidString(value) {
+		if sf.xlsxFile.InvalidUTF8 == InvalidUTF8Rejected {
+			sf.err = sf.cellTextError(sf.currentSheet.cellIndex, sf.currentSheet.rowCount-1, value)
+			return sf.err
+		}
+		value = sf.xlsxFile.InvalidUTF8.apply(value)
+	}
+	cell, err := sf.currentSheet.columnType(sf.currentSheet.cellIndex).streamCell(value)
+	if err != nil {
+		sf.err = fmt.Errorf("cell %s: %v", sf.cellRef(sf.currentSheet.cellIndex, sf.currentSheet.rowCount-1), err)
+		return sf.err
+	}
+	if err := sf.writeCell(sf.currentSheet.cellIndex, cell.Value, cell.Type, 0); err != nil {
+		sf.err = err
+		return err
+	}
+	sf.currentSheet.cellIndex++
+	return nil
+}
+
+// EndRow finishes the row started by BeginRow, which must have as many cells as the header, formula columns aside,
+// and flushes it like Write.
+func (sf *StreamFile) EndRow() error {
+	if sf.err != nil {
+		return sf.err
+	}
+	if sf.currentSheet == nil || !sf.currentSheet.rowOpen {
+		return NoRowInProgressError
+	}
+	if err := sf.writeFormulaCells(); err != nil {
+		sf.err = err
+		return err
+	}
+	if sf.currentSheet.cellIndex != sf.currentSheet.totalColumnCount() {
+		sf.err = WrongNumberOfRowsError
+		return sf.err
+	}
+	sf.currentSheet.rowOpen = false
+	if err := sf.endRow(); err != nil {
+		sf.err = err
+		return err
+	}
+	return nil
+}
+
+// Error reports any error that has occurred during a previous Write or Flush.
+func (sf *StreamFile) Error() error {
+	return sf.err
+}
+
+// Flush writes the rows written so far to the io, whatever the flush interval, see
+// StreamFileBuilder.SetFlushInterval.
+func (sf *StreamFile) Flush() {
+	if sf.err == nil {
+		sf.err = sf.flush()
+	}
+}
+
+// SetWriteDeadline sets the time past which the writes to the writer of the file fail with a WriteTimeoutError
+// instead of blocking, such as a deadline for each sheet, set before writing it, so that an export gives up on a dead
+// connection. Rows are written to the writer as the buffers of the file fill, and when a sheet ends, so the deadline
+// applies to the calls of NextSheet, Write and Close that write to it. A zero deadline removes it. It may be called
+// from another goroutine than that writing the file.
+func (sf *StreamFile) SetWriteDeadline(deadline time.Time) {
+	sf.output.deadline.setDeadline(deadline)
+}
+
+// NextSheet will switch to the next sheet. Sheets are selected in the same order they were added, the follow-on sheets
+// of a wide sheet being written along with it, and the sheets continuing the rows of another one being skipped.
+// Once you leave a sheet, you cannot return to it.
+func (sf *StreamFile) NextSheet() error {
+	if sf.err != nil {
+		return sf.err
+	}
+	if sf.currentSheet != nil && sf.currentSheet.rowOpen {
+		return RowInProgressError
+	}
+	if sf.spooledSheets != nil {
+		return SheetsSpooledError
+	}
+	sheetIndex := 1
+	if sf.currentSheet != nil {
+		sheetIndex = sf.nextSheetIndex()
+		if sheetIndex > len(sf.xlsxFile.Sheets) {
+			sf.err = AlreadyOnLastSheetError
+			return AlreadyOnLastSheetError
+		}
+		if err := sf.writeSheetEnd(); err != nil {
+			sf.currentSheet = nil
+			sf.err = err
+			return err
+		}
+	}
+	return sf.startSheet(sheetIndex)
+}
+
+// nextSheetIndex returns the index, starting at 1, of the sheet NextSheet moves on to from the current one, past its
+// follow-on sheets and the sheets continuing the rows of other ones.
+func (sf *StreamFile) nextSheetIndex() int {
+	sheetIndex := sf.currentSheet.lastIndex() + 1
+	if sf.continuedSheets[sf.currentSheet.index] != 0 {
+		sheetIndex = sf.settingsIndex(sf.currentSheet.index) + 1
+	}
+	for sf.continuedSheets[sheetIndex] != 0 {
+		sheetIndex++
+	}
+	return sheetIndex
+}
+
+// startSheet makes the sheet at sheetIndex, which starts at 1, the current sheet, and starts writing it.
+func (sf *StreamFile) startSheet(sheetIndex int) error {
+	sf.currentSheet = sf.makeStreamSheet(sheetIndex)
+	if sf.currentSheet.widths != nil {
+		// The columns come before the rows, so the start of a sheet
+		// sized to its contents is written once its rows are known.
+		sf.currentSheet.writer = &bytes.Buffer{}
+	} else {
+		sheetPath := sheetFilePathPrefix + strconv.Itoa(sf.currentSheet.index) + sheetFilePathSuffix
+		fileWriter, err := sf.createPart(sheetPath)
+		if err != nil {
+			sf.err = err
+			return err
+		}
+		sf.currentSheet.writer = fileWriter
+
+		if err := sf.writeSheetStart(); err != nil {
+			sf.err = err
+			return err
+		}
+	}
+	for i := 1; sheetIndex-1 < len(sf.followOns) && i <= sf.followOns[sheetIndex-1]; i++ {
+		followOn := sf.makeStreamSheet(sheetIndex + i)
+		followOn.writer = &bytes.Buffer{}
+		// The start of a follow-on sheet sized to its contents waits
+		// for its rows, like that of the sheet it follows on.
+		if followOn.widths == nil {
+			if err := followOn.write(sf.sheetXmlPrefix[followOn.index-1]); err != nil {
+				sf.err = err
+				return err
+			}
+		}
+		sf.currentSheet.followOns = append(sf.currentSheet.followOns, followOn)
+		sf.currentSheet.headers = append(sf.currentSheet.headers, followOn.headers...)
+	}
+	if sf.rowHook != nil {
+		sf.currentSheet.dropFormulaHeaders()
+	}
+	if sf.export != nil {
+		if err := sf.export.startSheet(sf); err != nil {
+			sf.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// AddSheet adds a sheet with the given name and headers after the other sheets, once the file is built, for the sheets
+// found to be needed while streaming. It is written to once NextSheet reaches it, like the sheets added to the
+// StreamFileBuilder, but its columns can't be styled, typed or split across sheets. The name and the headers are
+// message keys if StreamFileBuilder.SetLocalization was called.
+func (sf *StreamFile) AddSheet(name string, headers []string) error {
+	if sf.err != nil {
+		return sf.err
+	}
+	if sf.spooledSheets != nil {
+		return SheetsSpooledError
+	}
+	name, headers = sf.localization.text(name), sf.localization.texts(headers)
+	if len(headers) > Excel2006MaxColumnCount {
+		return &ColumnLimitError{Sheet: name, Columns: len(headers)}
+	}
+	sheet, err := sf.xlsxFile.AddSheet(name)
+	if err != nil {
+		return err
+	}
+	if len(headers) > 0 {
+		row := sheet.AddRow()
+		row.WriteSlice(&headers, -1)
+	}
+	data, err := sf.makeAddedSheetXML(sheet)
+	if err != nil {
+		sf.xlsxFile.removeLastSheet()
+		return err
+	}
+	prefix, suffix, err := splitSheetIntoPrefixAndSuffix(data)
+	if err != nil {
+		sf.xlsxFile.removeLastSheet()
+		return err
+	}
+	sf.sheetXmlPrefix = append(sf.sheetXmlPrefix, prefix)
+	sf.sheetXmlSuffix = append(sf.sheetXmlSuffix, suffix)
+	sf.styleIds = append(sf.styleIds, nil)
+	sf.sheetRels = append(sf.sheetRels, nil)
+	sf.rowCounts = append(sf.rowCounts, 0)
+	sf.cellCounts = append(sf.cellCounts, 0)
+	sf.contentTypes.Overrides = append(sf.contentTypes.Overrides, xlsxOverride{
+		PartName:    "/" + sheetFilePathPrefix + strconv.Itoa(len(sf.xlsxFile.Sheets)) + sheetFilePathSuffix,
+		ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml",
+	})
+	return nil
+}
+
+// makeAddedSheetXML returns the XML of a sheet added by AddSheet, holding its header row. The headers are added to the
+// shared strings when they are written, and are otherwise inline strings, as the shared strings of the file are
+// already written. The header cells are left with the default style, which needs no change to the style sheet.
+func (sf *StreamFile) makeAddedSheetXML(sheet *Sheet) (string, error) {
+	var store SharedStringStore = NewCappedSharedStringStore(0)
+	if sf.sharedStrings != nil {
+		store = sf.sharedStrings
+	}
+	xSheet := sheet.makeXLSXSheet(store, newXlsxStyleSheet(nil))
+	if xSheet.Cols != nil {
+		for i := range xSheet.Cols.Col {
+			xSheet.Cols.Col[i].Style = 0
+		}
+	}
+	for i := range xSheet.SheetData.Row {
+		for j := range xSheet.SheetData.Row[i].C {
+			xSheet.SheetData.Row[i].C[j].S = 0
+		}
+	}
+	body, err := xml.Marshal(xSheet)
+	if err != nil {
+		return "", err
+	}
+	return replaceDimensionTag(xml.Header+string(body), sheet, sf.dimensionPlaceholder())
+}
+
+// writeWorkbook writes the workbook and its relationships, listing the sheets added by AddSheet after those of the
+// StreamFileBuilder.
+func (sf *StreamFile) writeWorkbook() error {
+	workbook, rels := sf.workbookPart, sf.workbookRelsPart
+	if sf.uncachedFormulas && !strings.Contains(workbook, `fullCalcOnLoad="true"`) {
+		workbook = strings.Replace(workbook, `<calcPr`, `<calcPr fullCalcOnLoad="true"`, 1)
+	}
+	if sf.builtSheets < len(sf.xlsxFile.Sheets) {
+		// The sheets are listed in one go, since replacing the ends of
+		// the parts for each of them takes time growing with the
+		// square of their number.
+		usedIds := relationshipIds(rels)
+		var sheets, sheetRels bytes.Buffer
+		for i := sf.builtSheets; i < len(sf.xlsxFile.Sheets); i++ {
+			nextId := i + 1
+			for usedIds[`rId`+strconv.Itoa(nextId)] {
+				nextId++
+			}
+			rId := "rId" + strconv.Itoa(nextId)
+			usedIds[rId] = true
+			sheetId := strconv.Itoa(i + 1)
+			name := sf.xlsxFile.validUTF8(sf.xlsxFile.Sheets[i].Name)
+			sheets.WriteString(`<sheet name="` + escapeAttr(name) + `" sheetId="` + sheetId + `" r:id="` + rId + `" state="visible"></sheet>`)
+			sheetRels.WriteString(`<Relationship Id="` + rId + `" Target="worksheets/sheet` + sheetId + `.xml" Type="` + relationshipTypeWorksheet + `"></Relationship>`)
+		}
+		workbook = strings.Replace(workbook, `</sheets>`, sheets.String()+`</sheets>`, 1)
+		rels = strings.Replace(rels, `</Relationships>`, sheetRels.String()+`</Relationships>`, 1)
+	}
+	if err := sf.writeRawPart(workbookPart, []byte(workbook)); err != nil {
+		return err
+	}
+	return sf.writeRawPart(workbookRelsPart, []byte(rels))
+}
+
+// relationshipIds returns the ids of the relationships listed in rels, the XML of the relationships of the workbook.
+func relationshipIds(rels string) map[string]bool {
+	ids := make(map[string]bool)
+	for {
+		start := strings.Index(rels, ` Id="`)
+		if start < 0 {
+			return ids
+		}
+		rels = rels[start+len(` Id="`):]
+		end := strings.IndexByte(rels, '"')
+		if end < 0 {
+			return ids
+		}
+		ids[rels[:end]] = true
+		rels = rels[end:]
+	}
+}
+
+// makeStreamSheet returns the streamSheet writing the sheet at the given index, which starts at 1, once its header
+// row is written.
+func (sf *StreamFile) makeStreamSheet(sheetIndex int) *streamSheet {
+	sheet := sf.xlsxFile.Sheets[sheetIndex-1]
+	settings := sf.settingsIndex(sheetIndex) - 1
+	// Sheets without columns have no header row.
+	ss := &streamSheet{
+		index:       sheetIndex,
+		columnCount: len(sheet.Cols),
+		styleIds:    sf.styleIds[settings],
+		rowCount:    len(sheet.Rows),
+	}
+	sf.rowCounts[sheetIndex-1] = len(sheet.Rows)
+	sf.cellCounts[sheetIndex-1] = ss.columnCount
+	if settings < len(sf.bandedStyleIds) {
+		ss.bandedStyleIds = sf.bandedStyleIds[settings]
+	}
+	if sf.rowHook != nil {
+		ss.headers = streamHeaders(sheet)
+	}
+	if settings < len(sf.columnFormulas) {
+		ss.formulas = sf.columnFormulas[settings]
+	}
+	if settings < len(sf.columnTypes) {
+		ss.columnTypes = sf.columnTypes[settings]
+	}
+	if settings < len(sf.numericColumns) {
+		ss.numericColumns = sf.numericColumns[settings]
+	}
+	if settings < len(sf.longTextPolicies) {
+		ss.longTextPolicy = sf.longTextPolicies[settings]
+	}
+	if settings < len(sf.columnMasks) {
+		ss.masks = sf.columnMasks[settings]
+	}
+	if settings < len(sf.columnUnits) {
+		ss.units = sf.columnUnits[settings]
+	}
+	if settings < len(sf.dateColumns) {
+		ss.dateColumns = sf.dateColumns[settings]
+	}
+	if sf.duplicateWindow > 0 {
+		ss.duplicates = newDuplicateRowWindow(sf.duplicateWindow)
+	}
+	if settings < len(sf.autoColumnWidths) && sf.autoColumnWidths[settings] {
+		ss.widths = make([]int, ss.columnCount)
+		for i, header := range streamHeaders(sheet) {
+			ss.measure(i, header, CellTypeString)
+		}
+	}
+	ss.makeCellOpenings(sf.omitCellReferences, sf.sharedStrings != nil)
+	return ss
+}
+
+// Close closes the Stream File.
+// Any sheets that have not yet been written to will have an empty sheet created for them.
+func (sf *StreamFile) Close() error {
+	return sf.CloseContext(context.Background())
+}
+
+// CloseContext closes the StreamFile like Close, unless the context is done before it has written the sheets left,
+// which may take long for the spooled sheets or those sized to their contents. The error of the context is then
+// returned, and the file is left unfinished: the StreamFile can no longer be used.
+func (sf *StreamFile) CloseContext(ctx context.Context) error {
+	if sf.err != nil {
+		return sf.err
+	}
+	if sf.currentSheet != nil && sf.currentSheet.rowOpen {
+		return RowInProgressError
+	}
+	if err := ctx.Err(); err != nil {
+		sf.err = err
+		return err
+	}
+	// If there are sheets that have not been written yet, call NextSheet() which will add files to the zip for them.
+	// XLSX readers may error if the sheets registered in the metadata are not present in the file. The spooled sheets
+	// are copied from their spools instead.
+	if sf.currentSheet != nil {
+		for sf.spooledSheets == nil && sf.nextSheetIndex() <= len(sf.xlsxFile.Sheets) {
+			if err := ctx.Err(); err != nil {
+				sf.err = err
+				return err
+			}
+			if err := sf.NextSheet(); err != nil {
+				sf.err = err
+				return err
+			}
+		}
+		// Write the end of the last sheet.
+		if err := sf.writeSheetEnd(); err != nil {
+			sf.err = err
+			return err
+		}
+	}
+	if err := sf.writeSpooledSheets(ctx); err != nil {
+		sf.err = err
+		return err
+	}
+	if err := sf.writePendingParts(); err != nil {
+		sf.err = err
+		return err
+	}
+	if sf.sharedStrings != nil {
+		if err := sf.writeSharedStrings(); err != nil {
+			sf.err = err
+			return err
+		}
+	}
+	if err := sf.writeProvenance(); err != nil {
+		sf.err = err
+		return err
+	}
+	if err := sf.writeWorkbook(); err != nil {
+		sf.err = err
+		return err
+	}
+	// The content types are written last, since parts may be added
+	// until then.
+	if err := sf.writePart(contentTypesPart, sf.contentTypes); err != nil {
+		sf.err = err
+		return err
+	}
+	if sf.noZip64 {
+		if err := sf.checkZip64(); err != nil {
+			sf.err = err
+			return err
+		}
+	}
+	err := sf.zipWriter.Close()
+	if err != nil {
+		sf.err = err
+		return err
+	}
+	sf.openPart = ""
+	if sf.seekable != nil {
+		if err := sf.patchDimensions(); err != nil {
+			sf.err = err
+			return err
+		}
+	}
+	if sf.progressHook != nil {
+		sf.reportProgress(len(sf.xlsxFile.Sheets) - 1)
+	}
+	return nil
+}
+
+// flush writes the rows written so far to the io, along with the data held by the compressor when the parts are
+// compressed with CompressionDeflateStreaming. The rows of a spooled sheet stay in its spool until Close.
+func (sf *StreamFile) flush() error {
+	sf.unflushedRows, sf.unflushedBytes = 0, 0
+	if sf.spooled {
+		return nil
+	}
+	if sf.compressor != nil {
+		if err := sf.compressor.flush(); err != nil {
+			return err
+		}
+	}
+	return sf.zipWriter.Flush()
+}
+
+// checkZip64 returns ErrZip64Required if the file written so far needs ZIP64 records.
+func (sf *StreamFile) checkZip64() error {
+	if err := sf.zipWriter.Flush(); err != nil {
+		return err
+	}
+	var largestPart int64
+	for _, size := range sf.partSizes {
+		if size > largestPart {
+			largestPart = size
+		}
+	}
+	return checkZip64(len(sf.partSizes), largestPart, sf.output.bytesWritten())
+}
+
+// createPart adds the part called name to the file, counting the bytes written to it.
+func (sf *StreamFile) createPart(name string) (io.Writer, error) {
+	method := sf.compression.zipMethod()
+	if sf.compressor != nil {
+		method = sf.compressor.partCompressor.Method()
+	}
+	header := &zip.FileHeader{Name: name, Method: method}
+	writer, err := sf.zipWriter.CreateHeader(header)
+	if err != nil {
+		return nil, err
+	}
+	sf.partSizes[name] = 0
+	if sf.partHeaders == nil {
+		sf.partHeaders = make(map[string]*zip.FileHeader)
+	}
+	sf.partHeaders[name], sf.openPart = header, name
+	return &partSizeWriter{writer: writer, sizes: sf.partSizes, name: name}, nil
+}
+
+// Summary returns the number of rows and cells written to each sheet, the number of shared strings and the size of
+// each part written so far. Once Close has returned, it describes the whole file.
+func (sf *StreamFile) Summary() StreamSummary {
+	summary := StreamSummary{PartSizes: make(map[string]int64, len(sf.partSizes))}
+	for i, sheet := range sf.xlsxFile.Sheets {
+		summary.Sheets = append(summary.Sheets, StreamSheetSummary{
+			Name:  sheet.Name,
+			Rows:  sf.rowCounts[i],
+			Cells: sf.cellCounts[i],
+		})
+	}
+	for i := range summary.Sheets {
+		summary.Sheets[i].DuplicateRows = sf.duplicateCounts[summary.Sheets[i].Name]
+	}
+	if sf.sharedStrings != nil {
+		summary.SharedStrings = sf.sharedStrings.Length()
+		summary.InlineStrings = sf.inlineStrings
+	}
+	for name, size := range sf.partSizes {
+		summary.PartSizes[name] = size
+	}
+	summary.StoredPartSizes = make(map[string]int64, len(sf.partHeaders))
+	for name, header := range sf.partHeaders {
+		if name != sf.openPart {
+			summary.StoredPartSizes[name] = int64(header.CompressedSize64)
+		}
+	}
+	if len(sf.typeWarnings) > 0 {
+		summary.TypeWarnings = sf.TypeWarnings()
+	}
+	if len(sf.longTextCells) > 0 {
+		summary.LongTextCells = sf.LongTextCells()
+	}
+	summary.MissingTranslations = sf.MissingTranslations()
+	return summary
+}
+
+// RowCounts returns the number of rows written to each of the sheets so far, their header row included. Sheets that
+// haven't been reached yet count no rows until Close writes them with their header.
+func (sf *StreamFile) RowCounts() []int {
+	return append([]int(nil), sf.rowCounts...)
+}
+
+// VerifyStreamedFile opens the file written by sf, once it is closed, and checks that it can be read, that its sheets
+// have the names and headers given to the StreamFileBuilder, and that they hold as many rows as were written. This is
+// a cheap end-to-end check that exporters can run in their tests.
+func VerifyStreamedFile(r io.ReaderAt, size int64, sf *StreamFile) error {
+	file, err := OpenReaderAt(r, size)
+	if err != nil {
+		return fmt.Errorf("the streamed file can't be read: %v", err)
+	}
+	if len(file.Sheets) != len(sf.xlsxFile.Sheets) {
+		return fmt.Errorf("the streamed file has %d sheets instead of %d", len(file.Sheets), len(sf.xlsxFile.Sheets))
+	}
+	for i, expected := range sf.xlsxFile.Sheets {
+		sheet := file.Sheets[i]
+		if sheet.Name != expected.Name {
+			return fmt.Errorf("sheet %d of the streamed file is called %q instead of %q", i+1, sheet.Name, expected.Name)
+		}
+		if len(sheet.Rows) != sf.rowCounts[i] {
+			return fmt.Errorf("the sheet %q of the streamed file has %d rows instead of %d", sheet.Name, len(sheet.Rows), sf.rowCounts[i])
+		}
+		for j, header := range streamHeaders(expected) {
+			if value := sheet.Cell(0, j).Value; value != header {
+				return fmt.Errorf("the header of column %s of the sheet %q of the streamed file is %q instead of %q", ColIndexToLetters(j), sheet.Name, value, header)
+			}
+		}
+	}
+	return nil
+}
+
+// streamHeaders returns the cells of the header row of a sheet added to a StreamFileBuilder, none for sheets without
+// columns.
+func streamHeaders(sheet *Sheet) []string {
+	if len(sheet.Rows) == 0 {
+		return nil
+	}
+	headers := make([]string, len(sheet.Rows[0].Cells))
+	for i, cell := range sheet.Rows[0].Cells {
+		headers[i] = cell.Value
+	}
+	return headers
+}
+
+// writeSharedStrings writes the shared strings collected while writing the sheets.
+func (sf *StreamFile) writeSharedStrings() error {
+	return sf.writePart(sharedStringsPart, sf.sharedStrings.makeXLSXSST())
+}
+
+// writeSheetStart will write the start of the Sheet's XML
+func (sf *StreamFile) writeSheetStart() error {
+	if sf.currentSheet == nil {
+		return NoCurrentSheetError
+	}
+	return sf.currentSheet.write(sf.sheetXmlPrefix[sf.currentSheet.index-1])
+}
+
+// writeSheetEnd will write the end of the Sheet's XML, followed by its relationships, its follow-on sheets and the
+// parts added while it was written.
+func (sf *StreamFile) writeSheetEnd() error {
+	if sf.currentSheet == nil {
+		return NoCurrentSheetError
+	}
+	if sf.currentSheet.widths != nil {
+		if err := sf.writeBufferedSheet(sf.currentSheet); err != nil {
+			return err
+		}
+	}
+	if err := sf.writeStreamSheetEnd(sf.currentSheet); err != nil {
+		return err
+	}
+	for _, followOn := range sf.currentSheet.followOns {
+		if err := sf.writeBufferedSheet(followOn); err != nil {
+			return err
+		}
+		if err := sf.writeStreamSheetEnd(followOn); err != nil {
+			return err
+		}
+	}
+	return sf.writePendingParts()
+}
+
+// writeStreamSheetEnd writes the end of the XML of ss, followed by its relationships.
+func (sf *StreamFile) writeStreamSheetEnd(ss *streamSheet) error {
+	if err := sf.checkExpectedRows(ss, false); err != nil {
+		return err
+	}
+	ss.releaseRowBuffer()
+	if err := ss.write(endSheetDataTag); err != nil {
+		return err
+	}
+	suffix := sf.sheetXmlSuffix[ss.index-1]
+	settings := sf.settingsIndex(ss.index) - 1
+	if settings < len(sf.autoFilterRefs) && sf.autoFilterRefs[settings] != "" && ss.rowCount > 1 {
+		// The filter covers every row written to the sheet, which
+		// are known now.
+		ref := sf.autoFilterRefs[settings]
+		lastCell := ref[strings.Index(ref, cellRangeChar)+1:]
+		col, _, err := GetCoordsFromCellIDString(lastCell)
+		if err != nil {
+			return err
+		}
+		suffix = strings.Replace(suffix, `<autoFilter ref="`+ref+`"`,
+			`<autoFilter ref="A1:`+GetCellIDStringFromCoords(col, ss.rowCount-1)+`"`, 1)
+	}
+	if len(ss.merges) > 0 {
+		suffix = insertStreamMergeCells(suffix, ss.merges)
+	}
+	if settings < len(sf.conditionalFormats) && len(sf.conditionalFormats[settings]) > 0 {
+		// The formats cover the rows written below the header row,
+		// if any.
+		if firstRow := len(sf.xlsxFile.Sheets[ss.index-1].Rows); ss.rowCount > firstRow {
+			suffix = insertStreamConditionalFormats(suffix, sf.conditionalFormats[settings], firstRow, ss.rowCount-1)
+		}
+	}
+	if len(ss.hyperlinks) > 0 {
+		suffix = insertStreamHyperlinks(suffix, ss.hyperlinks)
+	}
+	if len(ss.notes) > 0 {
+		var err error
+		if suffix, err = sf.insertStreamNotes(ss, suffix); err != nil {
+			return err
+		}
+	}
+	if err := ss.write(suffix); err != nil {
+		return err
+	}
+	if sf.dimensionRefs != nil && strings.Contains(sf.sheetXmlPrefix[ss.index-1], dimensionPlaceholder) {
+		sf.dimensionRefs[sheetFilePathPrefix+strconv.Itoa(ss.index)+sheetFilePathSuffix] = ss.dimensionRef()
+	}
+	if len(ss.notes) > 0 {
+		if err := sf.writeStreamNotes(ss); err != nil {
+			return err
+		}
+	}
+	return sf.writeSheetRels(ss.index)
+}
+
+// AddRelationship adds a relationship of the given type from the current sheet to target, and returns its id, to be
+// used in the XML of the sheet, such as for hyperlinks, drawings or comments. A target inside the file, relative to
+// the xl/worksheets directory, should be added with AddPart, unless it is external. The relationships of the sheet are
+// written once it is done, after NextSheet or Close is called.
+func (sf *StreamFile) AddRelationship(relType, target string, external bool) (string, error) {
+	if sf.err != nil {
+		return "", sf.err
+	}
+	if sf.currentSheet == nil {
+		return "", NoCurrentSheetError
+	}
+	return sf.addSheetRelationship(sf.currentSheet.index-1, relType, target, external), nil
+}
+
+// addSheetRelationship adds a relationship from the sheet at index, which starts at 0, to target, and returns its id.
+func (sf *StreamFile) addSheetRelationship(index int, relType, target string, external bool) string {
+	rel := xlsxWorkbookRelation{Target: target, Type: relType}
+	if external {
+		rel.TargetMode = "External"
+	}
+	nextId := len(sf.sheetRels[index]) + 1
+	for hasRelationship(sf.sheetRels[index], "rId"+strconv.Itoa(nextId)) {
+		nextId++
+	}
+	rel.Id = "rId" + strconv.Itoa(nextId)
+	sf.sheetRels[index] = append(sf.sheetRels[index], rel)
+	return rel.Id
+}
+
+// AddPart adds a part with the given name, such as xl/drawings/drawing1.xml, and content type to the file. Since the
+// current sheet is still being written, the part is kept in memory and written once the sheet is done. An empty
+// content type leaves the part to the content type of its extension, such as for images.
+func (sf *StreamFile) AddPart(name string, data []byte, contentType string) error {
+	if sf.err != nil {
+		return sf.err
+	}
+	name = strings.TrimPrefix(name, "/")
+	if isGeneratedPart(name) || strings.HasPrefix(name, sheetFilePathPrefix) {
+		return fmt.Errorf("the part %s is written by the StreamFile", name)
+	}
+	for _, part := range sf.pendingParts {
+		if part.name == name {
+			return fmt.Errorf("the part %s was already added", name)
+		}
+	}
+	sf.pendingParts = append(sf.pendingParts, pendingPart{name: name, data: data})
+	if contentType != "" {
+		sf.contentTypes.Overrides = append(sf.contentTypes.Overrides, xlsxOverride{PartName: "/" + name, ContentType: contentType})
+	}
+	return nil
+}
+
+// writeSheetRels writes the relationships of the sheet at the given index, which starts at 1, if it has any.
+func (sf *StreamFile) writeSheetRels(sheetIndex int) error {
+	rels := sf.sheetRels[sheetIndex-1]
+	if len(rels) == 0 {
+		return nil
+	}
+	return sf.writePart(sheetRelsPath(sheetIndex), xlsxWorkbookRels{Relationships: rels})
+}
+
+// writePendingParts writes the parts added since the current sheet was started.
+func (sf *StreamFile) writePendingParts() error {
+	for _, part := range sf.pendingParts {
+		writer, err := sf.createPart(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := writer.Write(part.data); err != nil {
+			return err
+		}
+	}
+	sf.pendingParts = nil
+	return nil
+}
+
+// writePart writes thing as the XML part called name, through the part hook if any.
+func (sf *StreamFile) writePart(name string, thing interface{}) error {
+	body, err := xml.Marshal(thing)
+	if err != nil {
+		return err
+	}
+	return sf.writeRawPart(name, []byte(xml.Header+string(body)))
+}
+
+// writeRawPart writes data as the part called name, through the part hook if any.
+func (sf *StreamFile) writeRawPart(name string, data []byte) error {
+	var err error
+	if sf.partHook != nil {
+		if data, err = sf.partHook(name, data); err != nil {
+			return err
+		}
+	}
+	writer, err := sf.createPart(name)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	return err
+}
+
+// makeCellOpenings computes the parts of the c elements of the cells
+// that are the same for every row of the sheet, so that the cell
+// references and attributes aren't rebuilt for each of the cells.
+// Without cell references, the row number put between the parts must
+// be empty.  The cells hold inline strings, or the index of a shared
+// string if sharedStrings is true, except for the cells of formula
+// columns which hold their formula.
+func (ss *streamSheet) makeCellOpenings(omitCellReferences, sharedStrings bool) {
+	ss.cellOpenings = make([]string, ss.columnCount)
+	ss.cellOpeningEnds = make([]string, ss.columnCount)
+	if ss.bandedStyleIds != nil {
+		ss.bandedCellOpeningEnds = make([]string, ss.columnCount)
+	}
+	for colIndex := 0; colIndex < ss.columnCount; colIndex++ {
+		ss.cellOpenings[colIndex] = `<c`
+		cellOpeningEnd := ` t="inlineStr"`
+		value := `><is><t>`
+		if sharedStrings {
+			cellOpeningEnd = ` t="s"`
+			value = `><v>`
+		}
+		if ss.isFormulaColumn(colIndex) {
+			cellOpeningEnd = ``
+			value = `><f>`
+		}
+		if !omitCellReferences {
+			ss.cellOpenings[colIndex] = `<c r="` + ColIndexToLetters(colIndex)
+			cellOpeningEnd = `"` + cellOpeningEnd
+		}
+		styleId := 0
+		if colIndex < len(ss.styleIds) {
+			styleId = ss.styleIds[colIndex]
+		}
+		ss.cellOpeningEnds[colIndex] = cellOpeningEnd + styleAttribute(styleId) + value
+		if ss.bandedStyleIds != nil {
+			ss.bandedCellOpeningEnds[colIndex] = cellOpeningEnd + styleAttribute(ss.bandedStyleIds[colIndex]) + value
+		}
+	}
+}
+
+// styleAttribute returns the s attribute giving the style id of a
+// cell, or nothing if the cell is using the default style.
+func styleAttribute(styleId int) string {
+	if styleId == 0 {
+		return ""
+	}
+	return ` s="` + strconv.Itoa(styleId) + `"`
+}
+
+// writeStyleAttribute writes the s attribute giving the style id of a
+// cell to buffer, like styleAttribute.
+func writeStyleAttribute(buffer *bytes.Buffer, styleId int) {
+	if styleId != 0 {
+		buffer.WriteString(` s="`)
+		writeInt(buffer, styleId)
+		buffer.WriteByte('"')
+	}
+}
+
+// totalColumnCount returns the number of columns of the sheet and of its follow-on sheets.
+func (ss *streamSheet) totalColumnCount() int {
+	count := ss.columnCount
+	for _, followOn := range ss.followOns {
+		count += followOn.columnCount
+	}
+	return count
+}
+
+// dataColumnCount returns the number of columns of the sheet and of its follow-on sheets whose cells are written by
+// the caller, which are those that aren't formula columns.
+func (ss *streamSheet) dataColumnCount() int {
+	count := ss.totalColumnCount()
+	for colIndex := 0; colIndex < ss.totalColumnCount(); colIndex++ {
+		if ss.isFormulaColumn(colIndex) {
+			count--
+		}
+	}
+	return count
+}
+
+// dataIndex returns the index of the cell of the given column, which isn't a formula column, among the cells of a row
+// written by the caller.
+func (ss *streamSheet) dataIndex(colIndex int) int {
+	index := colIndex
+	for i := 0; i < colIndex; i++ {
+		if ss.isFormulaColumn(i) {
+			index--
+		}
+	}
+	return index
+}
+
+// column returns the sheet holding the given column of the sheet, which is the sheet itself or one of its follow-on
+// sheets, along with the index of the column in it.
+func (ss *streamSheet) column(colIndex int) (*streamSheet, int) {
+	sheet := ss
+	for _, followOn := range ss.followOns {
+		if colIndex < sheet.columnCount {
+			break
+		}
+		colIndex -= sheet.columnCount
+		sheet = followOn
+	}
+	return sheet, colIndex
+}
+
+// hasColumnTypes returns true if columns of the sheet, or of its follow-on sheets, have types.
+func (ss *streamSheet) hasColumnTypes() bool {
+	if len(ss.columnTypes) > 0 {
+		return true
+	}
+	for _, followOn := range ss.followOns {
+		if len(followOn.columnTypes) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// columnType returns the type of the values of the given column of the sheet, or of its follow-on sheets.
+func (ss *streamSheet) columnType(colIndex int) ColumnType {
+	sheet, colIndex := ss.column(colIndex)
+	if colIndex < len(sheet.columnTypes) {
+		return sheet.columnTypes[colIndex]
+	}
+	return ColumnTypeString
+}
+
+// isFormulaColumn returns true if the cells of the given column of the sheet, or of its follow-on sheets, are
+// written from a formula template.
+func (ss *streamSheet) isFormulaColumn(colIndex int) bool {
+	sheet, colIndex := ss.column(colIndex)
+	return colIndex < len(sheet.formulas) && sheet.formulas[colIndex] != ""
+}
+
+// lastIndex returns the index of the last of the sheet and of its follow-on sheets.
+func (ss *streamSheet) lastIndex() int {
+	return ss.index + len(ss.followOns)
+}
+
+// dropFormulaHeaders leaves the headers of the formula columns out of those passed to the row hook, like the rows
+// passed to it.
+func (ss *streamSheet) dropFormulaHeaders() {
+	var headers []string
+	for colIndex, header := range ss.headers {
+		if !ss.isFormulaColumn(colIndex) {
+			headers = append(headers, header)
+		}
+	}
+	ss.headers = headers
 }
 
 func (ss *streamSheet) write(data string) error {
@@ -9,27 +9,30 @@
 // to the io. All rows written to the same sheet must have the same number of cells as the header provided when the sheet
 // was created or an error will be returned.
 // 5. Call NextSheet() to proceed to the next sheet. Once NextSheet() is called, the previous sheet can not be edited.
+// Sheets found to be needed while streaming can be added with StreamFile.AddSheet(), after the others.
 // 6. Call Close() to finish.
 
 // Future work suggestions:
-// Currently the only supported cell type is string, since the main reason this library was written was to prevent
-// strings from being interpreted as numbers. It would be nice to have support for numbers and money so that the exported
-// files could better take advantage of XLSX's features.
-// All text is written with the same text style. Support for additional text styles could be added to highlight certain
-// data in the file.
-// The current default style uses fonts that are not on Macs by default so opening the XLSX files in Numbers causes a
-// pop up that says there are missing fonts. The font could be changed to something that is usually found on Mac and PC.
+// Write only writes strings, since the main reason this library was written was to prevent strings from being
+// interpreted as numbers. Numbers, dates and booleans can be written with WriteTyped, but there is no support for money
+// or other number formats yet.
+// The style of the text can be set per column, with SetColumnStyle, for every other row, with SetBandedRows, or per
+// cell, with the styles added by AddStyle and written with WriteStyled or WriteTyped.
+// Styles using fonts that are not on Macs by default cause a pop up in Numbers that says there are missing fonts, call
+// SetNumbersCompatible to replace them with a font that is usually found on Mac and PC, see WithNumbersFont.
 
 package xlsx
 
 import (
 	"archive/zip"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type StreamFileBuilder struct {
@@ -39,12 +42,131 @@ type StreamFileBuilder struct {
 	cellTypeToStyleIds map[CellType]int
 	maxStyleId         int
 	styleIds           [][]int
+	omitCellReferences bool
+	renameDuplicates   bool
+	googleSheets       bool
+	sharedStringLimit  int
+	rowHook            RowHook
+	partHook           PartHook
+	splitWideSheets    bool
+	// followOns holds, for each sheet, the number of sheets after it
+	// continuing its columns past the limit of Excel, see
+	// SetSplitWideSheets.
+	followOns []int
+	// columnStyles and bandColors hold the styles of the columns and
+	// the fill of the banded rows of each sheet, which are added to
+	// the style sheet when the file is built.
+	columnStyles [][]*Style
+	bandColors   []string
+	// cellStyles holds the styles added with AddStyle, and
+	// cellStyleIds their style ids in the style sheet once the file
+	// is built.
+	cellStyles   []streamStyle
+	cellStyleIds []int
+	// columnFormulas holds the formula templates of the columns of
+	// each sheet, see SetColumnFormula, and columnTypes the types of
+	// their values, see AddSheetWithTypes.
+	columnFormulas [][]string
+	columnTypes    [][]ColumnType
+	// numericColumns is true for the columns of each sheet declared
+	// numeric by the cell types given to AddSheet.
+	numericColumns [][]bool
+	// longTextPolicies holds the way the long text of each sheet is
+	// written, see SetLongTextPolicy.
+	longTextPolicies []LongTextPolicy
+	// wrappedXfIds holds the id of the wrapped twin of each style, when
+	// the text holding line breaks is wrapped, see SetLineBreaks.
+	wrappedXfIds []int
+	// columnMasks holds the masks of the columns of each sheet, see
+	// SetColumnMask.
+	columnMasks [][]Mask
+	// columnUnits holds the units of the columns of each sheet, see
+	// SetColumnUnit.
+	columnUnits [][]*Unit
+	// numberFormatsPerColumn is true when the number formats of the
+	// columns are given by their styles, see SetNumberFormatsPerColumn,
+	// dateColumns then holding, for each sheet, the date columns whose
+	// style has the date format.
+	numberFormatsPerColumn bool
+	dateColumns            [][]bool
+	// conditionalFormats holds the conditional formats of each
+	// sheet, see AddConditionalFormat, and conditionalStyles the
+	// differential styles of their cell value rules.
+	conditionalFormats [][]streamConditionalFormat
+	conditionalStyles  []ConditionalStyle
+	// images holds the images of each sheet, see AddImage.
+	images [][]streamImage
+	// rowLimitRollover continues the rows of the full sheets on new
+	// sheets, see SetRowLimitRollover.
+	rowLimitRollover bool
+	// lists holds the lists of values of the dropdowns added with
+	// AddListValidation, whose sheets are added by Build.
+	lists []streamList
+	// duplicateWindow and duplicateAction set up the duplicate row
+	// guard, see SetDuplicateRowGuard.
+	duplicateWindow int
+	duplicateAction DuplicateRowAction
+	// compression is the way the parts are compressed, see
+	// SetCompression, unless partCompressor compresses them, see
+	// SetCompressor.
+	compression    StreamCompression
+	partCompressor PartCompressor
+	// output is the writer of the zip writer, which writes to the
+	// writer of the file at the pace of the throttle, and rowThrottle
+	// paces the rows, see SetThrottle.
+	output      *throttledWriter
+	rowThrottle *throttle
+	// structSheets maps the structs written to the sheets added by
+	// AddSheetFromStruct to their columns, and is nil for the others.
+	structSheets []*structSheet
+	// progressHook is called every progressInterval rows, see
+	// SetProgressHook.
+	progressHook     ProgressHook
+	progressInterval int
+	// flushRows and flushBytes are the flush interval of the rows, see
+	// SetFlushInterval.
+	flushRows  int
+	flushBytes int
+	// autoColumnWidths is true for the sheets sized to their
+	// contents, see SetAutoColumnWidths.
+	autoColumnWidths []bool
+	// expectedRows is the number of rows expected below the header
+	// row of each sheet, -1 if it isn't known, see SetExpectedRows.
+	expectedRows []int
+	// columnTypeWidths holds the widths set with SetColumnTypeWidth,
+	// which take precedence over defaultColumnTypeWidths.
+	columnTypeWidths map[ColumnType]float64
+	// existingSheets is the number of sheets of the file read by
+	// NewStreamFileBuilderFromExisting, which come first.
+	existingSheets int
+	// theme is the theme of the file read by
+	// NewStreamFileBuilderFromExisting, if any.
+	theme string
+	// documentProperties and customProperties are the properties of
+	// the workbook, see SetDocumentProperties and SetCustomProperty.
+	documentProperties *DocumentProperties
+	customProperties   []customProperty
+	// defaultFont, numbersFont, sharedStrings and noZip64 are set by
+	// the options, see SetOptions.
+	defaultFont   *Font
+	numbersFont   string
+	sharedStrings SharedStringsMode
+	noZip64       bool
+	// localization translates the names of the sheets and the
+	// headers, see SetLocalization.
+	localization *localization
 }
 
 const (
 	sheetFilePathPrefix = "xl/worksheets/sheet"
 	sheetFilePathSuffix = ".xml"
 	endSheetDataTag     = "</sheetData>"
+	sharedStringsPart   = "xl/sharedStrings.xml"
+	contentTypesPart    = "[Content_Types].xml"
+	workbookPart        = "xl/workbook.xml"
+	workbookRelsPart    = "xl/_rels/workbook.xml.rels"
+	sheetRelsPathPrefix = "xl/worksheets/_rels/sheet"
+	sheetRelsPathSuffix = ".xml.rels"
 	dimensionTag        = `<dimension ref="%s"></dimension>`
 	// This is the index of the max style that this library will insert into XLSX sheets by default.
 	// This allows us to predict what the style id of styles that we add will be.
@@ -52,50 +174,96 @@ const (
 	initMaxStyleId = 1
 )
 
+// PartHook is called with the path and the content of each metadata part of a streamed file, that is every part but
+// the sheets and the parts added with StreamFile.AddPart, before it is written. It returns the content to write
+// instead, which lets callers patch parts such as xl/workbook.xml or xl/styles.xml for features this package doesn't
+// model. Most parts are written by Build, the relationships of the sheets once they are done and the workbook, its
+// relationships and the content types, along with the shared strings if any, by Close. An error stops the writing of the file.
+type PartHook func(path string, data []byte) ([]byte, error)
+
+// streamStyle is a style added with StreamFileBuilder.AddStyle, along with its number format.
+type streamStyle struct {
+	style  *Style
+	numFmt string
+}
+
 var BuiltStreamFileBuilderError = errors.New("StreamFileBuilder has already been built, functions may no longer be used")
 
 // NewStreamFileBuilder creates an StreamFileBuilder that will write to the the provided io.writer
 func NewStreamFileBuilder(writer io.Writer) *StreamFileBuilder {
+	output := &throttledWriter{writer: writer}
+	// The sheets of the file are only added, never renamed, so their
+	// names are kept folded for the duplicate checks of files with
+	// many sheets.
+	xlsxFile := NewFile()
+	xlsxFile.sheetNames = make(map[string]bool)
 	return &StreamFileBuilder{
-		zipWriter:          zip.NewWriter(writer),
-		xlsxFile:           NewFile(),
+		zipWriter:          zip.NewWriter(output),
+		output:             output,
+		xlsxFile:           xlsxFile,
 		cellTypeToStyleIds: make(map[CellType]int),
 		maxStyleId:         initMaxStyleId,
+		flushRows:          1,
 	}
 }
 
-// NewStreamFileBuilderForPath takes the name of an XLSX file and returns a builder for it.
+// NewStreamFileBuilderForPath takes the name of an XLSX file and returns a builder for it, set up with the options.
 // The file will be created if it does not exist, or truncated if it does.
-func NewStreamFileBuilderForPath(path string) (*StreamFileBuilder, error) {
+func NewStreamFileBuilderForPath(path string, options ...Option) (*StreamFileBuilder, error) {
 	file, err := os.Create(path)
 	if err != nil {
 		return nil, err
 	}
-	return NewStreamFileBuilder(file), nil
+	sb := NewStreamFileBuilder(file)
+	if err := sb.SetOptions(options...); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return sb, nil
 }
 
 // AddSheet will add sheets with the given name with the provided headers. The headers cannot be edited later, and all
-// rows written to the sheet must contain the same number of cells as the header. Sheet names must be unique, or an
-// error will be thrown.
+// rows written to the sheet must contain the same number of cells as the header. A sheet without headers has no
+// columns and stays empty, such as for reports without data. Sheet names must be unique regardless
+// of case, as Excel asks to repair files where they aren't, or an error will be thrown, unless SetRenameDuplicateSheets
+// was called. The text that isn't a number written to the columns that cellTypes declares numeric is reported by
+// StreamFile.TypeWarnings. The name and the headers are message keys if SetLocalization was called.
 func (sb *StreamFileBuilder) AddSheet(name string, headers []string, cellTypes []*CellType) error {
 	if sb.built {
 		return BuiltStreamFileBuilderError
 	}
+	return sb.addSheet(sb.localization.text(name), sb.localization.texts(headers), cellTypes)
+}
+
+// addSheet adds a sheet like AddSheet, its name and headers being translated already.
+func (sb *StreamFileBuilder) addSheet(name string, headers []string, cellTypes []*CellType) error {
 	if len(cellTypes) > len(headers) {
 		return errors.New("cellTypes is longer than headers")
 	}
+	if len(headers) > Excel2006MaxColumnCount {
+		if !sb.splitWideSheets {
+			return &ColumnLimitError{Sheet: name, Columns: len(headers)}
+		}
+		return sb.addWideSheet(name, headers, cellTypes)
+	}
+	if sb.renameDuplicates {
+		name = sb.xlsxFile.uniqueSheetName(name)
+	}
 	sheet, err := sb.xlsxFile.AddSheet(name)
 	if err != nil {
 		// Set built on error so that all subsequent calls to the builder will also fail.
 		sb.built = true
 		return err
 	}
-	sb.styleIds = append(sb.styleIds, []int{})
-	row := sheet.AddRow()
-	if count := row.WriteSlice(&headers, -1); count != len(headers) {
-		// Set built on error so that all subsequent calls to the builder will also fail.
-		sb.built = true
-		return errors.New("failed to write headers")
+	sb.addSheetSettings()
+	// A sheet without headers has no columns and stays empty.
+	if len(headers) > 0 {
+		row := sheet.AddRow()
+		if count := row.WriteSlice(&headers, -1); count != len(headers) {
+			// Set built on error so that all subsequent calls to the builder will also fail.
+			sb.built = true
+			return errors.New("failed to write headers")
+		}
 	}
 	for i, cellType := range cellTypes {
 		var cellStyleIndex int
@@ -113,12 +281,746 @@ func (sb *StreamFileBuilder) AddSheet(name string, headers []string, cellTypes [
 				sb.cellTypeToStyleIds[*cellType] = sb.maxStyleId
 			}
 			sheet.Cols[i].SetType(*cellType)
+			if *cellType == CellTypeNumeric {
+				sheetIndex := len(sb.numericColumns) - 1
+				if sb.numericColumns[sheetIndex] == nil {
+					sb.numericColumns[sheetIndex] = make([]bool, len(headers))
+				}
+				sb.numericColumns[sheetIndex][i] = true
+			}
 		}
 		sb.styleIds[len(sb.styleIds)-1] = append(sb.styleIds[len(sb.styleIds)-1], cellStyleIndex)
 	}
 	return nil
 }
 
+// addSheetSettings adds the settings of a sheet just added to the file, which has none yet.
+func (sb *StreamFileBuilder) addSheetSettings() {
+	sb.styleIds = append(sb.styleIds, []int{})
+	sb.followOns = append(sb.followOns, 0)
+	sb.columnStyles = append(sb.columnStyles, nil)
+	sb.columnFormulas = append(sb.columnFormulas, nil)
+	sb.columnTypes = append(sb.columnTypes, nil)
+	sb.numericColumns = append(sb.numericColumns, nil)
+	sb.longTextPolicies = append(sb.longTextPolicies, KeepLongText)
+	sb.columnMasks = append(sb.columnMasks, nil)
+	sb.columnUnits = append(sb.columnUnits, nil)
+	sb.conditionalFormats = append(sb.conditionalFormats, nil)
+	sb.images = append(sb.images, nil)
+	sb.bandColors = append(sb.bandColors, "")
+	sb.autoColumnWidths = append(sb.autoColumnWidths, false)
+	sb.expectedRows = append(sb.expectedRows, -1)
+	sb.structSheets = append(sb.structSheets, nil)
+}
+
+// AddSheetWithTypes adds a sheet like AddSheet, giving the types of the values of its columns, so that the strings
+// written to them by Write are parsed and written as numbers, dates or booleans, which Excel can sum and sort. A value
+// that can't be parsed as the type of its column makes Write fail, naming its cell. Empty values are written as empty
+// strings. The types may be fewer than the headers, the other columns holding strings.
+func (sb *StreamFileBuilder) AddSheetWithTypes(name string, headers []string, columnTypes []ColumnType) error {
+	if len(columnTypes) > len(headers) {
+		return errors.New("columnTypes is longer than headers")
+	}
+	cellTypes := make([]*CellType, len(columnTypes))
+	for i, columnType := range columnTypes {
+		if columnType < ColumnTypeString || columnType > ColumnTypeBool {
+			return fmt.Errorf("unknown column type %d", columnType)
+		}
+		cellTypes[i] = columnType.cellType()
+	}
+	sheetIndex := len(sb.xlsxFile.Sheets)
+	if err := sb.AddSheet(name, headers, cellTypes); err != nil {
+		return err
+	}
+	// A wide sheet may have been split, the types being split along
+	// with its columns.
+	for i := sheetIndex; i < len(sb.xlsxFile.Sheets) && len(columnTypes) > 0; i++ {
+		count := len(sb.xlsxFile.Sheets[i].Cols)
+		if count > len(columnTypes) {
+			count = len(columnTypes)
+		}
+		sb.columnTypes[i] = columnTypes[:count]
+		columnTypes = columnTypes[count:]
+	}
+	return nil
+}
+
+// StreamSheetOptions sets up a sheet added with AddSheetWithOptions.
+type StreamSheetOptions struct {
+	// ColumnTypes are the types of the values of the columns, as given to AddSheetWithTypes.
+	ColumnTypes []ColumnType
+	// FreezeHeader freezes the header row, which stays in view as the rows below it are scrolled.
+	FreezeHeader bool
+	// AutoFilter adds filter dropdowns to the header row, filtering every row written to the sheet.
+	AutoFilter bool
+	// Visibility hides the sheet, or shows it if SheetVisible, the default.
+	Visibility SheetVisibility
+	// TabColor is the ARGB color of the tab of the sheet, such as "FFFF0000", empty for the default.
+	TabColor string
+	// OutlineLevels is the number of outline levels, up to 7, of the rows written to the sheet, whose levels are set
+	// with StreamFile.SetOutlineLevel, so that detail rows can be collapsed under their summary rows. The summary rows
+	// are below their detail rows, unless SummaryRowsAbove is true.
+	OutlineLevels    int
+	SummaryRowsAbove bool
+}
+
+// SheetVisibility is whether a sheet is shown, see StreamSheetOptions.
+type SheetVisibility int
+
+const (
+	SheetVisible SheetVisibility = iota
+	// SheetHidden hides the sheet, which can be shown from the menus of Excel.
+	SheetHidden
+	// SheetVeryHidden hides the sheet so that it can only be shown by VBA code.
+	SheetVeryHidden
+)
+
+// maxOutlineLevels is the number of outline levels of Excel.
+const maxOutlineLevels = 7
+
+// AddSheetWithOptions adds a sheet like AddSheetWithTypes, with the header row frozen or given filter dropdowns, hidden,
+// with a tab color or with outlined rows as options says. The options of a wide sheet split across sheets apply to
+// each of them, see SetSplitWideSheets. A sheet without headers has no header row to freeze or filter, which options
+// can't ask for. A workbook must show at least one of its sheets, which Build checks.
+func (sb *StreamFileBuilder) AddSheetWithOptions(name string, headers []string, options StreamSheetOptions) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if len(headers) == 0 && (options.FreezeHeader || options.AutoFilter) {
+		return errors.New("a sheet without headers has no header row to freeze or filter")
+	}
+	if options.Visibility < SheetVisible || options.Visibility > SheetVeryHidden {
+		return fmt.Errorf("unknown sheet visibility %d", options.Visibility)
+	}
+	if options.OutlineLevels < 0 || options.OutlineLevels > maxOutlineLevels {
+		return fmt.Errorf("invalid outline levels %d, which must be from 0 to %d", options.OutlineLevels, maxOutlineLevels)
+	}
+	sheetIndex := len(sb.xlsxFile.Sheets)
+	if err := sb.AddSheetWithTypes(name, headers, options.ColumnTypes); err != nil {
+		return err
+	}
+	for _, sheet := range sb.xlsxFile.Sheets[sheetIndex:] {
+		if options.FreezeHeader {
+			sheet.SheetViews = []SheetView{{Pane: &Pane{
+				YSplit:      1,
+				TopLeftCell: "A2",
+				ActivePane:  "bottomLeft",
+				State:       "frozen",
+			}}}
+		}
+		if options.AutoFilter {
+			// The filter covers the header row until the rows written to the sheet are known, see
+			// StreamFile.writeStreamSheetEnd.
+			sheet.AutoFilter = &AutoFilter{
+				TopLeftCell:     "A1",
+				BottomRightCell: GetCellIDStringFromCoords(len(sheet.Cols)-1, 0),
+			}
+		}
+		sheet.Hidden = options.Visibility != SheetVisible
+		sheet.VeryHidden = options.Visibility == SheetVeryHidden
+		sheet.Properties.TabColor = options.TabColor
+		sheet.Properties.SummaryRowsAbove = options.SummaryRowsAbove
+		sheet.streamOutlineLevels = uint8(options.OutlineLevels)
+	}
+	return nil
+}
+
+// addWideSheet adds a sheet with more headers than Excel has columns, followed by as many sheets as needed to hold the
+// rest of its columns, see SetSplitWideSheets.
+func (sb *StreamFileBuilder) addWideSheet(name string, headers []string, cellTypes []*CellType) error {
+	sheetIndex := len(sb.xlsxFile.Sheets)
+	sheetName := name
+	for start := 0; start < len(headers); start += Excel2006MaxColumnCount {
+		end := start + Excel2006MaxColumnCount
+		if end > len(headers) {
+			end = len(headers)
+		}
+		var types []*CellType
+		if start < len(cellTypes) {
+			types = cellTypes[start:]
+			if len(types) > end-start {
+				types = types[:end-start]
+			}
+		}
+		if start > 0 {
+			// The follow-on sheets are named the way Excel renames
+			// duplicate sheets, so that the first one of "Data" is
+			// "Data (2)".
+			sheetName = sb.xlsxFile.uniqueSheetName(sb.xlsxFile.Sheets[sheetIndex].Name)
+		}
+		if err := sb.addSheet(sheetName, headers[start:end], types); err != nil {
+			return err
+		}
+	}
+	sb.followOns[sheetIndex] = len(sb.xlsxFile.Sheets) - sheetIndex - 1
+	return nil
+}
+
+// SetSplitWideSheets makes AddSheet continue the columns of a sheet with more headers than Excel can read on
+// follow-on sheets added after it, named after it the way Excel renames duplicate sheets, such as "Data (2)", rather
+// than return a ColumnLimitError. The rows written to the sheet are split between it and its follow-on sheets, which
+// take sheet indexes of their own for SetColumnStyle and SetBandedRows, and are skipped over by NextSheet. Since only
+// one part of the file can be written at a time, the rows of the follow-on sheets are kept in memory until the sheet is
+// done.
+func (sb *StreamFileBuilder) SetSplitWideSheets(split bool) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	sb.splitWideSheets = split
+	return nil
+}
+
+// SetOmitCellReferences makes the StreamFile leave out the r attributes giving the position of the rows and cells it
+// writes, which the XLSX format allows since they are written one after the other. This makes wide sheets about a
+// fifth smaller, and such files are read by Excel and LibreOffice.
+func (sb *StreamFileBuilder) SetOmitCellReferences(omit bool) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	sb.omitCellReferences = omit
+	return nil
+}
+
+// SetGoogleSheetsCompatible makes the StreamFile avoid what Google Sheets mishandles when importing files, which may
+// leave sheets blank: the rows and cells are written with their references, even if SetOmitCellReferences was called,
+// and strings are written to the shared strings rather than inline. The shared strings are written by Close, every
+// distinct string being kept in memory until then, unless capped by SetSharedStringLimit.
+func (sb *StreamFileBuilder) SetGoogleSheetsCompatible(compatible bool) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	sb.googleSheets = compatible
+	return nil
+}
+
+// SetSharedStringLimit caps the number of shared strings, see SetGoogleSheetsCompatible, so that the memory they take
+// stays bounded for exports with many distinct strings: once they hold limit strings, the strings written that aren't
+// among them are written inline, the strings already shared still being written as such. A limit of 0, the default,
+// leaves the shared strings unbounded.
+func (sb *StreamFileBuilder) SetSharedStringLimit(limit int) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if limit < 0 {
+		return fmt.Errorf("the limit of the shared strings can't be negative, not %d", limit)
+	}
+	sb.sharedStringLimit = limit
+	return nil
+}
+
+// SetNumbersCompatible makes the StreamFile open in Numbers without complaint, see File.NumbersCompatible. The fonts of
+// the styles that are not on Macs by default are replaced with Arial, or the font given WithNumbersFont.
+func (sb *StreamFileBuilder) SetNumbersCompatible(compatible bool) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	sb.xlsxFile.NumbersCompatible = compatible
+	return nil
+}
+
+// SetRowHook sets the RowHook called for every row written to the StreamFile, which can change the cells of the rows
+// or drop them, such as for redacting personal data.
+func (sb *StreamFileBuilder) SetRowHook(hook RowHook) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	sb.rowHook = hook
+	return nil
+}
+
+// SetProgressHook sets the ProgressHook called every interval rows written to the StreamFile, and once more when it is
+// closed, so that the progress of long exports can be shown.
+func (sb *StreamFileBuilder) SetProgressHook(hook ProgressHook, interval int) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if interval < 1 {
+		return fmt.Errorf("invalid progress interval %d, which must be at least one row", interval)
+	}
+	sb.progressHook = hook
+	sb.progressInterval = interval
+	return nil
+}
+
+// SetFlushInterval sets how often the rows written to the StreamFile are flushed to the io: once rows rows, or bytes
+// bytes of their XML, were written since they were last flushed, whichever comes first, 0 leaving either out. By
+// default every row is flushed, which suits downloads streamed as they are written, see CompressionDeflateStreaming,
+// but costs exports of millions of rows much of their time. With both 0, the rows reach the io as the buffer of the
+// zip writer fills up, or when StreamFile.Flush is called.
+func (sb *StreamFileBuilder) SetFlushInterval(rows, bytes int) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if rows < 0 || bytes < 0 {
+		return fmt.Errorf("invalid flush interval of %d rows and %d bytes, which can't be negative", rows, bytes)
+	}
+	sb.flushRows = rows
+	sb.flushBytes = bytes
+	return nil
+}
+
+// SetPartHook sets the PartHook called with each of the metadata parts of the file before it is written, such as
+// xl/workbook.xml or xl/styles.xml.
+func (sb *StreamFileBuilder) SetPartHook(hook PartHook) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	sb.partHook = hook
+	return nil
+}
+
+// SetDuplicateRowGuard makes the StreamFile compare each row written to a sheet with the window rows written to it just
+// before, 1 for consecutive rows only, and flag or skip the rows identical to one of them as action says, to catch
+// upstream bugs that repeat pages of query results in exports. The duplicates are reported by DuplicateRows and counted
+// in the Summary. The cells of the window rows are kept to compare them, and the rows started by BeginRow aren't
+// checked, their cells not being known at once. A window of 0 turns the guard off.
+func (sb *StreamFileBuilder) SetDuplicateRowGuard(window int, action DuplicateRowAction) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if window < 0 {
+		return fmt.Errorf("the window of the duplicate row guard can't be negative, not %d", window)
+	}
+	if window > 0 && action != FlagDuplicateRows && action != SkipDuplicateRows {
+		return fmt.Errorf("unknown duplicate row action %d", action)
+	}
+	sb.duplicateWindow = window
+	sb.duplicateAction = action
+	return nil
+}
+
+// SetCompression sets the way the parts of the file are compressed. By default they are compressed with Deflate, the
+// compressed rows reaching the writer a block at a time. CompressionDeflateStreaming flushes the compressor along with
+// the rows, so that each row written reaches the writer at once, and CompressionStore leaves the parts uncompressed.
+func (sb *StreamFileBuilder) SetCompression(compression StreamCompression) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if err := validateStreamCompression(compression); err != nil {
+		return err
+	}
+	sb.compression = compression
+	sb.partCompressor = nil
+	return nil
+}
+
+// SetCompressor compresses the parts of the file with compressor rather than with the Deflate compressor of
+// archive/zip, such as a faster Deflate implementation or, for readers that know it, another method such as Zstandard.
+// The writers of the compressor with a Flush method are flushed along with the rows, like with
+// CompressionDeflateStreaming. The compressor replaces the compression set by SetCompression, and the other way round.
+func (sb *StreamFileBuilder) SetCompressor(compressor PartCompressor) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if compressor == nil {
+		return errors.New("a compressor can't be nil")
+	}
+	if compressor.Method() == zip.Store {
+		return errors.New("the method of a compressor can't be that of the stored parts")
+	}
+	sb.compression = CompressionDeflate
+	sb.partCompressor = compressor
+	return nil
+}
+
+// SetThrottle limits the pace at which the file is written, in rows per second, in bytes per second once compressed,
+// or both, the writes waiting as long as needed to keep to the limits, so that a file streamed to a client on a slow
+// link doesn't starve the other traffic sharing it. The header rows, written when the sheets are started, aren't
+// counted. A pause in the writes lets at most a second's worth of rows or bytes through at once when they resume. A
+// zero StreamThrottle removes the limits.
+func (sb *StreamFileBuilder) SetThrottle(throttle StreamThrottle) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if err := validateStreamThrottle(throttle); err != nil {
+		return err
+	}
+	sb.output.throttle = newThrottle(throttle.BytesPerSecond)
+	sb.rowThrottle = newThrottle(throttle.RowsPerSecond)
+	return nil
+}
+
+// SetWriteTimeout sets the longest a write to the writer of the file may block for, past which the StreamFile fails
+// with a WriteTimeoutError, so that an export writing to a stalled network client gives up on it instead of hanging
+// forever. The writes are those of the compressed file, made as its buffers fill, rather than one per row. A timeout
+// of 0, the default, lets the writes block for as long as the writer does, see also StreamFile.SetWriteDeadline.
+func (sb *StreamFileBuilder) SetWriteTimeout(timeout time.Duration) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if err := validateWriteTimeout(timeout); err != nil {
+		return err
+	}
+	sb.output.deadline.timeout = timeout
+	return nil
+}
+
+// SetInvalidUTF8 sets the way the text of the headers and of the cells written that isn't valid UTF-8, such as text
+// read from legacy databases in another encoding, is dealt with. By default the invalid bytes are replaced by U+FFFD.
+// A rejected row is returned by Write as a TextError naming its cell, before anything of it is written.
+func (sb *StreamFileBuilder) SetInvalidUTF8(policy InvalidUTF8Policy) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	sb.xlsxFile.InvalidUTF8 = policy
+	return nil
+}
+
+// SetLineBreaks sets the way the line breaks of the text of the headers and of the cells written are dealt with, see
+// File.LineBreaks. By default the Windows and old Mac line breaks are replaced by line feeds.
+func (sb *StreamFileBuilder) SetLineBreaks(policy LineBreakPolicy) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if policy < LineBreaksNormalized || policy > LineBreaksKept {
+		return fmt.Errorf("invalid line break policy %d", policy)
+	}
+	sb.xlsxFile.LineBreaks = policy
+	return nil
+}
+
+// SetPlainLargeIntegers makes the StreamFile write the integers of 12 digits or more, such as order ids, with the "0"
+// number format, so that Excel doesn't show them in scientific notation, see File.PlainLargeIntegers. Only the number
+// cells without a style, from their column or of their own, are affected.
+func (sb *StreamFileBuilder) SetPlainLargeIntegers(plain bool) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	sb.xlsxFile.PlainLargeIntegers = plain
+	return nil
+}
+
+// SetNullAs sets the way the nulls written to the StreamFile, such as the JSON nulls of the records written by
+// WriteRecords or the cells made by NewNullStreamCell, are written, see File.NullAs. By default they are written as
+// empty cells.
+func (sb *StreamFileBuilder) SetNullAs(policy NullPolicy) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	sb.xlsxFile.NullAs = policy
+	return nil
+}
+
+// SetRenameDuplicateSheets makes AddSheet rename a sheet whose name is already taken, regardless of case, instead of
+// returning an error. A number is added to the name the way Excel does, so that a second "Data" sheet becomes
+// "Data (2)".
+func (sb *StreamFileBuilder) SetRenameDuplicateSheets(rename bool) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	sb.renameDuplicates = rename
+	return nil
+}
+
+// SetColumnStyle sets the style of the cells written to a column of a sheet, which replaces the style that would be
+// given to the cells for the type of the column. The number format of the column is kept.
+func (sb *StreamFileBuilder) SetColumnStyle(sheetIndex, colIndex int, style *Style) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if sheetIndex < 0 || sheetIndex >= len(sb.xlsxFile.Sheets) {
+		return fmt.Errorf("no sheet at index %d", sheetIndex)
+	}
+	if colIndex < 0 || colIndex >= len(sb.xlsxFile.Sheets[sheetIndex].Cols) {
+		return fmt.Errorf("no column at index %d in sheet '%s'", colIndex, sb.xlsxFile.Sheets[sheetIndex].Name)
+	}
+	for len(sb.columnStyles[sheetIndex]) <= colIndex {
+		sb.columnStyles[sheetIndex] = append(sb.columnStyles[sheetIndex], nil)
+	}
+	sb.columnStyles[sheetIndex][colIndex] = style
+	return nil
+}
+
+// SetLeadingZeros keeps the leading zeros of the values written to a column of a sheet, such as ZIP codes or account
+// numbers, whatever the type given to the column by AddSheetWithTypes. If digits is 0, the values are written as text,
+// Excel being told not to flag them as numbers stored as text. Otherwise they are written as numbers shown with at least
+// digits digits, padded with leading zeros, so that "02134" is shown as 02134 with 5 digits and can still be summed or
+// sorted as a number; values that aren't made of digits only, or that have more than 15 digits, are written as text.
+// The style of the column, see SetColumnStyle, is kept.
+func (sb *StreamFileBuilder) SetLeadingZeros(sheetIndex, colIndex, digits int) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if sheetIndex < 0 || sheetIndex >= len(sb.xlsxFile.Sheets) {
+		return fmt.Errorf("no sheet at index %d", sheetIndex)
+	}
+	sheet := sb.xlsxFile.Sheets[sheetIndex]
+	if colIndex < 0 || colIndex >= len(sheet.Cols) {
+		return fmt.Errorf("no column at index %d in sheet '%s'", colIndex, sheet.Name)
+	}
+	if digits < 0 || digits > maxZeroPaddedDigits {
+		return fmt.Errorf("digits must be between 0 and %d", maxZeroPaddedDigits)
+	}
+	// The types may share the slice given to AddSheetWithTypes, which is
+	// copied rather than changed.
+	columnTypes := make([]ColumnType, len(sheet.Cols))
+	copy(columnTypes, sb.columnTypes[sheetIndex])
+	sb.columnTypes[sheetIndex] = columnTypes
+	if digits == 0 {
+		columnTypes[colIndex] = ColumnTypeString
+		sheet.Cols[colIndex].numFmt = builtInNumFmt[builtInNumFmtIndex_STRING]
+		column := ColIndexToLetters(colIndex)
+		sheet.IgnoreNumberStoredAsText(fmt.Sprintf("%s2:%s%d", column, column, Excel2006MaxRowCount))
+	} else {
+		columnTypes[colIndex] = columnTypeZeroPadded
+		sheet.Cols[colIndex].numFmt = strings.Repeat("0", digits)
+	}
+	// The number format is given to the cells of the column by its style.
+	for len(sb.columnStyles[sheetIndex]) <= colIndex {
+		sb.columnStyles[sheetIndex] = append(sb.columnStyles[sheetIndex], nil)
+	}
+	if sb.columnStyles[sheetIndex][colIndex] == nil {
+		sb.columnStyles[sheetIndex][colIndex] = NewStyle()
+	}
+	return nil
+}
+
+// AddStyle adds a style for single cells, along with numFmt, a number format such as "#,##0.00" or "" for the general
+// format, and returns its id. The cells written with WriteStyled, or as StreamCells by WriteTyped, are given the style
+// of their id rather than that of their column or of their banded row. Ids start at 1, 0 leaving a cell with the style
+// it would have had otherwise.
+func (sb *StreamFileBuilder) AddStyle(style *Style, numFmt string) (int, error) {
+	if sb.built {
+		return 0, BuiltStreamFileBuilderError
+	}
+	if style == nil {
+		return 0, errors.New("a style can't be nil")
+	}
+	sb.cellStyles = append(sb.cellStyles, streamStyle{style: style, numFmt: numFmt})
+	return len(sb.cellStyles), nil
+}
+
+// SetColWidth sets the width of a column of a sheet, in characters, from 0 to 255, like Sheet.SetColWidth. Columns
+// default to ColWidth, which is narrow for most exports, or to the width of their type, see SetColumnTypeWidth.
+func (sb *StreamFileBuilder) SetColWidth(sheetIndex, colIndex int, width float64) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if sheetIndex < 0 || sheetIndex >= len(sb.xlsxFile.Sheets) {
+		return fmt.Errorf("no sheet at index %d", sheetIndex)
+	}
+	if colIndex < 0 || colIndex >= len(sb.xlsxFile.Sheets[sheetIndex].Cols) {
+		return fmt.Errorf("no column at index %d in sheet '%s'", colIndex, sb.xlsxFile.Sheets[sheetIndex].Name)
+	}
+	if width <= 0 || width > maxColumnWidth {
+		return fmt.Errorf("invalid column width %g, which must be more than 0 and at most %d", width, maxColumnWidth)
+	}
+	sb.xlsxFile.Sheets[sheetIndex].Cols[colIndex].Width = width
+	return nil
+}
+
+// SetColumnTypeWidth sets the width, in characters, from 0 to 255, of the columns of type columnType, see
+// AddSheetWithTypes, in place of its default width: 12 characters for dates, which would show as ##### in narrower
+// columns, 7 for booleans and ColWidth for the others. The width of a single column is set with SetColWidth, and the
+// columns of the sheets sized to their contents are sized like the others, see SetAutoColumnWidths.
+func (sb *StreamFileBuilder) SetColumnTypeWidth(columnType ColumnType, width float64) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if columnType < ColumnTypeString || columnType > ColumnTypeBool {
+		return fmt.Errorf("unknown column type %d", columnType)
+	}
+	if width <= 0 || width > maxColumnWidth {
+		return fmt.Errorf("invalid column width %g, which must be more than 0 and at most %d", width, maxColumnWidth)
+	}
+	if sb.columnTypeWidths == nil {
+		sb.columnTypeWidths = make(map[ColumnType]float64)
+	}
+	sb.columnTypeWidths[columnType] = width
+	return nil
+}
+
+// SetAutoColumnWidths sizes the columns of a sheet to their contents, like Excel does when their borders are double
+// clicked: each column is made as wide as the widest of its header and values, from ColWidth to 255 characters, unless
+// its width was set with SetColWidth. Since the widths of the columns come before the rows in the XML of a sheet, the
+// rows written to the sheet are kept in memory until the sheet is done, which suits sheets of a moderate size. The
+// follow-on sheets of a wide sheet are sized along with it, see SetSplitWideSheets.
+func (sb *StreamFileBuilder) SetAutoColumnWidths(sheetIndex int) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if sheetIndex < 0 || sheetIndex >= len(sb.xlsxFile.Sheets) {
+		return fmt.Errorf("no sheet at index %d", sheetIndex)
+	}
+	for i := sheetIndex; i <= sheetIndex+sb.followOns[sheetIndex]; i++ {
+		sb.autoColumnWidths[i] = true
+	}
+	return nil
+}
+
+// SetActiveCell sets the cell selected when a sheet is opened, and the cell in the top left corner of its view, so that
+// a report can open at its summary rather than at A1. An empty topLeftCell leaves the sheet scrolled to its top. With
+// the header row frozen, see AddSheetWithOptions, the rows scrolled are those below it, and topLeftCell can't be in
+// the header row.
+func (sb *StreamFileBuilder) SetActiveCell(sheetIndex int, activeCell, topLeftCell string) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if sheetIndex < 0 || sheetIndex >= len(sb.xlsxFile.Sheets) {
+		return fmt.Errorf("no sheet at index %d", sheetIndex)
+	}
+	if _, row, ok := parseCellID(activeCell); !ok || row < 0 {
+		return fmt.Errorf("invalid active cell '%s'", activeCell)
+	}
+	sheet := sb.xlsxFile.Sheets[sheetIndex]
+	if len(sheet.SheetViews) == 0 {
+		sheet.SheetViews = []SheetView{{}}
+	}
+	view := &sheet.SheetViews[0]
+	if topLeftCell != "" {
+		col, row, ok := parseCellID(topLeftCell)
+		if !ok || row < 0 {
+			return fmt.Errorf("invalid top left cell '%s'", topLeftCell)
+		}
+		if view.Pane != nil && view.Pane.State == "frozen" {
+			if float64(col) < view.Pane.XSplit || float64(row) < view.Pane.YSplit {
+				return fmt.Errorf("the top left cell %s is in the frozen panes of sheet '%s'", topLeftCell, sheet.Name)
+			}
+			view.Pane.TopLeftCell = topLeftCell
+		} else {
+			view.TopLeftCell = topLeftCell
+		}
+	}
+	view.ActiveCell = activeCell
+	return nil
+}
+
+// SetSplitPane splits the view of a sheet into panes that scroll apart, unlike frozen ones, xSplit points from its left
+// and ySplit points from its top, either of which may be 0 to split it one way only. topLeftCell is the first cell
+// shown in the pane below and right of the split, such as "B3". A sheet whose header row is frozen, see
+// AddSheetWithOptions, can't be split as well.
+func (sb *StreamFileBuilder) SetSplitPane(sheetIndex int, xSplit, ySplit float64, topLeftCell string) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if sheetIndex < 0 || sheetIndex >= len(sb.xlsxFile.Sheets) {
+		return fmt.Errorf("no sheet at index %d", sheetIndex)
+	}
+	if xSplit < 0 || ySplit < 0 || xSplit == 0 && ySplit == 0 {
+		return fmt.Errorf("invalid split %g, %g, which must be positive one way at least", xSplit, ySplit)
+	}
+	if _, row, ok := parseCellID(topLeftCell); !ok || row < 0 {
+		return fmt.Errorf("invalid top left cell '%s'", topLeftCell)
+	}
+	sheet := sb.xlsxFile.Sheets[sheetIndex]
+	if len(sheet.SheetViews) == 0 {
+		sheet.SheetViews = []SheetView{{}}
+	}
+	view := &sheet.SheetViews[0]
+	if view.Pane != nil && view.Pane.State == "frozen" {
+		return fmt.Errorf("sheet '%s' has frozen panes, which can't be split as well", sheet.Name)
+	}
+	// The pane that is active is that below and right of the split.
+	activePane := "bottomRight"
+	if xSplit == 0 {
+		activePane = "bottomLeft"
+	} else if ySplit == 0 {
+		activePane = "topRight"
+	}
+	view.Pane = &Pane{
+		XSplit:      xSplit * 20,
+		YSplit:      ySplit * 20,
+		TopLeftCell: topLeftCell,
+		ActivePane:  activePane,
+		State:       "split",
+	}
+	return nil
+}
+
+// SetColumnFormula makes a column of a sheet a formula column, whose cells hold formula, such as "=C{row}*D{row}",
+// with {row} replaced by the number of their row. The rows written to the sheet, and passed to the RowHook, then leave
+// out the cells of its formula columns, which are written along with the others. Since the values of the formulas
+// aren't written, the workbook is set to recalculate every formula when it is opened.
+func (sb *StreamFileBuilder) SetColumnFormula(sheetIndex, colIndex int, formula string) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if sheetIndex < 0 || sheetIndex >= len(sb.xlsxFile.Sheets) {
+		return fmt.Errorf("no sheet at index %d", sheetIndex)
+	}
+	if colIndex < 0 || colIndex >= len(sb.xlsxFile.Sheets[sheetIndex].Cols) {
+		return fmt.Errorf("no column at index %d in sheet '%s'", colIndex, sb.xlsxFile.Sheets[sheetIndex].Name)
+	}
+	formula = strings.TrimPrefix(formula, "=")
+	if formula == "" {
+		return errors.New("the formula of a column can't be empty")
+	}
+	for len(sb.columnFormulas[sheetIndex]) <= colIndex {
+		sb.columnFormulas[sheetIndex] = append(sb.columnFormulas[sheetIndex], "")
+	}
+	sb.columnFormulas[sheetIndex][colIndex] = formula
+	sb.xlsxFile.CalcProperties.FullCalcOnLoad = true
+	return nil
+}
+
+// SetColumnMask makes the values written to a column of a sheet pass through mask, such as MaskDigits(4) hiding all
+// but the last four digits of phone numbers or social security numbers, so that the file only holds what its readers
+// need. The masked values are written as text, once the row has been through the RowHook, nil writing the values as
+// they are again. The cells of formula columns and formula cells aren't masked.
+func (sb *StreamFileBuilder) SetColumnMask(sheetIndex, colIndex int, mask Mask) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if sheetIndex < 0 || sheetIndex >= len(sb.xlsxFile.Sheets) {
+		return fmt.Errorf("no sheet at index %d", sheetIndex)
+	}
+	if colIndex < 0 || colIndex >= len(sb.xlsxFile.Sheets[sheetIndex].Cols) {
+		return fmt.Errorf("no column at index %d in sheet '%s'", colIndex, sb.xlsxFile.Sheets[sheetIndex].Name)
+	}
+	for len(sb.columnMasks[sheetIndex]) <= colIndex {
+		sb.columnMasks[sheetIndex] = append(sb.columnMasks[sheetIndex], nil)
+	}
+	sb.columnMasks[sheetIndex][colIndex] = mask
+	return nil
+}
+
+// SetBandedRows gives every other row written to a sheet a solid fill of color, an ARGB color such as "FFDDEBF7", to
+// make the rows easier to follow across a wide sheet. Banding starts with the second row after the header, the rest
+// of the style of the cells being that of their column.
+func (sb *StreamFileBuilder) SetBandedRows(sheetIndex int, color string) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if sheetIndex < 0 || sheetIndex >= len(sb.xlsxFile.Sheets) {
+		return fmt.Errorf("no sheet at index %d", sheetIndex)
+	}
+	sb.bandColors[sheetIndex] = color
+	return nil
+}
+
+// SetSheetProtection protects a sheet against editing as protection says, such as a sheet protected with
+// Sheet.Protect, nil removing any protection. Its EditableRanges, such as "C2:C1048576" for the column C below the
+// header, can be edited without the password, so that only some columns of a form can be filled in.
+func (sb *StreamFileBuilder) SetSheetProtection(sheetIndex int, protection *SheetProtection) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if sheetIndex < 0 || sheetIndex >= len(sb.xlsxFile.Sheets) {
+		return fmt.Errorf("no sheet at index %d", sheetIndex)
+	}
+	if protection != nil {
+		for _, ref := range protection.EditableRanges {
+			for _, cellID := range strings.SplitN(ref, cellRangeChar, 2) {
+				if _, _, ok := parseCellID(cellID); !ok {
+					return fmt.Errorf("invalid editable range '%s'", ref)
+				}
+			}
+		}
+	}
+	sb.xlsxFile.Sheets[sheetIndex].Protection = protection
+	return nil
+}
+
+// SetWorkbookProtection protects the structure and windows of the workbook as protection says, such as to stop the
+// sheets of a form from being renamed or removed, nil removing any protection.
+func (sb *StreamFileBuilder) SetWorkbookProtection(protection *WorkbookProtection) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	sb.xlsxFile.Protection = protection
+	return nil
+}
+
 // AddValidation will add a validation to a specific column.
 func (sb *StreamFileBuilder) AddValidation(sheetIndex, colIndex, rowStartIndex int, validation *xlsxCellDataValidation) {
 	sheet := sb.xlsxFile.Sheets[sheetIndex]
@@ -126,24 +1028,190 @@ func (sb *StreamFileBuilder) AddValidation(sheetIndex, colIndex, rowStartIndex i
 	column.SetDataValidationWithStart(validation, rowStartIndex)
 }
 
+// AddIgnoredError will turn off Excel's error checking for a range of cells on a sheet, for example to stop numeric
+// identifiers written as strings from being flagged.
+func (sb *StreamFileBuilder) AddIgnoredError(sheetIndex int, ignoredError *IgnoredError) {
+	sheet := sb.xlsxFile.Sheets[sheetIndex]
+	sheet.AddIgnoredError(ignoredError)
+}
+
 // Build begins streaming the XLSX file to the io, by writing all the XLSX metadata. It creates a StreamFile struct
-// that can be used to write the rows to the sheets.
+// that can be used to write the rows to the sheets. A builder without sheets builds a workbook with a single empty
+// sheet called Sheet1, since workbooks must have at least one sheet.
 func (sb *StreamFileBuilder) Build() (*StreamFile, error) {
 	if sb.built {
 		return nil, BuiltStreamFileBuilderError
 	}
+	if len(sb.xlsxFile.Sheets) == 0 {
+		// A workbook must have at least one sheet, so an empty
+		// workbook is given an empty one.
+		if err := sb.addSheet("Sheet1", nil, nil); err != nil {
+			return nil, err
+		}
+	}
+	if err := sb.addListSheets(); err != nil {
+		return nil, err
+	}
+	visible := false
+	for _, sheet := range sb.xlsxFile.Sheets {
+		visible = visible || !sheet.Hidden && !sheet.VeryHidden
+	}
+	if !visible {
+		return nil, errors.New("every sheet of the workbook is hidden, which must show at least one")
+	}
+	largeIntegerStyleId := 0
+	if sb.xlsxFile.PlainLargeIntegers {
+		// The large integers are written with a style of their own
+		// like the cells given a style added with AddStyle.
+		var err error
+		largeIntegerStyleId, err = sb.AddStyle(NewStyle(), builtInNumFmt[builtInNumFmtIndex_INT])
+		if err != nil {
+			return nil, err
+		}
+	}
+	nullStyleId := 0
+	if style := sb.xlsxFile.NullAs.Style; style != nil {
+		// The cells holding nulls are styled like those given a style
+		// added with AddStyle.
+		var err error
+		if nullStyleId, err = sb.AddStyle(style, ""); err != nil {
+			return nil, err
+		}
+	}
+	if err := sb.addImages(); err != nil {
+		return nil, err
+	}
+	sb.setColumnTypeWidths()
+	sb.applyNumberFormatsPerColumn()
 	sb.built = true
-	parts, err := sb.xlsxFile.MarshallParts()
+	parts, err := sb.xlsxFile.marshallParts(writeOptions{defaultFont: sb.defaultFont, numbersFont: sb.numbersFont,
+		sharedStrings: sb.sharedStrings})
+	if err != nil {
+		return nil, err
+	}
+	if sb.theme != "" {
+		parts[themePart] = sb.theme
+	}
+	sb.addDocumentProperties(parts)
+	bandedStyleIds, dateStyleId, err := sb.addStreamStyles(parts)
 	if err != nil {
 		return nil, err
 	}
+	sb.addConditionalStyles(parts)
 	es := &StreamFile{
 		zipWriter:      sb.zipWriter,
 		xlsxFile:       sb.xlsxFile,
 		sheetXmlPrefix: make([]string, len(sb.xlsxFile.Sheets)),
 		sheetXmlSuffix: make([]string, len(sb.xlsxFile.Sheets)),
 		styleIds:       sb.styleIds,
+		bandedStyleIds: bandedStyleIds,
+		dateStyleId:    dateStyleId,
+		cellStyleIds:   sb.cellStyleIds,
+		nullStyleId:    nullStyleId,
+		builtSheets:    len(sb.xlsxFile.Sheets),
+
+		omitCellReferences: sb.omitCellReferences && !sb.googleSheets,
+		rowHook:            sb.rowHook,
+		partHook:           sb.partHook,
+		followOns:          sb.followOns,
+		columnFormulas:     sb.columnFormulas,
+		columnTypes:        sb.columnTypes,
+		numericColumns:     sb.numericColumns,
+		longTextPolicies:   sb.longTextPolicies,
+		localization:       sb.localization,
+		wrappedXfIds:       sb.wrappedXfIds,
+		columnMasks:        sb.columnMasks,
+		columnUnits:        sb.columnUnits,
+		dateColumns:        sb.dateColumns,
+		conditionalFormats: sb.conditionalFormats,
+		maxRows:            Excel2006MaxRowCount,
+		rowLimitRollover:   sb.rowLimitRollover,
+		duplicateWindow:    sb.duplicateWindow,
+		duplicateAction:    sb.duplicateAction,
+		compression:        sb.compression,
+		rowThrottle:        sb.rowThrottle,
+		output:             sb.output,
+		noZip64:            sb.noZip64,
+		progressHook:       sb.progressHook,
+		progressInterval:   sb.progressInterval,
+		flushRows:          sb.flushRows,
+		flushBytes:         sb.flushBytes,
+		structSheets:       sb.structSheets,
+		autoColumnWidths:   sb.autoColumnWidths,
+		expectedRows:       sb.expectedRows,
+		rowCounts:          make([]int, len(sb.xlsxFile.Sheets)),
+		cellCounts:         make([]int, len(sb.xlsxFile.Sheets)),
+		partSizes:          make(map[string]int64),
+	}
+	es.largeIntegerStyleId = largeIntegerStyleId
+	es.autoFilterRefs = make([]string, len(sb.xlsxFile.Sheets))
+	for i, sheet := range sb.xlsxFile.Sheets {
+		if sheet.AutoFilter != nil {
+			es.autoFilterRefs[i] = sheet.AutoFilter.TopLeftCell + cellRangeChar + sheet.AutoFilter.BottomRightCell
+		}
+	}
+	partCompressor := sb.partCompressor
+	if sb.compression == CompressionDeflateStreaming {
+		partCompressor = deflateStreamingCompressor{}
+	}
+	method := sb.compression.zipMethod()
+	if partCompressor != nil {
+		method = partCompressor.Method()
+	}
+	if output, offset := seekableOutputOf(sb.output.writer); output != nil && (method == zip.Deflate || method == zip.Store) {
+		// The dimensions of the sheets are patched in by Close, see
+		// patchDimensions.
+		es.seekable, es.seekableBase = output, offset-sb.output.bytesWritten()
+		es.dimensionRefs = make(map[string]string)
+		if method == zip.Deflate {
+			partCompressor = dimensionCompressor{compressor: partCompressor}
+		}
+	}
+	if partCompressor != nil {
+		// The compressor must be registered before the first part is
+		// created.
+		es.compressor = &streamCompressor{partCompressor: partCompressor}
+		sb.zipWriter.RegisterCompressor(partCompressor.Method(), es.compressor.open)
+	}
+	if sb.sharedStrings == SharedStringsShared || sb.googleSheets && sb.sharedStrings != SharedStringsInline {
+		// The shared strings already hold the headers, and are
+		// written once every string is known.
+		es.sharedStrings, err = readStreamSharedStrings(parts[sharedStringsPart])
+		if err != nil {
+			return nil, err
+		}
+		es.sharedStringLimit = sb.sharedStringLimit
+		delete(parts, sharedStringsPart)
 	}
+	// The relationships of the sheets and the content types are
+	// written once the sheets are done, so that relationships and
+	// parts can be added while they are streamed.
+	es.sheetRels = make([][]xlsxWorkbookRelation, len(sb.xlsxFile.Sheets))
+	for i := range sb.xlsxFile.Sheets {
+		relsPath := sheetRelsPath(i + 1)
+		if data, ok := parts[relsPath]; ok {
+			rels := xlsxWorkbookRels{}
+			if err := xml.Unmarshal([]byte(data), &rels); err != nil {
+				return nil, err
+			}
+			es.sheetRels[i] = rels.Relationships
+			delete(parts, relsPath)
+		}
+	}
+	if err := xml.Unmarshal([]byte(parts[contentTypesPart]), &es.contentTypes); err != nil {
+		return nil, err
+	}
+	if _, ok := parts[customPropertiesPart]; ok {
+		es.contentTypes.Overrides = append(es.contentTypes.Overrides,
+			xlsxOverride{PartName: "/" + customPropertiesPart, ContentType: contentTypeCustomProperties})
+	}
+	delete(parts, contentTypesPart)
+	// The workbook and its relationships list the sheets, which may
+	// be added until the file is closed.
+	es.workbookPart = parts[workbookPart]
+	es.workbookRelsPart = parts[workbookRelsPart]
+	delete(parts, workbookPart)
+	delete(parts, workbookRelsPart)
 	for path, data := range parts {
 		// If the part is a sheet, don't write it yet. We only want to write the XLSX metadata files, since at this
 		// point the sheets are still empty. The sheet files will be written later as their rows come in.
@@ -153,11 +1221,17 @@ func (sb *StreamFileBuilder) Build() (*StreamFile, error) {
 			}
 			continue
 		}
-		metadataFile, err := sb.zipWriter.Create(path)
+		body := []byte(data)
+		if sb.partHook != nil {
+			if body, err = sb.partHook(path, body); err != nil {
+				return nil, err
+			}
+		}
+		metadataFile, err := es.createPart(path)
 		if err != nil {
 			return nil, err
 		}
-		_, err = metadataFile.Write([]byte(data))
+		_, err = metadataFile.Write(body)
 		if err != nil {
 			return nil, err
 		}
@@ -169,6 +1243,99 @@ func (sb *StreamFileBuilder) Build() (*StreamFile, error) {
 	return es, nil
 }
 
+// addStreamStyles adds the column styles, the styles of the banded rows, the styles added with AddStyle and the style of
+// the date cells to the style sheet of the file, once its parts are made, and replaces the style sheet among parts. It
+// returns the style ids of the cells of the banded rows of each sheet, nil for sheets without banded rows, and the style
+// id of the date cells.
+func (sb *StreamFileBuilder) addStreamStyles(parts map[string]string) ([][]int, int, error) {
+	styles := sb.xlsxFile.styles
+	bandedStyleIds := make([][]int, len(sb.xlsxFile.Sheets))
+	// The style of the date cells is only known to be needed once
+	// they are written, so it is always added.
+	dateStyleId := handleStyleForXLSX(NewStyle(), styles.newNumFmt(DefaultDateFormat).NumFmtId, styles)
+	sb.cellStyleIds = make([]int, len(sb.cellStyles))
+	for i, cellStyle := range sb.cellStyles {
+		numFmtId := 0
+		if cellStyle.numFmt != "" {
+			numFmtId = styles.newNumFmt(cellStyle.numFmt).NumFmtId
+		}
+		sb.cellStyleIds[i] = handleStyleForXLSX(cellStyle.style, numFmtId, styles)
+	}
+	for sheetIndex, sheet := range sb.xlsxFile.Sheets {
+		numFmtIds := make([]int, len(sheet.Cols))
+		for colIndex, col := range sheet.Cols {
+			if col.numFmt != "" {
+				numFmtIds[colIndex] = styles.newNumFmt(col.numFmt).NumFmtId
+			}
+		}
+		// The ids of the styles of the columns given cell types were taken to follow the order of the types, which
+		// the number formats set by SetLeadingZeros upset, so they are looked up.
+		for colIndex, styleId := range sb.styleIds[sheetIndex] {
+			if styleId != 0 {
+				sb.styleIds[sheetIndex][colIndex] = handleStyleForXLSX(sheet.Cols[colIndex].GetStyle(), numFmtIds[colIndex], styles)
+			}
+		}
+		for colIndex, style := range sb.columnStyles[sheetIndex] {
+			if style == nil {
+				continue
+			}
+			for len(sb.styleIds[sheetIndex]) <= colIndex {
+				sb.styleIds[sheetIndex] = append(sb.styleIds[sheetIndex], 0)
+			}
+			sb.styleIds[sheetIndex][colIndex] = handleStyleForXLSX(style, numFmtIds[colIndex], styles)
+		}
+		if sb.bandColors[sheetIndex] == "" {
+			continue
+		}
+		bandedStyleIds[sheetIndex] = make([]int, len(sheet.Cols))
+		for colIndex := range sheet.Cols {
+			banded := NewStyle()
+			if colIndex < len(sb.columnStyles[sheetIndex]) && sb.columnStyles[sheetIndex][colIndex] != nil {
+				*banded = *sb.columnStyles[sheetIndex][colIndex]
+			}
+			banded.Fill = *NewFill("solid", sb.bandColors[sheetIndex], sb.bandColors[sheetIndex])
+			banded.ApplyFill = true
+			bandedStyleIds[sheetIndex][colIndex] = handleStyleForXLSX(banded, numFmtIds[colIndex], styles)
+		}
+	}
+	if sb.xlsxFile.wrapsLineBreaks() {
+		// The cells holding line breaks are only known once they
+		// are written, so every style has a wrapped twin.
+		sb.wrappedXfIds = make([]int, len(styles.CellXfs.Xf))
+		for xfId := range sb.wrappedXfIds {
+			sb.wrappedXfIds[xfId] = styles.wrappedCellXf(xfId)
+		}
+	}
+	if sb.defaultFont != nil {
+		styles.replaceDefaultFont(sb.defaultFont)
+	}
+	fonts := styles.Fonts
+	if sb.xlsxFile.NumbersCompatible {
+		fonts = fonts.withMacFonts(writeOptions{numbersFont: sb.numbersFont}.macFont())
+	}
+	styleSheet, err := styles.marshal(fonts)
+	if err != nil {
+		return nil, 0, err
+	}
+	parts["xl/styles.xml"] = styleSheet
+	return bandedStyleIds, dateStyleId, nil
+}
+
+// readStreamSharedStrings makes the table of the shared strings of a streamed file from the part holding those
+// written with the empty sheets.
+func readStreamSharedStrings(part string) (*RefTable, error) {
+	var sst xlsxSST
+	if err := xml.Unmarshal([]byte(part), &sst); err != nil {
+		return nil, err
+	}
+	refTable := NewSharedStringRefTable()
+	refTable.isWrite = true
+	for _, si := range sst.SI {
+		refTable.AddString(unescapeXString(si.T))
+	}
+	return refTable, nil
+}
+
 // processEmptySheetXML will take in the path and XML data of an empty sheet, and will save the beginning and end of the
 // XML file so that these can be written at the right time.
 func (sb *StreamFileBuilder) processEmptySheetXML(sf *StreamFile, path, data string) error {
@@ -179,8 +1346,14 @@ func (sb *StreamFileBuilder) processEmptySheetXML(sf *StreamFile, path, data str
 	}
 
 	// Remove the Dimension tag. Since more rows are going to be written to the sheet, it will be wrong.
-	// It is valid to for a sheet to be missing a Dimension tag, but it is not valid for it to be wrong.
-	data, err = removeDimensionTag(data, sf.xlsxFile.Sheets[sheetIndex])
+	// It is valid to for a sheet to be missing a Dimension tag, but it is not valid for it to be wrong. The sheets
+	// written to a seekable output get a placeholder for it instead, see patchDimensions, and those whose rows are
+	// expected get the dimension of their rows.
+	if sheetIndex < sb.existingSheets {
+		data, err = removeExistingDimensionTag(data)
+	} else {
+		data, err = replaceDimensionTag(data, sf.xlsxFile.Sheets[sheetIndex], sf.startDimensionTag(sheetIndex+1))
+	}
 	if err != nil {
 		return err
 	}
@@ -195,6 +1368,11 @@ func (sb *StreamFileBuilder) processEmptySheetXML(sf *StreamFile, path, data str
 	return nil
 }
 
+// sheetRelsPath returns the path of the relationships of the sheet with the given XLSX index, which starts at 1.
+func sheetRelsPath(sheetIndex int) string {
+	return sheetRelsPathPrefix + strconv.Itoa(sheetIndex) + sheetRelsPathSuffix
+}
+
 // getSheetIndex parses the path to the XLSX sheet data and returns the index
 // The files that store the data for each sheet must have the format:
 // xl/worksheets/sheet123.xml
@@ -218,26 +1396,17 @@ func getSheetIndex(sf *StreamFile, path string) (int, error) {
 // sheet is the Sheet struct that the XML was created from.
 // Can return an error if the XML's dimension tag does not match was is expected based on the provided Sheet
 func removeDimensionTag(data string, sheet *Sheet) (string, error) {
-	x := len(sheet.Cols) - 1
-	y := len(sheet.Rows) - 1
-	if x < 0 {
-		x = 0
-	}
-	if y < 0 {
-		y = 0
-	}
-	var dimensionRef string
-	if x == 0 && y == 0 {
-		dimensionRef = "A1"
-	} else {
-		endCoordinate := GetCellIDStringFromCoords(x, y)
-		dimensionRef = "A1:" + endCoordinate
-	}
+	return replaceDimensionTag(data, sheet, "")
+}
+
+// replaceDimensionTag is like removeDimensionTag, putting replacement in place of the dimension tag.
+func replaceDimensionTag(data string, sheet *Sheet, replacement string) (string, error) {
+	dimensionRef := dimensionRange(len(sheet.Cols), len(sheet.Rows))
 	dataParts := strings.Split(data, fmt.Sprintf(dimensionTag, dimensionRef))
 	if len(dataParts) != 2 {
 		return "", errors.New("unexpected Sheet XML: dimension tag not found")
 	}
-	return dataParts[0] + dataParts[1], nil
+	return dataParts[0] + replacement + dataParts[1], nil
 }
 
 // splitSheetIntoPrefixAndSuffix will split the provided XML sheet into a prefix and a suffix so that
@@ -0,0 +1,415 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StreamFileReader is the reading counterpart of StreamFile: rather than
+// loading whole sheets into a File, it reads the rows of a sheet one at a
+// time, decoding its XML as it goes, so that workbooks of several
+// gigabytes can be processed in constant memory.  Only the shared
+// strings of the workbook are held in memory.
+//
+// Rows are read as the raw values of their cells, as in Cell.Value:
+// numbers and dates aren't formatted.  ReadTypedRow reads them along
+// with their types.
+type StreamFileReader struct {
+	Date1904   bool
+	zipReader  *zip.Reader
+	closer     io.Closer
+	refTable   *RefTable
+	styles     *xlsxStyleSheet
+	sheetNames []string
+	sheetFiles []*zip.File
+	// err is the error which stopped AllSheets.
+	err error
+}
+
+// StreamSheetReader reads the rows of a sheet of a StreamFileReader.
+type StreamSheetReader struct {
+	Name     string
+	rc       io.ReadCloser
+	decoder  *xml.Decoder
+	refTable *RefTable
+	// styles tell the numbers formatted as dates, see ReadTypedRow.
+	styles         *xlsxStyleSheet
+	sharedFormulas map[int]sharedFormula
+	// date1904 is true if the dates of the workbook count the days
+	// since 1904.
+	date1904 bool
+	// nextRow is the index of the row returned by the next call to
+	// ReadRow.  A row read ahead of it, after a gap of empty rows, is
+	// kept in pending until its turn comes.
+	nextRow      int
+	pending      []StreamCell
+	pendingIndex int
+	hasPending   bool
+	done         bool
+	// err is the error which stopped AllRows.
+	err error
+}
+
+// ErrStreamSheetNotFound is returned when asking a StreamFileReader for a
+// sheet that the workbook doesn't have.
+var ErrStreamSheetNotFound = errors.New("sheet not found in the workbook")
+
+// NewStreamFileReader creates a StreamFileReader reading the XLSX file of
+// size bytes that r reads.
+func NewStreamFileReader(r io.ReaderAt, size int64) (*StreamFileReader, error) {
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return newStreamFileReader(zipReader, nil)
+}
+
+// NewStreamFileReaderForPath creates a StreamFileReader reading the XLSX
+// file at path, which stays open until the StreamFileReader is closed.
+func NewStreamFileReaderForPath(path string) (*StreamFileReader, error) {
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	sfr, err := newStreamFileReader(&z.Reader, z)
+	if err != nil {
+		z.Close()
+		return nil, err
+	}
+	return sfr, nil
+}
+
+// newStreamFileReader reads the list of sheets and the shared strings of
+// the workbook in zipReader.
+func newStreamFileReader(zipReader *zip.Reader, closer io.Closer) (*StreamFileReader, error) {
+	var workbookFile, workbookRels, sharedStrings, styles *zip.File
+	worksheets := make(map[string]*zip.File)
+	for _, v := range zipReader.File {
+		switch {
+		case v.Name == "xl/workbook.xml":
+			workbookFile = v
+		case v.Name == "xl/_rels/workbook.xml.rels":
+			workbookRels = v
+		case v.Name == "xl/sharedStrings.xml":
+			sharedStrings = v
+		case v.Name == "xl/styles.xml":
+			styles = v
+		case strings.HasPrefix(v.Name, "xl/worksheets/") && strings.HasSuffix(v.Name, ".xml") && !strings.Contains(v.Name[14:], "/"):
+			worksheets[v.Name[14:len(v.Name)-4]] = v
+		}
+	}
+	if workbookFile == nil {
+		return nil, fmt.Errorf("xl/workbook.xml not found in input xlsx.")
+	}
+	if workbookRels == nil {
+		return nil, fmt.Errorf("xl/_rels/workbook.xml.rels not found in input xlsx.")
+	}
+	sheetXMLMap, _, err := readWorkbookRelationsFromZipFile(workbookRels)
+	if err != nil {
+		return nil, err
+	}
+	refTable, err := readSharedStringsFromZipFile(sharedStrings)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := workbookFile.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	workbook := new(xlsxWorkbook)
+	if err = xml.NewDecoder(rc).Decode(workbook); err != nil {
+		return nil, err
+	}
+	sfr := &StreamFileReader{
+		Date1904:  workbook.WorkbookPr.Date1904,
+		zipReader: zipReader,
+		closer:    closer,
+		refTable:  refTable,
+	}
+	if styles != nil {
+		if sfr.styles, err = readStylesFromZipFile(styles, nil); err != nil {
+			return nil, err
+		}
+	}
+	for _, sheet := range workbook.Sheets.Sheet {
+		// Chartsheets have no worksheet, and no rows to read.
+		if f := worksheetFileForSheet(sheet, worksheets, sheetXMLMap); f != nil {
+			sfr.sheetNames = append(sfr.sheetNames, sheet.Name)
+			sfr.sheetFiles = append(sfr.sheetFiles, f)
+		}
+	}
+	if len(sfr.sheetFiles) == 0 {
+		return nil, &XLSXReaderError{Err: "No sheets found in XLSX File"}
+	}
+	return sfr, nil
+}
+
+// SheetNames returns the names of the sheets of the workbook, in order.
+func (sfr *StreamFileReader) SheetNames() []string {
+	return append([]string(nil), sfr.sheetNames...)
+}
+
+// Sheet starts reading the sheet at index.  The StreamSheetReader must be
+// closed once done with.
+func (sfr *StreamFileReader) Sheet(index int) (*StreamSheetReader, error) {
+	if index < 0 || index >= len(sfr.sheetFiles) {
+		return nil, ErrStreamSheetNotFound
+	}
+	rc, err := sfr.sheetFiles[index].Open()
+	if err != nil {
+		return nil, err
+	}
+	return &StreamSheetReader{
+		Name:     sfr.sheetNames[index],
+		rc:       rc,
+		decoder:  xml.NewDecoder(rc),
+		refTable: sfr.refTable,
+		styles:   sfr.styles,
+		date1904: sfr.Date1904,
+	}, nil
+}
+
+// SheetByName starts reading the sheet called name.
+func (sfr *StreamFileReader) SheetByName(name string) (*StreamSheetReader, error) {
+	for i, sheetName := range sfr.sheetNames {
+		if sheetName == name {
+			return sfr.Sheet(i)
+		}
+	}
+	return nil, ErrStreamSheetNotFound
+}
+
+// Close releases the file opened by NewStreamFileReaderForPath.  Sheets
+// can't be read once the StreamFileReader is closed.
+func (sfr *StreamFileReader) Close() error {
+	if sfr.closer == nil {
+		return nil
+	}
+	return sfr.closer.Close()
+}
+
+// ReadRow returns the values of the cells of the next row of the sheet,
+// or io.EOF once every row has been read.  Rows and cells missing from
+// the sheet are read as empty, so that the values stay at the position
+// of their cells, and a row has as many values as its last cell calls
+// for.
+func (ssr *StreamSheetReader) ReadRow() ([]string, error) {
+	cells, err := ssr.ReadTypedRow()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, len(cells))
+	for i, cell := range cells {
+		values[i] = cell.Value
+	}
+	return values, nil
+}
+
+// ReadTypedRow returns the cells of the next row of the sheet like
+// ReadRow, along with their types, so that they can be written to a
+// StreamFile with WriteTyped: numbers, booleans and the numbers
+// formatted as dates keep their types, and formulas are kept with their
+// values as cached values.  The other cells, and those missing from the
+// sheet, are strings.  The styles of the cells aren't read.
+func (ssr *StreamSheetReader) ReadTypedRow() ([]StreamCell, error) {
+	if !ssr.hasPending {
+		if ssr.done {
+			return nil, io.EOF
+		}
+		index, cells, err := ssr.readRowElement(ssr.nextRow)
+		if err == io.EOF {
+			ssr.done = true
+		}
+		if err != nil {
+			return nil, err
+		}
+		ssr.pending, ssr.pendingIndex, ssr.hasPending = cells, index, true
+	}
+	if ssr.pendingIndex > ssr.nextRow {
+		ssr.nextRow++
+		return []StreamCell{}, nil
+	}
+	ssr.hasPending = false
+	ssr.nextRow++
+	return ssr.pending, nil
+}
+
+// Rows calls fn with the index and values of each remaining row of the
+// sheet, as read by ReadRow, stopping at the first error it returns.
+func (ssr *StreamSheetReader) Rows(fn func(rowIndex int, cells []string) error) error {
+	for {
+		rowIndex := ssr.nextRow
+		cells, err := ssr.ReadRow()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = fn(rowIndex, cells); err != nil {
+			return err
+		}
+	}
+}
+
+// Close stops reading the sheet.
+func (ssr *StreamSheetReader) Close() error {
+	ssr.done = true
+	ssr.hasPending = false
+	return ssr.rc.Close()
+}
+
+// readRowElement decodes the next row element of the sheet, returning
+// its index and its cells.  A row that doesn't give its index follows
+// the one before it, at defaultIndex.
+func (ssr *StreamSheetReader) readRowElement(defaultIndex int) (int, []StreamCell, error) {
+	for {
+		token, err := ssr.decoder.Token()
+		if err != nil {
+			return 0, nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "row":
+			index := defaultIndex
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "r" {
+					r, err := parseIndex(attr.Value)
+					if err != nil || r < 1 {
+						return 0, nil, fmt.Errorf("sheet %s: invalid row number %q", ssr.Name, attr.Value)
+					}
+					index = r - 1
+				}
+			}
+			if index < defaultIndex {
+				return 0, nil, fmt.Errorf("sheet %s: row %d is out of order", ssr.Name, index+1)
+			}
+			cells, err := ssr.readCells(index)
+			return index, cells, err
+		case "sheetData", "worksheet":
+		default:
+			// The elements around the rows, such as the columns
+			// or the merged cells, aren't read.
+			if err = ssr.decoder.Skip(); err != nil {
+				return 0, nil, err
+			}
+		}
+	}
+}
+
+// readCells decodes the cells of the row element being read, the row at
+// rowIndex, up to its end.
+func (ssr *StreamSheetReader) readCells(rowIndex int) ([]StreamCell, error) {
+	cells := []StreamCell{}
+	for {
+		token, err := ssr.decoder.Token()
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.EndElement:
+			return cells, nil
+		case xml.StartElement:
+			if t.Name.Local != "c" {
+				if err = ssr.decoder.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			var rawCell xlsxC
+			if err = ssr.decoder.DecodeElement(&rawCell, &t); err != nil {
+				return nil, err
+			}
+			col := len(cells)
+			if rawCell.R != "" {
+				x, _, err := GetCoordsFromCellIDString(rawCell.R)
+				if err != nil || x < len(cells) {
+					return nil, fmt.Errorf("sheet %s: invalid cell reference %q", ssr.Name, rawCell.R)
+				}
+				col = x
+			} else {
+				// Shared formulas are shifted from the cell
+				// reference.
+				rawCell.R = GetCellIDStringFromCoords(col, rowIndex)
+			}
+			cell, err := ssr.typedCell(rawCell)
+			if err != nil {
+				return nil, err
+			}
+			for len(cells) < col {
+				cells = append(cells, StreamCell{})
+			}
+			cells = append(cells, cell)
+		}
+	}
+}
+
+// typedCell returns a cell read, typed as ReadTypedRow says.
+func (ssr *StreamSheetReader) typedCell(rawCell xlsxC) (StreamCell, error) {
+	value, err := ssr.cellValue(rawCell)
+	if err != nil {
+		return StreamCell{}, err
+	}
+	cell := StreamCell{Value: value}
+	if rawCell.F != nil {
+		if ssr.sharedFormulas == nil {
+			ssr.sharedFormulas = make(map[int]sharedFormula)
+		}
+		cell.Formula = formulaForCell(rawCell, ssr.sharedFormulas)
+	}
+	if value == "" {
+		return cell, nil
+	}
+	switch rawCell.T {
+	case "", "n":
+		cell.Type = CellTypeNumeric
+		if ssr.isDateStyle(rawCell.S) {
+			cell.Type = CellTypeDate
+		}
+	case "b":
+		cell.Type = CellTypeBool
+	}
+	return cell, nil
+}
+
+// isDateStyle returns true if the cells of the style at styleIndex have a
+// date or time number format.
+func (ssr *StreamSheetReader) isDateStyle(styleIndex int) bool {
+	if ssr.styles == nil || styleIndex < 0 || styleIndex >= len(ssr.styles.CellXfs.Xf) {
+		return false
+	}
+	_, parsed := ssr.styles.getNumberFormat(styleIndex)
+	return parsed.isTimeFormat
+}
+
+// cellValue returns the raw value of a cell, resolving shared strings.
+func (ssr *StreamSheetReader) cellValue(rawCell xlsxC) (string, error) {
+	switch rawCell.T {
+	case "s":
+		val := strings.Trim(rawCell.V, " \t\n\r")
+		if val == "" {
+			return "", nil
+		}
+		ref, err := parseIndex(val)
+		if err != nil || ssr.refTable == nil || ref < 0 || ref >= ssr.refTable.Length() {
+			return "", fmt.Errorf("sheet %s: cell %s refers to missing shared string %q", ssr.Name, rawCell.R, val)
+		}
+		return ssr.refTable.ResolveSharedString(ref), nil
+	case "inlineStr":
+		var cell Cell
+		fillCellDataFromInlineString(rawCell, &cell)
+		return cell.Value, nil
+	}
+	return strings.Trim(rawCell.V, " \t\n\r"), nil
+}
@@ -0,0 +1,133 @@
+package xlsx
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+type StreamFileReaderSuite struct{}
+
+var _ = Suite(&StreamFileReaderSuite{})
+
+func (s *StreamFileReaderSuite) TestReadStreamedFile(c *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	c.Assert(builder.AddSheet("First", []string{"Name", "Count"}, nil), IsNil)
+	c.Assert(builder.AddSheet("Second", []string{"Only"}, nil), IsNil)
+	streamFile, err := builder.Build()
+	c.Assert(err, IsNil)
+	c.Assert(streamFile.Write([]string{"a & b", "1"}), IsNil)
+	c.Assert(streamFile.Write([]string{"<c>", "2"}), IsNil)
+	c.Assert(streamFile.NextSheet(), IsNil)
+	c.Assert(streamFile.Write([]string{"x"}), IsNil)
+	c.Assert(streamFile.Close(), IsNil)
+
+	reader, err := NewStreamFileReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	c.Assert(err, IsNil)
+	defer reader.Close()
+	c.Assert(reader.SheetNames(), DeepEquals, []string{"First", "Second"})
+
+	sheet, err := reader.Sheet(0)
+	c.Assert(err, IsNil)
+	c.Assert(sheet.Name, Equals, "First")
+	var rows [][]string
+	for {
+		row, err := sheet.ReadRow()
+		if err == io.EOF {
+			break
+		}
+		c.Assert(err, IsNil)
+		rows = append(rows, row)
+	}
+	c.Assert(rows, DeepEquals, [][]string{{"Name", "Count"}, {"a & b", "1"}, {"<c>", "2"}})
+	_, err = sheet.ReadRow()
+	c.Assert(err, Equals, io.EOF)
+	c.Assert(sheet.Close(), IsNil)
+
+	sheet, err = reader.SheetByName("Second")
+	c.Assert(err, IsNil)
+	defer sheet.Close()
+	var indexes []int
+	rows = nil
+	err = sheet.Rows(func(rowIndex int, cells []string) error {
+		indexes = append(indexes, rowIndex)
+		rows = append(rows, cells)
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(indexes, DeepEquals, []int{0, 1})
+	c.Assert(rows, DeepEquals, [][]string{{"Only"}, {"x"}})
+
+	_, err = reader.SheetByName("Third")
+	c.Assert(err, Equals, ErrStreamSheetNotFound)
+	_, err = reader.Sheet(2)
+	c.Assert(err, Equals, ErrStreamSheetNotFound)
+}
+
+func (s *StreamFileReaderSuite) TestReadSparseSheet(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sparse")
+	c.Assert(err, IsNil)
+	row := sheet.AddRow()
+	row.AddCell().Value = "A1"
+	row.AddCell()
+	row.AddCell().SetInt(3)
+	row = sheet.Row(3)
+	row.AddCell()
+	row.AddCell().Value = "B4"
+	sheet.Row(4).AddCell().SetBool(true)
+	path := filepath.Join(c.MkDir(), "sparse.xlsx")
+	c.Assert(file.Save(path), IsNil)
+
+	reader, err := NewStreamFileReaderForPath(path)
+	c.Assert(err, IsNil)
+	defer reader.Close()
+	streamSheet, err := reader.Sheet(0)
+	c.Assert(err, IsNil)
+	defer streamSheet.Close()
+	var rows [][]string
+	c.Assert(streamSheet.Rows(func(rowIndex int, cells []string) error {
+		rows = append(rows, cells)
+		return nil
+	}), IsNil)
+	c.Assert(rows, DeepEquals, [][]string{
+		{"A1", "", "3"},
+		{},
+		{},
+		{"", "B4"},
+		{"1"},
+	})
+}
+
+func (s *StreamFileReaderSuite) TestRowsStopsAtError(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Data")
+	c.Assert(err, IsNil)
+	for i := 0; i < 3; i++ {
+		sheet.AddRow().AddCell().SetInt(i)
+	}
+	buffer := bytes.NewBuffer(nil)
+	c.Assert(file.Write(buffer), IsNil)
+
+	reader, err := NewStreamFileReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	c.Assert(err, IsNil)
+	streamSheet, err := reader.Sheet(0)
+	c.Assert(err, IsNil)
+	defer streamSheet.Close()
+	count := 0
+	err = streamSheet.Rows(func(rowIndex int, cells []string) error {
+		count++
+		if rowIndex == 1 {
+			return io.ErrShortWrite
+		}
+		return nil
+	})
+	c.Assert(err, Equals, io.ErrShortWrite)
+	c.Assert(count, Equals, 2)
+	row, err := streamSheet.ReadRow()
+	c.Assert(err, IsNil)
+	c.Assert(row, DeepEquals, []string{"2"})
+}
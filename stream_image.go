@@ -0,0 +1,166 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	// The formats of the images are registered to read their size.
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"strconv"
+	"strings"
+)
+
+const (
+	relationshipTypeDrawing = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/drawing"
+	relationshipTypeImage   = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"
+	contentTypeDrawing      = "application/vnd.openxmlformats-officedocument.drawing+xml"
+	// emusPerPixel is the number of English Metric Units, in which
+	// drawings are measured, per pixel at 96 dpi.
+	emusPerPixel = 9525
+)
+
+// ImageFormat is the format of an image added to a streamed sheet, see
+// StreamFileBuilder.AddImage.
+type ImageFormat string
+
+const (
+	ImageFormatPNG  ImageFormat = "png"
+	ImageFormatJPEG ImageFormat = "jpeg"
+	ImageFormatGIF  ImageFormat = "gif"
+)
+
+// streamImage is an image added to a streamed sheet, whose top left
+// corner is at the cell at col and row, and whose size is in pixels.
+type streamImage struct {
+	col, row      int
+	data          []byte
+	format        ImageFormat
+	width, height int
+}
+
+// AddImage adds the image held by imageData, in the given format, to the sheet called sheetName, its top left corner
+// at anchorCell, such as "A1" for a company logo at the top left of the sheet. The image keeps its size in pixels,
+// and moves with the cell it is anchored to. The images must be added before Build, which writes them along with the
+// drawing of each sheet showing them. A sheet of a file read by NewStreamFileBuilderFromExisting which already has a
+// drawing can't be given images. The name of the sheet is a message key if SetLocalization was called.
+func (sb *StreamFileBuilder) AddImage(sheetName, anchorCell string, imageData []byte, format ImageFormat) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	sheetName = sb.localization.text(sheetName)
+	sheetIndex := -1
+	for i, sheet := range sb.xlsxFile.Sheets {
+		if sheet.Name == sheetName {
+			sheetIndex = i
+			break
+		}
+	}
+	if sheetIndex < 0 {
+		return fmt.Errorf("no sheet named '%s'", sheetName)
+	}
+	if sb.xlsxFile.Sheets[sheetIndex].rawDrawing != nil {
+		return fmt.Errorf("the sheet '%s' already has a drawing", sheetName)
+	}
+	col, row, ok := parseCellID(anchorCell)
+	if !ok || row < 0 {
+		return fmt.Errorf("invalid anchor cell '%s'", anchorCell)
+	}
+	switch format {
+	case ImageFormatPNG, ImageFormatJPEG, ImageFormatGIF:
+	default:
+		return fmt.Errorf("unsupported image format '%s'", format)
+	}
+	config, decoded, err := image.DecodeConfig(bytes.NewReader(imageData))
+	if err != nil || ImageFormat(decoded) != format {
+		return fmt.Errorf("the image isn't a %s image", format)
+	}
+	sb.images[sheetIndex] = append(sb.images[sheetIndex], streamImage{
+		col: col, row: row, data: imageData, format: format, width: config.Width, height: config.Height,
+	})
+	return nil
+}
+
+// addImages adds the images of each sheet, the drawing showing them
+// and its relationships to the raw parts of the file, and the drawing
+// to its sheet, which File.MarshallParts writes along with the other
+// parts.
+func (sb *StreamFileBuilder) addImages() error {
+	f := sb.xlsxFile
+	for sheetIndex, images := range sb.images {
+		if len(images) == 0 {
+			continue
+		}
+		drawingPath := unusedRawPartName(f, "xl/drawings/drawing", ".xml")
+		var drawingRels xlsxWorkbookRels
+		var drawing bytes.Buffer
+		drawing.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+		drawing.WriteString(`<xdr:wsDr xmlns:xdr="http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing"` +
+			` xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"` +
+			` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+		for i, img := range images {
+			imagePath := unusedRawPartName(f, "xl/media/image", "."+string(img.format))
+			if err := f.SetRawPart(imagePath, img.data); err != nil {
+				return err
+			}
+			rId := "rId" + strconv.Itoa(i+1)
+			drawingRels.Relationships = append(drawingRels.Relationships, xlsxWorkbookRelation{
+				Id: rId, Target: "../media/" + strings.TrimPrefix(imagePath, "xl/media/"), Type: relationshipTypeImage,
+			})
+			cx, cy := strconv.Itoa(img.width*emusPerPixel), strconv.Itoa(img.height*emusPerPixel)
+			// The ids of the pictures start at 2, 1 being the id of
+			// the drawing.
+			drawing.WriteString(`<xdr:oneCellAnchor><xdr:from><xdr:col>` + strconv.Itoa(img.col) + `</xdr:col><xdr:colOff>0</xdr:colOff>` +
+				`<xdr:row>` + strconv.Itoa(img.row) + `</xdr:row><xdr:rowOff>0</xdr:rowOff></xdr:from>` +
+				`<xdr:ext cx="` + cx + `" cy="` + cy + `"/><xdr:pic><xdr:nvPicPr>` +
+				`<xdr:cNvPr id="` + strconv.Itoa(i+2) + `" name="Picture ` + strconv.Itoa(i+1) + `"/>` +
+				`<xdr:cNvPicPr><a:picLocks noChangeAspect="1"/></xdr:cNvPicPr></xdr:nvPicPr>` +
+				`<xdr:blipFill><a:blip r:embed="` + rId + `"/><a:stretch><a:fillRect/></a:stretch></xdr:blipFill>` +
+				`<xdr:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="` + cx + `" cy="` + cy + `"/></a:xfrm>` +
+				`<a:prstGeom prst="rect"><a:avLst/></a:prstGeom></xdr:spPr></xdr:pic><xdr:clientData/></xdr:oneCellAnchor>`)
+		}
+		drawing.WriteString(`</xdr:wsDr>`)
+		if err := f.SetRawPart(drawingPath, []byte(drawing.String())); err != nil {
+			return err
+		}
+		if err := f.SetRawPartContentType(drawingPath, contentTypeDrawing); err != nil {
+			return err
+		}
+		rels, err := marshalDrawingRels(drawingRels)
+		if err != nil {
+			return err
+		}
+		drawingName := strings.TrimPrefix(drawingPath, "xl/drawings/")
+		if err := f.SetRawPart("xl/drawings/_rels/"+drawingName+".rels", rels); err != nil {
+			return err
+		}
+		sheet := f.Sheets[sheetIndex]
+		rel := xlsxWorkbookRelation{Id: unusedRelationshipId(sheet.rawRelationships), Target: "../drawings/" + drawingName, Type: relationshipTypeDrawing}
+		sheet.rawRelationships = append(sheet.rawRelationships, rel)
+		sheet.rawDrawing = &xlsxDrawing{Id: rel.Id}
+	}
+	return nil
+}
+
+// unusedRawPartName returns the name of a part, prefix followed by a
+// number and suffix, which f doesn't have yet.
+func unusedRawPartName(f *File, prefix, suffix string) string {
+	for n := 1; ; n++ {
+		name := prefix + strconv.Itoa(n) + suffix
+		if _, exists := f.rawParts[name]; !exists {
+			return name
+		}
+	}
+}
+
+// marshalDrawingRels returns the relationships part of a drawing,
+// listing rels.
+func marshalDrawingRels(rels xlsxWorkbookRels) ([]byte, error) {
+	body, err := xml.Marshal(rels)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(xml.Header + string(body)), nil
+}
@@ -0,0 +1,91 @@
+package xlsx
+
+import (
+	"errors"
+)
+
+// Translator returns the text of the message key in the given locale,
+// such as "de-DE", and false if it has none, see
+// StreamFileBuilder.SetLocalization.
+type Translator func(locale, key string) (string, bool)
+
+// localization translates the names of the sheets and the headers given
+// to a StreamFileBuilder and to its StreamFile, see SetLocalization.
+type localization struct {
+	locale    string
+	translate Translator
+	// missing holds the keys without text, in the order they were
+	// met, and seen every key met.
+	missing []string
+	seen    map[string]bool
+}
+
+// SetLocalization makes the names of the sheets and the headers given to AddSheet, and to the other methods adding
+// sheets, message keys translated to locale by translate, so that the same code writes an export for each of the
+// locales of its readers. The keys translate has no text for are written as they are, so that the headers that needn't
+// be translated, such as codes, can be given as they are, and are reported by MissingTranslations. The sheets given to
+// AddImage are named by their keys as well. The localization must be set before the sheets are added.
+func (sb *StreamFileBuilder) SetLocalization(locale string, translate Translator) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if translate == nil {
+		return errors.New("a localization needs a translator")
+	}
+	if len(sb.xlsxFile.Sheets) > sb.existingSheets {
+		return errors.New("the localization must be set before the sheets are added")
+	}
+	sb.localization = &localization{locale: locale, translate: translate, seen: make(map[string]bool)}
+	return nil
+}
+
+// MissingTranslations returns the message keys given so far that the
+// translator has no text for, in the order they were given, see
+// SetLocalization.
+func (sb *StreamFileBuilder) MissingTranslations() []string {
+	return sb.localization.missingKeys()
+}
+
+// MissingTranslations returns the message keys given to the
+// StreamFileBuilder and to AddSheet that the translator has no text for,
+// see StreamFileBuilder.SetLocalization.
+func (sf *StreamFile) MissingTranslations() []string {
+	return sf.localization.missingKeys()
+}
+
+// text returns the text of key, or key if there is no localization or
+// the translator has no text for it.
+func (l *localization) text(key string) string {
+	if l == nil || key == "" {
+		return key
+	}
+	if text, ok := l.translate(l.locale, key); ok {
+		return text
+	}
+	if !l.seen[key] {
+		l.seen[key] = true
+		l.missing = append(l.missing, key)
+	}
+	return key
+}
+
+// texts returns the texts of keys, like text, which are keys themselves
+// if there is no localization.
+func (l *localization) texts(keys []string) []string {
+	if l == nil {
+		return keys
+	}
+	texts := make([]string, len(keys))
+	for i, key := range keys {
+		texts[i] = l.text(key)
+	}
+	return texts
+}
+
+// missingKeys returns a copy of the keys without text.
+func (l *localization) missingKeys() []string {
+	if l == nil {
+		return nil
+	}
+	return append([]string(nil), l.missing...)
+}
@@ -0,0 +1,197 @@
+package xlsx
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// MaxCellTextLength is the most characters of text a cell holds in
+// Excel, which truncates longer text, or reports the file as damaged.
+// Characters are counted in UTF-16 code units, as Excel does, the
+// characters outside the Basic Multilingual Plane, such as emoji,
+// counting twice.
+const MaxCellTextLength = 32767
+
+// TruncatedTextMarker is appended to the text truncated by
+// TruncateLongText, so that readers can tell it is missing its end.
+const TruncatedTextMarker = "[truncated]"
+
+// LongTextPolicy is the way a StreamFile writes the text longer than
+// MaxCellTextLength, see StreamFileBuilder.SetLongTextPolicy.  Every such
+// cell is reported, by StreamFile.LongTextCells and in the Summary,
+// whatever the policy.
+type LongTextPolicy int
+
+const (
+	// KeepLongText writes the text as it is, which is the default.
+	KeepLongText LongTextPolicy = iota
+	// TruncateLongText cuts the text to fit the cell, with
+	// TruncatedTextMarker at its end.
+	TruncateLongText
+	// NoteLongText cuts the text to fit the cell, and moves the rest of
+	// it to the note of the cell.
+	NoteLongText
+	// SplitLongText continues the text in the empty cells after it in
+	// the row, truncating it like TruncateLongText when they run out.
+	// The cells written one at a time, see StreamFile.BeginRow, are
+	// truncated, since the cells after them aren't known yet.
+	SplitLongText
+)
+
+// LongTextCell describes a cell whose text was longer than
+// MaxCellTextLength, of Length characters, and the policy it was
+// written with.  Cell is its reference, such as "C12".
+type LongTextCell struct {
+	Sheet  string
+	Cell   string
+	Length int
+	Policy LongTextPolicy
+}
+
+// SetLongTextPolicy sets the way the text longer than a cell holds, MaxCellTextLength, is written to a sheet, and to
+// the sheets continuing its columns, see SetSplitWideSheets. By default it is written as it is.
+func (sb *StreamFileBuilder) SetLongTextPolicy(sheetIndex int, policy LongTextPolicy) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if sheetIndex < 0 || sheetIndex >= len(sb.xlsxFile.Sheets) {
+		return fmt.Errorf("no sheet at index %d", sheetIndex)
+	}
+	if policy < KeepLongText || policy > SplitLongText {
+		return fmt.Errorf("invalid long text policy %d", policy)
+	}
+	for i := sheetIndex; i <= sheetIndex+sb.followOns[sheetIndex]; i++ {
+		sb.longTextPolicies[i] = policy
+	}
+	return nil
+}
+
+// LongTextCells returns the cells written so far whose text was longer
+// than MaxCellTextLength, in the order they were written.
+func (sf *StreamFile) LongTextCells() []LongTextCell {
+	return append([]LongTextCell(nil), sf.longTextCells...)
+}
+
+// cutText returns the start of text holding at most limit characters,
+// counted like MaxCellTextLength, and the rest of it.
+func cutText(text string, limit int) (string, string) {
+	length := 0
+	for i, r := range text {
+		length++
+		if r >= 0x10000 {
+			length++
+		}
+		if length > limit {
+			return text[:i], text[i:]
+		}
+	}
+	return text, ""
+}
+
+// isLongText returns true if text may be longer than MaxCellTextLength,
+// since text of fewer bytes can't be.
+func isLongText(text string) bool {
+	return len(text) > MaxCellTextLength && excelLength(text) > MaxCellTextLength
+}
+
+// fitLongText returns the text written to the cell at colIndex of ss,
+// in the row being written, for text longer than MaxCellTextLength,
+// reporting the cell.
+func (sf *StreamFile) fitLongText(ss *streamSheet, colIndex int, text string) string {
+	sf.addLongTextCell(ss, colIndex, text)
+	switch ss.longTextPolicy {
+	case TruncateLongText, SplitLongText:
+		return truncateText(text)
+	case NoteLongText:
+		text, rest := cutText(text, MaxCellTextLength)
+		ss.notes = append(ss.notes, streamNote{col: colIndex, row: ss.rowCount - 1, text: rest})
+		return text
+	}
+	return text
+}
+
+// truncateText returns the start of text followed by
+// TruncatedTextMarker, which fits in a cell.
+func truncateText(text string) string {
+	text, _ = cutText(text, MaxCellTextLength-utf8.RuneCountInString(TruncatedTextMarker))
+	return text + TruncatedTextMarker
+}
+
+// addLongTextCell reports the cell at colIndex of ss, in the row being
+// written, whose text is longer than MaxCellTextLength.
+func (sf *StreamFile) addLongTextCell(ss *streamSheet, colIndex int, text string) {
+	sf.longTextCells = append(sf.longTextCells, LongTextCell{
+		Sheet:  sf.xlsxFile.Sheets[ss.index-1].Name,
+		Cell:   GetCellIDStringFromCoords(colIndex, ss.rowCount-1),
+		Length: excelLength(text),
+		Policy: ss.longTextPolicy,
+	})
+}
+
+// splitLongText continues the text of the cells of the row being written
+// to the current sheet longer than MaxCellTextLength in the empty cells
+// after them, for the sheets whose long text is split, see SplitLongText.
+// cells, types and present are those given to writeRow, and are copied
+// rather than changed.
+func (sf *StreamFile) splitLongText(cells []string, types []CellType, present []bool) ([]string, []bool) {
+	if sf.currentSheet.longTextPolicy != SplitLongText {
+		return cells, present
+	}
+	copied := false
+	colIndex := 0
+	for i := range cells {
+		for sf.currentSheet.isFormulaColumn(colIndex) {
+			colIndex++
+		}
+		if !isText(types, i) || (present != nil && !present[i]) || !isLongText(cells[i]) {
+			colIndex++
+			continue
+		}
+		if !copied {
+			cells = append([]string(nil), cells...)
+			if present != nil {
+				present = append([]bool(nil), present...)
+			}
+			copied = true
+		}
+		ss, col := sf.currentSheet.column(colIndex)
+		sf.addLongTextCell(ss, col, cells[i])
+		// The text fills the cell, then the empty cells after it,
+		// its end being truncated in the last of them if they run
+		// out.
+		text := cells[i]
+		j := i
+		for {
+			var next int
+			for next = j + 1; next < len(cells); next++ {
+				empty := cells[next] == "" || (present != nil && !present[next])
+				if empty && isText(types, next) {
+					break
+				}
+			}
+			if next == len(cells) {
+				if isLongText(text) {
+					text = truncateText(text)
+				}
+				cells[j] = text
+				break
+			}
+			cells[j], text = cutText(text, MaxCellTextLength)
+			if text == "" {
+				break
+			}
+			if present != nil {
+				present[next] = true
+			}
+			j = next
+		}
+		colIndex++
+	}
+	return cells, present
+}
+
+// isText returns true if the cell at index i of a row of cells of the
+// given types, nil for strings, holds text.
+func isText(types []CellType, i int) bool {
+	return types == nil || types[i] == CellTypeString || types[i] == CellTypeInline
+}
@@ -0,0 +1,197 @@
+package xlsx
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// mergeRange is a range of cells merged by StreamFile.MergeCells, from
+// the column and row of its top left cell to those of its bottom right
+// one, starting at 0.
+type mergeRange struct {
+	startCol, startRow, endCol, endRow int
+}
+
+// ref returns the reference of the range, such as "A1:C1".
+func (mr mergeRange) ref() string {
+	return GetCellIDStringFromCoords(mr.startCol, mr.startRow) + cellRangeChar + GetCellIDStringFromCoords(mr.endCol, mr.endRow)
+}
+
+// overlaps returns true if the range shares cells with other.
+func (mr mergeRange) overlaps(other mergeRange) bool {
+	return mr.startCol <= other.endCol && other.startCol <= mr.endCol &&
+		mr.startRow <= other.endRow && other.startRow <= mr.endRow
+}
+
+// MergeCells merges the cells of the current sheet from the column and row startCol and startRow to endCol and endRow,
+// which start at 0, such as the cells of a section header spanning several columns, the value of the top left cell
+// being shown across them. The rows may be written before or after the cells are merged, since the merged ranges are
+// written along with the end of the sheet, after NextSheet or Close is called. A range must span at least two cells of
+// the columns of the sheet, and can't overlap another one.
+func (sf *StreamFile) MergeCells(startCol, startRow, endCol, endRow int) error {
+	if sf.err != nil {
+		return sf.err
+	}
+	if sf.currentSheet == nil {
+		return NoCurrentSheetError
+	}
+	ss := sf.currentSheet
+	if startCol < 0 || startRow < 0 || endCol < startCol || endRow < startRow {
+		return fmt.Errorf("invalid range of cells from column %d and row %d to column %d and row %d", startCol, startRow, endCol, endRow)
+	}
+	merge := mergeRange{startCol: startCol, startRow: startRow, endCol: endCol, endRow: endRow}
+	if endCol >= ss.columnCount || endRow > Excel2006MaxRowIndex {
+		return fmt.Errorf("the range %s is outside of sheet '%s'", merge.ref(), sf.xlsxFile.Sheets[ss.index-1].Name)
+	}
+	if startCol == endCol && startRow == endRow {
+		return fmt.Errorf("the range of %s is a single cell", GetCellIDStringFromCoords(startCol, startRow))
+	}
+	for _, other := range ss.merges {
+		if merge.overlaps(other) {
+			return fmt.Errorf("the range %s overlaps the range %s", merge.ref(), other.ref())
+		}
+	}
+	ss.merges = append(ss.merges, merge)
+	return nil
+}
+
+// mergeCellsCount matches the count of the mergeCells element of a sheet.
+var mergeCellsCount = regexp.MustCompile(`<mergeCells count="(\d+)"`)
+
+// insertStreamMergeCells returns the end of the XML of a sheet, suffix, with the mergeCells element listing merges,
+// or with merges added to the element it already has for the cells merged in its header row.
+func insertStreamMergeCells(suffix string, merges []mergeRange) string {
+	var out bytes.Buffer
+	for _, merge := range merges {
+		out.WriteString(`<mergeCell ref="` + merge.ref() + `"></mergeCell>`)
+	}
+	if match := mergeCellsCount.FindStringSubmatchIndex(suffix); match != nil {
+		count, _ := strconv.Atoi(suffix[match[2]:match[3]])
+		suffix = suffix[:match[2]] + strconv.Itoa(count+len(merges)) + suffix[match[3]:]
+		i := strings.Index(suffix, "</mergeCells>")
+		return suffix[:i] + out.String() + suffix[i:]
+	}
+	i := strings.Index(suffix, "<hyperlinks")
+	if i < 0 {
+		i = strings.Index(suffix, "<printOptions")
+	}
+	if i < 0 {
+		i = strings.LastIndex(suffix, "</worksheet>")
+	}
+	if i < 0 {
+		i = len(suffix)
+	}
+	return suffix[:i] + `<mergeCells count="` + strconv.Itoa(len(merges)) + `">` + out.String() + `</mergeCells>` + suffix[i:]
+}
+
+// WriteGroup writes a master-detail group to the current sheet: the parent row followed by its child rows, such as an
+// order followed by its lines, the cells of the parent in keyColumns, such as the order number and the customer, being
+// merged down across the rows of the group so that their values show once for it. The cells of the children in the
+// key columns are written empty, with the style of the cell of the parent, whatever they hold. The key columns are
+// columns of the sheet, starting at 0, which can't be formula columns, and the children must have as many cells as
+// the parent. A parent without children is written as a row of its own. The group is checked before its rows are
+// written, and starts on a new sheet if it doesn't fit in the rows left on a sheet whose rows roll over, see
+// StreamFileBuilder.SetRowLimitRollover.
+func (sf *StreamFile) WriteGroup(parent []StreamCell, children [][]StreamCell, keyColumns ...int) error {
+	if sf.err != nil {
+		return sf.err
+	}
+	if sf.currentSheet == nil {
+		return NoCurrentSheetError
+	}
+	if sf.currentSheet.rowOpen {
+		return RowInProgressError
+	}
+	for i, child := range children {
+		if len(child) != len(parent) {
+			return fmt.Errorf("the child row %d has %d cells, rather than the %d of its parent row", i, len(child), len(parent))
+		}
+	}
+	cellIndexes, err := sf.groupKeyCells(keyColumns, len(parent))
+	if err != nil {
+		return err
+	}
+	if err := sf.fitGroup(1 + len(children)); err != nil {
+		return err
+	}
+	ss := sf.currentSheet
+	var merges []mergeRange
+	if len(children) > 0 {
+		for _, col := range keyColumns {
+			merge := mergeRange{startCol: col, startRow: ss.rowCount, endCol: col, endRow: ss.rowCount + len(children)}
+			for _, other := range ss.merges {
+				if merge.overlaps(other) {
+					return fmt.Errorf("the range %s overlaps the range %s", merge.ref(), other.ref())
+				}
+			}
+			merges = append(merges, merge)
+		}
+	}
+	if err := sf.WriteTyped(parent); err != nil {
+		return err
+	}
+	for _, child := range children {
+		cells := append([]StreamCell(nil), child...)
+		for _, i := range cellIndexes {
+			cells[i] = StreamCell{Type: CellTypeString, StyleId: parent[i].StyleId}
+		}
+		if err := sf.WriteTyped(cells); err != nil {
+			return err
+		}
+	}
+	ss.merges = append(ss.merges, merges...)
+	return nil
+}
+
+// groupKeyCells returns the indexes, in a row of cells of the given
+// length written to the current sheet, of the cells of keyColumns, see
+// WriteGroup.
+func (sf *StreamFile) groupKeyCells(keyColumns []int, length int) ([]int, error) {
+	ss := sf.currentSheet
+	sheet := sf.xlsxFile.Sheets[ss.index-1]
+	indexes := make([]int, len(keyColumns))
+	seen := make(map[int]bool, len(keyColumns))
+	for k, col := range keyColumns {
+		if col < 0 || col >= ss.columnCount {
+			return nil, fmt.Errorf("the key column %d is outside of sheet '%s'", col, sheet.Name)
+		}
+		if ss.isFormulaColumn(col) {
+			return nil, fmt.Errorf("the key column %d of sheet '%s' is a formula column", col, sheet.Name)
+		}
+		if seen[col] {
+			return nil, fmt.Errorf("the key column %d is given twice", col)
+		}
+		seen[col] = true
+		indexes[k] = ss.dataIndex(col)
+		if indexes[k] >= length {
+			return nil, fmt.Errorf("the rows of the group have no cell in the key column %d", col)
+		}
+	}
+	return indexes, nil
+}
+
+// fitGroup makes the rows of the current sheet continue on a new sheet
+// if the given number of rows of a group doesn't fit in the rows left,
+// and they roll over, or returns an error if the group doesn't fit.
+func (sf *StreamFile) fitGroup(rows int) error {
+	ss := sf.currentSheet
+	if ss.rowCount+rows <= sf.maxRows {
+		return nil
+	}
+	sheet := sf.xlsxFile.Sheets[ss.index-1]
+	headerRows := len(sf.xlsxFile.Sheets[sf.settingsIndex(ss.index)-1].Rows)
+	if ss.rowCount == headerRows || headerRows+rows > sf.maxRows {
+		return fmt.Errorf("the group of %d rows doesn't fit in the sheet '%s'", rows, sheet.Name)
+	}
+	if err := sf.continueRows(); err != nil {
+		if _, ok := err.(*RowLimitError); ok {
+			return fmt.Errorf("the group of %d rows doesn't fit in the rows left in the sheet '%s'", rows, sheet.Name)
+		}
+		sf.err = err
+		return err
+	}
+	return nil
+}
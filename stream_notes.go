@@ -0,0 +1,230 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	relationshipTypeComments   = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/comments"
+	relationshipTypeVMLDrawing = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/vmlDrawing"
+	contentTypeComments        = "application/vnd.openxmlformats-officedocument.spreadsheetml.comments+xml"
+	contentTypeVMLDrawing      = "application/vnd.openxmlformats-officedocument.vmlDrawing"
+)
+
+// streamNote is the note of a cell written to a streamed sheet, shown
+// when the mouse hovers over the cell, see StreamCell.Note.
+type streamNote struct {
+	col, row int
+	text     string
+}
+
+// CurrencyConversion is the conversion of an amount from another
+// currency, recorded in the note of the cell holding the converted
+// amount, see NewConvertedStreamCell.
+type CurrencyConversion struct {
+	// Amount is the amount in its original currency.
+	Amount float64
+	// Currency is the original currency, such as "EUR".
+	Currency string
+	// Rate is the rate the amount was converted at, in units of the
+	// currency of the cell per unit of the original currency.
+	Rate float64
+}
+
+// Note returns the text of the note recording the conversion, such as
+// "Original amount: 1250.5 EUR" and "Conversion rate: 1.0842" on two
+// lines.
+func (c CurrencyConversion) Note() string {
+	return "Original amount: " + strconv.FormatFloat(c.Amount, 'f', -1, 64) + " " + c.Currency +
+		"\nConversion rate: " + strconv.FormatFloat(c.Rate, 'f', -1, 64)
+}
+
+// NewConvertedStreamCell returns a StreamCell holding amount, converted
+// from another currency as conversion says, whose note records the
+// original amount and currency and the rate, such as for audit exports.
+// The amount is written as it is, whatever rounding it went through,
+// and is best given a currency number format, such as one returned by
+// AccountingNumberFormat, with StreamCell.WithStyle.
+func NewConvertedStreamCell(amount float64, conversion CurrencyConversion) StreamCell {
+	return NewFloatStreamCell(amount).WithNote(conversion.Note())
+}
+
+// streamNotes returns the notes of the cells of a row written to a
+// StreamFile, or nil if none of the cells has a note.
+func streamNotes(cells []StreamCell) []string {
+	var notes []string
+	for i, cell := range cells {
+		if cell.Note == "" {
+			continue
+		}
+		if notes == nil {
+			notes = make([]string, len(cells))
+		}
+		notes[i] = cell.Note
+	}
+	return notes
+}
+
+// addNotes adds the notes of the cells of the row just written to the
+// current sheet of a StreamFile, given, the formula columns aside, by
+// notes.
+func (sf *StreamFile) addNotes(notes []string) {
+	dataIndex := 0
+	for colIndex := 0; colIndex < sf.currentSheet.totalColumnCount() && dataIndex < len(notes); colIndex++ {
+		if sf.currentSheet.isFormulaColumn(colIndex) {
+			continue
+		}
+		note := notes[dataIndex]
+		dataIndex++
+		if note == "" {
+			continue
+		}
+		ss, col := sf.currentSheet.column(colIndex)
+		if i := ss.rowNote(col); i >= 0 {
+			// The note holds the end of the long text of the cell,
+			// see NoteLongText, which follows the note written.
+			ss.notes[i].text = note + "\n\n" + ss.notes[i].text
+			continue
+		}
+		ss.notes = append(ss.notes, streamNote{col: col, row: ss.rowCount - 1, text: note})
+	}
+}
+
+// rowNote returns the index of the note of the cell at col in the row
+// being written to ss, or -1 if it has none.
+func (ss *streamSheet) rowNote(col int) int {
+	for i := len(ss.notes) - 1; i >= 0 && ss.notes[i].row == ss.rowCount-1; i-- {
+		if ss.notes[i].col == col {
+			return i
+		}
+	}
+	return -1
+}
+
+// streamNotesPaths returns the paths of the parts holding the notes of
+// the sheet at sheetIndex, starting at 1: the comments part listing
+// them and the VML drawing of the boxes Excel shows them in.
+func streamNotesPaths(sheetIndex int) (string, string) {
+	index := strconv.Itoa(sheetIndex)
+	return "xl/comments" + index + ".xml", "xl/drawings/vmlDrawing" + index + ".vml"
+}
+
+// insertStreamNotes adds the relationships of ss to the parts holding
+// its notes, and returns the end of the XML of the sheet, suffix, with
+// the legacyDrawing element referring to their drawing.
+func (sf *StreamFile) insertStreamNotes(ss *streamSheet, suffix string) (string, error) {
+	commentsPath, drawingPath := streamNotesPaths(ss.index)
+	for _, path := range []string{commentsPath, drawingPath} {
+		if sf.hasPart(path) {
+			return "", fmt.Errorf("the part %s, holding the notes of sheet '%s', was already added", path,
+				sf.xlsxFile.Sheets[ss.index-1].Name)
+		}
+	}
+	sf.addSheetRelationship(ss.index-1, relationshipTypeComments, "../"+strings.TrimPrefix(commentsPath, "xl/"), false)
+	drawingId := sf.addSheetRelationship(ss.index-1, relationshipTypeVMLDrawing, "../"+strings.TrimPrefix(drawingPath, "xl/"), false)
+	legacyDrawing := `<legacyDrawing xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" r:id="` +
+		drawingId + `"></legacyDrawing>`
+	// The legacyDrawing element follows the drawing, if any, and
+	// comes before the elements below.
+	i := strings.LastIndex(suffix, "</worksheet>")
+	for _, tag := range []string{"<legacyDrawingHF", "<picture", "<oleObjects", "<controls", "<webPublishItems", "<tableParts", "<extLst"} {
+		if j := strings.Index(suffix, tag); j >= 0 && (i < 0 || j < i) {
+			i = j
+		}
+	}
+	if i < 0 {
+		i = len(suffix)
+	}
+	return suffix[:i] + legacyDrawing + suffix[i:], nil
+}
+
+// writeStreamNotes writes the parts holding the notes of ss, once the
+// sheet is done.
+func (sf *StreamFile) writeStreamNotes(ss *streamSheet) error {
+	commentsPath, drawingPath := streamNotesPaths(ss.index)
+	if err := sf.writeRawPart(commentsPath, makeStreamComments(ss.notes)); err != nil {
+		return err
+	}
+	if err := sf.writeRawPart(drawingPath, makeStreamNotesDrawing(sf.noteShapeBlocks+1, ss.notes)); err != nil {
+		return err
+	}
+	sf.noteShapeBlocks += streamNotesBlocks(ss.notes)
+	sf.contentTypes.Overrides = append(sf.contentTypes.Overrides,
+		xlsxOverride{PartName: "/" + commentsPath, ContentType: contentTypeComments},
+		xlsxOverride{PartName: "/" + drawingPath, ContentType: contentTypeVMLDrawing})
+	return nil
+}
+
+// hasPart returns true if the part called name was written to the file
+// or added to it with AddPart.
+func (sf *StreamFile) hasPart(name string) bool {
+	if _, ok := sf.partSizes[name]; ok {
+		return true
+	}
+	for _, part := range sf.pendingParts {
+		if part.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// makeStreamComments returns the comments part listing notes, which
+// have no author.
+func makeStreamComments(notes []streamNote) []byte {
+	var out bytes.Buffer
+	out.WriteString(xml.Header)
+	out.WriteString(`<comments xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><authors><author></author></authors><commentList>`)
+	for _, note := range notes {
+		out.WriteString(`<comment ref="` + GetCellIDStringFromCoords(note.col, note.row) + `" authorId="0"><text><t xml:space="preserve">`)
+		writeEscapedText(&out, escapeXString(note.text))
+		out.WriteString(`</t></text></comment>`)
+	}
+	out.WriteString(`</commentList></comments>`)
+	return out.Bytes()
+}
+
+// streamNotesBlocks returns the number of blocks of 1024 shape ids
+// taken by the drawing of notes, the first id of a block being left
+// out.
+func streamNotesBlocks(notes []streamNote) int {
+	return len(notes)/1024 + 1
+}
+
+// makeStreamNotesDrawing returns the VML drawing of the hidden boxes in
+// which Excel shows notes when the mouse hovers over their cells.  The
+// ids of the shapes of the drawings of a file must differ, each drawing
+// numbering its shapes in blocks of 1024 ids of its own, starting with
+// the block at firstBlock.
+func makeStreamNotesDrawing(firstBlock int, notes []streamNote) []byte {
+	var out bytes.Buffer
+	out.WriteString(`<xml xmlns:v="urn:schemas-microsoft-com:vml" xmlns:o="urn:schemas-microsoft-com:office:office" xmlns:x="urn:schemas-microsoft-com:office:excel">`)
+	blocks := make([]string, streamNotesBlocks(notes))
+	for i := range blocks {
+		blocks[i] = strconv.Itoa(firstBlock + i)
+	}
+	out.WriteString(`<o:shapelayout v:ext="edit"><o:idmap v:ext="edit" data="` + strings.Join(blocks, ",") + `"/></o:shapelayout>`)
+	out.WriteString(`<v:shapetype id="_x0000_t202" coordsize="21600,21600" o:spt="202" path="m,l,21600r21600,l21600,xe">` +
+		`<v:stroke joinstyle="miter"/><v:path gradientshapeok="t" o:connecttype="rect"/></v:shapetype>`)
+	for i, note := range notes {
+		shapeId := firstBlock*1024 + i + 1
+		// The box spans two columns and four rows, next to the
+		// cell, which the anchor gives as the column and offset
+		// of its left side, the row and offset of its top, and so
+		// on.
+		anchor := fmt.Sprintf("%d, 15, %d, 2, %d, 15, %d, 16", note.col+1, note.row, note.col+3, note.row+4)
+		out.WriteString(`<v:shape id="_x0000_s` + strconv.Itoa(shapeId) + `" type="#_x0000_t202"` +
+			` style="position:absolute;margin-left:59.25pt;margin-top:1.5pt;width:108pt;height:59.25pt;z-index:` + strconv.Itoa(i+1) + `;visibility:hidden"` +
+			` fillcolor="#ffffe1" o:insetmode="auto"><v:fill color2="#ffffe1"/><v:shadow on="t" color="black" obscured="t"/>` +
+			`<v:path o:connecttype="none"/><v:textbox style="mso-direction-alt:auto"><div style="text-align:left"></div></v:textbox>` +
+			`<x:ClientData ObjectType="Note"><x:MoveWithCells/><x:SizeWithCells/><x:Anchor>` + anchor + `</x:Anchor>` +
+			`<x:AutoFill>False</x:AutoFill><x:Row>` + strconv.Itoa(note.row) + `</x:Row><x:Column>` + strconv.Itoa(note.col) +
+			`</x:Column></x:ClientData></v:shape>`)
+	}
+	out.WriteString(`</xml>`)
+	return []byte(out.String())
+}
@@ -0,0 +1,70 @@
+package xlsx
+
+// SetNumberFormatsPerColumn sets whether the number formats of the typed columns of the sheets, see AddSheetWithTypes,
+// are given by the styles of the columns, rather than by those of their cells only. By default the date cells are
+// given the date format of the file by a style of their own, which leaves out the style of their column, see
+// SetColumnStyle, and the col elements of the sheets keep their default styles. Per column, each date column is
+// given the style of the column with the date format, the default style of the column in its col element as well as
+// that of its date cells, and the other columns given a style are given it in their col element, so that the cells
+// of the columns not written, such as those left empty or typed in later in Excel, are formatted like the others. The
+// headers keep their style. Since Excel gives the style of a column only to its cells missing from the file, the
+// cells written keep their s attribute either way.
+func (sb *StreamFileBuilder) SetNumberFormatsPerColumn(perColumn bool) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	sb.numberFormatsPerColumn = perColumn
+	return nil
+}
+
+// applyNumberFormatsPerColumn gives the columns of the sheets their styles and number formats, if they are set per
+// column, see SetNumberFormatsPerColumn, before the sheets are marshalled.
+func (sb *StreamFileBuilder) applyNumberFormatsPerColumn() {
+	if !sb.numberFormatsPerColumn {
+		return
+	}
+	sb.dateColumns = make([][]bool, len(sb.xlsxFile.Sheets))
+	for sheetIndex, sheet := range sb.xlsxFile.Sheets {
+		for colIndex, col := range sheet.Cols {
+			var style *Style
+			if colIndex < len(sb.columnStyles[sheetIndex]) {
+				style = sb.columnStyles[sheetIndex][colIndex]
+			}
+			isDate := colIndex < len(sb.columnTypes[sheetIndex]) && sb.columnTypes[sheetIndex][colIndex] == ColumnTypeDate &&
+				(col.numFmt == "" || col.numFmt == builtInNumFmt[builtInNumFmtIndex_GENERAL])
+			if style == nil && !isDate {
+				continue
+			}
+			if len(sheet.Rows) > 0 && colIndex < len(sheet.Rows[0].Cells) {
+				// The header keeps the style the column had.
+				if header := sheet.Rows[0].Cells[colIndex]; header.style == nil {
+					header.SetStyle(col.GetStyle())
+				}
+			}
+			if style == nil {
+				style = col.GetStyle()
+				if style == nil {
+					style = NewStyle()
+				}
+				for len(sb.columnStyles[sheetIndex]) <= colIndex {
+					sb.columnStyles[sheetIndex] = append(sb.columnStyles[sheetIndex], nil)
+				}
+				sb.columnStyles[sheetIndex][colIndex] = style
+			}
+			col.SetStyle(style)
+			if isDate {
+				col.numFmt = DefaultDateFormat
+				if sb.dateColumns[sheetIndex] == nil {
+					sb.dateColumns[sheetIndex] = make([]bool, len(sheet.Cols))
+				}
+				sb.dateColumns[sheetIndex][colIndex] = true
+			}
+		}
+	}
+}
+
+// isDateColumn returns true if the date cells of the given column of the sheet are given the style of the column,
+// which has the date format, see StreamFileBuilder.SetNumberFormatsPerColumn.
+func (ss *streamSheet) isDateColumn(colIndex int) bool {
+	return colIndex < len(ss.dateColumns) && ss.dateColumns[colIndex]
+}
@@ -0,0 +1,33 @@
+package xlsx
+
+// ProgressHook is called every so many rows written to a StreamFile, see
+// StreamFileBuilder.SetProgressHook, with the index of the sheet being
+// written, starting at 0, the number of rows written to it, header
+// included, and the number of bytes written to the writer of the file
+// so far, once compressed, so that the progress of long exports can be
+// shown to their users.  It is called once more when the file is
+// closed, for its last sheet, with the size of the whole file.  It is
+// called from the goroutines writing the sheets spooled by
+// StreamFile.SpoolSheets, so it must then be safe for concurrent use.
+type ProgressHook func(sheetIndex, rowsWritten int, bytesWritten int64)
+
+// countProgress counts a row written to the current sheet, calling the
+// ProgressHook, if any, once enough rows were written since it was
+// last called.
+func (sf *StreamFile) countProgress() {
+	if sf.progressHook == nil {
+		return
+	}
+	sf.progressRows++
+	if sf.progressRows < sf.progressInterval {
+		return
+	}
+	sf.progressRows = 0
+	sf.reportProgress(sf.currentSheet.index - 1)
+}
+
+// reportProgress calls the ProgressHook with the rows written to the
+// sheet at sheetIndex, starting at 0, and the bytes written to the file.
+func (sf *StreamFile) reportProgress(sheetIndex int) {
+	sf.progressHook(sheetIndex, sf.rowCounts[sheetIndex], sf.output.bytesWritten())
+}
@@ -0,0 +1,162 @@
+package xlsx
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	customPropertiesPart             = "docProps/custom.xml"
+	relationshipTypeCustomProperties = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/custom-properties"
+	contentTypeCustomProperties      = "application/vnd.openxmlformats-officedocument.custom-properties+xml"
+	// customPropertyFormatId is the format id shared by the custom
+	// properties of Office documents.
+	customPropertyFormatId = "{D5CDD505-2E9C-101B-9397-08002B2CF9AE}"
+)
+
+// DocumentProperties are the properties of a streamed workbook shown by Excel in File > Info, see
+// StreamFileBuilder.SetDocumentProperties. The properties left empty aren't written.
+type DocumentProperties struct {
+	Title          string
+	Subject        string
+	Author         string
+	Description    string
+	Keywords       string
+	Category       string
+	LastModifiedBy string
+	// Company and Manager are extended properties, written to
+	// docProps/app.xml, the others being core properties.
+	Company  string
+	Manager  string
+	Created  time.Time
+	Modified time.Time
+}
+
+// customProperty is a custom property of a streamed workbook, see
+// StreamFileBuilder.SetCustomProperty.
+type customProperty struct {
+	name string
+	// value is the element of the value of the property, such as
+	// <vt:lpwstr>Internal</vt:lpwstr>.
+	value string
+}
+
+// SetDocumentProperties sets the properties of the workbook, such as its title, author and company, written to
+// docProps/core.xml and docProps/app.xml by Build.
+func (sb *StreamFileBuilder) SetDocumentProperties(properties DocumentProperties) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	sb.documentProperties = &properties
+	return nil
+}
+
+// SetCustomProperty sets the custom property of the workbook called name, such as a classification for compliance
+// tagging, written to docProps/custom.xml by Build and shown by Excel in File > Info > Properties. The value is a
+// string, a bool, an int, which must fit in 32 bits, a float64 or a time.Time. Setting a property again replaces its
+// value.
+func (sb *StreamFileBuilder) SetCustomProperty(name string, value interface{}) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if name == "" {
+		return fmt.Errorf("custom properties must have a name")
+	}
+	var element string
+	switch v := value.(type) {
+	case string:
+		element = `<vt:lpwstr>` + escapeAttr(v) + `</vt:lpwstr>`
+	case bool:
+		element = `<vt:bool>` + strconv.FormatBool(v) + `</vt:bool>`
+	case int:
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			return fmt.Errorf("the value %d of custom property '%s' doesn't fit in 32 bits", v, name)
+		}
+		element = `<vt:i4>` + strconv.Itoa(v) + `</vt:i4>`
+	case float64:
+		element = `<vt:r8>` + strconv.FormatFloat(v, 'g', -1, 64) + `</vt:r8>`
+	case time.Time:
+		element = `<vt:filetime>` + v.UTC().Format(time.RFC3339) + `</vt:filetime>`
+	default:
+		return fmt.Errorf("unsupported type %T of custom property '%s'", value, name)
+	}
+	for i, property := range sb.customProperties {
+		if property.name == name {
+			sb.customProperties[i].value = element
+			return nil
+		}
+	}
+	sb.customProperties = append(sb.customProperties, customProperty{name: name, value: element})
+	return nil
+}
+
+// addDocumentProperties replaces the document properties among parts
+// with those set on the builder, and adds the custom properties.
+func (sb *StreamFileBuilder) addDocumentProperties(parts map[string]string) {
+	if p := sb.documentProperties; p != nil {
+		parts["docProps/core.xml"] = makeCoreProperties(p)
+		parts["docProps/app.xml"] = makeAppProperties(p)
+	}
+	if len(sb.customProperties) == 0 {
+		return
+	}
+	var out bytes.Buffer
+	out.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	out.WriteString(`<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/custom-properties"` +
+		` xmlns:vt="http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes">`)
+	for i, property := range sb.customProperties {
+		// The ids of the properties start at 2.
+		fmt.Fprintf(&out, `<property fmtid="%s" pid="%d" name="%s">%s</property>`, customPropertyFormatId, i+2,
+			escapeAttr(property.name), property.value)
+	}
+	out.WriteString(`</Properties>`)
+	parts[customPropertiesPart] = out.String()
+	parts["_rels/.rels"] = strings.Replace(parts["_rels/.rels"], "</Relationships>",
+		`  <Relationship Id="rId4" Type="`+relationshipTypeCustomProperties+`" Target="`+customPropertiesPart+`"/>`+"\n</Relationships>", 1)
+}
+
+// makeCoreProperties returns the part holding the core properties of
+// a streamed workbook, docProps/core.xml.
+func makeCoreProperties(p *DocumentProperties) string {
+	var out bytes.Buffer
+	out.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	out.WriteString(`<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties"` +
+		` xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcmitype="http://purl.org/dc/dcmitype/"` +
+		` xmlns:dcterms="http://purl.org/dc/terms/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">`)
+	for _, property := range []struct{ element, value string }{
+		{"dc:title", p.Title}, {"dc:subject", p.Subject}, {"dc:creator", p.Author}, {"cp:keywords", p.Keywords},
+		{"dc:description", p.Description}, {"cp:lastModifiedBy", p.LastModifiedBy}, {"cp:category", p.Category},
+	} {
+		if property.value != "" {
+			out.WriteString(`<` + property.element + `>` + escapeAttr(property.value) + `</` + property.element + `>`)
+		}
+	}
+	for _, property := range []struct {
+		element string
+		value   time.Time
+	}{{"dcterms:created", p.Created}, {"dcterms:modified", p.Modified}} {
+		if !property.value.IsZero() {
+			out.WriteString(`<` + property.element + ` xsi:type="dcterms:W3CDTF">` + property.value.UTC().Format(time.RFC3339) +
+				`</` + property.element + `>`)
+		}
+	}
+	out.WriteString(`</cp:coreProperties>`)
+	return out.String()
+}
+
+// makeAppProperties returns the part holding the extended properties
+// of a streamed workbook, docProps/app.xml.
+func makeAppProperties(p *DocumentProperties) string {
+	var extra bytes.Buffer
+	if p.Manager != "" {
+		extra.WriteString(`  <Manager>` + escapeAttr(p.Manager) + `</Manager>` + "\n")
+	}
+	if p.Company != "" {
+		extra.WriteString(`  <Company>` + escapeAttr(p.Company) + `</Company>` + "\n")
+	}
+	return strings.Replace(TEMPLATE_DOCPROPS_APP, "</Properties>", extra.String()+"</Properties>", 1)
+}
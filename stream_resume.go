@@ -0,0 +1,239 @@
+package xlsx
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// SpoolCheckpoint is the state of a SpooledSheet once some of its rows
+// are in its spool, returned by SpooledSheet.Checkpoint, from which a
+// restarted export job can resume writing the sheet, see
+// StreamFile.ResumeSpooledSheets.  Its fields are exported so that it
+// can be persisted, as JSON for example, along with the spool.
+type SpoolCheckpoint struct {
+	// SheetIndex is the index of the sheet, from 1.
+	SheetIndex int
+	// Rows is the number of rows of the sheet, header rows included,
+	// and so the offset of the row the writing resumes at.
+	Rows int
+	// Offset is the size of the XML of the sheet in its spool.
+	Offset int64
+	// SpoolPath is the name of the temporary file used as spool, or ""
+	// if the spool was returned by the newSpool of SpoolSheets.
+	SpoolPath string
+	// Widths are the widths measured so far of the columns of a sheet
+	// sized to its contents, whose start is written once the sheet is
+	// closed, or nil.
+	Widths []int
+	// SharedStrings is the table of the strings shared by the cells of
+	// the file, serialized by RefTable.MarshalBinary, or nil if the
+	// strings are written inline.
+	SharedStrings []byte
+}
+
+// Checkpoint flushes the rows written to the sheet so far to its spool,
+// and returns the state of the sheet to resume it at the next row if
+// the export job is restarted, see StreamFile.ResumeSpooledSheets.  The
+// spool is synced to the disk if it is a file.  The hyperlinks, merged
+// cells and notes of a sheet, which are written at its end, aren't
+// kept, so a sheet holding any can't be checkpointed.
+func (s *SpooledSheet) Checkpoint() (SpoolCheckpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return SpoolCheckpoint{}, ClosedSpooledSheetError
+	}
+	file := s.file
+	if file.err != nil {
+		return SpoolCheckpoint{}, file.err
+	}
+	ss := file.currentSheet
+	if ss.rowOpen {
+		return SpoolCheckpoint{}, RowInProgressError
+	}
+	if len(ss.hyperlinks) > 0 || len(ss.merges) > 0 || len(ss.notes) > 0 {
+		return SpoolCheckpoint{}, fmt.Errorf("spooled sheet '%s' holds hyperlinks, merged cells or notes, which can't be checkpointed", s.name)
+	}
+	if err := s.buffer.Flush(); err != nil {
+		file.err = err
+		return SpoolCheckpoint{}, err
+	}
+	if syncer, ok := s.spool.(interface{ Sync() error }); ok {
+		if err := syncer.Sync(); err != nil {
+			return SpoolCheckpoint{}, err
+		}
+	}
+	offset, err := s.spool.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return SpoolCheckpoint{}, err
+	}
+	checkpoint := SpoolCheckpoint{
+		SheetIndex: ss.index,
+		Rows:       ss.rowCount,
+		Offset:     offset,
+		Widths:     append([]int(nil), ss.widths...),
+	}
+	if s.temp != nil {
+		checkpoint.SpoolPath = s.temp.Name()
+	}
+	if file.sharedStrings != nil {
+		file.sharedStringsLock.Lock()
+		checkpoint.SharedStrings, err = file.sharedStrings.MarshalBinary()
+		file.sharedStringsLock.Unlock()
+		if err != nil {
+			return SpoolCheckpoint{}, err
+		}
+	}
+	return checkpoint, nil
+}
+
+// ResumeSpooledSheets spools the sheets after the current one like
+// SpoolSheets, but resumes the sheets of the checkpoints, taken by
+// SpooledSheet.Checkpoint before the export job was restarted: their
+// spools, the temporary files of the checkpoints or else those returned
+// by newSpool, are cut back to the rows of the checkpoints, which the
+// sheets keep writing after.  The StreamFile must be built the same way
+// as the one of the checkpoints, and ResumeSpooledSheets called before
+// any string is written, so that the shared strings are restored with
+// the indices the rows in the spools refer to.  The duplicate rows,
+// type warnings and long text cells found before the checkpoints aren't
+// restored.
+//
+// Spools longer than their checkpoint must have a Truncate method, as
+// files do.  If an error is returned, the temporary files of the
+// checkpoints are kept, to be resumed again.
+func (sf *StreamFile) ResumeSpooledSheets(newSpool func(sheetIndex int) (io.ReadWriteSeeker, error), checkpoints []SpoolCheckpoint) ([]*SpooledSheet, error) {
+	if err := sf.checkSpoolable(); err != nil {
+		return nil, err
+	}
+	bySheet := make(map[int]SpoolCheckpoint, len(checkpoints))
+	var tables [][]byte
+	for _, checkpoint := range checkpoints {
+		sheetIndex := checkpoint.SheetIndex
+		if sheetIndex <= sf.currentSheet.lastIndex() || sheetIndex > len(sf.xlsxFile.Sheets) {
+			return nil, fmt.Errorf("no spooled sheet %d to resume", sheetIndex)
+		}
+		name := sf.xlsxFile.Sheets[sheetIndex-1].Name
+		if _, ok := bySheet[sheetIndex]; ok {
+			return nil, fmt.Errorf("spooled sheet '%s' has more than one checkpoint", name)
+		}
+		if newSpool == nil && checkpoint.SpoolPath == "" {
+			return nil, fmt.Errorf("the checkpoint of spooled sheet '%s' has no spool", name)
+		}
+		if (checkpoint.SharedStrings != nil) != (sf.sharedStrings != nil) {
+			return nil, fmt.Errorf("the checkpoint of spooled sheet '%s' doesn't match the shared strings of the file", name)
+		}
+		bySheet[sheetIndex] = checkpoint
+		if checkpoint.SharedStrings != nil {
+			tables = append(tables, checkpoint.SharedStrings)
+		}
+	}
+	if err := sf.restoreSharedStrings(tables); err != nil {
+		return nil, err
+	}
+	return sf.spoolSheets(newSpool, bySheet)
+}
+
+// restoreSharedStrings makes the shared strings of sf the longest of
+// the serialized tables, which must all, along with the strings of sf,
+// be the start of it, as they are if they were taken from the same
+// export job at different times.
+func (sf *StreamFile) restoreSharedStrings(tables [][]byte) error {
+	var longest *RefTable
+	restored := make([]*RefTable, len(tables))
+	for i, data := range tables {
+		restored[i] = NewSharedStringRefTable()
+		if err := restored[i].UnmarshalBinary(data); err != nil {
+			return fmt.Errorf("invalid shared strings in a checkpoint: %v", err)
+		}
+		if longest == nil || restored[i].Length() > longest.Length() {
+			longest = restored[i]
+		}
+	}
+	if longest == nil {
+		return nil
+	}
+	for _, table := range append(restored, sf.sharedStrings) {
+		if !isStringsPrefix(table.indexedStrings, longest.indexedStrings) {
+			return fmt.Errorf("the shared strings of the checkpoints don't match those of the file")
+		}
+	}
+	longest.makeWritable()
+	*sf.sharedStrings = *longest
+	return nil
+}
+
+// isStringsPrefix returns true if prefix is the start of strs.
+func isStringsPrefix(prefix, strs []string) bool {
+	if len(prefix) > len(strs) {
+		return false
+	}
+	for i, str := range prefix {
+		if strs[i] != str {
+			return false
+		}
+	}
+	return true
+}
+
+// resumeSpooledSheet returns the spooled sheet resuming the writing of
+// the sheet of the checkpoint after its rows.
+func (sf *StreamFile) resumeSpooledSheet(checkpoint SpoolCheckpoint, newSpool func(sheetIndex int) (io.ReadWriteSeeker, error)) (*SpooledSheet, error) {
+	sheetIndex := checkpoint.SheetIndex
+	sheet := &SpooledSheet{name: sf.xlsxFile.Sheets[sheetIndex-1].Name, resumed: true}
+	if checkpoint.SpoolPath != "" && newSpool == nil {
+		temp, err := os.OpenFile(checkpoint.SpoolPath, os.O_RDWR, 0)
+		if err != nil {
+			return nil, err
+		}
+		sheet.spool, sheet.temp = temp, temp
+	} else {
+		spool, err := newSpool(sheetIndex)
+		if err != nil {
+			return nil, err
+		}
+		sheet.spool = spool
+	}
+	sf.startSpooledSheet(sheet, sheetIndex)
+	if err := sheet.resume(checkpoint); err != nil {
+		sheet.discard()
+		return nil, fmt.Errorf("spooled sheet '%s' can't be resumed: %v", sheet.name, err)
+	}
+	return sheet, nil
+}
+
+// resume cuts the spool of the sheet back to the rows of the
+// checkpoint, and restores the state of the sheet.
+func (s *SpooledSheet) resume(checkpoint SpoolCheckpoint) error {
+	ss := s.file.currentSheet
+	if checkpoint.Rows < ss.rowCount || checkpoint.Offset < 0 {
+		return fmt.Errorf("invalid checkpoint of %d rows at offset %d", checkpoint.Rows, checkpoint.Offset)
+	}
+	if len(checkpoint.Widths) != len(ss.widths) || (checkpoint.Widths == nil) != (ss.widths == nil) {
+		return fmt.Errorf("the checkpoint doesn't match the columns of the sheet")
+	}
+	size, err := s.spool.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	switch {
+	case size < checkpoint.Offset:
+		return fmt.Errorf("the spool holds %d bytes, fewer than the %d of the checkpoint", size, checkpoint.Offset)
+	case size > checkpoint.Offset:
+		truncater, ok := s.spool.(interface{ Truncate(size int64) error })
+		if !ok {
+			return fmt.Errorf("the spool holds %d bytes, more than the %d of the checkpoint, and can't be truncated", size, checkpoint.Offset)
+		}
+		if err := truncater.Truncate(checkpoint.Offset); err != nil {
+			return err
+		}
+	}
+	if _, err := s.spool.Seek(checkpoint.Offset, io.SeekStart); err != nil {
+		return err
+	}
+	copy(ss.widths, checkpoint.Widths)
+	ss.rowCount = checkpoint.Rows
+	s.file.rowCounts[ss.index-1] = checkpoint.Rows
+	return nil
+}
@@ -0,0 +1,189 @@
+package xlsx
+
+import (
+	"errors"
+	"io"
+)
+
+// RolloverStreamFile writes rows like a StreamFile, across as many
+// files as needed to keep each of them about the size given to
+// NewRolloverStreamFile, such as for mail attachments.  Every file has
+// the sheets, headers and styles set up for it by the setup function,
+// the rows being written to the same sheet as they would have been in a
+// single file, the sheets before it being left with their headers.
+type RolloverStreamFile struct {
+	maxBytes int64
+	setup    func(sb *StreamFileBuilder) error
+	next     func(index int) (io.Writer, error)
+	// current is the file being written, and output the writer of
+	// its bytes, or nil once it was closed for reaching maxBytes, the
+	// next file being started by the next row.  sheetIndex is the
+	// index, starting at 1, of the sheet the rows are written to.
+	current    *StreamFile
+	output     *rolloverWriter
+	sheetIndex int
+	files      int
+	err        error
+}
+
+// ClosedRolloverFileError is returned by the writes to a
+// RolloverStreamFile once it is closed.
+var ClosedRolloverFileError = errors.New("the rollover file is closed")
+
+// rolloverWriter counts the bytes written to the writer of a file of a
+// RolloverStreamFile.
+type rolloverWriter struct {
+	writer  io.Writer
+	written int64
+}
+
+func (rw *rolloverWriter) Write(p []byte) (int, error) {
+	n, err := rw.writer.Write(p)
+	rw.written += int64(n)
+	return n, err
+}
+
+// NewRolloverStreamFile returns a RolloverStreamFile starting a new file
+// once the current one reaches maxBytes, compressed.  next returns the
+// writer of each file, whose index starts at 0, which is closed once the
+// file is done if it is an io.Closer, and setup adds the sheets of each
+// file and sets up their builder, which it mustn't build.  Since the
+// size is checked after each row, and the end of a file is written once
+// it is done, the files end up a little larger than maxBytes, which
+// should be set with room to spare.
+func NewRolloverStreamFile(maxBytes int64, setup func(sb *StreamFileBuilder) error, next func(index int) (io.Writer, error)) (*RolloverStreamFile, error) {
+	if maxBytes <= 0 {
+		return nil, errors.New("the size of the files must be positive")
+	}
+	if setup == nil || next == nil {
+		return nil, errors.New("a rollover file needs setup and next functions")
+	}
+	rf := &RolloverStreamFile{maxBytes: maxBytes, setup: setup, next: next, sheetIndex: 1}
+	if err := rf.start(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// start starts the next file, with its rows written to the current
+// sheet.
+func (rf *RolloverStreamFile) start() error {
+	writer, err := rf.next(rf.files)
+	if err != nil {
+		return err
+	}
+	output := &rolloverWriter{writer: writer}
+	builder := NewStreamFileBuilder(output)
+	if err := rf.setup(builder); err != nil {
+		return err
+	}
+	current, err := builder.Build()
+	if err != nil {
+		return err
+	}
+	for current.currentSheet.index < rf.sheetIndex {
+		if err := current.NextSheet(); err != nil {
+			return err
+		}
+	}
+	rf.current, rf.output = current, output
+	rf.files++
+	return nil
+}
+
+// finish closes the current file, and its writer if it is an io.Closer.
+func (rf *RolloverStreamFile) finish() error {
+	err := rf.current.Close()
+	if closer, ok := rf.output.writer.(io.Closer); ok {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	rf.output = nil
+	return err
+}
+
+// write writes a row with write to the current file, started if need
+// be, which is finished once it reaches the size of the files.
+func (rf *RolloverStreamFile) write(write func(sf *StreamFile) error) error {
+	if rf.err != nil {
+		return rf.err
+	}
+	if rf.output == nil {
+		if err := rf.start(); err != nil {
+			rf.err = err
+			return err
+		}
+	}
+	if err := write(rf.current); err != nil {
+		return err
+	}
+	if rf.output.written >= rf.maxBytes {
+		if err := rf.finish(); err != nil {
+			rf.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// Write writes a row to the current sheet like StreamFile.Write.
+func (rf *RolloverStreamFile) Write(cells []string) error {
+	return rf.write(func(sf *StreamFile) error {
+		return sf.Write(cells)
+	})
+}
+
+// WriteTyped writes a row of typed cells to the current sheet like
+// StreamFile.WriteTyped.
+func (rf *RolloverStreamFile) WriteTyped(cells []StreamCell) error {
+	return rf.write(func(sf *StreamFile) error {
+		return sf.WriteTyped(cells)
+	})
+}
+
+// NextSheet moves on to the next sheet like StreamFile.NextSheet, in
+// the current file and in the files after it.
+func (rf *RolloverStreamFile) NextSheet() error {
+	if rf.err != nil {
+		return rf.err
+	}
+	if rf.output != nil {
+		if err := rf.current.NextSheet(); err != nil {
+			return err
+		}
+		rf.sheetIndex = rf.current.currentSheet.index
+		return nil
+	}
+	// The file is done, its sheets telling which is the next one.
+	last := rf.sheetIndex
+	if rf.sheetIndex-1 < len(rf.current.followOns) {
+		last += rf.current.followOns[rf.sheetIndex-1]
+	}
+	if last >= len(rf.current.xlsxFile.Sheets) {
+		return AlreadyOnLastSheetError
+	}
+	rf.sheetIndex = last + 1
+	return nil
+}
+
+// Files returns the number of files started so far.
+func (rf *RolloverStreamFile) Files() int {
+	return rf.files
+}
+
+// Close finishes the current file, unless it was finished on reaching
+// the size of the files.  Nothing can be written afterwards.
+func (rf *RolloverStreamFile) Close() error {
+	if rf.err != nil {
+		return rf.err
+	}
+	if rf.output != nil {
+		if err := rf.finish(); err != nil {
+			rf.err = err
+			return err
+		}
+	}
+	rf.err = ClosedRolloverFileError
+	return nil
+}
@@ -0,0 +1,137 @@
+package xlsx
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// rowBufferPool holds the buffers in which the XML of the rows of the
+// streamed sheets is built, each row being written to its sheet at
+// once, shared by the sheets and files written one after the other.
+var rowBufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// maxPooledRowBuffer is the size of the largest buffer put back in
+// rowBufferPool, so that the buffer of a huge row isn't kept.
+const maxPooledRowBuffer = 1 << 16
+
+// rowBuffer returns the buffer of the row being written to ss.
+func (ss *streamSheet) rowBuffer() *bytes.Buffer {
+	if ss.row == nil {
+		ss.row = rowBufferPool.Get().(*bytes.Buffer)
+		ss.row.Reset()
+	}
+	return ss.row
+}
+
+// writeRowBuffer writes the row built in the buffer of ss to the sheet,
+// and returns its size.
+func (ss *streamSheet) writeRowBuffer() (int, error) {
+	row := ss.rowBuffer()
+	size := row.Len()
+	_, err := ss.writer.Write(row.Bytes())
+	row.Reset()
+	return size, err
+}
+
+// releaseRowBuffer puts the buffer of ss back in the pool once the sheet
+// is done.
+func (ss *streamSheet) releaseRowBuffer() {
+	if ss.row == nil {
+		return
+	}
+	if ss.row.Cap() <= maxPooledRowBuffer {
+		rowBufferPool.Put(ss.row)
+	}
+	ss.row = nil
+}
+
+// writeInt writes n in decimal to buffer.
+func writeInt(buffer *bytes.Buffer, n int) {
+	var digits [20]byte
+	buffer.Write(strconv.AppendInt(digits[:0], int64(n), 10))
+}
+
+// EscapeXML returns s escaped like xml.EscapeText does, as the cells of
+// streamed sheets are: the five XML entities, tabs and line breaks are
+// escaped, and the characters XML can't carry, such as control
+// characters and UTF-16 surrogates, and invalid UTF-8 are replaced by
+// the replacement character U+FFFD.  Text that needs no escaping, the
+// most common, is returned as it is, without allocating.
+func EscapeXML(s string) string {
+	i := escapedTextStart(s)
+	if i == len(s) {
+		return s
+	}
+	var buffer bytes.Buffer
+	buffer.Grow(len(s) + len(s)/8)
+	buffer.WriteString(s[:i])
+	writeEscapedText(&buffer, s[i:])
+	return buffer.String()
+}
+
+// escapedTextStart returns the index of the first byte of s that
+// writeEscapedText changes, or len(s) if it writes s as it is.
+func escapedTextStart(s string) int {
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b >= 0x20 && b < utf8.RuneSelf && b != '"' && b != '\'' && b != '&' && b != '<' && b != '>' {
+			i++
+			continue
+		}
+		if b < utf8.RuneSelf {
+			return i
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if !isXMLChar(r) || (r == utf8.RuneError && size == 1) {
+			return i
+		}
+		i += size
+	}
+	return len(s)
+}
+
+// writeEscapedText writes s to buffer escaped like xml.EscapeText, the
+// characters XML can't carry and invalid UTF-8 being replaced by the
+// replacement character U+FFFD.
+func writeEscapedText(buffer *bytes.Buffer, s string) {
+	last := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b >= 0x20 && b < utf8.RuneSelf && b != '"' && b != '\'' && b != '&' && b != '<' && b != '>' {
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		var escaped string
+		switch r {
+		case '"':
+			escaped = "&#34;"
+		case '\'':
+			escaped = "&#39;"
+		case '&':
+			escaped = "&amp;"
+		case '<':
+			escaped = "&lt;"
+		case '>':
+			escaped = "&gt;"
+		case '\t':
+			escaped = "&#x9;"
+		case '\n':
+			escaped = "&#xA;"
+		case '\r':
+			escaped = "&#xD;"
+		default:
+			if isXMLChar(r) && (r != utf8.RuneError || size != 1) {
+				i += size
+				continue
+			}
+			escaped = "\uFFFD"
+		}
+		buffer.WriteString(s[last:i])
+		buffer.WriteString(escaped)
+		i += size
+		last = i
+	}
+	buffer.WriteString(s[last:])
+}
@@ -0,0 +1,114 @@
+package xlsx
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Excel reads at most Excel2006MaxRowCount rows per sheet, so a
+// StreamFile stops with a RowLimitError rather than write a row past
+// them, unless the rows continue on new sheets, see
+// StreamFileBuilder.SetRowLimitRollover.  The size of the file isn't
+// limited: the zip writer switches to ZIP64 records for the parts and
+// files past 4 GiB, and for the files with more than 65,535 parts.
+
+// RowLimitError is returned when a row is written to a sheet already
+// holding as many rows as Excel can read.
+type RowLimitError struct {
+	Sheet string
+	Rows  int
+}
+
+// Error returns a description of the RowLimitError.
+func (e *RowLimitError) Error() string {
+	return fmt.Sprintf("the sheet %q already has %d rows, as many as Excel can read", e.Sheet, e.Rows)
+}
+
+// sheetRelationshipElements matches the elements of the XML of a sheet
+// referring to its relationships, which a sheet continuing its rows
+// doesn't have.
+var sheetRelationshipElements = regexp.MustCompile(`(?s)<(hyperlinks|drawing|legacyDrawing|legacyDrawingHF|picture|oleObjects|controls|tableParts)[ >/].*?(/>|</(hyperlinks|drawing|legacyDrawing|legacyDrawingHF|picture|oleObjects|controls|tableParts)>)`)
+
+// SetRowLimitRollover makes the rows written to a full sheet, which holds as many rows as Excel can read, continue on
+// a new sheet added after the other sheets, named after it the way Excel names copies, such as "Data (2)", rather
+// than fail with a RowLimitError. The new sheet starts with the rows of the full one above the streamed rows, such as
+// its header row, and has its columns, styles and settings, but not its images, tables or links. The rows of a sheet
+// whose columns are split across follow-on sheets, see SetSplitWideSheets, or of a spooled sheet, see
+// StreamFile.SpoolSheets, don't roll over.
+func (sb *StreamFileBuilder) SetRowLimitRollover(rollover bool) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	sb.rowLimitRollover = rollover
+	return nil
+}
+
+// settingsIndex returns the index, starting at 1, of the sheet whose settings the sheet at sheetIndex has: the sheet
+// whose rows it continues, if any, or else itself.
+func (sf *StreamFile) settingsIndex(sheetIndex int) int {
+	if origin, ok := sf.continuedSheets[sheetIndex]; ok {
+		return origin
+	}
+	return sheetIndex
+}
+
+// continueRows finishes the current sheet, which is full, and makes the sheet continuing its rows the current one, or
+// returns a RowLimitError if its rows don't roll over.
+func (sf *StreamFile) continueRows() error {
+	ss := sf.currentSheet
+	sheet := sf.xlsxFile.Sheets[ss.index-1]
+	if !sf.rowLimitRollover || sf.spooled || len(ss.followOns) > 0 {
+		return &RowLimitError{Sheet: sheet.Name, Rows: sf.maxRows}
+	}
+	origin := sf.settingsIndex(ss.index)
+	continuation, err := sf.xlsxFile.AddSheet(sf.xlsxFile.uniqueSheetName(sf.xlsxFile.Sheets[origin-1].Name))
+	if err != nil {
+		return err
+	}
+	for _, row := range sheet.Rows {
+		copied := continuation.AddRow()
+		for _, cell := range row.Cells {
+			value := ""
+			if cell != nil {
+				value = cell.Value
+			}
+			copied.AddCell().SetString(value)
+		}
+	}
+	// The cells added to the rows may have added columns.
+	continuation.Cols = append([]*Col(nil), sheet.Cols...)
+	continuation.streamOutlineLevels = sheet.streamOutlineLevels
+	// The continuation isn't selected along with the first sheet.
+	prefix := strings.Replace(sf.sheetXmlPrefix[ss.index-1], `tabSelected="true"`, `tabSelected="false"`, 1)
+	suffix := sheetRelationshipElements.ReplaceAllString(sf.sheetXmlSuffix[ss.index-1], "")
+	sf.sheetXmlPrefix = append(sf.sheetXmlPrefix, prefix)
+	sf.sheetXmlSuffix = append(sf.sheetXmlSuffix, suffix)
+	sf.styleIds = append(sf.styleIds, nil)
+	sf.sheetRels = append(sf.sheetRels, nil)
+	sf.rowCounts = append(sf.rowCounts, 0)
+	sf.cellCounts = append(sf.cellCounts, 0)
+	sf.contentTypes.Overrides = append(sf.contentTypes.Overrides, xlsxOverride{
+		PartName:    "/" + sheetFilePathPrefix + strconv.Itoa(len(sf.xlsxFile.Sheets)) + sheetFilePathSuffix,
+		ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml",
+	})
+	if sf.continuedSheets == nil {
+		sf.continuedSheets = make(map[int]int)
+	}
+	sf.continuedSheets[len(sf.xlsxFile.Sheets)] = origin
+	if err := sf.writeSheetEnd(); err != nil {
+		sf.err = err
+		return err
+	}
+	if err := sf.startSheet(len(sf.xlsxFile.Sheets)); err != nil {
+		return err
+	}
+	sf.currentSheet.outlineLevel = ss.outlineLevel
+	if ss.duplicates != nil {
+		// The rows are compared with those at the end of the full
+		// sheet.
+		sf.currentSheet.duplicates = ss.duplicates
+	}
+	return nil
+}
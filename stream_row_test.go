@@ -0,0 +1,127 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"math/rand"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+type StreamRowSuite struct{}
+
+var _ = Suite(&StreamRowSuite{})
+
+func (s *StreamRowSuite) TestWriteEscapedText(c *C) {
+	for _, text := range []string{
+		"",
+		"plain text",
+		`Smith & Sons <"quoted"> 'single'`,
+		"tab\tnew line\ncarriage return\r",
+		"control \x01\x1f characters",
+		"invalid \xff\xfe UTF-8",
+		"replacement � character",
+		"non-characters ￾￿",
+		"snowman ☃ and emoji 😀",
+		"trailing <",
+	} {
+		var expected bytes.Buffer
+		c.Assert(xml.EscapeText(&expected, []byte(text)), IsNil)
+		var escaped bytes.Buffer
+		writeEscapedText(&escaped, text)
+		c.Assert(escaped.String(), Equals, expected.String())
+	}
+}
+
+// escapeXMLTexts are texts escaped by EscapeXML in the tests, for each
+// case it handles.
+var escapeXMLTexts = []string{
+	"",
+	"plain text",
+	`Smith & Sons <"quoted"> 'single'`,
+	"tab\tnew line\ncarriage return\r",
+	"control \x00\x01\x1f\x7f characters",
+	"invalid \xff\xfe UTF-8",
+	"truncated \xe2\x98",
+	"surrogates \xed\xa0\x80\xed\xbf\xbf",
+	"replacement \uFFFD character",
+	"non-characters \uFFFE\uFFFF",
+	"snowman \u2603 and emoji \U0001F600",
+	"trailing <",
+	"<",
+}
+
+// xmlEscapeText returns s escaped by xml.EscapeText.
+func xmlEscapeText(s string) string {
+	var escaped bytes.Buffer
+	xml.EscapeText(&escaped, []byte(s))
+	return escaped.String()
+}
+
+func (s *StreamRowSuite) TestEscapeXML(c *C) {
+	for _, text := range escapeXMLTexts {
+		c.Assert(EscapeXML(text), Equals, xmlEscapeText(text))
+	}
+	c.Assert(EscapeXML("a < b"), Equals, "a &lt; b")
+	c.Assert(EscapeXML("\xed\xa0\x80"), Equals, "\uFFFD\uFFFD\uFFFD")
+	c.Assert(testing.AllocsPerRun(10, func() { EscapeXML("plain text, caf\u00e9") }), Equals, 0.0)
+}
+
+// TestEscapeXMLRandom compares EscapeXML with xml.EscapeText on random
+// texts made of the bytes and characters they treat differently.
+func (s *StreamRowSuite) TestEscapeXMLRandom(c *C) {
+	pieces := []string{"a", "Z", " ", "&", "<", ">", `"`, "'", "\t", "\n", "\r", "\x00", "\x1f", "\x7f",
+		"\xc3", "\xa9", "\xed", "\xa0", "\xff", "\u00e9", "\u2603", "\uFFFD", "\uFFFE", "\U0001F600", "\U0010FFFF"}
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		var text bytes.Buffer
+		for n := random.Intn(12); n > 0; n-- {
+			text.WriteString(pieces[random.Intn(len(pieces))])
+		}
+		c.Assert(EscapeXML(text.String()), Equals, xmlEscapeText(text.String()))
+	}
+}
+
+func (s *StreamRowSuite) TestRowBufferPool(c *C) {
+	ss := &streamSheet{writer: &bytes.Buffer{}}
+	ss.rowBuffer().WriteString(`<row>`)
+	ss.rowBuffer().WriteString(`</row>`)
+	size, err := ss.writeRowBuffer()
+	c.Assert(err, IsNil)
+	c.Assert(size, Equals, 11)
+	c.Assert(ss.writer.(*bytes.Buffer).String(), Equals, `<row></row>`)
+	c.Assert(ss.row.Len(), Equals, 0)
+	ss.releaseRowBuffer()
+	c.Assert(ss.row, IsNil)
+	// The buffers of huge rows aren't kept.
+	ss.rowBuffer().Write(make([]byte, maxPooledRowBuffer+1))
+	ss.releaseRowBuffer()
+	c.Assert(ss.row, IsNil)
+}
+
+func BenchmarkEscapeXMLPlain(b *testing.B) {
+	benchmarkEscapeXML(b, EscapeXML, "Smith and Sons, 12 Main Street, Springfield")
+}
+
+func BenchmarkEscapeXMLMarkup(b *testing.B) {
+	benchmarkEscapeXML(b, EscapeXML, `Smith & Sons <"Main Street">, Springfield`)
+}
+
+func BenchmarkXMLEscapeTextPlain(b *testing.B) {
+	benchmarkEscapeXML(b, xmlEscapeText, "Smith and Sons, 12 Main Street, Springfield")
+}
+
+func BenchmarkXMLEscapeTextMarkup(b *testing.B) {
+	benchmarkEscapeXML(b, xmlEscapeText, `Smith & Sons <"Main Street">, Springfield`)
+}
+
+// benchmarkEscapeXML escapes text with escape, such as the text of the
+// cells of streamed sheets, to compare EscapeXML with xml.EscapeText.
+func benchmarkEscapeXML(b *testing.B, escape func(string) string, text string) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(text)))
+	for i := 0; i < b.N; i++ {
+		escape(text)
+	}
+}
@@ -0,0 +1,94 @@
+package xlsx
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// StreamSchema describes the sheets and the columns of a streamed workbook, made from the configuration of its
+// builder, so that the consumers of exports can check the files they receive, see StreamFile.Schema. WriteSchema
+// writes it as JSON, alongside the file.
+type StreamSchema struct {
+	Sheets []StreamSheetSchema `json:"sheets"`
+}
+
+// StreamSheetSchema describes a sheet of a streamed workbook.
+type StreamSheetSchema struct {
+	Name    string               `json:"name"`
+	Hidden  bool                 `json:"hidden,omitempty"`
+	Columns []StreamColumnSchema `json:"columns"`
+}
+
+// StreamColumnSchema describes a column of a sheet of a streamed workbook.
+type StreamColumnSchema struct {
+	// Index is the index of the column, starting at 0, and Column its
+	// letters, such as "A".
+	Index  int    `json:"index"`
+	Column string `json:"column"`
+	Header string `json:"header"`
+	// Type is the type of the values of the column, such as "int",
+	// see ColumnType.String, or "formula" for the columns given a
+	// formula with StreamFileBuilder.SetColumnFormula.
+	Type string `json:"type"`
+	// NumberFormat is the number format of the column, if any, such
+	// as that of its dates.
+	NumberFormat string  `json:"numberFormat,omitempty"`
+	Formula      string  `json:"formula,omitempty"`
+	Width        float64 `json:"width,omitempty"`
+	Hidden       bool    `json:"hidden,omitempty"`
+	// Masked is true for the columns whose values are masked, see
+	// StreamFileBuilder.SetColumnMask.
+	Masked bool `json:"masked,omitempty"`
+}
+
+// Schema returns the schema of the workbook, describing its sheets, including those added since it was built, and
+// their columns.
+func (sf *StreamFile) Schema() StreamSchema {
+	schema := StreamSchema{Sheets: []StreamSheetSchema{}}
+	for sheetIndex, sheet := range sf.xlsxFile.Sheets {
+		sheetSchema := StreamSheetSchema{Name: sheet.Name, Hidden: sheet.Hidden, Columns: []StreamColumnSchema{}}
+		headers := streamHeaders(sheet)
+		for colIndex, col := range sheet.Cols {
+			column := StreamColumnSchema{
+				Index:  colIndex,
+				Column: ColIndexToLetters(colIndex),
+				Type:   ColumnTypeString.String(),
+				Width:  col.Width,
+				Hidden: col.Hidden,
+			}
+			if colIndex < len(headers) {
+				column.Header = headers[colIndex]
+			}
+			if col.numFmt != "" && col.numFmt != builtInNumFmt[builtInNumFmtIndex_GENERAL] {
+				column.NumberFormat = col.numFmt
+			}
+			if sheetIndex < len(sf.columnTypes) && colIndex < len(sf.columnTypes[sheetIndex]) {
+				column.Type = sf.columnTypes[sheetIndex][colIndex].String()
+				if sf.columnTypes[sheetIndex][colIndex] == ColumnTypeDate && column.NumberFormat == "" {
+					column.NumberFormat = DefaultDateFormat
+				}
+			}
+			if sheetIndex < len(sf.columnFormulas) && colIndex < len(sf.columnFormulas[sheetIndex]) &&
+				sf.columnFormulas[sheetIndex][colIndex] != "" {
+				column.Type = "formula"
+				column.Formula = sf.columnFormulas[sheetIndex][colIndex]
+			}
+			if sheetIndex < len(sf.columnMasks) && colIndex < len(sf.columnMasks[sheetIndex]) {
+				column.Masked = sf.columnMasks[sheetIndex][colIndex] != nil
+			}
+			sheetSchema.Columns = append(sheetSchema.Columns, column)
+		}
+		schema.Sheets = append(schema.Sheets, sheetSchema)
+	}
+	return schema
+}
+
+// WriteSchema writes the schema of the workbook, see Schema, to w as indented JSON.
+func (sf *StreamFile) WriteSchema(w io.Writer) error {
+	data, err := json.MarshalIndent(sf.Schema(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,426 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+)
+
+// A StreamFile can't know the range of a sheet before its rows are
+// written, so it leaves out the dimension tag of the sheets, which
+// comes before the rows.  When it writes to a seekable output, such as
+// an *os.File, the sheets hold a placeholder instead, an XML comment as
+// long as the longest dimension tag, which Close replaces once the file
+// is written, along with the CRCs of the sheets, since their bytes
+// change.  The placeholder is kept out of the compressed data, written
+// in a stored Deflate block of its own which the rest of the data
+// follows, so that it can be replaced where it is.  Outputs that can't
+// seek, such as sockets and pipes, are streamed to without placeholders.
+
+// seekableOutput is an output a StreamFile can go back over once the
+// file is written, such as an *os.File.
+type seekableOutput interface {
+	io.Writer
+	io.ReaderAt
+	io.WriterAt
+	io.Seeker
+}
+
+// dimensionPlaceholder takes the place of the dimension tag of the sheets
+// written to a seekable output, with the length of the longest one.
+var dimensionPlaceholder = "<!--dimension" +
+	strings.Repeat(" ", len(fmt.Sprintf(dimensionTag, "A1:XFD1048576"))-len("<!--dimension-->")) + "-->"
+
+// maxPlaceholderOffset is the furthest into a sheet its dimension
+// placeholder is looked for.
+const maxPlaceholderOffset = 1 << 16
+
+// seekableOutputOf returns writer as a seekableOutput, with its current
+// offset, if it can seek.
+func seekableOutputOf(writer io.Writer) (seekableOutput, int64) {
+	output, ok := writer.(seekableOutput)
+	if !ok {
+		return nil, 0
+	}
+	// Pipes and sockets opened as files can't seek.
+	offset, err := output.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, 0
+	}
+	return output, offset
+}
+
+// dimensionCompressor is the PartCompressor of the StreamFiles writing
+// their parts with Deflate to a seekable output, which writes the start
+// of the sheets up to their dimension placeholder in stored blocks,
+// and the rest with compressor.
+type dimensionCompressor struct {
+	compressor PartCompressor
+}
+
+func (dc dimensionCompressor) Method() uint16 {
+	return zip.Deflate
+}
+
+func (dc dimensionCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	var writer io.WriteCloser
+	if dc.compressor != nil {
+		var err error
+		if writer, err = dc.compressor.NewWriter(w); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if writer, err = flate.NewWriter(w, flate.DefaultCompression); err != nil {
+			return nil, err
+		}
+		// Only the streaming compressors are flushed with the rows.
+		writer = struct{ io.WriteCloser }{writer}
+	}
+	part := &dimensionPartWriter{output: w, writer: writer, searching: true}
+	if flusher, ok := writer.(partFlusher); ok {
+		return &flushedDimensionPartWriter{dimensionPartWriter: part, flusher: flusher}, nil
+	}
+	return part, nil
+}
+
+// dimensionPartWriter writes a part to output, holding its start back
+// until the dimension placeholder is found, then written in stored
+// blocks, or found not to be there, the rest going to writer.
+type dimensionPartWriter struct {
+	output    io.Writer
+	writer    io.WriteCloser
+	head      []byte
+	searching bool
+}
+
+func (dw *dimensionPartWriter) Write(p []byte) (int, error) {
+	if !dw.searching {
+		return dw.writer.Write(p)
+	}
+	dw.head = append(dw.head, p...)
+	if i := bytes.Index(dw.head, []byte(dimensionPlaceholder)); i >= 0 {
+		end := i + len(dimensionPlaceholder)
+		// The placeholder has a block of its own, so that its bytes
+		// follow each other.
+		if err := writeStoredBlocks(dw.output, dw.head[:i]); err != nil {
+			return 0, err
+		}
+		if err := writeStoredBlocks(dw.output, dw.head[i:end]); err != nil {
+			return 0, err
+		}
+		if err := dw.release(dw.head[end:]); err != nil {
+			return 0, err
+		}
+	} else if len(dw.head) > maxPlaceholderOffset {
+		if err := dw.release(dw.head); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// release stops the search for the placeholder, writing rest to writer.
+func (dw *dimensionPartWriter) release(rest []byte) error {
+	dw.searching = false
+	dw.head = nil
+	_, err := dw.writer.Write(rest)
+	return err
+}
+
+func (dw *dimensionPartWriter) Close() error {
+	if dw.searching {
+		if err := dw.release(dw.head); err != nil {
+			return err
+		}
+	}
+	return dw.writer.Close()
+}
+
+// flushedDimensionPartWriter is the dimensionPartWriter of a streaming
+// compressor, flushed with the rows.
+type flushedDimensionPartWriter struct {
+	*dimensionPartWriter
+	flusher partFlusher
+}
+
+func (fw *flushedDimensionPartWriter) Flush() error {
+	if fw.searching {
+		// The start of the part waits for its placeholder.
+		return nil
+	}
+	return fw.flusher.Flush()
+}
+
+// writeStoredBlocks writes data to w as stored Deflate blocks, none of
+// them final.
+func writeStoredBlocks(w io.Writer, data []byte) error {
+	for len(data) > 0 {
+		n := len(data)
+		if n > 0xffff {
+			n = 0xffff
+		}
+		// The header of the block, fitting in the first byte, is
+		// padded to the byte.
+		header := []byte{0, byte(n), byte(n >> 8), ^byte(n), ^byte(n >> 8)}
+		if _, err := w.Write(header); err != nil {
+			return err
+		}
+		if _, err := w.Write(data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// dimensionPlaceholder returns what takes the place of the dimension tag
+// of the sheets of sf, the placeholder if it writes to a seekable output.
+func (sf *StreamFile) dimensionPlaceholder() string {
+	if sf.seekable == nil {
+		return ""
+	}
+	return dimensionPlaceholder
+}
+
+// dimensionRef returns the range of the dimension tag of ss.
+func (ss *streamSheet) dimensionRef() string {
+	return dimensionRange(ss.totalColumnCount(), ss.rowCount)
+}
+
+// outputPatch is a change of the bytes of a seekable output, at offset
+// from the start of the file.
+type outputPatch struct {
+	offset int64
+	data   []byte
+}
+
+// patchDimensions replaces the dimension placeholders of the sheets of a
+// file written to a seekable output, and their CRCs.  Nothing is changed
+// if the output can't be read back.
+func (sf *StreamFile) patchDimensions() error {
+	if len(sf.dimensionRefs) == 0 {
+		return nil
+	}
+	size := sf.output.bytesWritten()
+	file := io.NewSectionReader(sf.seekable, sf.seekableBase, size)
+	zipReader, err := zip.NewReader(file, size)
+	if err != nil {
+		return nil
+	}
+	crcOffsets, err := centralDirectoryCRCOffsets(file, size)
+	if err != nil {
+		return nil
+	}
+	var patches []outputPatch
+	for _, part := range zipReader.File {
+		ref, ok := sf.dimensionRefs[part.Name]
+		if !ok || part.Flags&0x8 == 0 {
+			continue
+		}
+		offset, position, err := findDimensionPlaceholder(file, part)
+		if err != nil {
+			return nil
+		}
+		if offset < 0 {
+			continue
+		}
+		tag := fmt.Sprintf(dimensionTag, ref)
+		tag += strings.Repeat(" ", len(dimensionPlaceholder)-len(tag))
+		crc := patchedCRC(part.CRC32, position, []byte(dimensionPlaceholder), []byte(tag), int64(part.UncompressedSize64))
+		descriptor := make([]byte, 4)
+		dataOffset, err := part.DataOffset()
+		if err != nil {
+			return nil
+		}
+		if _, err := file.ReadAt(descriptor, dataOffset+int64(part.CompressedSize64)); err != nil ||
+			binary.LittleEndian.Uint32(descriptor) != 0x08074b50 {
+			return nil
+		}
+		crcOffset, ok := crcOffsets[part.Name]
+		if !ok {
+			return nil
+		}
+		crcBytes := make([]byte, 4)
+		binary.LittleEndian.PutUint32(crcBytes, crc)
+		patches = append(patches,
+			outputPatch{offset: offset, data: []byte(tag)},
+			outputPatch{offset: dataOffset + int64(part.CompressedSize64) + 4, data: crcBytes},
+			outputPatch{offset: crcOffset, data: crcBytes})
+	}
+	for _, patch := range patches {
+		if _, err := sf.seekable.WriteAt(patch.data, sf.seekableBase+patch.offset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findDimensionPlaceholder returns the offset in file of the dimension
+// placeholder of part, and its position in the data of the part, or -1
+// if it has none.
+func findDimensionPlaceholder(file io.ReaderAt, part *zip.File) (int64, int64, error) {
+	dataOffset, err := part.DataOffset()
+	if err != nil {
+		return 0, 0, err
+	}
+	if part.Method == zip.Store {
+		head := make([]byte, minInt64(int64(part.UncompressedSize64), maxPlaceholderOffset+int64(len(dimensionPlaceholder))))
+		if _, err := file.ReadAt(head, dataOffset); err != nil {
+			return 0, 0, err
+		}
+		i := bytes.Index(head, []byte(dimensionPlaceholder))
+		if i < 0 {
+			return -1, 0, nil
+		}
+		return dataOffset + int64(i), int64(i), nil
+	}
+	// The start of the data is in stored blocks, the placeholder in a
+	// block of its own, see dimensionPartWriter.
+	offset, position := dataOffset, int64(0)
+	header := make([]byte, 5)
+	for position <= maxPlaceholderOffset {
+		if _, err := file.ReadAt(header, offset); err != nil {
+			return 0, 0, err
+		}
+		n := int64(binary.LittleEndian.Uint16(header[1:]))
+		if header[0] != 0 || binary.LittleEndian.Uint16(header[3:]) != ^uint16(n) {
+			return -1, 0, nil
+		}
+		if n == int64(len(dimensionPlaceholder)) {
+			block := make([]byte, n)
+			if _, err := file.ReadAt(block, offset+5); err != nil {
+				return 0, 0, err
+			}
+			if string(block) == dimensionPlaceholder {
+				return offset + 5, position, nil
+			}
+		}
+		offset += 5 + n
+		position += n
+	}
+	return -1, 0, nil
+}
+
+// centralDirectoryCRCOffsets returns the offsets in the zip file of the
+// CRCs of its central directory, by the name of their parts.
+func centralDirectoryCRCOffsets(file io.ReaderAt, size int64) (map[string]int64, error) {
+	// The zip writer writes no comment, so the end of the central
+	// directory ends the file.
+	end := make([]byte, 22)
+	if _, err := file.ReadAt(end, size-22); err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(end) != 0x06054b50 {
+		return nil, errors.New("end of central directory not found")
+	}
+	directory := int64(binary.LittleEndian.Uint32(end[16:]))
+	entries := int64(binary.LittleEndian.Uint16(end[10:]))
+	if directory == 0xffffffff || entries == 0xffff {
+		locator := make([]byte, 20)
+		if _, err := file.ReadAt(locator, size-22-20); err != nil {
+			return nil, err
+		}
+		record := make([]byte, 56)
+		if _, err := file.ReadAt(record, int64(binary.LittleEndian.Uint64(locator[8:]))); err != nil {
+			return nil, err
+		}
+		directory = int64(binary.LittleEndian.Uint64(record[48:]))
+		entries = int64(binary.LittleEndian.Uint64(record[32:]))
+	}
+	offsets := make(map[string]int64)
+	header := make([]byte, 46)
+	offset := directory
+	for i := int64(0); i < entries; i++ {
+		if _, err := file.ReadAt(header, offset); err != nil {
+			return nil, err
+		}
+		if binary.LittleEndian.Uint32(header) != 0x02014b50 {
+			return nil, errors.New("central directory header not found")
+		}
+		nameLength := int64(binary.LittleEndian.Uint16(header[28:]))
+		name := make([]byte, nameLength)
+		if _, err := file.ReadAt(name, offset+46); err != nil {
+			return nil, err
+		}
+		offsets[string(name)] = offset + 16
+		offset += 46 + nameLength + int64(binary.LittleEndian.Uint16(header[30:])) + int64(binary.LittleEndian.Uint16(header[32:]))
+	}
+	return offsets, nil
+}
+
+// patchedCRC returns the CRC-32 of data of the given size, whose CRC-32
+// is crc, once the bytes old at position are replaced with replacement.  The CRC
+// of two messages of the same length differ by that of their difference
+// without its initial and final inversions, which is shifted over the
+// bytes after it.
+func patchedCRC(crc uint32, position int64, old, replacement []byte, size int64) uint32 {
+	difference := make([]byte, len(old))
+	for i := range old {
+		difference[i] = old[i] ^ replacement[i]
+	}
+	register := ^crc32.Update(^uint32(0), crc32.IEEETable, difference)
+	return crc ^ shiftCRC(register, size-position-int64(len(old)))
+}
+
+// shiftCRC returns the CRC-32 register crc after n zero bytes, as
+// crc32_combine of zlib does, squaring the operator of a zero bit.
+func shiftCRC(crc uint32, n int64) uint32 {
+	var even, odd [32]uint32
+	odd[0] = crc32.IEEE
+	row := uint32(1)
+	for i := 1; i < 32; i++ {
+		odd[i] = row
+		row <<= 1
+	}
+	// even is the operator of two zero bits, then odd of four.
+	squareGF2(&even, &odd)
+	squareGF2(&odd, &even)
+	for n > 0 {
+		squareGF2(&even, &odd)
+		if n&1 != 0 {
+			crc = timesGF2(&even, crc)
+		}
+		n >>= 1
+		if n == 0 {
+			break
+		}
+		squareGF2(&odd, &even)
+		if n&1 != 0 {
+			crc = timesGF2(&odd, crc)
+		}
+		n >>= 1
+	}
+	return crc
+}
+
+// timesGF2 returns the product of the matrix and the vector over GF(2).
+func timesGF2(matrix *[32]uint32, vector uint32) uint32 {
+	var sum uint32
+	for i := 0; vector != 0; i, vector = i+1, vector>>1 {
+		if vector&1 != 0 {
+			sum ^= matrix[i]
+		}
+	}
+	return sum
+}
+
+// squareGF2 sets square to the square of the matrix over GF(2).
+func squareGF2(square, matrix *[32]uint32) {
+	for i := range matrix {
+		square[i] = timesGF2(matrix, matrix[i])
+	}
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
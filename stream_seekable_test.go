@@ -0,0 +1,156 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type StreamSeekableSuite struct{}
+
+var _ = Suite(&StreamSeekableSuite{})
+
+// writeSeekableFile streams two sheets to the file at path with the
+// given compression, and returns the XML of the sheets.
+func writeSeekableFile(c *C, path string, compression StreamCompression) []string {
+	builder, err := NewStreamFileBuilderForPath(path)
+	c.Assert(err, IsNil)
+	c.Assert(builder.SetCompression(compression), IsNil)
+	c.Assert(builder.AddSheet("Wide", []string{"A", "B", "C"}, nil), IsNil)
+	c.Assert(builder.AddSheet("Empty", nil, nil), IsNil)
+	streamFile, err := builder.Build()
+	c.Assert(err, IsNil)
+	for i := 0; i < 200; i++ {
+		c.Assert(streamFile.Write([]string{"x", "y", strings.Repeat("z", i%7)}), IsNil)
+	}
+	c.Assert(streamFile.Close(), IsNil)
+
+	// Reading the parts to their end checks their CRCs.
+	reader, err := zip.OpenReader(path)
+	c.Assert(err, IsNil)
+	defer reader.Close()
+	var sheets []string
+	for _, name := range []string{"xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml"} {
+		for _, part := range reader.File {
+			if part.Name != name {
+				continue
+			}
+			rc, err := part.Open()
+			c.Assert(err, IsNil)
+			data, err := ioutil.ReadAll(rc)
+			c.Assert(err, IsNil)
+			c.Assert(rc.Close(), IsNil)
+			sheets = append(sheets, string(data))
+		}
+	}
+	c.Assert(sheets, HasLen, 2)
+	return sheets
+}
+
+func (s *StreamSeekableSuite) TestDimensionsPatched(c *C) {
+	for _, compression := range []StreamCompression{CompressionDeflate, CompressionDeflateStreaming, CompressionStore} {
+		path := filepath.Join(c.MkDir(), "seekable.xlsx")
+		sheets := writeSeekableFile(c, path, compression)
+		c.Assert(strings.Contains(sheets[0], `<dimension ref="A1:C201"></dimension>`), Equals, true)
+		c.Assert(strings.Contains(sheets[1], `<dimension ref="A1"></dimension>`), Equals, true)
+		c.Assert(strings.Contains(sheets[0], "<!--dimension"), Equals, false)
+
+		file, err := OpenFile(path)
+		c.Assert(err, IsNil)
+		c.Assert(file.Sheets[0].MaxRow, Equals, 201)
+		c.Assert(file.Sheets[0].Cell(200, 2).Value, Equals, strings.Repeat("z", 199%7))
+	}
+}
+
+func (s *StreamSeekableSuite) TestNotSeekable(c *C) {
+	var buffer bytes.Buffer
+	builder := NewStreamFileBuilder(&buffer)
+	c.Assert(builder.AddSheet("Sheet", []string{"A"}, nil), IsNil)
+	streamFile, err := builder.Build()
+	c.Assert(err, IsNil)
+	c.Assert(streamFile.Write([]string{"x"}), IsNil)
+	c.Assert(streamFile.Close(), IsNil)
+	_, contents := readOptionsParts(c, buffer.Bytes())
+	c.Assert(strings.Contains(contents["xl/worksheets/sheet1.xml"], "dimension"), Equals, false)
+
+	// A file opened for writing only can't be read back, and keeps
+	// its placeholders, which are comments.
+	path := filepath.Join(c.MkDir(), "writeonly.xlsx")
+	output, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0600)
+	c.Assert(err, IsNil)
+	builder = NewStreamFileBuilder(output)
+	c.Assert(builder.AddSheet("Sheet", []string{"A"}, nil), IsNil)
+	streamFile, err = builder.Build()
+	c.Assert(err, IsNil)
+	c.Assert(streamFile.Close(), IsNil)
+	c.Assert(output.Close(), IsNil)
+	file, err := OpenFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(file.Sheets[0].Cell(0, 0).Value, Equals, "A")
+}
+
+func (s *StreamSeekableSuite) TestPatchedCRC(c *C) {
+	data := []byte(strings.Repeat("the rows of a sheet ", 500))
+	old := []byte(data[120:140])
+	replacement := []byte("a replacement string")
+	patched := append([]byte(nil), data...)
+	copy(patched[120:], replacement)
+	crc := patchedCRC(crc32.ChecksumIEEE(data), 120, old, replacement, int64(len(data)))
+	c.Assert(crc, Equals, crc32.ChecksumIEEE(patched))
+}
+
+func (s *StreamSeekableSuite) TestExpectedRows(c *C) {
+	write := func(rows int) ([]byte, error) {
+		var buffer bytes.Buffer
+		builder := NewStreamFileBuilder(&buffer)
+		c.Assert(builder.AddSheet("Sheet", []string{"A", "B"}, nil), IsNil)
+		c.Assert(builder.SetExpectedRows(0, 3), IsNil)
+		streamFile, err := builder.Build()
+		c.Assert(err, IsNil)
+		for i := 0; i < rows; i++ {
+			if err = streamFile.Write([]string{"x", "y"}); err != nil {
+				return nil, err
+			}
+		}
+		if err = streamFile.Close(); err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	}
+	data, err := write(3)
+	c.Assert(err, IsNil)
+	_, contents := readOptionsParts(c, data)
+	c.Assert(strings.Contains(contents["xl/worksheets/sheet1.xml"], `<dimension ref="A1:B4"></dimension>`), Equals, true)
+	file, err := OpenBinary(data)
+	c.Assert(err, IsNil)
+	c.Assert(file.Sheets[0].MaxRow, Equals, 4)
+
+	_, err = write(4)
+	c.Assert(err, ErrorMatches, `more than the 3 rows expected were written to the sheet "Sheet"`)
+	_, err = write(2)
+	c.Assert(err, ErrorMatches, `2 rows were written to the sheet "Sheet", rather than the 3 expected`)
+	expectedErr, ok := err.(*ExpectedRowsError)
+	c.Assert(ok, Equals, true)
+	c.Assert(*expectedErr, Equals, ExpectedRowsError{Sheet: "Sheet", Expected: 3, Written: 2})
+
+	// The dimension written up front isn't patched on a seekable output.
+	path := filepath.Join(c.MkDir(), "expected.xlsx")
+	builder, err := NewStreamFileBuilderForPath(path)
+	c.Assert(err, IsNil)
+	c.Assert(builder.AddSheet("Sheet", []string{"A"}, nil), IsNil)
+	c.Assert(builder.SetExpectedRows(1, 1), ErrorMatches, "no sheet at index 1")
+	c.Assert(builder.SetExpectedRows(0, Excel2006MaxRowCount), ErrorMatches, "invalid number of rows 1048576 of sheet 'Sheet'")
+	c.Assert(builder.SetExpectedRows(0, 0), IsNil)
+	streamFile, err := builder.Build()
+	c.Assert(err, IsNil)
+	c.Assert(streamFile.Close(), IsNil)
+	file, err = OpenFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(file.Sheets[0].MaxRow, Equals, 1)
+}
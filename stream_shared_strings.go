@@ -0,0 +1,30 @@
+package xlsx
+
+import "strings"
+
+// sharedStringIndex returns the index in the shared strings of value,
+// which is added to them unless they already hold the most strings
+// allowed by StreamFileBuilder.SetSharedStringLimit, in which case false
+// is returned and value is to be written inline.
+func (sf *StreamFile) sharedStringIndex(value string) (int, bool) {
+	if sf.sharedStringsLock != nil {
+		sf.sharedStringsLock.Lock()
+		defer sf.sharedStringsLock.Unlock()
+	}
+	if index, ok := sf.sharedStrings.knownStrings[value]; ok {
+		return index, true
+	}
+	if sf.sharedStringLimit > 0 && sf.sharedStrings.Length() >= sf.sharedStringLimit {
+		sf.inlineStrings++
+		return 0, false
+	}
+	return sf.sharedStrings.AddString(value), true
+}
+
+// inlineCellOpeningEnd returns the end of the opening of a cell holding
+// an inline string, from that, openingEnd, of a cell holding a shared
+// string, for the strings past the limit of the shared strings.
+func inlineCellOpeningEnd(openingEnd string) string {
+	openingEnd = strings.Replace(openingEnd, ` t="s"`, ` t="inlineStr"`, 1)
+	return strings.TrimSuffix(openingEnd, `><v>`) + `><is><t>`
+}
@@ -0,0 +1,324 @@
+package xlsx
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+)
+
+var (
+	// SheetsSpooledError is returned by NextSheet and AddSheet once the
+	// sheets after the current one are spooled, and by SpoolSheets if
+	// they already are.
+	SheetsSpooledError = errors.New("the sheets after the current sheet are spooled")
+	// ClosedSpooledSheetError is returned when writing to a
+	// SpooledSheet once it is closed.
+	ClosedSpooledSheetError = errors.New("the spooled sheet is closed")
+)
+
+// SpooledSheet is a sheet of a StreamFile written to a spool of its own,
+// see StreamFile.SpoolSheets, so that the sheets of a file can be
+// written at the same time, from different goroutines.  Its methods are
+// safe for concurrent use.
+type SpooledSheet struct {
+	mu     sync.Mutex
+	file   *StreamFile
+	name   string
+	spool  io.ReadWriteSeeker
+	buffer *bufio.Writer
+	// temp is the temporary file used as spool, which is removed once
+	// the sheet is written to the file, or nil.
+	temp   *os.File
+	closed bool
+	// resumed is true for a sheet resumed from a checkpoint, see
+	// StreamFile.ResumeSpooledSheets.
+	resumed bool
+}
+
+// SpoolSheets makes the sheets after the current one, which keeps being
+// written by the StreamFile, spooled sheets, returned in order, each
+// written to the spool returned by newSpool for its index, starting at
+// 1, or to a temporary file if newSpool is nil.  The spools that are
+// io.Closers are closed, and the temporary files removed, once Close
+// has copied them into the file, after the current sheet.  Every
+// spooled sheet must be closed before the StreamFile is.
+//
+// NextSheet and AddSheet can't be called once the sheets are spooled,
+// and the sheets split across sheets, see
+// StreamFileBuilder.SetSplitWideSheets, can't be spooled.  The RowHook,
+// if any, is called from the goroutines writing the sheets, so it must
+// be safe for concurrent use.
+func (sf *StreamFile) SpoolSheets(newSpool func(sheetIndex int) (io.ReadWriteSeeker, error)) ([]*SpooledSheet, error) {
+	if err := sf.checkSpoolable(); err != nil {
+		return nil, err
+	}
+	return sf.spoolSheets(newSpool, nil)
+}
+
+// checkSpoolable returns the error SpoolSheets returns if the sheets
+// after the current one can't be spooled.
+func (sf *StreamFile) checkSpoolable() error {
+	if sf.err != nil {
+		return sf.err
+	}
+	if sf.currentSheet == nil {
+		return NoCurrentSheetError
+	}
+	if sf.currentSheet.rowOpen {
+		return RowInProgressError
+	}
+	if sf.spooledSheets != nil {
+		return SheetsSpooledError
+	}
+	if sf.export != nil {
+		return ExportSpooledError
+	}
+	for sheetIndex := sf.currentSheet.lastIndex() + 1; sheetIndex <= len(sf.xlsxFile.Sheets); sheetIndex++ {
+		if sheetIndex-1 < len(sf.followOns) && sf.followOns[sheetIndex-1] > 0 {
+			return fmt.Errorf("sheet '%s' is split across sheets and can't be spooled", sf.xlsxFile.Sheets[sheetIndex-1].Name)
+		}
+	}
+	return nil
+}
+
+// spoolSheets spools the sheets after the current one, resuming those
+// of the checkpoints, by sheet index, see ResumeSpooledSheets.
+func (sf *StreamFile) spoolSheets(newSpool func(sheetIndex int) (io.ReadWriteSeeker, error), checkpoints map[int]SpoolCheckpoint) ([]*SpooledSheet, error) {
+	if sf.sharedStrings != nil {
+		sf.sharedStringsLock = &sync.Mutex{}
+	}
+	sheets := []*SpooledSheet{}
+	for sheetIndex := sf.currentSheet.lastIndex() + 1; sheetIndex <= len(sf.xlsxFile.Sheets); sheetIndex++ {
+		var sheet *SpooledSheet
+		var err error
+		if checkpoint, ok := checkpoints[sheetIndex]; ok {
+			sheet, err = sf.resumeSpooledSheet(checkpoint, newSpool)
+		} else {
+			sheet, err = sf.spoolSheet(sheetIndex, newSpool)
+		}
+		if err != nil {
+			for _, sheet := range sheets {
+				sheet.discard()
+			}
+			return nil, err
+		}
+		sheets = append(sheets, sheet)
+	}
+	sf.spooledSheets = sheets
+	return sheets, nil
+}
+
+// spoolSheet returns the spooled sheet writing the sheet at the given
+// index to its spool, with a StreamFile of its own sharing the settings
+// of sf.
+func (sf *StreamFile) spoolSheet(sheetIndex int, newSpool func(sheetIndex int) (io.ReadWriteSeeker, error)) (*SpooledSheet, error) {
+	sheet := &SpooledSheet{name: sf.xlsxFile.Sheets[sheetIndex-1].Name}
+	if newSpool != nil {
+		spool, err := newSpool(sheetIndex)
+		if err != nil {
+			return nil, err
+		}
+		sheet.spool = spool
+	} else {
+		temp, err := ioutil.TempFile("", "xlsx-sheet-")
+		if err != nil {
+			return nil, err
+		}
+		sheet.spool, sheet.temp = temp, temp
+	}
+	sf.startSpooledSheet(sheet, sheetIndex)
+	// The start of a sheet sized to its contents is written once its
+	// rows are known, like that of the sheets written in order.
+	if sheet.file.currentSheet.widths == nil {
+		if err := sheet.file.writeSheetStart(); err != nil {
+			sheet.release()
+			return nil, err
+		}
+	}
+	return sheet, nil
+}
+
+// startSpooledSheet gives the spooled sheet the StreamFile of its own
+// writing the sheet at the given index to its spool, sharing the
+// settings of sf.
+func (sf *StreamFile) startSpooledSheet(sheet *SpooledSheet, sheetIndex int) {
+	file := *sf
+	file.spooled = true
+	file.spooledSheets = nil
+	file.duplicateRows = nil
+	file.duplicateCounts = nil
+	file.typeWarnings = nil
+	file.longTextCells = nil
+	file.provenance = nil
+	file.inlineStrings = 0
+	file.uncachedFormulas = false
+	file.currentSheet = sf.makeStreamSheet(sheetIndex)
+	sheet.buffer = bufio.NewWriter(sheet.spool)
+	file.currentSheet.writer = sheet.buffer
+	if sf.rowHook != nil {
+		file.currentSheet.dropFormulaHeaders()
+	}
+	sheet.file = &file
+}
+
+// Name returns the name of the sheet.
+func (s *SpooledSheet) Name() string {
+	return s.name
+}
+
+// Write writes a row of cells to the sheet, like StreamFile.Write.
+func (s *SpooledSheet) Write(cells []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ClosedSpooledSheetError
+	}
+	return s.file.Write(cells)
+}
+
+// WriteTyped writes a row of typed cells to the sheet, like
+// StreamFile.WriteTyped.
+func (s *SpooledSheet) WriteTyped(cells []StreamCell) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ClosedSpooledSheetError
+	}
+	return s.file.WriteTyped(cells)
+}
+
+// WriteSparse writes a row of the sheet holding only the given cells,
+// like StreamFile.WriteSparse.
+func (s *SpooledSheet) WriteSparse(cells map[int]StreamCell) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ClosedSpooledSheetError
+	}
+	return s.file.WriteSparse(cells)
+}
+
+// Close ends the sheet, whose rows are then all in its spool.  It
+// returns the first error met writing the sheet, if any.
+func (s *SpooledSheet) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return s.file.err
+	}
+	s.closed = true
+	if s.file.err != nil {
+		return s.file.err
+	}
+	if err := s.buffer.Flush(); err != nil {
+		s.file.err = err
+	}
+	return s.file.err
+}
+
+// release closes the spool of the sheet if it is an io.Closer, and
+// removes it if it is a temporary file.
+func (s *SpooledSheet) release() error {
+	if s.temp != nil {
+		err := s.temp.Close()
+		if removeErr := os.Remove(s.temp.Name()); err == nil {
+			err = removeErr
+		}
+		return err
+	}
+	if closer, ok := s.spool.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// discard releases the spool of a sheet that won't be written, except
+// for the temporary file of a resumed sheet, which is only closed so
+// that the sheet can be resumed again.
+func (s *SpooledSheet) discard() error {
+	if s.resumed && s.temp != nil {
+		return s.temp.Close()
+	}
+	return s.release()
+}
+
+// writeSpooledSheets copies the spooled sheets, if any, into the file,
+// after the sheets written in order, unless the context is done, and
+// releases their spools.
+func (sf *StreamFile) writeSpooledSheets(ctx context.Context) error {
+	sheets := sf.spooledSheets
+	defer func() {
+		for _, sheet := range sheets {
+			sheet.release()
+		}
+	}()
+	for _, sheet := range sheets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := sf.writeSpooledSheet(sheet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSpooledSheet adds the part of a closed spooled sheet, made of
+// the XML in its spool and the end of the sheet, and counts what was
+// found writing it along with the rest of the file.
+func (sf *StreamFile) writeSpooledSheet(sheet *SpooledSheet) error {
+	sheet.mu.Lock()
+	defer sheet.mu.Unlock()
+	if !sheet.closed {
+		return fmt.Errorf("spooled sheet '%s' wasn't closed", sheet.name)
+	}
+	file := sheet.file
+	if file.err != nil {
+		return file.err
+	}
+	for _, duplicate := range file.duplicateRows {
+		if len(sf.duplicateRows) < maxDuplicateRows {
+			sf.duplicateRows = append(sf.duplicateRows, duplicate)
+		}
+	}
+	for name, count := range file.duplicateCounts {
+		if sf.duplicateCounts == nil {
+			sf.duplicateCounts = make(map[string]int)
+		}
+		sf.duplicateCounts[name] += count
+	}
+	sf.typeWarnings = append(sf.typeWarnings, file.typeWarnings...)
+	sf.longTextCells = append(sf.longTextCells, file.longTextCells...)
+	sf.provenance = append(sf.provenance, file.provenance...)
+	sf.inlineStrings += file.inlineStrings
+	sf.uncachedFormulas = sf.uncachedFormulas || file.uncachedFormulas
+	if _, err := sheet.spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	ss := file.currentSheet
+	sheetPath := sheetFilePathPrefix + strconv.Itoa(ss.index) + sheetFilePathSuffix
+	fileWriter, err := sf.createPart(sheetPath)
+	if err != nil {
+		return err
+	}
+	ss.writer = fileWriter
+	if ss.widths != nil {
+		prefix, err := sizedSheetPrefix(sf.sheetXmlPrefix[ss.index-1], ss.widths)
+		if err != nil {
+			return err
+		}
+		if err := ss.write(prefix); err != nil {
+			return err
+		}
+	}
+	if _, err := io.Copy(fileWriter, sheet.spool); err != nil {
+		return err
+	}
+	return sf.writeStreamSheetEnd(ss)
+}
@@ -0,0 +1,256 @@
+package xlsx
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// structColumn is a column of a sheet added by
+// StreamFileBuilder.AddSheetFromStruct, holding the field of the struct
+// at index, which goes through its embedded structs.  styleId is the id
+// of the style given to the cells for format, the number format of the
+// field, or 0.  encoded is true if the values of the field are encoded
+// by a CellEncoder.
+type structColumn struct {
+	index      []int
+	header     string
+	format     string
+	columnType ColumnType
+	styleId    int
+	encoded    bool
+}
+
+// structSheet maps the structs written to a sheet added by
+// StreamFileBuilder.AddSheetFromStruct to its columns.
+type structSheet struct {
+	structType reflect.Type
+	columns    []structColumn
+}
+
+// errNotStruct is returned when the value given for a sheet of structs
+// isn't a struct or a pointer to one.
+var errNotStruct = errors.New("the value must be a struct or a pointer to a struct")
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// structType returns the type of the struct v is or points to.
+func structType(v interface{}) (reflect.Type, error) {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, errNotStruct
+	}
+	return t, nil
+}
+
+// structColumns returns the columns of the exported fields of the struct
+// t, and those of its exported embedded structs, reached through the
+// fields at index.  The xlsx tag of a field, such as `xlsx:"Unit price,0.00"`,
+// gives its header, the name of the field by default, and its number
+// format, and "-" leaves it out.
+func structColumns(t reflect.Type, index []int) ([]structColumn, error) {
+	var columns []structColumn
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("xlsx")
+		if tag == "-" || field.PkgPath != "" {
+			continue
+		}
+		fieldIndex := append(append([]int(nil), index...), i)
+		encoder, elem := cellEncoderFor(field.Type)
+		if encoder == nil && field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct && field.Type != timeType {
+			embedded, err := structColumns(field.Type, fieldIndex)
+			if err != nil {
+				return nil, err
+			}
+			columns = append(columns, embedded...)
+			continue
+		}
+		var column structColumn
+		if encoder != nil {
+			// The type and the number format of the column are those
+			// of the zero value of the field.
+			zero := field.Type
+			if elem {
+				zero = zero.Elem()
+			}
+			encoded, _, err := encodeCellValue(reflect.Zero(zero).Interface())
+			if err != nil {
+				return nil, fmt.Errorf("field %s of %s: %v", field.Name, t, err)
+			}
+			column = structColumn{columnType: encoded.columnType(), format: encoded.NumFmt, encoded: true}
+		} else {
+			columnType, ok := structFieldColumnType(field.Type)
+			if !ok {
+				return nil, fmt.Errorf("field %s of %s is a %s, which can't be written to a cell", field.Name, t, field.Type)
+			}
+			column.columnType = columnType
+		}
+		column.index, column.header = fieldIndex, field.Name
+		// The number format may hold commas, such as "#,##0.00".
+		header := tag
+		if comma := strings.Index(tag, ","); comma >= 0 {
+			header, column.format = tag[:comma], tag[comma+1:]
+		}
+		if header != "" {
+			column.header = header
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// structFieldColumnType returns the type of the column of a field of
+// type t, or false if it can't be written to a cell.  Pointers are
+// written as the values they point to, nil ones as nulls.
+func structFieldColumnType(t reflect.Type) (ColumnType, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return ColumnTypeDate, true
+	}
+	if t.Implements(stringerType) {
+		return ColumnTypeString, true
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return ColumnTypeString, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return ColumnTypeInt, true
+	case reflect.Float32, reflect.Float64:
+		return ColumnTypeFloat, true
+	case reflect.Bool:
+		return ColumnTypeBool, true
+	}
+	return ColumnTypeString, false
+}
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// structCell returns the StreamCell holding v, the value of a field
+// written to a column of type columnType.
+func structCell(v reflect.Value, columnType ColumnType) StreamCell {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return NewNullStreamCell()
+		}
+		v = v.Elem()
+	}
+	if columnType == ColumnTypeDate {
+		return NewDateStreamCell(v.Interface().(time.Time))
+	}
+	if stringer, ok := v.Interface().(fmt.Stringer); ok {
+		return NewStringStreamCell(stringer.String())
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return StreamCell{Value: strconv.FormatInt(v.Int(), 10), Type: CellTypeNumeric}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return StreamCell{Value: strconv.FormatUint(v.Uint(), 10), Type: CellTypeNumeric}
+	case reflect.Float32:
+		return StreamCell{Value: strconv.FormatFloat(v.Float(), 'f', -1, 32), Type: CellTypeNumeric}
+	case reflect.Float64:
+		return NewFloatStreamCell(v.Float())
+	case reflect.Bool:
+		return NewBoolStreamCell(v.Bool())
+	}
+	return NewStringStreamCell(v.String())
+}
+
+// AddSheetFromStruct adds a sheet like AddSheetWithTypes whose rows are the structs of the type of v, a struct or a
+// pointer to one, such as Order{}, written by StreamFile.WriteStruct. Its columns are the exported fields of the
+// struct, those of its exported embedded structs included, typed after them: integers, floats, booleans and time.Time fields
+// are written as numbers, booleans and dates, and the other fields, strings or fmt.Stringers, as text. Pointers are
+// written as the values they point to, nil ones as nulls, see SetNullAs. The xlsx tag of a field gives its header,
+// the name of the field by default, and a number format, such as `xlsx:"Unit price,#,##0.00"` or
+// `xlsx:"Shipped,yyyy-mm-dd"`, which takes precedence over the style of its column. A field tagged `xlsx:"-"` is
+// left out. The fields of the types given a CellEncoder, see RegisterCellEncoder, are encoded by it, their column
+// being typed after the encoding of their zero value, whose number format is that of the column unless the tag gives
+// one.
+func (sb *StreamFileBuilder) AddSheetFromStruct(name string, v interface{}) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	t, err := structType(v)
+	if err != nil {
+		return err
+	}
+	columns, err := structColumns(t, nil)
+	if err != nil {
+		return err
+	}
+	headers := make([]string, len(columns))
+	columnTypes := make([]ColumnType, len(columns))
+	for i := range columns {
+		if columns[i].format != "" {
+			// The number format is given to the cells by a style of
+			// their own, which the date cells need.
+			if columns[i].styleId, err = sb.AddStyle(NewStyle(), columns[i].format); err != nil {
+				return err
+			}
+		}
+		headers[i] = columns[i].header
+		columnTypes[i] = columns[i].columnType
+	}
+	sheetIndex := len(sb.xlsxFile.Sheets)
+	if err := sb.AddSheetWithTypes(name, headers, columnTypes); err != nil {
+		return err
+	}
+	sb.structSheets[sheetIndex] = &structSheet{structType: t, columns: columns}
+	return nil
+}
+
+// WriteStruct writes v, a struct or a pointer to one, as a row of the current sheet, which must have been added by
+// StreamFileBuilder.AddSheetFromStruct for structs of its type, each of its fields being written to its column. The
+// RowHook, if any, is called with the values of the cells, like for WriteTyped.
+func (sf *StreamFile) WriteStruct(v interface{}) error {
+	if sf.err != nil {
+		return sf.err
+	}
+	if sf.currentSheet == nil {
+		return NoCurrentSheetError
+	}
+	sheet := sf.xlsxFile.Sheets[sf.currentSheet.index-1]
+	var ss *structSheet
+	if settings := sf.settingsIndex(sf.currentSheet.index) - 1; settings < len(sf.structSheets) {
+		ss = sf.structSheets[settings]
+	}
+	if ss == nil {
+		return fmt.Errorf("sheet '%s' wasn't added by AddSheetFromStruct", sheet.Name)
+	}
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	if !value.IsValid() || value.Type() != ss.structType {
+		return fmt.Errorf("sheet '%s' holds %s structs, not %T", sheet.Name, ss.structType, v)
+	}
+	cells := make([]StreamCell, len(ss.columns))
+	for i, column := range ss.columns {
+		field := value.FieldByIndex(column.index)
+		if column.encoded {
+			encoded, ok, err := encodeCellValue(field.Interface())
+			if err != nil {
+				return fmt.Errorf("field %s of %s: %v", ss.structType.FieldByIndex(column.index).Name, ss.structType, err)
+			}
+			cells[i] = NewNullStreamCell()
+			if ok {
+				cells[i] = encoded.streamCell()
+			}
+		} else {
+			cells[i] = structCell(field, column.columnType)
+		}
+		if column.styleId != 0 && !cells[i].Null {
+			cells[i] = cells[i].WithStyle(column.styleId)
+		}
+	}
+	return sf.WriteTyped(cells)
+}
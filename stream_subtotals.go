@@ -0,0 +1,233 @@
+package xlsx
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// subtotalSum is the function number of SUBTOTAL summing the
+	// values, which leaves out those of the other SUBTOTAL formulas.
+	subtotalSum = 9
+	// grandTotalLabel is the label of the row summing the rows of every
+	// group, after the last of them.
+	grandTotalLabel = "Grand Total"
+)
+
+// SubtotalWriter writes the rows of the current sheet of a StreamFile
+// grouped the way the Subtotal command of Excel groups them: a subtotal
+// row follows the rows of each group, summing their values with
+// SUBTOTAL(9,…) formulas, and a grand total row follows the last group.
+// The rows are outlined so that the groups can be collapsed to their
+// subtotals, see NewSubtotalWriter.
+type SubtotalWriter struct {
+	sf *StreamFile
+	ss *streamSheet
+	// groupColumns and sumColumns are the columns of the sheet the
+	// rows are grouped by and summed.
+	groupColumns []int
+	sumColumns   []int
+	// keys holds the value of each group column in the last row
+	// written, and starts the number, from 1, of the first row of the
+	// group of each group column, the grand total first.
+	keys   []string
+	starts []int
+	// sums holds the sum of each sum column in the rows of the group
+	// of each group column, the grand total first.
+	sums [][]float64
+	// styleIds holds the style of the cells of each group and sum
+	// column in the last row written, given to the subtotal rows.
+	styleIds map[int]int
+	rows     int
+	closed   bool
+}
+
+// NewSubtotalWriter returns a SubtotalWriter writing rows to the current sheet, grouped by the values of
+// groupColumns, such as the region and then the country of sales, and summing those of sumColumns, such as the amounts
+// of the sales. The columns are those of the sheet, starting at 0, and can't be formula columns. The rows, written by
+// Write, must be sorted by the group columns, since a new group starts whenever one of their values changes, as with
+// Excel. The subtotal rows are labelled after the value of their group, such as "East Total", in the group column, and
+// are given the styles of the cells of the last row of their group. The detail rows are outlined at the level after
+// those of the subtotals of the group columns, so the sheet needs one more outline level than there are group
+// columns, see StreamSheetOptions.OutlineLevels. The rows can't roll over to another sheet, see
+// StreamFileBuilder.SetRowLimitRollover, since the formulas would miss them.
+func (sf *StreamFile) NewSubtotalWriter(groupColumns, sumColumns []int) (*SubtotalWriter, error) {
+	if sf.err != nil {
+		return nil, sf.err
+	}
+	if sf.currentSheet == nil {
+		return nil, NoCurrentSheetError
+	}
+	if sf.currentSheet.rowOpen {
+		return nil, RowInProgressError
+	}
+	ss := sf.currentSheet
+	sheet := sf.xlsxFile.Sheets[ss.index-1]
+	if len(groupColumns) == 0 {
+		return nil, fmt.Errorf("no group column to write subtotals of")
+	}
+	if int(sheet.streamOutlineLevels) <= len(groupColumns) {
+		return nil, fmt.Errorf("the sheet '%s' has %d outline levels, rather than the %d needed by %d group columns",
+			sheet.Name, sheet.streamOutlineLevels, len(groupColumns)+1, len(groupColumns))
+	}
+	seen := make(map[int]bool, len(groupColumns)+len(sumColumns))
+	for _, col := range append(append([]int(nil), groupColumns...), sumColumns...) {
+		if col < 0 || col >= ss.columnCount {
+			return nil, fmt.Errorf("no column at index %d in sheet '%s'", col, sheet.Name)
+		}
+		if ss.isFormulaColumn(col) {
+			return nil, fmt.Errorf("the column at index %d in sheet '%s' holds a formula", col, sheet.Name)
+		}
+		if seen[col] {
+			return nil, fmt.Errorf("the column at index %d is given twice", col)
+		}
+		seen[col] = true
+	}
+	w := &SubtotalWriter{
+		sf:           sf,
+		ss:           ss,
+		groupColumns: groupColumns,
+		sumColumns:   sumColumns,
+		keys:         make([]string, len(groupColumns)),
+		starts:       make([]int, len(groupColumns)+1),
+		sums:         make([][]float64, len(groupColumns)+1),
+		styleIds:     make(map[int]int),
+	}
+	for i := range w.sums {
+		w.sums[i] = make([]float64, len(sumColumns))
+	}
+	return w, nil
+}
+
+// Write writes a detail row of the sheet, like StreamFile.WriteTyped,
+// after the subtotal rows of the groups it ends, if any.
+func (w *SubtotalWriter) Write(cells []StreamCell) error {
+	if err := w.check(); err != nil {
+		return err
+	}
+	if len(cells) != w.ss.dataColumnCount() {
+		return fmt.Errorf("the row has %d cells, rather than the %d of the sheet '%s'", len(cells),
+			w.ss.dataColumnCount(), w.sf.xlsxFile.Sheets[w.ss.index-1].Name)
+	}
+	// The groups from the first group column whose value changed on
+	// end, and new ones start.
+	level := len(w.groupColumns)
+	if w.rows == 0 {
+		level = 0
+		w.starts[0] = w.ss.rowCount + 1
+	}
+	for i, col := range w.groupColumns {
+		if w.rows > 0 && cells[w.ss.dataIndex(col)].Value != w.keys[i] {
+			level = i
+			break
+		}
+	}
+	if err := w.writeSubtotals(level); err != nil {
+		return err
+	}
+	for i := level; i < len(w.groupColumns); i++ {
+		w.keys[i] = cells[w.ss.dataIndex(w.groupColumns[i])].Value
+		w.starts[i+1] = w.ss.rowCount + 1
+		for j := range w.sums[i+1] {
+			w.sums[i+1][j] = 0
+		}
+	}
+	if err := w.sf.SetOutlineLevel(len(w.groupColumns) + 1); err != nil {
+		return err
+	}
+	if err := w.sf.WriteTyped(cells); err != nil {
+		return err
+	}
+	for j, col := range w.sumColumns {
+		cell := cells[w.ss.dataIndex(col)]
+		if cell.Type != CellTypeNumeric || cell.Null || cell.Formula != "" {
+			continue
+		}
+		if value, err := parseFloat(cell.Value); err == nil {
+			if unit := w.ss.unit(col); unit != nil {
+				// The formulas sum the values in the unit shown.
+				value /= unit.Divisor
+			}
+			for i := range w.sums {
+				w.sums[i][j] += value
+			}
+		}
+	}
+	for _, col := range append(append([]int(nil), w.groupColumns...), w.sumColumns...) {
+		w.styleIds[col] = cells[w.ss.dataIndex(col)].StyleId
+	}
+	w.rows++
+	return nil
+}
+
+// Close writes the subtotal rows of the last groups and the grand total
+// row, if any row was written, and sets the outline level of the rows
+// written to the sheet after them back to 0.  The StreamFile itself
+// stays open.
+func (w *SubtotalWriter) Close() error {
+	if err := w.check(); err != nil {
+		return err
+	}
+	w.closed = true
+	if w.rows > 0 {
+		if err := w.writeSubtotals(0); err != nil {
+			return err
+		}
+		if err := w.writeSubtotal(-1); err != nil {
+			return err
+		}
+	}
+	return w.sf.SetOutlineLevel(0)
+}
+
+// check returns an error if the SubtotalWriter can no longer write rows.
+func (w *SubtotalWriter) check() error {
+	if w.closed {
+		return fmt.Errorf("the subtotal writer is closed")
+	}
+	if w.sf.err != nil {
+		return w.sf.err
+	}
+	if w.sf.currentSheet != w.ss {
+		return fmt.Errorf("the current sheet is no longer '%s'", w.sf.xlsxFile.Sheets[w.ss.index-1].Name)
+	}
+	return nil
+}
+
+// writeSubtotals writes the subtotal rows of the open groups of the group
+// columns from level on, the innermost first.
+func (w *SubtotalWriter) writeSubtotals(level int) error {
+	if w.rows == 0 {
+		return nil
+	}
+	for i := len(w.groupColumns) - 1; i >= level; i-- {
+		if err := w.writeSubtotal(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSubtotal writes the subtotal row of the group of the group column
+// at index i, or the grand total row if i is -1, outlined at its level.
+func (w *SubtotalWriter) writeSubtotal(i int) error {
+	labelCol, label := w.groupColumns[0], grandTotalLabel
+	if i >= 0 {
+		labelCol, label = w.groupColumns[i], w.keys[i]+" Total"
+	}
+	start, end := strconv.Itoa(w.starts[i+1]), strconv.Itoa(w.ss.rowCount)
+	cells := map[int]StreamCell{labelCol: NewStringStreamCell(label).WithStyle(w.styleIds[labelCol])}
+	for j, col := range w.sumColumns {
+		letters := ColIndexToLetters(col)
+		cells[col] = StreamCell{
+			Value:   strconv.FormatFloat(w.sums[i+1][j], 'f', -1, 64),
+			Type:    CellTypeNumeric,
+			StyleId: w.styleIds[col],
+			Formula: fmt.Sprintf("SUBTOTAL(%d,%s%s:%s%s)", subtotalSum, letters, start, letters, end),
+		}
+	}
+	if err := w.sf.SetOutlineLevel(i + 1); err != nil {
+		return err
+	}
+	return w.sf.WriteSparse(cells)
+}
@@ -1,11 +1,27 @@
 package xlsx
 
 import (
+	"archive/zip"
 	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	"image/png"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"testing"
+	"time"
 
 	. "gopkg.in/check.v1"
 )
@@ -485,3 +501,2997 @@ func (s *StreamSuite) TestCloseWithNothingWrittenToSheets(t *C) {
 		t.Fatal("Expected workbook data to be equal")
 	}
 }
+
+func (s *StreamSuite) TestCellOpeningsMatchCellReferences(t *C) {
+	sheet := &streamSheet{columnCount: 60, styleIds: []int{0, 3}}
+	sheet.makeCellOpenings(false, false)
+	for colIndex := 0; colIndex < sheet.columnCount; colIndex++ {
+		opening := sheet.cellOpenings[colIndex] + "12" + sheet.cellOpeningEnds[colIndex]
+		expected := `<c r="` + GetCellIDStringFromCoords(colIndex, 11) + `" t="inlineStr"`
+		if colIndex == 1 {
+			expected += ` s="3"`
+		}
+		t.Assert(opening, Equals, expected+`><is><t>`)
+	}
+}
+
+func (s *StreamSuite) TestOmitCellReferences(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.SetOmitCellReferences(true), IsNil)
+	t.Assert(builder.AddSheet("Sheet1", []string{"Token", "Name", "Price"}, nil), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(builder.SetOmitCellReferences(false), Equals, BuiltStreamFileBuilderError)
+	t.Assert(stream.Write([]string{"123", "Taco", "300"}), IsNil)
+	t.Assert(stream.Write([]string{"456", "Salsa", "200"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+
+	bufReader := bytes.NewReader(buffer.Bytes())
+	zipReader, err := zip.NewReader(bufReader, bufReader.Size())
+	t.Assert(err, IsNil)
+	for _, zipFile := range zipReader.File {
+		if zipFile.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		reader, err := zipFile.Open()
+		t.Assert(err, IsNil)
+		sheetXML, err := ioutil.ReadAll(reader)
+		t.Assert(err, IsNil)
+		t.Assert(strings.Contains(string(sheetXML), `<row><c t="inlineStr"><is><t>123</t>`), Equals, true)
+		t.Assert(strings.Contains(string(sheetXML), `r="A2"`), Equals, false)
+	}
+
+	_, workbookData := readXLSXFile(t, "", bufReader, bufReader.Size(), false)
+	t.Assert(workbookData, DeepEquals, [][][]string{{
+		{"Token", "Name", "Price"},
+		{"123", "Taco", "300"},
+		{"456", "Salsa", "200"},
+	}})
+}
+
+func (s *StreamSuite) TestAddSheetWithDuplicateNameInOtherCase(t *C) {
+	file := NewStreamFileBuilder(bytes.NewBuffer(nil))
+	t.Assert(file.AddSheet("Sheet1", []string{"Header"}, nil), IsNil)
+	t.Assert(file.AddSheet("SHEET1", []string{"Header"}, nil), ErrorMatches, "duplicate sheet name 'SHEET1'.")
+
+	file = NewStreamFileBuilder(bytes.NewBuffer(nil))
+	t.Assert(file.SetRenameDuplicateSheets(true), IsNil)
+	t.Assert(file.AddSheet("Sheet1", []string{"Header"}, nil), IsNil)
+	t.Assert(file.AddSheet("SHEET1", []string{"Header"}, nil), IsNil)
+	t.Assert(file.xlsxFile.Sheets[1].Name, Equals, "SHEET1 (2)")
+}
+
+func (s *StreamSuite) TestColumnStyleAndBandedRows(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Sheet1", []string{"Name", "Amount"}, []*CellType{nil, CellTypeNumeric.Ptr()}), IsNil)
+	bold := NewStyle()
+	bold.Font.Bold = true
+	bold.ApplyFont = true
+	t.Assert(builder.SetColumnStyle(0, 0, bold), IsNil)
+	t.Assert(builder.SetColumnStyle(0, 2, bold), ErrorMatches, "no column at index 2 in sheet 'Sheet1'")
+	t.Assert(builder.SetBandedRows(1, "FFDDEBF7"), ErrorMatches, "no sheet at index 1")
+	t.Assert(builder.SetBandedRows(0, "FFDDEBF7"), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(builder.SetBandedRows(0, "FFDDEBF7"), Equals, BuiltStreamFileBuilderError)
+	for _, row := range [][]string{{"Taco", "300"}, {"Salsa", "200"}, {"Chips", "100"}} {
+		t.Assert(stream.Write(row), IsNil)
+	}
+	t.Assert(stream.Close(), IsNil)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	for _, y := range []int{1, 3} {
+		t.Assert(sheet.Cell(y, 0).GetStyle().Font.Bold, Equals, true)
+		t.Assert(sheet.Cell(y, 0).GetStyle().Fill.FgColor, Not(Equals), "FFDDEBF7")
+		t.Assert(sheet.Cell(y, 1).GetStyle().Fill.FgColor, Not(Equals), "FFDDEBF7")
+	}
+	t.Assert(sheet.Cell(2, 0).GetStyle().Font.Bold, Equals, true)
+	t.Assert(sheet.Cell(2, 0).GetStyle().Fill.FgColor, Equals, "FFDDEBF7")
+	t.Assert(sheet.Cell(2, 1).GetStyle().Fill.FgColor, Equals, "FFDDEBF7")
+	t.Assert(sheet.Cell(2, 1).GetNumberFormat(), Equals, sheet.Cell(1, 1).GetNumberFormat())
+}
+
+func (s *StreamSuite) TestGoogleSheetsCompatible(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.SetOmitCellReferences(true), IsNil)
+	t.Assert(builder.SetGoogleSheetsCompatible(true), IsNil)
+	t.Assert(builder.AddSheet("Sheet1", []string{"Token", "Name"}, nil), IsNil)
+	t.Assert(builder.AddSheet("Sheet2", []string{"Name"}, nil), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(builder.SetGoogleSheetsCompatible(false), Equals, BuiltStreamFileBuilderError)
+	t.Assert(stream.Write([]string{"123", "Taco & Salsa"}), IsNil)
+	t.Assert(stream.Write([]string{"456", "Name"}), IsNil)
+	t.Assert(stream.NextSheet(), IsNil)
+	t.Assert(stream.Write([]string{"Taco & Salsa"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+
+	bufReader := bytes.NewReader(buffer.Bytes())
+	zipReader, err := zip.NewReader(bufReader, bufReader.Size())
+	t.Assert(err, IsNil)
+	parts := make(map[string]string)
+	for _, zipFile := range zipReader.File {
+		reader, err := zipFile.Open()
+		t.Assert(err, IsNil)
+		data, err := ioutil.ReadAll(reader)
+		t.Assert(err, IsNil)
+		_, exists := parts[zipFile.Name]
+		t.Assert(exists, Equals, false)
+		parts[zipFile.Name] = string(data)
+	}
+	t.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2" t="s"><v>3</v></c></row>`), Equals, true)
+	t.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], "inlineStr"), Equals, false)
+	t.Assert(strings.Contains(parts["xl/sharedStrings.xml"], `count="5"`), Equals, true)
+
+	_, workbookData := readXLSXFile(t, "", bufReader, bufReader.Size(), false)
+	t.Assert(workbookData, DeepEquals, [][][]string{
+		{{"Token", "Name"}, {"123", "Taco & Salsa"}, {"456", "Name"}},
+		{{"Name"}, {"Taco & Salsa"}},
+	})
+}
+
+func (s *StreamSuite) TestRowHook(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("People", []string{"Name", "Email"}, nil), IsNil)
+	t.Assert(builder.AddSheet("Totals", []string{"Total"}, nil), IsNil)
+	var audited []string
+	t.Assert(builder.SetRowHook(func(sheet string, headers, cells []string) ([]string, error) {
+		audited = append(audited, sheet+": "+strings.Join(cells, ","))
+		if sheet != "People" {
+			return cells, nil
+		}
+		if cells[0] == "" {
+			return nil, SkipRow
+		}
+		for i, header := range headers {
+			if header == "Email" {
+				cells[i] = "redacted"
+			}
+		}
+		return cells, nil
+	}), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(builder.SetRowHook(nil), Equals, BuiltStreamFileBuilderError)
+	t.Assert(stream.Write([]string{"Jane", "jane@example.com"}), IsNil)
+	t.Assert(stream.Write([]string{"", "nobody@example.com"}), IsNil)
+	t.Assert(stream.Write([]string{"John", "john@example.com"}), IsNil)
+	t.Assert(stream.NextSheet(), IsNil)
+	t.Assert(stream.Write([]string{"2"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(audited, DeepEquals, []string{
+		"People: Jane,jane@example.com",
+		"People: ,nobody@example.com",
+		"People: John,john@example.com",
+		"Totals: 2",
+	})
+
+	bufReader := bytes.NewReader(buffer.Bytes())
+	_, workbookData := readXLSXFile(t, "", bufReader, bufReader.Size(), false)
+	t.Assert(workbookData, DeepEquals, [][][]string{
+		{{"Name", "Email"}, {"Jane", "redacted"}, {"John", "redacted"}},
+		{{"Total"}, {"2"}},
+	})
+}
+
+func (s *StreamSuite) TestAddRelationship(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Sheet1", []string{"Name"}, nil), IsNil)
+	t.Assert(builder.AddSheet("Sheet2", []string{"Name"}, nil), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"Jane"}), IsNil)
+	id, err := stream.AddRelationship(relationshipTypeHyperlink, "https://example.com/", true)
+	t.Assert(err, IsNil)
+	t.Assert(id, Equals, "rId1")
+	id, err = stream.AddRelationship("http://schemas.openxmlformats.org/officeDocument/2006/relationships/drawing", "../drawings/drawing1.xml", false)
+	t.Assert(err, IsNil)
+	t.Assert(id, Equals, "rId2")
+	t.Assert(stream.AddPart("xl/drawings/drawing1.xml", []byte("<xdr:wsDr/>"), "application/vnd.openxmlformats-officedocument.drawing+xml"), IsNil)
+	t.Assert(stream.AddPart("xl/drawings/drawing1.xml", nil, ""), NotNil)
+	t.Assert(stream.AddPart("xl/workbook.xml", nil, ""), NotNil)
+	t.Assert(stream.NextSheet(), IsNil)
+	t.Assert(stream.Write([]string{"John"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+
+	bufReader := bytes.NewReader(buffer.Bytes())
+	zipReader, err := zip.NewReader(bufReader, bufReader.Size())
+	t.Assert(err, IsNil)
+	var names []string
+	parts := make(map[string]string)
+	for _, zipFile := range zipReader.File {
+		reader, err := zipFile.Open()
+		t.Assert(err, IsNil)
+		data, err := ioutil.ReadAll(reader)
+		t.Assert(err, IsNil)
+		names = append(names, zipFile.Name)
+		parts[zipFile.Name] = string(data)
+	}
+	// The relationships and parts of the first sheet follow it.
+	index := 0
+	for names[index] != "xl/worksheets/sheet1.xml" {
+		index++
+	}
+	t.Assert(names[index+1:index+4], DeepEquals, []string{"xl/worksheets/_rels/sheet1.xml.rels", "xl/drawings/drawing1.xml", "xl/worksheets/sheet2.xml"})
+	t.Assert(names[len(names)-1], Equals, "[Content_Types].xml")
+	t.Assert(strings.Contains(parts["xl/worksheets/_rels/sheet1.xml.rels"], `<Relationship Id="rId1" Target="https://example.com/" Type="`+relationshipTypeHyperlink+`" TargetMode="External">`), Equals, true)
+	t.Assert(strings.Contains(parts["[Content_Types].xml"], `<Override PartName="/xl/drawings/drawing1.xml" ContentType="application/vnd.openxmlformats-officedocument.drawing+xml">`), Equals, true)
+	_, ok := parts["xl/worksheets/_rels/sheet2.xml.rels"]
+	t.Assert(ok, Equals, false)
+
+	_, workbookData := readXLSXFile(t, "", bufReader, bufReader.Size(), false)
+	t.Assert(workbookData, DeepEquals, [][][]string{{{"Name"}, {"Jane"}}, {{"Name"}, {"John"}}})
+}
+
+func (s *StreamSuite) TestPartHook(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Sheet1", []string{"Name"}, nil), IsNil)
+	var paths []string
+	t.Assert(builder.SetPartHook(func(path string, data []byte) ([]byte, error) {
+		paths = append(paths, path)
+		if path == "xl/workbook.xml" {
+			return bytes.Replace(data, []byte(`<calcPr`), []byte(`<calcPr forceFullCalc="true"`), 1), nil
+		}
+		return data, nil
+	}), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(builder.SetPartHook(nil), Equals, BuiltStreamFileBuilderError)
+	t.Assert(stream.Write([]string{"Jane"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	sort.Strings(paths)
+	t.Assert(paths, DeepEquals, []string{
+		"[Content_Types].xml",
+		"_rels/.rels",
+		"docProps/app.xml",
+		"docProps/core.xml",
+		"xl/_rels/workbook.xml.rels",
+		"xl/sharedStrings.xml",
+		"xl/styles.xml",
+		"xl/theme/theme1.xml",
+		"xl/workbook.xml",
+	})
+
+	bufReader := bytes.NewReader(buffer.Bytes())
+	zipReader, err := zip.NewReader(bufReader, bufReader.Size())
+	t.Assert(err, IsNil)
+	for _, zipFile := range zipReader.File {
+		if zipFile.Name != "xl/workbook.xml" {
+			continue
+		}
+		reader, err := zipFile.Open()
+		t.Assert(err, IsNil)
+		data, err := ioutil.ReadAll(reader)
+		t.Assert(err, IsNil)
+		t.Assert(strings.Contains(string(data), `<calcPr forceFullCalc="true"`), Equals, true)
+	}
+
+	hookErr := errors.New("unexpected part")
+	builder = NewStreamFileBuilder(bytes.NewBuffer(nil))
+	t.Assert(builder.AddSheet("Sheet1", []string{"Name"}, nil), IsNil)
+	t.Assert(builder.SetPartHook(func(path string, data []byte) ([]byte, error) {
+		return nil, hookErr
+	}), IsNil)
+	_, err = builder.Build()
+	t.Assert(err, Equals, hookErr)
+}
+
+func (s *StreamSuite) TestVerifyStreamedFile(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.SetOmitCellReferences(true), IsNil)
+	t.Assert(builder.AddSheet("People", []string{"Name", "Email"}, nil), IsNil)
+	t.Assert(builder.AddSheet("Totals", []string{"Total"}, nil), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.RowCounts(), DeepEquals, []int{1, 0})
+	t.Assert(stream.Write([]string{"Jane", "jane@example.com"}), IsNil)
+	t.Assert(stream.Write([]string{"John", ""}), IsNil)
+	t.Assert(stream.RowCounts(), DeepEquals, []int{3, 0})
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(stream.RowCounts(), DeepEquals, []int{3, 1})
+
+	reader := bytes.NewReader(buffer.Bytes())
+	t.Assert(VerifyStreamedFile(reader, reader.Size(), stream), IsNil)
+
+	stream.rowCounts[0] = 4
+	t.Assert(VerifyStreamedFile(reader, reader.Size(), stream), ErrorMatches, `the sheet "People" of the streamed file has 3 rows instead of 4`)
+	stream.rowCounts[0] = 3
+	stream.xlsxFile.Sheets[1].Rows[0].Cells[0].Value = "Sum"
+	t.Assert(VerifyStreamedFile(reader, reader.Size(), stream), ErrorMatches, `the header of column A of the sheet "Totals" of the streamed file is "Total" instead of "Sum"`)
+	stream.xlsxFile.Sheets[1].Name = "Sums"
+	t.Assert(VerifyStreamedFile(reader, reader.Size(), stream), ErrorMatches, `sheet 2 of the streamed file is called "Totals" instead of "Sums"`)
+	truncated := bytes.NewReader(buffer.Bytes()[:100])
+	t.Assert(VerifyStreamedFile(truncated, truncated.Size(), stream), ErrorMatches, `the streamed file can't be read: .*`)
+}
+
+func (s *StreamSuite) TestAddSheetAfterBuild(t *C) {
+	for _, googleSheets := range []bool{false, true} {
+		buffer := bytes.NewBuffer(nil)
+		builder := NewStreamFileBuilder(buffer)
+		t.Assert(builder.SetGoogleSheetsCompatible(googleSheets), IsNil)
+		t.Assert(builder.AddSheet("Orders", []string{"Item", "Quantity"}, nil), IsNil)
+		stream, err := builder.Build()
+		t.Assert(err, IsNil)
+		t.Assert(stream.Write([]string{"Pens", "3"}), IsNil)
+		t.Assert(stream.AddSheet("Orders", []string{"Item"}), ErrorMatches, "duplicate sheet name 'Orders'.")
+		t.Assert(stream.AddSheet("ORDERS", []string{"Item"}), ErrorMatches, "duplicate sheet name 'ORDERS'.")
+		t.Assert(stream.AddSheet("Errors & Notes", []string{"Row", "Error"}), IsNil)
+		t.Assert(stream.AddSheet("Empty", nil), IsNil)
+		t.Assert(stream.RowCounts(), DeepEquals, []int{2, 0, 0})
+		t.Assert(stream.Write([]string{"Ink", "1"}), IsNil)
+		t.Assert(stream.NextSheet(), IsNil)
+		t.Assert(stream.Write([]string{"3", "unknown item"}), IsNil)
+		t.Assert(stream.Write([]string{"too few"}), Equals, WrongNumberOfRowsError)
+		stream.err = nil
+		t.Assert(stream.Close(), IsNil)
+		t.Assert(stream.RowCounts(), DeepEquals, []int{3, 2, 0})
+		t.Assert(VerifyStreamedFile(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()), stream), IsNil)
+
+		f, err := OpenBinary(buffer.Bytes())
+		t.Assert(err, IsNil)
+		t.Assert(len(f.Sheets), Equals, 3)
+		t.Assert(f.Sheets[1].Name, Equals, "Errors & Notes")
+		t.Assert(f.Sheets[1].Cell(0, 1).Value, Equals, "Error")
+		t.Assert(f.Sheets[1].Cell(1, 1).Value, Equals, "unknown item")
+		t.Assert(f.Sheets[2].Name, Equals, "Empty")
+		t.Assert(len(f.Sheets[2].Rows), Equals, 0)
+	}
+}
+
+func (s *StreamSuite) TestSetColumnMask(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheetWithTypes("People", []string{"Name", "Phone", "SSN"},
+		[]ColumnType{ColumnTypeString, ColumnTypeString, ColumnTypeInt}), IsNil)
+	t.Assert(builder.SetColumnMask(1, 0, MaskDigits(4)), ErrorMatches, "no sheet at index 1")
+	t.Assert(builder.SetColumnMask(0, 3, MaskDigits(4)), ErrorMatches, "no column at index 3 in sheet 'People'")
+	t.Assert(builder.SetColumnMask(0, 1, MaskDigits(4)), IsNil)
+	t.Assert(builder.SetColumnMask(0, 2, MaskDigits(4)), IsNil)
+	seen := []string{}
+	t.Assert(builder.SetRowHook(func(sheet string, headers, cells []string) ([]string, error) {
+		seen = append(seen, cells[1])
+		return cells, nil
+	}), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"Ann", "(202) 555-0123", "123456789"}), IsNil)
+	t.Assert(stream.WriteTyped([]StreamCell{NewStringStreamCell("Bob"), NewStringStreamCell("555-0199"), NewIntegerStreamCell(987654321)}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(builder.SetColumnMask(0, 1, nil), Equals, BuiltStreamFileBuilderError)
+	// The RowHook sees the values before they are masked.
+	t.Assert(seen, DeepEquals, []string{"(202) 555-0123", "555-0199"})
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	sheets, err := f.ToSlice()
+	t.Assert(err, IsNil)
+	t.Assert(sheets[0], DeepEquals, [][]string{
+		{"Name", "Phone", "SSN"},
+		{"Ann", "(***) ***-0123", "*****6789"},
+		{"Bob", "***-0199", "*****4321"},
+	})
+	t.Assert(f.Sheets[0].Cell(1, 2).Type(), Equals, CellTypeInline)
+}
+
+func (s *StreamSuite) TestSetNumberFormatsPerColumn(t *C) {
+	write := func(perColumn bool) (map[string]string, *File) {
+		buffer := bytes.NewBuffer(nil)
+		builder := NewStreamFileBuilder(buffer)
+		t.Assert(builder.AddSheetWithTypes("Orders", []string{"Date", "Amount", "Client"},
+			[]ColumnType{ColumnTypeDate, ColumnTypeFloat, ColumnTypeString}), IsNil)
+		bold := NewStyle()
+		bold.Font.Bold = true
+		t.Assert(builder.SetColumnStyle(0, 2, bold), IsNil)
+		t.Assert(builder.SetNumberFormatsPerColumn(perColumn), IsNil)
+		stream, err := builder.Build()
+		t.Assert(err, IsNil)
+		t.Assert(stream.Write([]string{"2024-03-15", "12.5", "Acme"}), IsNil)
+		t.Assert(stream.Close(), IsNil)
+		t.Assert(builder.SetNumberFormatsPerColumn(perColumn), Equals, BuiltStreamFileBuilderError)
+		_, parts := readOptionsParts(t, buffer.Bytes())
+		f, err := OpenBinary(buffer.Bytes())
+		t.Assert(err, IsNil)
+		return parts, f
+	}
+	styleOf := func(sheetXML, element string) string {
+		match := regexp.MustCompile(element + `[^>]*? s(?:tyle)?="(\d+)"`).FindStringSubmatch(sheetXML)
+		if match == nil {
+			return "0"
+		}
+		return match[1]
+	}
+
+	perCell, _ := write(false)
+	perCellXML := perCell["xl/worksheets/sheet1.xml"]
+	t.Assert(styleOf(perCellXML, `<col [^>]*?min="1"`) == styleOf(perCellXML, `<c r="A2"`), Equals, false)
+	parts, f := write(true)
+	sheetXML := parts["xl/worksheets/sheet1.xml"]
+	t.Assert(styleOf(sheetXML, `<col [^>]*?min="1"`), Equals, styleOf(sheetXML, `<c r="A2"`))
+	t.Assert(styleOf(sheetXML, `<col [^>]*?min="3"`), Equals, styleOf(sheetXML, `<c r="C2"`))
+	// The headers keep their style.
+	t.Assert(styleOf(sheetXML, `<c r="A1"`), Equals, styleOf(sheetXML, `<c r="C1"`))
+	sheet := f.Sheets[0]
+	t.Assert(sheet.Cell(0, 0).NumFmt, Equals, "general")
+	t.Assert(sheet.Cell(1, 0).NumFmt, Equals, DefaultDateFormat)
+	t.Assert(sheet.Cell(1, 2).GetStyle().Font.Bold, Equals, true)
+	t.Assert(sheet.Cell(0, 2).GetStyle().Font.Bold, Equals, false)
+}
+
+func (s *StreamSuite) TestSetColumnUnit(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Files", []string{"Name", "Size", "Load time"}, nil), IsNil)
+	t.Assert(builder.SetColumnUnit(1, 1, BytesAsMegabytes), ErrorMatches, "no sheet at index 1")
+	t.Assert(builder.SetColumnUnit(0, 3, BytesAsMegabytes), ErrorMatches, "no column at index 3 in sheet 'Files'")
+	t.Assert(builder.SetColumnUnit(0, 1, Unit{Format: "0"}), ErrorMatches, "invalid unit divisor 0")
+	t.Assert(builder.SetColumnUnit(0, 1, BytesAsMegabytes), IsNil)
+	t.Assert(builder.SetColumnUnit(0, 2, MillisecondsAsSeconds), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"a.iso", "3145728", "1500"}), IsNil)
+	t.Assert(stream.Write([]string{"b.iso", "unknown", "250"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(builder.SetColumnUnit(0, 1, BytesAsMegabytes), Equals, BuiltStreamFileBuilderError)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	t.Assert(sheet.Cell(1, 1).Value, Equals, "3")
+	t.Assert(sheet.Cell(1, 1).Type(), Equals, CellTypeNumeric)
+	t.Assert(sheet.Cell(1, 1).NumFmt, Equals, `#,##0.0 "MB"`)
+	t.Assert(sheet.Cell(1, 2).Value, Equals, "1.5")
+	t.Assert(sheet.Cell(2, 2).Value, Equals, "0.25")
+	t.Assert(sheet.Cell(2, 2).NumFmt, Equals, `#,##0.000 "s"`)
+	// The values that aren't numbers are written as they are.
+	t.Assert(sheet.Cell(2, 1).Value, Equals, "unknown")
+	t.Assert(sheet.Cell(2, 1).Type(), Equals, CellTypeInline)
+	t.Assert(sheet.Cell(0, 1).Value, Equals, "Size")
+}
+
+func (s *StreamSuite) TestValidations(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Orders", []string{"Item", "Quantity", "Color", "Size"}, nil), IsNil)
+	t.Assert(builder.AddSheet("Returns", []string{"Item", "Color"}, nil), IsNil)
+	quantity := NewXlsxCellDataValidation(true)
+	t.Assert(quantity.SetRange(1, 100, DataValidationTypeWhole, DataValidationOperatorBetween), IsNil)
+	t.Assert(builder.AddValidationRange(2, 0, 0, 1, -1, quantity), ErrorMatches, "no sheet at index 2")
+	t.Assert(builder.AddValidationRange(0, 1, 4, 1, -1, quantity), ErrorMatches, "no column at index 4 in sheet 'Orders'")
+	t.Assert(builder.AddValidationRange(0, 1, 1, 5, 2, quantity), ErrorMatches, "invalid range of cells .*")
+	t.Assert(builder.AddValidationRange(0, 1, 1, 1, 10, quantity), IsNil)
+	colors := []string{"Red", "Green", "Blue"}
+	t.Assert(builder.AddListValidation(0, 2, "Lists", nil), ErrorMatches, "a list validation needs values")
+	t.Assert(builder.AddListValidation(0, 2, "Lists", colors), IsNil)
+	t.Assert(builder.AddListValidation(1, 1, "Lists", colors), IsNil)
+	t.Assert(builder.AddListValidation(0, 3, "Lists", []string{"S", "M"}), ErrorMatches, "the list sheet 'Lists' already holds other values")
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"Pens", "3", "Red", "M"}), IsNil)
+	t.Assert(stream.NextSheet(), IsNil)
+	t.Assert(stream.Write([]string{"Ink", "Blue"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(builder.AddListValidation(0, 2, "Lists", colors), Equals, BuiltStreamFileBuilderError)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	t.Assert(f.Sheets, HasLen, 3)
+	lists := f.Sheets[2]
+	t.Assert(lists.Name, Equals, "Lists")
+	t.Assert(lists.Hidden, Equals, true)
+	sheets, err := f.ToSlice()
+	t.Assert(err, IsNil)
+	t.Assert(sheets[2], DeepEquals, [][]string{{"Red"}, {"Green"}, {"Blue"}})
+
+	zipReader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	t.Assert(err, IsNil)
+	parts := map[string]string{}
+	for _, part := range zipReader.File {
+		rc, err := part.Open()
+		t.Assert(err, IsNil)
+		data, err := ioutil.ReadAll(rc)
+		t.Assert(err, IsNil)
+		parts[part.Name] = string(data)
+	}
+	orders := parts["xl/worksheets/sheet1.xml"]
+	t.Assert(strings.Contains(orders, `sqref="B2:B11"`), Equals, true)
+	t.Assert(strings.Contains(orders, `<formula1>1</formula1><formula2>100</formula2>`), Equals, true)
+	t.Assert(strings.Contains(orders, `sqref="C2:C1048576"><formula1>&#39;Lists&#39;!$A$1:$A$3</formula1>`), Equals, true)
+	t.Assert(strings.Contains(parts["xl/worksheets/sheet2.xml"], `sqref="B2:B1048576"`), Equals, true)
+	t.Assert(strings.Contains(parts["xl/workbook.xml"], `<sheet name="Lists" sheetId="3" r:id="rId3" state="hidden">`), Equals, true)
+}
+
+func (s *StreamSuite) TestSetThrottle(t *C) {
+	builder := NewStreamFileBuilder(bytes.NewBuffer(nil))
+	t.Assert(builder.SetThrottle(StreamThrottle{RowsPerSecond: -1}), ErrorMatches, "the limits of a throttle can't be negative")
+
+	buffer := bytes.NewBuffer(nil)
+	builder = NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Rows", []string{"Id"}, nil), IsNil)
+	t.Assert(builder.SetThrottle(StreamThrottle{RowsPerSecond: 200, BytesPerSecond: 1 << 20}), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	start := time.Now()
+	for i := 0; i < 21; i++ {
+		t.Assert(stream.Write([]string{strconv.Itoa(i)}), IsNil)
+	}
+	// Each row takes at least 5ms.
+	t.Assert(time.Since(start) >= 100*time.Millisecond, Equals, true)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(builder.SetThrottle(StreamThrottle{}), Equals, BuiltStreamFileBuilderError)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	t.Assert(f.Sheets[0].MaxRow, Equals, 22)
+}
+
+func (s *StreamSuite) TestMergeCells(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Report", []string{"Region", "", "Q1", "Q2"}, nil), IsNil)
+	t.Assert(builder.AddSheet("Notes", []string{"Note", "Author"}, nil), IsNil)
+	// The header cells merged before the file is built are kept.
+	builder.xlsxFile.Sheets[0].Cell(0, 0).Merge(1, 0)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"Sales", "", "", ""}), IsNil)
+	t.Assert(stream.MergeCells(0, 1, 3, 1), IsNil)
+	t.Assert(stream.MergeCells(0, 2, 0, 3), IsNil)
+	t.Assert(stream.MergeCells(1, 1, 2, 2), ErrorMatches, "the range B2:C3 overlaps the range A2:D2")
+	t.Assert(stream.MergeCells(2, 2, 4, 2), ErrorMatches, "the range C3:E3 is outside of sheet 'Report'")
+	t.Assert(stream.MergeCells(1, 2, 1, 2), ErrorMatches, "the range of B3 is a single cell")
+	t.Assert(stream.MergeCells(2, 2, 1, 2), ErrorMatches, "invalid range of cells .*")
+	t.Assert(stream.Write([]string{"North", "", "10", "12"}), IsNil)
+	t.Assert(stream.Write([]string{"", "", "11", "13"}), IsNil)
+	t.Assert(stream.NextSheet(), IsNil)
+	t.Assert(stream.Write([]string{"Estimated", "Ann"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	t.Assert(sheet.Cell(0, 0).HMerge, Equals, 1)
+	t.Assert(sheet.Cell(1, 0).HMerge, Equals, 3)
+	t.Assert(sheet.Cell(2, 0).VMerge, Equals, 1)
+	t.Assert(sheet.Cell(2, 2).HMerge, Equals, 0)
+	t.Assert(f.Sheets[1].Cell(0, 0).HMerge, Equals, 0)
+}
+
+func (s *StreamSuite) TestFormulaCells(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Sales", []string{"Region", "Amount", "Paid"}, nil), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.WriteTyped([]StreamCell{NewStringStreamCell("North"), NewIntegerStreamCell(3), NewBoolStreamCell(true)}), IsNil)
+	t.Assert(stream.WriteTyped([]StreamCell{NewStringStreamCell("South"), NewIntegerStreamCell(4), NewBoolStreamCell(false)}), IsNil)
+	total := NewFormulaStreamCell("=SUM(B2:B3)")
+	total.Value = "7"
+	paid := NewFormulaStreamCell("=AND(C2:C3)")
+	paid.Value, paid.Type = "0", CellTypeBool
+	label := NewFormulaStreamCell(`="Total "&COUNTA(A2:A3)`)
+	label.Value, label.Type = "Total 2", CellTypeString
+	t.Assert(stream.WriteTyped([]StreamCell{label, total, paid}), IsNil)
+	t.Assert(stream.WriteTyped([]StreamCell{NewStringStreamCell("Average"), NewFormulaStreamCell("=AVERAGE(B2:B3)"), NewBoolStreamCell(false)}), IsNil)
+	bad := NewFormulaStreamCell("=B2")
+	bad.Value = "x"
+	t.Assert(stream.WriteTyped([]StreamCell{NewStringStreamCell("Bad"), bad, NewBoolStreamCell(false)}),
+		ErrorMatches, `cell 1 of the row: "x" isn't a number`)
+	stream.err = nil
+	t.Assert(stream.Close(), IsNil)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	t.Assert(sheet.MaxRow, Equals, 5)
+	t.Assert(sheet.Cell(3, 0).Formula(), Equals, `"Total "&COUNTA(A2:A3)`)
+	t.Assert(sheet.Cell(3, 0).Value, Equals, "Total 2")
+	t.Assert(sheet.Cell(3, 1).Formula(), Equals, "SUM(B2:B3)")
+	t.Assert(sheet.Cell(3, 1).Value, Equals, "7")
+	t.Assert(sheet.Cell(3, 2).Formula(), Equals, "AND(C2:C3)")
+	t.Assert(sheet.Cell(3, 2).Bool(), Equals, false)
+	t.Assert(sheet.Cell(4, 1).Formula(), Equals, "AVERAGE(B2:B3)")
+	t.Assert(sheet.Cell(4, 1).Value, Equals, "")
+	// The formula without a cached value is computed when the file is
+	// opened.
+	t.Assert(f.CalcProperties.FullCalcOnLoad, Equals, true)
+}
+
+func (s *StreamSuite) TestHyperlinkCells(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Audit", []string{"Event", "Total", "Link"}, nil), IsNil)
+	t.Assert(builder.SetColumnFormula(0, 1, "=LEN(A{row})"), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.WriteTyped([]StreamCell{
+		NewStringStreamCell("login"),
+		NewHyperlinkStreamCell("details", "https://example.com/events?id=1"),
+	}), IsNil)
+	t.Assert(stream.WriteTyped([]StreamCell{
+		NewHyperlinkStreamCell("logout", "https://example.com/events?id=1"),
+		NewHyperlinkStreamCell("mail", "mailto:audit@example.com"),
+	}), IsNil)
+	t.Assert(stream.WriteTyped([]StreamCell{NewStringStreamCell("none"), NewHyperlinkStreamCell("bad", "mailto:nobody")}),
+		ErrorMatches, "cell 1 of the row: .*")
+	stream.err = nil
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(VerifyStreamedFile(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()), stream), IsNil)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	t.Assert(sheet.Cell(1, 2).Value, Equals, "details")
+	t.Assert(sheet.Hyperlinks, HasLen, 3)
+	t.Assert(*sheet.Hyperlinks[0], DeepEquals, Hyperlink{Ref: "C2", Target: "https://example.com/events?id=1"})
+	t.Assert(*sheet.Hyperlinks[1], DeepEquals, Hyperlink{Ref: "A3", Target: "https://example.com/events?id=1"})
+	t.Assert(*sheet.Hyperlinks[2], DeepEquals, Hyperlink{Ref: "C3", Target: "mailto:audit@example.com"})
+	// The links to the same target share their relationship.
+	zipReader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	t.Assert(err, IsNil)
+	rels := ""
+	for _, part := range zipReader.File {
+		if part.Name == "xl/worksheets/_rels/sheet1.xml.rels" {
+			rc, err := part.Open()
+			t.Assert(err, IsNil)
+			data, err := ioutil.ReadAll(rc)
+			t.Assert(err, IsNil)
+			rels = string(data)
+		}
+	}
+	t.Assert(strings.Count(rels, relationshipTypeHyperlink), Equals, 2)
+}
+
+func (s *StreamSuite) TestNotes(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Payments", []string{"Payee", "Check", "Amount"}, nil), IsNil)
+	t.Assert(builder.SetColumnFormula(0, 1, "=LEN(A{row})"), IsNil)
+	t.Assert(builder.AddSheet("Refunds", []string{"Payee", "Amount"}, nil), IsNil)
+	currency, err := builder.AddStyle(NewStyle(), AccountingNumberFormat("$", 2, NegativeParentheses))
+	t.Assert(err, IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.WriteTyped([]StreamCell{
+		NewStringStreamCell("Smith & Sons"),
+		NewConvertedStreamCell(1355.83, CurrencyConversion{Amount: 1250.5, Currency: "EUR", Rate: 1.0842}).WithStyle(currency),
+	}), IsNil)
+	t.Assert(stream.WriteTyped([]StreamCell{
+		NewStringStreamCell("Jones").WithNote("Paid <late>"),
+		NewFloatStreamCell(20),
+	}), IsNil)
+	t.Assert(stream.NextSheet(), IsNil)
+	t.Assert(stream.WriteTyped([]StreamCell{NewStringStreamCell("Brown"), NewFloatStreamCell(-5).WithNote("Partial")}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(VerifyStreamedFile(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()), stream), IsNil)
+
+	zipReader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	t.Assert(err, IsNil)
+	parts := map[string]string{}
+	for _, part := range zipReader.File {
+		rc, err := part.Open()
+		t.Assert(err, IsNil)
+		data, err := ioutil.ReadAll(rc)
+		t.Assert(err, IsNil)
+		parts[part.Name] = string(data)
+	}
+	// The notes skip the formula columns.
+	comments := parts["xl/comments1.xml"]
+	t.Assert(strings.Contains(comments, `<comment ref="C2" authorId="0"><text><t xml:space="preserve">Original amount: 1250.5 EUR&#xA;Conversion rate: 1.0842</t></text></comment>`), Equals, true)
+	t.Assert(strings.Contains(comments, `<comment ref="A3" authorId="0"><text><t xml:space="preserve">Paid &lt;late&gt;</t></text></comment>`), Equals, true)
+	t.Assert(strings.Contains(parts["xl/comments2.xml"], `<comment ref="B2" authorId="0">`), Equals, true)
+	// Each drawing has shape ids of its own.
+	t.Assert(strings.Contains(parts["xl/drawings/vmlDrawing1.vml"], `<o:idmap v:ext="edit" data="1"/>`), Equals, true)
+	t.Assert(strings.Contains(parts["xl/drawings/vmlDrawing1.vml"], `<x:Row>2</x:Row><x:Column>0</x:Column>`), Equals, true)
+	t.Assert(strings.Contains(parts["xl/drawings/vmlDrawing2.vml"], `<o:idmap v:ext="edit" data="2"/>`), Equals, true)
+	t.Assert(strings.Contains(parts["xl/drawings/vmlDrawing2.vml"], `id="_x0000_s2049"`), Equals, true)
+	t.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `r:id="rId2"></legacyDrawing></worksheet>`), Equals, true)
+	rels := parts["xl/worksheets/_rels/sheet1.xml.rels"]
+	t.Assert(strings.Contains(rels, `Target="../comments1.xml" Type="`+relationshipTypeComments+`"`), Equals, true)
+	t.Assert(strings.Contains(rels, `Target="../drawings/vmlDrawing1.vml" Type="`+relationshipTypeVMLDrawing+`"`), Equals, true)
+
+	// A sheet of many notes takes several blocks of shape ids.
+	notes := make([]streamNote, 1500)
+	drawing := string(makeStreamNotesDrawing(3, notes))
+	t.Assert(strings.Contains(drawing, `data="3,4"`), Equals, true)
+	t.Assert(strings.Contains(drawing, `id="_x0000_s4572"`), Equals, true)
+
+	// The parts of the notes can't be added by the caller.
+	builder = NewStreamFileBuilder(ioutil.Discard)
+	t.Assert(builder.AddSheet("Fees", []string{"Fee"}, nil), IsNil)
+	stream, err = builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.AddPart("xl/comments1.xml", []byte("<comments/>"), ""), IsNil)
+	t.Assert(stream.WriteTyped([]StreamCell{NewFloatStreamCell(1).WithNote("Waived")}), IsNil)
+	t.Assert(stream.Close(), ErrorMatches, "the part xl/comments1.xml, holding the notes of sheet 'Fees', was already added")
+}
+
+func (s *StreamSuite) TestWriteSparse(t *C) {
+	for _, omit := range []bool{false, true} {
+		buffer := bytes.NewBuffer(nil)
+		builder := NewStreamFileBuilder(buffer)
+		t.Assert(builder.SetOmitCellReferences(omit), IsNil)
+		t.Assert(builder.AddSheet("Readings", []string{"Sensor", "Length", "Morning", "Noon", "Evening"}, nil), IsNil)
+		t.Assert(builder.SetColumnFormula(0, 1, "=LEN(A{row})"), IsNil)
+		stream, err := builder.Build()
+		t.Assert(err, IsNil)
+		t.Assert(stream.WriteSparse(map[int]StreamCell{0: NewStringStreamCell("north"), 3: NewFloatStreamCell(21.5)}), IsNil)
+		t.Assert(stream.WriteSparse(map[int]StreamCell{4: NewFloatStreamCell(18).WithNote("Estimated")}), IsNil)
+		t.Assert(stream.WriteSparse(nil), IsNil)
+		t.Assert(stream.Write([]string{"south", "1", "2", "3"}), IsNil)
+		t.Assert(stream.Close(), IsNil)
+		t.Assert(VerifyStreamedFile(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()), stream), IsNil)
+
+		zipReader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+		t.Assert(err, IsNil)
+		sheetXML := ""
+		for _, part := range zipReader.File {
+			if part.Name == "xl/worksheets/sheet1.xml" {
+				rc, err := part.Open()
+				t.Assert(err, IsNil)
+				data, err := ioutil.ReadAll(rc)
+				t.Assert(err, IsNil)
+				sheetXML = string(data)
+			}
+		}
+		f, err := OpenBinary(buffer.Bytes())
+		t.Assert(err, IsNil)
+		sheet := f.Sheets[0]
+		t.Assert(sheet.Cell(1, 0).Value, Equals, "north")
+		t.Assert(sheet.Cell(1, 1).Formula(), Equals, "LEN(A2)")
+		t.Assert(sheet.Cell(1, 3).Value, Equals, "21.5")
+		t.Assert(sheet.Cell(2, 4).Value, Equals, "18")
+		t.Assert(sheet.Cell(4, 4).Value, Equals, "3")
+		if omit {
+			// The cells left out keep the next cells in their
+			// columns.
+			t.Assert(strings.Contains(sheetXML, `<row><c></c><c><f>LEN(A3)</f></c><c></c><c></c><c><v>18</v></c></row>`), Equals, true)
+		} else {
+			t.Assert(strings.Contains(sheetXML, `<row r="3"><c r="B3"><f>LEN(A3)</f></c><c r="E3"><v>18</v></c></row>`), Equals, true)
+		}
+	}
+
+	builder := NewStreamFileBuilder(ioutil.Discard)
+	t.Assert(builder.AddSheet("Readings", []string{"Sensor", "Length"}, nil), IsNil)
+	t.Assert(builder.SetColumnFormula(0, 1, "=LEN(A{row})"), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.WriteSparse(map[int]StreamCell{2: NewFloatStreamCell(1)}), ErrorMatches, "no column at index 2 in sheet 'Readings'")
+	stream.err = nil
+	t.Assert(stream.WriteSparse(map[int]StreamCell{1: NewFloatStreamCell(1)}), ErrorMatches,
+		"the column at index 1 in sheet 'Readings' holds a formula")
+}
+
+func (s *StreamSuite) TestAppendToExistingFile(t *C) {
+	existing := NewFile()
+	notes, err := existing.AddSheet("Notes")
+	t.Assert(err, IsNil)
+	notes.AddRow().AddCell().SetString("Kept as it is")
+	orders, err := existing.AddSheet("Orders")
+	t.Assert(err, IsNil)
+	for _, values := range [][]string{{"Id", "Item", "Total"}, {"1", "Pen", "2.5"}, {"2", "Ink", "7"}} {
+		row := orders.AddRow()
+		for _, value := range values {
+			row.AddCell().SetValue(value)
+		}
+	}
+	orders.Rows[0].Cells[0].GetStyle().Font.Bold = true
+	var existingBuffer bytes.Buffer
+	t.Assert(existing.Write(&existingBuffer), IsNil)
+
+	buffer := bytes.NewBuffer(nil)
+	builder, err := NewStreamFileBuilderFromExisting(bytes.NewReader(existingBuffer.Bytes()), int64(existingBuffer.Len()), buffer)
+	t.Assert(err, IsNil)
+	t.Assert(builder.AddSheet("orders", []string{"Id"}, nil), ErrorMatches, "duplicate sheet name 'orders'.")
+	builder, err = NewStreamFileBuilderFromExisting(bytes.NewReader(existingBuffer.Bytes()), int64(existingBuffer.Len()), buffer)
+	t.Assert(err, IsNil)
+	t.Assert(builder.AddSheet("Summary", []string{"Orders"}, nil), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.SkipToSheet("Invoices"), ErrorMatches, "no sheet named 'Invoices'")
+	t.Assert(stream.SkipToSheet("Orders"), IsNil)
+	t.Assert(stream.SkipToSheet("Notes"), ErrorMatches, "the sheet 'Notes' was already written")
+	t.Assert(stream.Write([]string{"3", "Nib", "1.25"}), IsNil)
+	t.Assert(stream.Write([]string{"4", "Pad", "3"}), IsNil)
+	t.Assert(stream.Write([]string{"5", "Pad"}), Equals, WrongNumberOfRowsError)
+	stream.err = nil
+	t.Assert(stream.SkipToSheet("Summary"), IsNil)
+	t.Assert(stream.Write([]string{"4"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	t.Assert(len(f.Sheets), Equals, 3)
+	t.Assert(f.Sheets[0].Name, Equals, "Notes")
+	t.Assert(f.Sheets[0].Cell(0, 0).Value, Equals, "Kept as it is")
+	sheet := f.Sheets[1]
+	t.Assert(sheet.MaxRow, Equals, 5)
+	t.Assert(sheet.Cell(0, 0).GetStyle().Font.Bold, Equals, true)
+	t.Assert(sheet.Cell(2, 1).Value, Equals, "Ink")
+	t.Assert(sheet.Cell(3, 0).Value, Equals, "3")
+	t.Assert(sheet.Cell(4, 2).Value, Equals, "3")
+	t.Assert(f.Sheets[2].Cell(1, 0).Value, Equals, "4")
+}
+
+func (s *StreamSuite) TestFillTemplate(t *C) {
+	theme := strings.Replace(TEMPLATE_XL_THEME_THEME, `name="Office-Design"`, `name="Brand"`, 1)
+	parts := map[string]string{
+		"[Content_Types].xml": `<?xml version="1.0" encoding="UTF-8"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Default Extension="png" ContentType="image/png"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/><Override PartName="/xl/theme/theme1.xml" ContentType="application/vnd.openxmlformats-officedocument.theme+xml"/><Override PartName="/xl/drawings/drawing1.xml" ContentType="application/vnd.openxmlformats-officedocument.drawing+xml"/></Types>`,
+		"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Report" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/><Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme" Target="theme/theme1.xml"/></Relationships>`,
+		"xl/styles.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="1"><fill><patternFill patternType="none"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/></border></borders><cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" applyFont="1"/><xf numFmtId="14" fontId="0" fillId="0" borderId="0" applyNumberFormat="1"/></cellXfs></styleSheet>`,
+		"xl/theme/theme1.xml": theme,
+		"xl/worksheets/sheet1.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><dimension ref="A1:B4"/><sheetData>` +
+			`<row r="1"><c r="A1" t="inlineStr"><is><t>Quarterly report</t></is></c></row>` +
+			`<row r="2"><c r="A2" s="1" t="inlineStr"><is><t>Item</t></is></c><c r="B2" s="1" t="inlineStr"><is><t>Shipped</t></is></c></row>` +
+			`<row r="3"><c r="A3"/><c r="B3" s="2"/></row>` +
+			`<row r="4"><c r="A4" t="inlineStr"><is><t>Sample</t></is></c><c r="B4" s="2"><v>44197</v></c></row>` +
+			`</sheetData><drawing r:id="rId1"/></worksheet>`,
+		"xl/worksheets/_rels/sheet1.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/drawing" Target="../drawings/drawing1.xml"/></Relationships>`,
+		"xl/drawings/drawing1.xml": `<xdr:wsDr xmlns:xdr="http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing"></xdr:wsDr>`,
+	}
+	var template bytes.Buffer
+	w := zip.NewWriter(&template)
+	for name, data := range parts {
+		part, err := w.Create(name)
+		t.Assert(err, IsNil)
+		_, err = part.Write([]byte(data))
+		t.Assert(err, IsNil)
+	}
+	t.Assert(w.Close(), IsNil)
+
+	buffer := bytes.NewBuffer(nil)
+	builder, err := NewStreamFileBuilderFromExisting(bytes.NewReader(template.Bytes()), int64(template.Len()), buffer)
+	t.Assert(err, IsNil)
+	t.Assert(builder.SetStartRow(1, 2), ErrorMatches, "no sheet at index 1")
+	t.Assert(builder.SetStartRow(0, -1), ErrorMatches, "invalid start row -1, which can't be negative")
+	t.Assert(builder.SetStartRow(0, 2), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.WriteTyped([]StreamCell{NewStringStreamCell("Pens"), NewIntegerStreamCell(44259)}), IsNil)
+	t.Assert(stream.WriteTyped([]StreamCell{NewStringStreamCell("Ink"), NewIntegerStreamCell(44260)}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+
+	zipReader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	t.Assert(err, IsNil)
+	written := map[string]string{}
+	for _, part := range zipReader.File {
+		rc, err := part.Open()
+		t.Assert(err, IsNil)
+		data, err := ioutil.ReadAll(rc)
+		t.Assert(err, IsNil)
+		written[part.Name] = string(data)
+	}
+	t.Assert(written["xl/theme/theme1.xml"], Equals, theme)
+	t.Assert(written["xl/drawings/drawing1.xml"], Equals, parts["xl/drawings/drawing1.xml"])
+	t.Assert(strings.Contains(written["xl/worksheets/sheet1.xml"], `relationships:id="rId1"></drawing>`), Equals, true)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	t.Assert(sheet.MaxRow, Equals, 4)
+	t.Assert(sheet.Cell(0, 0).Value, Equals, "Quarterly report")
+	t.Assert(sheet.Cell(1, 1).GetStyle().Font.Bold, Equals, true)
+	t.Assert(sheet.Cell(2, 0).Value, Equals, "Pens")
+	t.Assert(sheet.Cell(3, 0).Value, Equals, "Ink")
+	// The streamed dates are formatted like the model row.
+	t.Assert(sheet.Cell(3, 1).GetNumberFormat(), Equals, "mm-dd-yy")
+	date, err := sheet.Cell(3, 1).GetTime(false)
+	t.Assert(err, IsNil)
+	t.Assert(date, Equals, time.Date(2021, 3, 5, 0, 0, 0, 0, time.UTC))
+}
+
+func (s *StreamSuite) TestDocumentProperties(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Orders", []string{"Id"}, nil), IsNil)
+	created := time.Date(2021, 3, 4, 9, 30, 0, 0, time.UTC)
+	t.Assert(builder.SetDocumentProperties(DocumentProperties{
+		Title: "Orders & returns", Author: "Exports", Company: "Example Ltd", Created: created,
+	}), IsNil)
+	t.Assert(builder.SetCustomProperty("Classification", "Internal"), IsNil)
+	t.Assert(builder.SetCustomProperty("Retention years", 5), IsNil)
+	t.Assert(builder.SetCustomProperty("Classification", "Confidential"), IsNil)
+	t.Assert(builder.SetCustomProperty("Reviewed", true), IsNil)
+	t.Assert(builder.SetCustomProperty("", "x"), ErrorMatches, "custom properties must have a name")
+	t.Assert(builder.SetCustomProperty("Size", int64(1)), ErrorMatches, "unsupported type int64 of custom property 'Size'")
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"1"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(builder.SetCustomProperty("Late", "x"), Equals, BuiltStreamFileBuilderError)
+
+	zipReader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	t.Assert(err, IsNil)
+	written := map[string]string{}
+	for _, part := range zipReader.File {
+		rc, err := part.Open()
+		t.Assert(err, IsNil)
+		data, err := ioutil.ReadAll(rc)
+		t.Assert(err, IsNil)
+		written[part.Name] = string(data)
+	}
+	t.Assert(strings.Contains(written["docProps/core.xml"], `<dc:title>Orders &amp; returns</dc:title><dc:creator>Exports</dc:creator>`+
+		`<dcterms:created xsi:type="dcterms:W3CDTF">2021-03-04T09:30:00Z</dcterms:created>`), Equals, true)
+	t.Assert(strings.Contains(written["docProps/app.xml"], `<Company>Example Ltd</Company>`), Equals, true)
+	t.Assert(strings.Contains(written["docProps/custom.xml"],
+		`<property fmtid="{D5CDD505-2E9C-101B-9397-08002B2CF9AE}" pid="2" name="Classification"><vt:lpwstr>Confidential</vt:lpwstr></property>`+
+			`<property fmtid="{D5CDD505-2E9C-101B-9397-08002B2CF9AE}" pid="3" name="Retention years"><vt:i4>5</vt:i4></property>`+
+			`<property fmtid="{D5CDD505-2E9C-101B-9397-08002B2CF9AE}" pid="4" name="Reviewed"><vt:bool>true</vt:bool></property>`), Equals, true)
+	t.Assert(strings.Contains(written["_rels/.rels"], `Target="docProps/custom.xml"`), Equals, true)
+	t.Assert(strings.Contains(written["[Content_Types].xml"], `PartName="/docProps/custom.xml"`), Equals, true)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	t.Assert(f.Inspect().Creator, Equals, "Exports")
+}
+
+func (s *StreamSuite) TestProtection(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Form", []string{"Id", "Name", "Answer"}, nil), IsNil)
+	protection := NewSheetProtection()
+	t.Assert(protection.SetPassword("secret"), IsNil)
+	protection.EditableRanges = []string{"C2:C"}
+	t.Assert(builder.SetSheetProtection(0, protection), ErrorMatches, "invalid editable range 'C2:C'")
+	t.Assert(builder.SetSheetProtection(1, protection), ErrorMatches, "no sheet at index 1")
+	protection.EditableRanges = []string{"C2:C1048576"}
+	t.Assert(builder.SetSheetProtection(0, protection), IsNil)
+	t.Assert(builder.SetWorkbookProtection(&WorkbookProtection{LockStructure: true}), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"1", "First", ""}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(builder.SetWorkbookProtection(nil), Equals, BuiltStreamFileBuilderError)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	t.Assert(f.Protection, DeepEquals, &WorkbookProtection{LockStructure: true})
+	sheet := f.Sheet["Form"]
+	t.Assert(sheet.Protection, NotNil)
+	t.Assert(sheet.Protection.EditableRanges, DeepEquals, []string{"C2:C1048576"})
+	ok, err := sheet.Protection.CheckPassword("secret")
+	t.Assert(err, IsNil)
+	t.Assert(ok, Equals, true)
+	t.Assert(sheet.Cell(1, 1).Value, Equals, "First")
+}
+
+func (s *StreamSuite) TestConditionalFormats(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheetWithTypes("Results", []string{"Region", "Sales", "Margin", "Change"},
+		[]ColumnType{ColumnTypeString, ColumnTypeInt, ColumnTypeFloat, ColumnTypeFloat}), IsNil)
+	t.Assert(builder.AddSheet("Empty", []string{"Value"}, nil), IsNil)
+	red := ConditionalStyle{FontColor: "FF9C0006", FillColor: "FFFFC7CE"}
+	t.Assert(builder.AddConditionalFormat(0, 1, 1, NewColorScale("FFF8696B", "FF63BE7B")), IsNil)
+	t.Assert(builder.AddConditionalFormat(0, 2, 2, NewDataBar("FF638EC6")), IsNil)
+	t.Assert(builder.AddConditionalFormat(0, 2, 3, NewCellValueRule(ConditionalLessThan, red, "0")), IsNil)
+	t.Assert(builder.AddConditionalFormat(1, 0, 0, NewCellValueRule(ConditionalEqual, ConditionalStyle{Bold: true}, `"x"`)), IsNil)
+	t.Assert(builder.AddConditionalFormat(0, 3, 4, NewDataBar("FF638EC6")), ErrorMatches, "no column at index 4 in sheet 'Results'")
+	t.Assert(builder.AddConditionalFormat(0, 3, 2, NewDataBar("FF638EC6")), ErrorMatches, "invalid columns from 3 to 2")
+	t.Assert(builder.AddConditionalFormat(0, 1, 1, NewCellValueRule(ConditionalBetween, red, "0")), ErrorMatches,
+		"the conditional operator between takes 2 values, not 1")
+	t.Assert(builder.AddConditionalFormat(0, 1, 1, ConditionalFormat{}), ErrorMatches, "invalid conditional format")
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"North", "120", "0.25", "-3.5"}), IsNil)
+	t.Assert(stream.Write([]string{"South", "80", "-0.1", "2"}), IsNil)
+	t.Assert(stream.MergeCells(0, 1, 0, 2), IsNil)
+	t.Assert(stream.Close(), IsNil)
+
+	zipReader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	t.Assert(err, IsNil)
+	written := map[string]string{}
+	for _, part := range zipReader.File {
+		rc, err := part.Open()
+		t.Assert(err, IsNil)
+		data, err := ioutil.ReadAll(rc)
+		t.Assert(err, IsNil)
+		written[part.Name] = string(data)
+	}
+	t.Assert(strings.Contains(written["xl/worksheets/sheet1.xml"], `</mergeCells>`+
+		`<conditionalFormatting sqref="B2:B3"><cfRule type="colorScale" priority="1"><colorScale><cfvo type="min"/><cfvo type="max"/>`+
+		`<color rgb="FFF8696B"/><color rgb="FF63BE7B"/></colorScale></cfRule></conditionalFormatting>`+
+		`<conditionalFormatting sqref="C2:C3"><cfRule type="dataBar" priority="2"><dataBar><cfvo type="min"/><cfvo type="max"/>`+
+		`<color rgb="FF638EC6"/></dataBar></cfRule></conditionalFormatting>`+
+		`<conditionalFormatting sqref="C2:D3"><cfRule type="cellIs" dxfId="0" priority="3" operator="lessThan"><formula>0</formula>`+
+		`</cfRule></conditionalFormatting>`), Equals, true)
+	// No rows were written to the second sheet.
+	t.Assert(strings.Contains(written["xl/worksheets/sheet2.xml"], `<conditionalFormatting`), Equals, false)
+	t.Assert(strings.Contains(written["xl/styles.xml"], `<dxfs count="2"><dxf><font><color rgb="FF9C0006"/></font>`+
+		`<fill><patternFill><bgColor rgb="FFFFC7CE"/></patternFill></fill></dxf><dxf><font><b/></font></dxf></dxfs></styleSheet>`), Equals, true)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	t.Assert(f.Sheet["Results"].Cell(2, 3).Value, Equals, "2")
+}
+
+func (s *StreamSuite) TestSheetVisibilityTabColorAndOutline(t *C) {
+	builder := NewStreamFileBuilder(ioutil.Discard)
+	t.Assert(builder.AddSheetWithOptions("Hidden", []string{"Id"}, StreamSheetOptions{Visibility: SheetHidden}), IsNil)
+	_, err := builder.Build()
+	t.Assert(err, ErrorMatches, "every sheet of the workbook is hidden, which must show at least one")
+
+	buffer := bytes.NewBuffer(nil)
+	builder = NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheetWithOptions("Sales", []string{"Region", "Total"}, StreamSheetOptions{
+		TabColor: "FF00B050", OutlineLevels: 2, SummaryRowsAbove: true,
+	}), IsNil)
+	t.Assert(builder.AddSheetWithOptions("Workings", []string{"Id"}, StreamSheetOptions{Visibility: SheetHidden}), IsNil)
+	t.Assert(builder.AddSheetWithOptions("Lookup", []string{"Id"}, StreamSheetOptions{Visibility: SheetVeryHidden}), IsNil)
+	t.Assert(builder.AddSheetWithOptions("Other", nil, StreamSheetOptions{OutlineLevels: 8}), ErrorMatches,
+		"invalid outline levels 8, which must be from 0 to 7")
+	t.Assert(builder.AddSheetWithOptions("Other", nil, StreamSheetOptions{Visibility: 3}), ErrorMatches, "unknown sheet visibility 3")
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	for _, row := range []struct {
+		level int
+		cells []string
+	}{{0, []string{"All", "30"}}, {1, []string{"North", "20"}}, {2, []string{"North east", "5"}}, {1, []string{"South", "10"}}} {
+		t.Assert(stream.SetOutlineLevel(row.level), IsNil)
+		t.Assert(stream.Write(row.cells), IsNil)
+	}
+	t.Assert(stream.SetOutlineLevel(3), ErrorMatches, "invalid outline level 3 of sheet 'Sales', which has 2 outline levels")
+	t.Assert(stream.NextSheet(), IsNil)
+	t.Assert(stream.SetOutlineLevel(1), ErrorMatches, "invalid outline level 1 of sheet 'Workings', which has 0 outline levels")
+	t.Assert(stream.Close(), IsNil)
+
+	zipReader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	t.Assert(err, IsNil)
+	written := map[string]string{}
+	for _, part := range zipReader.File {
+		rc, err := part.Open()
+		t.Assert(err, IsNil)
+		data, err := ioutil.ReadAll(rc)
+		t.Assert(err, IsNil)
+		written[part.Name] = string(data)
+	}
+	t.Assert(strings.Contains(written["xl/workbook.xml"], `<sheet name="Workings" sheetId="2" r:id="rId2" state="hidden"></sheet>`+
+		`<sheet name="Lookup" sheetId="3" r:id="rId3" state="veryHidden"></sheet>`), Equals, true)
+	t.Assert(strings.Contains(written["xl/worksheets/sheet1.xml"], `<tabColor rgb="FF00B050"></tabColor>`), Equals, true)
+	t.Assert(strings.Contains(written["xl/worksheets/sheet1.xml"], `outlineLevelRow="2"`), Equals, true)
+	t.Assert(strings.Contains(written["xl/worksheets/sheet1.xml"], `<row r="3" outlineLevel="1">`), Equals, true)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	sales := f.Sheet["Sales"]
+	t.Assert(sales.Properties.TabColor, Equals, "FF00B050")
+	t.Assert(sales.Properties.SummaryRowsAbove, Equals, true)
+	var levels []uint8
+	for _, row := range sales.Rows {
+		levels = append(levels, row.OutlineLevel)
+	}
+	t.Assert(levels, DeepEquals, []uint8{0, 0, 1, 2, 1})
+	t.Assert(f.Sheet["Workings"].Hidden, Equals, true)
+	t.Assert(f.Sheet["Workings"].VeryHidden, Equals, false)
+	t.Assert(f.Sheet["Lookup"].VeryHidden, Equals, true)
+}
+
+func (s *StreamSuite) TestAddImage(t *C) {
+	var logo bytes.Buffer
+	t.Assert(png.Encode(&logo, image.NewRGBA(image.Rect(0, 0, 2, 1))), IsNil)
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Report", []string{"Name", "Total"}, nil), IsNil)
+	t.Assert(builder.AddSheet("Data", []string{"Id"}, nil), IsNil)
+	t.Assert(builder.AddImage("Report", "D1", logo.Bytes(), ImageFormatPNG), IsNil)
+	t.Assert(builder.AddImage("Report", "D4", logo.Bytes(), ImageFormatPNG), IsNil)
+	t.Assert(builder.AddImage("Summary", "A1", logo.Bytes(), ImageFormatPNG), ErrorMatches, "no sheet named 'Summary'")
+	t.Assert(builder.AddImage("Report", "1A", logo.Bytes(), ImageFormatPNG), ErrorMatches, "invalid anchor cell '1A'")
+	t.Assert(builder.AddImage("Report", "A1", logo.Bytes(), "bmp"), ErrorMatches, "unsupported image format 'bmp'")
+	t.Assert(builder.AddImage("Report", "A1", logo.Bytes(), ImageFormatJPEG), ErrorMatches, "the image isn't a jpeg image")
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(builder.AddImage("Report", "A1", logo.Bytes(), ImageFormatPNG), Equals, BuiltStreamFileBuilderError)
+	t.Assert(stream.WriteTyped([]StreamCell{NewStringStreamCell("Jones").WithNote("Paid late"), NewIntegerStreamCell(10)}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+
+	zipReader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	t.Assert(err, IsNil)
+	written := map[string]string{}
+	for _, part := range zipReader.File {
+		rc, err := part.Open()
+		t.Assert(err, IsNil)
+		data, err := ioutil.ReadAll(rc)
+		t.Assert(err, IsNil)
+		written[part.Name] = string(data)
+	}
+	t.Assert(written["xl/media/image1.png"], Equals, logo.String())
+	t.Assert(written["xl/media/image2.png"], Equals, logo.String())
+	drawing := written["xl/drawings/drawing1.xml"]
+	t.Assert(strings.Count(drawing, "<xdr:oneCellAnchor>"), Equals, 2)
+	t.Assert(strings.Contains(drawing, `<xdr:from><xdr:col>3</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>3</xdr:row>`), Equals, true)
+	t.Assert(strings.Contains(drawing, `<xdr:ext cx="19050" cy="9525"/>`), Equals, true)
+	t.Assert(strings.Contains(written["xl/drawings/_rels/drawing1.xml.rels"], `Target="../media/image2.png"`), Equals, true)
+	t.Assert(strings.Contains(written["xl/worksheets/_rels/sheet1.xml.rels"], `Target="../drawings/drawing1.xml"`), Equals, true)
+	t.Assert(strings.Contains(written["[Content_Types].xml"], `ContentType="`+contentTypeDrawing+`"`), Equals, true)
+	sheet := written["xl/worksheets/sheet1.xml"]
+	t.Assert(strings.Index(sheet, "<drawing ") >= 0, Equals, true)
+	t.Assert(strings.Index(sheet, "<drawing ") < strings.Index(sheet, "<legacyDrawing "), Equals, true)
+	t.Assert(strings.Contains(written["xl/worksheets/sheet2.xml"], "<drawing "), Equals, false)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	data, err := f.RawPart("xl/media/image1.png")
+	t.Assert(err, IsNil)
+	t.Assert(data, DeepEquals, logo.Bytes())
+}
+
+func (s *StreamSuite) TestWriteGroup(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.SetRowLimitRollover(true), IsNil)
+	t.Assert(builder.AddSheet("Orders", []string{"Order", "Customer", "Product", "Total"}, nil), IsNil)
+	t.Assert(builder.SetColumnFormula(0, 3, "0"), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	stream.maxRows = 6
+	order := func(id string) []StreamCell {
+		return []StreamCell{NewStringStreamCell(id), NewStringStreamCell("Jane"), NewStringStreamCell("")}
+	}
+	line := func(product string) []StreamCell {
+		return []StreamCell{NewStringStreamCell("ignored"), NewStringStreamCell(""), NewStringStreamCell(product)}
+	}
+	t.Assert(stream.WriteGroup(order("1"), [][]StreamCell{line("Pen"), line("Ink")}, 0, 1), IsNil)
+	t.Assert(stream.WriteGroup(order("2"), nil, 0, 1), IsNil)
+	t.Assert(stream.WriteGroup(order("3"), [][]StreamCell{line("Pen")}, 3), ErrorMatches, "the key column 3 of sheet 'Orders' is a formula column")
+	t.Assert(stream.WriteGroup(order("3"), [][]StreamCell{line("Pen")}, 4), ErrorMatches, "the key column 4 is outside of sheet 'Orders'")
+	t.Assert(stream.WriteGroup(order("3"), [][]StreamCell{line("Pen")}, 0, 0), ErrorMatches, "the key column 0 is given twice")
+	t.Assert(stream.WriteGroup(order("3"), [][]StreamCell{line("Pen")[:2]}, 0), ErrorMatches, "the child row 0 has 2 cells, rather than the 3 of its parent row")
+	many := [][]StreamCell{line("Pen"), line("Pen"), line("Pen"), line("Pen"), line("Pen"), line("Pen")}
+	t.Assert(stream.WriteGroup(order("3"), many, 0), ErrorMatches, "the group of 7 rows doesn't fit in the sheet 'Orders'")
+	// The group starts on the sheet continuing the rows of the full one.
+	t.Assert(stream.WriteGroup(order("3"), [][]StreamCell{line("Pen"), line("Ink")}, 0), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(stream.RowCounts(), DeepEquals, []int{5, 4})
+
+	_, parts := readOptionsParts(t, buffer.Bytes())
+	t.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `<mergeCells count="2"><mergeCell ref="A2:A4"></mergeCell><mergeCell ref="B2:B4"></mergeCell></mergeCells>`), Equals, true)
+	t.Assert(strings.Contains(parts["xl/worksheets/sheet2.xml"], `<mergeCell ref="A2:A4"></mergeCell>`), Equals, true)
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	t.Assert(sheet.Cell(1, 0).Value, Equals, "1")
+	t.Assert(sheet.Cell(1, 0).VMerge, Equals, 2)
+	t.Assert(sheet.Cell(2, 0).Value, Equals, "")
+	t.Assert(sheet.Cell(3, 2).Value, Equals, "Ink")
+	t.Assert(sheet.Cell(4, 0).Value, Equals, "2")
+	t.Assert(f.Sheets[1].Cell(1, 0).Value, Equals, "3")
+}
+
+func (s *StreamSuite) TestSubtotalWriter(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheetWithOptions("Sales", []string{"Region", "Country", "Amount"}, StreamSheetOptions{OutlineLevels: 3}), IsNil)
+	t.Assert(builder.AddSheetWithOptions("Flat", []string{"Region", "Amount"}, StreamSheetOptions{OutlineLevels: 1}), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	_, err = stream.NewSubtotalWriter([]int{0, 1}, []int{1})
+	t.Assert(err, ErrorMatches, "the column at index 1 is given twice")
+	_, err = stream.NewSubtotalWriter([]int{0}, []int{3})
+	t.Assert(err, ErrorMatches, "no column at index 3 in sheet 'Sales'")
+	writer, err := stream.NewSubtotalWriter([]int{0, 1}, []int{2})
+	t.Assert(err, IsNil)
+	sale := func(region, country string, amount float64) []StreamCell {
+		return []StreamCell{NewStringStreamCell(region), NewStringStreamCell(country), NewFloatStreamCell(amount)}
+	}
+	t.Assert(writer.Write(sale("East", "China", 10)), IsNil)
+	t.Assert(writer.Write(sale("East", "China", 5)), IsNil)
+	t.Assert(writer.Write(sale("East", "Japan", 1)), IsNil)
+	t.Assert(writer.Write(sale("West", "Peru", 2.5)), IsNil)
+	t.Assert(writer.Write(sale("West", "Peru", 2.5)[:2]), ErrorMatches, "the row has 2 cells, rather than the 3 of the sheet 'Sales'")
+	t.Assert(writer.Close(), IsNil)
+	t.Assert(writer.Write(sale("West", "Peru", 1)), ErrorMatches, "the subtotal writer is closed")
+	t.Assert(stream.NextSheet(), IsNil)
+	_, err = stream.NewSubtotalWriter([]int{0}, []int{1})
+	t.Assert(err, ErrorMatches, "the sheet 'Flat' has 1 outline levels, rather than the 2 needed by 1 group columns")
+	t.Assert(stream.Close(), IsNil)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	type row struct {
+		level  uint8
+		values string
+	}
+	var rows []row
+	for r := 1; r < sheet.MaxRow; r++ {
+		values := ""
+		for c := 0; c < 3; c++ {
+			cell := sheet.Cell(r, c)
+			if formula := cell.Formula(); formula != "" {
+				values += "=" + formula + "->"
+			}
+			values += cell.Value + "|"
+		}
+		rows = append(rows, row{sheet.Rows[r].OutlineLevel, values})
+	}
+	t.Assert(rows, DeepEquals, []row{
+		{3, "East|China|10|"},
+		{3, "East|China|5|"},
+		{2, "|China Total|=SUBTOTAL(9,C2:C3)->15|"},
+		{3, "East|Japan|1|"},
+		{2, "|Japan Total|=SUBTOTAL(9,C5:C5)->1|"},
+		{1, "East Total||=SUBTOTAL(9,C2:C6)->16|"},
+		{3, "West|Peru|2.5|"},
+		{2, "|Peru Total|=SUBTOTAL(9,C8:C8)->2.5|"},
+		{1, "West Total||=SUBTOTAL(9,C8:C9)->2.5|"},
+		{0, "Grand Total||=SUBTOTAL(9,C2:C10)->18.5|"},
+	})
+}
+
+func (s *StreamSuite) TestRowLimit(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Data", []string{"Id"}, nil), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	stream.maxRows = 3
+	t.Assert(stream.WriteAll([][]string{{"1"}, {"2"}}), IsNil)
+	err = stream.Write([]string{"3"})
+	t.Assert(err, ErrorMatches, `the sheet "Data" already has 3 rows, as many as Excel can read`)
+	limitErr, ok := err.(*RowLimitError)
+	t.Assert(ok, Equals, true)
+	t.Assert(limitErr.Rows, Equals, 3)
+	t.Assert(stream.Close(), Equals, err)
+	t.Assert(stream.RowCounts(), DeepEquals, []int{3})
+}
+
+func (s *StreamSuite) TestRowLimitRollover(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.SetRowLimitRollover(true), IsNil)
+	t.Assert(builder.AddSheetWithTypes("Data", []string{"Id", "Name"}, []ColumnType{ColumnTypeInt}), IsNil)
+	t.Assert(builder.AddSheet("Other", []string{"Note"}, nil), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	stream.maxRows = 3
+	t.Assert(stream.WriteAll([][]string{{"1", "a"}, {"2", "b"}, {"3", "c"}, {"4", "d"}, {"5", "e"}}), IsNil)
+	t.Assert(stream.SkipToSheet("Data"), ErrorMatches, "the sheet 'Data' was already written")
+	t.Assert(stream.SkipToSheet("Data (3)"), IsNil)
+	t.Assert(stream.BeginRow(), IsNil)
+	t.Assert(stream.WriteCell("6"), IsNil)
+	t.Assert(stream.WriteCell("f"), IsNil)
+	t.Assert(stream.EndRow(), IsNil)
+	t.Assert(stream.NextSheet(), IsNil)
+	t.Assert(stream.Write([]string{"done"}), IsNil)
+	t.Assert(stream.SkipToSheet("Data (2)"), ErrorMatches, "the sheet 'Data \\(2\\)' continues the rows of sheet 'Data'")
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(stream.RowCounts(), DeepEquals, []int{3, 2, 3, 3})
+	t.Assert(VerifyStreamedFile(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()), stream), IsNil)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	var names []string
+	for _, sheet := range f.Sheets {
+		names = append(names, sheet.Name)
+	}
+	t.Assert(names, DeepEquals, []string{"Data", "Other", "Data (2)", "Data (3)"})
+	output, err := f.ToSlice()
+	t.Assert(err, IsNil)
+	t.Assert(output[0], DeepEquals, [][]string{{"Id", "Name"}, {"1", "a"}, {"2", "b"}})
+	t.Assert(output[1], DeepEquals, [][]string{{"Note"}, {"done"}})
+	t.Assert(output[2], DeepEquals, [][]string{{"Id", "Name"}, {"3", "c"}, {"4", "d"}})
+	t.Assert(output[3], DeepEquals, [][]string{{"Id", "Name"}, {"5", "e"}, {"6", "f"}})
+	t.Assert(f.Sheets[3].Rows[1].Cells[0].Type(), Equals, CellTypeNumeric)
+	sheet, err := readRawPart(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()), "xl/worksheets/sheet3.xml")
+	t.Assert(err, IsNil)
+	t.Assert(strings.Contains(sheet, `tabSelected="false"`), Equals, true)
+}
+
+func (s *StreamSuite) TestSchema(t *C) {
+	builder := NewStreamFileBuilder(ioutil.Discard)
+	t.Assert(builder.AddSheetWithTypes("Orders", []string{"Id", "Placed", "Total", "Total with tax", "Card"},
+		[]ColumnType{ColumnTypeInt, ColumnTypeDate, ColumnTypeFloat}), IsNil)
+	t.Assert(builder.SetColumnFormula(0, 3, "=C{row}*1.2"), IsNil)
+	t.Assert(builder.SetColumnMask(0, 4, MaskDigits(4)), IsNil)
+	t.Assert(builder.SetColWidth(0, 4, 20), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.AddSheet("Notes", nil), IsNil)
+
+	var buffer bytes.Buffer
+	t.Assert(stream.WriteSchema(&buffer), IsNil)
+	var schema StreamSchema
+	t.Assert(json.Unmarshal(buffer.Bytes(), &schema), IsNil)
+	t.Assert(schema, DeepEquals, stream.Schema())
+	t.Assert(schema.Sheets, HasLen, 2)
+	t.Assert(schema.Sheets[0].Name, Equals, "Orders")
+	t.Assert(schema.Sheets[0].Columns, DeepEquals, []StreamColumnSchema{
+		{Index: 0, Column: "A", Header: "Id", Type: "int", NumberFormat: "0", Width: 9.5},
+		{Index: 1, Column: "B", Header: "Placed", Type: "date", NumberFormat: "mm-dd-yy", Width: 12},
+		{Index: 2, Column: "C", Header: "Total", Type: "float", Width: 9.5},
+		{Index: 3, Column: "D", Header: "Total with tax", Type: "formula", Formula: "C{row}*1.2", Width: 9.5},
+		{Index: 4, Column: "E", Header: "Card", Type: "string", Width: 20, Masked: true},
+	})
+	t.Assert(schema.Sheets[1], DeepEquals, StreamSheetSchema{Name: "Notes", Columns: []StreamColumnSchema{}})
+	t.Assert(strings.Contains(buffer.String(), `"numberFormat": "mm-dd-yy"`), Equals, true)
+}
+
+func (s *StreamSuite) TestPlainLargeIntegers(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.SetPlainLargeIntegers(true), IsNil)
+	t.Assert(builder.AddSheetWithTypes("Orders", []string{"Id", "Total"}, []ColumnType{ColumnTypeFloat, ColumnTypeFloat}), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"123456789012", "12.5"}), IsNil)
+	t.Assert(stream.WriteTyped([]StreamCell{NewIntegerStreamCell(12345678901234), NewFloatStreamCell(1e12)}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	t.Assert(sheet.Cell(1, 0).NumFmt, Equals, "0")
+	t.Assert(sheet.Cell(1, 0).Type(), Equals, CellTypeNumeric)
+	t.Assert(sheet.Cell(1, 1).NumFmt, Equals, "general")
+	t.Assert(sheet.Cell(2, 0).NumFmt, Equals, "0")
+	t.Assert(sheet.Cell(2, 1).NumFmt, Equals, "0")
+}
+
+func (s *StreamSuite) TestColumnWidths(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheetWithTypes("Orders", []string{"Id", "Description", "Shipped", "Notes"},
+		[]ColumnType{ColumnTypeInt, ColumnTypeString, ColumnTypeDate}), IsNil)
+	t.Assert(builder.AddSheet("Totals", []string{"Total"}, nil), IsNil)
+	t.Assert(builder.SetColWidth(0, 3, 40), IsNil)
+	t.Assert(builder.SetColWidth(1, 0, 12.5), IsNil)
+	t.Assert(builder.SetColWidth(1, 1, 10), ErrorMatches, "no column at index 1 in sheet 'Totals'")
+	t.Assert(builder.SetColWidth(1, 0, 300), ErrorMatches, "invalid column width 300, .*")
+	t.Assert(builder.SetAutoColumnWidths(0), IsNil)
+	t.Assert(builder.SetAutoColumnWidths(2), ErrorMatches, "no sheet at index 2")
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"1", "A box of twenty-four blue ballpoint pens", "2024-03-15", "short"}), IsNil)
+	t.Assert(stream.Write([]string{"12345678901234", "Ink\nrefill", "2024-03-16", ""}), IsNil)
+	t.Assert(stream.NextSheet(), IsNil)
+	t.Assert(stream.Write([]string{"3"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(builder.SetAutoColumnWidths(0), Equals, BuiltStreamFileBuilderError)
+	t.Assert(VerifyStreamedFile(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()), stream), IsNil)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	orders := f.Sheets[0]
+	// The columns are as wide as their widest values, dates as
+	// their format, unless their width was set.
+	t.Assert(orders.Cols[0].Width, Equals, float64(16))
+	t.Assert(orders.Cols[1].Width, Equals, float64(42))
+	t.Assert(orders.Cols[2].Width, Equals, float64(10))
+	t.Assert(orders.Cols[3].Width, Equals, float64(40))
+	t.Assert(orders.Cell(2, 1).Value, Equals, "Ink\nrefill")
+	t.Assert(f.Sheets[1].Cols[0].Width, Equals, 12.5)
+}
+
+func (s *StreamSuite) TestColumnTypeWidths(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheetWithTypes("Orders", []string{"Id", "Shipped", "Paid", "Due", "Notes"},
+		[]ColumnType{ColumnTypeInt, ColumnTypeDate, ColumnTypeBool, ColumnTypeDate}), IsNil)
+	t.Assert(builder.AddSheetWithTypes("Sized", []string{"Shipped"}, []ColumnType{ColumnTypeDate}), IsNil)
+	t.Assert(builder.SetColWidth(0, 3, 20), IsNil)
+	t.Assert(builder.SetColumnTypeWidth(ColumnTypeInt, 14), IsNil)
+	t.Assert(builder.SetColumnTypeWidth(ColumnTypeBool, 0), ErrorMatches, "invalid column width 0, .*")
+	t.Assert(builder.SetColumnTypeWidth(ColumnType(7), 10), ErrorMatches, "unknown column type 7")
+	t.Assert(builder.SetAutoColumnWidths(1), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"1", "2024-03-15", "true", "2024-04-15", "rush"}), IsNil)
+	t.Assert(stream.NextSheet(), IsNil)
+	t.Assert(stream.Write([]string{"2024-03-16"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(builder.SetColumnTypeWidth(ColumnTypeDate, 15), Equals, BuiltStreamFileBuilderError)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	orders := f.Sheets[0]
+	// The columns are as wide as their types unless their width was
+	// set, the strings keeping the default width.
+	t.Assert(orders.Cols[0].Width, Equals, float64(14))
+	t.Assert(orders.Cols[1].Width, Equals, float64(12))
+	t.Assert(orders.Cols[2].Width, Equals, float64(7))
+	t.Assert(orders.Cols[3].Width, Equals, float64(20))
+	t.Assert(orders.Cols[4].Width, Equals, ColWidth)
+	// The columns of a sheet sized to its contents are sized like
+	// the others.
+	t.Assert(f.Sheets[1].Cols[0].Width, Equals, float64(10))
+}
+
+func (s *StreamSuite) TestAddSheetWithOptions(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheetWithOptions("Orders", []string{"Item", "Quantity", "Price"}, StreamSheetOptions{
+		ColumnTypes:  []ColumnType{ColumnTypeString, ColumnTypeInt},
+		FreezeHeader: true,
+		AutoFilter:   true,
+	}), IsNil)
+	t.Assert(builder.AddSheetWithOptions("Notes", []string{"Note"}, StreamSheetOptions{AutoFilter: true}), IsNil)
+	t.Assert(builder.AddSheetWithOptions("Empty", nil, StreamSheetOptions{FreezeHeader: true}), ErrorMatches,
+		"a sheet without headers has no header row to freeze or filter")
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"Pens", "3", "1.5"}), IsNil)
+	t.Assert(stream.Write([]string{"Ink", "1", "4"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(VerifyStreamedFile(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()), stream), IsNil)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	orders := f.Sheets[0]
+	t.Assert(orders.Cell(1, 1).Type(), Equals, CellTypeNumeric)
+	t.Assert(orders.SheetViews, HasLen, 1)
+	t.Assert(*orders.SheetViews[0].Pane, DeepEquals, Pane{YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft", State: "frozen"})
+	t.Assert(orders.AutoFilter.TopLeftCell, Equals, "A1")
+	t.Assert(orders.AutoFilter.BottomRightCell, Equals, "C3")
+	// The filter of a sheet without rows covers its header.
+	notes := f.Sheets[1]
+	t.Assert(notes.SheetViews[0].Pane, IsNil)
+	t.Assert(notes.AutoFilter.BottomRightCell, Equals, "A1")
+}
+
+func (s *StreamSuite) TestSetActiveCell(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheetWithOptions("Orders", []string{"Item", "Quantity"}, StreamSheetOptions{FreezeHeader: true}), IsNil)
+	t.Assert(builder.AddSheet("Summary", []string{"Total"}, nil), IsNil)
+	t.Assert(builder.AddSheet("Notes", []string{"Note"}, nil), IsNil)
+	t.Assert(builder.SetActiveCell(3, "A1", ""), ErrorMatches, "no sheet at index 3")
+	t.Assert(builder.SetActiveCell(1, "1A", ""), ErrorMatches, "invalid active cell '1A'")
+	t.Assert(builder.SetActiveCell(1, "B2", "?"), ErrorMatches, "invalid top left cell '\\?'")
+	t.Assert(builder.SetActiveCell(0, "B2", "A1"), ErrorMatches, "the top left cell A1 is in the frozen panes of sheet 'Orders'")
+	t.Assert(builder.SetActiveCell(0, "B40", "A30"), IsNil)
+	t.Assert(builder.SetActiveCell(1, "A20", "A15"), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(builder.SetActiveCell(1, "A1", ""), Equals, BuiltStreamFileBuilderError)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	orders := f.Sheets[0].SheetViews[0]
+	t.Assert(orders.ActiveCell, Equals, "B40")
+	t.Assert(orders.TopLeftCell, Equals, "A1")
+	t.Assert(*orders.Pane, DeepEquals, Pane{YSplit: 1, TopLeftCell: "A30", ActivePane: "bottomLeft", State: "frozen"})
+	summary := f.Sheets[1].SheetViews[0]
+	t.Assert(summary.ActiveCell, Equals, "A20")
+	t.Assert(summary.TopLeftCell, Equals, "A15")
+	t.Assert(summary.Pane, IsNil)
+	t.Assert(f.Sheets[2].SheetViews[0].ActiveCell, Equals, "A1")
+
+	// The selection of a sheet with frozen panes is in the pane that is active.
+	parts, err := f.MarshallParts()
+	t.Assert(err, IsNil)
+	t.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `<selection pane="bottomLeft" activeCell="B40" activeCellId="0" sqref="B40">`), Equals, true)
+}
+
+func (s *StreamSuite) TestSetSplitPane(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheetWithOptions("Orders", []string{"Item", "Quantity"}, StreamSheetOptions{FreezeHeader: true}), IsNil)
+	t.Assert(builder.AddSheet("Analysis", []string{"Item", "Quantity"}, nil), IsNil)
+	t.Assert(builder.AddSheet("Trend", []string{"Month"}, nil), IsNil)
+	t.Assert(builder.SetSplitPane(3, 10, 10, "B2"), ErrorMatches, "no sheet at index 3")
+	t.Assert(builder.SetSplitPane(1, 0, 0, "B2"), ErrorMatches, "invalid split 0, 0, which must be positive one way at least")
+	t.Assert(builder.SetSplitPane(1, 10, 10, "B"), ErrorMatches, "invalid top left cell 'B'")
+	t.Assert(builder.SetSplitPane(0, 10, 10, "B2"), ErrorMatches, "sheet 'Orders' has frozen panes, which can't be split as well")
+	t.Assert(builder.SetSplitPane(1, 60, 30, "C3"), IsNil)
+	t.Assert(builder.SetActiveCell(1, "D5", ""), IsNil)
+	t.Assert(builder.SetSplitPane(2, 0, 45, "A4"), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(builder.SetSplitPane(1, 10, 10, "B2"), Equals, BuiltStreamFileBuilderError)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	t.Assert(f.Sheets[0].SheetViews[0].Pane.State, Equals, "frozen")
+	analysis := f.Sheets[1].SheetViews[0]
+	t.Assert(*analysis.Pane, DeepEquals, Pane{XSplit: 1200, YSplit: 600, TopLeftCell: "C3", ActivePane: "bottomRight", State: "split"})
+	t.Assert(analysis.ActiveCell, Equals, "D5")
+	t.Assert(*f.Sheets[2].SheetViews[0].Pane, DeepEquals, Pane{YSplit: 900, TopLeftCell: "A4", ActivePane: "bottomLeft", State: "split"})
+}
+
+func (s *StreamSuite) TestSetCompression(t *C) {
+	for _, compression := range []StreamCompression{CompressionDeflate, CompressionDeflateStreaming, CompressionStore} {
+		buffer := bytes.NewBuffer(nil)
+		builder := NewStreamFileBuilder(buffer)
+		t.Assert(builder.SetCompression(compression), IsNil)
+		t.Assert(builder.AddSheet("Orders", []string{"Item", "Quantity"}, nil), IsNil)
+		stream, err := builder.Build()
+		t.Assert(err, IsNil)
+		t.Assert(stream.Write([]string{"Pens 0", "0"}), IsNil)
+		for i := 1; i < 3; i++ {
+			size := buffer.Len()
+			t.Assert(stream.Write([]string{"Pens " + strconv.Itoa(i), strconv.Itoa(i)}), IsNil)
+			// Only the compressor flushed along with the rows lets
+			// the small rows through at once.
+			t.Assert(buffer.Len() > size, Equals, compression != CompressionDeflate)
+		}
+		t.Assert(stream.Close(), IsNil)
+		t.Assert(VerifyStreamedFile(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()), stream), IsNil)
+
+		zipReader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+		t.Assert(err, IsNil)
+		for _, part := range zipReader.File {
+			t.Assert(part.Method, Equals, compression.zipMethod())
+		}
+		f, err := OpenBinary(buffer.Bytes())
+		t.Assert(err, IsNil)
+		t.Assert(f.Sheets[0].Cell(3, 0).Value, Equals, "Pens 2")
+	}
+
+	builder := NewStreamFileBuilder(bytes.NewBuffer(nil))
+	t.Assert(builder.SetCompression(StreamCompression(7)), ErrorMatches, "unknown compression 7")
+	_, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(builder.SetCompression(CompressionStore), Equals, BuiltStreamFileBuilderError)
+}
+
+// testPartCompressor compresses the parts with Deflate, recorded under
+// another method, counting the flushes of its writers.
+type testPartCompressor struct {
+	method  uint16
+	flushes int
+}
+
+func (c *testPartCompressor) Method() uint16 {
+	return c.method
+}
+
+func (c *testPartCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	fw, err := flate.NewWriter(w, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	return &testPartWriter{Writer: fw, compressor: c}, nil
+}
+
+type testPartWriter struct {
+	*flate.Writer
+	compressor *testPartCompressor
+}
+
+func (w *testPartWriter) Flush() error {
+	w.compressor.flushes++
+	return w.Writer.Flush()
+}
+
+func (s *StreamSuite) TestSetCompressor(t *C) {
+	builder := NewStreamFileBuilder(bytes.NewBuffer(nil))
+	t.Assert(builder.SetCompressor(nil), ErrorMatches, "a compressor can't be nil")
+	t.Assert(builder.SetCompressor(&testPartCompressor{method: zip.Store}), ErrorMatches, "the method of a compressor .*")
+
+	buffer := bytes.NewBuffer(nil)
+	builder = NewStreamFileBuilder(buffer)
+	compressor := &testPartCompressor{method: 99}
+	t.Assert(builder.SetCompressor(compressor), IsNil)
+	t.Assert(builder.AddSheet("Orders", []string{"Item", "Quantity"}, nil), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"Pens", "3"}), IsNil)
+	flushes := compressor.flushes
+	t.Assert(stream.Write([]string{"Ink", "5"}), IsNil)
+	// The writer of the sheet is flushed along with the row.
+	t.Assert(compressor.flushes > flushes, Equals, true)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(builder.SetCompressor(compressor), Equals, BuiltStreamFileBuilderError)
+
+	zipReader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	t.Assert(err, IsNil)
+	zipReader.RegisterDecompressor(99, flate.NewReader)
+	sheet := ""
+	for _, part := range zipReader.File {
+		t.Assert(part.Method, Equals, uint16(99))
+		if part.Name == "xl/worksheets/sheet1.xml" {
+			rc, err := part.Open()
+			t.Assert(err, IsNil)
+			data, err := ioutil.ReadAll(rc)
+			t.Assert(err, IsNil)
+			sheet = string(data)
+		}
+	}
+	t.Assert(strings.Contains(sheet, "<t>Ink</t>"), Equals, true)
+}
+
+func (s *StreamSuite) TestSetSharedStringLimit(t *C) {
+	builder := NewStreamFileBuilder(bytes.NewBuffer(nil))
+	t.Assert(builder.SetSharedStringLimit(-1), ErrorMatches, "the limit of the shared strings can't be negative, not -1")
+
+	buffer := bytes.NewBuffer(nil)
+	builder = NewStreamFileBuilder(buffer)
+	t.Assert(builder.SetGoogleSheetsCompatible(true), IsNil)
+	t.Assert(builder.SetSharedStringLimit(4), IsNil)
+	t.Assert(builder.AddSheet("People", []string{"Name", "City"}, nil), IsNil)
+	italic := NewStyle()
+	italic.Font.Italic = true
+	italicId, err := builder.AddStyle(italic, "")
+	t.Assert(err, IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"Jane", "Paris"}), IsNil)
+	t.Assert(stream.Write([]string{"John", "Paris"}), IsNil)
+	t.Assert(stream.WriteStyled([]string{"Jane", "Lyon"}, []int{0, italicId}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(builder.SetSharedStringLimit(0), Equals, BuiltStreamFileBuilderError)
+
+	summary := stream.Summary()
+	t.Assert(summary.SharedStrings, Equals, 4)
+	t.Assert(summary.InlineStrings, Equals, 2)
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	sheets, err := f.ToSlice()
+	t.Assert(err, IsNil)
+	t.Assert(sheets[0], DeepEquals, [][]string{
+		{"Name", "City"},
+		{"Jane", "Paris"},
+		{"John", "Paris"},
+		{"Jane", "Lyon"},
+	})
+	t.Assert(f.Sheets[0].Cell(3, 1).GetStyle().Font.Italic, Equals, true)
+}
+
+// closingBuffer is a buffer recording whether it was closed.
+type closingBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (cb *closingBuffer) Close() error {
+	cb.closed = true
+	return nil
+}
+
+func (s *StreamSuite) TestRolloverStreamFile(t *C) {
+	setup := func(sb *StreamFileBuilder) error {
+		if err := sb.AddSheet("Orders", []string{"Item"}, nil); err != nil {
+			return err
+		}
+		return sb.AddSheet("Returns", []string{"Item", "Reason"}, nil)
+	}
+	var buffers []*closingBuffer
+	next := func(index int) (io.Writer, error) {
+		t.Assert(index, Equals, len(buffers))
+		buffers = append(buffers, &closingBuffer{})
+		return buffers[index], nil
+	}
+	_, err := NewRolloverStreamFile(0, setup, next)
+	t.Assert(err, ErrorMatches, "the size of the files must be positive")
+
+	// Metadata alone outgrows a single byte, so every row ends its file.
+	rf, err := NewRolloverStreamFile(1, setup, next)
+	t.Assert(err, IsNil)
+	t.Assert(rf.Write([]string{"Pens"}), IsNil)
+	t.Assert(rf.Write([]string{"Ink"}), IsNil)
+	t.Assert(rf.NextSheet(), IsNil)
+	t.Assert(rf.Write([]string{"Tape", "Broken"}), IsNil)
+	t.Assert(rf.NextSheet(), Equals, AlreadyOnLastSheetError)
+	t.Assert(rf.Files(), Equals, 3)
+	t.Assert(rf.Close(), IsNil)
+	t.Assert(rf.Write([]string{"Glue", "Late"}), Equals, ClosedRolloverFileError)
+
+	t.Assert(buffers, HasLen, 3)
+	expected := [][][]string{
+		{{"Item"}, {"Pens"}},
+		{{"Item"}, {"Ink"}},
+		{{"Item"}},
+	}
+	for i, buffer := range buffers {
+		t.Assert(buffer.closed, Equals, true)
+		f, err := OpenBinary(buffer.Bytes())
+		t.Assert(err, IsNil)
+		sheets, err := f.ToSlice()
+		t.Assert(err, IsNil)
+		t.Assert(sheets[0], DeepEquals, expected[i])
+	}
+	f, err := OpenBinary(buffers[2].Bytes())
+	t.Assert(err, IsNil)
+	sheets, err := f.ToSlice()
+	t.Assert(err, IsNil)
+	t.Assert(sheets[1], DeepEquals, [][]string{{"Item", "Reason"}, {"Tape", "Broken"}})
+
+	// A large enough size keeps every row in one file.
+	buffers = nil
+	rf, err = NewRolloverStreamFile(1<<20, setup, next)
+	t.Assert(err, IsNil)
+	t.Assert(rf.Write([]string{"Pens"}), IsNil)
+	t.Assert(rf.Write([]string{"Ink"}), IsNil)
+	t.Assert(rf.Close(), IsNil)
+	t.Assert(rf.Files(), Equals, 1)
+	t.Assert(buffers[0].closed, Equals, true)
+}
+
+func (s *StreamSuite) TestSpoolSheets(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.SetGoogleSheetsCompatible(true), IsNil)
+	t.Assert(builder.AddSheet("Orders", []string{"Item"}, nil), IsNil)
+	t.Assert(builder.AddSheet("Stock", []string{"Item", "Count"}, nil), IsNil)
+	t.Assert(builder.AddSheetWithTypes("Prices", []string{"Item", "Price"},
+		[]ColumnType{ColumnTypeString, ColumnTypeFloat}), IsNil)
+	t.Assert(builder.SetAutoColumnWidths(2), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"Pens"}), IsNil)
+
+	sheets, err := stream.SpoolSheets(nil)
+	t.Assert(err, IsNil)
+	t.Assert(sheets, HasLen, 2)
+	t.Assert(sheets[0].Name(), Equals, "Stock")
+	t.Assert(sheets[1].Name(), Equals, "Prices")
+	_, err = stream.SpoolSheets(nil)
+	t.Assert(err, Equals, SheetsSpooledError)
+	t.Assert(stream.NextSheet(), Equals, SheetsSpooledError)
+	t.Assert(stream.AddSheet("Late", []string{"Item"}), Equals, SheetsSpooledError)
+
+	// The spooled sheets are written alongside the current sheet, and
+	// share its strings.
+	const rows = 100
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*(rows+1))
+	for i, sheet := range sheets {
+		wg.Add(1)
+		go func(i int, sheet *SpooledSheet) {
+			defer wg.Done()
+			for row := 0; row < rows; row++ {
+				item := "Item " + strconv.Itoa(row)
+				if i == 0 {
+					errs <- sheet.Write([]string{item, strconv.Itoa(row)})
+				} else {
+					errs <- sheet.WriteTyped([]StreamCell{NewStringStreamCell(item), NewFloatStreamCell(float64(row) / 2)})
+				}
+			}
+			errs <- sheet.Close()
+		}(i, sheet)
+	}
+	for row := 0; row < rows; row++ {
+		t.Assert(stream.Write([]string{"Item " + strconv.Itoa(row)}), IsNil)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Assert(err, IsNil)
+	}
+	t.Assert(sheets[0].Write([]string{"Late", "1"}), Equals, ClosedSpooledSheetError)
+	t.Assert(stream.Close(), IsNil)
+	// The headers, Pens, and the items and counts of the rows.
+	t.Assert(stream.Summary().SharedStrings, Equals, 3+1+2*rows)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	t.Assert(f.Sheets, HasLen, 3)
+	t.Assert(f.Sheets[1].Cols[0].Width, Equals, autoColumnWidth(len("Item 99")))
+	slices, err := f.ToSlice()
+	t.Assert(err, IsNil)
+	t.Assert(slices[0], HasLen, rows+2)
+	t.Assert(slices[0][1], DeepEquals, []string{"Pens"})
+	t.Assert(slices[1], HasLen, rows+1)
+	t.Assert(slices[1][100], DeepEquals, []string{"Item 99", "99"})
+	t.Assert(slices[2], HasLen, rows+1)
+	t.Assert(slices[2][4], DeepEquals, []string{"Item 3", "1.5"})
+}
+
+func (s *StreamSuite) TestSpoolSheetsWithSpools(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Orders", []string{"Item"}, nil), IsNil)
+	t.Assert(builder.AddSheet("Returns", []string{"Item", "Reason"}, nil), IsNil)
+	t.Assert(builder.AddSheet("Notes", []string{"Note"}, nil), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+
+	dir := t.MkDir()
+	var spools []*os.File
+	sheets, err := stream.SpoolSheets(func(sheetIndex int) (io.ReadWriteSeeker, error) {
+		spool, err := os.Create(filepath.Join(dir, strconv.Itoa(sheetIndex)))
+		spools = append(spools, spool)
+		return spool, err
+	})
+	t.Assert(err, IsNil)
+	t.Assert(sheets, HasLen, 2)
+	t.Assert(sheets[0].Write([]string{"Tape", "Broken"}), IsNil)
+	// Every spooled sheet must be closed first.
+	t.Assert(sheets[0].Close(), IsNil)
+	t.Assert(stream.Close(), ErrorMatches, "spooled sheet 'Notes' wasn't closed")
+
+	// Spooling nothing is harmless.
+	stream, err = NewStreamFileBuilder(buffer).Build()
+	t.Assert(err, IsNil)
+	sheets, err = stream.SpoolSheets(nil)
+	t.Assert(err, IsNil)
+	t.Assert(sheets, HasLen, 0)
+	t.Assert(stream.Close(), IsNil)
+
+	buffer.Reset()
+	builder = NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Orders", []string{"Item"}, nil), IsNil)
+	t.Assert(builder.AddSheet("Returns", []string{"Item", "Reason"}, nil), IsNil)
+	stream, err = builder.Build()
+	t.Assert(err, IsNil)
+	spools = nil
+	sheets, err = stream.SpoolSheets(func(sheetIndex int) (io.ReadWriteSeeker, error) {
+		spool, err := os.Create(filepath.Join(dir, "spool"))
+		spools = append(spools, spool)
+		return spool, err
+	})
+	t.Assert(err, IsNil)
+	t.Assert(sheets[0].Write([]string{"Tape", "Broken"}), IsNil)
+	t.Assert(sheets[0].Close(), IsNil)
+	t.Assert(stream.Write([]string{"Pens"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	// The spools are closed once copied into the file.
+	_, err = spools[0].Write([]byte("x"))
+	t.Assert(err, NotNil)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	slices, err := f.ToSlice()
+	t.Assert(err, IsNil)
+	t.Assert(slices, DeepEquals, [][][]string{
+		{{"Item"}, {"Pens"}},
+		{{"Item", "Reason"}, {"Tape", "Broken"}},
+	})
+}
+
+// resumableExport returns the StreamFile of an export of orders and of
+// stock, whose sheet is sized to its contents, written to buffer.
+func resumableExport(t *C, buffer *bytes.Buffer) *StreamFile {
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.SetGoogleSheetsCompatible(true), IsNil)
+	t.Assert(builder.AddSheet("Orders", []string{"Item"}, nil), IsNil)
+	t.Assert(builder.AddSheet("Stock", []string{"Item", "Count"}, nil), IsNil)
+	t.Assert(builder.SetAutoColumnWidths(1), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	return stream
+}
+
+// interruptedExport writes the rows of the stock sheet, spooled to a
+// temporary file, until it fails after rows, and returns the checkpoint
+// taken after checkpointed rows, as persisted in JSON.
+func interruptedExport(t *C, rows, checkpointed int) []byte {
+	stream := resumableExport(t, bytes.NewBuffer(nil))
+	sheets, err := stream.SpoolSheets(nil)
+	t.Assert(err, IsNil)
+	var checkpoint SpoolCheckpoint
+	for row := 0; row < rows; row++ {
+		if row == checkpointed {
+			checkpoint, err = sheets[0].Checkpoint()
+			t.Assert(err, IsNil)
+		}
+		item := "Item " + strconv.Itoa(row)
+		if row == 1 {
+			item = "A much longer item"
+		}
+		t.Assert(sheets[0].Write([]string{item, strconv.Itoa(row)}), IsNil)
+	}
+	// The rows after the checkpoint reach the spool before the export
+	// fails, leaving the temporary file behind.
+	t.Assert(sheets[0].buffer.Flush(), IsNil)
+	t.Assert(sheets[0].temp.Close(), IsNil)
+	data, err := json.Marshal(checkpoint)
+	t.Assert(err, IsNil)
+	return data
+}
+
+func (s *StreamSuite) TestResumeSpooledSheets(t *C) {
+	var checkpoint SpoolCheckpoint
+	t.Assert(json.Unmarshal(interruptedExport(t, 5, 3), &checkpoint), IsNil)
+	t.Assert(checkpoint.SheetIndex, Equals, 2)
+	t.Assert(checkpoint.Rows, Equals, 4)
+	t.Assert(checkpoint.SpoolPath, Not(Equals), "")
+
+	// The restarted export resumes the sheet at its fifth row.
+	buffer := bytes.NewBuffer(nil)
+	stream := resumableExport(t, buffer)
+	sheets, err := stream.ResumeSpooledSheets(nil, []SpoolCheckpoint{checkpoint})
+	t.Assert(err, IsNil)
+	t.Assert(sheets, HasLen, 1)
+	t.Assert(sheets[0].Name(), Equals, "Stock")
+	for row := 3; row < 6; row++ {
+		t.Assert(sheets[0].Write([]string{"Item " + strconv.Itoa(row), strconv.Itoa(row * 10)}), IsNil)
+	}
+	t.Assert(sheets[0].Close(), IsNil)
+	t.Assert(stream.Write([]string{"Item 0"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	_, err = os.Stat(checkpoint.SpoolPath)
+	t.Assert(os.IsNotExist(err), Equals, true)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	t.Assert(f.Sheets[1].Cols[0].Width, Equals, autoColumnWidth(len("A much longer item")))
+	slices, err := f.ToSlice()
+	t.Assert(err, IsNil)
+	t.Assert(slices[0], DeepEquals, [][]string{{"Item"}, {"Item 0"}})
+	t.Assert(slices[1], DeepEquals, [][]string{
+		{"Item", "Count"},
+		{"Item 0", "0"},
+		{"A much longer item", "1"},
+		{"Item 2", "2"},
+		{"Item 3", "30"},
+		{"Item 4", "40"},
+		{"Item 5", "50"},
+	})
+}
+
+func (s *StreamSuite) TestResumeSpooledSheetsInvalid(t *C) {
+	var checkpoint SpoolCheckpoint
+	t.Assert(json.Unmarshal(interruptedExport(t, 3, 2), &checkpoint), IsNil)
+	defer os.Remove(checkpoint.SpoolPath)
+
+	stream := resumableExport(t, bytes.NewBuffer(nil))
+	wrongSheet := checkpoint
+	wrongSheet.SheetIndex = 1
+	_, err := stream.ResumeSpooledSheets(nil, []SpoolCheckpoint{wrongSheet})
+	t.Assert(err, ErrorMatches, "no spooled sheet 1 to resume")
+	_, err = stream.ResumeSpooledSheets(nil, []SpoolCheckpoint{checkpoint, checkpoint})
+	t.Assert(err, ErrorMatches, "spooled sheet 'Stock' has more than one checkpoint")
+	tooLong := checkpoint
+	tooLong.Offset += 1000
+	_, err = stream.ResumeSpooledSheets(nil, []SpoolCheckpoint{tooLong})
+	t.Assert(err, ErrorMatches, "spooled sheet 'Stock' can't be resumed: the spool holds [0-9]+ bytes, fewer than the [0-9]+ of the checkpoint")
+	// The spool is kept, to be resumed again.
+	_, err = os.Stat(checkpoint.SpoolPath)
+	t.Assert(err, IsNil)
+
+	// The strings written since the file was built must be those of
+	// the checkpoint.
+	stream = resumableExport(t, bytes.NewBuffer(nil))
+	t.Assert(stream.Write([]string{"Elsewhere"}), IsNil)
+	_, err = stream.ResumeSpooledSheets(nil, []SpoolCheckpoint{checkpoint})
+	t.Assert(err, ErrorMatches, "the shared strings of the checkpoints don't match those of the file")
+}
+
+func (s *StreamSuite) TestSummary(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.SetGoogleSheetsCompatible(true), IsNil)
+	t.Assert(builder.AddSheet("People", []string{"Name", "Email"}, nil), IsNil)
+	t.Assert(builder.AddSheet("Totals", []string{"Total"}, nil), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"Jane", "jane@example.com"}), IsNil)
+	t.Assert(stream.Write([]string{"John", "Name"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+
+	summary := stream.Summary()
+	t.Assert(summary.Sheets, DeepEquals, []StreamSheetSummary{
+		{Name: "People", Rows: 3, Cells: 6},
+		{Name: "Totals", Rows: 1, Cells: 1},
+	})
+	t.Assert(summary.SharedStrings, Equals, 6)
+
+	bufReader := bytes.NewReader(buffer.Bytes())
+	zipReader, err := zip.NewReader(bufReader, bufReader.Size())
+	t.Assert(err, IsNil)
+	t.Assert(summary.PartSizes, HasLen, len(zipReader.File))
+	for _, zipFile := range zipReader.File {
+		t.Assert(summary.PartSizes[zipFile.Name], Equals, int64(zipFile.UncompressedSize64))
+	}
+}
+
+func (s *StreamSuite) TestSummaryStoredPartSizes(t *C) {
+	for _, compression := range []StreamCompression{CompressionDeflate, CompressionStore} {
+		buffer := bytes.NewBuffer(nil)
+		builder := NewStreamFileBuilder(buffer)
+		t.Assert(builder.SetCompression(compression), IsNil)
+		t.Assert(builder.AddSheet("Log", []string{"Line"}, nil), IsNil)
+		stream, err := builder.Build()
+		t.Assert(err, IsNil)
+		for i := 0; i < 100; i++ {
+			t.Assert(stream.Write([]string{"the same line, again and again"}), IsNil)
+		}
+		// The sheet being written is left out until Close.
+		_, ok := stream.Summary().StoredPartSizes["xl/worksheets/sheet1.xml"]
+		t.Assert(ok, Equals, false)
+		t.Assert(stream.Close(), IsNil)
+
+		summary := stream.Summary()
+		bufReader := bytes.NewReader(buffer.Bytes())
+		zipReader, err := zip.NewReader(bufReader, bufReader.Size())
+		t.Assert(err, IsNil)
+		t.Assert(summary.StoredPartSizes, HasLen, len(zipReader.File))
+		for _, zipFile := range zipReader.File {
+			t.Assert(summary.StoredPartSizes[zipFile.Name], Equals, int64(zipFile.CompressedSize64))
+		}
+		if compression == CompressionStore {
+			t.Assert(summary.CompressionRatio(), Equals, 1.0)
+		} else {
+			t.Assert(summary.CompressionRatio() < 0.5, Equals, true)
+		}
+	}
+	t.Assert(StreamSummary{}.CompressionRatio(), Equals, 0.0)
+}
+
+func (s *StreamSuite) TestEmptySheetsAndWorkbooks(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("No data", nil, nil), IsNil)
+	t.Assert(builder.AddSheet("People", []string{"Name"}, nil), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{}), IsNil)
+	t.Assert(stream.Write([]string{"Jane"}), Equals, WrongNumberOfRowsError)
+
+	buffer = bytes.NewBuffer(nil)
+	builder = NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("No data", nil, nil), IsNil)
+	t.Assert(builder.AddSheet("People", []string{"Name"}, nil), IsNil)
+	stream, err = builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{}), IsNil)
+	t.Assert(stream.NextSheet(), IsNil)
+	t.Assert(stream.Write([]string{"Jane"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(stream.RowCounts(), DeepEquals, []int{0, 2})
+	reader := bytes.NewReader(buffer.Bytes())
+	t.Assert(VerifyStreamedFile(reader, reader.Size(), stream), IsNil)
+	_, workbookData := readXLSXFile(t, "", reader, reader.Size(), false)
+	t.Assert(workbookData, DeepEquals, [][][]string{{}, {{"Name"}, {"Jane"}}})
+
+	// A workbook without sheets is given an empty one.
+	buffer = bytes.NewBuffer(nil)
+	builder = NewStreamFileBuilder(buffer)
+	stream, err = builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Close(), IsNil)
+	reader = bytes.NewReader(buffer.Bytes())
+	t.Assert(VerifyStreamedFile(reader, reader.Size(), stream), IsNil)
+	file, err := OpenReaderAt(reader, reader.Size())
+	t.Assert(err, IsNil)
+	t.Assert(file.Sheets, HasLen, 1)
+	t.Assert(file.Sheets[0].Name, Equals, "Sheet1")
+	t.Assert(file.Sheets[0].Rows, HasLen, 0)
+}
+
+func (s *StreamSuite) TestWriteAllRowError(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("People", []string{"Name", "Email"}, nil), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	err = stream.WriteAll([][]string{{"Jane", "jane@example.com"}, {"John"}, {"Joe", "joe@example.com"}})
+	rowErr, ok := err.(*RowError)
+	t.Assert(ok, Equals, true)
+	t.Assert(rowErr.Row, Equals, 1)
+	t.Assert(rowErr.Err, Equals, WrongNumberOfRowsError)
+	t.Assert(rowErr.Unwrap(), Equals, WrongNumberOfRowsError)
+	t.Assert(err, ErrorMatches, "row 1: invalid number of cells .*")
+	t.Assert(stream.Write([]string{"Joe", "joe@example.com"}), Equals, err)
+}
+
+func (s *StreamSuite) TestWriteAllContext(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Numbers", []string{"Number"}, nil), IsNil)
+	var written int
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Assert(builder.SetRowHook(func(sheet string, headers, cells []string) ([]string, error) {
+		written++
+		if written == 5 {
+			cancel()
+		}
+		return cells, nil
+	}), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	var records [][]string
+	for i := 0; i < 10; i++ {
+		records = append(records, []string{strconv.Itoa(i)})
+	}
+	err = stream.WriteAllContext(ctx, records, 3)
+	rowErr, ok := err.(*RowError)
+	t.Assert(ok, Equals, true)
+	t.Assert(rowErr.Row, Equals, 6)
+	t.Assert(rowErr.Err, Equals, context.Canceled)
+	// The chunks written before the cancellation are kept.
+	t.Assert(stream.WriteAllContext(context.Background(), records[6:], 0), IsNil)
+	t.Assert(stream.Close(), IsNil)
+
+	bufReader := bytes.NewReader(buffer.Bytes())
+	_, workbookData := readXLSXFile(t, "", bufReader, bufReader.Size(), false)
+	t.Assert(workbookData[0], HasLen, 11)
+	t.Assert(workbookData[0][10], DeepEquals, []string{"9"})
+}
+
+func (s *StreamSuite) TestWriteContextAndCloseContext(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Numbers", []string{"Number"}, nil), IsNil)
+	t.Assert(builder.AddSheet("Notes", []string{"Note"}, nil), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Assert(stream.WriteContext(ctx, []string{"1"}), IsNil)
+	cancel()
+	t.Assert(stream.WriteContext(ctx, []string{"2"}), Equals, context.Canceled)
+	// The StreamFile can still be used once a write is given up.
+	t.Assert(stream.Write([]string{"3"}), IsNil)
+	t.Assert(stream.CloseContext(ctx), Equals, context.Canceled)
+	t.Assert(stream.Close(), Equals, context.Canceled)
+
+	buffer.Reset()
+	builder = NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Numbers", []string{"Number"}, nil), IsNil)
+	stream, err = builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.WriteContext(context.Background(), []string{"1"}), IsNil)
+	t.Assert(stream.CloseContext(context.Background()), IsNil)
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	slices, err := f.ToSlice()
+	t.Assert(err, IsNil)
+	t.Assert(slices[0], DeepEquals, [][]string{{"Number"}, {"1"}})
+}
+
+func (s *StreamSuite) TestSetProgressHook(t *C) {
+	type progress struct {
+		sheetIndex, rows int
+		bytes            int64
+	}
+	var reports []progress
+	hook := func(sheetIndex, rowsWritten int, bytesWritten int64) {
+		reports = append(reports, progress{sheetIndex, rowsWritten, bytesWritten})
+	}
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Numbers", []string{"Number"}, nil), IsNil)
+	t.Assert(builder.AddSheet("Notes", []string{"Note"}, nil), IsNil)
+	t.Assert(builder.SetProgressHook(hook, 0), ErrorMatches, "invalid progress interval 0, which must be at least one row")
+	t.Assert(builder.SetProgressHook(hook, 2), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	for i := 0; i < 5; i++ {
+		t.Assert(stream.Write([]string{strconv.Itoa(i)}), IsNil)
+	}
+	t.Assert(stream.NextSheet(), IsNil)
+	t.Assert(stream.BeginRow(), IsNil)
+	t.Assert(stream.WriteCell("Done"), IsNil)
+	t.Assert(stream.EndRow(), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(builder.SetProgressHook(hook, 1), Equals, BuiltStreamFileBuilderError)
+
+	// Every other row is reported, and the whole file once it is closed.
+	t.Assert(reports, HasLen, 4)
+	t.Assert(reports[0].sheetIndex, Equals, 0)
+	t.Assert(reports[0].rows, Equals, 3)
+	t.Assert(reports[1].rows, Equals, 5)
+	t.Assert(reports[2].sheetIndex, Equals, 1)
+	t.Assert(reports[2].rows, Equals, 2)
+	t.Assert(reports[3], Equals, progress{1, 2, int64(buffer.Len())})
+	t.Assert(reports[0].bytes > 0, Equals, true)
+	t.Assert(reports[1].bytes >= reports[0].bytes, Equals, true)
+}
+
+type streamOrderStatus int
+
+func (status streamOrderStatus) String() string {
+	return [...]string{"open", "shipped"}[status]
+}
+
+type StreamOrderAudit struct {
+	CreatedBy string `xlsx:"Created by"`
+}
+
+type streamOrder struct {
+	ID       uint64 `xlsx:"Order"`
+	Item     string
+	Price    float64 `xlsx:"Unit price,#,##0.00"`
+	Quantity int
+	Paid     bool
+	Shipped  *time.Time        `xlsx:",yyyy-mm-dd"`
+	Status   streamOrderStatus `xlsx:"Status"`
+	Internal string            `xlsx:"-"`
+	note     string
+	StreamOrderAudit
+}
+
+func (s *StreamSuite) TestWriteStruct(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheetFromStruct("Numbers", 3), Equals, errNotStruct)
+	t.Assert(builder.AddSheetFromStruct("Bad", struct{ Tags []string }{}), ErrorMatches,
+		"field Tags of struct { Tags \\[\\]string } is a \\[\\]string, which can't be written to a cell")
+	t.Assert(builder.AddSheetFromStruct("Orders", (*streamOrder)(nil)), IsNil)
+	t.Assert(builder.AddSheet("Notes", []string{"Note"}, nil), IsNil)
+	t.Assert(builder.SetNullAs(NullAsNA), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	shipped := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	t.Assert(stream.WriteStruct(&streamOrder{ID: 12345678901, Item: "Pens", Price: 1234.5, Quantity: 3, Paid: true,
+		Shipped: &shipped, Status: 1, Internal: "secret", note: "private", StreamOrderAudit: StreamOrderAudit{CreatedBy: "Ann"}}), IsNil)
+	t.Assert(stream.WriteStruct(streamOrder{Item: "Ink"}), IsNil)
+	t.Assert(stream.WriteStruct(struct{ Item string }{"Tape"}), ErrorMatches,
+		"sheet 'Orders' holds xlsx.streamOrder structs, not struct { Item string }")
+	t.Assert(stream.NextSheet(), IsNil)
+	stream.err = nil
+	t.Assert(stream.WriteStruct(streamOrder{}), ErrorMatches, "sheet 'Notes' wasn't added by AddSheetFromStruct")
+	t.Assert(stream.Close(), IsNil)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	slices, err := f.ToSlice()
+	t.Assert(err, IsNil)
+	t.Assert(slices[0], DeepEquals, [][]string{
+		{"Order", "Item", "Unit price", "Quantity", "Paid", "Shipped", "Status", "Created by"},
+		{"12345678901", "Pens", "1234.50", "3", "TRUE", "2024-03-05", "shipped", "Ann"},
+		{"0", "Ink", "0.00", "0", "FALSE", "N/A", "open", ""},
+	})
+	orders := f.Sheets[0]
+	t.Assert(orders.Cell(1, 0).Type(), Equals, CellTypeNumeric)
+	t.Assert(orders.Cell(1, 4).Type(), Equals, CellTypeBool)
+	t.Assert(orders.Cell(1, 2).GetNumberFormat(), Equals, "#,##0.00")
+	shippedCell, err := orders.Cell(1, 5).GetTime(false)
+	t.Assert(err, IsNil)
+	t.Assert(shippedCell, Equals, shipped)
+}
+
+func (s *StreamSuite) TestWriteCells(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.SetOmitCellReferences(true), IsNil)
+	t.Assert(builder.AddSheet("Wide", []string{"A", "B", "C"}, nil), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.WriteCell("1"), Equals, NoRowInProgressError)
+	t.Assert(stream.EndRow(), Equals, NoRowInProgressError)
+	t.Assert(stream.BeginRow(), IsNil)
+	t.Assert(stream.BeginRow(), Equals, RowInProgressError)
+	for _, value := range []string{"1", "2", "3"} {
+		t.Assert(stream.WriteCell(value), IsNil)
+	}
+	t.Assert(stream.Write([]string{"4", "5", "6"}), Equals, RowInProgressError)
+	t.Assert(stream.Error(), Equals, RowInProgressError)
+
+	buffer = bytes.NewBuffer(nil)
+	builder = NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Wide", []string{"A", "B", "C"}, nil), IsNil)
+	stream, err = builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.BeginRow(), IsNil)
+	for _, value := range []string{"1", "2", "3"} {
+		t.Assert(stream.WriteCell(value), IsNil)
+	}
+	t.Assert(stream.Close(), Equals, RowInProgressError)
+	t.Assert(stream.EndRow(), IsNil)
+	t.Assert(stream.Write([]string{"4", "5", "6"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(stream.Summary().Sheets[0].Cells, Equals, 9)
+	bufReader := bytes.NewReader(buffer.Bytes())
+	_, workbookData := readXLSXFile(t, "", bufReader, bufReader.Size(), false)
+	t.Assert(workbookData, DeepEquals, [][][]string{{{"A", "B", "C"}, {"1", "2", "3"}, {"4", "5", "6"}}})
+
+	// Rows must have as many cells as the header.
+	builder = NewStreamFileBuilder(bytes.NewBuffer(nil))
+	t.Assert(builder.AddSheet("Wide", []string{"A", "B"}, nil), IsNil)
+	stream, err = builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.BeginRow(), IsNil)
+	t.Assert(stream.WriteCell("1"), IsNil)
+	t.Assert(stream.EndRow(), Equals, WrongNumberOfRowsError)
+	builder = NewStreamFileBuilder(bytes.NewBuffer(nil))
+	t.Assert(builder.AddSheet("Wide", []string{"A"}, nil), IsNil)
+	stream, err = builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.BeginRow(), IsNil)
+	t.Assert(stream.WriteCell("1"), IsNil)
+	t.Assert(stream.WriteCell("2"), Equals, WrongNumberOfRowsError)
+}
+
+func (s *StreamSuite) TestWriteAllContinueOnError(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("People", []string{"Name", "Email"}, nil), IsNil)
+	t.Assert(builder.SetRowHook(func(sheet string, headers, cells []string) ([]string, error) {
+		if len(cells) > 0 && cells[0] == "" {
+			return nil, errors.New("the name is missing")
+		}
+		if len(cells) > 0 && cells[0] == "Test" {
+			return nil, SkipRow
+		}
+		return cells, nil
+	}), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.WriteAllContinueOnError([][]string{{"Jane", "jane@example.com"}, {"Test", ""}}), IsNil)
+	err = stream.WriteAllContinueOnError([][]string{
+		{"John"},
+		{"", "nobody@example.com"},
+		{"Joe", "joe@example.com"},
+	})
+	rowErrs, ok := err.(RowErrors)
+	t.Assert(ok, Equals, true)
+	t.Assert(rowErrs, HasLen, 2)
+	t.Assert(rowErrs[0].Row, Equals, 0)
+	t.Assert(rowErrs[0].Err, Equals, WrongNumberOfRowsError)
+	t.Assert(rowErrs[1].Row, Equals, 1)
+	t.Assert(err, ErrorMatches, "row 0: invalid number of cells .*, and 1 other rows")
+	t.Assert(rowErrs[1:].Error(), Equals, "row 1: the name is missing")
+	t.Assert(stream.Write([]string{"Jim", "jim@example.com"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+
+	bufReader := bytes.NewReader(buffer.Bytes())
+	_, workbookData := readXLSXFile(t, "", bufReader, bufReader.Size(), false)
+	t.Assert(workbookData, DeepEquals, [][][]string{
+		{{"Name", "Email"}, {"Jane", "jane@example.com"}, {"Joe", "joe@example.com"}, {"Jim", "jim@example.com"}},
+	})
+}
+
+func (s *StreamSuite) TestRowHookError(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("People", []string{"Name"}, nil), IsNil)
+	hookErr := errors.New("unexpected column")
+	t.Assert(builder.SetRowHook(func(sheet string, headers, cells []string) ([]string, error) {
+		return nil, hookErr
+	}), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"Jane"}), Equals, hookErr)
+	t.Assert(stream.Close(), Equals, hookErr)
+}
+
+func (s *StreamSuite) TestSplitWideSheets(t *C) {
+	headers := make([]string, Excel2006MaxColumnCount+2)
+	row := make([]string, len(headers))
+	for i := range headers {
+		headers[i] = "H" + strconv.Itoa(i)
+		row[i] = strconv.Itoa(i)
+	}
+	builder := NewStreamFileBuilder(bytes.NewBuffer(nil))
+	err := builder.AddSheet("Data", headers, nil)
+	limitErr, ok := err.(*ColumnLimitError)
+	t.Assert(ok, Equals, true)
+	t.Assert(limitErr.Sheet, Equals, "Data")
+	t.Assert(limitErr.Columns, Equals, Excel2006MaxColumnCount+2)
+
+	buffer := bytes.NewBuffer(nil)
+	builder = NewStreamFileBuilder(buffer)
+	t.Assert(builder.SetSplitWideSheets(true), IsNil)
+	t.Assert(builder.AddSheet("Data", headers, nil), IsNil)
+	t.Assert(builder.AddSheet("Other", []string{"A"}, nil), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write(row[:3]), Equals, WrongNumberOfRowsError)
+
+	buffer = bytes.NewBuffer(nil)
+	builder = NewStreamFileBuilder(buffer)
+	t.Assert(builder.SetSplitWideSheets(true), IsNil)
+	t.Assert(builder.AddSheet("Data", headers, nil), IsNil)
+	t.Assert(builder.AddSheet("Other", []string{"A"}, nil), IsNil)
+	stream, err = builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write(row), IsNil)
+	t.Assert(stream.BeginRow(), IsNil)
+	for _, value := range row {
+		t.Assert(stream.WriteCell(value), IsNil)
+	}
+	t.Assert(stream.EndRow(), IsNil)
+	t.Assert(stream.NextSheet(), IsNil)
+	t.Assert(stream.Write([]string{"a"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(stream.RowCounts(), DeepEquals, []int{3, 3, 2})
+	summary := stream.Summary()
+	t.Assert(summary.Sheets[1], DeepEquals, StreamSheetSummary{Name: "Data (2)", Rows: 3, Cells: 6})
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	t.Assert(f.Sheets, HasLen, 3)
+	data := f.Sheets[0]
+	t.Assert(data.Name, Equals, "Data")
+	t.Assert(data.MaxCol, Equals, Excel2006MaxColumnCount)
+	t.Assert(data.Cell(1, Excel2006MaxColumnCount-1).Value, Equals, strconv.Itoa(Excel2006MaxColumnCount-1))
+	followOn := f.Sheets[1]
+	t.Assert(followOn.Name, Equals, "Data (2)")
+	t.Assert(followOn.MaxCol, Equals, 2)
+	for i := 0; i < 3; i++ {
+		t.Assert(followOn.Cell(i, 1).Value, Equals, [][]string{headers, row, row}[i][Excel2006MaxColumnCount+1])
+	}
+	t.Assert(f.Sheets[2].Name, Equals, "Other")
+	t.Assert(f.Sheets[2].Cell(1, 0).Value, Equals, "a")
+}
+
+func (s *StreamSuite) TestColumnFormula(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Orders", []string{"Item", "Total", "Quantity", "Price"}, nil), IsNil)
+	t.Assert(builder.SetColumnFormula(0, 4, "=C{row}*D{row}"), ErrorMatches, "no column at index 4 in sheet 'Orders'")
+	t.Assert(builder.SetColumnFormula(0, 1, "="), NotNil)
+	t.Assert(builder.SetColumnFormula(0, 1, "=C{row}*D{row}"), IsNil)
+	var hookHeaders []string
+	t.Assert(builder.SetRowHook(func(sheet string, headers, cells []string) ([]string, error) {
+		hookHeaders = headers
+		return cells, nil
+	}), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"Pens", "", "3", "2"}), Equals, WrongNumberOfRowsError)
+
+	buffer = bytes.NewBuffer(nil)
+	builder = NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Orders", []string{"Item", "Total", "Quantity", "Price"}, nil), IsNil)
+	t.Assert(builder.SetColumnFormula(0, 1, "=C{row}*D{row}"), IsNil)
+	t.Assert(builder.SetRowHook(func(sheet string, headers, cells []string) ([]string, error) {
+		hookHeaders = headers
+		return cells, nil
+	}), IsNil)
+	stream, err = builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"Pens", "3", "2"}), IsNil)
+	t.Assert(hookHeaders, DeepEquals, []string{"Item", "Quantity", "Price"})
+	t.Assert(stream.BeginRow(), IsNil)
+	for _, value := range []string{"Ink", "1", "5"} {
+		t.Assert(stream.WriteCell(value), IsNil)
+	}
+	t.Assert(stream.WriteCell("extra"), Equals, WrongNumberOfRowsError)
+
+	buffer = bytes.NewBuffer(nil)
+	builder = NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Orders", []string{"Item", "Quantity", "Price", "Total"}, nil), IsNil)
+	t.Assert(builder.SetColumnFormula(0, 3, "C{row}*B{row}"), IsNil)
+	stream, err = builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"Pens", "3", "2"}), IsNil)
+	t.Assert(stream.BeginRow(), IsNil)
+	for _, value := range []string{"Ink", "1", "5"} {
+		t.Assert(stream.WriteCell(value), IsNil)
+	}
+	t.Assert(stream.EndRow(), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(stream.Summary().Sheets[0].Cells, Equals, 12)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	t.Assert(sheet.Cell(0, 3).Value, Equals, "Total")
+	t.Assert(sheet.Cell(1, 0).Value, Equals, "Pens")
+	t.Assert(sheet.Cell(1, 3).Formula(), Equals, "C2*B2")
+	t.Assert(sheet.Cell(2, 2).Value, Equals, "5")
+	t.Assert(sheet.Cell(2, 3).Formula(), Equals, "C3*B3")
+	t.Assert(f.CalcProperties.FullCalcOnLoad, Equals, true)
+}
+
+func (s *StreamSuite) TestDuplicateRowGuard(t *C) {
+	builder := NewStreamFileBuilder(bytes.NewBuffer(nil))
+	t.Assert(builder.SetDuplicateRowGuard(-1, SkipDuplicateRows), NotNil)
+	t.Assert(builder.SetDuplicateRowGuard(1, 0), NotNil)
+
+	buffer := bytes.NewBuffer(nil)
+	builder = NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Skipped", []string{"Id"}, nil), IsNil)
+	t.Assert(builder.AddSheet("Flagged", []string{"Id"}, nil), IsNil)
+	t.Assert(builder.SetDuplicateRowGuard(2, SkipDuplicateRows), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.WriteAll([][]string{{"1"}, {"2"}, {"2"}, {"1"}, {"3"}, {"1"}}), IsNil)
+	t.Assert(stream.DuplicateRows(), DeepEquals, []DuplicateRow{
+		{Sheet: "Skipped", Row: 4, Original: 3, Skipped: true},
+		{Sheet: "Skipped", Row: 4, Original: 2, Skipped: true},
+	})
+	t.Assert(stream.NextSheet(), IsNil)
+	t.Assert(stream.WriteAll([][]string{{"1"}}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	summary := stream.Summary()
+	t.Assert(summary.Sheets[0].Rows, Equals, 5)
+	t.Assert(summary.Sheets[0].DuplicateRows, Equals, 2)
+	t.Assert(summary.Sheets[1].DuplicateRows, Equals, 0)
+	bufReader := bytes.NewReader(buffer.Bytes())
+	_, workbookData := readXLSXFile(t, "", bufReader, bufReader.Size(), false)
+	t.Assert(workbookData[0], DeepEquals, [][]string{{"Id"}, {"1"}, {"2"}, {"3"}, {"1"}})
+
+	buffer = bytes.NewBuffer(nil)
+	builder = NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Flagged", []string{"Id", "Name"}, nil), IsNil)
+	t.Assert(builder.SetDuplicateRowGuard(1, FlagDuplicateRows), IsNil)
+	stream, err = builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.WriteAll([][]string{{"1", "a"}, {"1", "a"}, {"2", "b"}, {"1", "a"}}), IsNil)
+	t.Assert(stream.DuplicateRows(), DeepEquals, []DuplicateRow{{Sheet: "Flagged", Row: 3, Original: 2}})
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(stream.Summary().Sheets[0].Rows, Equals, 5)
+	// Only the first duplicates are reported, all of them being
+	// counted.
+	builder = NewStreamFileBuilder(bytes.NewBuffer(nil))
+	t.Assert(builder.AddSheet("Repeated", []string{"Id"}, nil), IsNil)
+	t.Assert(builder.SetDuplicateRowGuard(1, SkipDuplicateRows), IsNil)
+	stream, err = builder.Build()
+	t.Assert(err, IsNil)
+	for i := 0; i < maxDuplicateRows+3; i++ {
+		t.Assert(stream.Write([]string{"1"}), IsNil)
+	}
+	t.Assert(stream.DuplicateRows(), HasLen, maxDuplicateRows)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(stream.Summary().Sheets[0].DuplicateRows, Equals, maxDuplicateRows+2)
+
+	// The duplicates of spooled sheets are counted by each of them,
+	// and added to those of the file as they are written.
+	builder = NewStreamFileBuilder(bytes.NewBuffer(nil))
+	t.Assert(builder.AddSheet("Current", []string{"Id"}, nil), IsNil)
+	t.Assert(builder.AddSheet("Spooled", []string{"Id"}, nil), IsNil)
+	t.Assert(builder.SetDuplicateRowGuard(1, FlagDuplicateRows), IsNil)
+	stream, err = builder.Build()
+	t.Assert(err, IsNil)
+	sheets, err := stream.SpoolSheets(nil)
+	t.Assert(err, IsNil)
+	t.Assert(sheets[0].Write([]string{"1"}), IsNil)
+	t.Assert(sheets[0].Write([]string{"1"}), IsNil)
+	t.Assert(sheets[0].Write([]string{"1"}), IsNil)
+	t.Assert(sheets[0].Close(), IsNil)
+	t.Assert(stream.Write([]string{"1"}), IsNil)
+	t.Assert(stream.Write([]string{"1"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	summary = stream.Summary()
+	t.Assert(summary.Sheets[0].DuplicateRows, Equals, 1)
+	t.Assert(summary.Sheets[1].DuplicateRows, Equals, 2)
+	t.Assert(stream.DuplicateRows(), HasLen, 3)
+}
+
+func (s *StreamSuite) TestWriteTyped(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Orders", []string{"Item", "Quantity", "Price", "Shipped", "Date"}, nil), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	date := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	t.Assert(stream.WriteTyped([]StreamCell{
+		NewStringStreamCell("Pens"),
+		NewIntegerStreamCell(3),
+		NewFloatStreamCell(2.5),
+		NewBoolStreamCell(true),
+		NewDateStreamCell(date),
+	}), IsNil)
+	t.Assert(stream.Write([]string{"Ink", "1", "5", "0", "2024-03-16"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	t.Assert(sheet.Cell(1, 0).Type(), Equals, CellTypeInline)
+	t.Assert(sheet.Cell(1, 1).Type(), Equals, CellTypeNumeric)
+	quantity, err := sheet.Cell(1, 1).Int()
+	t.Assert(err, IsNil)
+	t.Assert(quantity, Equals, 3)
+	price, err := sheet.Cell(1, 2).Float()
+	t.Assert(err, IsNil)
+	t.Assert(price, Equals, 2.5)
+	t.Assert(sheet.Cell(1, 3).Type(), Equals, CellTypeBool)
+	t.Assert(sheet.Cell(1, 3).Bool(), Equals, true)
+	t.Assert(sheet.Cell(1, 4).IsTime(), Equals, true)
+	readDate, err := sheet.Cell(1, 4).GetTime(false)
+	t.Assert(err, IsNil)
+	t.Assert(readDate.Equal(date), Equals, true)
+	// Write keeps writing strings.
+	t.Assert(sheet.Cell(2, 1).Type(), Equals, CellTypeInline)
+
+	builder = NewStreamFileBuilder(bytes.NewBuffer(nil))
+	t.Assert(builder.AddSheet("Orders", []string{"Quantity"}, nil), IsNil)
+	stream, err = builder.Build()
+	t.Assert(err, IsNil)
+	err = stream.WriteTyped([]StreamCell{{Value: "three", Type: CellTypeNumeric}})
+	t.Assert(err, ErrorMatches, `cell 0 of the row: "three" isn't a number`)
+	// The values Go parses but spreadsheets reject aren't numbers.
+	for _, value := range []string{"NaN", "Inf", "-Infinity", "0x1p-2", "1e400", "1_000", ".", "1e"} {
+		t.Assert(checkStreamCellValue(value, CellTypeNumeric), ErrorMatches, `".*" isn't a number`)
+	}
+	for _, value := range []string{"42", "-3.14", "+.5", "5.", "1E-6", "12345678901234567"} {
+		t.Assert(checkStreamCellValue(value, CellTypeNumeric), IsNil)
+	}
+	_, err = ColumnTypeFloat.streamCell("NaN")
+	t.Assert(err, ErrorMatches, `"NaN" isn't a number`)
+}
+
+func (s *StreamSuite) TestAddSheetWithTypes(t *C) {
+	builder := NewStreamFileBuilder(bytes.NewBuffer(nil))
+	t.Assert(builder.AddSheetWithTypes("Orders", []string{"Item"}, []ColumnType{ColumnTypeString, ColumnTypeInt}), ErrorMatches, "columnTypes is longer than headers")
+	t.Assert(builder.AddSheetWithTypes("Orders", []string{"Item"}, []ColumnType{ColumnType(99)}), ErrorMatches, "unknown column type 99")
+
+	buffer := bytes.NewBuffer(nil)
+	builder = NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheetWithTypes("Orders", []string{"Item", "Quantity", "Price", "Date", "Shipped", "Note"},
+		[]ColumnType{ColumnTypeString, ColumnTypeInt, ColumnTypeFloat, ColumnTypeDate, ColumnTypeBool}), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"Pens", "3", "2.5", "2024-03-15", "true", "007"}), IsNil)
+	err = stream.WriteAllContinueOnError([][]string{
+		{"Ink", "one", "5", "2024-03-16", "false", ""},
+		{"Ink", "1", "", "2024-03-16T10:00:00Z", "0", ""},
+	})
+	t.Assert(err, ErrorMatches, `row 0: cell Orders!B3: "one" isn't an integer`)
+	t.Assert(stream.BeginRow(), IsNil)
+	for _, value := range []string{"Paper", "10", "0.5", "2024-03-17", "1", "note"} {
+		t.Assert(stream.WriteCell(value), IsNil)
+	}
+	t.Assert(stream.EndRow(), IsNil)
+	t.Assert(stream.Close(), IsNil)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	quantity, err := sheet.Cell(1, 1).Int()
+	t.Assert(err, IsNil)
+	t.Assert(quantity, Equals, 3)
+	t.Assert(sheet.Cell(1, 1).Type(), Equals, CellTypeNumeric)
+	t.Assert(sheet.Cell(1, 2).Type(), Equals, CellTypeNumeric)
+	date, err := sheet.Cell(1, 3).GetTime(false)
+	t.Assert(err, IsNil)
+	t.Assert(date.Equal(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)), Equals, true)
+	t.Assert(sheet.Cell(1, 4).Bool(), Equals, true)
+	t.Assert(sheet.Cell(1, 5).Value, Equals, "007")
+	// Empty values are written as empty strings.
+	t.Assert(sheet.Cell(2, 2).Type(), Equals, CellTypeInline)
+	t.Assert(sheet.Cell(2, 4).Bool(), Equals, false)
+	paper, err := sheet.Cell(3, 2).Float()
+	t.Assert(err, IsNil)
+	t.Assert(paper, Equals, 0.5)
+
+	builder = NewStreamFileBuilder(bytes.NewBuffer(nil))
+	t.Assert(builder.AddSheetWithTypes("Orders", []string{"Quantity"}, []ColumnType{ColumnTypeInt}), IsNil)
+	stream, err = builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.BeginRow(), IsNil)
+	t.Assert(stream.WriteCell("1.5"), ErrorMatches, `cell Orders!A2: "1.5" isn't an integer`)
+}
+
+func (s *StreamSuite) TestSetLeadingZeros(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	types := []ColumnType{ColumnTypeInt, ColumnTypeString, ColumnTypeInt}
+	t.Assert(builder.AddSheetWithTypes("Customers", []string{"Zip", "Account", "Visits"}, types), IsNil)
+	t.Assert(builder.SetLeadingZeros(1, 0, 0), ErrorMatches, "no sheet at index 1")
+	t.Assert(builder.SetLeadingZeros(0, 3, 0), ErrorMatches, "no column at index 3 in sheet 'Customers'")
+	t.Assert(builder.SetLeadingZeros(0, 1, 16), ErrorMatches, "digits must be between 0 and 15")
+	t.Assert(builder.SetLeadingZeros(0, 0, 0), IsNil)
+	t.Assert(builder.SetLeadingZeros(0, 1, 8), IsNil)
+	// The types given to AddSheetWithTypes are left as they were.
+	t.Assert(types[0], Equals, ColumnTypeInt)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"02134", "00012345", "3"}), IsNil)
+	t.Assert(stream.Write([]string{"10001-0001", "AB-12", "4"}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(builder.SetLeadingZeros(0, 0, 0), Equals, BuiltStreamFileBuilderError)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	sheets, err := f.ToSlice()
+	t.Assert(err, IsNil)
+	t.Assert(sheets[0], DeepEquals, [][]string{
+		{"Zip", "Account", "Visits"},
+		{"02134", "00012345", "3"},
+		{"10001-0001", "AB-12", "4"},
+	})
+	sheet := f.Sheets[0]
+	t.Assert(sheet.Cell(1, 0).Type(), Equals, CellTypeInline)
+	t.Assert(sheet.Cell(1, 1).Type(), Equals, CellTypeNumeric)
+	t.Assert(sheet.Cell(1, 1).Value, Equals, "12345")
+	t.Assert(sheet.Cell(2, 1).Type(), Equals, CellTypeInline)
+	t.Assert(sheet.Cell(1, 2).Type(), Equals, CellTypeNumeric)
+	t.Assert(sheet.IgnoredErrors, HasLen, 1)
+	t.Assert(sheet.IgnoredErrors[0].Ref, Equals, "A2:A1048576")
+	t.Assert(sheet.IgnoredErrors[0].NumberStoredAsText, Equals, true)
+}
+
+func (s *StreamSuite) TestAddStyle(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Sales", []string{"Region", "Amount"}, nil), IsNil)
+	t.Assert(builder.SetBandedRows(0, "FFDDEBF7"), IsNil)
+	_, err := builder.AddStyle(nil, "")
+	t.Assert(err, ErrorMatches, "a style can't be nil")
+	bold := NewStyle()
+	bold.Font.Bold = true
+	bold.ApplyFont = true
+	boldId, err := builder.AddStyle(bold, "")
+	t.Assert(err, IsNil)
+	t.Assert(boldId, Equals, 1)
+	red := NewStyle()
+	red.Fill = *NewFill("solid", "FFFF0000", "FFFF0000")
+	red.ApplyFill = true
+	moneyId, err := builder.AddStyle(red, "#,##0.00")
+	t.Assert(err, IsNil)
+	t.Assert(moneyId, Equals, 2)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	_, err = builder.AddStyle(bold, "")
+	t.Assert(err, Equals, BuiltStreamFileBuilderError)
+	t.Assert(stream.WriteStyled([]string{"North", "10"}, []int{boldId, 0}), IsNil)
+	t.Assert(stream.WriteTyped([]StreamCell{NewStringStreamCell("South"), NewFloatStreamCell(1234.5).WithStyle(moneyId)}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	t.Assert(sheet.Cell(1, 0).GetStyle().Font.Bold, Equals, true)
+	t.Assert(sheet.Cell(1, 1).GetStyle().Font.Bold, Equals, false)
+	// The style of a cell replaces that of its banded row.
+	t.Assert(sheet.Cell(2, 0).GetStyle().Fill.FgColor, Equals, "FFDDEBF7")
+	t.Assert(sheet.Cell(2, 1).GetStyle().Fill.FgColor, Equals, "FFFF0000")
+	t.Assert(sheet.Cell(2, 1).GetNumberFormat(), Equals, "#,##0.00")
+	t.Assert(sheet.Cell(2, 1).Type(), Equals, CellTypeNumeric)
+
+	builder = NewStreamFileBuilder(bytes.NewBuffer(nil))
+	t.Assert(builder.AddSheet("Sales", []string{"Region"}, nil), IsNil)
+	stream, err = builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.WriteStyled([]string{"West"}, []int{3}), ErrorMatches, "cell 0 of the row: style id 3 wasn't added with AddStyle")
+	stream.err = nil
+	t.Assert(stream.WriteStyled([]string{"East"}, []int{0, 0}), ErrorMatches, "2 style ids given for 1 cells")
+}
+
+func (s *StreamSuite) TestSetFlushInterval(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Notes", []string{"Note"}, nil), IsNil)
+	t.Assert(builder.SetCompression(CompressionStore), IsNil)
+	t.Assert(builder.SetFlushInterval(-1, 0), ErrorMatches, "invalid flush interval of -1 rows and 0 bytes, .*")
+	t.Assert(builder.SetFlushInterval(3, 0), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(builder.SetFlushInterval(1, 0), Equals, BuiltStreamFileBuilderError)
+	// The rows reach the writer every third row, counted from the
+	// last time they were flushed.
+	t.Assert(stream.Write([]string{"first"}), IsNil)
+	stream.Flush()
+	t.Assert(stream.Error(), IsNil)
+	size := buffer.Len()
+	t.Assert(stream.Write([]string{"second"}), IsNil)
+	t.Assert(stream.Write([]string{"third"}), IsNil)
+	t.Assert(buffer.Len(), Equals, size)
+	t.Assert(stream.Write([]string{"fourth"}), IsNil)
+	t.Assert(buffer.Len() > size, Equals, true)
+	size = buffer.Len()
+	t.Assert(stream.Write([]string{"fifth"}), IsNil)
+	t.Assert(buffer.Len(), Equals, size)
+	// Flush writes them whatever the interval.
+	stream.Flush()
+	t.Assert(stream.Error(), IsNil)
+	t.Assert(buffer.Len() > size, Equals, true)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(VerifyStreamedFile(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()), stream), IsNil)
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	t.Assert(f.Sheets[0].Cell(5, 0).Value, Equals, "fifth")
+
+	// The rows reach the writer once they hold enough bytes.
+	buffer = bytes.NewBuffer(nil)
+	builder = NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Notes", []string{"Note"}, nil), IsNil)
+	t.Assert(builder.SetCompression(CompressionStore), IsNil)
+	t.Assert(builder.SetFlushInterval(0, 250), IsNil)
+	stream, err = builder.Build()
+	t.Assert(err, IsNil)
+	note := strings.Repeat("x", 100)
+	t.Assert(stream.Write([]string{note}), IsNil)
+	stream.Flush()
+	size = buffer.Len()
+	t.Assert(stream.Write([]string{note}), IsNil)
+	t.Assert(buffer.Len(), Equals, size)
+	t.Assert(stream.Write([]string{note}), IsNil)
+	t.Assert(buffer.Len() > size, Equals, true)
+	t.Assert(stream.Close(), IsNil)
+	t.Assert(VerifyStreamedFile(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()), stream), IsNil)
+}
+
+func (s *StreamSuite) TestTypeWarnings(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	numeric := CellTypeNumeric
+	t.Assert(builder.AddSheet("Orders", []string{"Client", "Amount"}, []*CellType{nil, &numeric}), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	for _, amount := range []string{"12.5", "N/A", "", "1,234.5", "-3", "n/a", "TBD"} {
+		t.Assert(stream.Write([]string{"client", amount}), IsNil)
+	}
+	t.Assert(stream.WriteTyped([]StreamCell{NewStringStreamCell("typed"), NewStringStreamCell("none")}), IsNil)
+	t.Assert(stream.Close(), IsNil)
+	expected := []ColumnTypeWarning{{
+		Sheet:    "Orders",
+		Column:   1,
+		Header:   "Amount",
+		Count:    5,
+		Examples: []string{"N/A", "1,234.5", "n/a"},
+	}}
+	t.Assert(stream.TypeWarnings(), DeepEquals, expected)
+	t.Assert(stream.Summary().TypeWarnings, DeepEquals, expected)
+	t.Assert(expected[0].String(), Equals,
+		`5 values of the numeric column "Amount" of the sheet "Orders" aren't numbers, such as "N/A"`)
+	// The cells are still written, as text.
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	t.Assert(f.Sheets[0].Cell(2, 1).Value, Equals, "N/A")
+}
+
+func (s *StreamSuite) TestSetLongTextPolicy(t *C) {
+	long := strings.Repeat("x", 70000)
+	write := func(policy LongTextPolicy, cells ...StreamCell) (*StreamFile, map[string]string, *File) {
+		buffer := bytes.NewBuffer(nil)
+		builder := NewStreamFileBuilder(buffer)
+		t.Assert(builder.AddSheet("Notes", []string{"Note", "More", "Amount"}, nil), IsNil)
+		t.Assert(builder.SetLongTextPolicy(1, policy), ErrorMatches, "no sheet at index 1")
+		t.Assert(builder.SetLongTextPolicy(0, LongTextPolicy(9)), ErrorMatches, "invalid long text policy 9")
+		t.Assert(builder.SetLongTextPolicy(0, policy), IsNil)
+		stream, err := builder.Build()
+		t.Assert(err, IsNil)
+		t.Assert(stream.WriteTyped(cells), IsNil)
+		t.Assert(stream.Close(), IsNil)
+		_, parts := readOptionsParts(t, buffer.Bytes())
+		f, err := OpenBinary(buffer.Bytes())
+		t.Assert(err, IsNil)
+		return stream, parts, f
+	}
+
+	stream, parts, f := write(KeepLongText, NewStringStreamCell(long), NewStringStreamCell(""), NewIntegerStreamCell(1))
+	t.Assert(f.Sheets[0].Cell(1, 0).Value, Equals, long)
+	t.Assert(stream.LongTextCells(), DeepEquals, []LongTextCell{{Sheet: "Notes", Cell: "A2", Length: 70000}})
+	t.Assert(stream.Summary().LongTextCells, HasLen, 1)
+	t.Assert(parts["xl/comments1.xml"], Equals, "")
+
+	stream, _, f = write(TruncateLongText, NewStringStreamCell("short"), NewStringStreamCell(long), NewIntegerStreamCell(1))
+	value := f.Sheets[0].Cell(1, 1).Value
+	t.Assert(value, HasLen, MaxCellTextLength)
+	t.Assert(strings.HasSuffix(value, TruncatedTextMarker), Equals, true)
+	t.Assert(stream.LongTextCells()[0], Equals, LongTextCell{Sheet: "Notes", Cell: "B2", Length: 70000, Policy: TruncateLongText})
+
+	// The end of the text follows the note of the cell, if any.
+	stream, parts, f = write(NoteLongText, NewStringStreamCell(long).WithNote("Pasted"), NewStringStreamCell(""), NewIntegerStreamCell(1))
+	t.Assert(f.Sheets[0].Cell(1, 0).Value, HasLen, MaxCellTextLength)
+	t.Assert(strings.Contains(parts["xl/comments1.xml"], `<t xml:space="preserve">Pasted&#xA;&#xA;`+strings.Repeat("x", 70000-MaxCellTextLength)+`</t>`), Equals, true)
+	t.Assert(stream.LongTextCells(), HasLen, 1)
+
+	// The text continues in the empty text cells after it, and is
+	// truncated once they run out.
+	stream, _, f = write(SplitLongText, NewStringStreamCell(long), NewStringStreamCell(""), NewIntegerStreamCell(1))
+	t.Assert(f.Sheets[0].Cell(1, 0).Value, HasLen, MaxCellTextLength)
+	t.Assert(f.Sheets[0].Cell(1, 1).Value, HasLen, MaxCellTextLength)
+	t.Assert(strings.HasSuffix(f.Sheets[0].Cell(1, 1).Value, TruncatedTextMarker), Equals, true)
+	t.Assert(f.Sheets[0].Cell(1, 2).Value, Equals, "1")
+	t.Assert(stream.LongTextCells()[0].Policy, Equals, SplitLongText)
+
+	// Characters outside the Basic Multilingual Plane count twice.
+	t.Assert(excelLength("a😀"), Equals, 3)
+	head, rest := cutText("a😀b", 2)
+	t.Assert(head, Equals, "a")
+	t.Assert(rest, Equals, "😀b")
+}
+
+func (s *StreamSuite) TestProvenance(t *C) {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewStreamFileBuilder(buffer)
+	t.Assert(builder.AddSheet("Orders", []string{"Order", "Customer"}, nil), IsNil)
+	t.Assert(builder.AddSheet("Lines", []string{"Order", "Product"}, nil), IsNil)
+	stream, err := builder.Build()
+	t.Assert(err, IsNil)
+	t.Assert(stream.Write([]string{"1", "Jane"}), IsNil)
+	t.Assert(stream.SetRowProvenance("erp:order/1"), IsNil)
+	t.Assert(stream.SetCellProvenance(1, "crm:42"), IsNil)
+	t.Assert(stream.SetCellProvenance(-1, "crm:42"), ErrorMatches, "invalid column -1 to tag")
+	t.Assert(stream.NextSheet(), IsNil)
+	t.Assert(stream.SetRowProvenance("none"), IsNil)
+	t.Assert(stream.Write([]string{"1", "Pen"}), IsNil)
+	t.Assert(stream.SetCellProvenance(1, "erp:line/7"), IsNil)
+	t.Assert(stream.Close(), IsNil)
+
+	_, parts := readOptionsParts(t, buffer.Bytes())
+	t.Assert(strings.Contains(parts["xl/_rels/workbook.xml.rels"], `Target="../customXml/item1.xml" Type="`+relationshipTypeCustomXml+`"`), Equals, true)
+	f, err := OpenBinary(buffer.Bytes())
+	t.Assert(err, IsNil)
+	tags, err := f.Provenance()
+	t.Assert(err, IsNil)
+	t.Assert(tags, DeepEquals, []Provenance{
+		{Sheet: "Orders", Ref: "2:2", Tag: "erp:order/1"},
+		{Sheet: "Orders", Ref: "B2", Tag: "crm:42"},
+		{Sheet: "Lines", Ref: "1:1", Tag: "none"},
+		{Sheet: "Lines", Ref: "B2", Tag: "erp:line/7"},
+	})
+}
+
+func (s *StreamSuite) TestSetLocalization(t *C) {
+	messages := map[string]map[string]string{
+		"de-DE": {"sheet.orders": "Bestellungen", "header.amount": "Betrag", "sheet.returns": "Retouren"},
+		"fr-FR": {"sheet.orders": "Commandes", "header.amount": "Montant"},
+	}
+	translate := func(locale, key string) (string, bool) {
+		text, ok := messages[locale][key]
+		return text, ok
+	}
+	// The same code writes the export for each locale.
+	export := func(locale string) (*StreamFile, *File) {
+		buffer := bytes.NewBuffer(nil)
+		builder := NewStreamFileBuilder(buffer)
+		t.Assert(builder.SetLocalization(locale, nil), ErrorMatches, "a localization needs a translator")
+		t.Assert(builder.SetLocalization(locale, translate), IsNil)
+		t.Assert(builder.AddSheetWithTypes("sheet.orders", []string{"SKU", "header.amount"}, []ColumnType{ColumnTypeString, ColumnTypeFloat}), IsNil)
+		t.Assert(builder.SetLocalization(locale, translate), ErrorMatches, "the localization must be set before the sheets are added")
+		stream, err := builder.Build()
+		t.Assert(err, IsNil)
+		t.Assert(stream.Write([]string{"A-1", "12.5"}), IsNil)
+		t.Assert(stream.AddSheet("sheet.returns", []string{"SKU"}), IsNil)
+		t.Assert(stream.Close(), IsNil)
+		f, err := OpenBinary(buffer.Bytes())
+		t.Assert(err, IsNil)
+		return stream, f
+	}
+
+	stream, f := export("de-DE")
+	t.Assert(f.Sheets[0].Name, Equals, "Bestellungen")
+	t.Assert(f.Sheets[1].Name, Equals, "Retouren")
+	t.Assert(f.Sheets[0].Cell(0, 0).Value, Equals, "SKU")
+	t.Assert(f.Sheets[0].Cell(0, 1).Value, Equals, "Betrag")
+	t.Assert(stream.MissingTranslations(), DeepEquals, []string{"SKU"})
+
+	stream, f = export("fr-FR")
+	t.Assert(f.Sheets[0].Name, Equals, "Commandes")
+	t.Assert(f.Sheets[0].Cell(0, 1).Value, Equals, "Montant")
+	t.Assert(f.Sheets[1].Name, Equals, "sheet.returns")
+	t.Assert(stream.Summary().MissingTranslations, DeepEquals, []string{"SKU", "sheet.returns"})
+}
+
+// BenchmarkStreamFile1000Sheets builds and closes a file of 1,000 sheets, half of them added while streaming, such as
+// the per-client tabs of exports, whose time should grow linearly with the number of sheets.
+func BenchmarkStreamFile1000Sheets(b *testing.B) {
+	const sheets = 1000
+	headers := []string{"Date", "Client", "Amount"}
+	types := []ColumnType{ColumnTypeDate, ColumnTypeString, ColumnTypeFloat}
+	for i := 0; i < b.N; i++ {
+		builder := NewStreamFileBuilder(ioutil.Discard)
+		for sheet := 0; sheet < sheets/2; sheet++ {
+			if err := builder.AddSheetWithTypes("Client "+strconv.Itoa(sheet), headers, types); err != nil {
+				b.Fatal(err)
+			}
+		}
+		stream, err := builder.Build()
+		if err != nil {
+			b.Fatal(err)
+		}
+		for sheet := sheets / 2; sheet < sheets; sheet++ {
+			if err := stream.AddSheet("Client "+strconv.Itoa(sheet), headers); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := stream.Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkStreamFileRows streams rows of text, numbers and dates to a file, such as the rows of large exports, whose
+// time and allocations are those of writing the rows.
+func BenchmarkStreamFileRows(b *testing.B) {
+	const rows = 10000
+	headers := []string{"Date", "Client", "Notes", "Quantity", "Amount"}
+	types := []ColumnType{ColumnTypeDate, ColumnTypeString, ColumnTypeString, ColumnTypeInt, ColumnTypeFloat}
+	row := []string{"2024-03-15", "Smith & Sons", "Delivered <by hand>\nSigned \"J. Smith\"", "42", "1234.5"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		builder := NewStreamFileBuilder(ioutil.Discard)
+		if err := builder.AddSheetWithTypes("Orders", headers, types); err != nil {
+			b.Fatal(err)
+		}
+		stream, err := builder.Build()
+		if err != nil {
+			b.Fatal(err)
+		}
+		for j := 0; j < rows; j++ {
+			if err := stream.Write(row); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := stream.Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
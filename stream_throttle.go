@@ -0,0 +1,111 @@
+package xlsx
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StreamThrottle limits the pace at which a StreamFile writes, see
+// StreamFileBuilder.SetThrottle, so that a file streamed to a client on
+// a slow link leaves room for the other traffic sharing it.  A limit of
+// 0 leaves the pace unlimited.
+type StreamThrottle struct {
+	// RowsPerSecond is the most rows written per second, the header
+	// rows aside.
+	RowsPerSecond float64
+	// BytesPerSecond is the most bytes of the file, once compressed,
+	// written to the writer per second.
+	BytesPerSecond float64
+}
+
+// maxThrottleBurst is the longest a throttle may have been idle for,
+// past which the units it could have let through meanwhile are lost,
+// so that a pause in the writes isn't followed by a burst.
+const maxThrottleBurst = time.Second
+
+// throttle paces units, such as rows or bytes, to a rate per second,
+// sleeping until the units let through since start would have taken
+// their time at that rate.  now and sleep are those of the time
+// package, unless replaced by tests.
+type throttle struct {
+	// mu lets the sheets spooled by StreamFile.SpoolSheets share the
+	// throttle.
+	mu    sync.Mutex
+	rate  float64
+	start time.Time
+	units float64
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// newThrottle returns a throttle pacing units to rate per second, or
+// nil for a rate of 0.
+func newThrottle(rate float64) *throttle {
+	if rate <= 0 {
+		return nil
+	}
+	return &throttle{rate: rate, now: time.Now, sleep: time.Sleep}
+}
+
+// wait waits until n more units can be let through.  A nil throttle
+// lets every unit through at once.
+func (t *throttle) wait(n int) {
+	if t == nil || n <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	elapsed := now.Sub(t.start)
+	if t.start.IsZero() || elapsed-t.due() > maxThrottleBurst {
+		t.start, t.units, elapsed = now, 0, 0
+	}
+	t.units += float64(n)
+	if wait := t.due() - elapsed; wait > 0 {
+		t.sleep(wait)
+	}
+}
+
+// due returns the time the units let through since start take at the
+// rate of the throttle.
+func (t *throttle) due() time.Duration {
+	return time.Duration(t.units / t.rate * float64(time.Second))
+}
+
+// throttledWriter is the writer of the zip writer of a StreamFile,
+// writing to writer at the pace of its throttle, if any, within its
+// deadline, and counting the bytes written for the ProgressHook.
+type throttledWriter struct {
+	writer   io.Writer
+	throttle *throttle
+	deadline writeDeadline
+	// written is the number of bytes written to writer, see
+	// bytesWritten.
+	written int64
+}
+
+func (tw *throttledWriter) Write(p []byte) (int, error) {
+	tw.throttle.wait(len(p))
+	n, err := tw.deadline.write(tw.writer, p)
+	atomic.AddInt64(&tw.written, int64(n))
+	return n, err
+}
+
+// bytesWritten returns the number of bytes written so far, which the
+// sheets spooled by StreamFile.SpoolSheets may ask for while the file
+// is written.
+func (tw *throttledWriter) bytesWritten() int64 {
+	return atomic.LoadInt64(&tw.written)
+}
+
+// validateStreamThrottle returns an error for the throttles with
+// negative limits.
+func validateStreamThrottle(throttle StreamThrottle) error {
+	if throttle.RowsPerSecond < 0 || throttle.BytesPerSecond < 0 {
+		return errors.New("the limits of a throttle can't be negative")
+	}
+	return nil
+}
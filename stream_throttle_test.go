@@ -0,0 +1,38 @@
+package xlsx
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type ThrottleSuite struct{}
+
+var _ = Suite(&ThrottleSuite{})
+
+func (s *ThrottleSuite) TestThrottleWait(c *C) {
+	c.Assert(newThrottle(0), IsNil)
+	// A nil throttle lets everything through.
+	var none *throttle
+	none.wait(100)
+
+	now := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	var slept []time.Duration
+	t := newThrottle(10)
+	t.now = func() time.Time { return now }
+	t.sleep = func(d time.Duration) {
+		slept = append(slept, d)
+		now = now.Add(d)
+	}
+	t.wait(5)
+	t.wait(5)
+	c.Assert(slept, DeepEquals, []time.Duration{500 * time.Millisecond, 500 * time.Millisecond})
+	// Time spent elsewhere counts towards the next units.
+	now = now.Add(300 * time.Millisecond)
+	t.wait(5)
+	c.Assert(slept[2], Equals, 200*time.Millisecond)
+	// A long pause doesn't let a burst through.
+	now = now.Add(time.Minute)
+	t.wait(20)
+	c.Assert(slept[3], Equals, 2*time.Second)
+}
@@ -0,0 +1,77 @@
+package xlsx
+
+import (
+	"fmt"
+)
+
+// maxTypeWarningExamples is the number of values a ColumnTypeWarning
+// keeps as examples.
+const maxTypeWarningExamples = 3
+
+// ColumnTypeWarning reports the values that aren't numbers written as
+// text to a column declared numeric by the cell types given to
+// StreamFileBuilder.AddSheet, which usually comes from a bug upstream,
+// such as a column of amounts holding "N/A" or "1,234.5".  Column is the
+// index of the column in the sheet, from 0, and Header the value of its
+// header cell.  Count is the number of such values, and Examples the
+// first of them.
+type ColumnTypeWarning struct {
+	Sheet    string
+	Column   int
+	Header   string
+	Count    int
+	Examples []string
+}
+
+// String returns a description of the ColumnTypeWarning.
+func (w ColumnTypeWarning) String() string {
+	return fmt.Sprintf("%d values of the numeric column %q of the sheet %q aren't numbers, such as %q", w.Count,
+		w.Header, w.Sheet, w.Examples[0])
+}
+
+// TypeWarnings returns a ColumnTypeWarning for each column declared
+// numeric that text which isn't a number has been written to so far, in
+// the order they were found.  Close still writes the cells, as text, so
+// the warnings are best checked once it has returned, see Summary.
+func (sf *StreamFile) TypeWarnings() []ColumnTypeWarning {
+	warnings := make([]ColumnTypeWarning, len(sf.typeWarnings))
+	for i, warning := range sf.typeWarnings {
+		warning.Examples = append([]string(nil), warning.Examples...)
+		warnings[i] = warning
+	}
+	return warnings
+}
+
+// checkColumnType records a ColumnTypeWarning if value, written as text
+// to the column at colIndex of ss, isn't a number although the column is
+// declared numeric.
+func (sf *StreamFile) checkColumnType(ss *streamSheet, colIndex int, value string) {
+	if colIndex >= len(ss.numericColumns) || !ss.numericColumns[colIndex] || value == "" {
+		return
+	}
+	if _, err := parseFloat(value); err == nil {
+		return
+	}
+	sheet := sf.xlsxFile.Sheets[ss.index-1]
+	for i := range sf.typeWarnings {
+		warning := &sf.typeWarnings[i]
+		if warning.Sheet == sheet.Name && warning.Column == colIndex {
+			warning.Count++
+			if len(warning.Examples) < maxTypeWarningExamples {
+				warning.Examples = append(warning.Examples, value)
+			}
+			return
+		}
+	}
+	header := ""
+	if len(sheet.Rows) > 0 && colIndex < len(sheet.Rows[0].Cells) {
+		header = sheet.Rows[0].Cells[colIndex].Value
+	}
+	sf.typeWarnings = append(sf.typeWarnings, ColumnTypeWarning{
+		Sheet:    sheet.Name,
+		Column:   colIndex,
+		Header:   header,
+		Count:    1,
+		Examples: []string{value},
+	})
+}
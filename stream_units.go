@@ -0,0 +1,91 @@
+package xlsx
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Unit converts the values of a column of a streamed sheet written in a
+// canonical unit, such as bytes or milliseconds, to the unit they are
+// shown in, such as megabytes or seconds, see
+// StreamFileBuilder.SetColumnUnit.
+type Unit struct {
+	// Divisor is the number of canonical units in a unit shown, such
+	// as 1,048,576 bytes in a megabyte.
+	Divisor float64
+	// Format is the number format of the converted values, such as
+	// `#,##0.0 "MB"`.
+	Format string
+}
+
+// The units of the common canonical units.  CentsAsCurrency shows
+// amounts without currency symbol; a Unit of Divisor 100 and of the
+// format returned by CurrencyNumberFormat shows them with that of a
+// currency.
+var (
+	BytesAsKilobytes      = Unit{Divisor: 1 << 10, Format: `#,##0.0 "KB"`}
+	BytesAsMegabytes      = Unit{Divisor: 1 << 20, Format: `#,##0.0 "MB"`}
+	BytesAsGigabytes      = Unit{Divisor: 1 << 30, Format: `#,##0.00 "GB"`}
+	MillisecondsAsSeconds = Unit{Divisor: 1000, Format: `#,##0.000 "s"`}
+	// MillisecondsAsDuration shows milliseconds as hours, minutes and
+	// seconds, such as 26:03:07.250, the values being converted to
+	// days as Excel counts time.
+	MillisecondsAsDuration = Unit{Divisor: 24 * 60 * 60 * 1000, Format: "[h]:mm:ss.000"}
+	CentsAsCurrency        = Unit{Divisor: 100, Format: "#,##0.00"}
+)
+
+// convert returns value, a number in the canonical unit, in the unit
+// shown, or value itself if it isn't a number.
+func (u *Unit) convert(value string) (string, bool) {
+	number, err := parseFloat(value)
+	if err != nil {
+		return value, false
+	}
+	return strconv.FormatFloat(number/u.Divisor, 'g', -1, 64), true
+}
+
+// SetColumnUnit makes the values written to a column of a sheet, in a canonical unit such as bytes, be converted to
+// the unit they are shown in, such as megabytes with BytesAsMegabytes, and given its number format, so that the code
+// writing the rows keeps to the canonical units. The values that are numbers are written as numbers, whatever the type
+// of the column, the others being written as they are. The style of the column, see SetColumnStyle, is kept. The
+// cells of formula columns and formula cells aren't converted.
+func (sb *StreamFileBuilder) SetColumnUnit(sheetIndex, colIndex int, unit Unit) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if sheetIndex < 0 || sheetIndex >= len(sb.xlsxFile.Sheets) {
+		return fmt.Errorf("no sheet at index %d", sheetIndex)
+	}
+	sheet := sb.xlsxFile.Sheets[sheetIndex]
+	if colIndex < 0 || colIndex >= len(sheet.Cols) {
+		return fmt.Errorf("no column at index %d in sheet '%s'", colIndex, sheet.Name)
+	}
+	if !(unit.Divisor > 0) || math.IsInf(unit.Divisor, 1) {
+		return fmt.Errorf("invalid unit divisor %g", unit.Divisor)
+	}
+	for len(sb.columnUnits[sheetIndex]) <= colIndex {
+		sb.columnUnits[sheetIndex] = append(sb.columnUnits[sheetIndex], nil)
+	}
+	sb.columnUnits[sheetIndex][colIndex] = &unit
+	// The number format is given to the cells of the column by its style.
+	sheet.Cols[colIndex].numFmt = unit.Format
+	for len(sb.columnStyles[sheetIndex]) <= colIndex {
+		sb.columnStyles[sheetIndex] = append(sb.columnStyles[sheetIndex], nil)
+	}
+	if sb.columnStyles[sheetIndex][colIndex] == nil {
+		sb.columnStyles[sheetIndex][colIndex] = NewStyle()
+	}
+	return nil
+}
+
+// unit returns the Unit of the given column of the sheet, or of its
+// follow-on sheets, or nil if the values of the column aren't
+// converted.
+func (ss *streamSheet) unit(colIndex int) *Unit {
+	sheet, colIndex := ss.column(colIndex)
+	if colIndex < len(sheet.units) {
+		return sheet.units[colIndex]
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package xlsx
+
+import (
+	"errors"
+	"fmt"
+)
+
+// streamList is a list of allowed values kept on a hidden sheet, added
+// when the file is built, for the columns given a dropdown by
+// StreamFileBuilder.AddListValidation.
+type streamList struct {
+	sheetName string
+	values    []string
+	// columns holds the sheet and column indexes of the columns whose
+	// dropdowns list the values.
+	columns [][2]int
+}
+
+// AddValidationRange adds validation, such as a list of values made with SetDropList or a range of numbers made with
+// SetRange, to the cells of a sheet from the column and row startCol and startRow to endCol and endRow, which start at
+// 0, endRow being -1 for the rest of the columns. Since the header is the first row, the rows written to the sheet
+// start at 1. Each column is given a copy of validation, which can't be changed afterwards.
+func (sb *StreamFileBuilder) AddValidationRange(sheetIndex, startCol, endCol, startRow, endRow int, validation *xlsxCellDataValidation) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if sheetIndex < 0 || sheetIndex >= len(sb.xlsxFile.Sheets) {
+		return fmt.Errorf("no sheet at index %d", sheetIndex)
+	}
+	sheet := sb.xlsxFile.Sheets[sheetIndex]
+	for _, colIndex := range []int{startCol, endCol} {
+		if colIndex < 0 || colIndex >= len(sheet.Cols) {
+			return fmt.Errorf("no column at index %d in sheet '%s'", colIndex, sheet.Name)
+		}
+	}
+	if validation == nil {
+		return errors.New("a validation can't be nil")
+	}
+	if endCol < startCol || startRow < 0 || (endRow >= 0 && endRow < startRow) || endRow > Excel2006MaxRowIndex {
+		return fmt.Errorf("invalid range of cells from column %d and row %d to column %d and row %d", startCol, startRow, endCol, endRow)
+	}
+	for colIndex := startCol; colIndex <= endCol; colIndex++ {
+		columnValidation := *validation
+		sheet.Cols[colIndex].SetDataValidation(&columnValidation, startRow, endRow)
+	}
+	return nil
+}
+
+// AddListValidation gives the cells of a column of a sheet, below its header, a dropdown of values, which are kept in
+// the first column of a hidden sheet called listSheet, added after the other sheets when the file is built, rather
+// than in the validation itself, which Excel limits to 255 characters. Columns may share a list sheet by giving it
+// the same values. Since the list sheet is the last one, the other sheets keep their indexes; it is written by Close
+// without needing NextSheet.
+func (sb *StreamFileBuilder) AddListValidation(sheetIndex, colIndex int, listSheet string, values []string) error {
+	if sb.built {
+		return BuiltStreamFileBuilderError
+	}
+	if sheetIndex < 0 || sheetIndex >= len(sb.xlsxFile.Sheets) {
+		return fmt.Errorf("no sheet at index %d", sheetIndex)
+	}
+	if colIndex < 0 || colIndex >= len(sb.xlsxFile.Sheets[sheetIndex].Cols) {
+		return fmt.Errorf("no column at index %d in sheet '%s'", colIndex, sb.xlsxFile.Sheets[sheetIndex].Name)
+	}
+	if len(values) == 0 {
+		return errors.New("a list validation needs values")
+	}
+	for i := range sb.lists {
+		list := &sb.lists[i]
+		if list.sheetName != listSheet {
+			continue
+		}
+		if !equalStrings(list.values, values) {
+			return fmt.Errorf("the list sheet '%s' already holds other values", listSheet)
+		}
+		list.columns = append(list.columns, [2]int{sheetIndex, colIndex})
+		return nil
+	}
+	sb.lists = append(sb.lists, streamList{
+		sheetName: listSheet,
+		values:    append([]string(nil), values...),
+		columns:   [][2]int{{sheetIndex, colIndex}},
+	})
+	return nil
+}
+
+// addListSheets adds the hidden sheets holding the values of the lists, and the validations of the columns listing
+// them.
+func (sb *StreamFileBuilder) addListSheets() error {
+	for _, list := range sb.lists {
+		if err := sb.addSheet(list.sheetName, list.values[:1], nil); err != nil {
+			return err
+		}
+		sheet := sb.xlsxFile.Sheets[len(sb.xlsxFile.Sheets)-1]
+		sheet.Hidden = true
+		for _, value := range list.values[1:] {
+			sheet.AddRow().AddCell().SetString(value)
+		}
+		for _, column := range list.columns {
+			validation := NewXlsxCellDataValidation(true)
+			// The sheet may have been renamed, see SetRenameDuplicateSheets.
+			if err := validation.SetInFileList(sheet.Name, 0, 0, 0, len(list.values)-1); err != nil {
+				return err
+			}
+			sb.xlsxFile.Sheets[column[0]].Cols[column[1]].SetDataValidationWithStart(validation, 1)
+		}
+	}
+	return nil
+}
+
+// equalStrings returns true if a and b hold the same strings.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,8 +1,11 @@
 package xlsx
 
 import (
+	"bytes"
+	"encoding/xml"
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 type theme struct {
@@ -45,3 +48,141 @@ func (t *theme) themeColor(index int64, tint float64) string {
 		return fmt.Sprintf("FF%02X%02X%02X", br, bg, bb)
 	}
 }
+
+// Theme holds the colors and fonts of the theme of a workbook, which
+// styles can refer to, see File.Theme.  Colors are given as six
+// hexadecimal digits, such as "4F81BD".
+type Theme struct {
+	Name string
+	// Dark1 and Light1 are the colors of text and background, Dark2
+	// and Light2 their alternatives.
+	Dark1  string
+	Light1 string
+	Dark2  string
+	Light2 string
+	// Accents are the six accent colors, used by charts, tables and
+	// the theme colors of the palette.
+	Accents           [6]string
+	Hyperlink         string
+	FollowedHyperlink string
+	// MajorFont is the font of headings, MinorFont that of the body
+	// of the workbook, such as its cells.
+	MajorFont string
+	MinorFont string
+}
+
+// builtinThemes are the themes returned by BuiltinTheme, by name.
+var builtinThemes = map[string]Theme{
+	"Office": {
+		Name:              "Office",
+		Dark1:             "000000",
+		Light1:            "FFFFFF",
+		Dark2:             "1F497D",
+		Light2:            "EEECE1",
+		Accents:           [6]string{"4F81BD", "C0504D", "9BBB59", "8064A2", "4BACC6", "F79646"},
+		Hyperlink:         "0000FF",
+		FollowedHyperlink: "800080",
+		MajorFont:         "Cambria",
+		MinorFont:         "Arial",
+	},
+	"Office 2013": {
+		Name:              "Office 2013",
+		Dark1:             "000000",
+		Light1:            "FFFFFF",
+		Dark2:             "44546A",
+		Light2:            "E7E6E6",
+		Accents:           [6]string{"5B9BD5", "ED7D31", "A5A5A5", "FFC000", "4472C4", "70AD47"},
+		Hyperlink:         "0563C1",
+		FollowedHyperlink: "954F72",
+		MajorFont:         "Calibri Light",
+		MinorFont:         "Calibri",
+	},
+	"Grayscale": {
+		Name:              "Grayscale",
+		Dark1:             "000000",
+		Light1:            "FFFFFF",
+		Dark2:             "000000",
+		Light2:            "F8F8F8",
+		Accents:           [6]string{"DDDDDD", "B2B2B2", "969696", "808080", "5F5F5F", "4D4D4D"},
+		Hyperlink:         "5F5F5F",
+		FollowedHyperlink: "919191",
+		MajorFont:         "Arial",
+		MinorFont:         "Arial",
+	},
+	"Blue": {
+		Name:              "Blue",
+		Dark1:             "000000",
+		Light1:            "FFFFFF",
+		Dark2:             "17406D",
+		Light2:            "DBEFF9",
+		Accents:           [6]string{"0F6FC6", "009DD9", "0BD0D9", "10CF9B", "7CCA62", "A5C249"},
+		Hyperlink:         "F49100",
+		FollowedHyperlink: "85DFD0",
+		MajorFont:         "Calibri",
+		MinorFont:         "Calibri",
+	},
+}
+
+// BuiltinTheme returns a copy of the built-in theme with the given
+// name, "Office", which is the theme of new files, "Office 2013",
+// "Grayscale" or "Blue", to be used as is or as a base for a custom
+// Theme.
+func BuiltinTheme(name string) (*Theme, error) {
+	t, ok := builtinThemes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme '%s'", name)
+	}
+	return &t, nil
+}
+
+// makeXML returns the theme part of the Theme, made from the default
+// theme, whose effects and formats are kept.
+func (t *Theme) makeXML() (string, error) {
+	colors := []struct{ element, color string }{
+		{"dk1", t.Dark1}, {"lt1", t.Light1}, {"dk2", t.Dark2}, {"lt2", t.Light2},
+		{"accent1", t.Accents[0]}, {"accent2", t.Accents[1]}, {"accent3", t.Accents[2]},
+		{"accent4", t.Accents[3]}, {"accent5", t.Accents[4]}, {"accent6", t.Accents[5]},
+		{"hlink", t.Hyperlink}, {"folHlink", t.FollowedHyperlink},
+	}
+	var clrScheme bytes.Buffer
+	fmt.Fprintf(&clrScheme, `<a:clrScheme name="%s">`, escapeAttr(t.Name))
+	for _, c := range colors {
+		if !isRGBColor(c.color) {
+			return "", fmt.Errorf("invalid %s color '%s' in theme '%s', six hexadecimal digits are expected", c.element, c.color, t.Name)
+		}
+		fmt.Fprintf(&clrScheme, `<a:%s><a:srgbClr val="%s"/></a:%s>`, c.element, strings.ToUpper(c.color), c.element)
+	}
+	clrScheme.WriteString(`</a:clrScheme>`)
+	if t.MajorFont == "" || t.MinorFont == "" {
+		return "", fmt.Errorf("theme '%s' lacks a major or minor font", t.Name)
+	}
+
+	part := TEMPLATE_XL_THEME_THEME
+	start := strings.Index(part, "<a:clrScheme")
+	end := strings.Index(part, "</a:clrScheme>") + len("</a:clrScheme>")
+	part = part[:start] + clrScheme.String() + part[end:]
+	part = strings.Replace(part, `name="Office-Design"`, `name="`+escapeAttr(t.Name)+`"`, 1)
+	part = strings.Replace(part, `<a:latin typeface="Cambria"/>`, `<a:latin typeface="`+escapeAttr(t.MajorFont)+`"/>`, 1)
+	part = strings.Replace(part, `<a:latin typeface="Arial"/>`, `<a:latin typeface="`+escapeAttr(t.MinorFont)+`"/>`, 1)
+	return part, nil
+}
+
+// isRGBColor returns true if color is made of six hexadecimal digits.
+func isRGBColor(color string) bool {
+	if len(color) != 6 {
+		return false
+	}
+	for i := 0; i < len(color); i++ {
+		if !isHexDigit(color[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// escapeAttr escapes s to be written as the value of an XML attribute.
+func escapeAttr(s string) string {
+	var escaped bytes.Buffer
+	xml.EscapeText(&escaped, []byte(s))
+	return escaped.String()
+}
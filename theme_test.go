@@ -3,6 +3,7 @@ package xlsx
 import (
 	"bytes"
 	"encoding/xml"
+	"strings"
 
 	. "gopkg.in/check.v1"
 )
@@ -81,3 +82,52 @@ func (s *ThemeSuite) TestThemeColors(c *C) {
 	c.Assert(theme.themeColor(0, 0), Equals, "FFFFFFFF")
 	c.Assert(theme.themeColor(2, 0), Equals, "FFEEECE1")
 }
+
+func (s *ThemeSuite) TestBuiltinTheme(c *C) {
+	t, err := BuiltinTheme("Office 2013")
+	c.Assert(err, IsNil)
+	c.Assert(t.Accents[0], Equals, "5B9BD5")
+	// Changing the copy leaves the built-in theme unchanged.
+	t.Accents[0] = "000000"
+	t, err = BuiltinTheme("Office 2013")
+	c.Assert(err, IsNil)
+	c.Assert(t.Accents[0], Equals, "5B9BD5")
+	_, err = BuiltinTheme("Corporate")
+	c.Assert(err, ErrorMatches, "unknown theme 'Corporate'")
+}
+
+func (s *ThemeSuite) TestCustomTheme(c *C) {
+	t, err := BuiltinTheme("Office")
+	c.Assert(err, IsNil)
+	t.Name = "Brand & Co"
+	t.Accents[0] = "e4002b"
+	t.MajorFont = "Georgia"
+	t.MinorFont = "Verdana"
+
+	f := NewFile()
+	_, err = f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	f.Theme = t
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	part := parts["xl/theme/theme1.xml"]
+	c.Assert(strings.Contains(part, `<a:clrScheme name="Brand &amp; Co">`), Equals, true)
+	c.Assert(strings.Contains(part, `<a:majorFont>
+        <a:latin typeface="Georgia"/>`), Equals, true)
+	c.Assert(strings.Contains(part, `<a:minorFont>
+        <a:latin typeface="Verdana"/>`), Equals, true)
+
+	var buffer bytes.Buffer
+	c.Assert(f.Write(&buffer), IsNil)
+	f, err = OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(f.theme.colors, DeepEquals, []string{"FFFFFF", "000000", "EEECE1", "1F497D",
+		"E4002B", "C0504D", "9BBB59", "8064A2", "4BACC6", "F79646", "0000FF", "800080"})
+
+	t.Hyperlink = "blue"
+	_, err = f.MarshallParts()
+	c.Assert(err, IsNil)
+	f.Theme = t
+	_, err = f.MarshallParts()
+	c.Assert(err, ErrorMatches, "invalid hlink color 'blue' in theme 'Brand & Co', six hexadecimal digits are expected")
+}
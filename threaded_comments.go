@@ -0,0 +1,395 @@
+package xlsx
+
+import (
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	relationshipTypeThreadedComment = "http://schemas.microsoft.com/office/2017/10/relationships/threadedComment"
+	relationshipTypePerson          = "http://schemas.microsoft.com/office/2017/10/relationships/person"
+	contentTypeThreadedComments     = "application/vnd.ms-excel.threadedcomments+xml"
+	contentTypePersons              = "application/vnd.ms-excel.person+xml"
+
+	// threadedCommentTimeLayout is the layout of the dT attribute of
+	// threaded comments, in local time without a zone.  Any fraction
+	// of a second is accepted when parsing.
+	threadedCommentTimeLayout  = "2006-01-02T15:04:05.00"
+	threadedCommentParseLayout = "2006-01-02T15:04:05"
+)
+
+// ThreadedComment is a modern Excel comment, one that others can reply
+// to, as opposed to the legacy notes.  Threaded comments are read from
+// the parts of a loaded file, or added by Sheet.AddThreadedComment, and
+// are written back on save.
+type ThreadedComment struct {
+	// ID is the GUID identifying the comment.
+	ID string
+	// Ref is the cell the thread is attached to, such as "B2".
+	Ref     string
+	Author  string
+	Created time.Time
+	Text    string
+	// Done is true once the thread has been resolved.  Only the
+	// first comment of a thread carries it.
+	Done    bool
+	Replies []*ThreadedComment
+}
+
+// xlsxThreadedComments directly maps the ThreadedComments element in
+// the namespace
+// http://schemas.microsoft.com/office/spreadsheetml/2018/threadedcomments
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxThreadedComments struct {
+	XMLName         xml.Name              `xml:"http://schemas.microsoft.com/office/spreadsheetml/2018/threadedcomments ThreadedComments"`
+	ThreadedComment []xlsxThreadedComment `xml:"threadedComment"`
+	ExtLst          *xlsxRawElement       `xml:"extLst,omitempty"`
+}
+
+// xlsxThreadedComment directly maps the threadedComment element in the
+// namespace
+// http://schemas.microsoft.com/office/spreadsheetml/2018/threadedcomments
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxThreadedComment struct {
+	Ref      string          `xml:"ref,attr,omitempty"`
+	DT       string          `xml:"dT,attr,omitempty"`
+	PersonId string          `xml:"personId,attr"`
+	Id       string          `xml:"id,attr"`
+	ParentId string          `xml:"parentId,attr,omitempty"`
+	Done     bool            `xml:"done,attr,omitempty"`
+	Text     string          `xml:"text"`
+	Mentions *xlsxRawElement `xml:"mentions,omitempty"`
+	ExtLst   *xlsxRawElement `xml:"extLst,omitempty"`
+}
+
+// xlsxPersonList directly maps the personList element in the namespace
+// http://schemas.microsoft.com/office/spreadsheetml/2018/threadedcomments
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxPersonList struct {
+	XMLName xml.Name        `xml:"http://schemas.microsoft.com/office/spreadsheetml/2018/threadedcomments personList"`
+	Person  []xlsxPerson    `xml:"person"`
+	ExtLst  *xlsxRawElement `xml:"extLst,omitempty"`
+}
+
+// xlsxPerson directly maps the person element in the namespace
+// http://schemas.microsoft.com/office/spreadsheetml/2018/threadedcomments
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxPerson struct {
+	DisplayName string          `xml:"displayName,attr"`
+	Id          string          `xml:"id,attr"`
+	UserId      string          `xml:"userId,attr,omitempty"`
+	ProviderId  string          `xml:"providerId,attr,omitempty"`
+	ExtLst      *xlsxRawElement `xml:"extLst,omitempty"`
+}
+
+// xlsxRawElement keeps the content of an element that isn't modelled,
+// so that it can be written back as it was read.
+type xlsxRawElement struct {
+	Content string `xml:",innerxml"`
+}
+
+// ThreadedComments returns the threads of modern comments of the
+// Sheet, each holding its replies, in the order Excel stores them.
+func (s *Sheet) ThreadedComments() ([]*ThreadedComment, error) {
+	xComments, _, err := s.readThreadedComments()
+	if err != nil || xComments == nil {
+		return nil, err
+	}
+	xPersons, _, err := s.File.readPersons()
+	if err != nil {
+		return nil, err
+	}
+	authors := make(map[string]string)
+	if xPersons != nil {
+		for _, person := range xPersons.Person {
+			authors[person.Id] = person.DisplayName
+		}
+	}
+	var threads []*ThreadedComment
+	byId := make(map[string]*ThreadedComment)
+	for _, xComment := range xComments.ThreadedComment {
+		comment := &ThreadedComment{
+			ID:     xComment.Id,
+			Ref:    xComment.Ref,
+			Author: authors[xComment.PersonId],
+			Text:   xComment.Text,
+			Done:   xComment.Done,
+		}
+		if xComment.DT != "" {
+			comment.Created, err = time.Parse(threadedCommentParseLayout, xComment.DT)
+			if err != nil {
+				return nil, fmt.Errorf("threaded comment %s: %s", xComment.Id, err)
+			}
+		}
+		byId[comment.ID] = comment
+		if parent, ok := byId[xComment.ParentId]; ok {
+			parent.Replies = append(parent.Replies, comment)
+		} else {
+			threads = append(threads, comment)
+		}
+	}
+	return threads, nil
+}
+
+// AddThreadedComment starts a thread of comments on the cell ref, such
+// as "B2", with a comment by author, and returns the comment.  The
+// threaded comments part of the Sheet and the list of people of the
+// workbook are created if the File has none yet.  No legacy note is
+// written along with the thread, so versions of Excel that predate
+// threaded comments don't show it.  A cell holds a single thread, to
+// which ReplyToThreadedComment adds replies.
+func (s *Sheet) AddThreadedComment(ref, author, text string) (*ThreadedComment, error) {
+	if s.File == nil {
+		return nil, fmt.Errorf("the sheet '%s' doesn't belong to a file", s.Name)
+	}
+	if _, row, ok := parseCellID(ref); !ok || row < 0 {
+		return nil, fmt.Errorf("invalid cell reference '%s'", ref)
+	}
+	xComments, partName, err := s.readThreadedComments()
+	if err != nil {
+		return nil, err
+	}
+	if xComments == nil {
+		xComments = &xlsxThreadedComments{}
+		partName = unusedRawPartName(s.File, "xl/threadedComments/threadedComment", ".xml")
+	}
+	for _, xComment := range xComments.ThreadedComment {
+		if xComment.ParentId == "" && xComment.Ref == ref {
+			return nil, fmt.Errorf("the cell %s of sheet '%s' already has a thread of comments", ref, s.Name)
+		}
+	}
+	personId, err := s.File.threadedCommentPerson(author)
+	if err != nil {
+		return nil, err
+	}
+	id, err := newGUID()
+	if err != nil {
+		return nil, err
+	}
+	comment := &ThreadedComment{
+		ID:      id,
+		Ref:     ref,
+		Author:  author,
+		Created: time.Now(),
+		Text:    text,
+	}
+	xComments.ThreadedComment = append(xComments.ThreadedComment, xlsxThreadedComment{
+		Ref:      comment.Ref,
+		DT:       comment.Created.Format(threadedCommentTimeLayout),
+		PersonId: personId,
+		Id:       comment.ID,
+		Text:     text,
+	})
+	if _, exists := s.File.rawParts[partName]; exists {
+		return comment, s.File.setRawXMLPart(partName, xComments)
+	}
+	if err := s.File.addRawXMLPart(partName, xComments, contentTypeThreadedComments); err != nil {
+		return nil, err
+	}
+	s.rawRelationships = append(s.rawRelationships, xlsxWorkbookRelation{
+		Id:     unusedRelationshipId(s.rawRelationships),
+		Type:   relationshipTypeThreadedComment,
+		Target: "../" + strings.TrimPrefix(partName, "xl/"),
+	})
+	return comment, nil
+}
+
+// ReplyToThreadedComment adds a reply by author to the thread started
+// by the comment with the given ID, and returns the reply.  Authors
+// that haven't commented on the workbook yet are added to its list of
+// people, which is created if the File has none yet.
+func (s *Sheet) ReplyToThreadedComment(id, author, text string) (*ThreadedComment, error) {
+	xComments, partName, err := s.readThreadedComments()
+	if err != nil {
+		return nil, err
+	}
+	parent := findThreadedComment(xComments, id)
+	if parent == nil {
+		return nil, fmt.Errorf("no threaded comment with id '%s' in sheet '%s'", id, s.Name)
+	}
+	personId, err := s.File.threadedCommentPerson(author)
+	if err != nil {
+		return nil, err
+	}
+	replyId, err := newGUID()
+	if err != nil {
+		return nil, err
+	}
+	reply := &ThreadedComment{
+		ID:      replyId,
+		Ref:     parent.Ref,
+		Author:  author,
+		Created: time.Now(),
+		Text:    text,
+	}
+	xComments.ThreadedComment = append(xComments.ThreadedComment, xlsxThreadedComment{
+		Ref:      reply.Ref,
+		DT:       reply.Created.Format(threadedCommentTimeLayout),
+		PersonId: personId,
+		Id:       reply.ID,
+		ParentId: parent.Id,
+		Text:     text,
+	})
+	if err := s.File.setRawXMLPart(partName, xComments); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// SetThreadedCommentDone marks the thread started by the comment with
+// the given ID as resolved, or as active again.
+func (s *Sheet) SetThreadedCommentDone(id string, done bool) error {
+	xComments, partName, err := s.readThreadedComments()
+	if err != nil {
+		return err
+	}
+	comment := findThreadedComment(xComments, id)
+	if comment == nil {
+		return fmt.Errorf("no threaded comment with id '%s' in sheet '%s'", id, s.Name)
+	}
+	comment.Done = done
+	return s.File.setRawXMLPart(partName, xComments)
+}
+
+// findThreadedComment returns the first comment of the thread holding
+// the comment with the given ID, or nil if there is no such comment.
+func findThreadedComment(xComments *xlsxThreadedComments, id string) *xlsxThreadedComment {
+	if xComments == nil {
+		return nil
+	}
+	for i := range xComments.ThreadedComment {
+		if xComments.ThreadedComment[i].Id == id {
+			if parentId := xComments.ThreadedComment[i].ParentId; parentId != "" {
+				return findThreadedComment(xComments, parentId)
+			}
+			return &xComments.ThreadedComment[i]
+		}
+	}
+	return nil
+}
+
+// readThreadedComments returns the threaded comments part of the
+// Sheet along with its name, or nil if the Sheet has none.
+func (s *Sheet) readThreadedComments() (*xlsxThreadedComments, string, error) {
+	if s.File == nil {
+		return nil, "", nil
+	}
+	for _, rel := range s.File.keptRelationships(s.rawRelationships, "xl/worksheets") {
+		if rel.Type != relationshipTypeThreadedComment {
+			continue
+		}
+		partName := resolveTarget("xl/worksheets", rel.Target)
+		xComments := &xlsxThreadedComments{}
+		if err := xml.Unmarshal(s.File.rawParts[partName], xComments); err != nil {
+			return nil, "", err
+		}
+		return xComments, partName, nil
+	}
+	return nil, "", nil
+}
+
+// readPersons returns the persons part of the File along with its
+// name, or nil if the File has none.
+func (f *File) readPersons() (*xlsxPersonList, string, error) {
+	for _, rel := range f.keptRelationships(f.rawRelationships, "xl") {
+		if rel.Type != relationshipTypePerson {
+			continue
+		}
+		partName := resolveTarget("xl", rel.Target)
+		xPersons := &xlsxPersonList{}
+		if err := xml.Unmarshal(f.rawParts[partName], xPersons); err != nil {
+			return nil, "", err
+		}
+		return xPersons, partName, nil
+	}
+	return nil, "", nil
+}
+
+// threadedCommentPerson returns the id of the person with the given
+// display name, adding the person to the persons part of the File if
+// needed.  The persons part is created, along with its relationship,
+// if the File has none.
+func (f *File) threadedCommentPerson(displayName string) (string, error) {
+	xPersons, partName, err := f.readPersons()
+	if err != nil {
+		return "", err
+	}
+	if xPersons == nil {
+		xPersons = &xlsxPersonList{}
+	}
+	for _, person := range xPersons.Person {
+		if person.DisplayName == displayName {
+			return person.Id, nil
+		}
+	}
+	id, err := newGUID()
+	if err != nil {
+		return "", err
+	}
+	xPersons.Person = append(xPersons.Person, xlsxPerson{
+		DisplayName: displayName,
+		Id:          id,
+		UserId:      displayName,
+		ProviderId:  "None",
+	})
+	if partName != "" {
+		return id, f.setRawXMLPart(partName, xPersons)
+	}
+	// A relationship left behind by a persons part that was deleted
+	// points at the new part instead of being repeated.
+	for _, rel := range f.rawRelationships {
+		if rel.Type == relationshipTypePerson && rel.TargetMode != "External" {
+			return id, f.addRawXMLPart(resolveTarget("xl", rel.Target), xPersons, contentTypePersons)
+		}
+	}
+	partName = "xl/persons/person.xml"
+	if err := f.addRawXMLPart(partName, xPersons, contentTypePersons); err != nil {
+		return "", err
+	}
+	f.rawRelationships = append(f.rawRelationships, xlsxWorkbookRelation{
+		Id:     unusedRelationshipId(f.rawRelationships),
+		Type:   relationshipTypePerson,
+		Target: strings.TrimPrefix(partName, "xl/"),
+	})
+	return id, nil
+}
+
+// setRawXMLPart replaces the content of a raw part with v, marshalled
+// to XML.
+func (f *File) setRawXMLPart(name string, v interface{}) error {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	f.rawParts[name] = append([]byte(xml.Header), body...)
+	return nil
+}
+
+// addRawXMLPart adds a raw part called name, of the given content type,
+// holding v marshalled to XML.
+func (f *File) addRawXMLPart(name string, v interface{}, contentType string) error {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return f.setRawPartOfType(name, append([]byte(xml.Header), body...), contentType)
+}
+
+// newGUID returns a random GUID, in the braced upper case form used by
+// Excel.
+func newGUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("{%X-%X-%X-%X-%X}", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
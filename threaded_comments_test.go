@@ -0,0 +1,191 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type ThreadedCommentsSuite struct{}
+
+var _ = Suite(&ThreadedCommentsSuite{})
+
+// makeThreadedCommentsXLSX builds a minimal workbook holding a thread
+// of comments on B2, laid out the way Excel writes it.
+func makeThreadedCommentsXLSX(c *C) *File {
+	parts := map[string]string{
+		"[Content_Types].xml": `<?xml version="1.0" encoding="UTF-8"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Default Extension="vml" ContentType="application/vnd.openxmlformats-officedocument.vmlDrawing"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/comments1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.comments+xml"/><Override PartName="/xl/threadedComments/threadedComment1.xml" ContentType="application/vnd.ms-excel.threadedcomments+xml"/><Override PartName="/xl/persons/person.xml" ContentType="application/vnd.ms-excel.person+xml"/></Types>`,
+		"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Data" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.microsoft.com/office/2017/10/relationships/person" Target="persons/person.xml"/></Relationships>`,
+		"xl/worksheets/sheet1.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheetData><row r="2"><c r="B2"><v>42</v></c></row></sheetData><legacyDrawing r:id="rId2"/></worksheet>`,
+		"xl/worksheets/_rels/sheet1.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId3" Type="http://schemas.microsoft.com/office/2017/10/relationships/threadedComment" Target="../threadedComments/threadedComment1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/vmlDrawing" Target="../drawings/vmlDrawing1.vml"/><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/comments" Target="../comments1.xml"/></Relationships>`,
+		"xl/comments1.xml":                         `<comments xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><authors><author>tc={6B2E5A1C-0D8F-4B4B-9C1E-2F1F1A3B4C5D}</author></authors><commentList><commentList/></commentList></comments>`,
+		"xl/drawings/vmlDrawing1.vml":              `<xml xmlns:v="urn:schemas-microsoft-com:vml"></xml>`,
+		"xl/threadedComments/threadedComment1.xml": `<ThreadedComments xmlns="http://schemas.microsoft.com/office/spreadsheetml/2018/threadedcomments" xmlns:x="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><threadedComment ref="B2" dT="2026-03-02T09:15:30.25" personId="{A1B2C3D4-0000-4000-8000-000000000001}" id="{6B2E5A1C-0D8F-4B4B-9C1E-2F1F1A3B4C5D}"><text>Is this right?</text></threadedComment><threadedComment ref="B2" dT="2026-03-02T10:00:00.00" personId="{A1B2C3D4-0000-4000-8000-000000000002}" id="{6B2E5A1C-0D8F-4B4B-9C1E-2F1F1A3B4C5E}" parentId="{6B2E5A1C-0D8F-4B4B-9C1E-2F1F1A3B4C5D}"><text>Yes.</text></threadedComment></ThreadedComments>`,
+		"xl/persons/person.xml":                    `<personList xmlns="http://schemas.microsoft.com/office/spreadsheetml/2018/threadedcomments" xmlns:x="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><person displayName="Ann" id="{A1B2C3D4-0000-4000-8000-000000000001}" userId="ann@example.com" providerId="None"/><person displayName="Bob" id="{A1B2C3D4-0000-4000-8000-000000000002}" userId="bob@example.com" providerId="None"/></personList>`,
+	}
+	var buffer bytes.Buffer
+	w := zip.NewWriter(&buffer)
+	for name, data := range parts {
+		part, err := w.Create(name)
+		c.Assert(err, IsNil)
+		_, err = part.Write([]byte(data))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(w.Close(), IsNil)
+	file, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	return file
+}
+
+func (s *ThreadedCommentsSuite) TestThreadedComments(c *C) {
+	file := makeThreadedCommentsXLSX(c)
+	threads, err := file.Sheets[0].ThreadedComments()
+	c.Assert(err, IsNil)
+	c.Assert(threads, HasLen, 1)
+	thread := threads[0]
+	c.Assert(thread.Ref, Equals, "B2")
+	c.Assert(thread.Author, Equals, "Ann")
+	c.Assert(thread.Text, Equals, "Is this right?")
+	c.Assert(thread.Created, Equals, time.Date(2026, 3, 2, 9, 15, 30, 250000000, time.UTC))
+	c.Assert(thread.Replies, HasLen, 1)
+	c.Assert(thread.Replies[0].Author, Equals, "Bob")
+	c.Assert(thread.Replies[0].Text, Equals, "Yes.")
+}
+
+func (s *ThreadedCommentsSuite) TestNoThreadedComments(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	threads, err := sheet.ThreadedComments()
+	c.Assert(err, IsNil)
+	c.Assert(threads, HasLen, 0)
+}
+
+func (s *ThreadedCommentsSuite) TestThreadedCommentsSurviveRoundTrip(c *C) {
+	file := makeThreadedCommentsXLSX(c)
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `<legacyDrawing xmlns:relationships="http://schemas.openxmlformats.org/officeDocument/2006/relationships" relationships:id="rId2"></legacyDrawing>`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/worksheets/_rels/sheet1.xml.rels"], `Target="../threadedComments/threadedComment1.xml"`), Equals, true)
+
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	reread, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	threads, err := reread.Sheets[0].ThreadedComments()
+	c.Assert(err, IsNil)
+	c.Assert(threads, HasLen, 1)
+	c.Assert(threads[0].Replies, HasLen, 1)
+}
+
+func (s *ThreadedCommentsSuite) TestLegacyDrawingDroppedWithItsPart(c *C) {
+	file := makeThreadedCommentsXLSX(c)
+	file.DeleteRawPart("xl/drawings/vmlDrawing1.vml")
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], "legacyDrawing"), Equals, false)
+}
+
+func (s *ThreadedCommentsSuite) TestReplyToThreadedComment(c *C) {
+	file := makeThreadedCommentsXLSX(c)
+	sheet := file.Sheets[0]
+	reply, err := sheet.ReplyToThreadedComment("{6B2E5A1C-0D8F-4B4B-9C1E-2F1F1A3B4C5E}", "Carol", "Agreed.")
+	c.Assert(err, IsNil)
+	c.Assert(reply.Ref, Equals, "B2")
+	c.Assert(sheet.SetThreadedCommentDone("{6B2E5A1C-0D8F-4B4B-9C1E-2F1F1A3B4C5D}", true), IsNil)
+
+	threads, err := sheet.ThreadedComments()
+	c.Assert(err, IsNil)
+	c.Assert(threads, HasLen, 1)
+	c.Assert(threads[0].Done, Equals, true)
+	c.Assert(threads[0].Replies, HasLen, 2)
+	c.Assert(threads[0].Replies[1].ID, Equals, reply.ID)
+	c.Assert(threads[0].Replies[1].Author, Equals, "Carol")
+	c.Assert(threads[0].Replies[1].Text, Equals, "Agreed.")
+	persons, err := file.RawPart("xl/persons/person.xml")
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(persons), `displayName="Carol"`), Equals, true)
+}
+
+func (s *ThreadedCommentsSuite) TestReplyToUnknownThreadedComment(c *C) {
+	file := makeThreadedCommentsXLSX(c)
+	_, err := file.Sheets[0].ReplyToThreadedComment("{00000000-0000-0000-0000-000000000000}", "Carol", "Hello?")
+	c.Assert(err, ErrorMatches, "no threaded comment with id .* in sheet 'Data'")
+}
+
+func (s *ThreadedCommentsSuite) TestAddThreadedComment(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	sheet.Cell(1, 1).SetInt(42)
+	comment, err := sheet.AddThreadedComment("B2", "Ann", "Is this right?")
+	c.Assert(err, IsNil)
+	c.Assert(comment.Ref, Equals, "B2")
+	_, err = sheet.ReplyToThreadedComment(comment.ID, "Bob", "Yes.")
+	c.Assert(err, IsNil)
+	_, err = sheet.AddThreadedComment("C3", "Bob", "And this?")
+	c.Assert(err, IsNil)
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	types := parts["[Content_Types].xml"]
+	c.Assert(strings.Contains(types, `PartName="/xl/threadedComments/threadedComment1.xml" ContentType="application/vnd.ms-excel.threadedcomments+xml"`), Equals, true)
+	c.Assert(strings.Contains(types, `PartName="/xl/persons/person.xml" ContentType="application/vnd.ms-excel.person+xml"`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/_rels/workbook.xml.rels"], `Target="persons/person.xml" Type="http://schemas.microsoft.com/office/2017/10/relationships/person"`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/worksheets/_rels/sheet1.xml.rels"], `Target="../threadedComments/threadedComment1.xml"`), Equals, true)
+	c.Assert(strings.Count(parts["xl/persons/person.xml"], "<person "), Equals, 2)
+
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	reread, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	threads, err := reread.Sheets[0].ThreadedComments()
+	c.Assert(err, IsNil)
+	c.Assert(threads, HasLen, 2)
+	c.Assert(threads[0].ID, Equals, comment.ID)
+	c.Assert(threads[0].Author, Equals, "Ann")
+	c.Assert(threads[0].Text, Equals, "Is this right?")
+	c.Assert(threads[0].Replies, HasLen, 1)
+	c.Assert(threads[0].Replies[0].Author, Equals, "Bob")
+	c.Assert(threads[1].Ref, Equals, "C3")
+	c.Assert(threads[1].Author, Equals, "Bob")
+}
+
+func (s *ThreadedCommentsSuite) TestAddThreadedCommentToExistingThreads(c *C) {
+	file := makeThreadedCommentsXLSX(c)
+	sheet := file.Sheets[0]
+	_, err := sheet.AddThreadedComment("B2", "Carol", "Another thread?")
+	c.Assert(err, ErrorMatches, "the cell B2 of sheet 'Data' already has a thread of comments")
+	_, err = sheet.AddThreadedComment("2B", "Carol", "Here?")
+	c.Assert(err, ErrorMatches, "invalid cell reference '2B'")
+	_, err = sheet.AddThreadedComment("D4", "Ann", "Here.")
+	c.Assert(err, IsNil)
+	threads, err := sheet.ThreadedComments()
+	c.Assert(err, IsNil)
+	c.Assert(threads, HasLen, 2)
+	c.Assert(threads[1].Author, Equals, "Ann")
+	c.Assert(file.RawPartNames(), HasLen, 4)
+}
+
+func (s *ThreadedCommentsSuite) TestReplyCreatesPersons(c *C) {
+	file := makeThreadedCommentsXLSX(c)
+	file.DeleteRawPart("xl/persons/person.xml")
+	_, err := file.Sheets[0].ReplyToThreadedComment("{6B2E5A1C-0D8F-4B4B-9C1E-2F1F1A3B4C5D}", "Carol", "Agreed.")
+	c.Assert(err, IsNil)
+	persons, err := file.RawPart("xl/persons/person.xml")
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(persons), `displayName="Carol"`), Equals, true)
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Count(parts["xl/_rels/workbook.xml.rels"], `Target="persons/person.xml"`), Equals, 1)
+	c.Assert(strings.Contains(parts["[Content_Types].xml"], `PartName="/xl/persons/person.xml" ContentType="application/vnd.ms-excel.person+xml"`), Equals, true)
+}
@@ -0,0 +1,291 @@
+//go:build go1.18
+// +build go1.18
+
+package xlsx
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TypedSheet is a view of a sheet whose rows are the structs of type T,
+// one per row below its header row, which are appended with Append and
+// read back with Rows rather than cell by cell.  The columns of T are
+// those of StreamFileBuilder.AddSheetFromStruct, named after the xlsx
+// tags of its fields.
+//
+// A TypedSheet views a Sheet in memory, see NewTypedSheet, the current
+// sheet of a StreamFile, see NewTypedStreamWriter, or a sheet read by
+// a StreamSheetReader, see NewTypedStreamReader.
+type TypedSheet[T any] struct {
+	columns []structColumn
+	// colIndexes holds the index of the column of the sheet holding
+	// each of columns, or -1 if the sheet has no such column.
+	colIndexes []int
+	sheet      *Sheet
+	stream     *StreamFile
+	reader     *StreamSheetReader
+}
+
+var (
+	errTypedSheetWriteOnly = errors.New("the rows of a sheet being streamed can't be read")
+	errTypedSheetReadOnly  = errors.New("rows can't be appended to a sheet being read")
+)
+
+// typedColumns returns the columns of the sheets of structs of type T.
+func typedColumns[T any]() ([]structColumn, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, errNotStruct
+	}
+	return structColumns(t, nil)
+}
+
+// NewTypedSheet returns a TypedSheet viewing sheet, a Sheet in memory. An empty sheet is given a header row holding
+// the headers of the columns of T, and the structs appended go below it. The columns of a sheet with rows are matched
+// to the fields of T by the headers of its first row, whatever their case and the spaces around them, the fields
+// without a column being left alone.
+func NewTypedSheet[T any](sheet *Sheet) (*TypedSheet[T], error) {
+	columns, err := typedColumns[T]()
+	if err != nil {
+		return nil, err
+	}
+	ts := &TypedSheet[T]{columns: columns, colIndexes: make([]int, len(columns)), sheet: sheet}
+	if sheet.MaxRow == 0 {
+		row := sheet.AddRow()
+		for i, column := range columns {
+			row.AddCell().SetString(column.header)
+			ts.colIndexes[i] = i
+		}
+		return ts, nil
+	}
+	var headers []string
+	if row := sheet.row(0); row != nil {
+		for _, cell := range row.Cells {
+			header := ""
+			if cell != nil {
+				header = cell.Value
+			}
+			headers = append(headers, header)
+		}
+	}
+	ts.matchHeaders(headers)
+	return ts, nil
+}
+
+// NewTypedStreamWriter returns a TypedSheet appending the structs of type T to the current sheet of sf, which must have
+// been added by StreamFileBuilder.AddSheetFromStruct for structs of type T, such as with AddSheetFromStruct(name, T{}).
+// Its rows can't be read.
+func NewTypedStreamWriter[T any](sf *StreamFile) (*TypedSheet[T], error) {
+	columns, err := typedColumns[T]()
+	if err != nil {
+		return nil, err
+	}
+	return &TypedSheet[T]{columns: columns, stream: sf}, nil
+}
+
+// NewTypedStreamReader returns a TypedSheet reading the structs of type T from the rows of ssr, whose columns are
+// matched to the fields of T by the headers of the next row, read first, like those of NewTypedSheet. Rows can't be
+// appended to it.
+func NewTypedStreamReader[T any](ssr *StreamSheetReader) (*TypedSheet[T], error) {
+	columns, err := typedColumns[T]()
+	if err != nil {
+		return nil, err
+	}
+	ts := &TypedSheet[T]{columns: columns, colIndexes: make([]int, len(columns)), reader: ssr}
+	headers, err := ssr.ReadRow()
+	if err != nil {
+		return nil, err
+	}
+	ts.matchHeaders(headers)
+	return ts, nil
+}
+
+// matchHeaders maps the columns of ts to the columns of the sheet with
+// the given headers.
+func (ts *TypedSheet[T]) matchHeaders(headers []string) {
+	colIndexes := make(map[string]int, len(headers))
+	for i, header := range headers {
+		if _, ok := colIndexes[foldHeader(header)]; !ok {
+			colIndexes[foldHeader(header)] = i
+		}
+	}
+	for i, column := range ts.columns {
+		colIndex, ok := colIndexes[foldHeader(column.header)]
+		if !ok {
+			colIndex = -1
+		}
+		ts.colIndexes[i] = colIndex
+	}
+}
+
+// Append adds v as a row of the sheet, each of its fields being written to its column like StreamFile.WriteStruct
+// writes them.
+func (ts *TypedSheet[T]) Append(v T) error {
+	if ts.stream != nil {
+		return ts.stream.WriteStruct(v)
+	}
+	if ts.reader != nil {
+		return errTypedSheetReadOnly
+	}
+	width := 0
+	for _, colIndex := range ts.colIndexes {
+		if colIndex >= width {
+			width = colIndex + 1
+		}
+	}
+	row := ts.sheet.AddRow()
+	for len(row.Cells) < width {
+		row.AddCell()
+	}
+	value := reflect.ValueOf(v)
+	for i, column := range ts.columns {
+		if ts.colIndexes[i] >= 0 {
+			setTypedCell(row.Cells[ts.colIndexes[i]], value.FieldByIndex(column.index), column)
+		}
+	}
+	return nil
+}
+
+// Rows calls fn with the index, starting at 0, and the struct of each row of the sheet below its header row, stopping
+// at the first error it returns. The rows without a value in the columns of T are skipped, and the fields of the empty
+// cells are left to their zero value, nil for pointers. An error is returned if a value can't be read as the type of
+// its field, naming its cell. The fields written as fmt.Stringers can only be read back if they are strings.
+func (ts *TypedSheet[T]) Rows(fn func(rowIndex int, v T) error) error {
+	if ts.stream != nil {
+		return errTypedSheetWriteOnly
+	}
+	if ts.reader != nil {
+		return ts.reader.Rows(func(rowIndex int, cells []string) error {
+			return ts.readRow(rowIndex, cells, ts.reader.date1904, fn)
+		})
+	}
+	date1904 := ts.sheet.File != nil && ts.sheet.File.Date1904
+	for rowIndex := 1; rowIndex < len(ts.sheet.rowList()); rowIndex++ {
+		row := ts.sheet.row(rowIndex)
+		if row == nil {
+			continue
+		}
+		cells := make([]string, len(row.Cells))
+		for i, cell := range row.Cells {
+			if cell != nil {
+				cells[i] = cell.Value
+			}
+		}
+		if err := ts.readRow(rowIndex, cells, date1904, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readRow calls fn with the struct held by the values of the cells of
+// the row at rowIndex, unless they are all empty.
+func (ts *TypedSheet[T]) readRow(rowIndex int, cells []string, date1904 bool, fn func(int, T) error) error {
+	var v T
+	value := reflect.ValueOf(&v).Elem()
+	empty := true
+	for i, column := range ts.columns {
+		colIndex := ts.colIndexes[i]
+		if colIndex < 0 || colIndex >= len(cells) || strings.TrimSpace(cells[colIndex]) == "" {
+			continue
+		}
+		empty = false
+		if err := setTypedField(value.FieldByIndex(column.index), cells[colIndex], date1904); err != nil {
+			return fmt.Errorf("cell %s: %v", GetCellIDStringFromCoords(colIndex, rowIndex), err)
+		}
+	}
+	if empty {
+		return nil
+	}
+	return fn(rowIndex, v)
+}
+
+// setTypedCell sets cell to v, the value of a field written to column,
+// leaving it empty for nil pointers.
+func setTypedCell(cell *Cell, v reflect.Value, column structColumn) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch {
+	case column.columnType == ColumnTypeDate:
+		cell.SetDateTime(v.Interface().(time.Time))
+	case v.Type().Implements(stringerType):
+		cell.SetString(v.Interface().(fmt.Stringer).String())
+	default:
+		switch v.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			cell.setNumeric(strconv.FormatUint(v.Uint(), 10))
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			cell.SetInt64(v.Int())
+		case reflect.Float32, reflect.Float64:
+			cell.SetFloat(v.Float())
+		case reflect.Bool:
+			cell.SetBool(v.Bool())
+		default:
+			cell.SetString(v.String())
+		}
+	}
+	if column.format != "" {
+		cell.SetFormat(column.format)
+	}
+}
+
+// setTypedField sets field to the value of a cell, text, read as the
+// type of the field, dates being read as the numbers of Excel.
+func setTypedField(field reflect.Value, text string, date1904 bool) error {
+	if field.Kind() == reflect.Ptr {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setTypedField(ptr.Elem(), text, date1904); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+	if field.Type() == timeType {
+		f, err := parseFloat(strings.TrimSpace(text))
+		if err != nil {
+			return fmt.Errorf("%q isn't a date", text)
+		}
+		field.Set(reflect.ValueOf(TimeFromExcelTime(f, date1904)))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(text)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+		if err != nil || field.OverflowInt(n) {
+			return fmt.Errorf("%q isn't a whole number of type %s", text, field.Type())
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.TrimSpace(text), 10, 64)
+		if err != nil || field.OverflowUint(n) {
+			return fmt.Errorf("%q isn't a whole number of type %s", text, field.Type())
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := parseFloat(strings.TrimSpace(text))
+		if err != nil {
+			return fmt.Errorf("%q isn't a number", text)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(text))
+		if err != nil {
+			return fmt.Errorf("%q isn't a boolean", text)
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("a %s can't be read from a cell", field.Type())
+	}
+	return nil
+}
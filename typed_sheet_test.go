@@ -0,0 +1,126 @@
+//go:build go1.18
+// +build go1.18
+
+package xlsx
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type TypedSheetSuite struct{}
+
+var _ = Suite(&TypedSheetSuite{})
+
+type typedOrder struct {
+	Id       int
+	Customer string
+	Total    float64   `xlsx:"Order total,#,##0.00"`
+	Shipped  time.Time `xlsx:",yyyy-mm-dd"`
+	Paid     bool
+	Discount *float64
+	internal string
+}
+
+func typedOrders() []typedOrder {
+	discount := 2.5
+	return []typedOrder{
+		{Id: 1, Customer: "Smith", Total: 1250.5, Shipped: time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC), Paid: true, Discount: &discount},
+		{Id: 2, Customer: "Jones", Total: 80, Shipped: time.Date(2021, 3, 5, 0, 0, 0, 0, time.UTC)},
+	}
+}
+
+func readTypedOrders(c *C, ts *TypedSheet[typedOrder]) ([]int, []typedOrder) {
+	var rowIndexes []int
+	var orders []typedOrder
+	c.Assert(ts.Rows(func(rowIndex int, order typedOrder) error {
+		rowIndexes = append(rowIndexes, rowIndex)
+		orders = append(orders, order)
+		return nil
+	}), IsNil)
+	return rowIndexes, orders
+}
+
+func (s *TypedSheetSuite) TestInMemory(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Orders")
+	c.Assert(err, IsNil)
+	ts, err := NewTypedSheet[typedOrder](sheet)
+	c.Assert(err, IsNil)
+	for _, order := range typedOrders() {
+		c.Assert(ts.Append(order), IsNil)
+	}
+	c.Assert(sheet.Rows[0].Cells[2].Value, Equals, "Order total")
+	c.Assert(sheet.Rows[1].Cells[2].NumFmt, Equals, "#,##0.00")
+	c.Assert(sheet.Rows[2].Cells[5].Value, Equals, "")
+
+	var buffer bytes.Buffer
+	c.Assert(file.Write(&buffer), IsNil)
+	read, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	ts, err = NewTypedSheet[typedOrder](read.Sheet["Orders"])
+	c.Assert(err, IsNil)
+	rowIndexes, orders := readTypedOrders(c, ts)
+	c.Assert(rowIndexes, DeepEquals, []int{1, 2})
+	c.Assert(orders, DeepEquals, typedOrders())
+
+	stop := errors.New("stop")
+	c.Assert(ts.Rows(func(int, typedOrder) error { return stop }), Equals, stop)
+}
+
+func (s *TypedSheetSuite) TestMatchesHeaders(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Upload")
+	c.Assert(err, IsNil)
+	addValues(sheet, " customer ", "Notes", "ID")
+	addValues(sheet, "Smith", "first", 1)
+	addValues(sheet, nil, "only notes", nil)
+	addValues(sheet, "Jones", nil, "two")
+	ts, err := NewTypedSheet[typedOrder](sheet)
+	c.Assert(err, IsNil)
+	var orders []typedOrder
+	err = ts.Rows(func(rowIndex int, order typedOrder) error {
+		orders = append(orders, order)
+		return nil
+	})
+	c.Assert(err, ErrorMatches, `cell C4: "two" isn't a whole number of type int`)
+	c.Assert(orders, DeepEquals, []typedOrder{{Id: 1, Customer: "Smith"}})
+
+	c.Assert(ts.Append(typedOrder{Id: 3, Customer: "Brown", Total: 5}), IsNil)
+	c.Assert(sheet.Rows[4].Cells[0].Value, Equals, "Brown")
+	c.Assert(sheet.Rows[4].Cells[1].Value, Equals, "")
+	c.Assert(sheet.Rows[4].Cells[2].Value, Equals, "3")
+
+	_, err = NewTypedSheet[string](sheet)
+	c.Assert(err, Equals, errNotStruct)
+}
+
+func (s *TypedSheetSuite) TestStreaming(c *C) {
+	var buffer bytes.Buffer
+	builder := NewStreamFileBuilder(&buffer)
+	c.Assert(builder.AddSheetFromStruct("Orders", typedOrder{}), IsNil)
+	stream, err := builder.Build()
+	c.Assert(err, IsNil)
+	writer, err := NewTypedStreamWriter[typedOrder](stream)
+	c.Assert(err, IsNil)
+	for _, order := range typedOrders() {
+		c.Assert(writer.Append(order), IsNil)
+	}
+	c.Assert(writer.Rows(func(int, typedOrder) error { return nil }), Equals, errTypedSheetWriteOnly)
+	c.Assert(stream.Close(), IsNil)
+
+	reader, err := NewStreamFileReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	c.Assert(err, IsNil)
+	sheetReader, err := reader.SheetByName("Orders")
+	c.Assert(err, IsNil)
+	defer sheetReader.Close()
+	ts, err := NewTypedStreamReader[typedOrder](sheetReader)
+	c.Assert(err, IsNil)
+	c.Assert(ts.Append(typedOrder{}), Equals, errTypedSheetReadOnly)
+	rowIndexes, orders := readTypedOrders(c, ts)
+	c.Assert(rowIndexes, DeepEquals, []int{1, 2})
+	c.Assert(orders, DeepEquals, typedOrders())
+}
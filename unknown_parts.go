@@ -0,0 +1,136 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Most parts of a file read that this package doesn't model are kept
+// as raw parts and written back as they are, see File.RawPartNames.
+// The few others are dropped, or written back without what refers to
+// them, so they don't survive a round trip.  They are ignored by
+// default, and can be listed or rejected with WithUnknownParts, for
+// pipelines that must process files without losing anything.
+
+// UnknownParts is the way the parts of a file read that won't survive a round trip are dealt with, see
+// WithUnknownParts.
+type UnknownParts int
+
+const (
+	// UnknownPartsIgnored drops the parts silently, the default.
+	UnknownPartsIgnored UnknownParts = iota
+	// UnknownPartsListed lists the parts, see File.SkippedParts.
+	UnknownPartsListed
+	// UnknownPartsRejected fails the reading of the file with an
+	// *UnknownPartsError.
+	UnknownPartsRejected
+)
+
+const (
+	relationshipTypeOfficeDocument     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument"
+	relationshipTypeCoreProperties     = "http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties"
+	relationshipTypeExtendedProperties = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/extended-properties"
+)
+
+// unreadSheetTypes names the sheets other than worksheets, by the type
+// of their relationship from the workbook.
+var unreadSheetTypes = map[string]string{
+	"http://schemas.openxmlformats.org/officeDocument/2006/relationships/chartsheet":  "chartsheet",
+	"http://schemas.openxmlformats.org/officeDocument/2006/relationships/dialogsheet": "dialogsheet",
+	"http://schemas.microsoft.com/office/2006/relationships/xlMacrosheet":             "macrosheet",
+	"http://schemas.microsoft.com/office/2006/relationships/xlIntlMacrosheet":         "macrosheet",
+}
+
+// SkippedPart is a part of a file read which won't survive a round trip, with the reason why.
+type SkippedPart struct {
+	Name   string
+	Reason string
+}
+
+// UnknownPartsError is returned when reading, with UnknownPartsRejected, a file with parts which won't survive a round
+// trip.
+type UnknownPartsError struct {
+	Parts []SkippedPart
+}
+
+// Error returns a description of the UnknownPartsError, naming its first part.
+func (e *UnknownPartsError) Error() string {
+	first := e.Parts[0]
+	if len(e.Parts) == 1 {
+		return fmt.Sprintf("the part '%s' won't survive a round trip: %s", first.Name, first.Reason)
+	}
+	return fmt.Sprintf("%d parts won't survive a round trip, such as '%s': %s", len(e.Parts), first.Name, first.Reason)
+}
+
+// SkippedParts returns the parts of the file read which won't survive a round trip, sorted by name, if it was read
+// with UnknownPartsListed, or nil.
+func (f *File) SkippedParts() []SkippedPart {
+	return append([]SkippedPart(nil), f.skippedParts...)
+}
+
+// findSkippedParts returns the parts of r which won't survive a round trip, sorted by name, given sheetXMLMap and the
+// raw relationships of its workbook, and whether it is read with WithPreserve, which keeps the relationships of the
+// package.
+func findSkippedParts(r *zip.Reader, sheetXMLMap WorkBookRels, workbookRels []xlsxWorkbookRelation, preserve bool) ([]SkippedPart, error) {
+	var skipped []SkippedPart
+	worksheets := make(map[string]bool, len(sheetXMLMap))
+	for _, name := range sheetXMLMap {
+		worksheets[name] = true
+	}
+	for _, f := range r.File {
+		switch {
+		case f.Name == "xl/calcChain.xml":
+			skipped = append(skipped, SkippedPart{Name: f.Name, Reason: "the calculation chain is left for Excel to rebuild"})
+		case f.Name == "_rels/.rels" && !preserve:
+			rels, err := readPackageRelationships(f)
+			if err != nil {
+				return nil, err
+			}
+			for _, rel := range rels {
+				switch rel.Type {
+				case relationshipTypeOfficeDocument, relationshipTypeCoreProperties, relationshipTypeExtendedProperties:
+					continue
+				}
+				if rel.TargetMode != "External" {
+					skipped = append(skipped, SkippedPart{
+						Name:   resolveTarget("", rel.Target),
+						Reason: "the package relationships are written without it",
+					})
+				}
+			}
+		case path.Dir(f.Name) == "xl/worksheets" && strings.HasSuffix(f.Name, ".xml"):
+			if !worksheets[strings.TrimSuffix(path.Base(f.Name), ".xml")] {
+				skipped = append(skipped, SkippedPart{Name: f.Name, Reason: "the worksheet isn't a sheet of the workbook"})
+			}
+		}
+	}
+	for _, rel := range workbookRels {
+		if kind, ok := unreadSheetTypes[rel.Type]; ok {
+			skipped = append(skipped, SkippedPart{
+				Name:   resolveTarget("xl", rel.Target),
+				Reason: fmt.Sprintf("the %s isn't read, and is left out of the sheets of the workbook", kind),
+			})
+		}
+	}
+	sort.Slice(skipped, func(i, j int) bool { return skipped[i].Name < skipped[j].Name })
+	return skipped, nil
+}
+
+// readPackageRelationships returns the relationships of the package,
+// read from f, _rels/.rels.
+func readPackageRelationships(f *zip.File) ([]xlsxWorkbookRelation, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	var rels xlsxWorkbookRels
+	if err := xml.NewDecoder(rc).Decode(&rels); err != nil {
+		return nil, err
+	}
+	return rels.Relationships, nil
+}
@@ -0,0 +1,104 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type UnknownPartsSuite struct{}
+
+var _ = Suite(&UnknownPartsSuite{})
+
+// unknownPartsXLSX returns an XLSX file of two rows, whose parts are
+// replaced or completed by those of extra.
+func unknownPartsXLSX(c *C, extra func(parts map[string]string)) []byte {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	sheet.AddRow().AddCell().Value = "first"
+	sheet.AddRow().AddCell().Value = "second"
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	if extra != nil {
+		extra(parts)
+	}
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for name, data := range parts {
+		w, err := writer.Create(name)
+		c.Assert(err, IsNil)
+		_, err = w.Write([]byte(data))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(writer.Close(), IsNil)
+	return buffer.Bytes()
+}
+
+func addUnknownParts(parts map[string]string) {
+	parts["xl/calcChain.xml"] = `<calcChain/>`
+	parts["xl/worksheets/sheet9.xml"] = `<worksheet><sheetData/></worksheet>`
+	parts["docProps/custom.xml"] = `<Properties/>`
+	parts["_rels/.rels"] = strings.Replace(parts["_rels/.rels"], "</Relationships>",
+		`<Relationship Id="rId9" Type="`+relationshipTypeCustomProperties+`" Target="docProps/custom.xml"/></Relationships>`, 1)
+	parts["xl/chartsheets/sheet1.xml"] = `<chartsheet/>`
+	parts["xl/_rels/workbook.xml.rels"] = strings.Replace(parts["xl/_rels/workbook.xml.rels"], "</Relationships>",
+		`<Relationship Id="rId99" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/chartsheet" Target="chartsheets/sheet1.xml"/></Relationships>`, 1)
+}
+
+func (s *UnknownPartsSuite) TestListed(c *C) {
+	data := unknownPartsXLSX(c, addUnknownParts)
+	file, err := OpenBinaryWithOptions(data)
+	c.Assert(err, IsNil)
+	c.Assert(file.SkippedParts(), IsNil)
+
+	file, err = OpenBinaryWithOptions(data, WithUnknownParts(UnknownPartsListed))
+	c.Assert(err, IsNil)
+	c.Assert(file.SkippedParts(), DeepEquals, []SkippedPart{
+		{Name: "docProps/custom.xml", Reason: "the package relationships are written without it"},
+		{Name: "xl/calcChain.xml", Reason: "the calculation chain is left for Excel to rebuild"},
+		{Name: "xl/chartsheets/sheet1.xml", Reason: "the chartsheet isn't read, and is left out of the sheets of the workbook"},
+		{Name: "xl/worksheets/sheet9.xml", Reason: "the worksheet isn't a sheet of the workbook"},
+	})
+	c.Assert(file.Sheets[0].Cell(1, 0).Value, Equals, "second")
+}
+
+func (s *UnknownPartsSuite) TestRejected(c *C) {
+	_, err := OpenBinaryWithOptions(unknownPartsXLSX(c, addUnknownParts), WithUnknownParts(UnknownPartsRejected))
+	unknownErr, ok := err.(*UnknownPartsError)
+	c.Assert(ok, Equals, true)
+	c.Assert(unknownErr.Parts, HasLen, 4)
+	c.Assert(err, ErrorMatches, "4 parts won't survive a round trip, such as 'docProps/custom.xml': the package relationships are written without it")
+
+	_, err = OpenBinaryWithOptions(unknownPartsXLSX(c, func(parts map[string]string) {
+		parts["xl/calcChain.xml"] = `<calcChain/>`
+	}), WithUnknownParts(UnknownPartsRejected))
+	c.Assert(err, ErrorMatches, "the part 'xl/calcChain.xml' won't survive a round trip: the calculation chain is left for Excel to rebuild")
+
+	file, err := OpenBinaryWithOptions(unknownPartsXLSX(c, func(parts map[string]string) {
+		parts["xl/media/image1.png"] = "png"
+	}), WithUnknownParts(UnknownPartsRejected))
+	c.Assert(err, IsNil)
+	c.Assert(file.SkippedParts(), IsNil)
+	c.Assert(file.RawPartNames(), DeepEquals, []string{"xl/media/image1.png"})
+}
+
+func (s *UnknownPartsSuite) TestOpenOptions(c *C) {
+	data := unknownPartsXLSX(c, nil)
+	file, err := OpenReaderAtWithOptions(bytes.NewReader(data), int64(len(data)), WithRowLimit(1))
+	c.Assert(err, IsNil)
+	c.Assert(file.Sheets[0].MaxRow, Equals, 1)
+
+	_, err = OpenBinaryWithOptions(data, WithMemoryLimit(1))
+	_, ok := err.(*MemoryLimitError)
+	c.Assert(ok, Equals, true)
+
+	_, err = OpenBinaryWithOptions(data, WithRowLimit(-2))
+	c.Assert(err, ErrorMatches, "invalid row limit -2")
+	_, err = OpenBinaryWithOptions(data, WithMemoryLimit(0))
+	c.Assert(err, ErrorMatches, "invalid memory limit of 0 bytes")
+	_, err = OpenBinaryWithOptions(data, WithUnknownParts(UnknownParts(5)))
+	c.Assert(err, ErrorMatches, "invalid unknown parts mode 5")
+}
@@ -43,25 +43,33 @@ func (r *Row) WriteSlice(e interface{}, cols int) int {
 			cell := r.AddCell()
 			cell.SetString(t.String())
 		case sql.NullString:  // check null sql types nulls = ''
-			cell := r.AddCell()
-			if cell.SetString(``); t.Valid {
-				cell.SetValue(t.String)
+			if !t.Valid {
+				r.addNullCell()
+				break
 			}
-		case sql.NullBool:
 			cell := r.AddCell()
-			if cell.SetString(``); t.Valid {
-				cell.SetBool(t.Bool)
+			cell.SetValue(t.String)
+		case sql.NullBool:
+			if !t.Valid {
+				r.addNullCell()
+				break
 			}
-		case sql.NullInt64:
 			cell := r.AddCell()
-			if cell.SetString(``); t.Valid {
-				cell.SetValue(t.Int64)
+			cell.SetBool(t.Bool)
+		case sql.NullInt64:
+			if !t.Valid {
+				r.addNullCell()
+				break
 			}
-		case sql.NullFloat64:
 			cell := r.AddCell()
-			if cell.SetString(``); t.Valid {
-				cell.SetValue(t.Float64)
+			cell.SetValue(t.Int64)
+		case sql.NullFloat64:
+			if !t.Valid {
+				r.addNullCell()
+				break
 			}
+			cell := r.AddCell()
+			cell.SetValue(t.Float64)
 		default:
 			switch val.Kind() { // underlying type of slice
 			case reflect.String, reflect.Int, reflect.Int8,
@@ -72,6 +80,10 @@ func (r *Row) WriteSlice(e interface{}, cols int) int {
 				cell := r.AddCell()
 				cell.SetBool(t.(bool))
 			case reflect.Interface:
+				if val.IsNil() {
+					r.addNullCell()
+					break
+				}
 				setCell(reflect.ValueOf(t))
 			}
 		}
@@ -115,25 +127,33 @@ func (r *Row) WriteStruct(e interface{}, cols int) int {
 			cell := r.AddCell()
 			cell.SetString(t.String())
 		case sql.NullString: // check null sql types nulls = ''
-			cell := r.AddCell()
-			if cell.SetString(``); t.Valid {
-				cell.SetValue(t.String)
+			if !t.Valid {
+				r.addNullCell()
+				break
 			}
-		case sql.NullBool:
 			cell := r.AddCell()
-			if cell.SetString(``); t.Valid {
-				cell.SetBool(t.Bool)
+			cell.SetValue(t.String)
+		case sql.NullBool:
+			if !t.Valid {
+				r.addNullCell()
+				break
 			}
-		case sql.NullInt64:
 			cell := r.AddCell()
-			if cell.SetString(``); t.Valid {
-				cell.SetValue(t.Int64)
+			cell.SetBool(t.Bool)
+		case sql.NullInt64:
+			if !t.Valid {
+				r.addNullCell()
+				break
 			}
-		case sql.NullFloat64:
 			cell := r.AddCell()
-			if cell.SetString(``); t.Valid {
-				cell.SetValue(t.Float64)
+			cell.SetValue(t.Int64)
+		case sql.NullFloat64:
+			if !t.Valid {
+				r.addNullCell()
+				break
 			}
+			cell := r.AddCell()
+			cell.SetValue(t.Float64)
 		default:
 			switch f.Kind() {
 			case reflect.String, reflect.Int, reflect.Int8,
@@ -0,0 +1,54 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/xml"
+)
+
+// xlsxCoreProperties directly maps the coreProperties element in the
+// namespace
+// http://schemas.openxmlformats.org/package/2006/metadata/core-properties
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxCoreProperties struct {
+	XMLName        xml.Name `xml:"http://schemas.openxmlformats.org/package/2006/metadata/core-properties coreProperties"`
+	Title          string   `xml:"http://purl.org/dc/elements/1.1/ title,omitempty"`
+	Subject        string   `xml:"http://purl.org/dc/elements/1.1/ subject,omitempty"`
+	Creator        string   `xml:"http://purl.org/dc/elements/1.1/ creator,omitempty"`
+	Description    string   `xml:"http://purl.org/dc/elements/1.1/ description,omitempty"`
+	Keywords       string   `xml:"keywords,omitempty"`
+	LastModifiedBy string   `xml:"lastModifiedBy,omitempty"`
+	Revision       string   `xml:"revision,omitempty"`
+	Category       string   `xml:"category,omitempty"`
+	Created        string   `xml:"http://purl.org/dc/terms/ created,omitempty"`
+	Modified       string   `xml:"http://purl.org/dc/terms/ modified,omitempty"`
+}
+
+// makeXML returns the docProps/core.xml part holding the core
+// properties, with the prefixes Excel writes for their namespaces.
+func (cp *xlsxCoreProperties) makeXML() string {
+	var out bytes.Buffer
+	out.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	out.WriteString(`<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties"` +
+		` xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcmitype="http://purl.org/dc/dcmitype/"` +
+		` xmlns:dcterms="http://purl.org/dc/terms/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">`)
+	for _, property := range []struct{ element, value string }{
+		{"dc:title", cp.Title}, {"dc:subject", cp.Subject}, {"dc:creator", cp.Creator}, {"cp:keywords", cp.Keywords},
+		{"dc:description", cp.Description}, {"cp:lastModifiedBy", cp.LastModifiedBy}, {"cp:revision", cp.Revision},
+		{"cp:category", cp.Category},
+	} {
+		if property.value != "" {
+			out.WriteString(`<` + property.element + `>` + escapeAttr(property.value) + `</` + property.element + `>`)
+		}
+	}
+	for _, property := range []struct{ element, value string }{
+		{"dcterms:created", cp.Created}, {"dcterms:modified", cp.Modified},
+	} {
+		if property.value != "" {
+			out.WriteString(`<` + property.element + ` xsi:type="dcterms:W3CDTF">` + escapeAttr(property.value) +
+				`</` + property.element + `>`)
+		}
+	}
+	out.WriteString(`</cp:coreProperties>`)
+	return out.String()
+}
@@ -2,6 +2,7 @@ package xlsx
 
 import (
 	"encoding/xml"
+	"strings"
 )
 
 // xlsxSST directly maps the sst element from the namespace
@@ -21,6 +22,44 @@ type xlsxSST struct {
 type xlsxSI struct {
 	T string  `xml:"t"`
 	R []xlsxR `xml:"r"`
+	// preserved is true when the space of the t element read is
+	// preserved, which its text is then read with.
+	preserved bool
+}
+
+// xlsxSIText is the t element of an si element being written, whose
+// space is preserved when the text starts or ends with white space.
+type xlsxSIText struct {
+	Space string `xml:"http://www.w3.org/XML/1998/namespace space,attr,omitempty"`
+	Text  string `xml:",chardata"`
+}
+
+// MarshalXML writes the si element, preserving the white space at the
+// start and end of its text which Numbers, among others, would
+// otherwise trim.
+func (si xlsxSI) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	t := xlsxSIText{Text: si.T}
+	if t.Text != strings.TrimSpace(t.Text) {
+		t.Space = "preserve"
+	}
+	return e.EncodeElement(struct {
+		T xlsxSIText `xml:"t"`
+		R []xlsxR    `xml:"r"`
+	}{t, si.R}, start)
+}
+
+// UnmarshalXML reads the si element, or the is element of an inline
+// string, noting whether the space of its text is preserved.
+func (si *xlsxSI) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var read struct {
+		T xlsxSIText `xml:"t"`
+		R []xlsxR    `xml:"r"`
+	}
+	if err := d.DecodeElement(&read, &start); err != nil {
+		return err
+	}
+	si.T, si.R, si.preserved = read.T.Text, read.R, read.T.Space == "preserve"
+	return nil
 }
 
 // xlsxR directly maps the r element from the namespace
@@ -47,7 +47,7 @@ var builtInNumFmt = map[int]string{
 	39: "#,##0.00;(#,##0.00)",
 	40: "#,##0.00;[red](#,##0.00)",
 	41: `_(* #,##0_);_(* \(#,##0\);_(* "-"_);_(@_)`,
-	42: `_("$"* #,##0_);_("$* \(#,##0\);_("$"* "-"_);_(@_)`,
+	42: `_("$"* #,##0_);_("$"* \(#,##0\);_("$"* "-"_);_(@_)`,
 	43: `_(* #,##0.00_);_(* \(#,##0.00\);_(* "-"??_);_(@_)`,
 	44: `_("$"* #,##0.00_);_("$"* \(#,##0.00\);_("$"* "-"??_);_(@_)`,
 	45: "mm:ss",
@@ -107,6 +107,16 @@ type xlsxStyleSheet struct {
 	styleCache        map[int]*Style
 	numFmtRefTable    map[int]xlsxNumFmt
 	parsedNumFmtTable map[string]*parsedNumberFormat
+	// numFmtCache holds the number format of each style index
+	// looked up, since the same few styles are used by most cells.
+	numFmtCache map[int]cachedNumberFormat
+}
+
+// cachedNumberFormat is the result of getNumberFormat for a style
+// index.
+type cachedNumberFormat struct {
+	numberFormat string
+	parsed       *parsedNumberFormat
 }
 
 func newXlsxStyleSheet(t *theme) *xlsxStyleSheet {
@@ -135,6 +145,9 @@ func (styles *xlsxStyleSheet) reset() {
 	// add default xf
 	styles.CellXfs = xlsxCellXfs{Count: 1, Xf: []xlsxXf{{}}}
 	styles.NumFmts = xlsxNumFmts{}
+	styles.Lock()
+	styles.numFmtCache = nil
+	styles.Unlock()
 }
 
 func (styles *xlsxStyleSheet) getStyle(styleIndex int) *Style {
@@ -235,6 +248,12 @@ func getBuiltinNumberFormat(numFmtId int) string {
 }
 
 func (styles *xlsxStyleSheet) getNumberFormat(styleIndex int) (string, *parsedNumberFormat) {
+	styles.RLock()
+	cached, ok := styles.numFmtCache[styleIndex]
+	styles.RUnlock()
+	if ok {
+		return cached.numberFormat, cached.parsed
+	}
 	var numberFormat string = "general"
 	if styles.CellXfs.Xf != nil {
 		if styleIndex > -1 && styleIndex <= styles.CellXfs.Count {
@@ -249,14 +268,24 @@ func (styles *xlsxStyleSheet) getNumberFormat(styleIndex int) (string, *parsedNu
 			}
 		}
 	}
+	styles.RLock()
 	parsedFmt, ok := styles.parsedNumFmtTable[numberFormat]
+	styles.RUnlock()
 	if !ok {
+		parsedFmt = parseFullNumberFormatString(numberFormat)
+		styles.Lock()
 		if styles.parsedNumFmtTable == nil {
 			styles.parsedNumFmtTable = map[string]*parsedNumberFormat{}
 		}
-		parsedFmt = parseFullNumberFormatString(numberFormat)
 		styles.parsedNumFmtTable[numberFormat] = parsedFmt
+		styles.Unlock()
 	}
+	styles.Lock()
+	if styles.numFmtCache == nil {
+		styles.numFmtCache = make(map[int]cachedNumberFormat)
+	}
+	styles.numFmtCache[styleIndex] = cachedNumberFormat{numberFormat: numberFormat, parsed: parsedFmt}
+	styles.Unlock()
 	return numberFormat, parsedFmt
 }
 
@@ -333,6 +362,18 @@ func (styles *xlsxStyleSheet) addCellXf(xCellXf xlsxXf) (index int) {
 	return
 }
 
+// wrappedCellXf returns the index of the cell xf like the one at index
+// whose text is wrapped, adding it if there is none.
+func (styles *xlsxStyleSheet) wrappedCellXf(index int) int {
+	if index < 0 || index >= len(styles.CellXfs.Xf) || styles.CellXfs.Xf[index].Alignment.WrapText {
+		return index
+	}
+	xf := styles.CellXfs.Xf[index]
+	xf.Alignment.WrapText = true
+	xf.ApplyAlignment = true
+	return styles.addCellXf(xf)
+}
+
 // newNumFmt generate a xlsxNumFmt according the format code. When the FormatCode is built in, it will return a xlsxNumFmt with the NumFmtId defined in ECMA document, otherwise it will generate a new NumFmtId greater than 164.
 func (styles *xlsxStyleSheet) newNumFmt(formatCode string) xlsxNumFmt {
 	if compareFormatString(formatCode, "general") {
@@ -385,6 +426,12 @@ func (styles *xlsxStyleSheet) addNumFmt(xNumFmt xlsxNumFmt) {
 }
 
 func (styles *xlsxStyleSheet) Marshal() (string, error) {
+	return styles.marshal(styles.Fonts)
+}
+
+// marshal returns the style sheet as Marshal does, with the given fonts
+// instead of its own.
+func (styles *xlsxStyleSheet) marshal(fonts xlsxFonts) (string, error) {
 	result := xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`
 
 	xNumFmts, err := styles.NumFmts.Marshal()
@@ -394,7 +441,7 @@ func (styles *xlsxStyleSheet) Marshal() (string, error) {
 	result += xNumFmts
 
 	outputFontMap := make(map[int]int)
-	xfonts, err := styles.Fonts.Marshal(outputFontMap)
+	xfonts, err := fonts.Marshal(outputFontMap)
 	if err != nil {
 		return "", err
 	}
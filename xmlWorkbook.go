@@ -5,6 +5,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"path"
 )
 
 const (
@@ -15,6 +16,14 @@ const (
 	sheetStateVeryHidden = "veryHidden"
 )
 
+const (
+	// relationship types of the parts referred to by the workbook
+	relationshipTypeWorksheet     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"
+	relationshipTypeSharedStrings = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings"
+	relationshipTypeStyles        = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"
+	relationshipTypeTheme         = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme"
+)
+
 // xmlxWorkbookRels contains xmlxWorkbookRelations
 // which maps sheet id and sheet XML
 type xlsxWorkbookRels struct {
@@ -24,9 +33,10 @@ type xlsxWorkbookRels struct {
 
 // xmlxWorkbookRelation maps sheet id and xl/worksheets/sheet%d.xml
 type xlsxWorkbookRelation struct {
-	Id     string `xml:",attr"`
-	Target string `xml:",attr"`
-	Type   string `xml:",attr"`
+	Id         string `xml:",attr"`
+	Target     string `xml:",attr"`
+	Type       string `xml:",attr"`
+	TargetMode string `xml:",attr,omitempty"`
 }
 
 // xlsxWorkbook directly maps the workbook element from the namespace
@@ -42,6 +52,7 @@ type xlsxWorkbook struct {
 	Sheets             xlsxSheets             `xml:"sheets"`
 	DefinedNames       xlsxDefinedNames       `xml:"definedNames"`
 	CalcPr             xlsxCalcPr             `xml:"calcPr"`
+	ExtLst             *xlsxExtLst            `xml:"extLst,omitempty"`
 }
 
 // xlsxWorkbookProtection directly maps the workbookProtection element from the
@@ -49,7 +60,13 @@ type xlsxWorkbook struct {
 // - currently I have not checked it for completeness - it does as
 // much as I need.
 type xlsxWorkbookProtection struct {
-	// We don't need this, yet.
+	WorkbookPassword      string `xml:"workbookPassword,attr,omitempty"`
+	LockStructure         bool   `xml:"lockStructure,attr,omitempty"`
+	LockWindows           bool   `xml:"lockWindows,attr,omitempty"`
+	WorkbookAlgorithmName string `xml:"workbookAlgorithmName,attr,omitempty"`
+	WorkbookHashValue     string `xml:"workbookHashValue,attr,omitempty"`
+	WorkbookSaltValue     string `xml:"workbookSaltValue,attr,omitempty"`
+	WorkbookSpinCount     int    `xml:"workbookSpinCount,attr,omitempty"`
 }
 
 // xlsxFileVersion directly maps the fileVersion element from the
@@ -149,6 +166,47 @@ type xlsxDefinedName struct {
 	PublishToServer   bool   `xml:"publishToServer,attr,omitempty"`
 	WorkbookParameter bool   `xml:"workbookParameter,attr,omitempty"`
 	Xlm               bool   `xml:"xml,attr,omitempty"`
+	// local is true when the name is local to the sheet at
+	// LocalSheetID, which a LocalSheetID of 0 doesn't tell on its own.
+	local bool
+}
+
+// IsLocal returns true when the defined name is local to the sheet at
+// the index LocalSheetID rather than global to the workbook.
+func (d *xlsxDefinedName) IsLocal() bool {
+	return d.local || d.LocalSheetID != 0
+}
+
+// SetLocalSheetID makes the defined name local to the sheet at index,
+// which may be the first sheet, at 0.
+func (d *xlsxDefinedName) SetLocalSheetID(index int) {
+	d.LocalSheetID, d.local = index, true
+}
+
+// MarshalXML writes the definedName element, with the localSheetId
+// attribute of the names local to the first sheet, which omitempty
+// would otherwise leave out.
+func (d xlsxDefinedName) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type definedName xlsxDefinedName
+	if d.local && d.LocalSheetID == 0 {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "localSheetId"}, Value: "0"})
+	}
+	return e.EncodeElement(definedName(d), start)
+}
+
+// UnmarshalXML reads the definedName element, noting whether it has a
+// localSheetId attribute.
+func (d *xlsxDefinedName) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	type definedName xlsxDefinedName
+	if err := dec.DecodeElement((*definedName)(d), &start); err != nil {
+		return err
+	}
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "localSheetId" {
+			d.local = true
+		}
+	}
+	return nil
 }
 
 // xlsxCalcPr directly maps the calcPr element from the namespace
@@ -156,11 +214,15 @@ type xlsxDefinedName struct {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxCalcPr struct {
-	CalcId       string  `xml:"calcId,attr,omitempty"`
-	IterateCount int     `xml:"iterateCount,attr,omitempty"`
-	RefMode      string  `xml:"refMode,attr,omitempty"`
-	Iterate      bool    `xml:"iterate,attr,omitempty"`
-	IterateDelta float64 `xml:"iterateDelta,attr,omitempty"`
+	CalcId         string  `xml:"calcId,attr,omitempty"`
+	IterateCount   int     `xml:"iterateCount,attr,omitempty"`
+	RefMode        string  `xml:"refMode,attr,omitempty"`
+	Iterate        bool    `xml:"iterate,attr,omitempty"`
+	IterateDelta   float64 `xml:"iterateDelta,attr,omitempty"`
+	CalcMode       string  `xml:"calcMode,attr,omitempty"`
+	FullCalcOnLoad bool    `xml:"fullCalcOnLoad,attr,omitempty"`
+	CalcOnSave     *bool   `xml:"calcOnSave,attr"`
+	FullPrecision  *bool   `xml:"fullPrecision,attr"`
 }
 
 // Helper function to lookup the file corresponding to a xlsxSheet object in the worksheets map
@@ -176,6 +238,19 @@ func worksheetFileForSheet(sheet xlsxSheet, worksheets map[string]*zip.File, she
 	return worksheets[sheetName]
 }
 
+// worksheetRelsFileForSheet returns the file holding the
+// relationships of the worksheet referred to by a xlsxSheet, or nil
+// if it has none.
+func worksheetRelsFileForSheet(sheet xlsxSheet, worksheets map[string]*zip.File, sheetXMLMap map[string]string) *zip.File {
+	f := worksheetFileForSheet(sheet, worksheets, sheetXMLMap)
+	if f == nil {
+		return nil
+	}
+	// Worksheets are keyed by the name of their file, so their
+	// relationships are keyed by "_rels/" followed by it.
+	return worksheets["_rels/"+path.Base(f.Name)]
+}
+
 // getWorksheetFromSheet() is an internal helper function to open a
 // sheetN.xml file, referred to by an xlsx.xlsxSheet struct, from the XLSX
 // file and unmarshal it an xlsx.xlsxWorksheet struct
@@ -79,6 +79,7 @@ func (w *WorkbookSuite) TestUnmarshallWorkbookXML(c *C) {
 	dname := workbook.DefinedNames.DefinedName[0]
 	c.Assert(dname.Data, Equals, "Sheet1!$A$1533")
 	c.Assert(dname.LocalSheetID, Equals, 0)
+	c.Assert(dname.IsLocal(), Equals, true)
 	c.Assert(dname.Name, Equals, "monitors")
 	c.Assert(dname.Comment, Equals, "this is the comment")
 	c.Assert(dname.Description, Equals, "give cells a name")
@@ -17,13 +17,117 @@ type xlsxWorksheet struct {
 	SheetFormatPr   xlsxSheetFormatPr        `xml:"sheetFormatPr"`
 	Cols            *xlsxCols                `xml:"cols,omitempty"`
 	SheetData       xlsxSheetData            `xml:"sheetData"`
+	SheetProtection *xlsxSheetProtection     `xml:"sheetProtection,omitempty"`
+	ProtectedRanges *xlsxProtectedRanges     `xml:"protectedRanges,omitempty"`
 	DataValidations *xlsxCellDataValidations `xml:"dataValidations"`
 	AutoFilter      *xlsxAutoFilter          `xml:"autoFilter,omitempty"`
 	MergeCells      *xlsxMergeCells          `xml:"mergeCells,omitempty"`
+	Hyperlinks      *xlsxHyperlinks          `xml:"hyperlinks,omitempty"`
 	PrintOptions    xlsxPrintOptions         `xml:"printOptions"`
 	PageMargins     xlsxPageMargins          `xml:"pageMargins"`
 	PageSetUp       xlsxPageSetUp            `xml:"pageSetup"`
 	HeaderFooter    xlsxHeaderFooter         `xml:"headerFooter"`
+	IgnoredErrors   *xlsxIgnoredErrors       `xml:"ignoredErrors,omitempty"`
+	Drawing         *xlsxDrawing             `xml:"drawing,omitempty"`
+	LegacyDrawing   *xlsxLegacyDrawing       `xml:"legacyDrawing,omitempty"`
+	TableParts      *xlsxTableParts          `xml:"tableParts,omitempty"`
+	ExtLst          *xlsxExtLst              `xml:"extLst,omitempty"`
+}
+
+// xlsxTableParts directly maps the tableParts element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxTableParts struct {
+	Count     int             `xml:"count,attr"`
+	TablePart []xlsxTablePart `xml:"tablePart"`
+}
+
+// xlsxDrawing directly maps the drawing element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxDrawing struct {
+	Id string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+}
+
+// xlsxLegacyDrawing directly maps the legacyDrawing element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxLegacyDrawing struct {
+	Id string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+}
+
+// xlsxHyperlinks directly maps the hyperlinks element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxHyperlinks struct {
+	Hyperlink []xlsxHyperlink `xml:"hyperlink"`
+}
+
+// xlsxHyperlink directly maps the hyperlink element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxHyperlink struct {
+	Ref      string `xml:"ref,attr"`
+	Id       string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr,omitempty"`
+	Location string `xml:"location,attr,omitempty"`
+	Tooltip  string `xml:"tooltip,attr,omitempty"`
+	Display  string `xml:"display,attr,omitempty"`
+}
+
+// xlsxTablePart directly maps the tablePart element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxTablePart struct {
+	Id string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+}
+
+// xlsxExtLst directly maps the extLst element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxExtLst struct {
+	Ext []xlsxExt `xml:"ext"`
+}
+
+// xlsxExt directly maps the ext element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main - the
+// content of an extension is kept as raw XML, as it lives in a
+// namespace of its own that is identified by the URI.
+type xlsxExt struct {
+	URI        string     `xml:"uri,attr"`
+	Namespaces []xml.Attr `xml:",any,attr"`
+	Content    string     `xml:",innerxml"`
+}
+
+// xlsxIgnoredErrors directly maps the ignoredErrors element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxIgnoredErrors struct {
+	IgnoredError []xlsxIgnoredError `xml:"ignoredError"`
+}
+
+// xlsxIgnoredError directly maps the ignoredError element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxIgnoredError struct {
+	Sqref              string `xml:"sqref,attr"`
+	EvalError          bool   `xml:"evalError,attr,omitempty"`
+	TwoDigitTextYear   bool   `xml:"twoDigitTextYear,attr,omitempty"`
+	NumberStoredAsText bool   `xml:"numberStoredAsText,attr,omitempty"`
+	Formula            bool   `xml:"formula,attr,omitempty"`
+	FormulaRange       bool   `xml:"formulaRange,attr,omitempty"`
+	UnlockedFormula    bool   `xml:"unlockedFormula,attr,omitempty"`
+	EmptyCellReference bool   `xml:"emptyCellReference,attr,omitempty"`
+	ListDataValidation bool   `xml:"listDataValidation,attr,omitempty"`
+	CalculatedColumn   bool   `xml:"calculatedColumn,attr,omitempty"`
 }
 
 // xlsxHeaderFooter directly maps the headerFooter element in the namespace
@@ -172,10 +276,22 @@ type xlsxPane struct {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxSheetPr struct {
+	CodeName    string            `xml:"codeName,attr,omitempty"`
 	FilterMode  bool              `xml:"filterMode,attr"`
+	TabColor    *xlsxColor        `xml:"tabColor,omitempty"`
+	OutlinePr   *xlsxOutlinePr    `xml:"outlinePr,omitempty"`
 	PageSetUpPr []xlsxPageSetUpPr `xml:"pageSetUpPr"`
 }
 
+// xlsxOutlinePr directly maps the outlinePr element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxOutlinePr struct {
+	SummaryBelow *bool `xml:"summaryBelow,attr,omitempty"`
+	SummaryRight *bool `xml:"summaryRight,attr,omitempty"`
+}
+
 // xlsxPageSetUpPr directly maps the pageSetupPr element in the namespace
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
 // currently I have not checked it for completeness - it does as much
@@ -224,6 +340,59 @@ type xlsxSheetData struct {
 	Row     []xlsxRow `xml:"row"`
 }
 
+// xlsxSheetProtection directly maps the sheetProtection element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+// The attributes that default to true in the spec are pointers, so
+// that an attribute that was not present can be told apart from one
+// that was explicitly set to false.
+type xlsxSheetProtection struct {
+	Password            string `xml:"password,attr,omitempty"`
+	AlgorithmName       string `xml:"algorithmName,attr,omitempty"`
+	HashValue           string `xml:"hashValue,attr,omitempty"`
+	SaltValue           string `xml:"saltValue,attr,omitempty"`
+	SpinCount           int    `xml:"spinCount,attr,omitempty"`
+	Sheet               bool   `xml:"sheet,attr,omitempty"`
+	Objects             bool   `xml:"objects,attr,omitempty"`
+	Scenarios           bool   `xml:"scenarios,attr,omitempty"`
+	FormatCells         *bool  `xml:"formatCells,attr"`
+	FormatColumns       *bool  `xml:"formatColumns,attr"`
+	FormatRows          *bool  `xml:"formatRows,attr"`
+	InsertColumns       *bool  `xml:"insertColumns,attr"`
+	InsertRows          *bool  `xml:"insertRows,attr"`
+	InsertHyperlinks    *bool  `xml:"insertHyperlinks,attr"`
+	DeleteColumns       *bool  `xml:"deleteColumns,attr"`
+	DeleteRows          *bool  `xml:"deleteRows,attr"`
+	SelectLockedCells   bool   `xml:"selectLockedCells,attr,omitempty"`
+	Sort                *bool  `xml:"sort,attr"`
+	AutoFilter          *bool  `xml:"autoFilter,attr"`
+	PivotTables         *bool  `xml:"pivotTables,attr"`
+	SelectUnlockedCells bool   `xml:"selectUnlockedCells,attr,omitempty"`
+}
+
+// xlsxProtectedRanges directly maps the protectedRanges element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxProtectedRanges struct {
+	ProtectedRange []xlsxProtectedRange `xml:"protectedRange"`
+}
+
+// xlsxProtectedRange directly maps the protectedRange element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxProtectedRange struct {
+	Password      string `xml:"password,attr,omitempty"`
+	AlgorithmName string `xml:"algorithmName,attr,omitempty"`
+	HashValue     string `xml:"hashValue,attr,omitempty"`
+	SaltValue     string `xml:"saltValue,attr,omitempty"`
+	SpinCount     int    `xml:"spinCount,attr,omitempty"`
+	Name          string `xml:"name,attr"`
+	Sqref         string `xml:"sqref,attr"`
+}
+
 // xlsxCellDataValidations  excel cell data validation
 type xlsxCellDataValidations struct {
 	DataValidation []*xlsxCellDataValidation `xml:"dataValidation"`
@@ -291,10 +460,113 @@ type xlsxRow struct {
 	Ht           string  `xml:"ht,attr,omitempty"`
 	CustomHeight bool    `xml:"customHeight,attr,omitempty"`
 	OutlineLevel uint8   `xml:"outlineLevel,attr,omitempty"`
+	S            int     `xml:"s,attr,omitempty"`
+	CustomFormat bool    `xml:"customFormat,attr,omitempty"`
 }
 
 type xlsxAutoFilter struct {
-	Ref string `xml:"ref,attr"`
+	Ref          string             `xml:"ref,attr"`
+	FilterColumn []xlsxFilterColumn `xml:"filterColumn,omitempty"`
+	SortState    *xlsxSortState     `xml:"sortState,omitempty"`
+}
+
+// xlsxFilterColumn directly maps the filterColumn element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxFilterColumn struct {
+	ColId         int                `xml:"colId,attr"`
+	Filters       *xlsxFilters       `xml:"filters,omitempty"`
+	Top10         *xlsxTop10         `xml:"top10,omitempty"`
+	CustomFilters *xlsxCustomFilters `xml:"customFilters,omitempty"`
+	DynamicFilter *xlsxDynamicFilter `xml:"dynamicFilter,omitempty"`
+}
+
+// xlsxFilters directly maps the filters element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxFilters struct {
+	Blank         bool                `xml:"blank,attr,omitempty"`
+	Filter        []xlsxFilter        `xml:"filter"`
+	DateGroupItem []xlsxDateGroupItem `xml:"dateGroupItem"`
+}
+
+// xlsxDateGroupItem directly maps the dateGroupItem element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.  The fields below the year are written as the grouping
+// needs them, even when zero, such as an hour of 0.
+type xlsxDateGroupItem struct {
+	Year             int    `xml:"year,attr"`
+	Month            *int   `xml:"month,attr,omitempty"`
+	Day              *int   `xml:"day,attr,omitempty"`
+	Hour             *int   `xml:"hour,attr,omitempty"`
+	Minute           *int   `xml:"minute,attr,omitempty"`
+	Second           *int   `xml:"second,attr,omitempty"`
+	DateTimeGrouping string `xml:"dateTimeGrouping,attr"`
+}
+
+// xlsxFilter directly maps the filter element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxFilter struct {
+	Val string `xml:"val,attr"`
+}
+
+// xlsxTop10 directly maps the top10 element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxTop10 struct {
+	Top     bool    `xml:"top,attr"`
+	Percent bool    `xml:"percent,attr,omitempty"`
+	Val     float64 `xml:"val,attr"`
+}
+
+// xlsxCustomFilters directly maps the customFilters element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxCustomFilters struct {
+	And          bool               `xml:"and,attr,omitempty"`
+	CustomFilter []xlsxCustomFilter `xml:"customFilter"`
+}
+
+// xlsxCustomFilter directly maps the customFilter element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxCustomFilter struct {
+	Operator string `xml:"operator,attr,omitempty"`
+	Val      string `xml:"val,attr"`
+}
+
+// xlsxDynamicFilter directly maps the dynamicFilter element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxDynamicFilter struct {
+	Type string `xml:"type,attr"`
+}
+
+// xlsxSortState directly maps the sortState element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxSortState struct {
+	Ref           string              `xml:"ref,attr"`
+	SortCondition []xlsxSortCondition `xml:"sortCondition"`
+}
+
+// xlsxSortCondition directly maps the sortCondition element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxSortCondition struct {
+	Descending bool   `xml:"descending,attr,omitempty"`
+	Ref        string `xml:"ref,attr"`
 }
 
 type xlsxMergeCell struct {
@@ -335,12 +607,14 @@ func (mc *xlsxMergeCells) getExtent(cellRef string) (int, int, error) {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxC struct {
-	R  string  `xml:"r,attr"`           // Cell ID, e.g. A1
-	S  int     `xml:"s,attr,omitempty"` // Style reference.
-	T  string  `xml:"t,attr,omitempty"` // Type.
-	F  *xlsxF  `xml:"f,omitempty"`      // Formula
-	V  string  `xml:"v,omitempty"`      // Value
-	Is *xlsxSI `xml:"is,omitempty"`     // Inline String.
+	R  string  `xml:"r,attr"`            // Cell ID, e.g. A1
+	S  int     `xml:"s,attr,omitempty"`  // Style reference.
+	T  string  `xml:"t,attr,omitempty"`  // Type.
+	Cm int     `xml:"cm,attr,omitempty"` // Cell metadata index.
+	Vm int     `xml:"vm,attr,omitempty"` // Value metadata index.
+	F  *xlsxF  `xml:"f,omitempty"`       // Formula
+	V  string  `xml:"v,omitempty"`       // Value
+	Is *xlsxSI `xml:"is,omitempty"`      // Inline String.
 }
 
 // xlsxF directly maps the f element in the namespace
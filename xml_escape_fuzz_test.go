@@ -0,0 +1,19 @@
+//go:build go1.18
+// +build go1.18
+
+package xlsx
+
+import "testing"
+
+// FuzzEscapeXML checks that EscapeXML escapes any text the way
+// xml.EscapeText does.
+func FuzzEscapeXML(f *testing.F) {
+	for _, text := range escapeXMLTexts {
+		f.Add(text)
+	}
+	f.Fuzz(func(t *testing.T, text string) {
+		if escaped, expected := EscapeXML(text), xmlEscapeText(text); escaped != expected {
+			t.Fatalf("EscapeXML(%q) = %q, expected %q", text, escaped, expected)
+		}
+	})
+}
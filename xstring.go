@@ -0,0 +1,301 @@
+package xlsx
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// XML 1.0 can't carry most control characters, not even as character
+// references.  The text of cells is written the way Excel does, as
+// ST_Xstring, in which such a character is escaped as _xHHHH_, its
+// UTF-16 code in hexadecimal.  A literal "_xHHHH_" in the text has its
+// underscore escaped in turn, as _x005F_.  Other text, such as sheet
+// names, has no such escape: encoding/xml writes the replacement
+// character U+FFFD in place of what can't be represented, unless the
+// File is Strict.
+
+// isXMLChar returns true if r may appear in an XML 1.0 document.
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		(r >= 0x20 && r <= 0xD7FF) ||
+		(r >= 0xE000 && r <= 0xFFFD) ||
+		(r >= 0x10000 && r <= utf8.MaxRune)
+}
+
+// isXStringEscape returns true if s starts with an escaped character,
+// _xHHHH_.
+func isXStringEscape(s string) bool {
+	if len(s) < 7 || s[0] != '_' || s[1] != 'x' || s[6] != '_' {
+		return false
+	}
+	for i := 2; i < 6; i++ {
+		if !isHexDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isHexDigit(b byte) bool {
+	return (b >= '0' && b <= '9') || (b >= 'a' && b <= 'f') || (b >= 'A' && b <= 'F')
+}
+
+// escapeXString escapes the characters of s that XML can't carry, and
+// the literal escapes in s, so that s can be written as the text of a
+// cell.  Strings needing no escape are returned as they are.
+func escapeXString(s string) string {
+	i := 0
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if !isXMLChar(r) || isXStringEscape(s[i:]) {
+			break
+		}
+		i += size
+	}
+	if i == len(s) {
+		return s
+	}
+	var escaped bytes.Buffer
+	escaped.WriteString(s[:i])
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case isXStringEscape(s[i:]):
+			// Only the underscore is escaped, the rest of
+			// the escape is then left as it is.
+			escaped.WriteString("_x005F_")
+		case !isXMLChar(r):
+			fmt.Fprintf(&escaped, "_x%04X_", r)
+		default:
+			// Invalid UTF-8 is left as it is, to the XML
+			// encoder.
+			escaped.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return escaped.String()
+}
+
+// unescapeXString reverses escapeXString, decoding the escapes in the
+// text of a cell read from a file.  Strings holding no escape are
+// returned as they are, sharing their memory.
+func unescapeXString(s string) string {
+	i := strings.Index(s, "_x")
+	if i < 0 {
+		return s
+	}
+	var unescaped bytes.Buffer
+	unescaped.WriteString(s[:i])
+	for i < len(s) {
+		if isXStringEscape(s[i:]) {
+			code, _ := strconv.ParseUint(s[i+2:i+6], 16, 16)
+			// A pair of escaped surrogates stands for a single
+			// character.
+			if utf16.IsSurrogate(rune(code)) && isXStringEscape(s[i+7:]) {
+				low, _ := strconv.ParseUint(s[i+9:i+13], 16, 16)
+				if r := utf16.DecodeRune(rune(code), rune(low)); r != utf8.RuneError {
+					unescaped.WriteRune(r)
+					i += 14
+					continue
+				}
+			}
+			unescaped.WriteRune(rune(code))
+			i += 7
+			continue
+		}
+		unescaped.WriteByte(s[i])
+		i++
+	}
+	return unescaped.String()
+}
+
+// excelLength returns the length of s as counted by Excel, in UTF-16
+// code units, so that characters beyond the basic multilingual plane,
+// such as most emoji, count twice.
+func excelLength(s string) int {
+	length := 0
+	for _, r := range s {
+		length++
+		if r >= 0x10000 {
+			length++
+		}
+	}
+	return length
+}
+
+// InvalidUTF8Policy is the way text that isn't valid UTF-8, such as
+// text read from legacy databases in another encoding, is dealt with
+// when a File is saved or streamed, see File.InvalidUTF8.
+type InvalidUTF8Policy int
+
+const (
+	// InvalidUTF8Replaced replaces the invalid bytes by the
+	// replacement character U+FFFD.
+	InvalidUTF8Replaced InvalidUTF8Policy = iota
+	// InvalidUTF8Stripped leaves the invalid bytes out.
+	InvalidUTF8Stripped
+	// InvalidUTF8Rejected makes saving fail with a TextError for the
+	// first text that isn't valid UTF-8.
+	InvalidUTF8Rejected
+)
+
+// apply returns s with its invalid UTF-8 stripped if the policy says
+// so.  Replacing it is left to encoding/xml, which writes U+FFFD in
+// its place.
+func (p InvalidUTF8Policy) apply(s string) string {
+	if p != InvalidUTF8Stripped || utf8.ValidString(s) {
+		return s
+	}
+	valid := make([]byte, 0, len(s))
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		if r != utf8.RuneError || size > 1 {
+			valid = append(valid, s[:size]...)
+		}
+		s = s[size:]
+	}
+	return string(valid)
+}
+
+// validUTF8 returns s with its invalid UTF-8 dealt with as the
+// InvalidUTF8 policy of the File says, if there is a File.
+func (f *File) validUTF8(s string) string {
+	if f == nil {
+		return s
+	}
+	return f.InvalidUTF8.apply(s)
+}
+
+// LineBreakPolicy is the way the line breaks of the text of cells, such
+// as pasted logs, are dealt with when a File is saved or streamed, see
+// File.LineBreaks.
+type LineBreakPolicy int
+
+const (
+	// LineBreaksNormalized replaces the Windows and old Mac line breaks
+	// by line feeds, see NormalizeLineBreaks.
+	LineBreaksNormalized LineBreakPolicy = iota
+	// LineBreaksWrapped normalizes the line breaks, and wraps the text
+	// of the cells holding them, so that their lines are shown one
+	// below the other rather than run together on one line.
+	LineBreaksWrapped
+	// LineBreaksKept writes the line breaks as they are.
+	LineBreaksKept
+)
+
+// NormalizeLineBreaks returns s with its Windows line breaks, "\r\n",
+// and its old Mac ones, "\r", replaced by line feeds, "\n", the only line
+// breaks Excel, LibreOffice and Numbers all show as such, the carriage
+// returns being shown as boxes or as "_x000D_" by some of them.
+func NormalizeLineBreaks(s string) string {
+	if strings.IndexByte(s, '\r') < 0 {
+		return s
+	}
+	return strings.Replace(strings.Replace(s, "\r\n", "\n", -1), "\r", "\n", -1)
+}
+
+// cellText returns the text of a cell as it is saved, with its invalid
+// UTF-8 and its line breaks dealt with as the policies of the File say,
+// if there is a File.
+func (f *File) cellText(s string) string {
+	if f == nil {
+		return NormalizeLineBreaks(s)
+	}
+	s = f.InvalidUTF8.apply(s)
+	if f.LineBreaks != LineBreaksKept {
+		s = NormalizeLineBreaks(s)
+	}
+	return s
+}
+
+// wrapsLineBreaks returns true if the text of the cells holding line
+// breaks is wrapped, see LineBreaksWrapped.
+func (f *File) wrapsLineBreaks() bool {
+	return f != nil && f.LineBreaks == LineBreaksWrapped
+}
+
+// TextError is returned when saving a Strict File holding text that
+// can't be represented in an XLSX file.
+type TextError struct {
+	// Where names the text, such as "sheet name" or "cell Data!A1".
+	Where string
+	Text  string
+}
+
+// Error returns a description of the TextError.
+func (e *TextError) Error() string {
+	return fmt.Sprintf("the %s %q can't be represented in an XLSX file", e.Where, e.Text)
+}
+
+// checkText returns a TextError if s isn't valid UTF-8 or, unless
+// escaped is true, holds characters that XML can't carry.
+func checkText(where, s string, escaped bool) error {
+	if !utf8.ValidString(s) {
+		return &TextError{Where: where, Text: s}
+	}
+	if escaped {
+		return nil
+	}
+	for _, r := range s {
+		if !isXMLChar(r) {
+			return &TextError{Where: where, Text: s}
+		}
+	}
+	return nil
+}
+
+// checkUTF8 returns a TextError if s isn't valid UTF-8.
+func checkUTF8(where, s string, escaped bool) error {
+	if !utf8.ValidString(s) {
+		return &TextError{Where: where, Text: s}
+	}
+	return nil
+}
+
+// checkText returns a TextError for the first text of the File that
+// would be changed by saving it, see File.Strict.
+func (f *File) checkText() error {
+	return f.walkText(checkText)
+}
+
+// walkText calls check with each text of the File, stopping at the
+// first error.  The texts that are escaped when written, such as the
+// values of cells, are passed with escaped set to true.
+func (f *File) walkText(check func(where, s string, escaped bool) error) error {
+	for _, sheet := range f.Sheets {
+		if err := check("sheet name", sheet.Name, false); err != nil {
+			return err
+		}
+		for y, row := range sheet.rowList() {
+			if row == nil {
+				continue
+			}
+			row.load()
+			for x, cell := range row.Cells {
+				if cell == nil {
+					continue
+				}
+				where := "cell " + sheet.Name + "!" + GetCellIDStringFromCoords(x, y)
+				if err := check(where, cell.Value, true); err != nil {
+					return err
+				}
+				if err := check("formula of "+where, cell.formula, false); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, name := range f.DefinedNames {
+		if err := check("defined name", name.Name, false); err != nil {
+			return err
+		}
+		if err := check("definition of "+name.Name, name.Data, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
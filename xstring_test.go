@@ -0,0 +1,254 @@
+package xlsx
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type XStringSuite struct{}
+
+var _ = Suite(&XStringSuite{})
+
+// xstringCases are texts along with their escaped form, as written by
+// Excel and read by Excel and LibreOffice.
+var xstringCases = []struct {
+	text    string
+	escaped string
+}{
+	{"plain", "plain"},
+	{"tab\tline\nreturn\r", "tab\tline\nreturn\r"},
+	{"bell\x07", "bell_x0007_"},
+	{"\x00nul", "_x0000_nul"},
+	{"\x1b[0m", "_x001B_[0m"},
+	{"not a char \uFFFE", "not a char _xFFFE_"},
+	{"_x0041_", "_x005F_x0041_"},
+	{"_x005F_", "_x005F_x005F_"},
+	{"price_x", "price_x"},
+	{"_x12_", "_x12_"},
+	{"emoji 😀👍🏽", "emoji 😀👍🏽"},
+	{"rtl \u200Fשלום\u200E", "rtl \u200Fשלום\u200E"},
+	{"astral 𠀀𝄞", "astral 𠀀𝄞"},
+}
+
+func (s *XStringSuite) TestEscapeXString(c *C) {
+	for _, tc := range xstringCases {
+		c.Assert(escapeXString(tc.text), Equals, tc.escaped)
+		c.Assert(unescapeXString(tc.escaped), Equals, tc.text)
+	}
+}
+
+func (s *XStringSuite) TestUnescapeXString(c *C) {
+	c.Assert(unescapeXString("line_x000d__x000a_"), Equals, "line\r\n")
+	// Characters beyond the basic multilingual plane may be escaped
+	// as a pair of surrogates.
+	c.Assert(unescapeXString("_xD83D__xDE00_"), Equals, "😀")
+	c.Assert(unescapeXString("_xD83D_"), Equals, "\uFFFD")
+}
+
+func (s *XStringSuite) TestExcelLength(c *C) {
+	c.Assert(excelLength("Sheet1"), Equals, 6)
+	c.Assert(excelLength("שלום"), Equals, 4)
+	c.Assert(excelLength("😀"), Equals, 2)
+	c.Assert(excelLength("𠀀a"), Equals, 3)
+}
+
+// Sheet names longer than 31 characters counted in UTF-16 are refused.
+func (s *XStringSuite) TestAddSheetWithEmojiName(c *C) {
+	f := NewFile()
+	_, err := f.AddSheet("😀😀😀😀😀😀😀😀😀😀😀😀😀😀😀a")
+	c.Assert(err, IsNil)
+	_, err = f.AddSheet("😀😀😀😀😀😀😀😀😀😀😀😀😀😀😀😀")
+	c.Assert(err, ErrorMatches, "sheet name must be 31 or fewer characters long.  It is currently '32' characters long")
+}
+
+// Unicode text makes the round trip through every part it is written
+// to.
+func (s *XStringSuite) TestUnicodeRoundTrip(c *C) {
+	f := NewFile()
+	// The carriage returns are kept rather than normalized.
+	f.LineBreaks = LineBreaksKept
+	sheet, err := f.AddSheet("Données 😀 \u200Fשלום")
+	c.Assert(err, IsNil)
+	for _, tc := range xstringCases {
+		sheet.AddRow().AddCell().SetString(tc.text)
+	}
+	f.DefinedNames = append(f.DefinedNames, &xlsxDefinedName{
+		Name: "Πίνακας",
+		Data: "'Données 😀 \u200Fשלום'!$A$1:$A$13",
+	})
+
+	var buffer bytes.Buffer
+	c.Assert(f.Write(&buffer), IsNil)
+	f, err = OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	sheet = f.Sheets[0]
+	c.Assert(sheet.Name, Equals, "Données 😀 \u200Fשלום")
+	for i, tc := range xstringCases {
+		c.Assert(sheet.Cell(i, 0).Value, Equals, tc.text)
+	}
+	c.Assert(f.DefinedNames[0].Name, Equals, "Πίνακας")
+	c.Assert(f.DefinedNames[0].Data, Equals, "'Données 😀 \u200Fשלום'!$A$1:$A$13")
+}
+
+// Streamed cells are escaped as well.
+func (s *XStringSuite) TestStreamedControlCharacters(c *C) {
+	var buffer bytes.Buffer
+	builder := NewStreamFileBuilder(&buffer)
+	c.Assert(builder.AddSheet("Sheet1", []string{"Header"}, nil), IsNil)
+	stream, err := builder.Build()
+	c.Assert(err, IsNil)
+	c.Assert(stream.Write([]string{"bell\x07 _x0041_"}), IsNil)
+	c.Assert(stream.Close(), IsNil)
+
+	f, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheets[0].Cell(1, 0).Value, Equals, "bell\x07 _x0041_")
+}
+
+func (s *XStringSuite) TestStrict(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Bell\x07")
+	c.Assert(err, IsNil)
+	sheet.AddRow().AddCell().SetString("bell\x07")
+	var buffer bytes.Buffer
+	c.Assert(f.Write(&buffer), IsNil)
+
+	f.Strict = true
+	err = f.Write(&buffer)
+	_, ok := err.(*TextError)
+	c.Assert(ok, Equals, true)
+	c.Assert(err, ErrorMatches, `the sheet name "Bell\\a" can't be represented in an XLSX file`)
+
+	// Control characters in cells are escaped, invalid UTF-8 can't
+	// be.
+	sheet.Name = "Bell"
+	c.Assert(f.Write(&buffer), IsNil)
+	sheet.Cell(0, 0).SetString("\xff")
+	err = f.Write(&buffer)
+	c.Assert(err, ErrorMatches, `the cell Bell!A1 "\\xff" can't be represented in an XLSX file`)
+
+	sheet.Cell(0, 0).SetString("ok")
+	f.DefinedNames = append(f.DefinedNames, &xlsxDefinedName{Name: "Name\x01", Data: "Bell!$A$1"})
+	err = f.Write(&buffer)
+	c.Assert(err, ErrorMatches, `the defined name "Name\\x01" can't be represented in an XLSX file`)
+}
+
+func (s *XStringSuite) TestInvalidUTF8(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Legacy")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetString("caf\xe9 cr\xe8me")
+	sheet.Cell(0, 1).SetFormula("\"caf\xe9\"")
+
+	var buffer bytes.Buffer
+	c.Assert(f.Write(&buffer), IsNil)
+	read, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(read.Sheets[0].Cell(0, 0).Value, Equals, "caf\uFFFD cr\uFFFDme")
+
+	f.InvalidUTF8 = InvalidUTF8Stripped
+	buffer.Reset()
+	c.Assert(f.Write(&buffer), IsNil)
+	read, err = OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(read.Sheets[0].Cell(0, 0).Value, Equals, "caf crme")
+	c.Assert(read.Sheets[0].Cell(0, 1).Formula(), Equals, `"caf"`)
+	// The File itself is left as it is.
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "caf\xe9 cr\xe8me")
+
+	f.InvalidUTF8 = InvalidUTF8Rejected
+	err = f.Write(&buffer)
+	_, ok := err.(*TextError)
+	c.Assert(ok, Equals, true)
+	c.Assert(err, ErrorMatches, `the cell Legacy!A1 "caf\\xe9 cr\\xe8me" can't be represented in an XLSX file`)
+}
+
+func (s *XStringSuite) TestStreamedInvalidUTF8(c *C) {
+	var buffer bytes.Buffer
+	builder := NewStreamFileBuilder(&buffer)
+	c.Assert(builder.AddSheet("Legacy", []string{"Name", "City"}, nil), IsNil)
+	c.Assert(builder.SetInvalidUTF8(InvalidUTF8Stripped), IsNil)
+	stream, err := builder.Build()
+	c.Assert(err, IsNil)
+	row := []string{"Ren\xe9", "Paris"}
+	c.Assert(stream.Write(row), IsNil)
+	c.Assert(row[0], Equals, "Ren\xe9")
+	c.Assert(stream.BeginRow(), IsNil)
+	c.Assert(stream.WriteCell("Lyon"), IsNil)
+	c.Assert(stream.WriteCell("N\xeemes"), IsNil)
+	c.Assert(stream.EndRow(), IsNil)
+	c.Assert(stream.Close(), IsNil)
+	f, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheets[0].Cell(1, 0).Value, Equals, "Ren")
+	c.Assert(f.Sheets[0].Cell(2, 1).Value, Equals, "Nmes")
+
+	builder = NewStreamFileBuilder(&bytes.Buffer{})
+	c.Assert(builder.AddSheet("Legacy", []string{"Name", "City"}, nil), IsNil)
+	c.Assert(builder.SetInvalidUTF8(InvalidUTF8Rejected), IsNil)
+	stream, err = builder.Build()
+	c.Assert(err, IsNil)
+	err = stream.WriteAllContinueOnError([][]string{{"Anne", "Paris"}, {"Ren\xe9", "Paris"}})
+	c.Assert(err, ErrorMatches, `row 1: the cell Legacy!A3 "Ren\\xe9" can't be represented in an XLSX file`)
+	c.Assert(stream.BeginRow(), IsNil)
+	c.Assert(stream.WriteCell("Lyon"), IsNil)
+	c.Assert(stream.WriteCell("N\xeemes"), ErrorMatches, `the cell Legacy!B3 "N\\xeemes" can't be represented in an XLSX file`)
+
+	builder = NewStreamFileBuilder(&bytes.Buffer{})
+	c.Assert(builder.AddSheet("Legacy", []string{"Nom\xe9"}, nil), IsNil)
+	c.Assert(builder.SetInvalidUTF8(InvalidUTF8Rejected), IsNil)
+	_, err = builder.Build()
+	_, ok := err.(*TextError)
+	c.Assert(ok, Equals, true)
+}
+
+func (s *XStringSuite) TestLineBreaks(c *C) {
+	c.Assert(NormalizeLineBreaks("one\r\ntwo\rthree\nfour"), Equals, "one\ntwo\nthree\nfour")
+	c.Assert(NormalizeLineBreaks("one line"), Equals, "one line")
+
+	f := NewFile()
+	sheet, err := f.AddSheet("Logs")
+	c.Assert(err, IsNil)
+	row := sheet.AddRow()
+	row.AddCell().SetString("started\r\nfailed")
+	row.AddCell().SetString("one line")
+	bold := NewStyle()
+	bold.Font.Bold = true
+	cell := row.AddCell()
+	cell.SetString("bold\nlines")
+	cell.SetStyle(bold)
+	var buffer bytes.Buffer
+	c.Assert(f.Write(&buffer), IsNil)
+	read, err := OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(read.Sheets[0].Cell(0, 0).Value, Equals, "started\nfailed")
+	c.Assert(read.Sheets[0].Cell(0, 0).GetStyle().Alignment.WrapText, Equals, false)
+
+	f.LineBreaks = LineBreaksWrapped
+	buffer.Reset()
+	c.Assert(f.Write(&buffer), IsNil)
+	read, err = OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(read.Sheets[0].Cell(0, 0).GetStyle().Alignment.WrapText, Equals, true)
+	c.Assert(read.Sheets[0].Cell(0, 1).GetStyle().Alignment.WrapText, Equals, false)
+	c.Assert(read.Sheets[0].Cell(0, 2).GetStyle().Alignment.WrapText, Equals, true)
+	c.Assert(read.Sheets[0].Cell(0, 2).GetStyle().Font.Bold, Equals, true)
+
+	// The streamed cells holding line breaks get the wrapped twin of
+	// their style.
+	buffer.Reset()
+	builder := NewStreamFileBuilder(&buffer)
+	c.Assert(builder.AddSheet("Logs", []string{"Message", "Level"}, nil), IsNil)
+	c.Assert(builder.SetLineBreaks(LineBreakPolicy(5)), ErrorMatches, "invalid line break policy 5")
+	c.Assert(builder.SetLineBreaks(LineBreaksWrapped), IsNil)
+	stream, err := builder.Build()
+	c.Assert(err, IsNil)
+	c.Assert(stream.Write([]string{"started\r\nfailed", "error"}), IsNil)
+	c.Assert(stream.Close(), IsNil)
+	read, err = OpenBinary(buffer.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(read.Sheets[0].Cell(1, 0).Value, Equals, "started\nfailed")
+	c.Assert(read.Sheets[0].Cell(1, 0).GetStyle().Alignment.WrapText, Equals, true)
+	c.Assert(read.Sheets[0].Cell(1, 1).GetStyle().Alignment.WrapText, Equals, false)
+}
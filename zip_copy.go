@@ -0,0 +1,25 @@
+//go:build go1.17
+// +build go1.17
+
+package xlsx
+
+import (
+	"archive/zip"
+	"io"
+)
+
+// copyZipPart copies the part f of a zip file read to zipWriter as it
+// is, without decompressing it.
+func copyZipPart(zipWriter *zip.Writer, f *zip.File) error {
+	r, err := f.OpenRaw()
+	if err != nil {
+		return err
+	}
+	header := f.FileHeader
+	w, err := zipWriter.CreateRaw(&header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
@@ -0,0 +1,30 @@
+//go:build !go1.17
+// +build !go1.17
+
+package xlsx
+
+import (
+	"archive/zip"
+	"io"
+)
+
+// copyZipPart copies the part f of a zip file read to zipWriter, with
+// the same compression method.  Go 1.17 copies it without
+// decompressing it.
+func copyZipPart(zipWriter *zip.Writer, f *zip.File) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	// FileHeader.Modified is new in Go 1.10, so the time is set the way
+	// Go 1.8 sets it.
+	header := &zip.FileHeader{Name: f.Name, Method: f.Method}
+	header.SetModTime(f.ModTime())
+	w, err := zipWriter.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}